@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/serversupervisor/agent/internal/config"
+)
+
+type joinRequest struct {
+	Token     string `json:"token"`
+	Hostname  string `json:"hostname"`
+	IPAddress string `json:"ip_address"`
+	JoinID    string `json:"join_id"`
+}
+
+type joinResponse struct {
+	HostID string `json:"host_id"`
+	APIKey string `json:"api_key"`
+}
+
+// joinIfNeeded registers this host with a join token when the config has no
+// usable API key yet, then writes the issued key (and server URL) into the
+// config file. It is a no-op once the host is registered, so provisioning
+// tools can pass --join-token on every start.
+func joinIfNeeded(ctx context.Context, configPath, serverURL, token string) error {
+	if _, err := config.Load(configPath); err == nil {
+		slog.Debug("agent already registered, ignoring join token", "path", configPath)
+		return nil
+	}
+
+	existing, err := os.ReadFile(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	raw := string(existing)
+	if raw == "" {
+		raw = config.DefaultConfigFile()
+	}
+
+	if serverURL == "" {
+		serverURL = os.Getenv("SUPERVISOR_SERVER_URL")
+	}
+	if serverURL == "" && len(existing) > 0 {
+		var file struct {
+			ServerURL string `yaml:"server_url"`
+		}
+		_ = yaml.Unmarshal(existing, &file)
+		serverURL = file.ServerURL
+	}
+	serverURL = strings.TrimRight(strings.TrimSpace(serverURL), "/")
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("a valid server URL is required to join (--server-url, SUPERVISOR_SERVER_URL or server_url in the config)")
+	}
+
+	if dir := filepath.Dir(configPath); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("create config directory: %w", err)
+		}
+	}
+	joinIDPath := configPath + ".join-id"
+	joinID, err := loadJoinID(joinIDPath)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	body, _ := json.Marshal(joinRequest{Token: token, Hostname: hostname, IPAddress: outboundIP(u), JoinID: joinID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+"/api/agent/join", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("join request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("join refused: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	var out joinResponse
+	if err := json.Unmarshal(data, &out); err != nil || out.APIKey == "" {
+		return fmt.Errorf("invalid join response")
+	}
+
+	if err := os.WriteFile(configPath, []byte(config.WithCredentials(raw, serverURL, out.APIKey)), 0o600); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	_ = os.Remove(joinIDPath)
+	slog.Info("host registered with join token", "host_id", out.HostID, "path", configPath)
+	return nil
+}
+
+// loadJoinID returns the id of the pending join, creating it on the first
+// attempt. It is kept until the issued key is written so that a retry after a
+// lost response gets the host the first attempt created instead of a new one.
+func loadJoinID(path string) (string, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("write join id: %w", err)
+	}
+	return id, nil
+}
+
+// outboundIP returns the local address used to reach the server (no packet
+// is sent), or "" to let the server use the connection's source address.
+func outboundIP(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
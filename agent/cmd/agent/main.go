@@ -17,6 +17,7 @@ import (
 	"github.com/serversupervisor/agent/internal/collector"
 	"github.com/serversupervisor/agent/internal/config"
 	"github.com/serversupervisor/agent/internal/dispatcher"
+	"github.com/serversupervisor/agent/internal/identity"
 	"github.com/serversupervisor/agent/internal/logging"
 	"github.com/serversupervisor/agent/internal/reporter"
 	"github.com/serversupervisor/agent/internal/sender"
@@ -37,8 +38,9 @@ func main() {
 	configPath := flag.String("config", "/etc/serversupervisor/agent.yaml", "Path to config file")
 	initConfig := flag.Bool("init", false, "Generate and write a default config file")
 	initForce := flag.Bool("init-force", false, "Allow overwriting existing config file when used with --init")
-	initServerURL := flag.String("server-url", "", "Server URL override used with --init")
+	initServerURL := flag.String("server-url", "", "Server URL override used with --init or --join-token")
 	initAPIKey := flag.String("api-key", "", "API key override used with --init")
+	joinToken := flag.String("join-token", os.Getenv("SUPERVISOR_JOIN_TOKEN"), "Register this host with a join token when the config has no API key yet (env SUPERVISOR_JOIN_TOKEN)")
 	showVersion := flag.Bool("version", false, "Print the agent version and exit")
 	internalHealthcheck := flag.Bool("internal-healthcheck", false, "Run a one-shot self-test collection cycle and exit 0/1 accordingly (used by the self-updater to verify a new binary can actually collect metrics before committing to it, rather than only checking that it starts and parses flags)")
 	internalUpdate := flag.Bool("internal-update", false, "Run the detached self-update helper and exit")
@@ -116,6 +118,13 @@ func main() {
 		return
 	}
 
+	if *joinToken != "" {
+		if err := joinIfNeeded(context.Background(), *configPath, *initServerURL, *joinToken); err != nil {
+			slog.Error("failed to join server", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		slog.Error("failed to load config", "err", err)
@@ -165,7 +174,14 @@ func main() {
 		slog.Info("loaded custom tasks", "count", len(tc.Tasks))
 	}
 
-	s := sender.New(cfg)
+	// Per-host credential (auth_mode mtls or signature), shared by the HTTP
+	// sender and the WebSocket channel. Enrolled, then renewed, from the
+	// report loop.
+	id := identity.New(cfg)
+	if err := id.Load(); err != nil {
+		slog.Warn("failed to load the agent credential, enrolling a new one", "dir", cfg.IdentityDir, "err", err)
+	}
+	s := sender.New(cfg, id)
 
 	var skipMetrics atomic.Bool
 	rep := reporter.New(cfg, tc, &skipMetrics, Version)
@@ -187,7 +203,7 @@ func main() {
 	}()
 
 	// pollNowCh is nudged by agentws when the server pushes a "poll_now" over
-	// the optional low-latency channel. Buffered 1 and coalesced by the
+	// the optional low-latency channel (protocol v1 servers). Buffered 1 and coalesced by the
 	// non-blocking send in pollNow: several nudges before the main loop gets
 	// to them collapse into a single early poll, same as the ticker already
 	// does implicitly (it doesn't queue missed ticks either).
@@ -198,9 +214,37 @@ func main() {
 		default:
 		}
 	}
-	go agentws.Run(ctx, cfg, pollNow)
+	// Commands pushed over the channel (protocol v2) join the same worker
+	// queue as the ones a report claims. queueMu keeps that goroutine from
+	// sending on the queue once shutdown has closed it.
+	var queueMu sync.Mutex
+	queueClosed := false
+	enqueue := func(cmds []sender.PendingCommand) bool {
+		queueMu.Lock()
+		defer queueMu.Unlock()
+		if queueClosed {
+			return false
+		}
+		select {
+		case commandQueue <- cmds:
+			return true
+		default:
+			return false
+		}
+	}
+	ws := agentws.New(cfg, id, pollNow, enqueue)
+	s.SetChannel(ws)
+	go ws.Run(ctx)
 
-	rep.Send(ctx, s, commandQueue)
+	// Each cycle first enrolls or renews the agent credential when due, so
+	// the report goes out with a valid one.
+	send := func() {
+		if err := id.Ensure(ctx); err != nil {
+			slog.Warn("agent credential enrollment failed, will retry", "err", err)
+		}
+		rep.Send(ctx, s, commandQueue)
+	}
+	send()
 
 	ticker := time.NewTicker(time.Duration(cfg.ReportInterval) * time.Second)
 	defer ticker.Stop()
@@ -208,13 +252,16 @@ func main() {
 	for {
 		select {
 		case <-ticker.C:
-			rep.Send(ctx, s, commandQueue)
+			send()
 		case <-pollNowCh:
-			rep.Send(ctx, s, commandQueue)
+			send()
 			ticker.Reset(time.Duration(cfg.ReportInterval) * time.Second)
 		case <-ctx.Done():
 			slog.Info("agent shutting down")
+			queueMu.Lock()
+			queueClosed = true
 			close(commandQueue)
+			queueMu.Unlock()
 			workerWg.Wait()
 			return
 		}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/serversupervisor/agent/internal/collector"
 	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
 	"github.com/serversupervisor/agent/internal/sender"
 )
 
@@ -81,7 +82,12 @@ func runInternalUpdate(cfgPath, commandID, targetVersion string) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	s := sender.New(cfg)
+	// The credential enrolled by the agent; never renewed from here.
+	id := identity.New(cfg)
+	if err := id.Load(); err != nil {
+		logUpdate(fmt.Sprintf("failed to load the agent credential: %v", err))
+	}
+	s := sender.New(cfg, id)
 	ctx := context.Background()
 	progress := func(format string, args ...any) {
 		msg := fmt.Sprintf(format, args...)
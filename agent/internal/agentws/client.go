@@ -1,64 +1,132 @@
 // Package agentws maintains an optional, additive WebSocket connection from
-// the agent to the server, used only to be nudged to poll for pending
-// commands immediately (see the server's internal/ws.AgentHub) instead of
-// waiting out the regular report_interval ticker. It never carries command
-// content over the wire — a "poll_now" nudge just triggers the agent's
-// existing, atomic poll/claim pipeline sooner, rather than opening a second
-// delivery path that could race it. If the connection can't be established
-// or drops (older server, a proxy that blocks WebSocket upgrades, a network
-// hiccup), the agent's normal poll cycle is completely unaffected — this
-// package only ever asks for polls to happen sooner, never fewer or
-// differently.
+// the agent to the server (see the server's internal/ws.AgentHub).
+//
+// The agent opens every connection with a "hello" naming the highest protocol
+// version it speaks, and the server answers with the version in use:
+//
+//   - v1 (an older server, which never answers): the server only sends
+//     "poll_now" nudges, which trigger the agent's existing, atomic poll/claim
+//     pipeline sooner instead of waiting out report_interval.
+//   - v2: the server pushes the commands themselves ("commands"), which the
+//     agent acks once queued, and the agent sends their live output and
+//     results back on the same socket, each result acked by the server
+//     ("result_ack"). A result still unacked when the connection drops is
+//     resent as soon as a new session is up.
+//
+// HTTP stays the fallback throughout: the periodic report still claims any
+// pending command, and a result that cannot be acked over the socket in time
+// goes out over the regular HTTP endpoint (see sender.Channel). If the
+// connection can't be established or drops (older server, a proxy that blocks
+// WebSocket upgrades, a network hiccup), the agent keeps working exactly as
+// without it.
 package agentws
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
+	"github.com/serversupervisor/agent/internal/sender"
 )
 
+// ProtocolVersion is the highest channel protocol this agent speaks.
+const ProtocolVersion = 2
+
 const (
 	heartbeatInterval = 20 * time.Second
 	dialTimeout       = 10 * time.Second
+	writeTimeout      = 10 * time.Second
 	minBackoff        = 3 * time.Second
 	maxBackoff        = 60 * time.Second
+	// resultAckTimeout is how long a result waits for the server's ack,
+	// reconnects included, before the sender falls back to HTTP.
+	resultAckTimeout = 15 * time.Second
 )
 
-type message struct {
-	Type string `json:"type"`
+// inbound is any message the server sends.
+type inbound struct {
+	Type     string                  `json:"type"`
+	V        int                     `json:"v,omitempty"`        // welcome
+	Commands []sender.PendingCommand `json:"commands,omitempty"` // commands
+	Seq      uint64                  `json:"seq,omitempty"`      // result_ack
+	Error    string                  `json:"error,omitempty"`    // result_ack
 }
 
-// Run connects and reconnects (with backoff) for as long as ctx is not
-// cancelled. On each "poll_now" message from the server it calls pollNow —
-// a non-blocking signal into the agent's existing report loop; this package
-// never calls the server's report/command endpoints itself, so report
-// submissions stay serialized in the single main-loop goroutine that
-// already owns them.
-func Run(ctx context.Context, cfg *config.Config, pollNow func()) {
-	if cfg.DisableWSPush {
-		return
+// outbound is any message the agent sends.
+type outbound struct {
+	Type      string                `json:"type"`
+	V         int                   `json:"v,omitempty"`          // hello
+	IDs       []string              `json:"ids,omitempty"`        // ack, reject
+	Seq       uint64                `json:"seq,omitempty"`        // result
+	Result    *sender.CommandResult `json:"result,omitempty"`     // result
+	CommandID string                `json:"command_id,omitempty"` // output
+	Chunk     string                `json:"chunk,omitempty"`      // output
+}
+
+// pendingResult is a result sent (or waiting to be) and not acked yet.
+type pendingResult struct {
+	result *sender.CommandResult
+	acked  chan string // receives the server's refusal reason, "" when recorded
+}
+
+// Client is the agent end of the channel. It implements sender.Channel.
+type Client struct {
+	cfg     *config.Config
+	id      *identity.Identity
+	pollNow func()
+	enqueue func([]sender.PendingCommand) bool
+
+	mu      sync.Mutex
+	session *websocket.Conn // connection with a v2 session, nil when none
+	pending map[uint64]*pendingResult
+	seq     atomic.Uint64
+	writeMu sync.Mutex // gorilla allows a single concurrent writer
+}
+
+// New returns an idle Client; call Run. pollNow is called on each v1 nudge —
+// a non-blocking signal into the agent's report loop. enqueue hands commands
+// pushed over v2 to the command worker and reports whether they were queued;
+// it must not block.
+func New(cfg *config.Config, id *identity.Identity, pollNow func(), enqueue func([]sender.PendingCommand) bool) *Client {
+	return &Client{
+		cfg:     cfg,
+		id:      id,
+		pollNow: pollNow,
+		enqueue: enqueue,
+		pending: make(map[uint64]*pendingResult),
 	}
-	wsURL, err := toWebSocketURL(cfg.ServerURL)
-	if err != nil {
-		slog.Warn("agentws: invalid server_url, low-latency command push disabled", "err", err)
+}
+
+// Run connects and reconnects (with backoff) for as long as ctx is not
+// cancelled.
+func (c *Client) Run(ctx context.Context) {
+	if c.cfg.DisableWSPush {
 		return
 	}
-
 	backoff := minBackoff
 	for {
 		if ctx.Err() != nil {
 			return
 		}
 
-		if runOnce(ctx, wsURL, cfg.APIKey, cfg.InsecureSkipVerify, pollNow) {
+		// Resolved on every dial: enrolling a client certificate moves the
+		// agent to the server's mTLS listener.
+		wsURL, err := toWebSocketURL(c.id.BaseURL())
+		if err != nil {
+			slog.Warn("agentws: invalid server_url, low-latency command push disabled", "err", err)
+			return
+		}
+		if c.runOnce(ctx, wsURL) {
 			backoff = minBackoff
 		} else {
 			backoff = nextBackoff(backoff)
@@ -72,20 +140,23 @@ func Run(ctx context.Context, cfg *config.Config, pollNow func()) {
 	}
 }
 
-// runOnce dials, authenticates via the same X-API-Key header the agent
-// already sends on every HTTP call, then reads until the connection drops or
-// ctx is cancelled. Returns whether the dial itself succeeded, so the caller
-// can reset its backoff after any healthy session rather than only after a
-// long-lived one.
-func runOnce(ctx context.Context, wsURL, apiKey string, insecureSkipVerify bool, pollNow func()) bool {
+// runOnce dials, authenticates the way the agent's HTTP calls do (API key,
+// client certificate or signed upgrade request, see identity), says hello,
+// then serves the connection until it drops or ctx is cancelled. Returns
+// whether the dial itself succeeded, so the caller can reset its backoff
+// after any healthy session rather than only after a long-lived one.
+func (c *Client) runOnce(ctx context.Context, wsURL string) bool {
 	dialer := websocket.Dialer{
 		HandshakeTimeout: dialTimeout,
-		TLSClientConfig:  &tls.Config{InsecureSkipVerify: insecureSkipVerify}, //nolint:gosec // operator opt-in, mirrors sender.Sender's existing transport
+		TLSClientConfig:  c.id.TLSConfig(),
 	}
-	header := http.Header{}
-	header.Set("X-API-Key", apiKey)
+	upgrade, err := http.NewRequestWithContext(ctx, http.MethodGet, wsURL, nil)
+	if err != nil {
+		return false
+	}
+	c.id.Authorize(upgrade, nil)
 
-	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	conn, resp, err := dialer.DialContext(ctx, wsURL, upgrade.Header)
 	if resp != nil && resp.Body != nil {
 		_ = resp.Body.Close()
 	}
@@ -94,19 +165,22 @@ func runOnce(ctx context.Context, wsURL, apiKey string, insecureSkipVerify bool,
 		return false
 	}
 	defer func() { _ = conn.Close() }()
+	defer c.detach(conn)
+
+	if err := c.write(conn, outbound{Type: "hello", V: ProtocolVersion}); err != nil {
+		return true
+	}
 	slog.Info("agentws: connected — low-latency command push active")
 
 	done := make(chan struct{})
 	go func() {
 		defer close(done)
 		for {
-			var msg message
+			var msg inbound
 			if err := conn.ReadJSON(&msg); err != nil {
 				return
 			}
-			if msg.Type == "poll_now" {
-				pollNow()
-			}
+			c.handle(conn, msg)
 		}
 	}()
 
@@ -121,16 +195,136 @@ func runOnce(ctx context.Context, wsURL, apiKey string, insecureSkipVerify bool,
 			slog.Debug("agentws: disconnected, will reconnect")
 			return true
 		case <-heartbeat.C:
-			// Sole writer on this connection (the read loop above never
-			// writes), so this needs no extra locking beyond gorilla's
-			// documented "one reader + one writer" concurrency guarantee.
-			if err := conn.WriteJSON(message{Type: "heartbeat"}); err != nil {
+			if err := c.write(conn, outbound{Type: "heartbeat"}); err != nil {
 				return true
 			}
 		}
 	}
 }
 
+func (c *Client) handle(conn *websocket.Conn, msg inbound) {
+	switch msg.Type {
+	case "poll_now":
+		c.pollNow()
+	case "welcome":
+		if msg.V >= 2 {
+			c.attach(conn)
+		}
+	case "commands":
+		if len(msg.Commands) == 0 {
+			return
+		}
+		ids := make([]string, len(msg.Commands))
+		for i, cmd := range msg.Commands {
+			ids[i] = cmd.ID
+		}
+		reply := outbound{Type: "ack", IDs: ids}
+		if !c.enqueue(msg.Commands) {
+			// The server puts them back to pending for a later delivery.
+			slog.Warn("agentws: command queue full, handing pushed commands back", "count", len(ids))
+			reply.Type = "reject"
+		}
+		_ = c.write(conn, reply)
+	case "result_ack":
+		c.mu.Lock()
+		p := c.pending[msg.Seq]
+		delete(c.pending, msg.Seq)
+		c.mu.Unlock()
+		if p != nil {
+			p.acked <- msg.Error
+		}
+	}
+}
+
+// attach makes conn the v2 session and resends every result still unacked —
+// those sent on a connection that dropped before the server acked them.
+func (c *Client) attach(conn *websocket.Conn) {
+	c.mu.Lock()
+	c.session = conn
+	resend := make([]outbound, 0, len(c.pending))
+	for seq, p := range c.pending {
+		resend = append(resend, outbound{Type: "result", Seq: seq, Result: p.result})
+	}
+	c.mu.Unlock()
+	slog.Info("agentws: protocol v2 session up — commands and results travel over the push channel", "resent_results", len(resend))
+	for _, m := range resend {
+		if err := c.write(conn, m); err != nil {
+			return
+		}
+	}
+}
+
+func (c *Client) detach(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.session == conn {
+		c.session = nil
+	}
+	c.mu.Unlock()
+}
+
+func (c *Client) write(conn *websocket.Conn, m outbound) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(m)
+}
+
+// SendResult sends result over the v2 session and waits for the server's ack,
+// across reconnects, for up to resultAckTimeout. Without a session, or without
+// an ack in time, it returns an error wrapping sender.ErrChannelUnavailable so
+// the sender falls back to HTTP; a duplicate delivery this may cause is
+// dropped by the server.
+func (c *Client) SendResult(ctx context.Context, result *sender.CommandResult) error {
+	p := &pendingResult{result: result, acked: make(chan string, 1)}
+	seq := c.seq.Add(1)
+
+	c.mu.Lock()
+	conn := c.session
+	if conn == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: no session", sender.ErrChannelUnavailable)
+	}
+	c.pending[seq] = p
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, seq)
+		c.mu.Unlock()
+	}()
+
+	// A failed write is not final: attach resends on the next session.
+	_ = c.write(conn, outbound{Type: "result", Seq: seq, Result: result})
+
+	timer := time.NewTimer(resultAckTimeout)
+	defer timer.Stop()
+	select {
+	case refusal := <-p.acked:
+		if refusal != "" {
+			return errors.New("server refused command result: " + refusal)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w: no ack within %s", sender.ErrChannelUnavailable, resultAckTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendChunk sends a chunk of live output over the v2 session. Chunks are not
+// acked: like their HTTP counterpart they are best-effort.
+func (c *Client) SendChunk(commandID, chunk string) error {
+	c.mu.Lock()
+	conn := c.session
+	c.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("%w: no session", sender.ErrChannelUnavailable)
+	}
+	if err := c.write(conn, outbound{Type: "output", CommandID: commandID, Chunk: chunk}); err != nil {
+		return fmt.Errorf("%w: %v", sender.ErrChannelUnavailable, err)
+	}
+	return nil
+}
+
 func toWebSocketURL(serverURL string) (string, error) {
 	u, err := url.Parse(strings.TrimSpace(serverURL))
 	if err != nil {
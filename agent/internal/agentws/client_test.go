@@ -1,8 +1,19 @@
 package agentws
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
+	"github.com/serversupervisor/agent/internal/sender"
 )
 
 func TestToWebSocketURL(t *testing.T) {
@@ -48,3 +59,171 @@ func TestNextBackoff(t *testing.T) {
 		t.Errorf("nextBackoff just under max should cap at max, got %v", got)
 	}
 }
+
+// fakeServer accepts agent connections and hands each server-side end to the
+// test, which plays the server's part of the protocol.
+func fakeServer(t *testing.T) (wsURL string, conns <-chan *websocket.Conn) {
+	t.Helper()
+	ch := make(chan *websocket.Conn, 4)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		ch <- conn
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), ch
+}
+
+func accept(t *testing.T, conns <-chan *websocket.Conn) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-conns:
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent never connected")
+		return nil
+	}
+}
+
+func readOutbound(t *testing.T, conn *websocket.Conn, wantType string) outbound {
+	t.Helper()
+	for {
+		var msg outbound
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if msg.Type == "heartbeat" {
+			continue
+		}
+		if msg.Type != wantType {
+			t.Fatalf("got %+v, want a %s", msg, wantType)
+		}
+		return msg
+	}
+}
+
+// startSession runs one connection of c in the background and completes the
+// v2 handshake on the server side.
+func startSession(t *testing.T, c *Client, wsURL string, conns <-chan *websocket.Conn) (*websocket.Conn, <-chan struct{}) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() { c.runOnce(context.Background(), wsURL); close(done) }()
+	conn := accept(t, conns)
+	if hello := readOutbound(t, conn, "hello"); hello.V != ProtocolVersion {
+		t.Fatalf("hello announced v%d", hello.V)
+	}
+	if err := conn.WriteJSON(inbound{Type: "welcome", V: 2}); err != nil {
+		t.Fatal(err)
+	}
+	waitSession(t, c)
+	return conn, done
+}
+
+func waitSession(t *testing.T, c *Client) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		up := c.session != nil
+		c.mu.Unlock()
+		if up {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the v2 session never came up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClient_PushedCommandsAreQueuedAndAcked(t *testing.T) {
+	wsURL, conns := fakeServer(t)
+	var queued atomic.Int32
+	full := atomic.Bool{}
+	c := New(&config.Config{}, identity.New(&config.Config{}), func() {}, func(cmds []sender.PendingCommand) bool {
+		if full.Load() {
+			return false
+		}
+		queued.Add(int32(len(cmds)))
+		return true
+	})
+	conn, _ := startSession(t, c, wsURL, conns)
+
+	_ = conn.WriteJSON(inbound{Type: "commands", Commands: []sender.PendingCommand{{ID: "c1"}, {ID: "c2"}}})
+	if ack := readOutbound(t, conn, "ack"); len(ack.IDs) != 2 || queued.Load() != 2 {
+		t.Fatalf("ack %v, queued %d", ack.IDs, queued.Load())
+	}
+
+	full.Store(true)
+	_ = conn.WriteJSON(inbound{Type: "commands", Commands: []sender.PendingCommand{{ID: "c3"}}})
+	if reject := readOutbound(t, conn, "reject"); len(reject.IDs) != 1 || reject.IDs[0] != "c3" {
+		t.Fatalf("reject %v", reject.IDs)
+	}
+}
+
+func TestClient_ResultResentAfterReconnect(t *testing.T) {
+	wsURL, conns := fakeServer(t)
+	c := New(&config.Config{}, identity.New(&config.Config{}), func() {}, func([]sender.PendingCommand) bool { return true })
+	conn, done := startSession(t, c, wsURL, conns)
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- c.SendResult(context.Background(), &sender.CommandResult{CommandID: "c1", Status: "completed"})
+	}()
+	first := readOutbound(t, conn, "result")
+	// The connection drops before the server acked.
+	_ = conn.Close()
+	<-done
+
+	conn, _ = startSession(t, c, wsURL, conns)
+	again := readOutbound(t, conn, "result")
+	if again.Seq != first.Seq || again.Result.CommandID != "c1" {
+		t.Fatalf("resent %+v, want seq %d for c1", again, first.Seq)
+	}
+	_ = conn.WriteJSON(inbound{Type: "result_ack", Seq: again.Seq})
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Fatalf("SendResult: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendResult never returned")
+	}
+
+	// A refusal is final: no HTTP fallback.
+	go func() {
+		sent <- c.SendResult(context.Background(), &sender.CommandResult{CommandID: "c2", Status: "completed"})
+	}()
+	m := readOutbound(t, conn, "result")
+	_ = conn.WriteJSON(inbound{Type: "result_ack", Seq: m.Seq, Error: "command does not belong to host"})
+	if err := <-sent; err == nil || errors.Is(err, sender.ErrChannelUnavailable) {
+		t.Fatalf("want a final refusal, got %v", err)
+	}
+}
+
+func TestClient_OldServerStaysOnPollNudges(t *testing.T) {
+	wsURL, conns := fakeServer(t)
+	polled := make(chan struct{}, 1)
+	c := New(&config.Config{}, identity.New(&config.Config{}), func() { polled <- struct{}{} }, func([]sender.PendingCommand) bool { return true })
+	go c.runOnce(context.Background(), wsURL)
+	conn := accept(t, conns)
+	readOutbound(t, conn, "hello") // a v1 server ignores it and never welcomes
+
+	_ = conn.WriteJSON(inbound{Type: "poll_now"})
+	select {
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll_now did not trigger a poll")
+	}
+	if err := c.SendChunk("c1", "x"); !errors.Is(err, sender.ErrChannelUnavailable) {
+		t.Errorf("SendChunk without a v2 session = %v, want ErrChannelUnavailable", err)
+	}
+	if err := c.SendResult(context.Background(), &sender.CommandResult{CommandID: "c1"}); !errors.Is(err, sender.ErrChannelUnavailable) {
+		t.Errorf("SendResult without a v2 session = %v, want ErrChannelUnavailable", err)
+	}
+}
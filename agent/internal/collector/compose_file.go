@@ -0,0 +1,382 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/serversupervisor/agent/internal/security"
+)
+
+// composeBackupSuffix is appended to the compose/.env file names to hold the
+// single pre-apply snapshot used by the one-click rollback.
+const composeBackupSuffix = ".ss-backup"
+
+// composeAbsentSuffix marks a snapshot taken while the project had no .env.
+const composeAbsentSuffix = ".ss-backup-absent"
+
+// redactedMarker is the placeholder security.FilterYAML writes in place of a
+// sensitive value. An edited file that still carries it means "keep the value
+// currently on disk".
+const redactedMarker = " [REDACTED]"
+
+// ComposeFileContent is the masked, operator-facing view of a project's compose
+// file and .env, returned by module=compose action=read_file.
+type ComposeFileContent struct {
+	Project     string `json:"project"`
+	ConfigFile  string `json:"config_file"`
+	EnvFile     string `json:"env_file"`
+	Compose     string `json:"compose"`
+	Env         string `json:"env"`
+	HasEnv      bool   `json:"has_env"`
+	HasSnapshot bool   `json:"has_snapshot"`
+}
+
+// ComposeFilePaths resolves the primary compose file and the .env file of a
+// discovered project. Both paths come from Docker's own labels (trusted), never
+// from the server. When several config files are listed, only the first one is
+// editable — overrides are left to the operator's own tooling.
+func ComposeFilePaths(proj *ComposeProject) (configFile, envFile string, err error) {
+	files, err := ComposeConfigFiles(proj)
+	if err != nil {
+		return "", "", err
+	}
+	return files[0], filepath.Join(proj.WorkingDir, ".env"), nil
+}
+
+// ComposeConfigFiles resolves every config file of a discovered project, in
+// the order Docker's label lists them (base file first, then overrides). Each
+// one is passed as -f when validating or redeploying, so a project with a
+// non-default file name, a file outside its working directory or overrides is
+// deployed from the same file set it was started with.
+func ComposeConfigFiles(proj *ComposeProject) ([]string, error) {
+	if proj.WorkingDir == "" {
+		return nil, errors.New("compose project has no working directory label")
+	}
+	var files []string
+	for _, f := range strings.Split(proj.ConfigFile, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(proj.WorkingDir, f)
+		}
+		files = append(files, filepath.Clean(f))
+	}
+	if len(files) == 0 {
+		return nil, errors.New("compose project has no config file label")
+	}
+	return files, nil
+}
+
+// composeFileFlags renders the -f flags of a project's config files.
+func composeFileFlags(configFiles []string) []string {
+	flags := make([]string, 0, 2*len(configFiles))
+	for _, f := range configFiles {
+		flags = append(flags, "-f", f)
+	}
+	return flags
+}
+
+// ComposeUpFiles is ComposeUp for the whole project, deployed from exactly
+// configFiles (see ComposeConfigFiles).
+func ComposeUpFiles(ctx context.Context, projectName, workingDir string, configFiles []string, chunkCB func(string)) (string, error) {
+	rest := append(composeFileFlags(configFiles), "up", "-d")
+	return streamExec(ctx, chunkCB, "docker", composeArgs(projectName, workingDir, rest...)...)
+}
+
+// ReadComposeFiles returns the project's compose file and .env with every
+// sensitive value masked via security.FilterYAML.
+func ReadComposeFiles(proj *ComposeProject) (*ComposeFileContent, error) {
+	configFile, envFile, err := ComposeFilePaths(proj)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("read compose file: %w", err)
+	}
+	out := &ComposeFileContent{
+		Project:    proj.Name,
+		ConfigFile: configFile,
+		EnvFile:    envFile,
+		Compose:    security.FilterYAML(string(raw)),
+	}
+	if env, eerr := os.ReadFile(envFile); eerr == nil {
+		out.Env = security.FilterYAML(string(env))
+		out.HasEnv = true
+	} else if !errors.Is(eerr, os.ErrNotExist) {
+		return nil, fmt.Errorf("read env file: %w", eerr)
+	}
+	if _, serr := os.Stat(configFile + composeBackupSuffix); serr == nil {
+		out.HasSnapshot = true
+	}
+	return out, nil
+}
+
+// RestoreRedacted puts back the on-disk value of every line the operator left
+// as "[REDACTED]". Lines are matched on their exact prefix up to and including
+// the separator (indentation and key), consumed in order, so a key repeated in
+// several services keeps its own value. A redacted line with no counterpart in
+// the original is returned unchanged and will be rejected by validation.
+func RestoreRedacted(original, edited string) string {
+	secrets := make(map[string][]string)
+	for _, line := range strings.Split(original, "\n") {
+		masked := security.FilterYAML(line)
+		if masked == line || !strings.HasSuffix(masked, redactedMarker) {
+			continue
+		}
+		prefix := strings.TrimSuffix(masked, redactedMarker)
+		secrets[prefix] = append(secrets[prefix], line)
+	}
+
+	lines := strings.Split(edited, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if !strings.HasSuffix(trimmed, redactedMarker) {
+			continue
+		}
+		prefix := strings.TrimSuffix(trimmed, redactedMarker)
+		if queue := secrets[prefix]; len(queue) > 0 {
+			lines[i] = queue[0]
+			secrets[prefix] = queue[1:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateComposeFiles writes the candidate compose/.env content to temporary
+// files next to the real ones (so relative build contexts and volume paths
+// resolve identically) and runs `docker compose config -q` against the
+// project's whole file set, the temporary file standing in for configFile.
+// An empty env with no existing .env skips the --env-file flag.
+func ValidateComposeFiles(ctx context.Context, projectName, workingDir string, configFiles []string, configFile, compose string, env *string) (string, error) {
+	if strings.Contains(compose, redactedMarker) || (env != nil && strings.Contains(*env, redactedMarker)) {
+		return "", errors.New("content still contains [REDACTED] placeholders that do not match the current file")
+	}
+
+	tmpCompose, err := writeTemp(filepath.Dir(configFile), ".ss-validate-*.yml", compose)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmpCompose) }()
+
+	files := make([]string, len(configFiles))
+	for i, f := range configFiles {
+		files[i] = f
+		if f == configFile {
+			files[i] = tmpCompose
+		}
+	}
+	rest := composeFileFlags(files)
+	if env != nil {
+		tmpEnv, terr := writeTemp(workingDir, ".ss-validate-*.env", *env)
+		if terr != nil {
+			return "", terr
+		}
+		defer func() { _ = os.Remove(tmpEnv) }()
+		rest = append(rest, "--env-file", tmpEnv)
+	}
+	rest = append(rest, "config", "-q")
+
+	// The global flags must precede the sub-command, so the -f/--env-file pair
+	// is threaded through composeArgs' "rest" ahead of "config".
+	return streamExec(ctx, nil, "docker", composeArgs(projectName, workingDir, rest...)...)
+}
+
+// SnapshotComposeFiles copies the current compose file (and .env when present)
+// to their ".ss-backup" siblings, overwriting the previous snapshot. Only one
+// generation is kept: rollback is meant to undo the last apply, not to browse
+// history. With no .env today, an empty ".ss-backup-absent" marker records it,
+// so the restore removes a .env the apply created.
+func SnapshotComposeFiles(configFile, envFile string) error {
+	if err := copyFile(configFile, configFile+composeBackupSuffix); err != nil {
+		return fmt.Errorf("snapshot compose file: %w", err)
+	}
+	if _, err := os.Stat(envFile); err == nil {
+		if err := copyFile(envFile, envFile+composeBackupSuffix); err != nil {
+			return fmt.Errorf("snapshot env file: %w", err)
+		}
+		_ = os.Remove(envFile + composeAbsentSuffix)
+	} else {
+		// No .env today: drop any stale .env snapshot so a rollback does not
+		// resurrect a file that did not exist at snapshot time.
+		_ = os.Remove(envFile + composeBackupSuffix)
+		if err := os.WriteFile(envFile+composeAbsentSuffix, nil, 0o600); err != nil {
+			return fmt.Errorf("snapshot env file: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreComposeSnapshot puts the ".ss-backup" files back in place, and removes
+// the .env when there was none at snapshot time. Returns an error when no
+// snapshot exists.
+func RestoreComposeSnapshot(configFile, envFile string) error {
+	if _, err := os.Stat(configFile + composeBackupSuffix); err != nil {
+		return errors.New("no compose snapshot to restore")
+	}
+	if err := copyFile(configFile+composeBackupSuffix, configFile); err != nil {
+		return fmt.Errorf("restore compose file: %w", err)
+	}
+	if _, err := os.Stat(envFile + composeBackupSuffix); err == nil {
+		if err := copyFile(envFile+composeBackupSuffix, envFile); err != nil {
+			return fmt.Errorf("restore env file: %w", err)
+		}
+	} else if _, err := os.Stat(envFile + composeAbsentSuffix); err == nil {
+		if err := os.Remove(envFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove env file: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteComposeFile atomically replaces path with content, keeping the current
+// file mode (0644 for a new file).
+func WriteComposeFile(path, content string) error {
+	mode := os.FileMode(0o644)
+	if st, err := os.Stat(path); err == nil {
+		mode = st.Mode().Perm()
+	}
+	tmp, err := writeTemp(filepath.Dir(path), ".ss-write-*", content)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// UnifiedDiff renders a line-based unified diff (3 lines of context) between
+// a and b. Returns "" when both are identical. Compose files are small, so the
+// O(n*m) LCS table is fine here.
+func UnifiedDiff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	n, m := len(al), len(bl)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-', '+'
+		text string
+		ai   int // 0-based index in a (for ' ' and '-')
+		bi   int // 0-based index in b (for ' ' and '+')
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && al[i] == bl[j]:
+			ops = append(ops, op{' ', al[i], i, j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] >= lcs[i+1][j]):
+			ops = append(ops, op{'+', bl[j], i, j})
+			j++
+		default:
+			ops = append(ops, op{'-', al[i], i, j})
+			i++
+		}
+	}
+
+	const ctxLines = 3
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (proposed)\n", name, name)
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+		start := max(k-ctxLines, 0)
+		end := k
+		// Extend the hunk while changes are within 2*ctx lines of each other.
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*ctxLines {
+				end = min(end+ctxLines, len(ops))
+				break
+			}
+			end = run
+		}
+		var aCount, bCount int
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				aCount++
+			}
+			if o.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", ops[start].ai+1, aCount, ops[start].bi+1, bCount)
+		for _, o := range ops[start:end] {
+			out.WriteByte(o.kind)
+			out.WriteString(o.text)
+			out.WriteByte('\n')
+		}
+		k = end
+	}
+	return out.String()
+}
+
+// writeTemp creates a temp file in dir with the given content and returns its
+// path. The caller owns removal.
+func writeTemp(dir, pattern, content string) (string, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// copyFile copies src to dst, preserving src's permission bits.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, st.Mode().Perm())
+}
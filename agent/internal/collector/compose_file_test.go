@@ -0,0 +1,212 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serversupervisor/agent/internal/security"
+)
+
+func TestComposeFilePaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		proj       ComposeProject
+		wantConfig string
+		wantErr    bool
+	}{
+		{
+			name:       "absolute config file",
+			proj:       ComposeProject{WorkingDir: "/srv/app", ConfigFile: "/srv/app/docker-compose.yml"},
+			wantConfig: "/srv/app/docker-compose.yml",
+		},
+		{
+			name:       "relative config file joins working dir",
+			proj:       ComposeProject{WorkingDir: "/srv/app", ConfigFile: "compose.yaml"},
+			wantConfig: "/srv/app/compose.yaml",
+		},
+		{
+			name:       "only the first of several files",
+			proj:       ComposeProject{WorkingDir: "/srv/app", ConfigFile: "/srv/app/a.yml,/srv/app/b.yml"},
+			wantConfig: "/srv/app/a.yml",
+		},
+		{name: "missing working dir", proj: ComposeProject{ConfigFile: "/srv/app/a.yml"}, wantErr: true},
+		{name: "missing config file", proj: ComposeProject{WorkingDir: "/srv/app"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, env, err := ComposeFilePaths(&tt.proj)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg != tt.wantConfig {
+				t.Errorf("config = %q, want %q", cfg, tt.wantConfig)
+			}
+			if env != filepath.Join(tt.proj.WorkingDir, ".env") {
+				t.Errorf("env = %q", env)
+			}
+		})
+	}
+}
+
+func TestComposeConfigFiles(t *testing.T) {
+	proj := ComposeProject{WorkingDir: "/srv/app", ConfigFile: "/opt/stacks/app.yml, override.yml,"}
+	files, err := ComposeConfigFiles(&proj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/opt/stacks/app.yml", "/srv/app/override.yml"}; strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if got := composeFileFlags(files); strings.Join(got, " ") != "-f /opt/stacks/app.yml -f /srv/app/override.yml" {
+		t.Errorf("flags = %v", got)
+	}
+	if _, err := ComposeConfigFiles(&ComposeProject{WorkingDir: "/srv/app", ConfigFile: " , "}); err == nil {
+		t.Error("a label listing no file must fail")
+	}
+}
+
+func TestRestoreRedacted(t *testing.T) {
+	original := strings.Join([]string{
+		"services:",
+		"  db:",
+		"    environment:",
+		"      POSTGRES_PASSWORD: hunter2",
+		"  cache:",
+		"    environment:",
+		"      POSTGRES_PASSWORD: other",
+		"      MODE: prod",
+	}, "\n")
+	masked := security.FilterYAML(original)
+
+	// Untouched edit: every secret is restored in order.
+	if got := RestoreRedacted(original, masked); got != original {
+		t.Errorf("untouched round-trip mismatch:\n%s", got)
+	}
+
+	// Operator changes a non-secret line and overrides the second secret.
+	edited := strings.Replace(masked, "MODE: prod", "MODE: staging", 1)
+	edited = strings.Replace(edited, "      POSTGRES_PASSWORD: [REDACTED]\n      MODE", "      POSTGRES_PASSWORD: newpass\n      MODE", 1)
+	got := RestoreRedacted(original, edited)
+	if !strings.Contains(got, "POSTGRES_PASSWORD: hunter2") {
+		t.Errorf("first secret not restored:\n%s", got)
+	}
+	if !strings.Contains(got, "POSTGRES_PASSWORD: newpass") || strings.Contains(got, "other") {
+		t.Errorf("override not kept:\n%s", got)
+	}
+	if strings.Contains(got, "[REDACTED]") {
+		t.Errorf("placeholder left behind:\n%s", got)
+	}
+
+	// Env-style file.
+	if got := RestoreRedacted("API_TOKEN=abc\nPORT=80", "API_TOKEN= [REDACTED]\nPORT=8080"); got != "API_TOKEN=abc\nPORT=8080" {
+		t.Errorf("env restore = %q", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("f", "a\nb", "a\nb"); got != "" {
+		t.Errorf("identical input should yield empty diff, got %q", got)
+	}
+	a := "l1\nl2\nl3\nl4\nl5\nl6\nl7\nl8"
+	b := "l1\nl2\nl3\nl4\nCHANGED\nl6\nl7\nl8"
+	got := UnifiedDiff("f", a, b)
+	for _, want := range []string{"--- f\n", "@@ -2,7 +2,7 @@\n", "-l5\n", "+CHANGED\n", " l4\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, " l1\n") {
+		t.Errorf("context should stop at 3 lines:\n%s", got)
+	}
+}
+
+func TestSnapshotAndRestoreComposeFiles(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "docker-compose.yml")
+	env := filepath.Join(dir, ".env")
+	mustWrite := func(p, s string) {
+		t.Helper()
+		if err := os.WriteFile(p, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRead := func(p string) string {
+		t.Helper()
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if err := RestoreComposeSnapshot(cfg, env); err == nil {
+		t.Fatal("restore without snapshot should fail")
+	}
+
+	mustWrite(cfg, "v1")
+	mustWrite(env, "A=1")
+	if err := SnapshotComposeFiles(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteComposeFile(cfg, "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteComposeFile(env, "A=2"); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := os.Stat(cfg); st.Mode().Perm() != 0o600 {
+		t.Errorf("WriteComposeFile changed mode to %v", st.Mode().Perm())
+	}
+
+	if err := RestoreComposeSnapshot(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRead(cfg); got != "v1" {
+		t.Errorf("compose after restore = %q", got)
+	}
+	if got := mustRead(env); got != "A=1" {
+		t.Errorf("env after restore = %q", got)
+	}
+}
+
+// TestRestoreComposeSnapshot_RemovesCreatedEnv: a .env the apply created did
+// not exist at snapshot time and must not survive the rollback.
+func TestRestoreComposeSnapshot_RemovesCreatedEnv(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "docker-compose.yml")
+	env := filepath.Join(dir, ".env")
+	if err := os.WriteFile(cfg, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SnapshotComposeFiles(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteComposeFile(env, "A=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreComposeSnapshot(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(env); !os.IsNotExist(err) {
+		t.Errorf(".env created by the apply survived the rollback (err = %v)", err)
+	}
+
+	// A later snapshot with a .env clears the marker.
+	if err := WriteComposeFile(env, "A=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SnapshotComposeFiles(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreComposeSnapshot(cfg, env); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(env); err != nil || string(b) != "A=2" {
+		t.Errorf("env after restore = %q, %v", b, err)
+	}
+}
@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCrowdSecReportDecisions bounds one report's decision list. Local
+	// decisions are a few hundred at most on a busy host; anything beyond
+	// this is a runaway scenario the server does not need the tail of.
+	maxCrowdSecReportDecisions = 1000
+	// cscliTimeout bounds `cscli bouncers list`, which opens the CrowdSec
+	// database directly.
+	cscliTimeout = 10 * time.Second
+	// crowdSecInterval spaces collections: a bouncer pulls every ~10s and a
+	// stale-bouncer alert is meant in minutes, while the alerts enrichment
+	// behind CollectCrowdSecDecisions is not free on a busy LAPI.
+	crowdSecInterval = time.Minute
+)
+
+var (
+	crowdSecMu   sync.Mutex
+	crowdSecLast time.Time
+)
+
+// CrowdSecReport is this host's CrowdSec state for the server-side fleet view:
+// the decisions its local API enforces and the bouncers pulling them.
+// Mirrors server/internal/models.CrowdSecReport.
+type CrowdSecReport struct {
+	// Decisions are the local ones (crowdsec, cscli, console...). Community
+	// blocklist decisions (CAPI, lists) are the same on every host and run to
+	// tens of thousands: only their count is reported.
+	Decisions          []CrowdSecReportDecision `json:"decisions"`
+	CommunityDecisions int                      `json:"community_decisions"`
+	Truncated          bool                     `json:"truncated,omitempty"`
+	Bouncers           []CrowdSecBouncer        `json:"bouncers"`
+	// BouncersError is set when cscli could not list the bouncers; Bouncers
+	// is then empty and the server keeps the previous list.
+	BouncersError string `json:"bouncers_error,omitempty"`
+}
+
+// CrowdSecReportDecision is one active decision of the local API.
+type CrowdSecReportDecision struct {
+	Value    string `json:"value"` // IP or range
+	Type     string `json:"type"`  // ban, captcha...
+	Scenario string `json:"scenario"`
+	Origin   string `json:"origin"`
+	Country  string `json:"country,omitempty"`
+	ASName   string `json:"as_name,omitempty"`
+	// StartedAt is zero when no alert carried it; Until when the API gave
+	// neither an expiry nor a duration.
+	StartedAt time.Time `json:"started_at"`
+	Until     time.Time `json:"until"`
+}
+
+// CrowdSecBouncer is one bouncer registered on the local API.
+type CrowdSecBouncer struct {
+	Name      string `json:"name"`
+	IPAddress string `json:"ip_address,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Version   string `json:"version,omitempty"`
+	// LastPull is zero when the bouncer never pulled.
+	LastPull  time.Time `json:"last_pull"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// CollectCrowdSec reads the local API's decisions (see
+// CollectCrowdSecDecisions for the auth fallbacks) and lists the bouncers with
+// cscliCommand, at most once per crowdSecInterval: in between it returns
+// (nil, nil) and the server keeps the previous snapshot. A failing cscli
+// only empties the bouncer list.
+func CollectCrowdSec(ctx context.Context, connectionString, apiKey, alertsMachineID, alertsPassword, cscliCommand string) (*CrowdSecReport, error) {
+	crowdSecMu.Lock()
+	if !crowdSecLast.IsZero() && time.Since(crowdSecLast) < crowdSecInterval {
+		crowdSecMu.Unlock()
+		return nil, nil
+	}
+	crowdSecLast = time.Now()
+	crowdSecMu.Unlock()
+
+	decisions, err := CollectCrowdSecDecisions(connectionString, apiKey, alertsMachineID, alertsPassword)
+	if err != nil {
+		return nil, err
+	}
+	report := crowdSecReportFromDecisions(decisions)
+
+	bouncers, err := listCrowdSecBouncers(ctx, cscliCommand)
+	if err != nil {
+		report.BouncersError = err.Error()
+	}
+	report.Bouncers = bouncers
+	return report, nil
+}
+
+// crowdSecReportFromDecisions splits local decisions from community ones and
+// sorts the former by value so the server sees a stable list.
+func crowdSecReportFromDecisions(decisions map[string]CrowdSecDecision) *CrowdSecReport {
+	report := &CrowdSecReport{Decisions: []CrowdSecReportDecision{}, Bouncers: []CrowdSecBouncer{}}
+	for _, d := range decisions {
+		if isCrowdSecCommunityOrigin(d.Origin) {
+			report.CommunityDecisions++
+			continue
+		}
+		report.Decisions = append(report.Decisions, CrowdSecReportDecision{
+			Value:     d.IP,
+			Type:      d.Type,
+			Scenario:  d.Reason,
+			Origin:    d.Origin,
+			Country:   d.Country,
+			ASName:    d.ASName,
+			StartedAt: d.BlockedAt,
+			Until:     d.BlockedUntil,
+		})
+	}
+	sort.Slice(report.Decisions, func(i, j int) bool { return report.Decisions[i].Value < report.Decisions[j].Value })
+	if len(report.Decisions) > maxCrowdSecReportDecisions {
+		report.Decisions = report.Decisions[:maxCrowdSecReportDecisions]
+		report.Truncated = true
+	}
+	return report
+}
+
+func isCrowdSecCommunityOrigin(origin string) bool {
+	return strings.EqualFold(origin, "CAPI") || strings.EqualFold(origin, "lists")
+}
+
+// cscliBouncer is one entry of `cscli bouncers list -o json`. last_pull is
+// null for a bouncer that never pulled (CrowdSec 1.6+) or the zero time
+// (older releases).
+type cscliBouncer struct {
+	Name      string     `json:"name"`
+	IPAddress string     `json:"ip_address"`
+	Type      string     `json:"type"`
+	Version   string     `json:"version"`
+	LastPull  *time.Time `json:"last_pull"`
+	CreatedAt time.Time  `json:"created_at"`
+	Revoked   bool       `json:"revoked"`
+}
+
+func listCrowdSecBouncers(ctx context.Context, cscliCommand string) ([]CrowdSecBouncer, error) {
+	args := strings.Fields(cscliCommand)
+	if len(args) == 0 {
+		args = []string{"cscli"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, cscliTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], append(args[1:], "bouncers", "list", "-o", "json")...)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return []CrowdSecBouncer{}, fmt.Errorf("cscli bouncers list: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return []CrowdSecBouncer{}, fmt.Errorf("cscli bouncers list: %w", err)
+	}
+	return parseCscliBouncers(out)
+}
+
+// parseCscliBouncers reads `cscli bouncers list -o json`, sorted by name.
+func parseCscliBouncers(data []byte) ([]CrowdSecBouncer, error) {
+	var raw []cscliBouncer
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []CrowdSecBouncer{}, fmt.Errorf("failed to decode cscli bouncers: %w", err)
+	}
+	out := make([]CrowdSecBouncer, 0, len(raw))
+	for _, b := range raw {
+		if strings.TrimSpace(b.Name) == "" {
+			continue
+		}
+		bouncer := CrowdSecBouncer{
+			Name:      b.Name,
+			IPAddress: b.IPAddress,
+			Type:      b.Type,
+			Version:   b.Version,
+			CreatedAt: b.CreatedAt,
+			Revoked:   b.Revoked,
+		}
+		if b.LastPull != nil && b.LastPull.Year() > 1 {
+			bouncer.LastPull = *b.LastPull
+		}
+		out = append(out, bouncer)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseCscliBouncers(t *testing.T) {
+	data := []byte(`[
+		{"name":"firewall","ip_address":"127.0.0.1","type":"crowdsec-firewall-bouncer","version":"v0.0.28","last_pull":"2024-01-02T03:04:05Z","created_at":"2023-06-01T00:00:00Z","revoked":false},
+		{"name":"","ip_address":"10.0.0.9"},
+		{"name":"nginx","ip_address":"","type":"","version":"","last_pull":null,"created_at":"2024-01-01T00:00:00Z","revoked":false},
+		{"name":"legacy","last_pull":"0001-01-01T00:00:00Z","revoked":true}
+	]`)
+	got, err := parseCscliBouncers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "firewall" || got[1].Name != "legacy" || got[2].Name != "nginx" {
+		t.Fatalf("got %+v, want firewall, legacy, nginx", got)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !got[0].LastPull.Equal(want) || got[0].Type != "crowdsec-firewall-bouncer" {
+		t.Errorf("firewall = %+v", got[0])
+	}
+	if !got[1].LastPull.IsZero() || !got[1].Revoked {
+		t.Errorf("a zero last_pull means never pulled: %+v", got[1])
+	}
+	if !got[2].LastPull.IsZero() || got[2].CreatedAt.IsZero() {
+		t.Errorf("a null last_pull means never pulled: %+v", got[2])
+	}
+
+	if got, err := parseCscliBouncers([]byte("null")); err != nil || len(got) != 0 {
+		t.Errorf("no bouncer = %+v, %v", got, err)
+	}
+	if _, err := parseCscliBouncers([]byte("No bouncers found")); err == nil {
+		t.Error("non-JSON output must be an error")
+	}
+}
+
+func TestCrowdSecReportFromDecisions(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	decisions := map[string]CrowdSecDecision{
+		"203.0.113.9":  {IP: "203.0.113.9", Type: "ban", Reason: "crowdsecurity/ssh-bf", Origin: "crowdsec", Country: "FR", BlockedUntil: until},
+		"198.51.100.1": {IP: "198.51.100.1", Type: "ban", Reason: "manual", Origin: "cscli", BlockedUntil: until},
+		"192.0.2.1":    {IP: "192.0.2.1", Type: "ban", Origin: "CAPI"},
+		"192.0.2.2":    {IP: "192.0.2.2", Type: "ban", Origin: "lists"},
+	}
+	report := crowdSecReportFromDecisions(decisions)
+	if report.CommunityDecisions != 2 || report.Truncated || len(report.Decisions) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if d := report.Decisions[0]; d.Value != "198.51.100.1" || d.Scenario != "manual" || !d.Until.Equal(until) {
+		t.Errorf("decisions must be sorted by value: %+v", report.Decisions)
+	}
+	if d := report.Decisions[1]; d.Country != "FR" || d.Origin != "crowdsec" {
+		t.Errorf("decision = %+v", d)
+	}
+
+	many := make(map[string]CrowdSecDecision, maxCrowdSecReportDecisions+5)
+	for i := range maxCrowdSecReportDecisions + 5 {
+		ip := fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff)
+		many[ip] = CrowdSecDecision{IP: ip, Type: "ban", Origin: "crowdsec"}
+	}
+	if report := crowdSecReportFromDecisions(many); len(report.Decisions) != maxCrowdSecReportDecisions || !report.Truncated {
+		t.Errorf("a long list must be capped: %d decisions, truncated=%v", len(report.Decisions), report.Truncated)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/serversupervisor/agent/internal/config"
 )
@@ -52,13 +53,11 @@ func CheckConfig(cfg *config.Config) []DiagnosticIssue {
 	}
 
 	if cfg.CollectDocker {
-		if os.Getenv("DOCKER_HOST") == "" {
-			if _, err := os.Stat("/var/run/docker.sock"); err != nil {
-				issues = append(issues, DiagnosticIssue{
-					Collector: "docker", Severity: DiagnosticWarning,
-					Message: "socket Docker introuvable (/var/run/docker.sock) et DOCKER_HOST non défini — collect_docker est activé mais le client échouera à se connecter",
-				})
-			}
+		if os.Getenv("DOCKER_HOST") == "" && detectDockerSocket() == "" {
+			issues = append(issues, DiagnosticIssue{
+				Collector: "docker", Severity: DiagnosticWarning,
+				Message: "aucun socket Docker ou Podman trouvé (/var/run/docker.sock, /run/podman/podman.sock, /run/user/*/podman/podman.sock) et DOCKER_HOST non défini — collect_docker est activé mais le client échouera à se connecter",
+			})
 		}
 	}
 
@@ -88,6 +87,23 @@ func CheckConfig(cfg *config.Config) []DiagnosticIssue {
 		})
 	}
 
+	if cfg.CollectCrowdSec {
+		if cfg.CrowdSecAPIKey == "" && (cfg.CrowdSecAlertsMachineID == "" || cfg.CrowdSecAlertsPassword == "") {
+			issues = append(issues, DiagnosticIssue{
+				Collector: "crowdsec", Severity: DiagnosticWarning,
+				Message: "collect_crowdsec est activé mais ni crowdsec_api_key ni crowdsec_alerts_machine_id/crowdsec_alerts_password ne sont renseignés — les décisions CrowdSec ne peuvent pas être lues",
+			})
+		}
+		if args := strings.Fields(cfg.CrowdSecCscliCommand); len(args) > 0 {
+			if _, err := exec.LookPath(args[0]); err != nil {
+				issues = append(issues, DiagnosticIssue{
+					Collector: "crowdsec", Severity: DiagnosticWarning,
+					Message: fmt.Sprintf("collect_crowdsec est activé mais la commande %q est introuvable — les bouncers CrowdSec ne seront pas remontés (voir crowdsec_cscli_command)", args[0]),
+				})
+			}
+		}
+	}
+
 	if cfg.CollectRestic {
 		issues = append(issues, checkResticConfig(cfg)...)
 	}
@@ -122,7 +138,7 @@ func checkWebLogsConfig(cfg *config.Config) []DiagnosticIssue {
 	if len(expandGlobs(cfg.WebLogGlobs())) == 0 {
 		issues = append(issues, DiagnosticIssue{
 			Collector: "web_logs", Severity: DiagnosticWarning,
-			Message: "aucun fichier de log ne correspond aux motifs configurés (web_logs_log_paths) — collect_web_logs est activé mais ne trouvera rien à analyser",
+			Message: "aucun fichier de log ne correspond aux motifs configurés (web_logs_log_paths, web_logs_formats) — collect_web_logs est activé mais ne trouvera rien à analyser",
 		})
 	}
 	if cfg.WebLogsCursorFile != "" {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
@@ -80,6 +81,13 @@ var (
 // Unix socket. go-dockerclient does not open a persistent connection in the
 // constructor, so the singleton is safe to share across goroutines.
 //
+// Without DOCKER_HOST, the engine socket is auto-detected (Docker first, then
+// rootful and rootless Podman — see docker_engine.go) on every attempt, so an
+// engine started after the agent is still found. Once a non-default socket
+// answers a ping, it is exported as DOCKER_HOST for the whole process so the
+// `docker` / `docker compose` CLIs run by the dispatcher reach the same engine
+// as the collector; a dead socket never gets pinned there.
+//
 // Unlike sync.Once, this implementation retries on failure so that a transient
 // error at agent startup (daemon not yet ready) does not permanently disable
 // Docker monitoring for the lifetime of the process.
@@ -91,11 +99,37 @@ func newDockerClient() (*docker.Client, error) {
 		return dockerSingle, nil
 	}
 
-	c, err := docker.NewClientFromEnv()
+	var detected string
+	if os.Getenv("DOCKER_HOST") == "" {
+		if sock := detectDockerSocket(); sock != "" && sock != dockerSocketPaths[0] {
+			detected = "unix://" + sock
+		}
+	}
+
+	var c *docker.Client
+	var err error
+	if detected != "" {
+		c, err = docker.NewClient(detected)
+	} else {
+		c, err = docker.NewClientFromEnv()
+	}
 	if err != nil {
 		slog.Warn("docker client init failed (will retry on next collection)", "err", err)
 		return nil, err
 	}
+	if err := c.Ping(); err != nil {
+		slog.Warn("docker engine unreachable (will retry on next collection)", "endpoint", c.Endpoint(), "err", err)
+		return nil, err
+	}
+	if detected != "" {
+		_ = os.Setenv("DOCKER_HOST", detected)
+	}
+	engine := detectDockerEngine(c)
+	dockerEngineMu.Lock()
+	dockerEngine = engine
+	dockerEngineMu.Unlock()
+	slog.Info("container engine connected", "engine", engine, "endpoint", c.Endpoint())
+
 	dockerSingle = c
 	dockerInitOK = true
 	return dockerSingle, nil
@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// dockerDiskUsageInterval spaces out /system/df calls. The daemon walks every
+// volume and writable layer to answer it (the same cost as `docker system df
+// -v`), which is far too heavy for every 30s report — sizes move slowly, so a
+// quarter-hour resolution is plenty for history and growth alerts.
+const dockerDiskUsageInterval = 15 * time.Minute
+
+// dockerDiskUsageTopN bounds each per-item list in the payload. The summary
+// totals always cover every item; only the detail rows are cut.
+const dockerDiskUsageTopN = 50
+
+// DockerImageUsage is one image's disk footprint.
+type DockerImageUsage struct {
+	ImageID    string `json:"image_id"`
+	Image      string `json:"image"` // first repo tag, "<none>" for dangling images
+	SizeBytes  int64  `json:"size_bytes"`
+	SharedSize int64  `json:"shared_size"` // bytes shared with other images (-1 when unknown)
+	Containers int64  `json:"containers"`  // containers using it (-1 when unknown)
+}
+
+// DockerContainerUsage is one container's writable layer (SizeRw) plus the
+// total size of its root filesystem including the image (SizeRootFs).
+type DockerContainerUsage struct {
+	ContainerID    string `json:"container_id"`
+	Name           string `json:"name"`
+	Image          string `json:"image"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	SizeRwBytes    int64  `json:"size_rw_bytes"`
+	SizeRootFs     int64  `json:"size_root_fs_bytes"`
+}
+
+// DockerVolumeUsage is one volume's size. Size is -1 for volumes whose driver
+// does not report usage (non-"local" drivers).
+type DockerVolumeUsage struct {
+	Name           string `json:"name"`
+	Driver         string `json:"driver"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	SizeBytes      int64  `json:"size_bytes"`
+	RefCount       int64  `json:"ref_count"`
+}
+
+// DockerDiskUsageReport is the `docker system df -v` equivalent: per-kind
+// totals plus the top consumers of each kind.
+type DockerDiskUsageReport struct {
+	CollectedAt           time.Time              `json:"collected_at"`
+	LayersSize            int64                  `json:"layers_size"`
+	ImagesSize            int64                  `json:"images_size"`
+	ContainersSize        int64                  `json:"containers_size"` // sum of writable layers
+	VolumesSize           int64                  `json:"volumes_size"`
+	BuildCacheSize        int64                  `json:"build_cache_size"`
+	BuildCacheReclaimable int64                  `json:"build_cache_reclaimable"` // not in use by any build
+	Images                []DockerImageUsage     `json:"images"`
+	Containers            []DockerContainerUsage `json:"containers"`
+	Volumes               []DockerVolumeUsage    `json:"volumes"`
+}
+
+// systemDFResponse is the subset of GET /system/df we read. go-dockerclient's
+// DiskUsage() drops the volume UsageData and the build cache, so the endpoint
+// is decoded here directly.
+type systemDFResponse struct {
+	LayersSize int64 `json:"LayersSize"`
+	Images     []struct {
+		ID         string   `json:"Id"`
+		RepoTags   []string `json:"RepoTags"`
+		Size       int64    `json:"Size"`
+		SharedSize int64    `json:"SharedSize"`
+		Containers int64    `json:"Containers"`
+	} `json:"Images"`
+	Containers []struct {
+		ID         string            `json:"Id"`
+		Names      []string          `json:"Names"`
+		Image      string            `json:"Image"`
+		SizeRw     int64             `json:"SizeRw"`
+		SizeRootFs int64             `json:"SizeRootFs"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Containers"`
+	Volumes []struct {
+		Name      string            `json:"Name"`
+		Driver    string            `json:"Driver"`
+		Labels    map[string]string `json:"Labels"`
+		UsageData *struct {
+			Size     int64 `json:"Size"`
+			RefCount int64 `json:"RefCount"`
+		} `json:"UsageData"`
+	} `json:"Volumes"`
+	BuildCache []struct {
+		Size  int64 `json:"Size"`
+		InUse bool  `json:"InUse"`
+	} `json:"BuildCache"`
+}
+
+var (
+	dockerDiskUsageMu   sync.Mutex
+	dockerDiskUsageLast time.Time
+)
+
+// CollectDockerDiskUsage returns a fresh disk usage report at most once per
+// dockerDiskUsageInterval. It returns (nil, nil) when the previous collection
+// is still recent, so the report only carries the section — and the server
+// only stores a history point — when it was actually re-measured.
+func CollectDockerDiskUsage(ctx context.Context) (*DockerDiskUsageReport, error) {
+	dockerDiskUsageMu.Lock()
+	if !dockerDiskUsageLast.IsZero() && time.Since(dockerDiskUsageLast) < dockerDiskUsageInterval {
+		dockerDiskUsageMu.Unlock()
+		return nil, nil
+	}
+	// Claimed before the call so a slow /system/df is not re-entered by the
+	// next report cycle; a failure is retried on the following interval.
+	dockerDiskUsageLast = time.Now()
+	dockerDiskUsageMu.Unlock()
+
+	client, err := newDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+	var df systemDFResponse
+	if err := dockerAPIGet(ctx, client, "/system/df", &df); err != nil {
+		return nil, fmt.Errorf("docker system df: %w", err)
+	}
+	report := buildDockerDiskUsage(&df, dockerDiskUsageTopN)
+	report.CollectedAt = time.Now()
+	slog.Debug("docker disk usage collected",
+		"images", len(df.Images), "containers", len(df.Containers), "volumes", len(df.Volumes))
+	return report, nil
+}
+
+// buildDockerDiskUsage turns the raw /system/df answer into totals plus the
+// topN largest items of each kind.
+func buildDockerDiskUsage(df *systemDFResponse, topN int) *DockerDiskUsageReport {
+	r := &DockerDiskUsageReport{
+		LayersSize: df.LayersSize,
+		Images:     make([]DockerImageUsage, 0, len(df.Images)),
+		Containers: make([]DockerContainerUsage, 0, len(df.Containers)),
+		Volumes:    make([]DockerVolumeUsage, 0, len(df.Volumes)),
+	}
+
+	for _, img := range df.Images {
+		name := "<none>"
+		for _, t := range img.RepoTags {
+			if t != "" && t != "<none>:<none>" {
+				name = t
+				break
+			}
+		}
+		r.ImagesSize += img.Size
+		r.Images = append(r.Images, DockerImageUsage{
+			ImageID:    img.ID,
+			Image:      name,
+			SizeBytes:  img.Size,
+			SharedSize: img.SharedSize,
+			Containers: img.Containers,
+		})
+	}
+
+	for _, c := range df.Containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		r.ContainersSize += c.SizeRw
+		r.Containers = append(r.Containers, DockerContainerUsage{
+			ContainerID:    c.ID,
+			Name:           name,
+			Image:          c.Image,
+			ComposeProject: c.Labels["com.docker.compose.project"],
+			SizeRwBytes:    c.SizeRw,
+			SizeRootFs:     c.SizeRootFs,
+		})
+	}
+
+	for _, v := range df.Volumes {
+		size, refs := int64(-1), int64(-1)
+		if v.UsageData != nil {
+			size, refs = v.UsageData.Size, v.UsageData.RefCount
+		}
+		if size > 0 {
+			r.VolumesSize += size
+		}
+		r.Volumes = append(r.Volumes, DockerVolumeUsage{
+			Name:           v.Name,
+			Driver:         v.Driver,
+			ComposeProject: v.Labels["com.docker.compose.project"],
+			SizeBytes:      size,
+			RefCount:       refs,
+		})
+	}
+
+	for _, b := range df.BuildCache {
+		r.BuildCacheSize += b.Size
+		if !b.InUse {
+			r.BuildCacheReclaimable += b.Size
+		}
+	}
+
+	sort.Slice(r.Images, func(i, j int) bool { return r.Images[i].SizeBytes > r.Images[j].SizeBytes })
+	sort.Slice(r.Containers, func(i, j int) bool { return r.Containers[i].SizeRwBytes > r.Containers[j].SizeRwBytes })
+	sort.Slice(r.Volumes, func(i, j int) bool { return r.Volumes[i].SizeBytes > r.Volumes[j].SizeBytes })
+	if len(r.Images) > topN {
+		r.Images = r.Images[:topN]
+	}
+	if len(r.Containers) > topN {
+		r.Containers = r.Containers[:topN]
+	}
+	if len(r.Volumes) > topN {
+		r.Volumes = r.Volumes[:topN]
+	}
+	return r
+}
+
+// dockerAPIGet performs a raw GET against the Docker Engine API through the
+// shared client's transport, for the few endpoints go-dockerclient decodes
+// lossily. Mirrors the client's own URL building: unix sockets use a fake
+// host, TCP endpoints use http(s) depending on the TLS config.
+func dockerAPIGet(ctx context.Context, client *docker.Client, path string, out any) error {
+	endpoint := client.Endpoint()
+	var base string
+	switch {
+	case strings.HasPrefix(endpoint, "unix://"), strings.HasPrefix(endpoint, "npipe://"):
+		base = "http://unix.sock"
+	case strings.HasPrefix(endpoint, "tcp://"):
+		scheme := "http://"
+		if client.TLSConfig != nil {
+			scheme = "https://"
+		}
+		base = scheme + strings.TrimPrefix(endpoint, "tcp://")
+	default:
+		base = endpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(base, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const systemDFFixture = `{
+  "LayersSize": 3000,
+  "Images": [
+    {"Id": "sha256:a", "RepoTags": ["nginx:1.25"], "Size": 1000, "SharedSize": 200, "Containers": 1},
+    {"Id": "sha256:b", "RepoTags": ["<none>:<none>"], "Size": 2000, "SharedSize": -1, "Containers": 0}
+  ],
+  "Containers": [
+    {"Id": "c1", "Names": ["/web"], "Image": "nginx:1.25", "SizeRw": 50, "SizeRootFs": 1050,
+     "Labels": {"com.docker.compose.project": "site"}},
+    {"Id": "c2", "Names": ["/db"], "Image": "postgres:16", "SizeRw": 500, "SizeRootFs": 900}
+  ],
+  "Volumes": [
+    {"Name": "site_data", "Driver": "local", "Labels": {"com.docker.compose.project": "site"},
+     "UsageData": {"Size": 4096, "RefCount": 1}},
+    {"Name": "nfs", "Driver": "nfs", "UsageData": {"Size": -1, "RefCount": -1}},
+    {"Name": "orphan", "Driver": "local", "UsageData": {"Size": 10, "RefCount": 0}}
+  ],
+  "BuildCache": [
+    {"Size": 700, "InUse": false},
+    {"Size": 300, "InUse": true}
+  ]
+}`
+
+func TestBuildDockerDiskUsage(t *testing.T) {
+	var df systemDFResponse
+	if err := json.Unmarshal([]byte(systemDFFixture), &df); err != nil {
+		t.Fatal(err)
+	}
+	r := buildDockerDiskUsage(&df, 50)
+
+	if r.LayersSize != 3000 || r.ImagesSize != 3000 || r.ContainersSize != 550 {
+		t.Errorf("totals: layers=%d images=%d containers=%d", r.LayersSize, r.ImagesSize, r.ContainersSize)
+	}
+	// Unknown (-1) volume sizes must not drag the total down.
+	if r.VolumesSize != 4106 {
+		t.Errorf("volumes total = %d, want 4106", r.VolumesSize)
+	}
+	if r.BuildCacheSize != 1000 || r.BuildCacheReclaimable != 700 {
+		t.Errorf("build cache = %d/%d, want 1000/700", r.BuildCacheSize, r.BuildCacheReclaimable)
+	}
+
+	// Sorted largest first.
+	if r.Images[0].ImageID != "sha256:b" || r.Images[0].Image != "<none>" {
+		t.Errorf("largest image = %+v", r.Images[0])
+	}
+	if r.Containers[0].Name != "db" || r.Containers[1].ComposeProject != "site" {
+		t.Errorf("containers = %+v", r.Containers)
+	}
+	if r.Volumes[0].Name != "site_data" || r.Volumes[0].ComposeProject != "site" || r.Volumes[2].SizeBytes != -1 {
+		t.Errorf("volumes = %+v", r.Volumes)
+	}
+}
+
+func TestBuildDockerDiskUsage_TopN(t *testing.T) {
+	var df systemDFResponse
+	if err := json.Unmarshal([]byte(systemDFFixture), &df); err != nil {
+		t.Fatal(err)
+	}
+	r := buildDockerDiskUsage(&df, 1)
+	if len(r.Images) != 1 || len(r.Containers) != 1 || len(r.Volumes) != 1 {
+		t.Fatalf("topN not applied: %d/%d/%d", len(r.Images), len(r.Containers), len(r.Volumes))
+	}
+	// Totals still cover every item.
+	if r.ImagesSize != 3000 || r.VolumesSize != 4106 {
+		t.Errorf("totals must ignore the cut: images=%d volumes=%d", r.ImagesSize, r.VolumesSize)
+	}
+}
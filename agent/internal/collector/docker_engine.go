@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Container engines the collector can talk to. Podman serves a
+// Docker-compatible REST API on its own socket, so everything built on the
+// shared client (containers, networks, compose labels, /system/df) works
+// unchanged once the right socket is picked.
+const (
+	DockerEngineDocker = "docker"
+	DockerEnginePodman = "podman"
+)
+
+// dockerSocketPaths lists the system-wide engine sockets in priority order:
+// a rootful Docker daemon wins over a rootful Podman service. Per-user
+// (rootless) Podman sockets are appended by dockerSocketCandidates.
+var dockerSocketPaths = []string{
+	"/var/run/docker.sock",
+	"/run/podman/podman.sock",
+}
+
+// dockerUserSocketGlob matches rootless Podman sockets
+// (`systemctl --user enable --now podman.socket`), one per logged-in user.
+var dockerUserSocketGlob = "/run/user/*/podman/podman.sock"
+
+var (
+	dockerEngineMu sync.RWMutex
+	dockerEngine   string
+)
+
+// DockerEngine returns the engine behind the shared client ("docker" or
+// "podman"), or "" before the first successful connection.
+func DockerEngine() string {
+	dockerEngineMu.RLock()
+	defer dockerEngineMu.RUnlock()
+	return dockerEngine
+}
+
+// dockerSocketCandidates returns every engine socket worth probing, in
+// priority order: the system-wide sockets, the agent user's own
+// $XDG_RUNTIME_DIR Podman socket, then the other users' rootless sockets by
+// ascending UID (so the choice is stable across restarts).
+func dockerSocketCandidates() []string {
+	candidates := append([]string{}, dockerSocketPaths...)
+	if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "podman", "podman.sock"))
+	}
+
+	userSockets, _ := filepath.Glob(dockerUserSocketGlob)
+	sort.Slice(userSockets, func(i, j int) bool {
+		return socketUID(userSockets[i]) < socketUID(userSockets[j])
+	})
+	candidates = append(candidates, userSockets...)
+
+	seen := make(map[string]bool, len(candidates))
+	out := candidates[:0]
+	for _, c := range candidates {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// socketUID extracts the UID from /run/user/<uid>/..., or a large value when
+// the path does not follow that layout.
+func socketUID(path string) int {
+	rest := strings.TrimPrefix(path, "/run/user/")
+	if idx := strings.IndexByte(rest, '/'); idx > 0 {
+		if uid, err := strconv.Atoi(rest[:idx]); err == nil {
+			return uid
+		}
+	}
+	return int(^uint(0) >> 1)
+}
+
+// detectDockerSocket returns the first candidate that exists and is a Unix
+// socket, or "" when none does. A present but dead socket is still returned:
+// the client then fails with a clear connection error instead of silently
+// falling through to another engine.
+func detectDockerSocket() string {
+	for _, path := range dockerSocketCandidates() {
+		if st, err := os.Stat(path); err == nil && st.Mode()&os.ModeSocket != 0 {
+			return path
+		}
+	}
+	return ""
+}
+
+// detectDockerEngine asks the engine which product it is. Podman lists a
+// "Podman Engine" component in GET /version; Docker never does.
+func detectDockerEngine(client *docker.Client) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var v struct {
+		Components []struct {
+			Name string `json:"Name"`
+		} `json:"Components"`
+	}
+	if err := dockerAPIGet(ctx, client, "/version", &v); err != nil {
+		return DockerEngineDocker
+	}
+	for _, c := range v.Components {
+		if strings.Contains(strings.ToLower(c.Name), "podman") {
+			return DockerEnginePodman
+		}
+	}
+	return DockerEngineDocker
+}
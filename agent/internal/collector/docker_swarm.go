@@ -0,0 +1,317 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// swarmFailedTaskWindow is how long a failed/rejected task stays in the
+// report after it stopped, so a crash-looping service is visible between two
+// reports without shipping Swarm's whole task history.
+const swarmFailedTaskWindow = time.Hour
+
+// swarmMaxTasks bounds the task list on large clusters.
+const swarmMaxTasks = 500
+
+// SwarmService is one Swarm service with its replica health. For global
+// services DesiredReplicas is the number of eligible nodes.
+type SwarmService struct {
+	ServiceID       string `json:"service_id"`
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	Mode            string `json:"mode"`            // replicated, global, replicated-job, global-job
+	Stack           string `json:"stack,omitempty"` // com.docker.stack.namespace label
+	DesiredReplicas int    `json:"desired_replicas"`
+	RunningReplicas int    `json:"running_replicas"`
+	UpdateState     string `json:"update_state,omitempty"` // updating, paused, completed, rollback_*
+}
+
+// SwarmTask is one task (a service replica slot on a node).
+type SwarmTask struct {
+	TaskID       string    `json:"task_id"`
+	ServiceID    string    `json:"service_id"`
+	ServiceName  string    `json:"service_name"`
+	NodeID       string    `json:"node_id"`
+	Slot         int       `json:"slot,omitempty"`
+	State        string    `json:"state"`
+	DesiredState string    `json:"desired_state"`
+	Message      string    `json:"message,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SwarmNode is one cluster member and its scheduling availability.
+type SwarmNode struct {
+	NodeID        string `json:"node_id"`
+	Hostname      string `json:"hostname"`
+	Role          string `json:"role"`         // manager, worker
+	Availability  string `json:"availability"` // active, pause, drain
+	State         string `json:"state"`        // ready, down, disconnected, unknown
+	Leader        bool   `json:"leader,omitempty"`
+	Reachability  string `json:"reachability,omitempty"` // managers only
+	Addr          string `json:"addr,omitempty"`
+	EngineVersion string `json:"engine_version,omitempty"`
+}
+
+// DockerSwarmReport is the Swarm view of a host. It is sent whenever the
+// engine answered GET /info, even on non-managers (Manager false, no lists),
+// so the server can drop a host's Swarm state once it is demoted or leaves
+// the cluster. Services, tasks and nodes are only readable on managers.
+type DockerSwarmReport struct {
+	LocalNodeState string         `json:"local_node_state"` // inactive, pending, active, error, locked
+	Manager        bool           `json:"manager"`
+	ClusterID      string         `json:"cluster_id,omitempty"`
+	NodeID         string         `json:"node_id,omitempty"`
+	Services       []SwarmService `json:"services,omitempty"`
+	Tasks          []SwarmTask    `json:"tasks,omitempty"`
+	Nodes          []SwarmNode    `json:"nodes,omitempty"`
+}
+
+// Raw Engine API shapes (GET /info, /services, /tasks, /nodes). The swarm
+// endpoints were dropped from go-dockerclient, so they are decoded here.
+type swarmInfoResponse struct {
+	Swarm struct {
+		NodeID           string `json:"NodeID"`
+		LocalNodeState   string `json:"LocalNodeState"`
+		ControlAvailable bool   `json:"ControlAvailable"`
+		Cluster          *struct {
+			ID string `json:"ID"`
+		} `json:"Cluster"`
+	} `json:"Swarm"`
+}
+
+type swarmServiceResponse struct {
+	ID   string `json:"ID"`
+	Spec struct {
+		Name         string            `json:"Name"`
+		Labels       map[string]string `json:"Labels"`
+		TaskTemplate struct {
+			ContainerSpec struct {
+				Image string `json:"Image"`
+			} `json:"ContainerSpec"`
+		} `json:"TaskTemplate"`
+		Mode struct {
+			Replicated *struct {
+				Replicas *int `json:"Replicas"`
+			} `json:"Replicated"`
+			Global        *struct{} `json:"Global"`
+			ReplicatedJob *struct {
+				TotalCompletions *int `json:"TotalCompletions"`
+			} `json:"ReplicatedJob"`
+			GlobalJob *struct{} `json:"GlobalJob"`
+		} `json:"Mode"`
+	} `json:"Spec"`
+	UpdateStatus *struct {
+		State string `json:"State"`
+	} `json:"UpdateStatus"`
+	// Only filled with ?status=true (Engine API >= 1.41).
+	ServiceStatus *struct {
+		RunningTasks int `json:"RunningTasks"`
+		DesiredTasks int `json:"DesiredTasks"`
+	} `json:"ServiceStatus"`
+}
+
+type swarmTaskResponse struct {
+	ID        string    `json:"ID"`
+	ServiceID string    `json:"ServiceID"`
+	NodeID    string    `json:"NodeID"`
+	Slot      int       `json:"Slot"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+	Status    struct {
+		State   string `json:"State"`
+		Message string `json:"Message"`
+		Err     string `json:"Err"`
+	} `json:"Status"`
+	DesiredState string `json:"DesiredState"`
+}
+
+type swarmNodeResponse struct {
+	ID          string `json:"ID"`
+	Description struct {
+		Hostname string `json:"Hostname"`
+		Engine   struct {
+			EngineVersion string `json:"EngineVersion"`
+		} `json:"Engine"`
+	} `json:"Description"`
+	Spec struct {
+		Role         string `json:"Role"`
+		Availability string `json:"Availability"`
+	} `json:"Spec"`
+	Status struct {
+		State string `json:"State"`
+		Addr  string `json:"Addr"`
+	} `json:"Status"`
+	ManagerStatus *struct {
+		Leader       bool   `json:"Leader"`
+		Reachability string `json:"Reachability"`
+	} `json:"ManagerStatus"`
+}
+
+// CollectDockerSwarm reports the host's Swarm membership and, on managers,
+// the cluster's services, tasks and nodes. Podman has no Swarm mode: its
+// /info carries no Swarm section, which reads as an inactive node.
+func CollectDockerSwarm(ctx context.Context) (*DockerSwarmReport, error) {
+	client, err := newDockerClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+
+	var info swarmInfoResponse
+	if err := dockerAPIGet(ctx, client, "/info", &info); err != nil {
+		return nil, fmt.Errorf("docker info: %w", err)
+	}
+	if info.Swarm.LocalNodeState != "active" || !info.Swarm.ControlAvailable {
+		return buildSwarmReport(&info, nil, nil, nil, time.Now()), nil
+	}
+
+	var services []swarmServiceResponse
+	if err := dockerAPIGet(ctx, client, "/services?status=true", &services); err != nil {
+		return nil, fmt.Errorf("swarm services: %w", err)
+	}
+	var tasks []swarmTaskResponse
+	if err := dockerAPIGet(ctx, client, "/tasks", &tasks); err != nil {
+		return nil, fmt.Errorf("swarm tasks: %w", err)
+	}
+	var nodes []swarmNodeResponse
+	if err := dockerAPIGet(ctx, client, "/nodes", &nodes); err != nil {
+		return nil, fmt.Errorf("swarm nodes: %w", err)
+	}
+	return buildSwarmReport(&info, services, tasks, nodes, time.Now()), nil
+}
+
+// buildSwarmReport turns the raw API answers into the report. Running
+// replicas come from ServiceStatus when the engine provides it, else from
+// counting running tasks whose desired state is still running.
+func buildSwarmReport(info *swarmInfoResponse, services []swarmServiceResponse, tasks []swarmTaskResponse, nodes []swarmNodeResponse, now time.Time) *DockerSwarmReport {
+	r := &DockerSwarmReport{
+		LocalNodeState: info.Swarm.LocalNodeState,
+		Manager:        info.Swarm.LocalNodeState == "active" && info.Swarm.ControlAvailable,
+		NodeID:         info.Swarm.NodeID,
+	}
+	if r.LocalNodeState == "" {
+		r.LocalNodeState = "inactive"
+	}
+	if info.Swarm.Cluster != nil {
+		r.ClusterID = info.Swarm.Cluster.ID
+	}
+	if !r.Manager {
+		return r
+	}
+
+	readyNodes := 0
+	for _, n := range nodes {
+		if n.Status.State == "ready" && n.Spec.Availability == "active" {
+			readyNodes++
+		}
+	}
+
+	running := make(map[string]int)
+	activeTasks := make(map[string]int)
+	for _, t := range tasks {
+		if t.DesiredState != "running" {
+			continue
+		}
+		activeTasks[t.ServiceID]++
+		if t.Status.State == "running" {
+			running[t.ServiceID]++
+		}
+	}
+
+	names := make(map[string]string, len(services))
+	r.Services = make([]SwarmService, 0, len(services))
+	for _, s := range services {
+		names[s.ID] = s.Spec.Name
+		svc := SwarmService{
+			ServiceID:       s.ID,
+			Name:            s.Spec.Name,
+			Image:           stripImageDigest(s.Spec.TaskTemplate.ContainerSpec.Image),
+			Stack:           s.Spec.Labels["com.docker.stack.namespace"],
+			RunningReplicas: running[s.ID],
+		}
+		switch m := s.Spec.Mode; {
+		case m.Replicated != nil:
+			svc.Mode = "replicated"
+			if m.Replicated.Replicas != nil {
+				svc.DesiredReplicas = *m.Replicated.Replicas
+			}
+		case m.Global != nil:
+			svc.Mode = "global"
+			svc.DesiredReplicas = activeTasks[s.ID]
+			if svc.DesiredReplicas == 0 {
+				svc.DesiredReplicas = readyNodes
+			}
+		case m.ReplicatedJob != nil:
+			svc.Mode = "replicated-job"
+		case m.GlobalJob != nil:
+			svc.Mode = "global-job"
+		}
+		if s.ServiceStatus != nil {
+			svc.DesiredReplicas = s.ServiceStatus.DesiredTasks
+			svc.RunningReplicas = s.ServiceStatus.RunningTasks
+		}
+		if strings.HasSuffix(svc.Mode, "-job") {
+			// Jobs run to completion: nothing is "missing" once they finish.
+			svc.DesiredReplicas = svc.RunningReplicas
+		}
+		if s.UpdateStatus != nil {
+			svc.UpdateState = s.UpdateStatus.State
+		}
+		r.Services = append(r.Services, svc)
+	}
+	sort.Slice(r.Services, func(i, j int) bool { return r.Services[i].Name < r.Services[j].Name })
+
+	r.Tasks = make([]SwarmTask, 0)
+	for _, t := range tasks {
+		failed := t.Status.State == "failed" || t.Status.State == "rejected"
+		if t.DesiredState != "running" && !(failed && now.Sub(t.UpdatedAt) <= swarmFailedTaskWindow) {
+			continue
+		}
+		r.Tasks = append(r.Tasks, SwarmTask{
+			TaskID:       t.ID,
+			ServiceID:    t.ServiceID,
+			ServiceName:  names[t.ServiceID],
+			NodeID:       t.NodeID,
+			Slot:         t.Slot,
+			State:        t.Status.State,
+			DesiredState: t.DesiredState,
+			Message:      t.Status.Message,
+			Error:        t.Status.Err,
+			UpdatedAt:    t.UpdatedAt,
+		})
+	}
+	sort.Slice(r.Tasks, func(i, j int) bool { return r.Tasks[i].UpdatedAt.After(r.Tasks[j].UpdatedAt) })
+	if len(r.Tasks) > swarmMaxTasks {
+		r.Tasks = r.Tasks[:swarmMaxTasks]
+	}
+
+	r.Nodes = make([]SwarmNode, 0, len(nodes))
+	for _, n := range nodes {
+		node := SwarmNode{
+			NodeID:        n.ID,
+			Hostname:      n.Description.Hostname,
+			Role:          n.Spec.Role,
+			Availability:  n.Spec.Availability,
+			State:         n.Status.State,
+			Addr:          n.Status.Addr,
+			EngineVersion: n.Description.Engine.EngineVersion,
+		}
+		if n.ManagerStatus != nil {
+			node.Leader = n.ManagerStatus.Leader
+			node.Reachability = n.ManagerStatus.Reachability
+		}
+		r.Nodes = append(r.Nodes, node)
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool { return r.Nodes[i].Hostname < r.Nodes[j].Hostname })
+	return r
+}
+
+// stripImageDigest drops the "@sha256:..." pin Swarm adds to service images.
+func stripImageDigest(image string) string {
+	if idx := strings.Index(image, "@"); idx >= 0 {
+		return image[:idx]
+	}
+	return image
+}
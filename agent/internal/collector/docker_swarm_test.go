@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBuildSwarmReport_NotManager(t *testing.T) {
+	var info swarmInfoResponse
+	if err := json.Unmarshal([]byte(`{"Swarm":{"NodeID":"n2","LocalNodeState":"active","ControlAvailable":false}}`), &info); err != nil {
+		t.Fatal(err)
+	}
+	r := buildSwarmReport(&info, nil, nil, nil, time.Now())
+	if r.Manager || r.LocalNodeState != "active" || r.Services != nil || r.Nodes != nil {
+		t.Errorf("worker report = %+v", r)
+	}
+
+	// Podman: no Swarm section at all.
+	r = buildSwarmReport(&swarmInfoResponse{}, nil, nil, nil, time.Now())
+	if r.Manager || r.LocalNodeState != "inactive" {
+		t.Errorf("podman report = %+v", r)
+	}
+}
+
+func TestBuildSwarmReport_Manager(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	var (
+		info     swarmInfoResponse
+		services []swarmServiceResponse
+		tasks    []swarmTaskResponse
+		nodes    []swarmNodeResponse
+	)
+	mustUnmarshal := func(s string, v any) {
+		t.Helper()
+		if err := json.Unmarshal([]byte(s), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustUnmarshal(`{"Swarm":{"NodeID":"n1","LocalNodeState":"active","ControlAvailable":true,"Cluster":{"ID":"c1"}}}`, &info)
+	mustUnmarshal(`[
+	  {"ID":"s1","Spec":{"Name":"web","Labels":{"com.docker.stack.namespace":"site"},
+	   "TaskTemplate":{"ContainerSpec":{"Image":"nginx:1.25@sha256:abc"}},"Mode":{"Replicated":{"Replicas":3}}}},
+	  {"ID":"s2","Spec":{"Name":"agent","TaskTemplate":{"ContainerSpec":{"Image":"agent:1"}},"Mode":{"Global":{}}},
+	   "ServiceStatus":{"RunningTasks":1,"DesiredTasks":2}}
+	]`, &services)
+	mustUnmarshal(`[
+	  {"ID":"t1","ServiceID":"s1","NodeID":"n1","Slot":1,"DesiredState":"running","Status":{"State":"running"},"UpdatedAt":"2026-10-18T11:00:00Z"},
+	  {"ID":"t2","ServiceID":"s1","NodeID":"n2","Slot":2,"DesiredState":"running","Status":{"State":"preparing"},"UpdatedAt":"2026-10-18T11:59:00Z"},
+	  {"ID":"t3","ServiceID":"s1","NodeID":"n2","Slot":3,"DesiredState":"shutdown","Status":{"State":"failed","Err":"exit 1"},"UpdatedAt":"2026-10-18T11:30:00Z"},
+	  {"ID":"t4","ServiceID":"s1","NodeID":"n2","Slot":3,"DesiredState":"shutdown","Status":{"State":"failed"},"UpdatedAt":"2026-10-18T09:00:00Z"},
+	  {"ID":"t5","ServiceID":"s1","NodeID":"n1","Slot":1,"DesiredState":"shutdown","Status":{"State":"shutdown"},"UpdatedAt":"2026-10-18T11:58:00Z"}
+	]`, &tasks)
+	mustUnmarshal(`[
+	  {"ID":"n2","Description":{"Hostname":"worker"},"Spec":{"Role":"worker","Availability":"drain"},"Status":{"State":"ready"}},
+	  {"ID":"n1","Description":{"Hostname":"manager","Engine":{"EngineVersion":"27.3.1"}},"Spec":{"Role":"manager","Availability":"active"},
+	   "Status":{"State":"ready","Addr":"10.0.0.1"},"ManagerStatus":{"Leader":true,"Reachability":"reachable"}}
+	]`, &nodes)
+
+	r := buildSwarmReport(&info, services, tasks, nodes, now)
+	if !r.Manager || r.ClusterID != "c1" || r.NodeID != "n1" {
+		t.Fatalf("header = %+v", r)
+	}
+
+	if len(r.Services) != 2 {
+		t.Fatalf("services = %+v", r.Services)
+	}
+	agent, web := r.Services[0], r.Services[1]
+	if web.Mode != "replicated" || web.DesiredReplicas != 3 || web.RunningReplicas != 1 || web.Image != "nginx:1.25" || web.Stack != "site" {
+		t.Errorf("web = %+v", web)
+	}
+	// ServiceStatus wins over counted tasks.
+	if agent.Mode != "global" || agent.DesiredReplicas != 2 || agent.RunningReplicas != 1 {
+		t.Errorf("agent = %+v", agent)
+	}
+
+	// Active tasks plus the failure from the last hour; old failures and
+	// clean shutdowns are dropped. Most recent first.
+	if len(r.Tasks) != 3 || r.Tasks[0].TaskID != "t2" || r.Tasks[1].TaskID != "t3" || r.Tasks[2].TaskID != "t1" {
+		t.Errorf("tasks = %+v", r.Tasks)
+	}
+	if r.Tasks[1].Error != "exit 1" || r.Tasks[1].ServiceName != "web" {
+		t.Errorf("failed task = %+v", r.Tasks[1])
+	}
+
+	if len(r.Nodes) != 2 || r.Nodes[0].Hostname != "manager" || !r.Nodes[0].Leader || r.Nodes[1].Availability != "drain" {
+		t.Errorf("nodes = %+v", r.Nodes)
+	}
+}
+
+func TestSocketUID(t *testing.T) {
+	if got := socketUID("/run/user/1000/podman/podman.sock"); got != 1000 {
+		t.Errorf("socketUID = %d", got)
+	}
+	if socketUID("/run/user/999/podman/podman.sock") >= socketUID("/run/user/1000/podman/podman.sock") {
+		t.Error("uids must sort numerically, not lexically")
+	}
+	if socketUID("/tmp/podman.sock") <= 1<<20 {
+		t.Error("non /run/user paths must sort last")
+	}
+}
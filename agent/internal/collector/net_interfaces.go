@@ -0,0 +1,234 @@
+//go:build linux
+
+// Package collector's net_interfaces.go reports every network interface's own
+// cumulative counters and link state, where system.go's getNetworkBytes only
+// keeps the host-wide rx/tx sum. Counters come from /proc/net/dev, link
+// attributes (operstate, speed, duplex, bond/bridge membership) from
+// /sys/class/net and VLAN parents from /proc/net/vlan/config — no external
+// binary, same as the rest of this package.
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxNetInterfaces bounds one report's interface list: a Docker or
+// Kubernetes host can carry hundreds of virtual links, most of them already
+// skipped below, and the server keeps a history row per interface per cycle.
+const maxNetInterfaces = 64
+
+// proxmoxGuestLinkRegex matches the links Proxmox VE creates per guest NIC:
+// the tap device and, with the firewall enabled, its fwbr bridge and fwln/fwpr
+// veth pair (tap100i0, fwbr100i0, fwln100i0, fwpr100p0). Like veth, they come
+// and go with the guest; the vmbr bridges they plug into are kept.
+var proxmoxGuestLinkRegex = regexp.MustCompile(`^(tap|fwbr|fwln|fwpr)\d+[ip]\d+$`)
+
+// netInterfaceKindRank orders kinds when the list must be cut: the links that
+// carry the host's own traffic go before the virtual ones.
+var netInterfaceKindRank = map[string]int{
+	NetInterfacePhysical: 0,
+	NetInterfaceBond:     1,
+	NetInterfaceBridge:   2,
+	NetInterfaceVLAN:     3,
+	NetInterfaceVirtual:  4,
+}
+
+// Interface kinds, as inferred from sysfs.
+const (
+	NetInterfacePhysical = "physical"
+	NetInterfaceBond     = "bond"
+	NetInterfaceBridge   = "bridge"
+	NetInterfaceVLAN     = "vlan"
+	NetInterfaceVirtual  = "virtual"
+)
+
+// NetInterface is one interface's link state and cumulative counters for this
+// report cycle. Counters are the kernel's since-boot totals (the server
+// derives rates from consecutive samples, resetting on a counter drop).
+// Mirrors server/internal/models.NetInterface.
+type NetInterface struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// OperState is /sys/class/net/<if>/operstate verbatim: up, down,
+	// lowerlayerdown, dormant, unknown (common for tun and some virtual
+	// links that still carry traffic)...
+	OperState string `json:"operstate"`
+	// SpeedMbps is 0 when the driver does not report a link speed (virtual
+	// interfaces, links that are down).
+	SpeedMbps int    `json:"speed_mbps,omitempty"`
+	Duplex    string `json:"duplex,omitempty"`
+	MTU       int    `json:"mtu,omitempty"`
+	// Master is the bond or bridge this interface is enslaved to.
+	Master string `json:"master,omitempty"`
+	// Parent and VLANID are only set for 802.1Q sub-interfaces.
+	Parent string `json:"parent,omitempty"`
+	VLANID int    `json:"vlan_id,omitempty"`
+	// Members lists a bond's slaves or a bridge's ports.
+	Members   []string `json:"members,omitempty"`
+	RxBytes   uint64   `json:"rx_bytes"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	RxPackets uint64   `json:"rx_packets"`
+	TxPackets uint64   `json:"tx_packets"`
+	RxErrors  uint64   `json:"rx_errors"`
+	TxErrors  uint64   `json:"tx_errors"`
+	RxDropped uint64   `json:"rx_dropped"`
+	TxDropped uint64   `json:"tx_dropped"`
+}
+
+// CollectNetInterfaces returns the per-interface view of this host's network
+// links. Loopback, veth pairs (one per container, created and destroyed with
+// it) and Proxmox's per-guest links are skipped; the bridges they plug into
+// are kept.
+func CollectNetInterfaces() ([]NetInterface, error) {
+	return collectNetInterfaces("/proc/net/dev", "/sys/class/net", "/proc/net/vlan/config")
+}
+
+func collectNetInterfaces(procNetDev, sysClassNet, vlanConfig string) ([]NetInterface, error) {
+	data, err := os.ReadFile(procNetDev)
+	if err != nil {
+		return nil, err
+	}
+	ifaces := parseProcNetDev(string(data))
+	vlans := readVLANConfig(vlanConfig)
+
+	out := make([]NetInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		if iface.Name == "lo" || strings.HasPrefix(iface.Name, "veth") || proxmoxGuestLinkRegex.MatchString(iface.Name) {
+			continue
+		}
+		readNetInterfaceSysfs(&iface, filepath.Join(sysClassNet, iface.Name))
+		if v, ok := vlans[iface.Name]; ok {
+			iface.Kind = NetInterfaceVLAN
+			iface.Parent = v.parent
+			iface.VLANID = v.id
+		}
+		out = append(out, iface)
+	}
+	if len(out) > maxNetInterfaces {
+		// Keep physical, bond, bridge and VLAN links over virtual ones, so
+		// a host full of virtual links does not push its uplinks out.
+		sort.SliceStable(out, func(i, j int) bool {
+			ri, rj := netInterfaceKindRank[out[i].Kind], netInterfaceKindRank[out[j].Kind]
+			if ri != rj {
+				return ri < rj
+			}
+			return out[i].Name < out[j].Name
+		})
+		out = out[:maxNetInterfaces]
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// parseProcNetDev reads /proc/net/dev's per-interface counters. The receive
+// block is bytes, packets, errs, drop, fifo, frame, compressed, multicast;
+// the transmit block starts at field 8 with the same first four.
+func parseProcNetDev(data string) []NetInterface {
+	var out []NetInterface
+	for _, line := range strings.Split(data, "\n") {
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 12 {
+			continue // the two header lines, or a truncated read
+		}
+		n := make([]uint64, 12)
+		for i := range n {
+			n[i], _ = strconv.ParseUint(fields[i], 10, 64)
+		}
+		out = append(out, NetInterface{
+			Name:      strings.TrimSpace(name),
+			RxBytes:   n[0],
+			RxPackets: n[1],
+			RxErrors:  n[2],
+			RxDropped: n[3],
+			TxBytes:   n[8],
+			TxPackets: n[9],
+			TxErrors:  n[10],
+			TxDropped: n[11],
+		})
+	}
+	return out
+}
+
+// readNetInterfaceSysfs fills in link attributes from /sys/class/net/<if>.
+// Every file is optional: reading speed on a link that is down fails with
+// EINVAL, and virtual interfaces have no device/ at all.
+func readNetInterfaceSysfs(iface *NetInterface, dir string) {
+	iface.OperState = readSysfsString(filepath.Join(dir, "operstate"))
+	if speed, err := strconv.Atoi(readSysfsString(filepath.Join(dir, "speed"))); err == nil && speed > 0 {
+		iface.SpeedMbps = speed
+	}
+	if duplex := readSysfsString(filepath.Join(dir, "duplex")); duplex != "unknown" {
+		iface.Duplex = duplex
+	}
+	iface.MTU, _ = strconv.Atoi(readSysfsString(filepath.Join(dir, "mtu")))
+	if target, err := os.Readlink(filepath.Join(dir, "master")); err == nil {
+		iface.Master = filepath.Base(target)
+	}
+
+	switch {
+	case pathExists(filepath.Join(dir, "bonding")):
+		iface.Kind = NetInterfaceBond
+		iface.Members = strings.Fields(readSysfsString(filepath.Join(dir, "bonding", "slaves")))
+	case pathExists(filepath.Join(dir, "bridge")):
+		iface.Kind = NetInterfaceBridge
+		if entries, err := os.ReadDir(filepath.Join(dir, "brif")); err == nil {
+			for _, e := range entries {
+				iface.Members = append(iface.Members, e.Name())
+			}
+		}
+	case pathExists(filepath.Join(dir, "device")):
+		iface.Kind = NetInterfacePhysical
+	default:
+		iface.Kind = NetInterfaceVirtual
+	}
+}
+
+type vlanInfo struct {
+	parent string
+	id     int
+}
+
+// readVLANConfig parses /proc/net/vlan/config ("eth0.100 | 100 | eth0" rows
+// under a two-line header). The file only exists once the 8021q module is
+// loaded, so a missing file simply means no VLANs.
+func readVLANConfig(path string) map[string]vlanInfo {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]vlanInfo)
+	for _, line := range strings.Split(string(data), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			continue // the "VLAN Dev name | VLAN ID" header
+		}
+		out[strings.TrimSpace(parts[0])] = vlanInfo{parent: strings.TrimSpace(parts[2]), id: id}
+	}
+	return out
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
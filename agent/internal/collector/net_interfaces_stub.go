@@ -0,0 +1,32 @@
+//go:build !linux
+
+package collector
+
+// NetInterface mirrors the Linux implementation's shape (see
+// net_interfaces.go) so the wire format stays identical across platforms.
+type NetInterface struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	OperState string   `json:"operstate"`
+	SpeedMbps int      `json:"speed_mbps,omitempty"`
+	Duplex    string   `json:"duplex,omitempty"`
+	MTU       int      `json:"mtu,omitempty"`
+	Master    string   `json:"master,omitempty"`
+	Parent    string   `json:"parent,omitempty"`
+	VLANID    int      `json:"vlan_id,omitempty"`
+	Members   []string `json:"members,omitempty"`
+	RxBytes   uint64   `json:"rx_bytes"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	RxPackets uint64   `json:"rx_packets"`
+	TxPackets uint64   `json:"tx_packets"`
+	RxErrors  uint64   `json:"rx_errors"`
+	TxErrors  uint64   `json:"tx_errors"`
+	RxDropped uint64   `json:"rx_dropped"`
+	TxDropped uint64   `json:"tx_dropped"`
+}
+
+// CollectNetInterfaces has no /proc/net/dev or /sys/class/net to read on
+// non-Linux platforms — reports no interfaces rather than erroring.
+func CollectNetInterfaces() ([]NetInterface, error) {
+	return nil, nil
+}
@@ -0,0 +1,157 @@
+//go:build linux
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProcNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 5000      50    0    0    0     0          0         0     5000      50    0    0    0     0       0          0
+  eth0: 1000      10    1    2    0     0          0         0     2000      20    3    4    0     0       0          0
+  eth1: 300        3    0    0    0     0          0         0      400       4    0    0    0     0       0          0
+ bond0: 1300      13    1    2    0     0          0         0     2400      24    3    4    0     0       0          0
+bond0.100: 70      7    0    0    0     0          0         0       80       8    0    0    0     0       0          0
+  vmbr0: 900       9    0    0    0     0          0         0      800       8    0    0    0     0       0          0
+vethab12: 10       1    0    0    0     0          0         0       10       1    0    0    0     0       0          0
+`
+
+// writeSysfs lays out a fake /sys/class/net/<name> directory.
+func writeSysfs(t *testing.T, root, name string, files map[string]string, dirs ...string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	for _, d := range append(dirs, "") {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for f, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCollectNetInterfaces(t *testing.T) {
+	tmp := t.TempDir()
+	procNetDev := filepath.Join(tmp, "dev")
+	vlanConfig := filepath.Join(tmp, "vlan_config")
+	sys := filepath.Join(tmp, "sys")
+	if err := os.WriteFile(procNetDev, []byte(testProcNetDev), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vlanConfig, []byte("VLAN Dev name	 | VLAN ID\nName-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD\nbond0.100      | 100  | bond0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeSysfs(t, sys, "eth0", map[string]string{"operstate": "up", "speed": "1000", "duplex": "full", "mtu": "1500"}, "device")
+	writeSysfs(t, sys, "eth1", map[string]string{"operstate": "down", "speed": "-1", "duplex": "unknown", "mtu": "1500"}, "device")
+	writeSysfs(t, sys, "bond0", map[string]string{"operstate": "up", "speed": "1000", "mtu": "1500", "bonding/slaves": "eth0 eth1"}, "bonding")
+	writeSysfs(t, sys, "bond0.100", map[string]string{"operstate": "up", "mtu": "1500"})
+	writeSysfs(t, sys, "vmbr0", map[string]string{"operstate": "up", "mtu": "1500"}, "bridge", "brif/bond0.100")
+	for _, slave := range []string{"eth0", "eth1"} {
+		if err := os.Symlink("../bond0", filepath.Join(sys, slave, "master")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := collectNetInterfaces(procNetDev, sys, vlanConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []NetInterface{
+		{Name: "bond0", Kind: NetInterfaceBond, OperState: "up", SpeedMbps: 1000, MTU: 1500, Members: []string{"eth0", "eth1"},
+			RxBytes: 1300, RxPackets: 13, RxErrors: 1, RxDropped: 2, TxBytes: 2400, TxPackets: 24, TxErrors: 3, TxDropped: 4},
+		{Name: "bond0.100", Kind: NetInterfaceVLAN, OperState: "up", MTU: 1500, Parent: "bond0", VLANID: 100,
+			RxBytes: 70, RxPackets: 7, TxBytes: 80, TxPackets: 8},
+		{Name: "eth0", Kind: NetInterfacePhysical, OperState: "up", SpeedMbps: 1000, Duplex: "full", MTU: 1500, Master: "bond0",
+			RxBytes: 1000, RxPackets: 10, RxErrors: 1, RxDropped: 2, TxBytes: 2000, TxPackets: 20, TxErrors: 3, TxDropped: 4},
+		{Name: "eth1", Kind: NetInterfacePhysical, OperState: "down", MTU: 1500, Master: "bond0",
+			RxBytes: 300, RxPackets: 3, TxBytes: 400, TxPackets: 4},
+		{Name: "vmbr0", Kind: NetInterfaceBridge, OperState: "up", MTU: 1500, Members: []string{"bond0.100"},
+			RxBytes: 900, RxPackets: 9, TxBytes: 800, TxPackets: 8},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCollectNetInterfacesWithoutSysfsOrVLANs(t *testing.T) {
+	tmp := t.TempDir()
+	procNetDev := filepath.Join(tmp, "dev")
+	if err := os.WriteFile(procNetDev, []byte(testProcNetDev), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := collectNetInterfaces(procNetDev, filepath.Join(tmp, "missing"), filepath.Join(tmp, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 || got[0].Kind != NetInterfaceVirtual || got[0].OperState != "" {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := collectNetInterfaces(filepath.Join(tmp, "missing"), "", ""); err == nil {
+		t.Error("a missing /proc/net/dev must be an error")
+	}
+}
+
+// TestCollectNetInterfacesKeepsUplinksOnBusyHosts: on a Proxmox node the
+// per-guest links are skipped, and when the list must still be cut the
+// bridges and VLANs outlive the virtual links sorting before them by name.
+func TestCollectNetInterfacesKeepsUplinksOnBusyHosts(t *testing.T) {
+	tmp := t.TempDir()
+	procNetDev := filepath.Join(tmp, "dev")
+	sys := filepath.Join(tmp, "sys")
+	line := func(name string) string {
+		return name + ": 1 1 0 0 0 0 0 0 1 1 0 0 0 0 0 0\n"
+	}
+	var b strings.Builder
+	b.WriteString("header\nheader\n")
+	for vmid := 100; vmid < 130; vmid++ {
+		for _, l := range []string{"tap%di0", "fwbr%di0", "fwln%di0", "fwpr%dp0"} {
+			b.WriteString(line(fmt.Sprintf(l, vmid)))
+		}
+	}
+	for i := 0; i < maxNetInterfaces+10; i++ {
+		b.WriteString(line(fmt.Sprintf("cali%03d", i)))
+	}
+	b.WriteString(line("vmbr0"))
+	b.WriteString(line("vmbr0.20"))
+	b.WriteString(line("eno1"))
+	if err := os.WriteFile(procNetDev, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeSysfs(t, sys, "eno1", map[string]string{"operstate": "up"}, "device")
+	writeSysfs(t, sys, "vmbr0", map[string]string{"operstate": "up"}, "bridge")
+	vlanConfig := filepath.Join(tmp, "vlan_config")
+	if err := os.WriteFile(vlanConfig, []byte("VLAN Dev name	 | VLAN ID\nvmbr0.20 | 20 | vmbr0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := collectNetInterfaces(procNetDev, sys, vlanConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != maxNetInterfaces {
+		t.Fatalf("got %d interfaces, want the cap %d", len(got), maxNetInterfaces)
+	}
+	kept := map[string]bool{}
+	for _, iface := range got {
+		if proxmoxGuestLinkRegex.MatchString(iface.Name) {
+			t.Errorf("per-guest link %s reported", iface.Name)
+		}
+		kept[iface.Name] = true
+	}
+	for _, name := range []string{"eno1", "vmbr0", "vmbr0.20"} {
+		if !kept[name] {
+			t.Errorf("%s cut from the list", name)
+		}
+	}
+	if got[0].Name != "cali000" || got[len(got)-1].Name != "vmbr0.20" {
+		t.Errorf("list not sorted by name: first %s, last %s", got[0].Name, got[len(got)-1].Name)
+	}
+}
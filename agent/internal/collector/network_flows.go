@@ -45,12 +45,15 @@ type NetworkFlowsReport struct {
 	// Available is false when this host can't provide per-connection byte
 	// counters right now (nf_conntrack not loaded, accounting disabled, or
 	// collection disabled in agent.yaml) — never an error, a capability flag.
-	Available   bool                `json:"available"`
-	Reason      string              `json:"reason,omitempty"`
-	TopTalkers  []NetworkFlowTalker `json:"top_talkers,omitempty"`
-	Others      *NetworkFlowBucket  `json:"others,omitempty"`
-	TotalFlows  int                 `json:"total_flows"`
-	CollectedAt time.Time           `json:"collected_at"`
+	Available  bool                `json:"available"`
+	Reason     string              `json:"reason,omitempty"`
+	TopTalkers []NetworkFlowTalker `json:"top_talkers,omitempty"`
+	Others     *NetworkFlowBucket  `json:"others,omitempty"`
+	TotalFlows int                 `json:"total_flows"`
+	// DNS is what the optional L7 capture saw of this host's lookups this
+	// cycle (network_flows_dns.go); nil when the capture is off or saw none.
+	DNS         *DNSQueryStats `json:"dns,omitempty"`
+	CollectedAt time.Time      `json:"collected_at"`
 }
 
 // NetworkFlowTalker is one aggregated remote peer for this cycle.
@@ -67,10 +70,11 @@ type NetworkFlowTalker struct {
 	// fallback (see containerLabelPrefix). Never overwrites a real match.
 	ProcessName string `json:"process_name,omitempty"`
 	PID         int    `json:"pid,omitempty"`
-	// ServerName is the TLS SNI hostname observed on this peer, populated only
+	// ServerName is the TLS SNI hostname observed on this peer, else the name
+	// this host resolved the peer's IP from (outbound only), populated only
 	// when the optional L7 capture is enabled (network_flows_l7_capture, off by
-	// default — see network_flows_l7.go). Empty otherwise; the UI falls back to
-	// its own well-known-port heuristic.
+	// default — see network_flows_l7.go and network_flows_dns.go). Empty
+	// otherwise; the UI falls back to its own well-known-port heuristic.
 	ServerName  string `json:"server_name,omitempty"`
 	RxBytes     uint64 `json:"rx_bytes"`
 	TxBytes     uint64 `json:"tx_bytes"`
@@ -145,7 +149,7 @@ func CollectNetworkFlows(ctx context.Context, topN int, l7Capture bool) (*Networ
 		return &NetworkFlowsReport{Available: false, Reason: reason, CollectedAt: now}, nil
 	}
 
-	// Start the optional L7 (TLS SNI) capture first so it samples packets
+	// Start the optional L7 (TLS SNI, DNS) capture first so it samples packets
 	// *while* the conntrack/proc work below runs, rather than adding its window
 	// to the collection budget. Returns immediately (and a nil sniffer) when
 	// the feature is off or the capability is missing — never blocks or fails
@@ -176,12 +180,17 @@ func CollectNetworkFlows(ctx context.Context, topN int, l7Capture bool) (*Networ
 	classified := classifyFlows(raw, local)
 	if len(classified) == 0 {
 		stopSNISniffer(sniffer)
-		return &NetworkFlowsReport{Available: true, TopTalkers: []NetworkFlowTalker{}, CollectedAt: now}, nil
+		return &NetworkFlowsReport{Available: true, TopTalkers: []NetworkFlowTalker{}, DNS: sniffer.dnsStats(), CollectedAt: now}, nil
 	}
 
 	pa := newProcessAttribution(ctx)
 	talkers := aggregateTalkers(classified, pa, containerIPs)
 	mergeServerNames(talkers, stopSNISniffer(sniffer))
+	if sniffer != nil {
+		// SNI first: it names the exact server the TLS session asked for,
+		// where a resolved name is only the last lookup answered by that IP.
+		mergeResolvedNames(talkers, resolvedNames, time.Now())
+	}
 
 	sort.Slice(talkers, func(i, j int) bool {
 		return talkers[i].RxBytes+talkers[i].TxBytes > talkers[j].RxBytes+talkers[j].TxBytes
@@ -206,6 +215,7 @@ func CollectNetworkFlows(ctx context.Context, topN int, l7Capture bool) (*Networ
 		TopTalkers:  talkers,
 		Others:      others,
 		TotalFlows:  total,
+		DNS:         sniffer.dnsStats(),
 		CollectedAt: now,
 	}, nil
 }
@@ -0,0 +1,377 @@
+//go:build linux
+
+// network_flows_dns.go is the second signal of the optional L7 capture (see
+// network_flows_l7.go): DNS responses. The same AF_PACKET sample that yields
+// TLS SNI also carries the answers to this host's lookups, which serve two
+// purposes:
+//
+//   - naming: every A/AAAA answer maps an IP to the name that was asked for,
+//     so a talker with no SNI (plain HTTP, SSH, database protocols…) is still
+//     labelled with the domain its process actually resolved;
+//   - reporting: per-cycle response and NXDOMAIN counts per queried name,
+//     the raw material of the server's "top queried domains" / NXDOMAIN rate
+//     view (misconfigured resolvers, dead dependencies, beaconing malware).
+//
+// Only UDP responses from port 53 are parsed. DNS over TCP (large answers,
+// zone transfers), DoT and DoH are not: the first is rare on the lookup path
+// and the other two are encrypted by design.
+package collector
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ipProtoUDP = 17
+	dnsPort    = 53
+	// dnsRcodeNXDomain is RFC 1035's "name error": the queried name does not
+	// exist.
+	dnsRcodeNXDomain = 3
+	dnsTypeA         = 1
+	dnsTypeAAAA      = 28
+	dnsClassIN       = 1
+	// dnsMaxNameLen is RFC 1035's limit on a name's wire length.
+	dnsMaxNameLen = 255
+	// dnsMaxPointers bounds compression-pointer chasing: a crafted message can
+	// loop pointers onto each other.
+	dnsMaxPointers = 16
+	// dnsMaxDomains bounds the per-cycle domain table, whatever the traffic.
+	dnsMaxDomains = 1000
+	// dnsTopDomains is how many domains a report carries, busiest first.
+	dnsTopDomains = 20
+	// dnsMaxDedup bounds the per-cycle set of responses already counted.
+	dnsMaxDedup = 4000
+	// dnsNameMinRetention keeps a resolved name well past a short TTL: a
+	// connection routinely outlives the record that opened it (CDN answers
+	// often carry a 20-60 s TTL), and the IP is still that name's server.
+	dnsNameMinRetention = time.Hour
+	// dnsNameMaxRetention caps a long TTL, so a reassigned IP is not named
+	// after a stale lookup for days.
+	dnsNameMaxRetention = 24 * time.Hour
+	// dnsCacheMax bounds the IP → name cache across cycles.
+	dnsCacheMax = 4096
+)
+
+// DNSQueryStats is what the capture window saw of this host's DNS lookups.
+// Counts are samples (responses captured during the window, not every lookup
+// of the cycle), so rates are meaningful, absolute volumes only relatively.
+type DNSQueryStats struct {
+	Responses  int             `json:"responses"`
+	NXDomain   int             `json:"nxdomain"`
+	TopDomains []DNSDomainStat `json:"top_domains,omitempty"`
+}
+
+// DNSDomainStat is one queried name over the capture window.
+type DNSDomainStat struct {
+	Name      string `json:"name"`
+	Responses int    `json:"responses"`
+	NXDomain  int    `json:"nxdomain"`
+}
+
+// dnsResponse is the part of a DNS response the collector uses.
+type dnsResponse struct {
+	id      uint16
+	name    string // first question, lowercased, without the trailing dot
+	rcode   int
+	answers []dnsAnswer
+}
+
+type dnsAnswer struct {
+	ip  string
+	ttl time.Duration
+}
+
+// dnsKey identifies one response within a cycle. The same response crosses
+// the wire twice when Docker forwards it to a container (once to the host,
+// once on the bridge after NAT), with the same transaction id and question.
+type dnsKey struct {
+	id    uint16
+	name  string
+	rcode int
+}
+
+// dnsCounter accumulates one cycle's DNS responses. Not safe for concurrent
+// use: sniSniffer guards it with its own mutex.
+type dnsCounter struct {
+	responses int
+	nxdomain  int
+	domains   map[string]*DNSDomainStat
+	seen      map[dnsKey]struct{}
+}
+
+func newDNSCounter() *dnsCounter {
+	return &dnsCounter{domains: map[string]*DNSDomainStat{}, seen: map[dnsKey]struct{}{}}
+}
+
+// add counts r once per cycle.
+func (c *dnsCounter) add(r dnsResponse) {
+	key := dnsKey{id: r.id, name: r.name, rcode: r.rcode}
+	if _, dup := c.seen[key]; dup {
+		return
+	}
+	if len(c.seen) < dnsMaxDedup {
+		c.seen[key] = struct{}{}
+	}
+	c.responses++
+	nx := r.rcode == dnsRcodeNXDomain
+	if nx {
+		c.nxdomain++
+	}
+	d, ok := c.domains[r.name]
+	if !ok {
+		if len(c.domains) >= dnsMaxDomains {
+			return // still in the totals, just not ranked
+		}
+		d = &DNSDomainStat{Name: r.name}
+		c.domains[r.name] = d
+	}
+	d.Responses++
+	if nx {
+		d.NXDomain++
+	}
+}
+
+// stats returns the cycle's totals and top domains, nil when nothing was seen.
+func (c *dnsCounter) stats() *DNSQueryStats {
+	if c == nil || c.responses == 0 {
+		return nil
+	}
+	top := make([]DNSDomainStat, 0, len(c.domains))
+	for _, d := range c.domains {
+		top = append(top, *d)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Responses != top[j].Responses {
+			return top[i].Responses > top[j].Responses
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > dnsTopDomains {
+		top = top[:dnsTopDomains]
+	}
+	return &DNSQueryStats{Responses: c.responses, NXDomain: c.nxdomain, TopDomains: top}
+}
+
+// dnsNameCache maps a resolved IP to the name it was looked up as, across
+// cycles: a lookup usually happens just before the connection, which may well
+// be in an earlier capture window than the one sampling its traffic.
+type dnsNameCache struct {
+	mu      sync.Mutex
+	entries map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+var resolvedNames = &dnsNameCache{entries: map[string]dnsCacheEntry{}}
+
+// remember records the answers of r. The latest lookup wins: an IP shared by
+// several names (CDN, virtual hosting) is labelled with the one most
+// recently resolved to it.
+func (c *dnsNameCache) remember(r dnsResponse, now time.Time) {
+	if len(r.answers) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, a := range r.answers {
+		if _, known := c.entries[a.ip]; !known && len(c.entries) >= dnsCacheMax {
+			c.evictExpired(now)
+			if len(c.entries) >= dnsCacheMax {
+				continue
+			}
+		}
+		ttl := min(max(a.ttl, dnsNameMinRetention), dnsNameMaxRetention)
+		c.entries[a.ip] = dnsCacheEntry{name: r.name, expires: now.Add(ttl)}
+	}
+}
+
+func (c *dnsNameCache) evictExpired(now time.Time) {
+	for ip, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, ip)
+		}
+	}
+}
+
+// lookup returns the name ip was last resolved as, if still retained.
+func (c *dnsNameCache) lookup(ip string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[ip]
+	if !ok || now.After(e.expires) {
+		return "", false
+	}
+	return e.name, true
+}
+
+// mergeResolvedNames names the outbound talkers SNI left unnamed with the
+// domain their remote IP was resolved as. Inbound talkers are left alone for
+// the same reason as in mergeServerNames: the lookup was made by this host for
+// a server it calls, not for a client calling in.
+func mergeResolvedNames(talkers []NetworkFlowTalker, cache *dnsNameCache, now time.Time) {
+	for i := range talkers {
+		if talkers[i].Direction != "outbound" || talkers[i].ServerName != "" {
+			continue
+		}
+		if name, ok := cache.lookup(talkers[i].RemoteIP, now); ok {
+			talkers[i].ServerName = name
+		}
+	}
+}
+
+// ---- Pure parser ----
+
+// parseDNSResponseFromIPPacket walks IPv4/IPv6 → UDP from port 53 → DNS
+// response. ok is false for anything else, and for responses from a loopback
+// resolver: a local stub (systemd-resolved, dnsmasq) forwards each lookup it
+// cannot answer from cache upstream, so counting both legs would report every
+// miss twice. Its upstream leg is what gets counted.
+func parseDNSResponseFromIPPacket(pkt []byte) (dnsResponse, bool) {
+	if len(pkt) < 20 {
+		return dnsResponse{}, false
+	}
+	var src net.IP
+	var proto byte
+	var rest []byte
+	switch pkt[0] >> 4 {
+	case 4:
+		ihl := int(pkt[0]&0x0f) * 4
+		if ihl < 20 || len(pkt) < ihl {
+			return dnsResponse{}, false
+		}
+		if binary.BigEndian.Uint16(pkt[6:8])&0x1fff != 0 {
+			return dnsResponse{}, false
+		}
+		proto = pkt[9]
+		src = net.IP(pkt[12:16])
+		rest = pkt[ihl:]
+	case 6:
+		if len(pkt) < 40 {
+			return dnsResponse{}, false
+		}
+		proto = pkt[6]
+		src = net.IP(pkt[8:24])
+		rest = pkt[40:]
+	default:
+		return dnsResponse{}, false
+	}
+	if proto != ipProtoUDP || len(rest) < 8 || src.IsLoopback() {
+		return dnsResponse{}, false
+	}
+	if binary.BigEndian.Uint16(rest[0:2]) != dnsPort {
+		return dnsResponse{}, false
+	}
+	return parseDNSResponse(rest[8:])
+}
+
+// parseDNSResponse decodes a DNS message's header, first question and A/AAAA
+// answers. Same contract as parseTLSClientHelloSNI: every read is checked
+// against attacker-controlled data, a malformed message returns false and
+// never panics. A snaplen-truncated answer section keeps the answers read so
+// far.
+func parseDNSResponse(msg []byte) (dnsResponse, bool) {
+	if len(msg) < 12 {
+		return dnsResponse{}, false
+	}
+	flags := binary.BigEndian.Uint16(msg[2:4])
+	if flags&0x8000 == 0 || (flags>>11)&0x0f != 0 { // QR=response, opcode QUERY
+		return dnsResponse{}, false
+	}
+	qdCount := binary.BigEndian.Uint16(msg[4:6])
+	anCount := int(binary.BigEndian.Uint16(msg[6:8]))
+	if qdCount == 0 {
+		return dnsResponse{}, false
+	}
+	name, off, ok := readDNSName(msg, 12)
+	if !ok || name == "" || off+4 > len(msg) {
+		return dnsResponse{}, false
+	}
+	off += 4 // qtype + qclass
+	// Only the first question is used (every resolver sends exactly one); any
+	// other is skipped so the answers can be found.
+	for i := 1; i < int(qdCount); i++ {
+		if _, off, ok = readDNSName(msg, off); !ok || off+4 > len(msg) {
+			return dnsResponse{}, false
+		}
+		off += 4
+	}
+
+	r := dnsResponse{id: binary.BigEndian.Uint16(msg[0:2]), name: name, rcode: int(flags & 0x0f)}
+	for i := 0; i < anCount; i++ {
+		if _, off, ok = readDNSName(msg, off); !ok || off+10 > len(msg) {
+			break
+		}
+		rrType := binary.BigEndian.Uint16(msg[off : off+2])
+		rrClass := binary.BigEndian.Uint16(msg[off+2 : off+4])
+		ttl := binary.BigEndian.Uint32(msg[off+4 : off+8])
+		rdLen := int(binary.BigEndian.Uint16(msg[off+8 : off+10]))
+		off += 10
+		if off+rdLen > len(msg) {
+			break
+		}
+		rdata := msg[off : off+rdLen]
+		off += rdLen
+		if rrClass != dnsClassIN {
+			continue
+		}
+		// CNAME records in between are skipped: the address is named after the
+		// question, i.e. what the process asked for, not the CDN's alias.
+		if (rrType == dnsTypeA && rdLen == 4) || (rrType == dnsTypeAAAA && rdLen == 16) {
+			r.answers = append(r.answers, dnsAnswer{
+				ip:  net.IP(rdata).String(),
+				ttl: time.Duration(ttl) * time.Second,
+			})
+		}
+	}
+	return r, true
+}
+
+// readDNSName decodes the (possibly compressed) name at msg[off:], returning
+// it lowercased without the trailing dot, and the offset just past it in the
+// original position.
+func readDNSName(msg []byte, off int) (string, int, bool) {
+	var b strings.Builder
+	next := -1
+	pointers := 0
+	for {
+		if off >= len(msg) {
+			return "", 0, false
+		}
+		l := int(msg[off])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.ToLower(b.String()), next, true
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(msg) || pointers >= dnsMaxPointers {
+				return "", 0, false
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:off+2]) & 0x3fff)
+			pointers++
+		case l&0xc0 != 0:
+			return "", 0, false // reserved label types
+		default:
+			if off+1+l > len(msg) || b.Len()+l+1 > dnsMaxNameLen {
+				return "", 0, false
+			}
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.Write(msg[off+1 : off+1+l])
+			off += 1 + l
+		}
+	}
+}
@@ -0,0 +1,226 @@
+//go:build linux
+
+package collector
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---- Test packet builders ----
+
+type testRR struct {
+	rrType uint16
+	ttl    uint32
+	rdata  []byte
+}
+
+func encodeDNSName(name string) []byte {
+	var b []byte
+	for _, label := range strings.Split(name, ".") {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+// buildDNSResponse assembles a response to one A question whose answers all
+// point back at the question name (0xc00c), the way resolvers compress them.
+func buildDNSResponse(id uint16, name string, rcode int, answers ...testRR) []byte {
+	h := make([]byte, 12)
+	binary.BigEndian.PutUint16(h[0:2], id)
+	binary.BigEndian.PutUint16(h[2:4], 0x8180|uint16(rcode)) // QR, RD, RA
+	binary.BigEndian.PutUint16(h[4:6], 1)
+	binary.BigEndian.PutUint16(h[6:8], uint16(len(answers)))
+	msg := append(h, encodeDNSName(name)...)
+	msg = append(msg, 0x00, dnsTypeA, 0x00, dnsClassIN)
+	for _, a := range answers {
+		rr := []byte{0xc0, 0x0c, byte(a.rrType >> 8), byte(a.rrType), 0x00, dnsClassIN}
+		rr = binary.BigEndian.AppendUint32(rr, a.ttl)
+		rr = binary.BigEndian.AppendUint16(rr, uint16(len(a.rdata)))
+		msg = append(msg, append(rr, a.rdata...)...)
+	}
+	return msg
+}
+
+func buildUDP(srcPort, dstPort int, payload []byte) []byte {
+	h := make([]byte, 8)
+	binary.BigEndian.PutUint16(h[0:2], uint16(srcPort))
+	binary.BigEndian.PutUint16(h[2:4], uint16(dstPort))
+	binary.BigEndian.PutUint16(h[4:6], uint16(8+len(payload)))
+	return append(h, payload...)
+}
+
+func dnsPacketV4(resolver, client string, msg []byte) []byte {
+	return buildIPv4(resolver, client, ipProtoUDP, buildUDP(dnsPort, 40000, msg))
+}
+
+func aRecord(ip string, ttl uint32) testRR {
+	return testRR{rrType: dnsTypeA, ttl: ttl, rdata: net.ParseIP(ip).To4()}
+}
+
+// ---- Parser tests ----
+
+func TestParseDNSResponseFromIPPacket(t *testing.T) {
+	t.Run("A and AAAA answers are named after the question", func(t *testing.T) {
+		msg := buildDNSResponse(0x1234, "Api.Example.COM", 0,
+			testRR{rrType: 5, ttl: 60, rdata: encodeDNSName("edge.cdn.test")}, // CNAME
+			aRecord("93.184.216.34", 300),
+			testRR{rrType: dnsTypeAAAA, ttl: 30, rdata: net.ParseIP("2606:2800::1")},
+		)
+		r, ok := parseDNSResponseFromIPPacket(dnsPacketV4("9.9.9.9", "10.0.0.5", msg))
+		if !ok {
+			t.Fatal("expected a parse")
+		}
+		if r.id != 0x1234 || r.name != "api.example.com" || r.rcode != 0 {
+			t.Fatalf("got %+v", r)
+		}
+		if len(r.answers) != 2 || r.answers[0].ip != "93.184.216.34" || r.answers[0].ttl != 300*time.Second ||
+			r.answers[1].ip != "2606:2800::1" {
+			t.Fatalf("answers = %+v", r.answers)
+		}
+	})
+
+	t.Run("IPv6 NXDOMAIN", func(t *testing.T) {
+		msg := buildDNSResponse(7, "nope.invalid", dnsRcodeNXDomain)
+		r, ok := parseDNSResponseFromIPPacket(buildIPv6("2001:db8::53", "2001:db8::2", ipProtoUDP, buildUDP(dnsPort, 40000, msg)))
+		if !ok || r.rcode != dnsRcodeNXDomain || r.name != "nope.invalid" || len(r.answers) != 0 {
+			t.Fatalf("got (%+v, %v)", r, ok)
+		}
+	})
+
+	t.Run("non-responses are ignored", func(t *testing.T) {
+		query := buildDNSResponse(1, "example.com", 0)
+		query[2] &^= 0x80 // QR=0
+		valid := buildDNSResponse(1, "example.com", 0, aRecord("1.2.3.4", 60))
+		cases := map[string][]byte{
+			"a query":             dnsPacketV4("10.0.0.5", "9.9.9.9", query),
+			"not from port 53":    buildIPv4("9.9.9.9", "10.0.0.5", ipProtoUDP, buildUDP(5353, 40000, valid)),
+			"TCP":                 buildIPv4("9.9.9.9", "10.0.0.5", ipProtoTCP, buildTCP(dnsPort, 40000, valid)),
+			"a loopback resolver": dnsPacketV4("127.0.0.53", "127.0.0.1", valid),
+			"too short":           {0x45, 0x00},
+		}
+		for name, pkt := range cases {
+			if _, ok := parseDNSResponseFromIPPacket(pkt); ok {
+				t.Errorf("%s: expected no parse", name)
+			}
+		}
+	})
+
+	t.Run("malformed messages are rejected without panicking", func(t *testing.T) {
+		full := buildDNSResponse(1, "truncation.test", 0, aRecord("1.2.3.4", 60), aRecord("5.6.7.8", 60))
+		for i := 0; i < len(full); i++ {
+			_, _ = parseDNSResponse(full[:i]) // must not panic
+		}
+		// A truncated answer section keeps what was read.
+		if r, ok := parseDNSResponse(full[:len(full)-2]); !ok || len(r.answers) != 1 {
+			t.Errorf("truncated answers: got (%+v, %v)", r, ok)
+		}
+
+		loop := buildDNSResponse(1, "x", 0)
+		loop = append(loop[:12], 0xc0, 0x0c) // the question name points at itself
+		if _, ok := parseDNSResponse(loop); ok {
+			t.Error("a pointer loop must not parse")
+		}
+	})
+}
+
+// ---- Counting and naming ----
+
+func TestDNSCounterStats(t *testing.T) {
+	c := newDNSCounter()
+	if c.stats() != nil {
+		t.Fatal("an empty counter has no stats")
+	}
+	c.add(dnsResponse{id: 1, name: "a.test"})
+	c.add(dnsResponse{id: 1, name: "a.test"}) // the same response forwarded to a container
+	c.add(dnsResponse{id: 2, name: "a.test"})
+	c.add(dnsResponse{id: 3, name: "b.test", rcode: dnsRcodeNXDomain})
+	c.add(dnsResponse{id: 4, name: "b.test", rcode: dnsRcodeNXDomain})
+	c.add(dnsResponse{id: 5, name: "c.test"})
+
+	s := c.stats()
+	if s.Responses != 5 || s.NXDomain != 2 || len(s.TopDomains) != 3 {
+		t.Fatalf("stats = %+v", s)
+	}
+	want := []DNSDomainStat{{"a.test", 2, 0}, {"b.test", 2, 2}, {"c.test", 1, 0}}
+	for i, w := range want {
+		if s.TopDomains[i] != w {
+			t.Errorf("TopDomains[%d] = %+v, want %+v", i, s.TopDomains[i], w)
+		}
+	}
+}
+
+func TestResolvedNames(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cache := &dnsNameCache{entries: map[string]dnsCacheEntry{}}
+	cache.remember(dnsResponse{name: "short.test", answers: []dnsAnswer{{ip: "1.1.1.1", ttl: 20 * time.Second}}}, now)
+	cache.remember(dnsResponse{name: "long.test", answers: []dnsAnswer{{ip: "2.2.2.2", ttl: 7 * 24 * time.Hour}}}, now)
+
+	if name, ok := cache.lookup("1.1.1.1", now.Add(30*time.Minute)); !ok || name != "short.test" {
+		t.Errorf("a short TTL should still name the IP within %s, got (%q, %v)", dnsNameMinRetention, name, ok)
+	}
+	if _, ok := cache.lookup("2.2.2.2", now.Add(25*time.Hour)); ok {
+		t.Errorf("a long TTL should be capped at %s", dnsNameMaxRetention)
+	}
+
+	talkers := []NetworkFlowTalker{
+		{RemoteIP: "1.1.1.1", RemotePort: 80, Direction: "outbound"},
+		{RemoteIP: "1.1.1.1", RemotePort: 443, Direction: "outbound", ServerName: "sni.test"},
+		{RemoteIP: "1.1.1.1", RemotePort: 51000, Direction: "inbound"},
+		{RemoteIP: "3.3.3.3", RemotePort: 80, Direction: "outbound"},
+	}
+	mergeResolvedNames(talkers, cache, now)
+	want := []string{"short.test", "sni.test", "", ""}
+	for i, w := range want {
+		if talkers[i].ServerName != w {
+			t.Errorf("talkers[%d].ServerName = %q, want %q", i, talkers[i].ServerName, w)
+		}
+	}
+}
+
+func TestSniSnifferCaptureCountsDNS(t *testing.T) {
+	src := &fakeSource{packets: [][]byte{
+		dnsPacketV4("9.9.9.9", "10.0.0.5", buildDNSResponse(10, "capture.test", 0, aRecord("198.51.100.7", 60))),
+		tlsPacketV4("10.0.0.5", "198.51.100.7", 443, "capture.test"),
+		dnsPacketV4("9.9.9.9", "10.0.0.5", buildDNSResponse(11, "typo.test", dnsRcodeNXDomain)),
+	}}
+	s := &sniSniffer{names: map[string]string{}, dns: newDNSCounter(), done: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(s.done)
+		s.capture(ctx, src)
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		s.mu.Lock()
+		n := s.dns.responses
+		s.mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for DNS responses (got %d)", n)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-s.done
+
+	stats := s.dnsStats()
+	if stats == nil || stats.Responses != 2 || stats.NXDomain != 1 {
+		t.Fatalf("dnsStats = %+v", stats)
+	}
+	if name, ok := resolvedNames.lookup("198.51.100.7", time.Now()); !ok || name != "capture.test" {
+		t.Errorf("resolvedNames[198.51.100.7] = (%q, %v)", name, ok)
+	}
+	if (*sniSniffer)(nil).dnsStats() != nil {
+		t.Error("a nil sniffer has no DNS stats")
+	}
+}
@@ -2,14 +2,14 @@
 
 // network_flows_l7.go adds an *optional*, off-by-default application-layer
 // signal to the conntrack-derived top-talkers collector: the TLS SNI hostname a
-// flow's ClientHello asked for. Enabled with the agent's
-// `network_flows_l7_capture` config key.
+// flow's ClientHello asked for, and the DNS responses this host received (see
+// network_flows_dns.go). Enabled with the agent's `network_flows_l7_capture`
+// config key.
 //
-// Scope, deliberately narrow — see the "maturity" note in the feature's report:
-// TLS ClientHello SNI only. DNS query names and HTTP Host headers are the
-// obvious next signals and are explicitly *not* implemented here; they would
-// each need their own parser plus (for HTTP) cross-segment reassembly, and
-// shipping one well-tested signal beats three shaky ones.
+// Scope, deliberately narrow: TLS ClientHello SNI and UDP DNS responses. HTTP
+// Host headers are the obvious next signal and are explicitly *not*
+// implemented here; they would need cross-segment reassembly, and shipping
+// well-tested signals beats shaky ones.
 //
 // # Why no new dependency
 //
@@ -82,11 +82,13 @@ type packetSource interface {
 	Close() error
 }
 
-// sniSniffer accumulates observed "remote endpoint → SNI hostname" pairs for
-// one collection cycle.
+// sniSniffer accumulates observed "remote endpoint → SNI hostname" pairs and
+// DNS responses for one collection cycle. Resolved addresses go straight to
+// the cross-cycle resolvedNames cache; dns only counts (nil: not counted).
 type sniSniffer struct {
 	mu     sync.Mutex
 	names  map[string]string
+	dns    *dnsCounter
 	done   chan struct{}
 	cancel context.CancelFunc
 }
@@ -139,6 +141,7 @@ func startSNISniffer(ctx context.Context, enabled bool) *sniSniffer {
 	captureCtx, cancel := context.WithTimeout(ctx, sniCaptureWindow)
 	s := &sniSniffer{
 		names:  map[string]string{},
+		dns:    newDNSCounter(),
 		done:   make(chan struct{}),
 		cancel: cancel,
 	}
@@ -153,8 +156,8 @@ func startSNISniffer(ctx context.Context, enabled bool) *sniSniffer {
 
 // capture reads until the window closes, the packet cap is hit, or the source
 // fails. Every parse failure is silent by design — an unfiltered socket sees
-// ARP, DNS, VXLAN and every other non-TLS packet on the wire, so "this isn't a
-// ClientHello" is the overwhelmingly common case, not an error.
+// ARP, VXLAN and every other non-TLS, non-DNS packet on the wire, so "this
+// isn't a ClientHello" is the overwhelmingly common case, not an error.
 func (s *sniSniffer) capture(ctx context.Context, src packetSource) {
 	for i := 0; i < sniMaxPackets; i++ {
 		if ctx.Err() != nil {
@@ -172,6 +175,14 @@ func (s *sniSniffer) capture(ctx context.Context, src packetSource) {
 		}
 		ip, port, name, ok := parseSNIFromIPPacket(pkt)
 		if !ok {
+			if r, isDNS := parseDNSResponseFromIPPacket(pkt); isDNS {
+				resolvedNames.remember(r, time.Now())
+				if s.dns != nil {
+					s.mu.Lock()
+					s.dns.add(r)
+					s.mu.Unlock()
+				}
+			}
 			continue
 		}
 		s.mu.Lock()
@@ -206,6 +217,17 @@ func stopSNISniffer(s *sniSniffer) map[string]string {
 	return out
 }
 
+// dnsStats returns the DNS responses counted by a stopped sniffer, nil when
+// there were none. Nil-safe, like stopSNISniffer.
+func (s *sniSniffer) dnsStats() *DNSQueryStats {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dns.stats()
+}
+
 // mergeServerNames stamps observed SNI hostnames onto matching talkers, in
 // place. Outbound only: SNI travels client→server, so the ClientHello's
 // destination is the *remote peer* only for a connection this host initiated.
@@ -16,6 +16,7 @@ type NetworkFlowsReport struct {
 	TopTalkers  []NetworkFlowTalker `json:"top_talkers,omitempty"`
 	Others      *NetworkFlowBucket  `json:"others,omitempty"`
 	TotalFlows  int                 `json:"total_flows"`
+	DNS         *DNSQueryStats      `json:"dns,omitempty"`
 	CollectedAt time.Time           `json:"collected_at"`
 }
 
@@ -33,6 +34,18 @@ type NetworkFlowTalker struct {
 	Connections int    `json:"connections"`
 }
 
+type DNSQueryStats struct {
+	Responses  int             `json:"responses"`
+	NXDomain   int             `json:"nxdomain"`
+	TopDomains []DNSDomainStat `json:"top_domains,omitempty"`
+}
+
+type DNSDomainStat struct {
+	Name      string `json:"name"`
+	Responses int    `json:"responses"`
+	NXDomain  int    `json:"nxdomain"`
+}
+
 type NetworkFlowBucket struct {
 	Connections int    `json:"connections"`
 	RxBytes     uint64 `json:"rx_bytes"`
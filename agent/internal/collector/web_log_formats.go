@@ -0,0 +1,478 @@
+package collector
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/agent/internal/config"
+)
+
+// clfTimeLayout is the [time_local] layout shared by nginx, Apache, NPM and
+// Traefik's CLF output.
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// webLogParser turns one access log line into a parsedLine.
+type webLogParser func(line string) (parsedLine, bool)
+
+type webLogFormatRule struct {
+	glob  string
+	parse webLogParser
+}
+
+// buildWebLogParsers compiles the web_logs_formats rules. config.Load has
+// already validated them; a rule that still fails to build is skipped so the
+// files it covers fall back to auto-detection.
+func buildWebLogParsers(formats []config.WebLogFormat) []webLogFormatRule {
+	rules := make([]webLogFormatRule, 0, len(formats))
+	for _, f := range formats {
+		var parse webLogParser
+		switch f.Format {
+		case config.WebLogFormatAuto:
+			parse = parseAccessLine
+		case config.WebLogFormatCombined:
+			parse = parseCombinedLine
+		case config.WebLogFormatNginxTimed:
+			parse = parseNginxTimedLine
+		case config.WebLogFormatCaddyJSON:
+			parse = parseCaddyJSONLine
+		case config.WebLogFormatTraefikJSON:
+			parse = parseTraefikJSONLine
+		case config.WebLogFormatTraefikCLF:
+			parse = parseTraefikCLFLine
+		case config.WebLogFormatHAProxy:
+			parse = parseHAProxyLine
+		case config.WebLogFormatRegex:
+			p, err := newRegexWebLogParser(f.Pattern, f.TimeLayout)
+			if err != nil {
+				slog.Warn("web_logs: ignoring invalid regex format", "path", f.Path, "err", err)
+				continue
+			}
+			parse = p
+		default:
+			continue
+		}
+		rules = append(rules, webLogFormatRule{glob: f.Path, parse: parse})
+	}
+	return rules
+}
+
+// parserForFile returns the parser of the first rule whose glob matches file,
+// or auto-detection when none does.
+func parserForFile(rules []webLogFormatRule, file string) webLogParser {
+	for _, r := range rules {
+		if r.glob == file {
+			return r.parse
+		}
+		if ok, _ := filepath.Match(r.glob, file); ok {
+			return r.parse
+		}
+	}
+	return parseAccessLine
+}
+
+func parseCombinedLine(line string) (parsedLine, bool) {
+	m := commonAccessLogRegex.FindStringSubmatch(line)
+	if len(m) == 0 {
+		return parsedLine{}, false
+	}
+	return parsedLine{
+		timestamp: parseTimeOrNow([]string{m[2]}, []string{clfTimeLayout}),
+		ip:        strings.TrimSpace(m[1]),
+		method:    strings.ToUpper(m[3]),
+		path:      cleanPath(m[4]),
+		query:     rawQuery(m[4]),
+		status:    atoiOrZero(m[5]),
+		bytes:     parseBytes(m[6]),
+		ua:        strings.TrimSpace(m[7]),
+		domain:    "(unknown)",
+		source:    "nginx",
+	}, true
+}
+
+// nginxTimedLogRegex matches the combined format followed by
+// $request_time $upstream_response_time [$upstream_status [$host]]. Upstream
+// values list every upstream tried ("0.002, 0.010"), "-" when none was.
+var nginxTimedLogRegex = regexp.MustCompile(
+	`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) ([^\s"]+)[^"]*" (\d{3}) (\d+|-) "[^"]*" "([^"]*)" ([\d.]+|-) ((?:[\d.]+|-)(?:, (?:[\d.]+|-))*)(?: ((?:\d{3}|-)(?:, (?:\d{3}|-))*))?(?: (\S+))?\s*$`,
+)
+
+func parseNginxTimedLine(line string) (parsedLine, bool) {
+	m := nginxTimedLogRegex.FindStringSubmatch(line)
+	if len(m) == 0 {
+		return parsedLine{}, false
+	}
+	domain := m[11]
+	if domain == "" || domain == "-" {
+		domain = "(unknown)"
+	}
+	return parsedLine{
+		timestamp:      parseTimeOrNow([]string{m[2]}, []string{clfTimeLayout}),
+		ip:             strings.TrimSpace(m[1]),
+		method:         strings.ToUpper(m[3]),
+		path:           cleanPath(m[4]),
+		query:          rawQuery(m[4]),
+		status:         atoiOrZero(m[5]),
+		bytes:          parseBytes(m[6]),
+		ua:             strings.TrimSpace(m[7]),
+		domain:         domain,
+		source:         "nginx",
+		responseTimeMs: secondsToMs(m[8]),
+		upstreamTimeMs: sumUpstreamSeconds(m[9]),
+		upstreamStatus: lastUpstreamStatus(m[10]),
+	}, true
+}
+
+// caddyAccessLog is the subset of Caddy's JSON access log (logger
+// http.log.access) that is reported.
+type caddyAccessLog struct {
+	TS      json.RawMessage `json:"ts"`
+	Request struct {
+		RemoteIP string              `json:"remote_ip"`
+		ClientIP string              `json:"client_ip"`
+		Method   string              `json:"method"`
+		Host     string              `json:"host"`
+		URI      string              `json:"uri"`
+		Headers  map[string][]string `json:"headers"`
+	} `json:"request"`
+	Duration json.RawMessage `json:"duration"`
+	Size     int64           `json:"size"`
+	Status   int             `json:"status"`
+}
+
+func parseCaddyJSONLine(line string) (parsedLine, bool) {
+	var e caddyAccessLog
+	if err := json.Unmarshal([]byte(line), &e); err != nil || e.Status == 0 || e.Request.Method == "" {
+		return parsedLine{}, false
+	}
+	ip := e.Request.ClientIP
+	if ip == "" {
+		ip = e.Request.RemoteIP
+	}
+	ua := ""
+	if v := e.Request.Headers["User-Agent"]; len(v) > 0 {
+		ua = v[0]
+	}
+	domain := e.Request.Host
+	if h, _, ok := strings.Cut(domain, ":"); ok && !strings.Contains(h, "]") {
+		domain = h
+	}
+	if domain == "" {
+		domain = "(unknown)"
+	}
+	return parsedLine{
+		timestamp:      caddyTime(e.TS),
+		ip:             ip,
+		method:         strings.ToUpper(e.Request.Method),
+		path:           cleanPath(e.Request.URI),
+		query:          rawQuery(e.Request.URI),
+		status:         e.Status,
+		bytes:          e.Size,
+		ua:             ua,
+		domain:         domain,
+		source:         "caddy",
+		responseTimeMs: caddyDuration(e.Duration),
+	}, true
+}
+
+// caddyTime reads ts as Caddy's default unix seconds float, or as a string
+// when time_format is set in the log encoder.
+func caddyTime(raw json.RawMessage) time.Time {
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil && f > 0 {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC()
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseTimeOrNow([]string{s}, []string{time.RFC3339Nano, clfTimeLayout})
+	}
+	return time.Now().UTC()
+}
+
+// caddyDuration reads duration as seconds (default) or as a Go duration
+// string (duration_format: string).
+func caddyDuration(raw json.RawMessage) *float64 {
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return msPtr(f * 1000)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if d, err := time.ParseDuration(s); err == nil {
+			return msPtr(float64(d) / float64(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// traefikAccessLog is the subset of Traefik's JSON access log that is
+// reported. Durations are nanoseconds; the User-Agent is only present when
+// accessLog.fields.headers keeps it.
+type traefikAccessLog struct {
+	ClientHost            string `json:"ClientHost"`
+	DownstreamContentSize int64  `json:"DownstreamContentSize"`
+	DownstreamStatus      int    `json:"DownstreamStatus"`
+	Duration              *int64 `json:"Duration"`
+	OriginDuration        *int64 `json:"OriginDuration"`
+	OriginStatus          int    `json:"OriginStatus"`
+	RequestHost           string `json:"RequestHost"`
+	RequestMethod         string `json:"RequestMethod"`
+	RequestPath           string `json:"RequestPath"`
+	ServiceName           string `json:"ServiceName"`
+	StartUTC              string `json:"StartUTC"`
+	UserAgent             string `json:"request_User-Agent"`
+}
+
+func parseTraefikJSONLine(line string) (parsedLine, bool) {
+	var e traefikAccessLog
+	if err := json.Unmarshal([]byte(line), &e); err != nil || e.DownstreamStatus == 0 || e.RequestMethod == "" {
+		return parsedLine{}, false
+	}
+	domain := e.RequestHost
+	if domain == "" {
+		domain = "(unknown)"
+	}
+	return parsedLine{
+		timestamp:      parseTimeOrNow([]string{e.StartUTC}, []string{time.RFC3339Nano}),
+		ip:             e.ClientHost,
+		method:         strings.ToUpper(e.RequestMethod),
+		path:           cleanPath(e.RequestPath),
+		query:          rawQuery(e.RequestPath),
+		status:         e.DownstreamStatus,
+		bytes:          e.DownstreamContentSize,
+		ua:             e.UserAgent,
+		domain:         domain,
+		source:         "traefik",
+		responseTimeMs: nsToMs(e.Duration),
+		upstreamTimeMs: nsToMs(e.OriginDuration),
+		upstreamStatus: e.OriginStatus,
+		upstream:       e.ServiceName,
+	}, true
+}
+
+// traefikCLFLogRegex matches Traefik's common log format: combined, then the
+// request count, "router" "server URL" and the duration in ms.
+var traefikCLFLogRegex = regexp.MustCompile(
+	`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) ([^\s"]+)[^"]*" (\d{3}) (\d+|-) "[^"]*" "([^"]*)" \d+ "([^"]*)" "([^"]*)" (\d+)ms`,
+)
+
+func parseTraefikCLFLine(line string) (parsedLine, bool) {
+	m := traefikCLFLogRegex.FindStringSubmatch(line)
+	if len(m) == 0 {
+		return parsedLine{}, false
+	}
+	ms, _ := strconv.ParseFloat(m[10], 64)
+	upstream := m[9]
+	if upstream == "-" {
+		upstream = ""
+	}
+	return parsedLine{
+		timestamp:      parseTimeOrNow([]string{m[2]}, []string{clfTimeLayout}),
+		ip:             strings.TrimSpace(m[1]),
+		method:         strings.ToUpper(m[3]),
+		path:           cleanPath(m[4]),
+		query:          rawQuery(m[4]),
+		status:         atoiOrZero(m[5]),
+		bytes:          parseBytes(m[6]),
+		ua:             strings.TrimSpace(m[7]),
+		domain:         "(unknown)",
+		source:         "traefik",
+		responseTimeMs: msPtr(ms),
+		upstream:       upstream,
+	}, true
+}
+
+// haproxyHTTPLogRegex matches HAProxy's "option httplog" format (syslog
+// prefix optional): client, accept date, frontend, backend/server,
+// TR/Tw/Tc/Tr/Ta timers, status, bytes, then the optional captured request
+// and response headers and the request line.
+var haproxyHTTPLogRegex = regexp.MustCompile(
+	`(\S+):\d+ \[([^\]]+)\] \S+ (\S+)/(\S+) -?\d+/-?\d+/-?\d+/(-?\d+)/\+?(-?\d+) (\d{3}) \+?(\d+) \S+ \S+ \S+ \S+ \S+(?: \{([^}]*)\})?(?: \{[^}]*\})? "(\S+) (\S+)`,
+)
+
+// parseHAProxyLine reads the host and User-Agent from the first captured
+// request headers ("capture request header Host", then "User-Agent").
+func parseHAProxyLine(line string) (parsedLine, bool) {
+	m := haproxyHTTPLogRegex.FindStringSubmatch(line)
+	if len(m) == 0 {
+		return parsedLine{}, false
+	}
+	domain, ua := "(unknown)", ""
+	if m[9] != "" {
+		headers := strings.Split(m[9], "|")
+		if h := strings.TrimSpace(headers[0]); h != "" {
+			domain, _, _ = strings.Cut(h, ":")
+		}
+		if len(headers) > 1 {
+			ua = strings.TrimSpace(headers[1])
+		}
+	}
+	ts, err := time.ParseInLocation("02/Jan/2006:15:04:05.000", m[2], time.Local)
+	if err != nil {
+		ts = time.Now()
+	}
+	e := parsedLine{
+		timestamp: ts.UTC(),
+		ip:        m[1],
+		method:    strings.ToUpper(m[10]),
+		path:      cleanPath(m[11]),
+		query:     rawQuery(m[11]),
+		status:    atoiOrZero(m[7]),
+		bytes:     parseBytes(m[8]),
+		ua:        ua,
+		domain:    domain,
+		source:    "haproxy",
+		upstream:  m[3] + "/" + m[4],
+	}
+	// -1 means the timer never ran (aborted request, no server); 0 is a
+	// fast response.
+	if ta := atoiOrZero(m[6]); ta >= 0 {
+		e.responseTimeMs = msPtr(float64(ta))
+	}
+	if tr := atoiOrZero(m[5]); tr >= 0 {
+		e.upstreamTimeMs = msPtr(float64(tr))
+	}
+	if m[4] == "<NOSRV>" {
+		e.upstream = m[3]
+	}
+	return e, true
+}
+
+// newRegexWebLogParser builds a parser from a pattern with named groups
+// (see config.WebLogFormat). Times use timeLayout, else CLF or RFC 3339.
+func newRegexWebLogParser(pattern, timeLayout string) (webLogParser, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	layouts := []string{clfTimeLayout, time.RFC3339Nano}
+	if timeLayout != "" {
+		layouts = []string{timeLayout}
+	}
+	names := re.SubexpNames()
+	return func(line string) (parsedLine, bool) {
+		m := re.FindStringSubmatch(line)
+		if len(m) == 0 {
+			return parsedLine{}, false
+		}
+		g := make(map[string]string, len(names))
+		for i, name := range names {
+			if name != "" && m[i] != "" {
+				g[name] = m[i]
+			}
+		}
+		method, path := g["method"], g["path"]
+		if req := g["request"]; req != "" {
+			fields := strings.Fields(req)
+			if len(fields) >= 2 {
+				method, path = fields[0], fields[1]
+			}
+		}
+		status := atoiOrZero(g["status"])
+		if status == 0 || path == "" {
+			return parsedLine{}, false
+		}
+		e := parsedLine{
+			timestamp:      time.Now().UTC(),
+			ip:             g["ip"],
+			method:         strings.ToUpper(method),
+			path:           cleanPath(path),
+			query:          rawQuery(path),
+			status:         status,
+			bytes:          parseBytes(g["bytes"]),
+			ua:             g["user_agent"],
+			domain:         g["domain"],
+			source:         "custom",
+			upstreamStatus: lastUpstreamStatus(g["upstream_status"]),
+			upstream:       g["upstream"],
+		}
+		if t := g["time"]; t != "" {
+			e.timestamp = parseTimeOrNow([]string{t}, layouts)
+		}
+		if e.domain == "" {
+			e.domain = "(unknown)"
+		}
+		if v, ok := g["response_time_ms"]; ok {
+			e.responseTimeMs = parseMs(v)
+		} else {
+			e.responseTimeMs = secondsToMs(g["response_time"])
+		}
+		if v, ok := g["upstream_time_ms"]; ok {
+			e.upstreamTimeMs = parseMs(v)
+		} else {
+			e.upstreamTimeMs = sumUpstreamSeconds(g["upstream_time"])
+		}
+		return e, true
+	}, nil
+}
+
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// parseBytes reads a byte count, "-" (nothing sent) being 0.
+func parseBytes(s string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return n
+}
+
+// secondsToMs converts a time logged in seconds to ms, nil when the field is
+// empty or "-".
+func secondsToMs(s string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	return msPtr(f * 1000)
+}
+
+// parseMs reads a time already in ms, nil when the field is empty or "-".
+func parseMs(s string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	return msPtr(f)
+}
+
+// nsToMs converts an optional duration in nanoseconds.
+func nsToMs(ns *int64) *float64 {
+	if ns == nil {
+		return nil
+	}
+	return msPtr(float64(*ns) / 1e6)
+}
+
+// msPtr returns a pointer to a timing: a timing is present (and may be 0, a
+// response faster than the log's resolution) or nil when not logged.
+func msPtr(ms float64) *float64 {
+	return &ms
+}
+
+// sumUpstreamSeconds adds up nginx's comma-separated $upstream_response_time
+// (one value per upstream tried), in ms. nil when no upstream was timed ("-").
+func sumUpstreamSeconds(s string) *float64 {
+	var total *float64
+	for _, part := range strings.Split(s, ",") {
+		if ms := secondsToMs(part); ms != nil {
+			if total == nil {
+				total = msPtr(0)
+			}
+			*total += *ms
+		}
+	}
+	return total
+}
+
+// lastUpstreamStatus returns the status of the last upstream tried, which is
+// the one whose response was sent.
+func lastUpstreamStatus(s string) int {
+	parts := strings.Split(s, ",")
+	return atoiOrZero(parts[len(parts)-1])
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/serversupervisor/agent/internal/config"
+)
+
+// ms dereferences a parsed timing, -1 when it was not logged.
+func ms(p *float64) float64 {
+	if p == nil {
+		return -1
+	}
+	return *p
+}
+
+func TestParseNginxTimedLine(t *testing.T) {
+	line := `1.2.3.4 - - [09/May/2024:12:34:56 +0000] "GET /api/items?id=3 HTTP/1.1" 200 512 "-" "curl/8" 0.120 0.050, 0.060 502, 200 shop.example.com`
+	got, ok := parseNginxTimedLine(line)
+	if !ok {
+		t.Fatal("expected nginx_timed line to parse")
+	}
+	if got.path != "/api/items" || got.query != "id=3" || got.status != 200 || got.domain != "shop.example.com" {
+		t.Errorf("got path=%q query=%q status=%d domain=%q", got.path, got.query, got.status, got.domain)
+	}
+	if ms(got.responseTimeMs) != 120 || ms(got.upstreamTimeMs) < 109.9 || ms(got.upstreamTimeMs) > 110.1 || got.upstreamStatus != 200 {
+		t.Errorf("timing = %v / %v / %d, want 120 / 110 / 200", ms(got.responseTimeMs), ms(got.upstreamTimeMs), got.upstreamStatus)
+	}
+
+	// Without upstream status nor host, and no upstream reached.
+	got, ok = parseNginxTimedLine(`1.2.3.4 - - [09/May/2024:12:34:56 +0000] "GET / HTTP/1.1" 404 0 "-" "curl/8" 0.001 -`)
+	if !ok || got.upstreamTimeMs != nil || got.upstreamStatus != 0 || got.domain != "(unknown)" {
+		t.Errorf("short line: ok=%v got=%+v", ok, got)
+	}
+
+	// A response faster than the log's resolution is timed at 0, not untimed.
+	got, ok = parseNginxTimedLine(`1.2.3.4 - - [09/May/2024:12:34:56 +0000] "GET / HTTP/1.1" 200 0 "-" "curl/8" 0.000 0.000`)
+	if !ok || ms(got.responseTimeMs) != 0 || ms(got.upstreamTimeMs) != 0 {
+		t.Errorf("zero timing = %v / %v, want 0 / 0", ms(got.responseTimeMs), ms(got.upstreamTimeMs))
+	}
+}
+
+func TestParseCaddyJSONLine(t *testing.T) {
+	line := `{"level":"info","ts":1715258096.5,"logger":"http.log.access","msg":"handled request","request":{"remote_ip":"10.0.0.1","client_ip":"1.2.3.4","proto":"HTTP/2.0","method":"get","host":"example.com:443","uri":"/login?next=/","headers":{"User-Agent":["Mozilla/5.0"]}},"duration":0.0042,"size":1024,"status":302}`
+	got, ok := parseCaddyJSONLine(line)
+	if !ok {
+		t.Fatal("expected caddy line to parse")
+	}
+	if got.ip != "1.2.3.4" || got.method != "GET" || got.path != "/login" || got.domain != "example.com" {
+		t.Errorf("got ip=%q method=%q path=%q domain=%q", got.ip, got.method, got.path, got.domain)
+	}
+	if got.status != 302 || got.bytes != 1024 || got.ua != "Mozilla/5.0" || got.source != "caddy" {
+		t.Errorf("got %+v", got)
+	}
+	if ms(got.responseTimeMs) < 4.19 || ms(got.responseTimeMs) > 4.21 {
+		t.Errorf("responseTimeMs = %v, want 4.2", ms(got.responseTimeMs))
+	}
+	if got.timestamp.Unix() != 1715258096 {
+		t.Errorf("timestamp = %v", got.timestamp)
+	}
+	if _, ok := parseCaddyJSONLine(`{"level":"info","msg":"server running"}`); ok {
+		t.Error("a non-access Caddy log line must not parse")
+	}
+}
+
+func TestParseTraefikLines(t *testing.T) {
+	jsonLine := `{"ClientHost":"1.2.3.4","DownstreamContentSize":90,"DownstreamStatus":502,"Duration":25000000,"OriginDuration":20000000,"OriginStatus":503,"RequestHost":"app.example.com","RequestMethod":"POST","RequestPath":"/api","ServiceName":"app@docker","StartUTC":"2024-05-09T12:34:56.123Z","request_User-Agent":"Go-http-client/1.1"}`
+	got, ok := parseTraefikJSONLine(jsonLine)
+	if !ok {
+		t.Fatal("expected traefik JSON line to parse")
+	}
+	if got.status != 502 || got.upstreamStatus != 503 || got.upstream != "app@docker" || got.domain != "app.example.com" {
+		t.Errorf("got %+v", got)
+	}
+	if ms(got.responseTimeMs) != 25 || ms(got.upstreamTimeMs) != 20 {
+		t.Errorf("timing = %v / %v, want 25 / 20", ms(got.responseTimeMs), ms(got.upstreamTimeMs))
+	}
+
+	clf := `1.2.3.4 - - [09/May/2024:12:34:56 +0000] "GET /health HTTP/1.1" 200 2 "-" "kube-probe/1.29" 42 "app@docker" "http://10.0.0.5:8080" 3ms`
+	got, ok = parseTraefikCLFLine(clf)
+	if !ok {
+		t.Fatal("expected traefik CLF line to parse")
+	}
+	if got.path != "/health" || ms(got.responseTimeMs) != 3 || got.upstream != "http://10.0.0.5:8080" || got.source != "traefik" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestParseHAProxyLine(t *testing.T) {
+	line := `May  9 12:34:56 lb haproxy[14389]: 1.2.3.4:33317 [09/May/2024:12:34:56.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {www.example.com|Mozilla/5.0} {} "GET /index.html HTTP/1.1"`
+	got, ok := parseHAProxyLine(line)
+	if !ok {
+		t.Fatal("expected haproxy line to parse")
+	}
+	if got.ip != "1.2.3.4" || got.path != "/index.html" || got.status != 200 || got.bytes != 2750 {
+		t.Errorf("got %+v", got)
+	}
+	if got.domain != "www.example.com" || got.ua != "Mozilla/5.0" || got.upstream != "static/srv1" {
+		t.Errorf("got domain=%q ua=%q upstream=%q", got.domain, got.ua, got.upstream)
+	}
+	if ms(got.responseTimeMs) != 109 || ms(got.upstreamTimeMs) != 69 {
+		t.Errorf("timing = %v / %v, want 109 / 69", ms(got.responseTimeMs), ms(got.upstreamTimeMs))
+	}
+
+	// No captured headers, request refused before any server was picked.
+	got, ok = parseHAProxyLine(`1.2.3.4:33317 [09/May/2024:12:34:56.655] http-in http-in/<NOSRV> 0/-1/-1/-1/0 403 192 - - PR-- 1/1/0/0/0 0/0 "GET /admin HTTP/1.1"`)
+	if !ok || got.upstream != "http-in" || got.upstreamTimeMs != nil || got.domain != "(unknown)" {
+		t.Errorf("refused request: ok=%v got=%+v", ok, got)
+	}
+}
+
+func TestRegexWebLogParser(t *testing.T) {
+	parse, err := newRegexWebLogParser(
+		`^(?P<time>\S+) (?P<ip>\S+) (?P<domain>\S+) "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<bytes>\d+) (?P<response_time_ms>[\d.]+)ms`,
+		"2006-01-02T15:04:05Z07:00",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := parse(`2024-05-09T12:34:56Z 1.2.3.4 api.example.com "DELETE /v1/users/7 HTTP/1.1" 204 0 12.5ms`)
+	if !ok {
+		t.Fatal("expected custom line to parse")
+	}
+	if got.method != "DELETE" || got.path != "/v1/users/7" || got.status != 204 || got.domain != "api.example.com" {
+		t.Errorf("got %+v", got)
+	}
+	if ms(got.responseTimeMs) != 12.5 || got.source != "custom" || got.timestamp.Hour() != 12 {
+		t.Errorf("got %+v", got)
+	}
+	if _, ok := parse("garbage"); ok {
+		t.Error("a non-matching line must not parse")
+	}
+}
+
+func TestParserForFile(t *testing.T) {
+	rules := buildWebLogParsers([]config.WebLogFormat{
+		{Path: "/var/log/caddy/*.log", Format: config.WebLogFormatCaddyJSON},
+		{Path: "/var/log/haproxy.log", Format: config.WebLogFormatHAProxy},
+	})
+	caddy := `{"ts":1715258096.5,"request":{"client_ip":"1.2.3.4","method":"GET","host":"a","uri":"/"},"duration":0.001,"size":1,"status":200}`
+	if _, ok := parserForFile(rules, "/var/log/caddy/access.log")(caddy); !ok {
+		t.Error("caddy rule not applied to a matching file")
+	}
+	if _, ok := parserForFile(rules, "/var/log/nginx/access.log")(caddy); ok {
+		t.Error("files without a rule must use auto-detection")
+	}
+}
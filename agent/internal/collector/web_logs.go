@@ -15,6 +15,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/serversupervisor/agent/internal/config"
 )
 
 // fileIdentityCache tracks each log file's last-seen identity (device+inode,
@@ -55,6 +57,18 @@ type WebRequest struct {
 	BlockedReason string     `json:"blocked_reason,omitempty"`
 	BlockedAt     *time.Time `json:"blocked_at,omitempty"`
 	BlockedUntil  *time.Time `json:"blocked_until,omitempty"`
+
+	// Timing and upstream fields, only for formats that log them (see
+	// web_log_formats.go). Times are in milliseconds; nil when not logged,
+	// so that a fast response logged as 0 still counts.
+	ResponseTimeMs *float64 `json:"response_time_ms,omitempty"`
+	UpstreamTimeMs *float64 `json:"upstream_time_ms,omitempty"`
+	UpstreamStatus int      `json:"upstream_status,omitempty"`
+	Upstream       string   `json:"upstream,omitempty"`
+
+	// Query is the raw query string without the leading "?", truncated to
+	// maxQueryLength; the server's threat rules can match it.
+	Query string `json:"query,omitempty"`
 }
 
 type NPMPathHit struct {
@@ -119,6 +133,13 @@ type parsedLine struct {
 	ua        string
 	domain    string
 	source    string
+
+	responseTimeMs *float64
+	upstreamTimeMs *float64
+	upstreamStatus int
+	upstream       string
+
+	query string
 }
 
 type webLogCursorState struct {
@@ -143,7 +164,7 @@ var commonAccessLogRegex = regexp.MustCompile(
 	`^(\S+) \S+ \S+ \[([^\]]+)\] "(\S+) ([^\s"]+) [^"]+" (\d{3}) (\d+|-) "[^"]*" "([^"]*)"`,
 )
 
-func CollectWebLogs(ctx context.Context, logPathGlobs []string, tailLines int, topN int, requestLimit int, cursorFile string, crowdSecConnectionString string, crowdSecAPIKey string, crowdSecAlertsMachineID string, crowdSecAlertsPassword string, crowdSecEnabled bool) (*WebLogReport, error) {
+func CollectWebLogs(ctx context.Context, logPathGlobs []string, formats []config.WebLogFormat, tailLines int, topN int, requestLimit int, cursorFile string, crowdSecConnectionString string, crowdSecAPIKey string, crowdSecAlertsMachineID string, crowdSecAlertsPassword string, crowdSecEnabled bool) (*WebLogReport, error) {
 	if tailLines <= 0 {
 		tailLines = 5000
 	}
@@ -175,6 +196,7 @@ func CollectWebLogs(ctx context.Context, logPathGlobs []string, tailLines int, t
 	domainMethods := map[string]map[string]int{}
 	domainPaths := map[string]map[string]int{}
 	sourceHits := map[string]int{}
+	formatRules := buildWebLogParsers(formats)
 
 	for _, file := range files {
 		// A stuck/very slow file (huge un-rotated NPM access log, stalled
@@ -191,8 +213,9 @@ func CollectWebLogs(ctx context.Context, logPathGlobs []string, tailLines int, t
 			continue
 		}
 		cursor.Files[file] = nextEntry
+		parse := parserForFile(formatRules, file)
 		for _, line := range lines {
-			e, ok := parseAccessLine(line)
+			e, ok := parse(line)
 			if !ok {
 				continue
 			}
@@ -237,6 +260,13 @@ func CollectWebLogs(ctx context.Context, logPathGlobs []string, tailLines int, t
 				Bytes:     e.bytes,
 				UserAgent: e.ua,
 				Domain:    domain,
+
+				ResponseTimeMs: e.responseTimeMs,
+				UpstreamTimeMs: e.upstreamTimeMs,
+				UpstreamStatus: e.upstreamStatus,
+				Upstream:       e.upstream,
+
+				Query: e.query,
 			}
 			report.Requests = append(report.Requests, request)
 		}
@@ -356,6 +386,7 @@ func parseAccessLine(line string) (parsedLine, bool) {
 			ip:        strings.TrimSpace(m[6]),
 			method:    strings.ToUpper(strings.TrimSpace(m[3])),
 			path:      path,
+			query:     rawQuery(m[5]),
 			status:    status,
 			bytes:     bytes,
 			ua:        strings.TrimSpace(m[8]),
@@ -384,6 +415,7 @@ func parseAccessLine(line string) (parsedLine, bool) {
 			ip:        strings.TrimSpace(m[1]),
 			method:    strings.ToUpper(strings.TrimSpace(m[3])),
 			path:      path,
+			query:     rawQuery(m[4]),
 			status:    status,
 			bytes:     bytes,
 			ua:        strings.TrimSpace(m[7]),
@@ -406,6 +438,22 @@ func cleanPath(path string) string {
 	return path
 }
 
+// maxQueryLength bounds the query string kept per request.
+const maxQueryLength = 1024
+
+// rawQuery returns the query string of a request target, without the "?".
+func rawQuery(target string) string {
+	q := strings.IndexByte(target, '?')
+	if q < 0 {
+		return ""
+	}
+	query := strings.TrimSpace(target[q+1:])
+	if len(query) > maxQueryLength {
+		query = query[:maxQueryLength]
+	}
+	return query
+}
+
 func parseTimeOrNow(values []string, layouts []string) time.Time {
 	for _, v := range values {
 		for _, layout := range layouts {
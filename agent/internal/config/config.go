@@ -3,12 +3,41 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+// Web access log formats (web_logs_formats[].format).
+const (
+	WebLogFormatAuto        = "auto" // NPM or common/combined, detected per line
+	WebLogFormatCombined    = "combined"
+	WebLogFormatNginxTimed  = "nginx_timed"
+	WebLogFormatCaddyJSON   = "caddy_json"
+	WebLogFormatTraefikJSON = "traefik_json"
+	WebLogFormatTraefikCLF  = "traefik_clf"
+	WebLogFormatHAProxy     = "haproxy"
+	WebLogFormatRegex       = "regex"
+)
+
+// WebLogFormat assigns a parser to the access logs matching Path. Pattern
+// (named groups, see README) and TimeLayout only apply to the regex format.
+type WebLogFormat struct {
+	Path       string `yaml:"path"`
+	Format     string `yaml:"format"`
+	Pattern    string `yaml:"pattern"`
+	TimeLayout string `yaml:"time_layout"`
+}
+
+// Agent authentication modes (auth_mode).
+const (
+	AuthAPIKey    = "api_key"
+	AuthMTLS      = "mtls"
+	AuthSignature = "signature"
+)
+
 type Config struct {
 	// Server connection
 	ServerURL string `yaml:"server_url"`
@@ -29,6 +58,9 @@ type Config struct {
 	WebLogsTopN           int      `yaml:"web_logs_top_n"`
 	WebLogsRequestsLimit  int      `yaml:"web_logs_requests_limit"`
 	WebLogsCursorFile     string   `yaml:"web_logs_cursor_file"`
+	// WebLogsFormats picks the parser per log glob; its paths are scanned in
+	// addition to WebLogsLogPaths. Unlisted files use auto-detection.
+	WebLogsFormats []WebLogFormat `yaml:"web_logs_formats"`
 
 	// CrowdSec correlation
 	CollectCrowdSecCorrelation bool   `yaml:"collect_crowdsec_correlation"`
@@ -36,6 +68,13 @@ type Config struct {
 	CrowdSecAPIKey             string `yaml:"crowdsec_api_key"`
 	CrowdSecAlertsMachineID    string `yaml:"crowdsec_alerts_machine_id"`
 	CrowdSecAlertsPassword     string `yaml:"crowdsec_alerts_password"`
+	// CollectCrowdSec reports this host's local CrowdSec decisions and
+	// registered bouncers so the server can aggregate them across the fleet
+	// (see collector.CollectCrowdSec). Bouncers are only listed by cscli —
+	// the LAPI does not expose them — hence CrowdSecCscliCommand, e.g.
+	// "docker exec crowdsec cscli" when CrowdSec runs in a container.
+	CollectCrowdSec      bool   `yaml:"collect_crowdsec"`
+	CrowdSecCscliCommand string `yaml:"crowdsec_cscli_command"`
 
 	// Network flows ("top talkers"): per-host bandwidth by remote peer,
 	// derived from conntrack accounting. CollectNetworkFlows only controls
@@ -45,8 +84,10 @@ type Config struct {
 	CollectNetworkFlows bool `yaml:"collect_network_flows"`
 	NetworkFlowsTopN    int  `yaml:"network_flows_top_n"`
 	// NetworkFlowsL7Capture opts into a bounded packet-sampling pass that reads
-	// the TLS SNI hostname off outbound handshakes, so a talker can be labelled
-	// with the domain it actually contacted instead of just an IP and a port.
+	// the TLS SNI hostname off outbound handshakes and the answers of this
+	// host's DNS lookups, so a talker can be labelled with the domain it
+	// actually contacted instead of just an IP and a port, and the report
+	// carries the host's top queried domains and NXDOMAIN count.
 	// Default false: it needs CAP_NET_RAW and reads packet headers off the
 	// wire, which is a materially bigger ask than the rest of the agent's
 	// /proc-and-netlink collection. Missing capability degrades to the UI's
@@ -72,12 +113,32 @@ type Config struct {
 	ResticBackupIdleTimeoutMinutes int     `yaml:"restic_backup_idle_timeout_minutes"`
 
 	// Low-latency command push: an optional, additive WebSocket connection the
-	// agent opens to the server purely to be nudged to poll immediately when a
-	// command is dispatched, instead of waiting out report_interval. Disabling
-	// it only removes that latency win — command delivery still works exactly
-	// as before via the regular poll cycle.
+	// agent opens to the server. With a recent server it carries the commands
+	// and their output and results (protocol v2); with an older one it only
+	// nudges the agent to poll immediately instead of waiting out
+	// report_interval. Disabling it only removes that latency win — command
+	// delivery still works exactly as before via the regular poll cycle and
+	// the HTTP result endpoints.
 	DisableWSPush bool `yaml:"disable_ws_push"`
 
+	// Offline buffering: the metrics of a report the server never received
+	// (outage, upgrade, network cut) are kept in a bounded on-disk queue and
+	// replayed with their original timestamps once the server is reachable.
+	// An empty path disables buffering; once the bound is reached the oldest
+	// samples are dropped first (2880 = 24h at the default 30s interval).
+	OfflineBufferFile       string `yaml:"offline_buffer_file"`
+	OfflineBufferMaxSamples int    `yaml:"offline_buffer_max_samples"`
+
+	// Agent authentication. api_key (default) sends the API key on every
+	// request. mtls and signature use it only once, to enroll a per-host
+	// credential kept in IdentityDir: a client certificate issued by the
+	// server's CA (renewed before expiry, requests then go to the server's
+	// mTLS listener), or an Ed25519 key signing every request (for servers
+	// behind a TLS-terminating proxy). Once enrolled, the server refuses the
+	// API key alone for this host.
+	AuthMode    string `yaml:"auth_mode"`
+	IdentityDir string `yaml:"identity_dir"`
+
 	// TLS
 	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
 
@@ -86,9 +147,62 @@ type Config struct {
 	LogFormat string `yaml:"log_format"` // text|json (default text)
 }
 
-// WebLogGlobs returns configured web access log globs.
+// WebLogGlobs returns configured web access log globs: web_logs_log_paths
+// plus the paths of web_logs_formats not already listed.
 func (c *Config) WebLogGlobs() []string {
-	return c.WebLogsLogPaths
+	globs := append([]string(nil), c.WebLogsLogPaths...)
+	seen := make(map[string]bool, len(globs))
+	for _, g := range globs {
+		seen[g] = true
+	}
+	for _, f := range c.WebLogsFormats {
+		if f.Path != "" && !seen[f.Path] {
+			seen[f.Path] = true
+			globs = append(globs, f.Path)
+		}
+	}
+	return globs
+}
+
+// webLogRegexFields are the named groups a regex format may capture.
+var webLogRegexFields = map[string]bool{
+	"ip": true, "time": true, "method": true, "path": true, "request": true,
+	"status": true, "bytes": true, "user_agent": true, "domain": true,
+	"response_time": true, "response_time_ms": true, "upstream_time": true,
+	"upstream_time_ms": true, "upstream_status": true, "upstream": true,
+}
+
+func validateWebLogFormats(formats []WebLogFormat) error {
+	for i, f := range formats {
+		if strings.TrimSpace(f.Path) == "" {
+			return fmt.Errorf("web_logs_formats[%d]: path is required", i)
+		}
+		switch f.Format {
+		case WebLogFormatAuto, WebLogFormatCombined, WebLogFormatNginxTimed, WebLogFormatCaddyJSON,
+			WebLogFormatTraefikJSON, WebLogFormatTraefikCLF, WebLogFormatHAProxy:
+		case WebLogFormatRegex:
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil {
+				return fmt.Errorf("web_logs_formats[%d]: invalid pattern: %w", i, err)
+			}
+			groups := map[string]bool{}
+			for _, name := range re.SubexpNames()[1:] {
+				if name == "" {
+					continue
+				}
+				if !webLogRegexFields[name] {
+					return fmt.Errorf("web_logs_formats[%d]: unknown named group %q", i, name)
+				}
+				groups[name] = true
+			}
+			if !groups["status"] || (!groups["path"] && !groups["request"]) {
+				return fmt.Errorf("web_logs_formats[%d]: pattern needs a status group and a path or request group", i)
+			}
+		default:
+			return fmt.Errorf("web_logs_formats[%d]: unknown format %q", i, f.Format)
+		}
+	}
+	return nil
 }
 
 func Load(path string) (*Config, error) {
@@ -202,6 +316,21 @@ func Load(path string) (*Config, error) {
 	if env := os.Getenv("SUPERVISOR_DISABLE_WS_PUSH"); env != "" {
 		cfg.DisableWSPush = env == "true" || env == "1"
 	}
+	// Set but empty disables buffering, hence LookupEnv.
+	if env, ok := os.LookupEnv("SUPERVISOR_OFFLINE_BUFFER_FILE"); ok {
+		cfg.OfflineBufferFile = strings.TrimSpace(env)
+	}
+	if env := os.Getenv("SUPERVISOR_OFFLINE_BUFFER_MAX_SAMPLES"); env != "" {
+		if n, err := strconv.Atoi(env); err == nil && n > 0 {
+			cfg.OfflineBufferMaxSamples = n
+		}
+	}
+	if env := os.Getenv("SUPERVISOR_AUTH_MODE"); env != "" {
+		cfg.AuthMode = strings.TrimSpace(env)
+	}
+	if env := os.Getenv("SUPERVISOR_IDENTITY_DIR"); env != "" {
+		cfg.IdentityDir = strings.TrimSpace(env)
+	}
 	if env := os.Getenv("SUPERVISOR_INSECURE_SKIP_VERIFY"); env != "" {
 		cfg.InsecureSkipVerify = env == "true" || env == "1"
 	}
@@ -226,6 +355,12 @@ func Load(path string) (*Config, error) {
 	if env := os.Getenv("SUPERVISOR_CROWDSEC_ALERTS_PASSWORD"); env != "" {
 		cfg.CrowdSecAlertsPassword = strings.TrimSpace(env)
 	}
+	if env := os.Getenv("SUPERVISOR_COLLECT_CROWDSEC"); env != "" {
+		cfg.CollectCrowdSec = env == "true" || env == "1"
+	}
+	if env := os.Getenv("SUPERVISOR_CROWDSEC_CSCLI_COMMAND"); env != "" {
+		cfg.CrowdSecCscliCommand = strings.TrimSpace(env)
+	}
 	if env := os.Getenv("SUPERVISOR_COLLECT_NETWORK_FLOWS"); env != "" {
 		cfg.CollectNetworkFlows = env == "true" || env == "1"
 	}
@@ -272,6 +407,14 @@ func Load(path string) (*Config, error) {
 	if cfg.APIKey == "" {
 		return nil, fmt.Errorf("api_key is required (set in config or SUPERVISOR_API_KEY env var)")
 	}
+	switch cfg.AuthMode {
+	case AuthAPIKey, AuthMTLS, AuthSignature:
+	default:
+		return nil, fmt.Errorf("auth_mode must be %q, %q or %q", AuthAPIKey, AuthMTLS, AuthSignature)
+	}
+	if err := validateWebLogFormats(cfg.WebLogsFormats); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -301,7 +444,13 @@ func defaultConfig() *Config {
 		CrowdSecAPIKey:                 "",
 		CrowdSecAlertsMachineID:        "",
 		CrowdSecAlertsPassword:         "",
+		CollectCrowdSec:                false,
+		CrowdSecCscliCommand:           "cscli",
 		DisableWSPush:                  false,
+		OfflineBufferFile:              "/var/lib/serversupervisor/offline_buffer.jsonl",
+		OfflineBufferMaxSamples:        2880,
+		AuthMode:                       AuthAPIKey,
+		IdentityDir:                    "/var/lib/serversupervisor/identity",
 		LogLevel:                       "info",
 		LogFormat:                      "text",
 		CollectNetworkFlows:            true,
@@ -365,6 +514,20 @@ web_logs_requests_limit: 200
 # Incremental cursor state file used to avoid re-reading already processed lines.
 web_logs_cursor_file: "/var/lib/serversupervisor/web_logs_cursor.json"
 
+# Per-path log format (paths are scanned in addition to web_logs_log_paths).
+# Files not listed here are auto-detected (NPM or common/combined).
+# Formats: auto, combined, nginx_timed, caddy_json, traefik_json, traefik_clf,
+# haproxy, regex (named groups: ip, time, method, path or request, status,
+# bytes, user_agent, domain, response_time[_ms], upstream_time[_ms],
+# upstream_status, upstream).
+web_logs_formats: []
+#  - path: "/var/log/caddy/access*.log"
+#    format: caddy_json
+#  - path: "/var/log/custom/app.log"
+#    format: regex
+#    pattern: '^(?P<ip>\S+) \[(?P<time>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3})'
+#    time_layout: "2006-01-02T15:04:05Z07:00"
+
 # Logging
 # log_level: debug|info|warn|error (default info). The --verbose flag forces debug.
 # log_format: text|json (default text — switch to json for centralized ingestion).
@@ -372,12 +535,33 @@ log_level: "info"
 log_format: "text"
 
 # Low-latency command push: an optional WebSocket connection the agent opens
-# to the server purely to be nudged to poll immediately when a command is
-# dispatched, instead of waiting out report_interval. Purely additive — set
-# to true only if your network blocks WebSocket upgrades to the server;
-# command delivery still works exactly as before via the regular poll cycle.
+# to the server. It carries commands, their live output and results as soon
+# as they exist (or, with an older server, only a "poll now" nudge) instead of
+# waiting out report_interval. Purely additive — set to true only if your
+# network blocks WebSocket upgrades to the server; command delivery still
+# works exactly as before via the regular poll cycle and HTTP.
 disable_ws_push: false
 
+# Offline buffering: when a report cannot be delivered (server down or being
+# upgraded, network cut), its metrics are kept in this bounded on-disk queue
+# and replayed with their original timestamps once the server is back, so
+# graphs have no hole. Empty string disables it. The oldest samples are
+# dropped first once offline_buffer_max_samples is reached (2880 = 24h at
+# the default 30s report_interval).
+offline_buffer_file: "/var/lib/serversupervisor/offline_buffer.jsonl"
+offline_buffer_max_samples: 2880
+
+# Agent authentication: api_key (default), mtls or signature.
+# With mtls or signature the API key is only used once, to enroll a per-host
+# credential stored in identity_dir; from then on the server refuses the API
+# key alone for this host, so a leaked key can no longer be replayed.
+#   mtls: client certificate issued by the server (requires AGENT_MTLS_URL on
+#         the server), renewed automatically before it expires.
+#   signature: Ed25519 key signing every request, for a server behind a
+#         TLS-terminating reverse proxy.
+auth_mode: "api_key"
+identity_dir: "/var/lib/serversupervisor/identity"
+
 # Skip TLS verification (for self-signed certs)
 insecure_skip_verify: false
 
@@ -393,6 +577,12 @@ crowdsec_api_key: ""
 crowdsec_alerts_machine_id: ""
 crowdsec_alerts_password: ""
 
+# CrowdSec fleet sync: report this host's local decisions and bouncers so the
+# server can list them across hosts and alert on a bouncer that stops pulling.
+# Bouncers are listed with cscli (e.g. "docker exec crowdsec cscli").
+collect_crowdsec: false
+crowdsec_cscli_command: "cscli"
+
 # Network flows ("top talkers"): per-host bandwidth by remote peer, derived
 # from conntrack accounting (requires nf_conntrack_acct=1, checked at runtime
 # — see the host's diagnostics banner if this stays empty). Only bounds
@@ -400,14 +590,16 @@ crowdsec_alerts_password: ""
 collect_network_flows: true
 network_flows_top_n: 50
 
-# OPTIONAL, off by default. Sample outbound TLS handshakes to label a talker
-# with the domain it actually contacted (SNI) instead of just an IP + port.
+# OPTIONAL, off by default. Sample outbound TLS handshakes (SNI) and DNS
+# responses to label a talker with the domain it actually contacted instead of
+# just an IP + port, and to report the host's top queried domains and NXDOMAIN
+# rate.
 # Requires CAP_NET_RAW on the agent binary:
 #   setcap cap_net_raw+ep /usr/local/bin/serversupervisor-agent
 # Without it the agent logs once, raises a diagnostic, and falls back to the
-# interface's port-based guess — it never fails a report cycle. Only TLS SNI is
-# extracted (no DNS, no HTTP); capture is bounded to a few seconds and a few
-# thousand packets per cycle.
+# interface's port-based guess — it never fails a report cycle. Only TLS SNI and
+# UDP DNS responses are extracted (no HTTP, no DoT/DoH); capture is bounded to a
+# few seconds and a few thousand packets per cycle.
 network_flows_l7_capture: false
 
 # Restic backup supervision. Only paths/flags — never put restic/Swift/SMTP
@@ -442,6 +634,29 @@ restic_backup_idle_timeout_minutes: 20
 	return raw
 }
 
+var (
+	serverURLLine = regexp.MustCompile(`(?m)^server_url:.*$`)
+	apiKeyLine    = regexp.MustCompile(`(?m)^api_key:.*$`)
+)
+
+// WithCredentials returns the config file content raw with its server_url and
+// api_key set, keeping every other line (and comment) as written. Used by
+// --join-token to record the API key the server issued.
+func WithCredentials(raw, serverURL, apiKey string) string {
+	set := func(raw string, re *regexp.Regexp, key, value string) string {
+		line := fmt.Sprintf("%s: %q", key, value)
+		if re.MatchString(raw) {
+			return re.ReplaceAllLiteralString(raw, line)
+		}
+		if raw != "" && !strings.HasSuffix(raw, "\n") {
+			raw += "\n"
+		}
+		return raw + line + "\n"
+	}
+	raw = set(raw, serverURLLine, "server_url", serverURL)
+	return set(raw, apiKeyLine, "api_key", apiKey)
+}
+
 func splitCSV(raw string) []string {
 	parts := []string{}
 	for _, p := range strings.Split(raw, ",") {
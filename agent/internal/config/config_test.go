@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.yaml")
+
+	// Fresh default file: placeholders replaced, comments kept.
+	raw := WithCredentials(DefaultConfigFile(), "https://supervisor.example.com", "host-1.secret")
+	if !strings.Contains(raw, "# Report interval in seconds") {
+		t.Error("comments of the default file were dropped")
+	}
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ServerURL != "https://supervisor.example.com" || cfg.APIKey != "host-1.secret" {
+		t.Fatalf("credentials = %q / %q", cfg.ServerURL, cfg.APIKey)
+	}
+
+	// A hand-written file without the keys gets them appended.
+	raw = WithCredentials("report_interval: 60", "http://s:8080", "k")
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ReportInterval != 60 || cfg.APIKey != "k" || cfg.ServerURL != "http://s:8080" {
+		t.Fatalf("config = %+v", cfg)
+	}
+}
+
+func TestValidateWebLogFormats(t *testing.T) {
+	valid := []WebLogFormat{
+		{Path: "/var/log/caddy/*.log", Format: WebLogFormatCaddyJSON},
+		{Path: "/var/log/app.log", Format: WebLogFormatRegex, Pattern: `^(?P<ip>\S+) "(?P<request>[^"]*)" (?P<status>\d{3})`},
+	}
+	if err := validateWebLogFormats(valid); err != nil {
+		t.Fatalf("valid formats: %v", err)
+	}
+	for _, f := range []WebLogFormat{
+		{Path: "", Format: WebLogFormatHAProxy},
+		{Path: "/x", Format: "iis"},
+		{Path: "/x", Format: WebLogFormatRegex, Pattern: `(`},
+		{Path: "/x", Format: WebLogFormatRegex, Pattern: `(?P<status>\d{3})`},
+		{Path: "/x", Format: WebLogFormatRegex, Pattern: `(?P<status>\d{3}) (?P<path>\S+) (?P<referer>\S+)`},
+	} {
+		if err := validateWebLogFormats([]WebLogFormat{f}); err == nil {
+			t.Errorf("%+v: expected a validation error", f)
+		}
+	}
+
+	cfg := &Config{WebLogsLogPaths: []string{"/a.log"}, WebLogsFormats: []WebLogFormat{{Path: "/a.log"}, {Path: "/b.log"}}}
+	if got := cfg.WebLogGlobs(); len(got) != 2 || got[1] != "/b.log" {
+		t.Errorf("WebLogGlobs = %v, want format paths added once", got)
+	}
+}
@@ -40,6 +40,11 @@ func cmdTimeout(cmd sender.PendingCommand) time.Duration {
 	return maxCmdDuration
 }
 
+// seenRetention is how long a command ID is remembered to drop redeliveries.
+// A command is only ever redelivered after a lost push-channel ack, within
+// seconds; an hour is far beyond that and keeps the set small.
+const seenRetention = time.Hour
+
 // UpdaterFunc starts a detached self-update helper process. Injected from the
 // main package so the dispatcher does not need the HTTP/binary-install logic.
 type UpdaterFunc func(s *sender.Sender, cmd sender.PendingCommand, cfgPath string) error
@@ -53,6 +58,9 @@ type Dispatcher struct {
 	cfgPath   string
 	updater   UpdaterFunc
 	composeMu sync.Map // project name -> *sync.Mutex (serialize compose updates per project)
+
+	seenMu sync.Mutex
+	seen   map[string]time.Time // command ID -> first received
 }
 
 // lockCompose serializes compose updates for a single project. Concurrent
@@ -73,15 +81,40 @@ func New(cfg *config.Config, cfgPath string, tasks *config.TasksConfig, updater
 		cfg:     cfg,
 		cfgPath: cfgPath,
 		updater: updater,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// firstSeen records id and reports whether it is new. The server may deliver
+// a command twice when the push channel drops before the agent's ack reached
+// it (the command goes back to pending); the second copy must not run again.
+func (d *Dispatcher) firstSeen(id string) bool {
+	d.seenMu.Lock()
+	defer d.seenMu.Unlock()
+	now := time.Now()
+	if _, ok := d.seen[id]; ok {
+		return false
+	}
+	for k, at := range d.seen {
+		if now.Sub(at) > seenRetention {
+			delete(d.seen, k)
+		}
 	}
+	d.seen[id] = now
+	return true
 }
 
 // Process runs each command in its own goroutine and waits for all to complete.
+// A command ID already received is skipped (see firstSeen).
 // APT commands serialise on aptMu (dpkg locks are exclusive); other modules
 // share the 4-slot cmdSem.
 func (d *Dispatcher) Process(s *sender.Sender, commands []sender.PendingCommand) {
 	var wg sync.WaitGroup
 	for _, cmd := range commands {
+		if !d.firstSeen(cmd.ID) {
+			slog.Info("ignoring redelivered command", "command_id", cmd.ID, "module", cmd.Module, "action", cmd.Action)
+			continue
+		}
 		wg.Add(1)
 		go func(c sender.PendingCommand) {
 			defer wg.Done()
@@ -0,0 +1,16 @@
+package dispatcher
+
+import "testing"
+
+func TestFirstSeen_DropsRedeliveries(t *testing.T) {
+	d := New(nil, "", nil, nil)
+	if !d.firstSeen("c1") {
+		t.Fatal("a new command must run")
+	}
+	if d.firstSeen("c1") {
+		t.Error("a redelivered command must not run twice")
+	}
+	if !d.firstSeen("c2") {
+		t.Error("another command must still run")
+	}
+}
@@ -36,7 +36,13 @@ type composePayload struct {
 // project: optional pre-hook → pull → up -d → healthcheck → optional rollback →
 // optional post-hook → optional image prune. cmd.Target is the compose project
 // name, accepted only if it exists in the agent's local inventory.
+// File-level actions (read/validate/apply/rollback of the compose file) are
+// routed to composeFileActions in handler_compose_file.go.
 func handleCompose(ctx context.Context, d *Dispatcher, s *sender.Sender, cmd sender.PendingCommand) {
+	if fileHandler, ok := composeFileActions[cmd.Action]; ok {
+		fileHandler(ctx, d, s, cmd)
+		return
+	}
 	if cmd.Action != "update" {
 		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("unknown compose action: %s", cmd.Action))
 		return
@@ -0,0 +1,335 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/agent/internal/collector"
+	"github.com/serversupervisor/agent/internal/sender"
+)
+
+// composeFileActions are the module=compose actions operating on the compose
+// file itself rather than on images. They are routed from handleCompose so
+// the whole compose module keeps a single registry entry.
+var composeFileActions = map[string]ModuleHandler{
+	"read_file":     handleComposeReadFile,
+	"validate_file": handleComposeValidateFile,
+	"apply_file":    handleComposeApplyFile,
+	"rollback_file": handleComposeRollbackFile,
+}
+
+// composeFilePayload is the typed payload for validate_file/apply_file (and
+// rollback_file, which only reads HealthTimeoutSec). Compose/Env are the
+// operator's edited content as returned by read_file: lines still reading
+// "[REDACTED]" keep their on-disk value. A nil Env leaves .env untouched.
+type composeFilePayload struct {
+	Compose          string  `json:"compose"`
+	Env              *string `json:"env"`
+	HealthTimeoutSec int     `json:"healthcheck_timeout_sec"`
+}
+
+// composeValidation is the JSON output of validate_file.
+type composeValidation struct {
+	Valid   bool   `json:"valid"`
+	Diff    string `json:"diff"`
+	EnvDiff string `json:"env_diff"`
+	Detail  string `json:"detail"`
+}
+
+// resolveComposeTarget validates cmd.Target and resolves it against the local
+// inventory, reporting the terminal failure itself when it cannot.
+func resolveComposeTarget(ctx context.Context, s *sender.Sender, cmd sender.PendingCommand) (*collector.ComposeProject, bool) {
+	if !validComposeName.MatchString(cmd.Target) {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("invalid compose project name: %q", cmd.Target))
+		return nil, false
+	}
+	proj, err := collector.ResolveComposeProject(cmd.Target)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("compose discovery failed: %v", err))
+		return nil, false
+	}
+	if proj == nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("compose project %q not found locally", cmd.Target))
+		return nil, false
+	}
+	return proj, true
+}
+
+func parseComposeFilePayload(ctx context.Context, s *sender.Sender, cmd sender.PendingCommand) (composeFilePayload, bool) {
+	var p composeFilePayload
+	if cmd.Payload != "" {
+		if err := json.Unmarshal([]byte(cmd.Payload), &p); err != nil {
+			reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("invalid payload: %v", err))
+			return p, false
+		}
+	}
+	return p, true
+}
+
+// handleComposeReadFile returns the masked compose file and .env as JSON.
+func handleComposeReadFile(ctx context.Context, _ *Dispatcher, s *sender.Sender, cmd sender.PendingCommand) {
+	proj, ok := resolveComposeTarget(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	reportRunning(ctx, s, cmd)
+	content, err := collector.ReadComposeFiles(proj)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR: %v", err))
+		return
+	}
+	out, err := json.Marshal(content)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR marshaling compose file: %v", err))
+		return
+	}
+	reportTerminal(ctx, s, cmd, "completed", string(out))
+}
+
+// composeCandidate is the operator's edit with redacted values restored, plus
+// the masked diffs against the current files.
+type composeCandidate struct {
+	configFiles         []string
+	configFile, envFile string
+	compose             string
+	env                 *string
+	diff, envDiff       string
+}
+
+// buildComposeCandidate reads the current files, restores every "[REDACTED]"
+// line the operator did not touch and computes the masked diffs. Diffs are
+// built from the masked views on both sides so no secret ever leaves the host.
+func buildComposeCandidate(proj *collector.ComposeProject, p composeFilePayload) (*composeCandidate, error) {
+	configFiles, err := collector.ComposeConfigFiles(proj)
+	if err != nil {
+		return nil, err
+	}
+	configFile, envFile, err := collector.ComposeFilePaths(proj)
+	if err != nil {
+		return nil, err
+	}
+	current, err := collector.ReadComposeFiles(proj)
+	if err != nil {
+		return nil, err
+	}
+	rawCompose, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("read compose file: %w", err)
+	}
+
+	c := &composeCandidate{
+		configFiles: configFiles,
+		configFile:  configFile,
+		envFile:     envFile,
+		compose:     collector.RestoreRedacted(string(rawCompose), p.Compose),
+		diff:        collector.UnifiedDiff(configFile, current.Compose, p.Compose),
+	}
+	if p.Env != nil {
+		rawEnv, rerr := os.ReadFile(envFile)
+		if rerr != nil && !os.IsNotExist(rerr) {
+			return nil, fmt.Errorf("read env file: %w", rerr)
+		}
+		restored := collector.RestoreRedacted(string(rawEnv), *p.Env)
+		c.env = &restored
+		c.envDiff = collector.UnifiedDiff(envFile, current.Env, *p.Env)
+	}
+	return c, nil
+}
+
+// handleComposeValidateFile runs `docker compose config` against the edited
+// content without touching the real files and returns the diff + verdict.
+func handleComposeValidateFile(ctx context.Context, _ *Dispatcher, s *sender.Sender, cmd sender.PendingCommand) {
+	proj, ok := resolveComposeTarget(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	p, ok := parseComposeFilePayload(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	reportRunning(ctx, s, cmd)
+
+	cand, err := buildComposeCandidate(proj, p)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR: %v", err))
+		return
+	}
+	res := composeValidation{Valid: true, Diff: cand.diff, EnvDiff: cand.envDiff}
+	if vout, verr := collector.ValidateComposeFiles(ctx, proj.Name, proj.WorkingDir, cand.configFiles, cand.configFile, cand.compose, cand.env); verr != nil {
+		res.Valid = false
+		res.Detail = strings.TrimSpace(decorateErrorOutput(verr, vout))
+	}
+
+	status := "completed"
+	if !res.Valid {
+		status = "failed"
+	}
+	out, _ := json.Marshal(res)
+	reportTerminal(ctx, s, cmd, status, string(out))
+}
+
+// handleComposeApplyFile validates, snapshots, writes and redeploys the edited
+// compose file. A failed `up -d` or health wait restores the snapshot and
+// redeploys it, mirroring the image rollback of the update action.
+func handleComposeApplyFile(ctx context.Context, d *Dispatcher, s *sender.Sender, cmd sender.PendingCommand) {
+	proj, ok := resolveComposeTarget(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	p, ok := parseComposeFilePayload(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(p.Compose) == "" {
+		reportTerminal(ctx, s, cmd, "failed", "compose content is empty")
+		return
+	}
+
+	unlock := d.lockCompose(proj.Name)
+	defer unlock()
+
+	reportRunning(ctx, s, cmd)
+	var out strings.Builder
+	stream := func(chunk string) {
+		out.WriteString(chunk)
+		streamChunk(ctx, s, cmd.ID, chunk)
+	}
+	section := func(title string) { stream("\n=== " + title + " ===\n") }
+
+	cand, err := buildComposeCandidate(proj, p)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR: %v", err))
+		return
+	}
+	if cand.diff == "" && cand.envDiff == "" {
+		reportTerminal(ctx, s, cmd, "completed", "No changes to apply.")
+		return
+	}
+
+	section("Diff")
+	stream(cand.diff + cand.envDiff)
+
+	section("Validating (docker compose config)")
+	if _, verr := collector.ValidateComposeFiles(ctx, proj.Name, proj.WorkingDir, cand.configFiles, cand.configFile, cand.compose, cand.env); verr != nil {
+		reportTerminal(ctx, s, cmd, "failed", out.String()+fmt.Sprintf("\nERROR: validation failed: %v (nothing was changed)", verr))
+		return
+	}
+	stream("ok\n")
+
+	section("Snapshotting current files")
+	if serr := collector.SnapshotComposeFiles(cand.configFile, cand.envFile); serr != nil {
+		reportTerminal(ctx, s, cmd, "failed", out.String()+fmt.Sprintf("\nERROR: %v (nothing was changed)", serr))
+		return
+	}
+	if werr := collector.WriteComposeFile(cand.configFile, cand.compose); werr != nil {
+		reportTerminal(ctx, s, cmd, "failed", out.String()+fmt.Sprintf("\nERROR: write compose file: %v", werr))
+		return
+	}
+	if cand.env != nil && cand.envDiff != "" {
+		if werr := collector.WriteComposeFile(cand.envFile, *cand.env); werr != nil {
+			fmt.Fprintf(&out, "\nERROR: write env file: %v", werr)
+			tryRestoreComposeFiles(ctx, proj, cand.configFiles, cand.envFile, stream)
+			reportTerminal(ctx, s, cmd, "failed", out.String())
+			return
+		}
+	}
+
+	if derr := deployComposeFiles(ctx, proj, cand.configFiles, p.HealthTimeoutSec, stream); derr != nil {
+		fmt.Fprintf(&out, "\nERROR: %v", derr)
+		if tryRestoreComposeFiles(ctx, proj, cand.configFiles, cand.envFile, stream) {
+			out.WriteString("\nRolled back to previous compose file.")
+		}
+		reportTerminal(ctx, s, cmd, "failed", out.String())
+		return
+	}
+
+	slog.Info("compose file applied", "project", proj.Name)
+	reportTerminal(ctx, s, cmd, "completed", out.String())
+}
+
+// handleComposeRollbackFile restores the snapshot taken by the last apply and
+// redeploys it.
+func handleComposeRollbackFile(ctx context.Context, d *Dispatcher, s *sender.Sender, cmd sender.PendingCommand) {
+	proj, ok := resolveComposeTarget(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	p, ok := parseComposeFilePayload(ctx, s, cmd)
+	if !ok {
+		return
+	}
+	configFiles, err := collector.ComposeConfigFiles(proj)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR: %v", err))
+		return
+	}
+	configFile, envFile, err := collector.ComposeFilePaths(proj)
+	if err != nil {
+		reportTerminal(ctx, s, cmd, "failed", fmt.Sprintf("ERROR: %v", err))
+		return
+	}
+
+	unlock := d.lockCompose(proj.Name)
+	defer unlock()
+
+	reportRunning(ctx, s, cmd)
+	var out strings.Builder
+	stream := func(chunk string) {
+		out.WriteString(chunk)
+		streamChunk(ctx, s, cmd.ID, chunk)
+	}
+
+	stream("\n=== Restoring snapshot ===\n")
+	if rerr := collector.RestoreComposeSnapshot(configFile, envFile); rerr != nil {
+		reportTerminal(ctx, s, cmd, "failed", out.String()+fmt.Sprintf("\nERROR: %v", rerr))
+		return
+	}
+	if derr := deployComposeFiles(ctx, proj, configFiles, p.HealthTimeoutSec, stream); derr != nil {
+		reportTerminal(ctx, s, cmd, "failed", out.String()+fmt.Sprintf("\nERROR: %v", derr))
+		return
+	}
+
+	slog.Info("compose file rolled back", "project", proj.Name)
+	reportTerminal(ctx, s, cmd, "completed", out.String())
+}
+
+// deployComposeFiles runs `up -d` on the whole project, from its own config
+// files, and, when a timeout is set, waits for every container to become
+// healthy — the same steps 4 and 5 of the update action.
+func deployComposeFiles(ctx context.Context, proj *collector.ComposeProject, configFiles []string, healthTimeoutSec int, stream func(string)) error {
+	stream("\n=== Applying (up -d) ===\n")
+	if _, err := collector.ComposeUpFiles(ctx, proj.Name, proj.WorkingDir, configFiles, stream); err != nil {
+		return fmt.Errorf("up failed: %w", err)
+	}
+	if healthTimeoutSec <= 0 {
+		return nil
+	}
+	stream(fmt.Sprintf("\n=== Waiting for health (timeout %ds) ===\n", healthTimeoutSec))
+	healthy, detail := collector.WaitComposeHealthy(ctx, proj.Name, "",
+		time.Duration(healthTimeoutSec)*time.Second, stream)
+	stream("\n" + detail + "\n")
+	if !healthy {
+		return fmt.Errorf("containers did not become healthy")
+	}
+	return nil
+}
+
+// tryRestoreComposeFiles puts the pre-apply snapshot back and redeploys it.
+// Returns true if the restore and the redeploy both succeeded.
+func tryRestoreComposeFiles(ctx context.Context, proj *collector.ComposeProject, configFiles []string, envFile string, stream func(string)) bool {
+	stream("\n=== Rolling back ===\n")
+	if err := collector.RestoreComposeSnapshot(configFiles[0], envFile); err != nil {
+		stream(fmt.Sprintf("rollback restore failed: %v\n", err))
+		return false
+	}
+	if _, err := collector.ComposeUpFiles(ctx, proj.Name, proj.WorkingDir, configFiles, stream); err != nil {
+		stream(fmt.Sprintf("rollback redeploy failed: %v\n", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,535 @@
+// Package identity authenticates the agent's requests to the server.
+//
+// With auth_mode api_key every request carries the API key, as always. With
+// mtls or signature the API key is only used to enroll a per-host credential
+// (POST /api/agent/identity/enroll), kept in identity_dir:
+//
+//   - mtls: an ECDSA key and the client certificate the server's CA issued
+//     for it, plus that CA and the URL of the server's mTLS listener. Requests
+//     then go to that listener and the certificate is renewed once two thirds
+//     of its lifetime have passed.
+//   - signature: an Ed25519 key signing every request (method, path,
+//     timestamp, nonce and body digest), for a server behind a
+//     TLS-terminating proxy.
+//
+// Once enrolled, the server refuses the API key alone for this host. When it
+// rejects the credential (revoked by an admin, expired while the agent was
+// off), the agent enrolls again — with the API key, which the server accepts
+// again once the host has no active credential left.
+package identity
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/serversupervisor/agent/internal/config"
+)
+
+// Headers of a signed request; mirrors the server-side agentpki constants.
+const (
+	HeaderHost      = "X-Agent-Host"
+	HeaderTimestamp = "X-Agent-Timestamp"
+	HeaderNonce     = "X-Agent-Nonce"
+	HeaderSignature = "X-Agent-Signature"
+)
+
+const (
+	enrollPath       = "/api/agent/identity/enroll"
+	signatureVersion = "ss-agent-v1"
+	// retryDelay spaces out enrollment attempts after a failure, so a
+	// misconfigured server (mTLS not enabled, say) is not hit every cycle.
+	retryDelay = 10 * time.Minute
+
+	stateFile      = "identity.json"
+	clientKeyFile  = "client.key"
+	clientCertFile = "client.crt"
+	caCertFile     = "ca.crt"
+	signingKeyFile = "signing.key"
+)
+
+// Credential kinds, as the server names them.
+const (
+	kindCertificate = "certificate"
+	kindEd25519     = "ed25519"
+)
+
+// state is identity.json: what was enrolled, besides the key files.
+type state struct {
+	Kind         string    `json:"kind"`
+	CredentialID string    `json:"credential_id"`
+	MTLSURL      string    `json:"mtls_url,omitempty"`
+	EnrolledAt   time.Time `json:"enrolled_at"`
+}
+
+type enrollRequest struct {
+	Kind      string `json:"kind"`
+	CSR       string `json:"csr,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+type enrollResponse struct {
+	CredentialID  string `json:"credential_id"`
+	Kind          string `json:"kind"`
+	Certificate   string `json:"certificate"`
+	CACertificate string `json:"ca_certificate"`
+	MTLSURL       string `json:"mtls_url"`
+}
+
+// Identity is shared by every component talking to the server. It is an
+// http.RoundTripper: requests to the mTLS listener present the client
+// certificate, the others go through the regular transport.
+type Identity struct {
+	cfg    *config.Config
+	hostID string
+	plain  *http.Transport
+	now    func() time.Time
+
+	mu          sync.RWMutex
+	st          *state // nil until enrolled
+	cert        *tls.Certificate
+	mtls        *http.Transport
+	mtlsHost    string
+	mtlsTLS     *tls.Config
+	signKey     ed25519.PrivateKey
+	rejected    bool
+	nextAttempt time.Time
+}
+
+// New returns the identity for cfg, not enrolled yet; call Load to pick up a
+// credential enrolled by a previous run.
+func New(cfg *config.Config) *Identity {
+	hostID, _, _ := strings.Cut(cfg.APIKey, ".")
+	return &Identity{
+		cfg:    cfg,
+		hostID: hostID,
+		plain: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: cfg.InsecureSkipVerify, //nolint:gosec // operator opt-in
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Load reads the credential stored in identity_dir, if any.
+func (id *Identity) Load() error {
+	if id.cfg.AuthMode != config.AuthMTLS && id.cfg.AuthMode != config.AuthSignature {
+		return nil
+	}
+	data, err := os.ReadFile(id.path(stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("parse %s: %w", stateFile, err)
+	}
+
+	id.mu.Lock()
+	defer id.mu.Unlock()
+	switch st.Kind {
+	case kindCertificate:
+		keyPEM, err := os.ReadFile(id.path(clientKeyFile))
+		if err != nil {
+			return err
+		}
+		certPEM, err := os.ReadFile(id.path(clientCertFile))
+		if err != nil {
+			return err
+		}
+		caPEM, err := os.ReadFile(id.path(caCertFile))
+		if err != nil {
+			return err
+		}
+		return id.useCertificate(&st, certPEM, keyPEM, caPEM)
+	case kindEd25519:
+		keyPEM, err := os.ReadFile(id.path(signingKeyFile))
+		if err != nil {
+			return err
+		}
+		key, err := parseSigningKey(keyPEM)
+		if err != nil {
+			return err
+		}
+		id.st, id.signKey = &st, key
+		return nil
+	default:
+		return fmt.Errorf("%s: unknown credential kind %q", stateFile, st.Kind)
+	}
+}
+
+// BaseURL is where requests go: the server's mTLS listener once a client
+// certificate is enrolled, server_url otherwise.
+func (id *Identity) BaseURL() string {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	if id.st != nil && id.st.Kind == kindCertificate {
+		return strings.TrimRight(id.st.MTLSURL, "/")
+	}
+	return strings.TrimRight(id.cfg.ServerURL, "/")
+}
+
+// TLSConfig is the TLS configuration for a connection to BaseURL, for
+// clients that do not go through RoundTrip (the WebSocket dialer).
+func (id *Identity) TLSConfig() *tls.Config {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	if id.mtlsTLS != nil {
+		return id.mtlsTLS
+	}
+	return id.plain.TLSClientConfig
+}
+
+// Authorize adds the credentials to req, whose body is body: the API key
+// until a credential is enrolled, the signature headers with a signing key,
+// nothing with a client certificate (presented in the TLS handshake).
+func (id *Identity) Authorize(req *http.Request, body []byte) {
+	id.mu.RLock()
+	st, key := id.st, id.signKey
+	id.mu.RUnlock()
+	switch {
+	case st == nil:
+		req.Header.Set("X-API-Key", id.cfg.APIKey)
+	case st.Kind == kindEd25519:
+		signRequest(req, id.hostID, key, body, id.now())
+	}
+}
+
+// RoundTrip implements http.RoundTripper. A 401 on a request authenticated by
+// the enrolled credential makes the next Ensure enroll again.
+func (id *Identity) RoundTrip(req *http.Request) (*http.Response, error) {
+	id.mu.RLock()
+	transport, enrolled := id.plain, id.st != nil
+	if id.mtls != nil && req.URL.Host == id.mtlsHost {
+		transport = id.mtls
+	}
+	id.mu.RUnlock()
+
+	resp, err := transport.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && enrolled && req.Header.Get("X-API-Key") == "" {
+		id.mu.Lock()
+		if !id.rejected {
+			slog.Warn("server rejected the agent credential, enrolling again", "path", req.URL.Path)
+		}
+		id.rejected = true
+		id.nextAttempt = time.Time{}
+		id.mu.Unlock()
+	}
+	return resp, err
+}
+
+// Ensure enrolls a credential when auth_mode asks for one and none is held,
+// renews the client certificate once two thirds of its lifetime have passed,
+// and enrolls again after the server rejected the current credential. Cheap
+// when there is nothing to do; call it every report cycle.
+func (id *Identity) Ensure(ctx context.Context) error {
+	kind := ""
+	switch id.cfg.AuthMode {
+	case config.AuthMTLS:
+		kind = kindCertificate
+	case config.AuthSignature:
+		kind = kindEd25519
+	default:
+		return nil
+	}
+
+	id.mu.Lock()
+	now := id.now()
+	due, reason := id.enrollmentDue(kind, now)
+	if !due || now.Before(id.nextAttempt) {
+		id.mu.Unlock()
+		return nil
+	}
+	// A credential the server rejected, or an expired certificate (refused
+	// in the TLS handshake), cannot authenticate its own replacement.
+	if id.rejected || (id.cert != nil && now.After(id.cert.Leaf.NotAfter)) {
+		id.st, id.cert, id.signKey = nil, nil, nil
+		id.mtls, id.mtlsHost, id.mtlsTLS = nil, "", nil
+	}
+	id.rejected = false
+	id.mu.Unlock()
+
+	slog.Info("enrolling agent credential", "kind", kind, "reason", reason)
+	err := id.enroll(ctx, kind)
+	var authErr *unauthorizedError
+	if errors.As(err, &authErr) && id.enrolled() {
+		// The current credential was refused: start over with the API key.
+		id.mu.Lock()
+		id.st, id.cert, id.signKey = nil, nil, nil
+		id.mtls, id.mtlsHost, id.mtlsTLS = nil, "", nil
+		id.mu.Unlock()
+		err = id.enroll(ctx, kind)
+	}
+	if err != nil {
+		id.mu.Lock()
+		id.nextAttempt = id.now().Add(retryDelay)
+		id.mu.Unlock()
+		return err
+	}
+	slog.Info("agent credential enrolled", "kind", kind)
+	return nil
+}
+
+// enrollmentDue reports whether Ensure must enroll, and why. Caller holds mu.
+func (id *Identity) enrollmentDue(kind string, now time.Time) (bool, string) {
+	switch {
+	case id.st == nil:
+		return true, "not enrolled"
+	case id.rejected:
+		return true, "credential rejected by the server"
+	case id.st.Kind != kind:
+		return true, "auth_mode changed"
+	case id.cert != nil:
+		leaf := id.cert.Leaf
+		renewAt := leaf.NotBefore.Add(leaf.NotAfter.Sub(leaf.NotBefore) * 2 / 3)
+		if !now.Before(renewAt) {
+			return true, "certificate renewal"
+		}
+	}
+	return false, ""
+}
+
+func (id *Identity) enrolled() bool {
+	id.mu.RLock()
+	defer id.mu.RUnlock()
+	return id.st != nil
+}
+
+type unauthorizedError struct{ msg string }
+
+func (e *unauthorizedError) Error() string { return e.msg }
+
+// enroll generates a new key of the given kind, has the server register it
+// (authenticated by the current credential, or the API key when none) and
+// switches to it once stored on disk.
+func (id *Identity) enroll(ctx context.Context, kind string) error {
+	req := enrollRequest{Kind: kind}
+	var certKeyPEM, signKeyPEM []byte
+	var signKey ed25519.PrivateKey
+	switch kind {
+	case kindCertificate:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return err
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+		if err != nil {
+			return err
+		}
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return err
+		}
+		certKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+		req.CSR = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+	case kindEd25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return err
+		}
+		signKey = priv
+		signKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+		req.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	}
+
+	resp, err := id.post(ctx, req)
+	if err != nil {
+		return err
+	}
+	st := &state{Kind: kind, CredentialID: resp.CredentialID, MTLSURL: resp.MTLSURL, EnrolledAt: id.now().UTC()}
+
+	if err := os.MkdirAll(id.cfg.IdentityDir, 0o700); err != nil {
+		return err
+	}
+	id.mu.Lock()
+	defer id.mu.Unlock()
+	oldMTLS := id.mtls
+	id.rejected = false
+	switch kind {
+	case kindCertificate:
+		if err := id.useCertificate(st, []byte(resp.Certificate), certKeyPEM, []byte(resp.CACertificate)); err != nil {
+			return err
+		}
+		for name, data := range map[string][]byte{
+			clientKeyFile:  certKeyPEM,
+			clientCertFile: []byte(resp.Certificate),
+			caCertFile:     []byte(resp.CACertificate),
+		} {
+			if err := writeFile(id.path(name), data); err != nil {
+				return err
+			}
+		}
+	case kindEd25519:
+		if err := writeFile(id.path(signingKeyFile), signKeyPEM); err != nil {
+			return err
+		}
+		id.st, id.signKey = st, signKey
+		id.cert, id.mtls, id.mtlsHost, id.mtlsTLS = nil, nil, "", nil
+	}
+	data, _ := json.MarshalIndent(st, "", "  ")
+	if err := writeFile(id.path(stateFile), data); err != nil {
+		return err
+	}
+	// Pooled connections still present the previous certificate, which the
+	// server only accepts for a short grace period.
+	if oldMTLS != nil {
+		oldMTLS.CloseIdleConnections()
+	}
+	return nil
+}
+
+// post sends the enrollment request to BaseURL.
+func (id *Identity) post(ctx context.Context, body enrollRequest) (*enrollResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, id.BaseURL()+enrollPath, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	id.Authorize(req, data)
+
+	client := &http.Client{Timeout: 30 * time.Second, Transport: id}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("enroll: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, &unauthorizedError{msg: fmt.Sprintf("enroll: server returned status 401: %s", respBody)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("enroll: server returned status %d: %s", resp.StatusCode, respBody)
+	}
+	var out enrollResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("enroll: parse response: %w", err)
+	}
+	if body.Kind == kindCertificate && (out.Certificate == "" || out.CACertificate == "" || out.MTLSURL == "") {
+		return nil, errors.New("enroll: incomplete certificate response")
+	}
+	return &out, nil
+}
+
+// useCertificate switches to a client certificate and the transport for the
+// mTLS listener. The server certificate of that listener is verified against
+// the agent CA only. Caller holds mu.
+func (id *Identity) useCertificate(st *state, certPEM, keyPEM, caPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("client certificate: %w", err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return fmt.Errorf("client certificate: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return errors.New("invalid agent CA certificate")
+	}
+	u, err := url.Parse(st.MTLSURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("invalid mTLS URL %q", st.MTLSURL)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cert},
+	}
+	id.st, id.cert = st, &cert
+	id.mtlsHost, id.mtlsTLS = u.Host, tlsCfg
+	id.mtls = &http.Transport{TLSClientConfig: tlsCfg}
+	id.signKey = nil
+	return nil
+}
+
+func (id *Identity) path(name string) string {
+	return filepath.Join(id.cfg.IdentityDir, name)
+}
+
+// signRequest sets the signature headers of req, signed by key.
+func signRequest(req *http.Request, hostID string, key ed25519.PrivateKey, body []byte, now time.Time) {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	n := hex.EncodeToString(nonce)
+	sig := ed25519.Sign(key, SigningString(req.Method, req.URL.Path, ts, n, body))
+	req.Header.Set(HeaderHost, hostID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderNonce, n)
+	req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(sig))
+}
+
+// SigningString is what a signed request signs; it must stay identical to
+// the server's agentpki.SigningString (see protocol/README.md).
+func SigningString(method, path, timestamp, nonce string, body []byte) []byte {
+	if i := strings.Index(path, "/api/agent/"); i > 0 {
+		path = path[i:]
+	}
+	sum := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		signatureVersion,
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(sum[:]),
+	}, "\n"))
+}
+
+func parseSigningKey(keyPEM []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid signing key PEM")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an Ed25519 key")
+	}
+	return priv, nil
+}
+
+// writeFile replaces path atomically, readable by the agent only.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
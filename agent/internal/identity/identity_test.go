@@ -0,0 +1,207 @@
+package identity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/agent/internal/config"
+)
+
+// fakeServer enrolls Ed25519 keys like the server does: with the API key
+// first, then only with a valid signature; and it refuses the reports signed
+// by a key it revoked.
+type fakeServer struct {
+	mu      sync.Mutex
+	keys    []ed25519.PublicKey
+	revoked bool
+	enrolls []string // how each enrollment was authenticated
+}
+
+func (f *fakeServer) verified(r *http.Request, body []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.revoked || len(f.keys) == 0 || r.Header.Get(HeaderHost) != "host-1" {
+		return false
+	}
+	sig, _ := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSignature))
+	msg := SigningString(r.Method, r.URL.Path, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), body)
+	return ed25519.Verify(f.keys[len(f.keys)-1], msg, sig)
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.URL.Path == enrollPath:
+		f.mu.Lock()
+		active := len(f.keys) > 0 && !f.revoked
+		f.mu.Unlock()
+		method := ""
+		switch {
+		case r.Header.Get("X-API-Key") == "host-1.secret" && !active:
+			method = "api_key"
+		case f.verified(r, body):
+			method = "signature"
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req enrollRequest
+		_ = json.Unmarshal(body, &req)
+		pub, _ := base64.StdEncoding.DecodeString(req.PublicKey)
+		f.mu.Lock()
+		f.keys = append(f.keys, ed25519.PublicKey(pub))
+		f.revoked = false
+		f.enrolls = append(f.enrolls, method)
+		f.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(enrollResponse{CredentialID: "cred", Kind: kindEd25519})
+	case f.verified(r, body):
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}
+
+func newSignatureIdentity(t *testing.T, serverURL, dir string) *Identity {
+	t.Helper()
+	return New(&config.Config{ServerURL: serverURL, APIKey: "host-1.secret", AuthMode: config.AuthSignature, IdentityDir: dir})
+}
+
+func report(t *testing.T, id *Identity) int {
+	t.Helper()
+	body := []byte(`{"metrics":{}}`)
+	req, _ := http.NewRequest(http.MethodPost, id.BaseURL()+"/api/agent/report", strings.NewReader(string(body)))
+	id.Authorize(req, body)
+	resp, err := (&http.Client{Transport: id}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestEnsure_SignatureEnrollAndReload(t *testing.T) {
+	srv := &fakeServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	dir := t.TempDir()
+
+	id := newSignatureIdentity(t, ts.URL, dir)
+	if err := id.Ensure(context.Background()); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if code := report(t, id); code != http.StatusOK {
+		t.Fatalf("signed report: status %d", code)
+	}
+	// Nothing to do on the next cycle.
+	if err := id.Ensure(context.Background()); err != nil || len(srv.enrolls) != 1 {
+		t.Fatalf("second ensure: err=%v enrolls=%v", err, srv.enrolls)
+	}
+
+	// A restart signs with the stored key, without the API key.
+	reloaded := newSignatureIdentity(t, ts.URL, dir)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/agent/ws", nil)
+	reloaded.Authorize(req, nil)
+	if req.Header.Get("X-API-Key") != "" || req.Header.Get(HeaderSignature) == "" {
+		t.Fatalf("reloaded identity headers: %v", req.Header)
+	}
+	if code := report(t, reloaded); code != http.StatusOK {
+		t.Fatalf("report after reload: status %d", code)
+	}
+}
+
+func TestEnsure_EnrollsAgainAfterRevocation(t *testing.T) {
+	srv := &fakeServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	id := newSignatureIdentity(t, ts.URL, t.TempDir())
+	if err := id.Ensure(context.Background()); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	srv.mu.Lock()
+	srv.revoked = true
+	srv.mu.Unlock()
+
+	if code := report(t, id); code != http.StatusUnauthorized {
+		t.Fatalf("report with a revoked key: status %d", code)
+	}
+	if err := id.Ensure(context.Background()); err != nil {
+		t.Fatalf("ensure after revocation: %v", err)
+	}
+	if len(srv.enrolls) != 2 || srv.enrolls[1] != "api_key" {
+		t.Fatalf("enrollments = %v, want a second one with the API key", srv.enrolls)
+	}
+	if code := report(t, id); code != http.StatusOK {
+		t.Fatalf("report after enrolling again: status %d", code)
+	}
+}
+
+func TestEnrollmentDue_CertificateRenewal(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "ca"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour * 24 * 365),
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	issued := time.Now().Add(-20 * 24 * time.Hour)
+	leafDER, _ := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "host-1"},
+		NotBefore: issued, NotAfter: issued.Add(30 * 24 * time.Hour),
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, &key.PublicKey, caKey)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	id := New(&config.Config{APIKey: "host-1.secret", AuthMode: config.AuthMTLS, IdentityDir: t.TempDir()})
+	id.mu.Lock()
+	defer id.mu.Unlock()
+	err := id.useCertificate(&state{Kind: kindCertificate, MTLSURL: "https://supervisor.example.com:8443"},
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	if err != nil {
+		t.Fatalf("use certificate: %v", err)
+	}
+
+	if due, _ := id.enrollmentDue(kindCertificate, issued.Add(19*24*time.Hour)); due {
+		t.Error("renewal due before two thirds of the lifetime")
+	}
+	if due, _ := id.enrollmentDue(kindCertificate, issued.Add(20*24*time.Hour)); !due {
+		t.Error("renewal not due at two thirds of the lifetime")
+	}
+	if due, _ := id.enrollmentDue(kindEd25519, issued.Add(time.Hour)); !due {
+		t.Error("switching auth_mode must enroll the new kind")
+	}
+}
+
+// TestSigningString pins the documented format (protocol/README.md); the
+// server's agentpki test pins the same vector.
+func TestSigningString(t *testing.T) {
+	got := string(SigningString("post", "/supervisor/api/agent/report", "1700000000", "n1", []byte(`{}`)))
+	want := "ss-agent-v1\nPOST\n/api/agent/report\n1700000000\nn1\n44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if got != want {
+		t.Fatalf("SigningString = %q, want %q", got, want)
+	}
+}
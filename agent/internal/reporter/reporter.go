@@ -12,6 +12,7 @@ import (
 	"github.com/serversupervisor/agent/internal/collector"
 	"github.com/serversupervisor/agent/internal/config"
 	"github.com/serversupervisor/agent/internal/sender"
+	"github.com/serversupervisor/agent/internal/spool"
 )
 
 // collectionTimeout bounds the parallel metric-collection phase of Send as a
@@ -32,6 +33,15 @@ const collectionTimeout = 25 * time.Second
 // otherwise run for tens of minutes.
 const postUUAptRefreshTimeout = 5 * time.Minute
 
+// Offline buffer replay: samples go out in batches well under the server's
+// per-request limit (500), and each cycle replays at most
+// backfillBatchesPerCycle of them so a long backlog (24h = 2880 samples at
+// the default interval) drains over a few cycles instead of holding up one.
+const (
+	backfillBatchSize       = 200
+	backfillBatchesPerCycle = 10
+)
+
 // Reporter builds and sends periodic host reports.
 type Reporter struct {
 	cfg         *config.Config
@@ -50,17 +60,33 @@ type Reporter struct {
 	// every cycle: an unbounded goroutine/memory leak ending in an OOM kill,
 	// not just one slow report.
 	webLogsRunning atomic.Bool
+
+	// buffer holds the metrics of reports the server never received; nil
+	// when offline buffering is disabled or its file could not be opened.
+	buffer *spool.Queue
 }
 
 // New returns a ready Reporter. skipMetrics is shared with the caller — the
 // reporter updates it after each successful send based on the server directive.
 func New(cfg *config.Config, tasks *config.TasksConfig, skipMetrics *atomic.Bool, version string) *Reporter {
-	return &Reporter{
+	r := &Reporter{
 		cfg:         cfg,
 		tasks:       tasks,
 		skipMetrics: skipMetrics,
 		version:     version,
 	}
+	if cfg.OfflineBufferFile != "" {
+		q, err := spool.Open(cfg.OfflineBufferFile, max(cfg.OfflineBufferMaxSamples, 1))
+		if err != nil {
+			slog.Warn("offline buffer disabled", "path", cfg.OfflineBufferFile, "err", err)
+		} else {
+			r.buffer = q
+			if n := q.Len(); n > 0 {
+				slog.Info("offline buffer loaded, will replay once the server is reachable", "samples", n)
+			}
+		}
+	}
+	return r
 }
 
 // Send collects host metrics, builds the report, sends it, then enqueues any
@@ -73,8 +99,11 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		dockerData       *sender.DockerPayload
 		dockerNetworks   []collector.DockerNetwork
 		composeProjects  []collector.ComposeProject
+		dockerDiskUsage  *collector.DockerDiskUsageReport
+		dockerSwarm      *collector.DockerSwarmReport
 		diskMetrics      []collector.DiskMetrics
 		diskHealth       []collector.DiskHealth
+		netInterfaces    []collector.NetInterface
 		uuData           *collector.UnattendedUpgradesStatus
 		aptStatus        *collector.AptStatus
 		webLogs          *collector.WebLogReport
@@ -82,6 +111,7 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		resticProfiles   []string
 		resticGroups     []string
 		networkFlows     *collector.NetworkFlowsReport
+		crowdSec         *collector.CrowdSecReport
 	)
 
 	var wg sync.WaitGroup
@@ -127,6 +157,18 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 			} else {
 				slog.Warn("compose projects collection skipped", "err", err)
 			}
+
+			if usage, err := collector.CollectDockerDiskUsage(ctx); err == nil {
+				dockerDiskUsage = usage
+			} else {
+				slog.Warn("docker disk usage collection skipped", "err", err)
+			}
+
+			if swarm, err := collector.CollectDockerSwarm(ctx); err == nil {
+				dockerSwarm = swarm
+			} else {
+				slog.Warn("docker swarm collection skipped", "err", err)
+			}
 		}()
 	} else {
 		dockerData = &sender.DockerPayload{Containers: []collector.DockerContainer{}}
@@ -178,6 +220,16 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var err error
+		netInterfaces, err = collector.CollectNetInterfaces()
+		if err != nil {
+			slog.Warn("failed to collect network interfaces", "err", err)
+		}
+	}()
+
 	if r.cfg.CollectSMART {
 		wg.Add(1)
 		go func() {
@@ -215,6 +267,7 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 			report, err := collector.CollectWebLogs(
 				webLogsCtx,
 				globs,
+				r.cfg.WebLogsFormats,
 				r.cfg.WebLogsTailLines,
 				r.cfg.WebLogsTopN,
 				r.cfg.WebLogsRequestsLimit,
@@ -249,6 +302,28 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		}()
 	}
 
+	if r.cfg.CollectCrowdSec {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report, err := collector.CollectCrowdSec(ctx,
+				r.cfg.CrowdSecConnectionString,
+				r.cfg.CrowdSecAPIKey,
+				r.cfg.CrowdSecAlertsMachineID,
+				r.cfg.CrowdSecAlertsPassword,
+				r.cfg.CrowdSecCscliCommand,
+			)
+			if err != nil {
+				slog.Warn("crowdsec collection skipped", "err", err)
+				return
+			}
+			if report != nil && report.BouncersError != "" {
+				slog.Warn("crowdsec bouncers not listed", "err", report.BouncersError)
+			}
+			crowdSec = report
+		}()
+	}
+
 	if r.cfg.CollectRestic {
 		wg.Add(1)
 		go func() {
@@ -305,6 +380,7 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		Journal:      true,
 		Restic:       r.cfg.CollectRestic,
 		NetworkFlows: r.cfg.CollectNetworkFlows,
+		CrowdSec:     r.cfg.CrowdSecConnectionString != "" && r.cfg.CrowdSecAlertsMachineID != "" && r.cfg.CrowdSecAlertsPassword != "",
 	}
 
 	diagnostics := collector.CheckConfig(r.cfg)
@@ -320,14 +396,18 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 		WebLogs:            webLogs,
 		DockerNetworks:     dockerNetworks,
 		ComposeProjects:    composeProjects,
+		DockerDiskUsage:    dockerDiskUsage,
+		DockerSwarm:        dockerSwarm,
 		DiskMetrics:        diskMetrics,
 		DiskHealth:         diskHealth,
+		NetInterfaces:      netInterfaces,
 		CustomTasks:        customTasksList,
 		TasksConfigYAML:    config.LoadTasksConfigRaw(),
 		Restic:             resticStatus,
 		ResticProfiles:     resticProfiles,
 		ResticGroups:       resticGroups,
 		NetworkFlows:       networkFlows,
+		CrowdSec:           crowdSec,
 		Timestamp:          time.Now(),
 	}
 	trimWebLogsForReportSize(report, r.cfg.MaxReportBodyBytes)
@@ -335,8 +415,17 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 	response, err := s.SendReportWithRetry(ctx, report)
 	if err != nil {
 		slog.Error("failed to send report", "err", err)
+		if collectedMetrics != nil && !r.skipMetrics.Load() {
+			r.bufferSample(&sender.BackfillSample{
+				Timestamp:   report.Timestamp,
+				Metrics:     collectedMetrics,
+				DiskMetrics: diskMetrics,
+			})
+		}
 		return
 	}
+	// Commands go first; the backlog is replayed once they are queued.
+	defer r.replayBuffer(ctx, s)
 
 	if r.skipMetrics.Load() {
 		slog.Info("report sent", "source", "proxmox", "uptime_s", collectedMetrics.Uptime)
@@ -369,6 +458,50 @@ func (r *Reporter) Send(ctx context.Context, s *sender.Sender, cmdQueue chan<- [
 	}
 }
 
+// bufferSample keeps the metrics of an undelivered report for a later replay.
+func (r *Reporter) bufferSample(sample *sender.BackfillSample) {
+	if r.buffer == nil {
+		return
+	}
+	if err := r.buffer.Push(sample); err != nil {
+		slog.Warn("failed to buffer undelivered metrics", "err", err)
+		return
+	}
+	slog.Info("metrics buffered for replay", "buffered_samples", r.buffer.Len())
+}
+
+// replayBuffer sends buffered samples, oldest first, after a successful
+// report. A batch is dropped from the buffer once the server answered — the
+// samples it skipped (already stored, too old) would be skipped again — and
+// kept on any error so the next cycle retries it.
+func (r *Reporter) replayBuffer(ctx context.Context, s *sender.Sender) {
+	if r.buffer == nil || r.buffer.Len() == 0 {
+		return
+	}
+	accepted, skipped := 0, 0
+	for range backfillBatchesPerCycle {
+		batch := r.buffer.Peek(backfillBatchSize)
+		if len(batch) == 0 || ctx.Err() != nil {
+			break
+		}
+		resp, err := s.SendBackfill(ctx, batch)
+		if err != nil {
+			slog.Warn("metrics backfill failed, will retry next cycle", "buffered_samples", r.buffer.Len(), "err", err)
+			break
+		}
+		if err := r.buffer.Drop(len(batch)); err != nil {
+			slog.Warn("failed to trim offline buffer", "err", err)
+			break
+		}
+		accepted += resp.Accepted
+		skipped += resp.Skipped
+	}
+	if accepted+skipped > 0 {
+		slog.Info("buffered metrics replayed",
+			"accepted", accepted, "skipped", skipped, "remaining", r.buffer.Len())
+	}
+}
+
 // trimWebLogsForReportSize shrinks web.Requests until the marshaled report fits
 // within maxBodyBytes. Uses a proportional estimate (2 marshals in the common
 // case) instead of the previous O(log N) full-report marshal loop.
@@ -0,0 +1,74 @@
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/agent/internal/collector"
+	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
+	"github.com/serversupervisor/agent/internal/sender"
+)
+
+// TestReplayBuffer_KeepsSamplesUntilServerAnswers buffers samples, fails one
+// replay (they stay buffered), then replays them oldest first and empties the
+// buffer, which also survives an agent restart in between.
+func TestReplayBuffer_KeepsSamplesUntilServerAnswers(t *testing.T) {
+	var fail atomic.Bool
+	var got []sender.BackfillSample
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/agent/backfill" {
+			http.NotFound(w, r)
+			return
+		}
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var body struct {
+			Samples []sender.BackfillSample `json:"samples"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body.Samples...)
+		_ = json.NewEncoder(w).Encode(sender.BackfillResponse{Accepted: len(body.Samples)})
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		ServerURL:               srv.URL,
+		OfflineBufferFile:       filepath.Join(t.TempDir(), "offline_buffer.jsonl"),
+		OfflineBufferMaxSamples: 10,
+	}
+	r := New(cfg, nil, &atomic.Bool{}, "test")
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range 3 {
+		r.bufferSample(&sender.BackfillSample{
+			Timestamp: base.Add(time.Duration(i) * time.Minute),
+			Metrics:   &collector.SystemMetrics{CPUUsagePercent: float64(i)},
+		})
+	}
+
+	s := sender.New(cfg, identity.New(cfg))
+	fail.Store(true)
+	r.replayBuffer(context.Background(), s)
+	if r.buffer.Len() != 3 {
+		t.Fatalf("a failed replay must keep the samples, %d left", r.buffer.Len())
+	}
+
+	// Restart: the buffer is reloaded from disk.
+	r = New(cfg, nil, &atomic.Bool{}, "test")
+	fail.Store(false)
+	r.replayBuffer(context.Background(), s)
+	if r.buffer.Len() != 0 {
+		t.Fatalf("%d samples left after a successful replay", r.buffer.Len())
+	}
+	if len(got) != 3 || !got[0].Timestamp.Equal(base) || got[2].Metrics.CPUUsagePercent != 2 {
+		t.Fatalf("replayed %+v, want the 3 samples oldest first with their original timestamps", got)
+	}
+}
@@ -3,8 +3,8 @@ package sender
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -13,12 +13,28 @@ import (
 
 	"github.com/serversupervisor/agent/internal/collector"
 	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
 )
 
 type Sender struct {
 	cfg           *config.Config
-	reportClient  *http.Client // 30s — periodic reports
-	commandClient *http.Client // 30min — long-running command results/streaming
+	id            *identity.Identity // server URL and request authentication
+	reportClient  *http.Client       // 30s — periodic reports
+	commandClient *http.Client       // 30min — long-running command results/streaming
+	channel       Channel            // optional push channel tried before HTTP for results/streaming
+}
+
+// ErrChannelUnavailable is returned (wrapped) by a Channel that could not carry
+// a message: no session, or no ack in time. The Sender then falls back to HTTP.
+var ErrChannelUnavailable = errors.New("push channel unavailable")
+
+// Channel is a second transport for command results and output — the agent
+// WebSocket's protocol v2 session (see agentws). Any error wrapping
+// ErrChannelUnavailable makes the Sender fall back to its HTTP endpoints; any
+// other error is final.
+type Channel interface {
+	SendResult(ctx context.Context, result *CommandResult) error
+	SendChunk(commandID, chunk string) error
 }
 
 // Capabilities reports which collectors are active on this agent. It mirrors the
@@ -34,6 +50,7 @@ type Capabilities struct {
 	Journal      bool `json:"journal"`
 	Restic       bool `json:"restic"`
 	NetworkFlows bool `json:"network_flows"`
+	CrowdSec     bool `json:"crowdsec"` // machine credentials set: crowdsec ban/unban commands work
 }
 
 // DockerPayload is the "docker" section of a report. The server decodes it into
@@ -68,6 +85,7 @@ type Report struct {
 	ComposeProjects []collector.ComposeProject    `json:"compose_projects,omitempty"`  // Docker Compose projects
 	DiskMetrics     []collector.DiskMetrics       `json:"disk_metrics,omitempty"`      // Detailed disk usage with inodes
 	DiskHealth      []collector.DiskHealth        `json:"disk_health,omitempty"`       // SMART disk health data
+	NetInterfaces   []collector.NetInterface      `json:"net_interfaces,omitempty"`    // Per-interface link state and cumulative counters, see collector.CollectNetInterfaces
 	CustomTasks     []config.TaskSummary          `json:"custom_tasks,omitempty"`      // Available custom tasks from tasks.yaml
 	TasksConfigYAML string                        `json:"tasks_config_yaml,omitempty"` // Raw tasks.yaml content
 	Restic          *collector.ResticStatus       `json:"restic,omitempty"`
@@ -75,6 +93,17 @@ type Report struct {
 	ResticGroups    []string                      `json:"restic_groups,omitempty"`   // Group names from resticprofile.yaml's "groups" section
 	NetworkFlows    *collector.NetworkFlowsReport `json:"network_flows,omitempty"`   // Per-cycle "top talkers" (conntrack-derived), see collector.CollectNetworkFlows
 	Timestamp       time.Time                     `json:"timestamp"`
+
+	// DockerDiskUsage is only set on the cycles where /system/df was actually
+	// re-measured (see collector.CollectDockerDiskUsage), so every copy the
+	// server receives is a new history point.
+	DockerDiskUsage *collector.DockerDiskUsageReport `json:"docker_disk_usage,omitempty"`
+	// DockerSwarm is set whenever the engine answered /info; Manager false
+	// tells the server to drop any Swarm state it kept for this host.
+	DockerSwarm *collector.DockerSwarmReport `json:"docker_swarm,omitempty"`
+	// CrowdSec is only set on the cycles where the local API was actually
+	// queried (see collector.CollectCrowdSec).
+	CrowdSec *collector.CrowdSecReport `json:"crowdsec,omitempty"`
 }
 
 type ReportResponse struct {
@@ -86,6 +115,23 @@ type ReportResponse struct {
 	SkipMetrics bool `json:"skip_metrics"`
 }
 
+// BackfillSample is the metrics-only form of a report the server never
+// received, kept in the offline buffer and replayed via SendBackfill with its
+// original collection time. Mirrors the server-side models.BackfillSample.
+type BackfillSample struct {
+	Timestamp   time.Time                `json:"timestamp"`
+	Metrics     *collector.SystemMetrics `json:"metrics"`
+	DiskMetrics []collector.DiskMetrics  `json:"disk_metrics,omitempty"`
+}
+
+// BackfillResponse counts the samples the server stored and those it skipped
+// (already stored, too old, or not older than its live data). Either way the
+// agent is done with them.
+type BackfillResponse struct {
+	Accepted int `json:"accepted"`
+	Skipped  int `json:"skipped"`
+}
+
 type PendingCommand struct {
 	ID      string `json:"id"`      // UUID
 	Module  string `json:"module"`  // docker | apt | systemd | journal
@@ -106,30 +152,31 @@ type CommandResult struct {
 	UnattendedUpgrades interface{} `json:"unattended_upgrades,omitempty"`
 }
 
-func New(cfg *config.Config) *Sender {
+func New(cfg *config.Config, id *identity.Identity) *Sender {
 	if cfg.InsecureSkipVerify {
 		slog.Warn("TLS certificate verification is disabled — not suitable for production")
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: cfg.InsecureSkipVerify,
-		},
-	}
-
 	return &Sender{
 		cfg: cfg,
+		id:  id,
 		reportClient: &http.Client{
 			Timeout:   30 * time.Second,
-			Transport: transport,
+			Transport: id,
 		},
 		commandClient: &http.Client{
 			Timeout:   30 * time.Minute,
-			Transport: transport,
+			Transport: id,
 		},
 	}
 }
 
+// SetChannel routes command results and output through ch first. Must be
+// called before any command runs.
+func (s *Sender) SetChannel(ch Channel) {
+	s.channel = ch
+}
+
 // SendReport sends a full report to the server and returns any pending commands
 func (s *Sender) SendReport(ctx context.Context, report *Report) (*ReportResponse, error) {
 	data, err := json.Marshal(report)
@@ -137,13 +184,13 @@ func (s *Sender) SendReport(ctx context.Context, report *Report) (*ReportRespons
 		return nil, fmt.Errorf("failed to marshal report: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/report", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/report", bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.reportClient.Do(req)
 	if err != nil {
@@ -194,21 +241,68 @@ func (s *Sender) SendReportWithRetry(ctx context.Context, report *Report) (*Repo
 	return nil, fmt.Errorf("all %d report attempts failed: %w", len(delays)+1, lastErr)
 }
 
-// ReportCommandResult sends the result of a command execution back to the server.
+// SendBackfill replays buffered samples, oldest first. Any non-OK answer is an
+// error: the samples stay buffered for the next attempt.
+func (s *Sender) SendBackfill(ctx context.Context, samples []json.RawMessage) (*BackfillResponse, error) {
+	data, err := json.Marshal(struct {
+		Samples []json.RawMessage `json:"samples"`
+	}{samples})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backfill: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/backfill", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	s.id.Authorize(req, data)
+
+	resp, err := s.reportClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send backfill: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response BackfillResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// ReportCommandResult sends the result of a command execution back to the server,
+// over the push channel when one is up, else over HTTP.
 // Uses the long-timeout commandClient to support lengthy operations (apt upgrade, etc.).
 func (s *Sender) ReportCommandResult(ctx context.Context, result *CommandResult) error {
+	if s.channel != nil {
+		err := s.channel.SendResult(ctx, result)
+		if !errors.Is(err, ErrChannelUnavailable) {
+			return err
+		}
+		slog.Debug("command result not acked on the push channel, falling back to HTTP", "command_id", result.CommandID, "err", err)
+	}
+
 	data, err := json.Marshal(result)
 	if err != nil {
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/command/result", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/command/result", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.commandClient.Do(req)
 	if err != nil {
@@ -225,9 +319,14 @@ func (s *Sender) ReportCommandResult(ctx context.Context, result *CommandResult)
 	return nil
 }
 
-// StreamCommandChunk sends a chunk of command output to the server for real-time streaming.
+// StreamCommandChunk sends a chunk of command output to the server for real-time streaming,
+// over the push channel when one is up, else over HTTP.
 // Uses commandClient (30min timeout) since streaming can span long operations.
 func (s *Sender) StreamCommandChunk(ctx context.Context, commandID string, chunk string) error {
+	if s.channel != nil && s.channel.SendChunk(commandID, chunk) == nil {
+		return nil
+	}
+
 	payload := struct {
 		CommandID string `json:"command_id"`
 		Chunk     string `json:"chunk"`
@@ -241,13 +340,13 @@ func (s *Sender) StreamCommandChunk(ctx context.Context, commandID string, chunk
 		return fmt.Errorf("failed to marshal chunk: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/command/stream", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/command/stream", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.commandClient.Do(req)
 	if err != nil {
@@ -274,13 +373,13 @@ func (s *Sender) SendAptStatus(ctx context.Context, status interface{}) error {
 		return fmt.Errorf("failed to marshal apt status: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/apt-status", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/apt-status", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.commandClient.Do(req)
 	if err != nil {
@@ -305,13 +404,13 @@ func (s *Sender) SendResticStatus(ctx context.Context, status *collector.ResticS
 		return fmt.Errorf("failed to marshal restic status: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/restic-status", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/restic-status", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.commandClient.Do(req)
 	if err != nil {
@@ -342,13 +441,13 @@ func (s *Sender) SendAuditLog(ctx context.Context, module, action, status, detai
 		return fmt.Errorf("failed to marshal audit log: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.ServerURL+"/api/agent/audit", bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.id.BaseURL()+"/api/agent/audit", bytes.NewReader(data))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-API-Key", s.cfg.APIKey)
+	s.id.Authorize(req, data)
 
 	resp, err := s.reportClient.Do(req)
 	if err != nil {
@@ -0,0 +1,51 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/serversupervisor/agent/internal/config"
+	"github.com/serversupervisor/agent/internal/identity"
+)
+
+// stubChannel answers every call with err.
+type stubChannel struct{ err error }
+
+func (c stubChannel) SendResult(context.Context, *CommandResult) error { return c.err }
+func (c stubChannel) SendChunk(string, string) error                   { return c.err }
+
+func TestReportCommandResult_ChannelThenHTTPFallback(t *testing.T) {
+	var posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer srv.Close()
+	cfg := &config.Config{ServerURL: srv.URL}
+	s := New(cfg, identity.New(cfg))
+	ctx := context.Background()
+	result := &CommandResult{CommandID: "c1", Status: "completed"}
+
+	s.SetChannel(stubChannel{})
+	if err := s.ReportCommandResult(ctx, result); err != nil || posts.Load() != 0 {
+		t.Fatalf("acked on the channel: err=%v, HTTP posts=%d, want none", err, posts.Load())
+	}
+
+	s.SetChannel(stubChannel{err: fmt.Errorf("%w: no session", ErrChannelUnavailable)})
+	if err := s.ReportCommandResult(ctx, result); err != nil || posts.Load() != 1 {
+		t.Fatalf("channel unavailable: err=%v, HTTP posts=%d, want one", err, posts.Load())
+	}
+	if err := s.StreamCommandChunk(ctx, "c1", "x"); err != nil || posts.Load() != 2 {
+		t.Fatalf("chunk fallback: err=%v, HTTP posts=%d, want two", err, posts.Load())
+	}
+
+	refused := errors.New("server refused command result")
+	s.SetChannel(stubChannel{err: refused})
+	if err := s.ReportCommandResult(ctx, result); !errors.Is(err, refused) || posts.Load() != 2 {
+		t.Fatalf("refusal: err=%v, HTTP posts=%d, want no fallback", err, posts.Load())
+	}
+}
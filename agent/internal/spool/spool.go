@@ -0,0 +1,175 @@
+// Package spool is a small, bounded, on-disk FIFO of JSON records, one per line.
+// The reporter uses it to keep the metrics of reports the server never
+// received, and replays them once the server is reachable again.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxLineBytes bounds a single record. A metrics sample is a few KB; anything
+// larger is a corrupt line and is skipped on load.
+const maxLineBytes = 1 << 20
+
+// Queue is a FIFO of raw JSON records persisted as JSON Lines. Push appends to
+// the file; dropping records (replayed, or evicted to honour the bound)
+// rewrites it through a temp file and rename(2), so a crash leaves either the
+// old or the new file, never a partial one. Safe for concurrent use.
+type Queue struct {
+	path string
+	max  int
+
+	mu    sync.Mutex
+	items []json.RawMessage
+}
+
+// Open loads the queue stored at path, creating its directory if needed. A
+// missing file is an empty queue; unreadable lines (a torn write after a
+// crash) are dropped. max bounds the number of records kept: once reached,
+// the oldest ones are evicted first.
+func Open(path string, max int) (*Queue, error) {
+	if max <= 0 {
+		return nil, fmt.Errorf("spool: max must be positive, got %d", max)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("spool: create directory: %w", err)
+	}
+	q := &Queue{path: path, max: max}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("spool: open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	dirty := false
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			dirty = true
+			continue
+		}
+		q.items = append(q.items, json.RawMessage(bytes.Clone(line)))
+	}
+	if err := sc.Err(); err != nil {
+		// An oversized line stops the scan: keep what was read, rewrite below.
+		dirty = true
+	}
+	if len(q.items) > q.max {
+		q.items = q.items[len(q.items)-q.max:]
+		dirty = true
+	}
+	if dirty {
+		if err := q.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// Len returns the number of queued records.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Push marshals v and appends it at the tail, evicting the oldest record when
+// the queue is full.
+func (q *Queue) Push(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("spool: marshal record: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.items = append(q.items, data)
+	if len(q.items) > q.max {
+		q.items = q.items[len(q.items)-q.max:]
+		return q.rewrite()
+	}
+
+	f, err := os.OpenFile(q.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("spool: open %s: %w", q.path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("spool: append: %w", err)
+	}
+	return f.Close()
+}
+
+// Peek returns up to n records from the head, oldest first, without removing
+// them.
+func (q *Queue) Peek(n int) []json.RawMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n = min(n, len(q.items))
+	out := make([]json.RawMessage, n)
+	copy(out, q.items[:n])
+	return out
+}
+
+// Drop removes the n oldest records, typically the ones just returned by Peek
+// and delivered.
+func (q *Queue) Drop(n int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n = min(n, len(q.items))
+	if n <= 0 {
+		return nil
+	}
+	q.items = q.items[n:]
+	return q.rewrite()
+}
+
+// rewrite replaces the file with the in-memory records. Caller holds q.mu.
+func (q *Queue) rewrite() error {
+	dir := filepath.Dir(q.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(q.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("spool: create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }() // no-op once the rename below succeeds
+
+	w := bufio.NewWriter(tmp)
+	for _, item := range q.items {
+		_, _ = w.Write(item)
+		_ = w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("spool: write: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("spool: sync: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("spool: close: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		return fmt.Errorf("spool: chmod: %w", err)
+	}
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		return fmt.Errorf("spool: rename: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package spool
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type record struct {
+	N int `json:"n"`
+}
+
+func values(t *testing.T, items []json.RawMessage) []int {
+	t.Helper()
+	out := make([]int, len(items))
+	for i, raw := range items {
+		var r record
+		if err := json.Unmarshal(raw, &r); err != nil {
+			t.Fatalf("unmarshal %s: %v", raw, err)
+		}
+		out[i] = r.N
+	}
+	return out
+}
+
+func TestQueue_FIFOPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "buffer.jsonl")
+	q, err := Open(path, 10)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		if err := q.Push(record{N: i}); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	if got := values(t, q.Peek(2)); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("peek = %v, want [1 2]", got)
+	}
+	if err := q.Drop(2); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+
+	reopened, err := Open(path, 10)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := values(t, reopened.Peek(10)); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Fatalf("after reopen = %v, want [3 4]", got)
+	}
+}
+
+func TestQueue_EvictsOldestWhenFull(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.jsonl")
+	q, err := Open(path, 3)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := q.Push(record{N: i}); err != nil {
+			t.Fatalf("push %d: %v", i, err)
+		}
+	}
+	if got := values(t, q.Peek(10)); len(got) != 3 || got[0] != 3 || got[2] != 5 {
+		t.Fatalf("queue = %v, want [3 4 5]", got)
+	}
+	reopened, err := Open(path, 3)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Len() != 3 {
+		t.Errorf("file holds %d records, want 3", reopened.Len())
+	}
+}
+
+func TestOpen_SkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "buffer.jsonl")
+	// A crash mid-append leaves a truncated last line.
+	if err := os.WriteFile(path, []byte("{\"n\":1}\n\n{\"n\":2}\n{\"n\":"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Open(path, 10)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if got := values(t, q.Peek(10)); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("queue = %v, want [1 2]", got)
+	}
+	// The torn line is gone from disk too, so later appends start on a clean line.
+	if err := q.Push(record{N: 3}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	reopened, err := Open(path, 10)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.Len() != 3 {
+		t.Errorf("reopened %d records, want 3", reopened.Len())
+	}
+}
@@ -1,12 +1,17 @@
 import { api } from './client'
-import type { DockerContainer, ComposeProject, DockerContainersPage } from '../types/docker'
+import type { DockerContainer, ComposeProject, DockerContainersPage, ComposeFileCommandRequest, DockerVolumeGrowth } from '../types/docker'
 
 export const dockerApi = {
   getContainers: (hostId: string) => api.get<DockerContainer[]>(`/v1/hosts/${hostId}/containers`),
   getAllContainers: () => api.get<DockerContainersPage>('/v1/docker/containers'),
   getComposeProjects: () => api.get<ComposeProject[]>('/v1/docker/compose'),
+  // Fleet-wide volumes with the largest growth over the period (latest - oldest sample).
+  getDockerVolumeGrowth: (period = '24h', limit = 10) =>
+    api.get<DockerVolumeGrowth[]>('/v1/docker/volumes/growth', { params: { period, limit } }),
   sendDockerCommand: (hostId: string, containerName: string, action: string, workingDir?: string) =>
     api.post('/v1/docker/command', { host_id: hostId, container_name: containerName, action, working_dir: workingDir ?? '' }),
+  sendComposeFileCommand: (req: ComposeFileCommandRequest) =>
+    api.post('/v1/docker/compose/file', req),
   sendJournalCommand: (hostId: string, serviceName: string) =>
     api.post('/v1/system/journalctl', { host_id: hostId, service_name: serviceName }),
   sendSystemdCommand: (hostId: string, serviceName: string, action: string) =>
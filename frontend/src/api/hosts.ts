@@ -2,6 +2,7 @@ import { api, rangeParams } from './client'
 import type { TimeRange } from './client'
 import type { Host, HostExposure, HostRegistration, HostUpdate } from '../types/host'
 import type { DiscoveredHost } from '../types/discovery'
+import type { DockerDiskUsageSummary, DockerDiskUsageHistory, DockerSwarmState } from '../types/docker'
 
 interface BulkHostResult {
   name: string
@@ -51,6 +52,21 @@ export const hostsApi = {
   getNetworkFlowsSummary: (hostId: string, period?: string, range?: TimeRange) =>
     api.get(`/v1/hosts/${hostId}/network/flows/summary`, { params: { period: period ?? '24h', ...rangeParams(range) } }),
 
+  // Docker disk usage (`docker system df -v`, re-measured every ~15 min).
+  // kind is a volume/container (name required) or a *_total kind (no name).
+  getDockerDiskUsage: (hostId: string) =>
+    api.get<DockerDiskUsageSummary>(`/v1/hosts/${hostId}/docker/disk-usage`),
+  getDockerDiskUsageHistory: (
+    hostId: string,
+    params: { kind: string; name?: string; period?: string },
+    range?: TimeRange,
+  ) =>
+    api.get<DockerDiskUsageHistory>(`/v1/hosts/${hostId}/docker/disk-usage/history`, {
+      params: { period: '168h', ...params, ...rangeParams(range) },
+    }),
+  getDockerSwarm: (hostId: string) =>
+    api.get<DockerSwarmState>(`/v1/hosts/${hostId}/docker/swarm`),
+
   // Metrics
   getMetricsHistory: (hostId: string, hours?: number) =>
     api.get(`/v1/hosts/${hostId}/metrics/history`, { params: { hours: hours ?? 24 } }),
@@ -11,6 +11,25 @@ import type {
   ProxmoxBackupRun,
   ProxmoxGuestLinkRequest,
   ProxmoxGuestLinkUpdate,
+  ProxmoxSnapshot,
+  ProxmoxSnapshotCreate,
+  ProxmoxSnapshotPolicy,
+  ProxmoxSnapshotPolicyRequest,
+  ProxmoxSnapshotPolicyRun,
+  ProxmoxProvision,
+  ProxmoxProvisionRequest,
+  ProxmoxTemplate,
+  ProxmoxClusterHealth,
+  ProxmoxStorageVolume,
+  ProxmoxStorageDownloadRequest,
+  ProxmoxConsoleSession,
+  ProxmoxRightsizingRun,
+  ProxmoxRightsizingReport,
+  PBSConnection,
+  PBSConnectionRequest,
+  PBSDatastore,
+  PBSBackupGroup,
+  PBSJob,
 } from '../types/proxmox'
 import type { HostExposure } from '../types/host'
 
@@ -51,6 +70,60 @@ export const proxmoxApi = {
     api.get<HostExposure>(`/v1/proxmox/guests/${guestId}/exposure`, { params: { period: period ?? '24h' }, signal }),
   proxmoxGuestAction: (guestId: string, action: 'start' | 'shutdown' | 'reboot') =>
     api.post<{ upid: string; message: string }>(`/v1/proxmox/guests/${guestId}/action`, { action }),
+  // Console ticket (admin, or operator of the linked host); attach the
+  // returned session on /api/v1/ws/proxmox/console/:session within seconds.
+  openProxmoxConsole: (guestId: string, kind: 'vnc' | 'term') =>
+    api.post<ProxmoxConsoleSession>(`/v1/proxmox/guests/${guestId}/console`, { kind }),
+
+  // Guest snapshots — mutations require confirm: true (admin only)
+  getProxmoxGuestSnapshots: (guestId: string) =>
+    api.get<ProxmoxSnapshot[]>(`/v1/proxmox/guests/${guestId}/snapshots`),
+  createProxmoxGuestSnapshot: (guestId: string, payload: ProxmoxSnapshotCreate) =>
+    api.post<{ upid: string; name: string; message: string }>(`/v1/proxmox/guests/${guestId}/snapshots`, payload),
+  rollbackProxmoxGuestSnapshot: (guestId: string, name: string) =>
+    api.post<{ upid: string; message: string }>(
+      `/v1/proxmox/guests/${guestId}/snapshots/${encodeURIComponent(name)}/rollback`, { confirm: true }),
+  deleteProxmoxGuestSnapshot: (guestId: string, name: string) =>
+    api.delete<{ upid: string; message: string }>(
+      `/v1/proxmox/guests/${guestId}/snapshots/${encodeURIComponent(name)}`, { data: { confirm: true } }),
+
+  // Scheduled snapshot policies (admin only)
+  getProxmoxSnapshotPolicies: () => api.get<ProxmoxSnapshotPolicy[]>('/v1/proxmox/snapshot-policies'),
+  createProxmoxSnapshotPolicy: (payload: ProxmoxSnapshotPolicyRequest) =>
+    api.post<ProxmoxSnapshotPolicy>('/v1/proxmox/snapshot-policies', payload),
+  updateProxmoxSnapshotPolicy: (id: string, payload: ProxmoxSnapshotPolicyRequest) =>
+    api.put<ProxmoxSnapshotPolicy>(`/v1/proxmox/snapshot-policies/${id}`, payload),
+  deleteProxmoxSnapshotPolicy: (id: string) => api.delete(`/v1/proxmox/snapshot-policies/${id}`),
+  runProxmoxSnapshotPolicy: (id: string) => api.post(`/v1/proxmox/snapshot-policies/${id}/run`),
+  getProxmoxSnapshotPolicyRuns: (id: string, limit = 100) =>
+    api.get<ProxmoxSnapshotPolicyRun[]>(`/v1/proxmox/snapshot-policies/${id}/runs`, { params: { limit } }),
+
+  // VM provisioning from template (admin only)
+  getProxmoxProvisionTemplates: (connectionId: string) =>
+    api.get<ProxmoxTemplate[]>(`/v1/proxmox/instances/${connectionId}/templates`),
+  getProxmoxProvisions: () => api.get<ProxmoxProvision[]>('/v1/proxmox/provisions'),
+  getProxmoxProvision: (id: string) => api.get<ProxmoxProvision>(`/v1/proxmox/provisions/${id}`),
+  createProxmoxProvision: (payload: ProxmoxProvisionRequest) =>
+    api.post<ProxmoxProvision>('/v1/proxmox/provisions', payload),
+  deleteProxmoxProvision: (id: string) => api.delete(`/v1/proxmox/provisions/${id}`),
+
+  // Ceph / HA manager / corosync health (one entry per connection)
+  getProxmoxClusterHealth: () => api.get<ProxmoxClusterHealth[]>('/v1/proxmox/cluster-health'),
+  getProxmoxClusterHealthDetail: (connectionId: string) =>
+    api.get<ProxmoxClusterHealth>(`/v1/proxmox/cluster-health/${connectionId}`),
+
+  // Weekly right-sizing recommendations (migrations via migrateProxmoxGuest)
+  getProxmoxRightsizing: () => api.get<ProxmoxRightsizingReport>('/v1/proxmox/rightsizing'),
+  runProxmoxRightsizing: () => api.post<ProxmoxRightsizingRun>('/v1/proxmox/rightsizing/run'),
+
+  // Storage content browser (admin only) — mutations require confirm: true
+  getProxmoxStorageContent: (storageId: string, params?: { content?: string; orphaned?: boolean }) =>
+    api.get<ProxmoxStorageVolume[]>(`/v1/proxmox/storages/${storageId}/content`, { params: params ?? {} }),
+  downloadToProxmoxStorage: (storageId: string, payload: ProxmoxStorageDownloadRequest) =>
+    api.post<{ upid: string; filename: string; message: string }>(`/v1/proxmox/storages/${storageId}/download-url`, payload),
+  deleteProxmoxStorageVolume: (storageId: string, volid: string) =>
+    api.delete<{ upid: string; message: string }>(
+      `/v1/proxmox/storages/${storageId}/content`, { data: { volid, confirm: true } }),
 
   // Guest ↔ host links
   getProxmoxLinks: (status?: string) =>
@@ -80,6 +153,22 @@ export const proxmoxApi = {
   getProxmoxBackupRuns: (connectionId?: string) =>
     api.get<ProxmoxBackupRun[]>('/v1/proxmox/backup-runs', { params: connectionId ? { connection_id: connectionId } : {} }),
 
+  // Proxmox Backup Server (connections admin only)
+  getPBSInstances: () => api.get<PBSConnection[]>('/v1/proxmox/pbs/instances'),
+  createPBSInstance: (payload: Partial<PBSConnectionRequest>) => api.post('/v1/proxmox/pbs/instances', payload),
+  updatePBSInstance: (id: string, payload: Partial<PBSConnectionRequest>) =>
+    api.put(`/v1/proxmox/pbs/instances/${id}`, payload),
+  deletePBSInstance: (id: string) => api.delete(`/v1/proxmox/pbs/instances/${id}`),
+  testPBSConnection: (payload: Partial<PBSConnectionRequest>) => api.post('/v1/proxmox/pbs/instances/test', payload),
+  testPBSInstanceById: (id: string) => api.post(`/v1/proxmox/pbs/instances/${id}/test`),
+  pollPBSNow: (id: string) => api.post(`/v1/proxmox/pbs/instances/${id}/poll-now`),
+  getPBSDatastores: (connectionId?: string) =>
+    api.get<PBSDatastore[]>('/v1/proxmox/pbs/datastores', { params: connectionId ? { connection_id: connectionId } : {} }),
+  getPBSBackupGroups: (params?: { connection_id?: string; store?: string }) =>
+    api.get<PBSBackupGroup[]>('/v1/proxmox/pbs/groups', { params: params ?? {} }),
+  getPBSJobs: (connectionId?: string) =>
+    api.get<PBSJob[]>('/v1/proxmox/pbs/jobs', { params: connectionId ? { connection_id: connectionId } : {} }),
+
   // Node live data
   getProxmoxNodeStatus: (nodeId: string) => api.get(`/v1/proxmox/nodes/${nodeId}/status`),
   getProxmoxTaskLog: (nodeId: string, upid: string) =>
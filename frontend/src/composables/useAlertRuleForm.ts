@@ -1,26 +1,30 @@
 import { ref, Ref } from 'vue'
-import { getAlertMetricMeta } from '../utils/alertMetrics'
+import { getAlertMetricMeta, getAlertMetricSource } from '../utils/alertMetrics'
 
 function isProxmoxMetric(metric: string): boolean {
-  return getAlertMetricMeta(metric).category === 'proxmox'
+  return getAlertMetricSource(metric) === 'proxmox'
 }
 
 function isSyntheticMetric(metric: string): boolean {
-  return getAlertMetricMeta(metric).category === 'synthetic'
+  return getAlertMetricSource(metric) === 'synthetic'
 }
 
 function isDockerMetric(metric: string): boolean {
-  return getAlertMetricMeta(metric).category === 'docker'
+  return getAlertMetricSource(metric) === 'docker'
 }
 
 function isProxmoxGuestMetric(metric: string): boolean {
-  return metric === 'proxmox_guest_cpu_percent' || metric === 'proxmox_guest_memory_percent'
+  return metric === 'proxmox_guest_cpu_percent' || metric === 'proxmox_guest_memory_percent' || metric === 'proxmox_snapshot_policy_failures'
 }
 
 function isProxmoxDiskMetric(metric: string): boolean {
   return metric === 'proxmox_disk_failed_count' || metric === 'proxmox_disk_min_wearout_percent'
 }
 
+function isPBSMetric(metric: string): boolean {
+  return metric.startsWith('pbs_')
+}
+
 function isProxmoxCountMetric(metric: string): boolean {
   const meta = getAlertMetricMeta(metric)
   return meta.category === 'proxmox' && meta.unit === '' && metric !== 'proxmox_auth_failures_recent'
@@ -48,10 +52,11 @@ interface ProxmoxScope {
   storage_id: string
   guest_id: string
   disk_id: string
+  backup_group_id: string
 }
 
 export interface DockerScope {
-  scope_mode: string   // 'host' | 'container' | 'compose_project'
+  scope_mode: string   // 'host' | 'container' | 'compose_project' | 'swarm_service' | 'swarm_node'
   host_id: string
   // container_id is the legacy single-container field (still accepted by the
   // server for rules saved before multi-select existed). The form only ever
@@ -59,6 +64,8 @@ export interface DockerScope {
   container_id: string
   container_ids: string[]
   project_name: string
+  service_name: string   // swarm_service scope
+  node_id: string        // swarm_node scope
   warn_states: string[]  // container states triggering warn alert (docker_container_state)
   crit_states: string[]  // container states triggering crit alert (docker_container_state)
 }
@@ -155,6 +162,7 @@ export function useAlertRuleForm(): AlertRuleFormApi {
       storage_id: '',
       guest_id: '',
       disk_id: '',
+      backup_group_id: '',
     },
     docker_scope: {
       scope_mode: 'host',
@@ -162,6 +170,8 @@ export function useAlertRuleForm(): AlertRuleFormApi {
       container_id: '',
       container_ids: [],
       project_name: '',
+      service_name: '',
+      node_id: '',
       warn_states: [],
       crit_states: [],
     },
@@ -215,6 +225,7 @@ export function useAlertRuleForm(): AlertRuleFormApi {
         storage_id: scope.storage_id || '',
         guest_id: scope.guest_id || '',
         disk_id: scope.disk_id || '',
+        backup_group_id: scope.backup_group_id || '',
       },
       docker_scope: {
         scope_mode: dscope.scope_mode || 'host',
@@ -231,6 +242,8 @@ export function useAlertRuleForm(): AlertRuleFormApi {
               ? [dscope.container_id]
               : [],
         project_name: dscope.project_name || '',
+        service_name: dscope.service_name || '',
+        node_id: dscope.node_id || '',
         warn_states: dscope.warn_states || [],
         crit_states: dscope.crit_states || [],
       },
@@ -300,6 +313,31 @@ export function useAlertRuleForm(): AlertRuleFormApi {
         form.value.threshold_clear_warn = undefined
         form.value.threshold_clear_crit = undefined
         form.value.duration = 0
+      } else if (form.value.metric === 'docker_swarm_service_missing_replicas') {
+        if (form.value.docker_scope.scope_mode !== 'swarm_service') {
+          form.value.docker_scope.scope_mode = 'host'
+        }
+        form.value.operator = '>='
+        form.value.threshold_warn = 1
+        form.value.threshold_crit = 1
+        form.value.threshold_clear_warn = undefined
+        form.value.threshold_clear_crit = undefined
+        // A rolling update briefly runs fewer replicas: give it a minute.
+        form.value.duration = 60
+      } else if (form.value.metric === 'docker_swarm_node_state') {
+        if (form.value.docker_scope.scope_mode !== 'swarm_node') {
+          form.value.docker_scope.scope_mode = 'host'
+        }
+        // 0 = ready, 1 = drain/pause, 2 = down — same internal thresholds as container state.
+        form.value.operator = '>'
+        form.value.threshold_warn = 0.5
+        form.value.threshold_crit = 1.5
+        form.value.threshold_clear_warn = undefined
+        form.value.threshold_clear_crit = undefined
+        form.value.duration = 0
+      }
+      if (!form.value.metric.startsWith('docker_swarm_') && form.value.docker_scope.scope_mode.startsWith('swarm_')) {
+        form.value.docker_scope.scope_mode = 'host'
       }
     } else if (isProxmoxMetric(form.value.metric)) {
       form.value.source_type = 'proxmox'
@@ -309,10 +347,16 @@ export function useAlertRuleForm(): AlertRuleFormApi {
         form.value.proxmox_scope.scope_mode = 'guest'
       } else if (isProxmoxDiskMetric(form.value.metric)) {
         form.value.proxmox_scope.scope_mode = 'disk'
-      } else if (form.value.proxmox_scope.scope_mode === 'guest' || form.value.proxmox_scope.scope_mode === 'disk') {
+      } else if (isPBSMetric(form.value.metric)) {
+        // PBS metrics are evaluated per backup group or across all of them.
+        if (form.value.proxmox_scope.scope_mode !== 'backup_group') {
+          form.value.proxmox_scope.scope_mode = 'global'
+        }
+      } else if (['guest', 'disk', 'backup_group'].includes(form.value.proxmox_scope.scope_mode)) {
         form.value.proxmox_scope.scope_mode = 'global'
         form.value.proxmox_scope.guest_id = ''
         form.value.proxmox_scope.disk_id = ''
+        form.value.proxmox_scope.backup_group_id = ''
       }
     } else if (isSyntheticMetric(form.value.metric)) {
       form.value.source_type = 'synthetic'
@@ -330,6 +374,27 @@ export function useAlertRuleForm(): AlertRuleFormApi {
       return
     }
 
+    if (form.value.metric === 'pbs_backup_age_hours') {
+      form.value.operator = '>'
+      if (!form.value.threshold_crit || form.value.threshold_crit === 85) {
+        form.value.threshold_warn = 30
+        form.value.threshold_crit = 36
+      }
+      form.value.duration = 0
+      return
+    }
+
+    if (form.value.metric === 'proxmox_ceph_health') {
+      // 0 = HEALTH_OK, 1 = HEALTH_WARN, 2 = HEALTH_ERR
+      form.value.operator = '>'
+      if (!form.value.threshold_crit || form.value.threshold_crit === 85) {
+        form.value.threshold_warn = 0.5
+        form.value.threshold_crit = 1.5
+      }
+      form.value.duration = 0
+      return
+    }
+
     if (isProxmoxCountMetric(form.value.metric)) {
       form.value.operator = '>'
       if (!form.value.threshold_crit || form.value.threshold_crit === 85) {
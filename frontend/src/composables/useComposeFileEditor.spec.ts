@@ -0,0 +1,98 @@
+import { describe, it, expect, vi, beforeEach } from 'vitest'
+
+const { sendComposeFileCommand, collectCommandOutput, confirm } = vi.hoisted(() => ({
+  sendComposeFileCommand: vi.fn(),
+  collectCommandOutput: vi.fn(),
+  confirm: vi.fn(),
+}))
+
+vi.mock('../api', () => ({
+  default: { sendComposeFileCommand },
+  getApiErrorMessage: (e: unknown, fallback: string) => (e instanceof Error ? e.message : fallback),
+}))
+vi.mock('./useCommandStream', () => ({
+  useCommandStream: () => ({ collectCommandOutput }),
+}))
+vi.mock('./useConfirmDialog', () => ({
+  useConfirmDialog: () => ({ confirm }),
+}))
+vi.mock('./useGlobalToast', () => ({ addToast: vi.fn() }))
+
+import { useComposeFileEditor } from './useComposeFileEditor'
+
+const READ = JSON.stringify({
+  project: 'web', config_file: '/srv/web/compose.yaml', env_file: '/srv/web/.env',
+  compose: 'services:\n  app:\n    image: nginx:1.25\n', env: '', has_env: false, has_snapshot: false,
+})
+
+// Each command resolves with the output queued for its action.
+function agentReplies(outputs: Record<string, string | Error>): void {
+  sendComposeFileCommand.mockImplementation((req: { action: string }) =>
+    Promise.resolve({ data: { command_id: `cmd-${req.action}` } }))
+  collectCommandOutput.mockImplementation((id: string) => {
+    const out = outputs[id.replace('cmd-', '')]
+    return out instanceof Error ? Promise.reject(out) : Promise.resolve(out)
+  })
+}
+
+describe('useComposeFileEditor', () => {
+  beforeEach(() => {
+    sendComposeFileCommand.mockReset()
+    collectCommandOutput.mockReset()
+    confirm.mockReset()
+  })
+
+  it('only offers apply once the current content is validated, and shows the diff', async () => {
+    const diff = '--- /srv/web/compose.yaml\n+++ /srv/web/compose.yaml\n@@ -3 +3 @@\n-    image: nginx:1.25\n+    image: nginx:1.27\n'
+    agentReplies({ read: READ, validate: JSON.stringify({ valid: true, diff, env_diff: '', detail: '' }), apply: 'ok' })
+    confirm.mockResolvedValue(true)
+
+    const editor = useComposeFileEditor()
+    await editor.open({ host_id: 'h1', name: 'web' })
+    expect(editor.compose.value).toContain('nginx:1.25')
+    expect(editor.canApply.value).toBe(false)
+
+    editor.compose.value = editor.compose.value.replace('1.25', '1.27')
+    await editor.validate()
+    expect(editor.validation.value?.diff).toBe(diff)
+    expect(editor.canApply.value).toBe(true)
+    // No .env on the host and none typed: env is left untouched.
+    expect(sendComposeFileCommand.mock.calls[1][0]).toMatchObject({ action: 'validate', env: undefined })
+
+    // Editing again invalidates the validation.
+    editor.compose.value += '\n'
+    expect(editor.canApply.value).toBe(false)
+    editor.compose.value = editor.compose.value.slice(0, -1)
+
+    await editor.apply()
+    expect(confirm).toHaveBeenCalledOnce()
+    expect(sendComposeFileCommand).toHaveBeenCalledWith(expect.objectContaining({ action: 'apply', compose: expect.stringContaining('nginx:1.27') }))
+  })
+
+  it('reads the verdict of an invalid file from the failed command', async () => {
+    agentReplies({
+      read: READ,
+      validate: new Error(JSON.stringify({ valid: false, diff: '', env_diff: '', detail: 'services.app.image must be a string' })),
+    })
+    const editor = useComposeFileEditor()
+    await editor.open({ host_id: 'h1', name: 'web' })
+    editor.compose.value = 'services:\n  app:\n    image: [1]\n'
+    await editor.validate()
+
+    expect(editor.error.value).toBe('')
+    expect(editor.validation.value?.valid).toBe(false)
+    expect(editor.validation.value?.detail).toContain('must be a string')
+    expect(editor.canApply.value).toBe(false)
+  })
+
+  it('does not roll back without confirmation', async () => {
+    agentReplies({ read: READ.replace('"has_snapshot":false', '"has_snapshot":true') })
+    confirm.mockResolvedValue(false)
+    const editor = useComposeFileEditor()
+    await editor.open({ host_id: 'h1', name: 'web' })
+
+    await editor.rollback()
+    expect(confirm).toHaveBeenCalledOnce()
+    expect(sendComposeFileCommand).toHaveBeenCalledTimes(1)
+  })
+})
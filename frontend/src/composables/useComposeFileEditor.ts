@@ -0,0 +1,202 @@
+import { ref, computed } from 'vue'
+import api, { getApiErrorMessage } from '../api'
+import { useCommandStream } from './useCommandStream'
+import { useConfirmDialog } from './useConfirmDialog'
+import { addToast } from './useGlobalToast'
+import type { ComposeFileContent, ComposeFileValidation } from '../types/docker'
+
+export interface ComposeEditorTarget {
+  host_id: string
+  name: string
+}
+
+type EditorAction = 'read' | 'validate' | 'apply' | 'rollback'
+
+const READ_TIMEOUT_MS = 60_000
+// apply/rollback run `up -d` and may wait up to 600s for health.
+const DEPLOY_TIMEOUT_MS = 15 * 60_000
+
+/**
+ * Drives the compose file editor (agent module=compose, *_file actions):
+ * read the masked compose file and .env, validate the edit (agent-side
+ * `docker compose config` + masked unified diff), then apply it or roll back
+ * to the snapshot of the last apply. Apply is only offered once the current
+ * content has been validated, so the operator always sees the diff first.
+ */
+export function useComposeFileEditor() {
+  const dialog = useConfirmDialog()
+  const { collectCommandOutput } = useCommandStream()
+
+  const target = ref<ComposeEditorTarget | null>(null)
+  const content = ref<ComposeFileContent | null>(null)
+  const compose = ref('')
+  const env = ref('')
+  const healthTimeoutSec = ref(60)
+  const loading = ref(false)
+  const busy = ref<EditorAction | null>(null)
+  const error = ref('')
+  const output = ref('')
+  const validation = ref<ComposeFileValidation | null>(null)
+  // Content the current validation was computed for: any edit invalidates it.
+  const validatedContent = ref('')
+
+  const currentContent = computed(() => `${compose.value}\u0000${env.value}`)
+  const validationIsCurrent = computed(() => !!validation.value && validatedContent.value === currentContent.value)
+  const hasChanges = computed(() => !!validation.value && !!(validation.value.diff || validation.value.env_diff))
+  const canApply = computed(() => validationIsCurrent.value && !!validation.value?.valid && hasChanges.value && !busy.value)
+
+  // .env is only sent when the host has one or the operator typed one: a nil
+  // env leaves the host's .env untouched.
+  function envPayload(): string | undefined {
+    return content.value?.has_env || env.value !== '' ? env.value : undefined
+  }
+
+  async function run(action: EditorAction, onChunk?: (output: string) => void): Promise<string> {
+    const t = target.value!
+    const res = await api.sendComposeFileCommand({
+      host_id: t.host_id,
+      project: t.name,
+      action,
+      compose: action === 'validate' || action === 'apply' ? compose.value : '',
+      env: action === 'validate' || action === 'apply' ? envPayload() : undefined,
+      healthcheck_timeout_sec: Number(healthTimeoutSec.value) || 0,
+    })
+    return collectCommandOutput(res.data.command_id, {
+      timeoutMs: action === 'apply' || action === 'rollback' ? DEPLOY_TIMEOUT_MS : READ_TIMEOUT_MS,
+      onInit: (_p, out) => onChunk?.(out),
+      onChunk: (_p, out) => onChunk?.(out),
+    })
+  }
+
+  async function load(): Promise<void> {
+    if (!target.value) return
+    loading.value = true
+    error.value = ''
+    validation.value = null
+    try {
+      const out = await run('read')
+      const parsed = JSON.parse(out) as ComposeFileContent
+      content.value = parsed
+      compose.value = parsed.compose
+      env.value = parsed.env
+    } catch (e: unknown) {
+      error.value = e instanceof SyntaxError
+        ? 'Réponse de l\'agent illisible'
+        : getApiErrorMessage(e, 'Impossible de lire le fichier compose')
+    } finally {
+      loading.value = false
+    }
+  }
+
+  async function open(t: ComposeEditorTarget): Promise<void> {
+    target.value = t
+    content.value = null
+    output.value = ''
+    await load()
+  }
+
+  function close(): void {
+    target.value = null
+    content.value = null
+    validation.value = null
+    output.value = ''
+    error.value = ''
+  }
+
+  async function validate(): Promise<void> {
+    if (!target.value || busy.value) return
+    busy.value = 'validate'
+    error.value = ''
+    const validated = currentContent.value
+    let out: string
+    try {
+      out = await run('validate')
+    } catch (e: unknown) {
+      // An invalid file is a failed command whose output is still the
+      // validation JSON.
+      out = e instanceof Error ? e.message : ''
+      if (!out.startsWith('{')) {
+        error.value = getApiErrorMessage(e, 'Échec de la validation')
+        busy.value = null
+        return
+      }
+    }
+    try {
+      validation.value = JSON.parse(out) as ComposeFileValidation
+      validatedContent.value = validated
+    } catch {
+      error.value = 'Réponse de l\'agent illisible'
+    } finally {
+      busy.value = null
+    }
+  }
+
+  async function apply(): Promise<void> {
+    if (!target.value || !canApply.value) return
+    const ok = await dialog.confirm({
+      title: 'Appliquer le fichier compose',
+      message: `Les fichiers actuels de « ${target.value.name} » seront sauvegardés, remplacés par la version validée puis redéployés (up -d). En cas d'échec, la sauvegarde est restaurée.`,
+      variant: 'warning',
+      okLabel: 'Appliquer',
+    })
+    if (!ok) return
+    await deploy('apply', 'Fichier compose appliqué', 'Échec de l\'application')
+  }
+
+  async function rollback(): Promise<void> {
+    if (!target.value || busy.value || !content.value?.has_snapshot) return
+    const ok = await dialog.confirm({
+      title: 'Restaurer la version précédente',
+      message: `Les fichiers de « ${target.value.name} » seront remplacés par la sauvegarde du dernier apply puis redéployés.`,
+      variant: 'danger',
+      okLabel: 'Restaurer',
+    })
+    if (!ok) return
+    await deploy('rollback', 'Version précédente restaurée', 'Échec de la restauration')
+  }
+
+  async function deploy(action: 'apply' | 'rollback', successMsg: string, errorMsg: string): Promise<void> {
+    busy.value = action
+    error.value = ''
+    output.value = ''
+    try {
+      output.value = await run(action, (out) => { output.value = out })
+      addToast(successMsg, 'success')
+      validation.value = null
+      busy.value = null
+      await load()
+    } catch (e: unknown) {
+      // Once the agent streamed its log, the failed command's error carries
+      // the full log: show it there rather than in the error banner.
+      if (e instanceof Error && output.value) {
+        output.value = e.message
+        error.value = errorMsg
+      } else {
+        error.value = getApiErrorMessage(e, errorMsg)
+      }
+    } finally {
+      busy.value = null
+    }
+  }
+
+  return {
+    target,
+    content,
+    compose,
+    env,
+    healthTimeoutSec,
+    loading,
+    busy,
+    error,
+    output,
+    validation,
+    validationIsCurrent,
+    hasChanges,
+    canApply,
+    open,
+    close,
+    validate,
+    apply,
+    rollback,
+  }
+}
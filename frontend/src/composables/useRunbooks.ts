@@ -5,7 +5,7 @@ import { useHostsStore } from '../stores/hosts'
 import { useCommandStream } from './useCommandStream'
 import type { CommandStreamInitMsg, CommandStreamChunkMsg, CommandStatusUpdateMsg } from '../types/ws'
 import type { Runbook, RunbookCreate, RunbookStepCreate, RunbookExecution, RunbookExecutionStep } from '../types/generated'
-import type { DispatchOption } from '../utils/dispatchStep'
+import { DISPATCH_MODULES, type DispatchOption } from '../utils/dispatchStep'
 
 const EXECUTION_POLL_MS = 3_000
 
@@ -19,6 +19,7 @@ const ACTION_LABELS: Record<string, string> = {
   compose_logs: 'Voir les logs Compose', compose_restart: 'Redémarrer (Compose)',
   update: 'apt update', upgrade: 'apt upgrade', 'full-upgrade': 'apt full-upgrade', autoremove: 'apt autoremove',
   status: 'Statut', list: 'Lister', read: 'Lire', run: 'Exécuter',
+  snapshot: 'Snapshot de la VM/LXC liée',
 }
 
 const MODULE_ACTIONS: Record<string, string[]> = {
@@ -28,8 +29,17 @@ const MODULE_ACTIONS: Record<string, string[]> = {
   systemd: ['status', 'start', 'stop', 'restart', 'list'],
   processes: ['list'],
   custom: ['run'],
+  proxmox: ['snapshot'],
 }
 
+// Runbooks add one server-executed module to the shared dispatch set: a
+// Proxmox snapshot of the guest linked to the step's host, typically taken
+// as a first step before a risky change.
+export const RUNBOOK_MODULES: DispatchOption[] = [
+  ...DISPATCH_MODULES,
+  { value: 'proxmox', label: 'Proxmox (snapshot)' },
+]
+
 const MODULES_REQUIRING_TARGET = new Set(['journal', 'systemd', 'custom'])
 
 export function actionsForModule(module: string): DispatchOption[] {
@@ -6,3 +6,15 @@ declare module '*.vue' {
 }
 
 declare module 'topojson-client'
+
+// noVNC ships plain ES modules without type declarations.
+declare module '@novnc/novnc/core/rfb' {
+  export default class RFB extends EventTarget {
+    constructor(target: HTMLElement, urlOrChannel: string | WebSocket, options?: { credentials?: { password?: string }; wsProtocols?: string[] })
+    scaleViewport: boolean
+    resizeSession: boolean
+    focus(): void
+    disconnect(): void
+    sendCtrlAltDel(): void
+  }
+}
@@ -1,7 +1,7 @@
 // Docker domain types — model shapes re-exported from generated.ts.
-import type { DockerContainer } from './generated'
+import type { DockerContainer, DockerDiskUsagePoint } from './generated'
 
-export type { DockerContainer, ComposeProject, DockerNetwork, VersionComparison, DockerImageVersion } from './generated'
+export type { DockerContainer, ComposeProject, DockerNetwork, VersionComparison, DockerImageVersion, ComposeFileCommandRequest, DockerDiskUsageSummary, DockerDiskUsageItem, DockerDiskUsagePoint, DockerVolumeGrowth, SwarmService, SwarmTask, SwarmNode, DockerSwarmState } from './generated'
 
 /**
  * Verdict of a VersionComparison row, computed server-side (see
@@ -17,3 +17,35 @@ export interface DockerContainersPage {
   limit: number
   offset: number
 }
+
+/**
+ * Output of a compose `read` file command (agent ComposeFileContent, JSON in
+ * RemoteCommand.output). Sensitive values read "[REDACTED]"; sending them back
+ * unchanged keeps the value currently on disk.
+ */
+export interface ComposeFileContent {
+  project: string
+  config_file: string
+  env_file: string
+  compose: string
+  env: string
+  has_env: boolean
+  has_snapshot: boolean
+}
+
+/** Output of a compose `validate` file command (JSON in RemoteCommand.output). */
+export interface ComposeFileValidation {
+  valid: boolean
+  diff: string
+  env_diff: string
+  detail: string
+}
+
+/** Envelope of GET /api/v1/hosts/:id/docker/disk-usage/history (not a model). */
+export interface DockerDiskUsageHistory {
+  since: string
+  until?: string
+  kind: string
+  name: string
+  points: DockerDiskUsagePoint[]
+}
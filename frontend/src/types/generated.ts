@@ -3,6 +3,134 @@
 // Code generated by tygo from server/internal/models. DO NOT EDIT.
 // Regenerate: cd server && go run github.com/gzuidhof/tygo@v0.2.21 generate
 
+//////////
+// source: agent_identity.go
+
+/**
+ * Agent credential kinds: a client certificate for mTLS, or an Ed25519 key
+ * the agent signs its requests with.
+ */
+export const AgentCredentialCertificate = "certificate";
+/**
+ * Agent credential kinds: a client certificate for mTLS, or an Ed25519 key
+ * the agent signs its requests with.
+ */
+export const AgentCredentialEd25519 = "ed25519";
+/**
+ * AgentCredential is a credential a host enrolled besides its API key. Once a
+ * host has an active one, its API key alone is refused.
+ */
+export interface AgentCredential {
+  id: string;
+  host_id: string;
+  kind: string;
+  serial?: string;
+  public_key?: string;
+  fingerprint: string;
+  not_after?: string;
+  created_at: string;
+  revoked_at?: string;
+}
+/**
+ * AgentEnrollRequest is the body of POST /api/agent/identity/enroll. A host
+ * without any active credential enrolls with its API key; a renewal (new
+ * certificate before expiry, or new signing key) is authenticated by the
+ * current credential.
+ */
+export interface AgentEnrollRequest {
+  kind: string;
+  /**
+   * CSR is a PEM certificate request (kind certificate).
+   */
+  csr?: string;
+  /**
+   * PublicKey is a base64 raw Ed25519 public key (kind ed25519).
+   */
+  public_key?: string;
+}
+/**
+ * AgentEnrollResponse carries the enrolled credential back to the agent.
+ */
+export interface AgentEnrollResponse {
+  credential_id: string;
+  kind: string;
+  /**
+   * Certificate, CACertificate and MTLSURL are set for kind certificate:
+   * the issued client certificate, the CA to pin, and the URL of the mTLS
+   * listener the agent must use from now on.
+   */
+  certificate?: string;
+  ca_certificate?: string;
+  mtls_url?: string;
+  not_after?: string;
+}
+
+//////////
+// source: agent_join.go
+
+/**
+ * AgentJoinToken lets agents started with --join-token register their own
+ * host. Short-lived and multi-use: every host it creates gets Tags and the
+ * Permissions below. The token itself is only shown once, at creation.
+ */
+export interface AgentJoinToken {
+  id: string;
+  name: string;
+  tags: string[];
+  permissions: AgentJoinPermission[];
+  max_uses: number /* int */; // 0 = unlimited until ExpiresAt
+  uses: number /* int */;
+  expires_at: string;
+  created_by: string;
+  created_at: string;
+  last_used_at?: string;
+  revoked_at?: string;
+}
+/**
+ * AgentJoinPermission is a per-host permission granted on every host a join
+ * token creates (see HostPermission).
+ */
+export interface AgentJoinPermission {
+  username: string;
+  level: string; // "viewer" | "operator"
+}
+/**
+ * AgentJoinTokenCreate is the body of POST /api/v1/agent-join-tokens.
+ */
+export interface AgentJoinTokenCreate {
+  name: string;
+  tags: string[];
+  permissions: AgentJoinPermission[];
+  max_uses: number /* int */;
+  /**
+   * ExpiresInHours defaults to 24.
+   */
+  expires_in_hours: number /* int */;
+}
+/**
+ * AgentJoinRequest is the body of POST /api/agent/join, sent by the agent.
+ */
+export interface AgentJoinRequest {
+  token: string;
+  hostname: string;
+  /**
+   * IPAddress is the agent's own address; the caller's address when empty.
+   */
+  ip_address: string;
+  /**
+   * JoinID identifies this join across retries (16 to 64 characters): a
+   * retry after a lost response gets the host the first attempt created.
+   */
+  join_id?: string;
+}
+/**
+ * AgentJoinResponse carries the new host's credential back to the agent.
+ */
+export interface AgentJoinResponse {
+  host_id: string;
+  api_key: string;
+}
+
 //////////
 // source: alert.go
 
@@ -17,7 +145,8 @@ export interface CommandTrigger {
 }
 /**
  * ProxmoxMetricScope defines how a Proxmox metric should be evaluated.
- * ScopeMode can be one of: global, connection, node, storage.
+ * ScopeMode can be one of: global, connection, node, storage, guest, disk,
+ * backup_group.
  */
 export interface ProxmoxMetricScope {
   scope_mode?: string;
@@ -26,11 +155,16 @@ export interface ProxmoxMetricScope {
   storage_id?: string;
   guest_id?: string;
   disk_id?: string;
+  /**
+   * BackupGroupID is a pbs_backup_groups row (Proxmox Backup Server).
+   */
+  backup_group_id?: string;
 }
 /**
  * DockerMetricScope defines how a Docker metric should be evaluated.
- * ScopeMode can be one of: host, container, compose_project.
- * HostID is always required. ContainerID or ProjectName are required for their respective modes.
+ * ScopeMode can be one of: host, container, compose_project, swarm_service, swarm_node.
+ * HostID is always required (for Swarm metrics, the manager host whose view is used).
+ * ContainerID, ProjectName, ServiceName or NodeID are required for their respective modes.
  * WarnStates and CritStates are used by docker_container_state to select which container states trigger each severity.
  */
 export interface DockerMetricScope {
@@ -45,6 +179,8 @@ export interface DockerMetricScope {
   container_id?: string; // DB UUID of docker_containers row
   container_ids?: string[]; // DB UUIDs of docker_containers rows
   project_name?: string; // compose project name
+  service_name?: string; // Swarm service name
+  node_id?: string; // Swarm node ID
   warn_states?: string[]; // container states triggering warn
   crit_states?: string[]; // container states triggering crit
 }
@@ -98,6 +234,10 @@ export interface AlertProxmoxScope {
   storages: AlertScopeOption[];
   guests: AlertScopeOption[];
   disks: AlertScopeOption[];
+  /**
+   * BackupGroups lists the Proxmox Backup Server groups (backup_group mode).
+   */
+  backup_groups: AlertScopeOption[];
 }
 /**
  * AlertSplitCapabilities is the Proxmox capabilities response (metrics + scope).
@@ -124,13 +264,24 @@ export interface AlertDockerScopeProject {
   services: string[];
 }
 /**
- * AlertDockerHostScope groups a host's container + compose-project scope options.
+ * AlertDockerScopeSwarmNode is a Swarm node option for a Docker alert scope.
+ */
+export interface AlertDockerScopeSwarmNode {
+  node_id: string;
+  hostname: string;
+  role: string;
+}
+/**
+ * AlertDockerHostScope groups a host's container + compose-project scope
+ * options, plus its Swarm services and nodes when the host is a manager.
  */
 export interface AlertDockerHostScope {
   host_id: string;
   host_name: string;
   containers: AlertDockerScopeContainer[];
   projects: AlertDockerScopeProject[];
+  swarm_services: string[];
+  swarm_nodes: AlertDockerScopeSwarmNode[];
 }
 /**
  * AlertDockerCapabilities is the Docker capabilities response (metrics + hosts).
@@ -552,6 +703,21 @@ export interface DockerCommandRequest {
   action: string;
   working_dir: string; // required for compose_* actions
 }
+/**
+ * ComposeFileCommandRequest drives the compose file editor: read returns the
+ * masked compose file and .env, validate runs `docker compose config` on the
+ * edited content and returns a diff, apply snapshots + writes + redeploys, and
+ * rollback restores the snapshot of the last apply. Compose/Env are ignored by
+ * read and rollback; a nil Env leaves the host's .env untouched.
+ */
+export interface ComposeFileCommandRequest {
+  host_id: string;
+  project: string;
+  action: string;
+  compose: string;
+  env?: string;
+  healthcheck_timeout_sec: number /* int */;
+}
 export interface PendingCommand {
   id: string; // UUID
   module: string; // docker | apt | systemd | journal
@@ -629,6 +795,165 @@ export interface AuditLog {
   category: string;
 }
 
+//////////
+// source: crowdsec.go
+
+/**
+ * CrowdSecSnapshotMaxAge is how old a host's CrowdSec snapshot may get
+ * before the fleet view stops trusting it: the agent re-sends it every
+ * minute, so this is several missed cycles (agent down, LAPI
+ * unreachable).
+ */
+export const CrowdSecSnapshotMaxAge = 15 * any /* time.Minute */;
+/**
+ * CrowdSecBouncerStaleAfter flags a bouncer in the fleet view. Bouncers
+ * pull every few seconds by default; the crowdsec_bouncer_last_pull_minutes
+ * alert lets each rule pick its own threshold.
+ */
+export const CrowdSecBouncerStaleAfter = 10 * any /* time.Minute */;
+/**
+ * CrowdSecReport is a host's CrowdSec state as sent by the agent (see
+ * agent/internal/collector/crowdsec_sync.go). Mirrors
+ * agent/internal/collector.CrowdSecReport.
+ */
+export interface CrowdSecReport {
+  /**
+   * Decisions are the local ones; community blocklists (CAPI, lists) are
+   * only counted.
+   */
+  decisions: CrowdSecReportDecision[];
+  community_decisions: number /* int */;
+  truncated?: boolean;
+  bouncers: CrowdSecBouncer[];
+  /**
+   * BouncersError is set when cscli could not list the bouncers: the
+   * previously stored list is kept.
+   */
+  bouncers_error?: string;
+}
+/**
+ * CrowdSecReportDecision is one active decision of a host's local API.
+ */
+export interface CrowdSecReportDecision {
+  value: string; // IP or range
+  type: string; // ban, captcha...
+  scenario: string;
+  origin: string;
+  country?: string;
+  as_name?: string;
+  started_at: string; // zero when unknown
+  until: string; // zero when unknown
+}
+/**
+ * CrowdSecBouncer is one bouncer registered on a host's local API.
+ */
+export interface CrowdSecBouncer {
+  name: string;
+  ip_address?: string;
+  type?: string;
+  version?: string;
+  last_pull: string; // zero when it never pulled
+  created_at: string;
+  revoked: boolean;
+}
+/**
+ * CrowdSecHostSync is the freshness of one host's stored CrowdSec snapshot.
+ */
+export interface CrowdSecHostSync {
+  host_id: string;
+  host_name: string;
+  collected_at: string;
+  local_decisions: number /* int */;
+  community_decisions: number /* int */;
+  truncated: boolean;
+  bouncers_error?: string;
+  /**
+   * Stale is true once the snapshot is older than CrowdSecSnapshotMaxAge.
+   */
+  stale: boolean;
+}
+/**
+ * CrowdSecHostDecision is one stored decision of one host.
+ */
+export interface CrowdSecHostDecision {
+  host_id: string;
+  host_name: string;
+  type: string;
+  scenario: string;
+  origin: string;
+  started_at?: string;
+  until?: string;
+}
+/**
+ * CrowdSecFleetDecision is every active decision on one IP or range across
+ * the fleet.
+ */
+export interface CrowdSecFleetDecision {
+  value: string;
+  types: string[];
+  scenarios: string[];
+  origins: string[];
+  country?: string;
+  as_name?: string;
+  /**
+   * StartedAt is the earliest known start, Until the latest expiry.
+   */
+  started_at?: string;
+  until?: string;
+  hosts: CrowdSecHostDecision[];
+  /**
+   * MissingHostIDs are the hosts with a fresh snapshot that do not enforce
+   * this decision: where a fleet-wide ban would add it.
+   */
+  missing_host_ids: string[];
+}
+/**
+ * CrowdSecFleetDecisions is the deduplicated fleet-wide decision list.
+ */
+export interface CrowdSecFleetDecisions {
+  hosts: CrowdSecHostSync[];
+  decisions: CrowdSecFleetDecision[];
+}
+/**
+ * CrowdSecBouncerStatus is a stored bouncer with its host and pull health.
+ */
+export interface CrowdSecBouncerStatus extends CrowdSecBouncer {
+  host_id: string;
+  host_name: string;
+  /**
+   * ListedAt is when the agent last listed this host's bouncers.
+   */
+  listed_at: string;
+  /**
+   * MinutesSinceLastPull counts from the last pull, or from the
+   * registration of a bouncer that never pulled; nil when neither is known.
+   */
+  minutes_since_last_pull?: number /* float64 */;
+  /**
+   * Stale is true for a non-revoked bouncer past CrowdSecBouncerStaleAfter.
+   */
+  stale: boolean;
+}
+/**
+ * CrowdSecFleetActionInput is the body of a fleet-wide ban.
+ */
+export interface CrowdSecFleetActionInput {
+  ip: string;
+  duration: string;
+}
+/**
+ * CrowdSecFleetAction is the outcome of a fleet-wide ban or unban: one agent
+ * command per CrowdSec-capable host.
+ */
+export interface CrowdSecFleetAction {
+  ip: string;
+  action: string; // "ban" or "unban"
+  duration?: string;
+  host_ids: string[];
+  command_ids: string[];
+  failures: string[];
+}
+
 //////////
 // source: dashboard.go
 
@@ -696,8 +1021,8 @@ export interface DockerContainer {
    * IPAddresses are the container's Docker-assigned addresses across its
    * attached networks. Reported by the agent (which needs them locally to
    * attribute container traffic in the network-flow collector — see
-   * agent/internal/collector/container_ips.go) and accepted here as container
-   * metadata; not persisted, same as Labels/EnvVars/Volumes above.
+   * agent/internal/collector/container_ips.go) and persisted so the service
+   * dependency map can resolve a flow's remote IP to a container.
    */
   ip_addresses: string[];
   net_rx_bytes: number /* uint64 */;
@@ -720,6 +1045,213 @@ export interface ComposeProject {
   raw_config: string;
   updated_at: string;
 }
+/**
+ * DockerImageUsage mirrors agent/internal/collector.DockerImageUsage.
+ */
+export interface DockerImageUsage {
+  image_id: string;
+  image: string;
+  size_bytes: number /* int64 */;
+  shared_size: number /* int64 */;
+  containers: number /* int64 */;
+}
+/**
+ * DockerContainerUsage mirrors agent/internal/collector.DockerContainerUsage.
+ */
+export interface DockerContainerUsage {
+  container_id: string;
+  name: string;
+  image: string;
+  compose_project?: string;
+  size_rw_bytes: number /* int64 */;
+  size_root_fs_bytes: number /* int64 */;
+}
+/**
+ * DockerVolumeUsage mirrors agent/internal/collector.DockerVolumeUsage.
+ * SizeBytes is -1 when the volume driver does not report usage.
+ */
+export interface DockerVolumeUsage {
+  name: string;
+  driver: string;
+  compose_project?: string;
+  size_bytes: number /* int64 */;
+  ref_count: number /* int64 */;
+}
+/**
+ * DockerDiskUsageReport mirrors agent/internal/collector.DockerDiskUsageReport:
+ * per-kind totals plus the agent's top-N largest items of each kind. Only sent
+ * on the report cycles where the agent actually re-measured (every ~15 min).
+ */
+export interface DockerDiskUsageReport {
+  collected_at: string;
+  layers_size: number /* int64 */;
+  images_size: number /* int64 */;
+  containers_size: number /* int64 */;
+  volumes_size: number /* int64 */;
+  build_cache_size: number /* int64 */;
+  build_cache_reclaimable: number /* int64 */;
+  images: DockerImageUsage[];
+  containers: DockerContainerUsage[];
+  volumes: DockerVolumeUsage[];
+}
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindImage = "image";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindContainer = "container";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindVolume = "volume";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindImagesTotal = "images_total";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindContainersTotal = "containers_total";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindVolumesTotal = "volumes_total";
+/**
+ * Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+ * consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+ * the per-host sum under an empty name.
+ */
+export const DockerDiskKindBuildCacheTotal = "build_cache_total";
+/**
+ * DockerDiskUsageItem is one current top consumer of a host's Docker disk
+ * space (an image, a container's writable layer or a volume), flattened for
+ * the "top consumers" view.
+ */
+export interface DockerDiskUsageItem {
+  kind: string;
+  name: string;
+  detail: string; // image ref for containers, driver for volumes, image ID for images
+  compose_project?: string;
+  size_bytes: number /* int64 */;
+  collected_at: string;
+}
+/**
+ * DockerDiskUsageSummary is a host's current Docker disk usage: per-kind
+ * totals plus the largest items across all kinds.
+ */
+export interface DockerDiskUsageSummary {
+  host_id: string;
+  collected_at?: string;
+  images_size: number /* int64 */;
+  containers_size: number /* int64 */;
+  volumes_size: number /* int64 */;
+  build_cache_size: number /* int64 */;
+  build_cache_reclaimable: number /* int64 */;
+  top_consumers: DockerDiskUsageItem[];
+}
+/**
+ * DockerDiskUsagePoint is one bucketed history sample of a kind total or of a
+ * single volume/container.
+ */
+export interface DockerDiskUsagePoint {
+  timestamp: string;
+  size_bytes: number /* int64 */;
+}
+/**
+ * DockerVolumeGrowth is one row of the fleet-wide "top volume growth" view:
+ * a volume's latest size and how much it grew over the requested window.
+ */
+export interface DockerVolumeGrowth {
+  host_id: string;
+  hostname: string;
+  name: string;
+  compose_project?: string;
+  size_bytes: number /* int64 */;
+  growth_bytes: number /* int64 */;
+}
+/**
+ * SwarmService mirrors agent/internal/collector.SwarmService. For global
+ * services DesiredReplicas is the number of eligible nodes.
+ */
+export interface SwarmService {
+  service_id: string;
+  name: string;
+  image: string;
+  mode: string;
+  stack?: string;
+  desired_replicas: number /* int */;
+  running_replicas: number /* int */;
+  update_state?: string;
+}
+/**
+ * SwarmTask mirrors agent/internal/collector.SwarmTask.
+ */
+export interface SwarmTask {
+  task_id: string;
+  service_id: string;
+  service_name: string;
+  node_id: string;
+  slot?: number /* int */;
+  state: string;
+  desired_state: string;
+  message?: string;
+  error?: string;
+  updated_at: string;
+}
+/**
+ * SwarmNode mirrors agent/internal/collector.SwarmNode.
+ */
+export interface SwarmNode {
+  node_id: string;
+  hostname: string;
+  role: string;
+  availability: string;
+  state: string;
+  leader?: boolean;
+  reachability?: string;
+  addr?: string;
+  engine_version?: string;
+}
+/**
+ * DockerSwarmReport mirrors agent/internal/collector.DockerSwarmReport. Sent
+ * by every Docker host; only managers (Manager true) carry the lists.
+ */
+export interface DockerSwarmReport {
+  local_node_state: string;
+  manager: boolean;
+  cluster_id?: string;
+  node_id?: string;
+  services?: SwarmService[];
+  tasks?: SwarmTask[];
+  nodes?: SwarmNode[];
+}
+/**
+ * DockerSwarmState is the Swarm cluster as last seen by one manager host.
+ * UpdatedAt is nil when the host is not (or never was) a Swarm manager.
+ */
+export interface DockerSwarmState {
+  host_id: string;
+  cluster_id: string;
+  node_id: string;
+  updated_at?: string;
+  services: SwarmService[];
+  tasks: SwarmTask[];
+  nodes: SwarmNode[];
+}
 /**
  * DockerImageRef is a normalized (image, tag) pair — the cache key of the
  * ambient image-version engine and the unit of work of its refresh job.
@@ -886,23 +1418,70 @@ export interface UnattendedUpgradesConfigureRequest {
 }
 
 //////////
-// source: host.go
+// source: geoip.go
 
 /**
- * HostPermission records per-host access granted to a user.
+ * GeoInfo is the offline geolocation of an IP (see internal/geoip). Empty for
+ * private addresses and when no database is configured.
  */
-export interface HostPermission {
-  username: string;
-  host_id: string;
-  level: string; // "viewer" | "operator"
-  created_at: string;
+export interface GeoInfo {
+  country_code?: string;
+  country?: string;
+  city?: string;
+  asn?: number /* uint32 */;
+  as_org?: string;
 }
-export interface Host {
-  id: string;
-  name: string; // User-defined name (e.g., "Prod Web Server")
-  hostname: string; // System hostname (auto-populated by agent)
-  ip_address: string;
-  os: string; // Auto-populated by agent
+/**
+ * GeoGroup is one row of a group-by-country or group-by-ASN summary. Hits is
+ * set for web logs, RxBytes/TxBytes for network flows.
+ */
+export interface GeoGroup {
+  key: string; // ISO country code or "AS<n>"
+  label: string;
+  country_code?: string;
+  asn?: number /* uint32 */;
+  ips: number /* int */;
+  hits?: number /* int64 */;
+  rx_bytes?: number /* uint64 */;
+  tx_bytes?: number /* uint64 */;
+}
+/**
+ * GeoIPDatabase describes one loaded MMDB file.
+ */
+export interface GeoIPDatabase {
+  path: string;
+  loaded: boolean;
+  database_type?: string;
+  build_date?: string;
+  error?: string;
+}
+/**
+ * GeoIPStatus reports which geolocation databases the server uses.
+ */
+export interface GeoIPStatus {
+  enabled: boolean;
+  city?: GeoIPDatabase;
+  asn?: GeoIPDatabase;
+}
+
+//////////
+// source: host.go
+
+/**
+ * HostPermission records per-host access granted to a user.
+ */
+export interface HostPermission {
+  username: string;
+  host_id: string;
+  level: string; // "viewer" | "operator"
+  created_at: string;
+}
+export interface Host {
+  id: string;
+  name: string; // User-defined name (e.g., "Prod Web Server")
+  hostname: string; // System hostname (auto-populated by agent)
+  ip_address: string;
+  os: string; // Auto-populated by agent
   agent_version: string; // Agent version
   tags: string[];
   status: string; // online, offline, warning
@@ -1067,6 +1646,100 @@ export interface HostExposure {
   total_blocked_requests: number /* int64 */;
 }
 
+//////////
+// source: ip_block.go
+
+/**
+ * IP block decision statuses.
+ */
+export const IPBlockStatusApplied = "applied"; // CrowdSec bans dispatched
+/**
+ * IP block decision statuses.
+ */
+export const IPBlockStatusDryRun = "dry_run"; // notified only
+/**
+ * IP block decision statuses.
+ */
+export const IPBlockStatusFailed = "failed"; // no ban could be dispatched
+/**
+ * IP block decision statuses.
+ */
+export const IPBlockStatusReverted = "reverted"; // unbanned by an admin
+/**
+ * IPBlockPolicy bans, on every host with CrowdSec, the IPs whose threat score
+ * over the last WindowMinutes (all hosts together) exceeds MinScore. In
+ * DryRun mode it only records and notifies the decision.
+ */
+export interface IPBlockPolicy {
+  id: string;
+  name: string;
+  enabled: boolean;
+  min_score: number /* float64 */;
+  window_minutes: number /* int */;
+  ban_duration: string;
+  dry_run: boolean;
+  allowlist: string[]; // IPs or CIDRs never blocked
+  notify_channels: string[];
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+  last_run_at?: string;
+}
+/**
+ * IPBlockPolicyInput is the create/update payload. Omitted fields take their
+ * defaults on create (window 10 min, ban 4h, dry run on, browser
+ * notifications) and keep their value on update.
+ */
+export interface IPBlockPolicyInput {
+  name: string;
+  enabled?: boolean;
+  min_score?: number /* float64 */;
+  window_minutes?: number /* int */;
+  ban_duration?: string;
+  dry_run?: boolean;
+  allowlist: string[];
+  notify_channels: string[];
+}
+/**
+ * IPBlockDecision is the audit record of one automatic decision.
+ * TargetHostIDs/CommandIDs are the hosts banned and their crowdsec commands.
+ */
+export interface IPBlockDecision {
+  id: number /* int64 */;
+  policy_id: string;
+  policy_name: string;
+  ip: string;
+  score: number /* float64 */;
+  level: string;
+  hits: number /* int64 */;
+  unique_paths: number /* int64 */;
+  host_count: number /* int64 */;
+  duration: string;
+  status: string;
+  error?: string;
+  target_host_ids: string[];
+  command_ids: string[];
+  created_at: string;
+  expires_at: string;
+  reverted_at?: string;
+  reverted_by?: string;
+  /**
+   * Geo is the offline geolocation of IP, added on read.
+   */
+  geo?: GeoInfo;
+}
+/**
+ * IPThreatScore is one IP's threat score over a window, all hosts together.
+ */
+export interface IPThreatScore {
+  ip: string;
+  score: number /* float64 */;
+  level: string;
+  hits: number /* int64 */;
+  unique_paths: number /* int64 */;
+  host_count: number /* int64 */;
+}
+
 //////////
 // source: maintenance.go
 
@@ -1095,6 +1768,105 @@ export interface MaintenanceWindowRequest {
   ends_at: string;
 }
 
+//////////
+// source: net_interfaces.go
+
+/**
+ * NetInterface is one network interface as reported by the agent for a report
+ * cycle: link state plus the kernel's cumulative since-boot counters (see
+ * agent/internal/collector/net_interfaces.go). Mirrors
+ * agent/internal/collector.NetInterface. SystemMetrics' network_rx_bytes /
+ * network_tx_bytes stay the host-wide sum; this is the per-link view.
+ */
+export interface NetInterface {
+  name: string;
+  /**
+   * Kind is "physical", "bond", "bridge", "vlan" or "virtual".
+   */
+  kind: string;
+  /**
+   * OperState is the kernel's operstate verbatim (up, down,
+   * lowerlayerdown, dormant, unknown...). See NetInterfaceDown.
+   */
+  operstate: string;
+  /**
+   * SpeedMbps is 0 when the driver reports no link speed (virtual links,
+   * links that are down) — utilization is then unknown.
+   */
+  speed_mbps?: number /* int */;
+  duplex?: string;
+  mtu?: number /* int */;
+  master?: string; // bond or bridge this interface is enslaved to
+  parent?: string; // 802.1Q parent of a VLAN sub-interface
+  vlan_id?: number /* int */; // 802.1Q tag of a VLAN sub-interface
+  members?: string[]; // a bond's slaves or a bridge's ports
+  rx_bytes: number /* uint64 */;
+  tx_bytes: number /* uint64 */;
+  rx_packets: number /* uint64 */;
+  tx_packets: number /* uint64 */;
+  rx_errors: number /* uint64 */;
+  tx_errors: number /* uint64 */;
+  rx_dropped: number /* uint64 */;
+  tx_dropped: number /* uint64 */;
+}
+/**
+ * NetInterfaceStatus is an interface's latest sample, with rates derived from
+ * the sample before it. Rates are zero when there is no previous sample or the
+ * counters were reset in between (reboot, driver reload).
+ */
+export interface NetInterfaceStatus extends NetInterface {
+  timestamp: string;
+  rx_bytes_per_sec: number /* float64 */;
+  tx_bytes_per_sec: number /* float64 */;
+  rx_packets_per_sec: number /* float64 */;
+  tx_packets_per_sec: number /* float64 */;
+  errors_per_sec: number /* float64 */;
+  drops_per_sec: number /* float64 */;
+  /**
+   * UtilizationPercent is max(rx, tx) against the link speed; nil when the
+   * speed is unknown.
+   */
+  utilization_percent?: number /* float64 */;
+}
+/**
+ * NetInterfacePoint is one time bucket of an interface's history.
+ */
+export interface NetInterfacePoint {
+  timestamp: string;
+  rx_bytes_per_sec: number /* float64 */;
+  tx_bytes_per_sec: number /* float64 */;
+  rx_packets_per_sec: number /* float64 */;
+  tx_packets_per_sec: number /* float64 */;
+  errors_per_sec: number /* float64 */;
+  drops_per_sec: number /* float64 */;
+  utilization_percent?: number /* float64 */;
+  /**
+   * Down is true when any sample of the bucket saw the link down.
+   */
+  down: boolean;
+}
+/**
+ * NetInterfaceHistory is the bucketed history of one interface.
+ */
+export interface NetInterfaceHistory {
+  name: string;
+  points: NetInterfacePoint[];
+}
+/**
+ * NetInterfaceWindow sums an interface's counter deltas over an alert
+ * evaluation window. Intervals across a counter reset are left out, so
+ * Seconds can be shorter than the window.
+ */
+export interface NetInterfaceWindow {
+  Seconds: number /* float64 */;
+  RxBytes: number /* uint64 */;
+  TxBytes: number /* uint64 */;
+  Packets: number /* uint64 */;
+  Errors: number /* uint64 */;
+  SpeedMbps: number /* int */;
+  OperState: string;
+}
+
 //////////
 // source: network.go
 
@@ -1164,6 +1936,11 @@ export interface TopologySnapshot {
   hosts: NetworkHost[];
   containers: NetworkContainer[];
   config?: NetworkTopologyConfig;
+  /**
+   * Dependencies is the flow-derived service dependency layer, only built
+   * when requested (?layers=dependencies).
+   */
+  dependencies?: ServiceDependencyGraph;
   updated_at: string;
 }
 /**
@@ -1204,6 +1981,190 @@ export interface NetworkIPInventory {
   npm_hosts: NetworkNPMEntry[];
 }
 
+//////////
+// source: network_dependencies.go
+
+/**
+ * Service dependency node types.
+ */
+export const DependencyNodeHost = "host";
+/**
+ * Service dependency node types.
+ */
+export const DependencyNodeContainer = "container";
+/**
+ * Service dependency node types.
+ */
+export const DependencyNodeProxmoxGuest = "proxmox_guest";
+/**
+ * Service dependency node types.
+ */
+export const DependencyNodeExternal = "external";
+/**
+ * NetworkFlowObservation is one talker of one host summed over a window:
+ * the raw material of the dependency map, before its IPs are resolved.
+ */
+export interface NetworkFlowObservation {
+  host_id: string;
+  remote_ip: string;
+  remote_port: number /* int */;
+  protocol: string;
+  direction: string;
+  process_name?: string;
+  server_name?: string;
+  rx_bytes: number /* uint64 */;
+  tx_bytes: number /* uint64 */;
+  connections: number /* int64 */;
+  first_seen: string;
+  last_seen: string;
+}
+/**
+ * ServiceDependencyNode is an endpoint of the dependency map: an agented
+ * host, a container, a Proxmox guest without agent, or an unknown IP.
+ */
+export interface ServiceDependencyNode {
+  id: string; // "host:<id>", "container:<host_id>:<name>", "proxmox_guest:<id>", "ip:<addr>"
+  type: string; // host | container | proxmox_guest | external
+  name: string;
+  host_id?: string; // the host running a container
+  ip?: string;
+}
+/**
+ * ServiceDependencyEdge is "Source calls Target on Port": every observation
+ * of the same call summed over the window. Port is 0 when the call was only
+ * seen from the callee side, which does not know the service port. Sent and
+ * received bytes are from the caller's side.
+ */
+export interface ServiceDependencyEdge {
+  source: string;
+  target: string;
+  port: number /* int */;
+  protocol: string;
+  processes: string[];
+  server_names: string[];
+  sent_bytes: number /* uint64 */;
+  received_bytes: number /* uint64 */;
+  connections: number /* int64 */;
+  first_seen: string;
+  last_seen: string;
+  /**
+   * Observations are the talkers behind the edge; each one's history is
+   * GET /hosts/:host_id/network/flows/history.
+   */
+  observations: ServiceDependencyObservation[];
+}
+/**
+ * ServiceDependencyObservation identifies a talker of a host.
+ */
+export interface ServiceDependencyObservation {
+  host_id: string;
+  remote_ip: string;
+  remote_port: number /* int */;
+  protocol: string;
+  direction: string;
+}
+/**
+ * ServiceDependencyGraph is the "who calls whom" layer of the topology over
+ * [Since, Until].
+ */
+export interface ServiceDependencyGraph {
+  since: string;
+  until: string;
+  nodes: ServiceDependencyNode[];
+  edges: ServiceDependencyEdge[];
+}
+
+//////////
+// source: network_flow_policy.go
+
+/**
+ * Network flow policy modes.
+ */
+export const NetworkFlowPolicyAllow = "allow"; // only the listed peers are expected
+/**
+ * Network flow policy modes.
+ */
+export const NetworkFlowPolicyDeny = "deny"; // the listed peers are forbidden
+/**
+ * NetworkFlowPolicy states which peers the hosts in its scope talk to. In
+ * allow mode a talker matching none of the rules is a violation ("db hosts
+ * only talk to 10.0.0.0/24:5432"); in deny mode a talker matching any rule
+ * is ("no container may reach port 25"). Only the talkers of Direction and
+ * Subject are checked.
+ */
+export interface NetworkFlowPolicy {
+  id: string;
+  name: string;
+  description: string;
+  enabled: boolean;
+  host_ids: string[]; // scope, with Tags; both empty = every host
+  tags: string[]; // hosts with any of these tags
+  direction: string; // outbound | inbound | any
+  subject: string; // any | container | host (non-container processes)
+  mode: string; // allow | deny
+  rules: NetworkFlowPolicyRule[];
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+}
+/**
+ * NetworkFlowPolicyRule matches a peer. Empty fields match anything. Port
+ * is the remote port and only applies to outbound flows: on an inbound flow
+ * the remote port is the client's ephemeral port.
+ */
+export interface NetworkFlowPolicyRule {
+  cidr?: string; // IP or CIDR
+  port?: number /* int */; // 1-65535
+  protocol?: string; // tcp | udp
+  server_name?: string; // SNI or resolved name from the L7 capture, "*.example.com" allowed
+}
+/**
+ * NetworkFlowPolicyInput is the create/update payload. Omitted fields take
+ * their defaults on create (outbound, any subject) and keep their value on
+ * update; a list, when present, replaces the stored one.
+ */
+export interface NetworkFlowPolicyInput {
+  name: string;
+  description?: string;
+  enabled?: boolean;
+  host_ids: string[];
+  tags: string[];
+  direction?: string;
+  subject?: string;
+  mode?: string;
+  rules: NetworkFlowPolicyRule[];
+}
+/**
+ * NetworkFlowViolation is one offending flow of a host under a policy,
+ * refreshed on every report it is seen in. Connections and bytes add up the
+ * report cycles; Container is the container name when ProcessName carries
+ * the agent's "conteneur: <name>" attribution.
+ */
+export interface NetworkFlowViolation {
+  id: number /* int64 */;
+  policy_id: string;
+  policy_name: string;
+  mode: string;
+  host_id: string;
+  host_name: string;
+  remote_ip: string;
+  remote_port: number /* int */; // 0 for inbound flows
+  protocol: string;
+  direction: string;
+  process_name?: string;
+  container?: string;
+  server_name?: string;
+  connections: number /* int64 */;
+  rx_bytes: number /* uint64 */;
+  tx_bytes: number /* uint64 */;
+  first_seen: string;
+  last_seen: string;
+  /**
+   * Geo is the offline geolocation of RemoteIP, added on read.
+   */
+  geo?: GeoInfo;
+}
+
 //////////
 // source: network_flows.go
 
@@ -1230,6 +2191,11 @@ export interface NetworkFlowsReport {
   top_talkers?: NetworkFlowTalker[];
   others?: NetworkFlowBucket;
   total_flows: number /* int */;
+  /**
+   * DNS is what the agent's optional L7 capture saw of the host's DNS
+   * lookups this cycle; nil when the capture is off or saw none.
+   */
+  dns?: DNSQueryStats;
   collected_at: string;
 }
 /**
@@ -1255,8 +2221,9 @@ export interface NetworkFlowTalker {
   process_name?: string;
   pid?: number /* int */;
   /**
-   * ServerName is the TLS SNI hostname observed on an outbound flow. Only
-   * ever populated when the agent's optional, off-by-default L7 capture is
+   * ServerName is the TLS SNI hostname observed on an outbound flow, else
+   * the name the host resolved the remote IP from (DNS answer). Only ever
+   * populated when the agent's optional, off-by-default L7 capture is
    * enabled (network_flows_l7_capture, needs CAP_NET_RAW); empty otherwise,
    * in which case the frontend falls back to its own well-known-port guess.
    */
@@ -1296,6 +2263,21 @@ export interface NetworkFlowMetric {
   tx_bytes: number /* uint64 */;
   packets: number /* uint64 */;
   connections: number /* int */;
+  /**
+   * Geo is the offline geolocation of RemoteIP (internal/geoip), added on
+   * read — never stored.
+   */
+  geo?: GeoInfo;
+}
+/**
+ * NetworkFlowPeerTotal is one remote IP's traffic summed over a window, all
+ * ports and protocols together — the input of the per-country/ASN summary.
+ */
+export interface NetworkFlowPeerTotal {
+  remote_ip: string;
+  rx_bytes: number /* uint64 */;
+  tx_bytes: number /* uint64 */;
+  connections: number /* int64 */;
 }
 /**
  * NetworkFlowSummaryPoint is one time-bucketed point of a host's total tracked
@@ -1306,6 +2288,54 @@ export interface NetworkFlowSummaryPoint {
   rx_bytes: number /* uint64 */;
   tx_bytes: number /* uint64 */;
 }
+/**
+ * DNSQueryStats is the agent-reported summary of the DNS responses its L7
+ * capture sampled over one cycle: totals plus the busiest queried names
+ * (agent-side top 20). Mirrors agent/internal/collector.DNSQueryStats.
+ */
+export interface DNSQueryStats {
+  responses: number /* int */;
+  nxdomain: number /* int */;
+  top_domains?: DNSDomainStat[];
+}
+/**
+ * DNSDomainStat is one queried name's responses, NXDOMAIN included.
+ */
+export interface DNSDomainStat {
+  name: string;
+  responses: number /* int */;
+  nxdomain: number /* int */;
+}
+/**
+ * DNSDomainTotal is one queried name summed over a window.
+ */
+export interface DNSDomainTotal {
+  name: string;
+  responses: number /* int64 */;
+  nxdomain: number /* int64 */;
+}
+/**
+ * DNSQueryPoint is one time-bucketed point of a host's DNS responses.
+ */
+export interface DNSQueryPoint {
+  timestamp: string;
+  responses: number /* int64 */;
+  nxdomain: number /* int64 */;
+}
+/**
+ * DNSQueryReport is a host's DNS activity over a window: the most queried
+ * names, the names failing most (NXDOMAIN) and the NXDOMAIN rate over time.
+ * Counts are samples (the capture window of each cycle), so rates and
+ * rankings are meaningful, absolute volumes only relatively.
+ */
+export interface DNSQueryReport {
+  responses: number /* int64 */;
+  nxdomain: number /* int64 */;
+  nxdomain_rate: number /* float64 */; // percent, 0 without responses
+  top_domains: DNSDomainTotal[];
+  top_nxdomains: DNSDomainTotal[];
+  points: DNSQueryPoint[];
+}
 
 //////////
 // source: npm.go
@@ -1405,13 +2435,13 @@ export interface NPMProxyHostPreview {
 }
 
 //////////
-// source: proxmox.go
+// source: pbs.go
 
 /**
- * ProxmoxConnection stores configuration for one Proxmox VE endpoint.
- * token_secret is never serialised to JSON to avoid leaks.
+ * PBSConnection stores configuration for one Proxmox Backup Server endpoint.
+ * Token secret is never exposed via API.
  */
-export interface ProxmoxConnection {
+export interface PBSConnection {
   id: string;
   name: string;
   api_url: string;
@@ -1419,6 +2449,7 @@ export interface ProxmoxConnection {
   insecure_skip_verify: boolean;
   enabled: boolean;
   poll_interval_sec: number /* int */;
+  version: string;
   last_error: string;
   last_error_at?: string;
   last_success_at?: string;
@@ -1427,15 +2458,114 @@ export interface ProxmoxConnection {
   /**
    * Computed stats (joined, not stored)
    */
-  node_count?: number /* int */;
-  guest_count?: number /* int */;
+  datastore_count?: number /* int */;
+  group_count?: number /* int */;
 }
-export interface ProxmoxNode {
+/**
+ * PBSConnectionRequest is the body for create/update endpoints.
+ * TokenSecret is optional on update (empty means "keep existing").
+ */
+export interface PBSConnectionRequest {
+  name: string;
+  api_url: string;
+  token_id: string;
+  token_secret: string;
+  insecure_skip_verify: boolean;
+  enabled: boolean;
+  poll_interval_sec: number /* int */;
+}
+/**
+ * PBSDatastore is the usage of one datastore, plus its last GC result.
+ */
+export interface PBSDatastore {
   id: string;
   connection_id: string;
-  node_name: string;
-  cpu_temp_source_host_id?: string;
-  cpu_temp_source_host_name?: string;
+  connection_name: string;
+  store: string;
+  total: number /* int64 */;
+  used: number /* int64 */;
+  avail: number /* int64 */;
+  last_error?: string;
+  gc_state: string;
+  gc_last_run_at?: string;
+  group_count: number /* int */;
+  last_seen_at: string;
+}
+/**
+ * PBSBackupGroup is one backup group (vm/100, ct/101, host/name) of a datastore.
+ * GuestName is resolved from the PVE inventory when the VMID is known.
+ */
+export interface PBSBackupGroup {
+  id: string;
+  connection_id: string;
+  connection_name: string;
+  store: string;
+  backup_type: string; // vm | ct | host
+  backup_id: string;
+  guest_name?: string;
+  backup_count: number /* int */;
+  last_backup_at?: string;
+  owner?: string;
+  comment?: string;
+  last_verify_state: string; // ok | failed | "" (never verified)
+  last_verify_at?: string;
+  verify_failed_count: number /* int */;
+  last_seen_at: string;
+}
+/**
+ * PBSJob is the status of one GC, prune, verify or sync job.
+ */
+export interface PBSJob {
+  id: string;
+  connection_id: string;
+  connection_name: string;
+  job_type: string; // gc | prune | verify | sync
+  job_id: string;
+  store: string;
+  schedule: string;
+  last_run_state: string;
+  last_run_at?: string;
+  last_run_upid?: string;
+  next_run_at?: string;
+  remote?: string;
+  remote_store?: string;
+  removed_bytes?: number /* int64 */;
+  pending_bytes?: number /* int64 */;
+  last_seen_at: string;
+}
+
+//////////
+// source: proxmox.go
+
+/**
+ * ProxmoxConnection stores configuration for one Proxmox VE endpoint.
+ * token_secret is never serialised to JSON to avoid leaks.
+ */
+export interface ProxmoxConnection {
+  id: string;
+  name: string;
+  api_url: string;
+  token_id: string;
+  insecure_skip_verify: boolean;
+  enabled: boolean;
+  poll_interval_sec: number /* int */;
+  last_error: string;
+  last_error_at?: string;
+  last_success_at?: string;
+  created_at: string;
+  updated_at: string;
+  /**
+   * Computed stats (joined, not stored)
+   */
+  node_count?: number /* int */;
+  guest_count?: number /* int */;
+}
+export interface ProxmoxNode {
+  id: string;
+  connection_id: string;
+  node_name: string;
+  cpu_temp_source_host_id?: string;
+  cpu_temp_source_host_name?: string;
   fan_rpm_source_host_id?: string;
   fan_rpm_source_host_name?: string;
   status: string;
@@ -1497,6 +2627,7 @@ export interface ProxmoxStorage {
   enabled: boolean;
   active: boolean;
   shared: boolean;
+  content: string; // comma-separated PVE content types
   last_seen_at: string;
 }
 /**
@@ -1517,155 +2648,529 @@ export interface ProxmoxTask {
   last_seen_at: string;
 }
 /**
- * ProxmoxBackupJob represents a Proxmox backup job configuration (GET /cluster/backup).
+ * ProxmoxBackupJob represents a Proxmox backup job configuration (GET /cluster/backup).
+ */
+export interface ProxmoxBackupJob {
+  id: string;
+  connection_id: string;
+  job_id: string;
+  enabled: boolean;
+  schedule: string;
+  storage: string;
+  mode: string; // snapshot | suspend | stop
+  compress: string;
+  vmids: string; // comma-separated VMIDs or "all"
+  mail_to: string;
+  last_seen_at: string;
+}
+/**
+ * ProxmoxBackupRun stores the latest backup result per VM (derived from vzdump tasks).
+ */
+export interface ProxmoxBackupRun {
+  id: string;
+  connection_id: string;
+  node_name: string;
+  vmid: number /* int */;
+  task_upid: string;
+  status: string; // OK | error | running
+  start_time?: string;
+  end_time?: string;
+  exit_status: string;
+  last_seen_at: string;
+  /**
+   * Joined from proxmox_guests
+   */
+  guest_name?: string;
+}
+/**
+ * ProxmoxDisk represents a physical disk in a Proxmox node (GET /nodes/{node}/disks/list).
+ */
+export interface ProxmoxDisk {
+  id: string;
+  connection_id: string;
+  node_name: string;
+  dev_path: string;
+  model: string;
+  serial: string;
+  size_bytes: number /* int64 */;
+  disk_type: string; // ssd | hdd | nvme | unknown
+  health: string; // PASSED | FAILED | UNKNOWN
+  wearout: number /* int */; // SSD wear % (100=new), -1 if N/A
+  last_seen_at: string;
+}
+/**
+ * ProxmoxConnectionRequest is the body for create/update endpoints.
+ * TokenSecret is optional on update (empty means "keep existing").
+ */
+export interface ProxmoxConnectionRequest {
+  name: string;
+  api_url: string;
+  token_id: string;
+  token_secret: string;
+  insecure_skip_verify: boolean;
+  enabled: boolean;
+  poll_interval_sec: number /* int */;
+}
+/**
+ * ProxmoxSummary is returned by GET /proxmox/summary.
+ */
+export interface ProxmoxSummary {
+  connection_count: number /* int */;
+  node_count: number /* int */;
+  vm_count: number /* int */;
+  lxc_count: number /* int */;
+  storage_total: number /* int64 */;
+  storage_used: number /* int64 */;
+  /**
+   * Health signals (computed, read-only)
+   */
+  nodes_down: number /* int */;
+  storage_near_full: number /* int */; // usage > 80 %
+  storage_offline: number /* int */; // active=false or enabled=false
+  recent_failed_tasks: number /* int */; // exit_status != 'OK' in last 24 h
+  /**
+   * IDs of the proxmox_nodes rows behind each health signal above, so the
+   * frontend can filter/highlight the node table instead of showing an
+   * inactionable count. Node-level (NodesDown) is a direct proxmox_nodes
+   * query; the other three join through proxmox_storages/proxmox_tasks on
+   * (connection_id, node_name) since those tables don't carry a node ID.
+   */
+  nodes_down_ids?: string[];
+  storage_near_full_node_ids?: string[];
+  storage_offline_node_ids?: string[];
+  failed_task_node_ids?: string[];
+}
+/**
+ * ProxmoxNodeMetricsSummary is a time-bucketed aggregate used for dashboard trend charts.
+ * cpu_avg is expressed as a percentage (0‒100).
+ */
+export interface ProxmoxNodeMetricsSummary {
+  timestamp: string;
+  cpu_avg: number /* float64 */;
+  memory_avg: number /* float64 */;
+  sample_count: number /* int */;
+}
+/**
+ * ProxmoxGuestLink maps a Proxmox guest (VM/LXC) to a ServerSupervisor host (agent).
+ * Status lifecycle: suggested (auto-detected) → confirmed (validated) or ignored (dismissed).
+ * MetricsSource controls which data source feeds CPU/RAM/disk in host views.
+ */
+export interface ProxmoxGuestLink {
+  id: string;
+  guest_id: string;
+  host_id: string;
+  status: string; // suggested | confirmed | ignored
+  metrics_source: string; // auto | agent | proxmox
+  created_at: string;
+  updated_at: string;
+  /**
+   * Joined display fields (populated on list/get)
+   */
+  guest_name?: string;
+  guest_type?: string;
+  node_name?: string;
+  vmid?: number /* int */;
+  host_name?: string;
+  host_hostname?: string;
+  /**
+   * Live metrics from the Proxmox guest (populated on list/get)
+   */
+  cpu_alloc: number /* float64 */;
+  cpu_usage: number /* float64 */;
+  mem_alloc: number /* int64 */;
+  mem_usage: number /* int64 */;
+  disk_alloc: number /* int64 */;
+  disk_usage: number /* int64 */;
+}
+/**
+ * ProxmoxGuestLinkRequest is the body for POST /proxmox/links.
+ */
+export interface ProxmoxGuestLinkRequest {
+  guest_id: string;
+  host_id: string;
+  status: string; // defaults to "confirmed"
+  metrics_source: string; // defaults to "auto"
+}
+/**
+ * ProxmoxGuestLinkUpdate is the body for PUT /proxmox/links/:id.
+ */
+export interface ProxmoxGuestLinkUpdate {
+  status?: string;
+  metrics_source?: string;
+}
+/**
+ * ProxmoxSnapshot is one snapshot of a guest, read live from PVE.
+ * Automatic marks snapshots created (and pruned) by a snapshot policy.
+ */
+export interface ProxmoxSnapshot {
+  name: string;
+  description: string;
+  snap_time?: string;
+  parent?: string;
+  vmstate: boolean;
+  automatic: boolean;
+}
+/**
+ * ProxmoxSnapshotCreate is the body for POST /proxmox/guests/:id/snapshots.
+ * Name defaults to "manual_<timestamp>". Confirm must be true: the UI only
+ * sets it after the admin confirmed the action.
+ */
+export interface ProxmoxSnapshotCreate {
+  name: string;
+  description: string;
+  vmstate: boolean;
+  confirm: boolean;
+}
+/**
+ * ProxmoxSnapshotAction is the body for the rollback and delete routes.
+ */
+export interface ProxmoxSnapshotAction {
+  confirm: boolean;
+}
+/**
+ * ProxmoxSnapshotPolicy snapshots its guests on a cron schedule (server local
+ * time) and prunes the snapshots it created, restic-style: a snapshot is kept
+ * when any keep_* rule selects it.
+ */
+export interface ProxmoxSnapshotPolicy {
+  id: string;
+  name: string;
+  enabled: boolean;
+  schedule: string; // 5-field cron expression
+  keep_last: number /* int */;
+  keep_daily: number /* int */;
+  keep_weekly: number /* int */;
+  keep_monthly: number /* int */;
+  vmstate: boolean;
+  guest_ids: string[];
+  last_run_at?: string;
+  last_status: string; // "" (never ran) | success | partial | failed
+  last_error?: string;
+  next_run_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+/**
+ * ProxmoxSnapshotPolicyRequest is the body for creating/updating a policy.
+ */
+export interface ProxmoxSnapshotPolicyRequest {
+  name: string;
+  enabled?: boolean; // defaults to true
+  schedule: string;
+  keep_last: number /* int */;
+  keep_daily: number /* int */;
+  keep_weekly: number /* int */;
+  keep_monthly: number /* int */;
+  vmstate: boolean;
+  guest_ids: string[];
+}
+/**
+ * ProxmoxSnapshotPolicyRun is the outcome of one policy run on one guest.
+ */
+export interface ProxmoxSnapshotPolicyRun {
+  id: number /* int64 */;
+  policy_id: string;
+  guest_id: string;
+  guest_name: string;
+  vmid: number /* int */;
+  started_at: string;
+  finished_at?: string;
+  status: string; // running | success | failed
+  snapshot_name: string;
+  pruned: number /* int */;
+  error?: string;
+}
+/**
+ * ProxmoxProvision is one clone-from-template request and its progress.
+ * Status: pending → cloning → configuring → starting → waiting_agent → linked,
+ * or failed.
+ */
+export interface ProxmoxProvision {
+  id: string;
+  connection_id: string;
+  connection_name: string;
+  node_name: string;
+  template_vmid: number /* int */;
+  vmid: number /* int */;
+  name: string;
+  cores: number /* int */;
+  memory_mb: number /* int */;
+  disk: string;
+  disk_size_gb: number /* int */;
+  bridge: string;
+  vlan_tag: number /* int */;
+  ip_config: string; // PVE ipconfig0 value, "" = dhcp
+  ssh_authorized_keys: string;
+  host_id?: string;
+  guest_id?: string;
+  seed_fetched_at?: string;
+  status: string;
+  error?: string;
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+  linked_at?: string;
+}
+/**
+ * ProxmoxProvisionRequest is the body of POST /proxmox/provisions.
+ */
+export interface ProxmoxProvisionRequest {
+  connection_id: string;
+  node_name: string;
+  template_vmid: number /* int */;
+  vmid: number /* int */; // 0 = next free VMID
+  name: string;
+  full_clone: boolean;
+  storage: string; // full clone target, "" = template's
+  cores: number /* int */;
+  memory_mb: number /* int */;
+  disk: string; // disk to grow, defaults to scsi0
+  disk_size_gb: number /* int */;
+  bridge: string;
+  vlan_tag: number /* int */;
+  /**
+   * IPConfig is a PVE ipconfig0 value ("ip=10.0.0.20/24,gw=10.0.0.1");
+   * empty means DHCP.
+   */
+  ip_config: string;
+  nameserver: string;
+  ssh_authorized_keys: string;
+  tags: string[];
+}
+/**
+ * ProxmoxTemplate is a QEMU template that can be cloned.
+ */
+export interface ProxmoxTemplate {
+  node_name: string;
+  vmid: number /* int */;
+  name: string;
+}
+/**
+ * ProxmoxClusterHealth is the Ceph / HA / corosync state of one connection,
+ * refreshed on every poll. The per-object lists are filled by the detail read.
+ */
+export interface ProxmoxClusterHealth {
+  connection_id: string;
+  connection_name: string;
+  quorate: boolean;
+  cluster_nodes: number /* int */; // 0 = standalone node
+  cluster_nodes_online: number /* int */;
+  ceph_available: boolean;
+  ceph_health: string; // HEALTH_OK | HEALTH_WARN | HEALTH_ERR | HEALTH_UNKNOWN
+  ceph_checks: ProxmoxCephCheck[];
+  ceph_error?: string;
+  osds_total: number /* int */;
+  osds_up: number /* int */;
+  osds_in: number /* int */;
+  pgs_total: number /* int */;
+  pgs_active_clean: number /* int */;
+  pg_states: ProxmoxCephPGState[];
+  ceph_bytes_used: number /* int64 */;
+  ceph_bytes_total: number /* int64 */;
+  mons_total: number /* int */;
+  mons_in_quorum: number /* int */;
+  mons: ProxmoxCephMon[];
+  ha_enabled: boolean;
+  ha_quorate: boolean;
+  ha_master_node: string;
+  ha_error?: string;
+  osds?: ProxmoxCephOSD[];
+  pools?: ProxmoxCephPool[];
+  ha_entries?: ProxmoxHAEntry[];
+  updated_at: string;
+}
+/**
+ * ProxmoxCephCheck is one active Ceph health check (OSD_DOWN, PG_DEGRADED…).
+ */
+export interface ProxmoxCephCheck {
+  code: string;
+  severity: string;
+  message: string;
+  muted: boolean;
+}
+/**
+ * ProxmoxCephPGState counts the placement groups in one state.
+ */
+export interface ProxmoxCephPGState {
+  state: string;
+  count: number /* int */;
+}
+/**
+ * ProxmoxCephMon is one Ceph monitor and whether it is part of the quorum.
+ */
+export interface ProxmoxCephMon {
+  name: string;
+  host: string;
+  in_quorum: boolean;
+}
+/**
+ * ProxmoxCephOSD is one OSD and the node hosting it.
+ */
+export interface ProxmoxCephOSD {
+  osd_id: number /* int */;
+  name: string;
+  host: string;
+  status: string; // up | down
+  in: boolean;
+  device_class: string;
+  percent_used: number /* float64 */; // -1 if unknown
+  last_seen_at: string;
+}
+/**
+ * ProxmoxCephPool is one Ceph pool with its usage.
  */
-export interface ProxmoxBackupJob {
-  id: string;
-  connection_id: string;
-  job_id: string;
-  enabled: boolean;
-  schedule: string;
-  storage: string;
-  mode: string; // snapshot | suspend | stop
-  compress: string;
-  vmids: string; // comma-separated VMIDs or "all"
-  mail_to: string;
+export interface ProxmoxCephPool {
+  name: string;
+  size: number /* int */;
+  min_size: number /* int */;
+  pg_num: number /* int */;
+  bytes_used: number /* int64 */;
+  percent_used: number /* float64 */; // 0–100
   last_seen_at: string;
 }
 /**
- * ProxmoxBackupRun stores the latest backup result per VM (derived from vzdump tasks).
+ * ProxmoxHAEntry is the CRM master, one node's LRM or one HA resource.
  */
-export interface ProxmoxBackupRun {
-  id: string;
-  connection_id: string;
+export interface ProxmoxHAEntry {
+  entry_id: string;
+  entry_type: string; // master | lrm | service
   node_name: string;
-  vmid: number /* int */;
-  task_upid: string;
-  status: string; // OK | error | running
-  start_time?: string;
-  end_time?: string;
-  exit_status: string;
+  sid?: string;
+  state: string;
+  request_state?: string;
+  status: string;
+  healthy: boolean;
   last_seen_at: string;
+}
+/**
+ * ProxmoxStorageVolume is one item of a storage's content (ISO, container
+ * template, backup or disk image), read live from PVE.
+ */
+export interface ProxmoxStorageVolume {
+  volid: string;
+  content: string; // iso | vztmpl | backup | images | rootdir | snippets
+  format: string;
+  size: number /* int64 */;
+  used?: number /* int64 */;
+  vmid?: number /* int */;
+  guest_name?: string;
+  created_at?: string;
+  notes?: string;
+  protected: boolean;
   /**
-   * Joined from proxmox_guests
+   * Orphaned marks a disk image whose VMID no longer exists in the cluster.
    */
-  guest_name?: string;
+  orphaned: boolean;
 }
 /**
- * ProxmoxDisk represents a physical disk in a Proxmox node (GET /nodes/{node}/disks/list).
+ * ProxmoxStorageDownloadRequest is the body for
+ * POST /proxmox/storages/:id/download-url. Filename defaults to the last
+ * segment of the URL; Checksum is optional (hex, with its algorithm).
  */
-export interface ProxmoxDisk {
-  id: string;
-  connection_id: string;
-  node_name: string;
-  dev_path: string;
-  model: string;
-  serial: string;
-  size_bytes: number /* int64 */;
-  disk_type: string; // ssd | hdd | nvme | unknown
-  health: string; // PASSED | FAILED | UNKNOWN
-  wearout: number /* int */; // SSD wear % (100=new), -1 if N/A
-  last_seen_at: string;
+export interface ProxmoxStorageDownloadRequest {
+  url: string;
+  filename: string;
+  content: string; // iso | vztmpl
+  checksum: string;
+  checksum_algorithm: string;
+  skip_cert_verify: boolean;
+  confirm: boolean;
 }
 /**
- * ProxmoxConnectionRequest is the body for create/update endpoints.
- * TokenSecret is optional on update (empty means "keep existing").
+ * ProxmoxStorageVolumeDelete is the body for DELETE /proxmox/storages/:id/content.
  */
-export interface ProxmoxConnectionRequest {
-  name: string;
-  api_url: string;
-  token_id: string;
-  token_secret: string;
-  insecure_skip_verify: boolean;
-  enabled: boolean;
-  poll_interval_sec: number /* int */;
+export interface ProxmoxStorageVolumeDelete {
+  volid: string;
+  confirm: boolean;
 }
 /**
- * ProxmoxSummary is returned by GET /proxmox/summary.
+ * ProxmoxConsoleRequest is the body for POST /proxmox/guests/:id/console.
  */
-export interface ProxmoxSummary {
-  connection_count: number /* int */;
-  node_count: number /* int */;
-  vm_count: number /* int */;
-  lxc_count: number /* int */;
-  storage_total: number /* int64 */;
-  storage_used: number /* int64 */;
-  /**
-   * Health signals (computed, read-only)
-   */
-  nodes_down: number /* int */;
-  storage_near_full: number /* int */; // usage > 80 %
-  storage_offline: number /* int */; // active=false or enabled=false
-  recent_failed_tasks: number /* int */; // exit_status != 'OK' in last 24 h
-  /**
-   * IDs of the proxmox_nodes rows behind each health signal above, so the
-   * frontend can filter/highlight the node table instead of showing an
-   * inactionable count. Node-level (NodesDown) is a direct proxmox_nodes
-   * query; the other three join through proxmox_storages/proxmox_tasks on
-   * (connection_id, node_name) since those tables don't carry a node ID.
-   */
-  nodes_down_ids?: string[];
-  storage_near_full_node_ids?: string[];
-  storage_offline_node_ids?: string[];
-  failed_task_node_ids?: string[];
+export interface ProxmoxConsoleRequest {
+  kind: string; // vnc (noVNC, QEMU only) | term (xterm.js)
 }
 /**
- * ProxmoxNodeMetricsSummary is a time-bucketed aggregate used for dashboard trend charts.
- * cpu_avg is expressed as a percentage (0‒100).
+ * ProxmoxConsoleSession is a console opened on a guest, to be attached through
+ * the /ws/proxmox/console/:session websocket before ExpiresAt. Password is the
+ * one-time PVE ticket noVNC answers the VNC auth with; it is useless without
+ * a PVE session, which only the server holds.
  */
-export interface ProxmoxNodeMetricsSummary {
-  timestamp: string;
-  cpu_avg: number /* float64 */;
-  memory_avg: number /* float64 */;
-  sample_count: number /* int */;
+export interface ProxmoxConsoleSession {
+  session_id: string;
+  kind: string;
+  guest_name: string;
+  password?: string;
+  expires_at: string;
 }
 /**
- * ProxmoxGuestLink maps a Proxmox guest (VM/LXC) to a ServerSupervisor host (agent).
- * Status lifecycle: suggested (auto-detected) → confirmed (validated) or ignored (dismissed).
- * MetricsSource controls which data source feeds CPU/RAM/disk in host views.
+ * ProxmoxPendingConsole is a console session opened on one replica and not
+ * attached yet (table proxmox_console_sessions). Ticket is the PVE
+ * vncproxy/termproxy answer, JSON-encoded. Not exposed over the API.
  */
-export interface ProxmoxGuestLink {
+export interface ProxmoxPendingConsole {
+  ID: string;
+  Username: string;
+  ClientIP: string;
+  Kind: string;
+  GuestID: string;
+  HostID: string;
+  Ticket: string;
+  ExpiresAt: string;
+}
+/**
+ * ProxmoxRightsizingRun is one weekly right-sizing analysis.
+ */
+export interface ProxmoxRightsizingRun {
   id: string;
-  guest_id: string;
-  host_id: string;
-  status: string; // suggested | confirmed | ignored
-  metrics_source: string; // auto | agent | proxmox
-  created_at: string;
-  updated_at: string;
-  /**
-   * Joined display fields (populated on list/get)
-   */
+  started_at: string;
+  finished_at?: string;
+  status: string; // running | success | failed
+  guests_analyzed: number /* int */;
+  nodes_analyzed: number /* int */;
+  recommendations: number /* int */;
+  error?: string;
+}
+/**
+ * ProxmoxRightsizingRecommendation is one finding of a right-sizing run: a
+ * guest to resize or migrate, or a node whose failure the rest of its
+ * cluster could not absorb. Suggested sizes are advice (applied in PVE);
+ * a TargetNode can be applied with the guest migration endpoint, from
+ * NodeID (the guest's current node).
+ */
+export interface ProxmoxRightsizingRecommendation {
+  id: string;
+  connection_id: string;
+  connection_name: string;
+  guest_id?: string;
   guest_name?: string;
-  guest_type?: string;
-  node_name?: string;
+  guest_type?: string; // vm | lxc
   vmid?: number /* int */;
-  host_name?: string;
-  host_hostname?: string;
+  node_name: string;
+  node_id?: string;
+  kind: string; // overprovisioned_memory | underprovisioned_memory | underprovisioned_cpu | cpu_contention | node_failover | rebalance
+  severity: string; // info | warning | critical
+  current_cpus: number /* float64 */;
+  current_mem: number /* int64 */;
+  suggested_cpus: number /* float64 */;
+  suggested_mem: number /* int64 */;
+  target_node?: string;
+  reason: string;
   /**
-   * Live metrics from the Proxmox guest (populated on list/get)
+   * Observed over the analysis window: p95 of RAM and CPU as a share of
+   * the allocation (0-1), share of samples with the balloon inflated
+   * (0-1), average CPU pressure (%).
    */
-  cpu_alloc: number /* float64 */;
-  cpu_usage: number /* float64 */;
-  mem_alloc: number /* int64 */;
-  mem_usage: number /* int64 */;
-  disk_alloc: number /* int64 */;
-  disk_usage: number /* int64 */;
-}
-/**
- * ProxmoxGuestLinkRequest is the body for POST /proxmox/links.
- */
-export interface ProxmoxGuestLinkRequest {
-  guest_id: string;
-  host_id: string;
-  status: string; // defaults to "confirmed"
-  metrics_source: string; // defaults to "auto"
+  mem_p95: number /* float64 */;
+  cpu_p95: number /* float64 */;
+  balloon_ratio: number /* float64 */;
+  cpu_pressure: number /* float64 */;
 }
 /**
- * ProxmoxGuestLinkUpdate is the body for PUT /proxmox/links/:id.
+ * ProxmoxRightsizingReport is the latest run with its recommendations.
  */
-export interface ProxmoxGuestLinkUpdate {
-  status?: string;
-  metrics_source?: string;
+export interface ProxmoxRightsizingReport {
+  run?: ProxmoxRightsizingRun;
+  recommendations: ProxmoxRightsizingRecommendation[];
 }
 
 //////////
@@ -1688,6 +3193,11 @@ export interface AgentCapabilities {
    * kernel could actually provide byte counters — see NetworkFlowsReport.Available.
    */
   network_flows: boolean;
+  /**
+   * CrowdSec is set when the agent has CrowdSec machine credentials, i.e.
+   * can apply crowdsec ban/unban commands.
+   */
+  crowdsec: boolean;
 }
 /**
  * DiagnosticIssue mirrors agent/internal/collector.DiagnosticIssue — one
@@ -1741,6 +3251,7 @@ export interface AgentReport {
   compose_projects?: ComposeProject[];
   disk_metrics?: DiskMetrics[];
   disk_health?: DiskHealth[];
+  net_interfaces?: NetInterface[];
   custom_tasks?: CustomTaskSummary[];
   tasks_config_yaml?: string;
   restic?: ResticStatus;
@@ -1748,6 +3259,44 @@ export interface AgentReport {
   restic_groups?: string[];
   network_flows?: NetworkFlowsReport;
   timestamp: string;
+  /**
+   * DockerDiskUsage is only present on the cycles where the agent re-measured
+   * (see agent collector.CollectDockerDiskUsage) — each one is a history point.
+   */
+  docker_disk_usage?: DockerDiskUsageReport;
+  /**
+   * DockerSwarm is present whenever the agent could read the engine's
+   * /info; Manager false means "drop this host's Swarm state".
+   */
+  docker_swarm?: DockerSwarmReport;
+  /**
+   * CrowdSec is only present on the cycles where the agent queried its
+   * local API (see agent collector.CollectCrowdSec).
+   */
+  crowdsec?: CrowdSecReport;
+}
+/**
+ * BackfillSample is the compact, metrics-only form of a report the agent could
+ * not deliver, replayed later with its original collection time.
+ */
+export interface BackfillSample {
+  timestamp: string;
+  metrics?: SystemMetrics;
+  disk_metrics?: DiskMetrics[];
+}
+/**
+ * BackfillRequest is the body of POST /api/agent/backfill, oldest sample first.
+ */
+export interface BackfillRequest {
+  samples: BackfillSample[];
+}
+/**
+ * BackfillResult tells the agent how many samples were stored; skipped ones
+ * (already stored, too old, or not older than the live data) are dropped too.
+ */
+export interface BackfillResult {
+  accepted: number /* int */;
+  skipped: number /* int */;
 }
 
 //////////
@@ -2057,6 +3606,163 @@ export interface CustomTaskSummary {
   name: string;
 }
 
+//////////
+// source: threat_rule.go
+
+/**
+ * Threat rule severities, highest first. When several enabled rules match a
+ * request, the most severe one classifies it.
+ */
+export const ThreatSeverityCritical = "critical";
+/**
+ * Threat rule severities, highest first. When several enabled rules match a
+ * request, the most severe one classifies it.
+ */
+export const ThreatSeverityHigh = "high";
+/**
+ * Threat rule severities, highest first. When several enabled rules match a
+ * request, the most severe one classifies it.
+ */
+export const ThreatSeverityMedium = "medium";
+/**
+ * Threat rule severities, highest first. When several enabled rules match a
+ * request, the most severe one classifies it.
+ */
+export const ThreatSeverityLow = "low";
+/**
+ * Threat rule sources: the signatures shipped with the server, the ones an
+ * admin wrote, and otherwise the name of the imported rule pack.
+ */
+export const ThreatRuleSourceBuiltin = "builtin";
+/**
+ * Threat rule sources: the signatures shipped with the server, the ones an
+ * admin wrote, and otherwise the name of the imported rule pack.
+ */
+export const ThreatRuleSourceCustom = "custom";
+/**
+ * ThreatRule is a web-request signature used by internal/threatdetect. Every
+ * non-empty pattern is a Go regexp that must match (AND); Query is matched
+ * without the leading "?", Status against the decimal code.
+ */
+export interface ThreatRule {
+  id: string;
+  name: string;
+  description: string;
+  category: string;
+  severity: string;
+  method_pattern: string;
+  path_pattern: string;
+  query_pattern: string;
+  user_agent_pattern: string;
+  status_pattern: string;
+  enabled: boolean;
+  source: string;
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+  /**
+   * Hit counters, computed from the stored web_log_requests.
+   */
+  hits_24h: number /* int64 */;
+  hits_7d: number /* int64 */;
+  last_hit_at?: string;
+}
+/**
+ * ThreatRuleHits are the hit counters of one rule.
+ */
+export interface ThreatRuleHits {
+  Hits24h: number /* int64 */;
+  Hits7d: number /* int64 */;
+  LastHitAt?: string;
+}
+/**
+ * ThreatRuleInput is the create/update payload. On update, a built-in rule
+ * only takes Enabled into account.
+ */
+export interface ThreatRuleInput {
+  name: string;
+  description: string;
+  category: string;
+  severity: string;
+  method_pattern: string;
+  path_pattern: string;
+  query_pattern: string;
+  user_agent_pattern: string;
+  status_pattern: string;
+  enabled?: boolean;
+}
+/**
+ * ThreatRuleSample is a request to try a rule against.
+ */
+export interface ThreatRuleSample {
+  method: string;
+  path: string;
+  query: string;
+  user_agent: string;
+  status: number /* int */;
+}
+/**
+ * ThreatRuleTestRequest tries a draft rule against samples and against the
+ * requests stored over the last Hours (default 24; negative skips the
+ * replay).
+ */
+export interface ThreatRuleTestRequest {
+  rule: ThreatRuleInput;
+  samples: ThreatRuleSample[];
+  hours: number /* int */;
+}
+/**
+ * ThreatRuleTestResult reports what a draft rule matches. Scanned/Matched
+ * cover the replay over stored requests (the most recent ones, capped), with
+ * a few matching examples to spot false positives.
+ */
+export interface ThreatRuleTestResult {
+  sample_matches: boolean[];
+  scanned: number /* int */;
+  matched: number /* int */;
+  examples: ThreatRuleSample[];
+}
+/**
+ * ThreatRulePackRule is one signature of an imported rule pack; ID is unique
+ * within the pack.
+ */
+export interface ThreatRulePackRule {
+  id: string;
+  name: string;
+  description: string;
+  category: string;
+  severity: string;
+  method_pattern: string;
+  path_pattern: string;
+  query_pattern: string;
+  user_agent_pattern: string;
+  status_pattern: string;
+}
+/**
+ * ThreatRulePack is an importable set of signatures. Importing the same pack
+ * again updates its rules and keeps their enabled state.
+ */
+export interface ThreatRulePack {
+  name: string;
+  rules: ThreatRulePackRule[];
+}
+/**
+ * ThreatRuleImportRequest imports either a pack shipped with the server
+ * (Bundled, e.g. "crs-lite") or the given Pack.
+ */
+export interface ThreatRuleImportRequest {
+  bundled: string;
+  pack?: ThreatRulePack;
+}
+/**
+ * ThreatRuleImportResult counts the rules an import added and updated.
+ */
+export interface ThreatRuleImportResult {
+  pack: string;
+  created: number /* int */;
+  updated: number /* int */;
+}
+
 //////////
 // source: tracker.go
 
@@ -2310,6 +4016,21 @@ export interface WebRequest {
   blocked_reason?: string;
   blocked_at?: string;
   blocked_until?: string;
+  /**
+   * Timing and upstream fields, absent when the agent's log format does not
+   * carry them. Times are in milliseconds; 0 is a timed response faster
+   * than the log's resolution.
+   */
+  response_time_ms?: number /* float64 */;
+  upstream_time_ms?: number /* float64 */;
+  upstream_status?: number /* int */;
+  upstream?: string;
+  /**
+   * Query is the raw query string, without the leading "?". RuleID is the
+   * threat rule that set Category, filled in server-side with it.
+   */
+  query?: string;
+  rule_id?: string;
 }
 export interface NPMPathHit {
   path: string;
@@ -2378,6 +4099,41 @@ export interface WebLogIPTimelineRow {
   blocked_reason?: string;
   blocked_at?: string;
   blocked_until?: string;
+  /**
+   * Nil when the log format does not carry them (see WebRequest).
+   */
+  response_time_ms?: number /* float64 */;
+  upstream_status?: number /* int */;
+  upstream?: string;
+}
+/**
+ * WebPerformanceStat is the latency and error profile of a domain, or of one
+ * normalized route of a domain (Route empty on domain rows). Percentiles only
+ * cover the requests whose log format carries a response time: they are 0
+ * when TimedRequests is 0.
+ */
+export interface WebPerformanceStat {
+  domain: string;
+  route?: string;
+  requests: number /* int64 */;
+  timed_requests: number /* int64 */;
+  errors_4xx: number /* int64 */;
+  errors_5xx: number /* int64 */;
+  error_rate: number /* float64 */; // share of 5xx, in percent
+  p50_ms: number /* float64 */;
+  p95_ms: number /* float64 */;
+  p99_ms: number /* float64 */;
+}
+/**
+ * WebPerformanceReport is the performance view of the access logs: every
+ * domain, the busiest routes, and the routes ranked by error rate and by p95
+ * latency.
+ */
+export interface WebPerformanceReport {
+  domains: WebPerformanceStat[];
+  routes: WebPerformanceStat[];
+  error_routes: WebPerformanceStat[];
+  slowest_routes: WebPerformanceStat[];
 }
 
 //////////
@@ -2635,6 +4391,14 @@ export interface WSBackupRunMessage {
   type: string;
   notification: WSBackupNotification;
 }
+/**
+ * WSIPBlockMessage is pushed when an IP blocking policy makes a decision,
+ * dry runs included (type "ip_block").
+ */
+export interface WSIPBlockMessage {
+  type: string;
+  notification: IPBlockDecision;
+}
 /**
  * WSReleaseTrackerNotification is the nested payload of release-tracker messages.
  * version/release_url/release_name/label are populated only for
@@ -12,4 +12,27 @@ export type {
   ProxmoxSummary,
   ProxmoxGuestLinkRequest,
   ProxmoxGuestLinkUpdate,
+  ProxmoxSnapshot,
+  ProxmoxSnapshotCreate,
+  ProxmoxSnapshotPolicy,
+  ProxmoxSnapshotPolicyRequest,
+  ProxmoxSnapshotPolicyRun,
+  ProxmoxProvision,
+  ProxmoxProvisionRequest,
+  ProxmoxTemplate,
+  ProxmoxClusterHealth,
+  ProxmoxCephOSD,
+  ProxmoxCephPool,
+  ProxmoxHAEntry,
+  ProxmoxStorageVolume,
+  ProxmoxStorageDownloadRequest,
+  ProxmoxConsoleSession,
+  ProxmoxRightsizingRun,
+  ProxmoxRightsizingRecommendation,
+  ProxmoxRightsizingReport,
+  PBSConnection,
+  PBSConnectionRequest,
+  PBSDatastore,
+  PBSBackupGroup,
+  PBSJob,
 } from './generated'
@@ -1,9 +1,14 @@
+export type AlertMetricSource = 'agent' | 'proxmox' | 'synthetic' | 'docker'
+
 export interface AlertMetricMeta {
   label: string
   unit: string
   icon: string
   badgeClass: string
-  category: 'host' | 'proxmox' | 'synthetic' | 'docker'
+  category: 'host' | 'proxmox' | 'synthetic' | 'docker' | 'web' | 'network' | 'security'
+  // Rule source the server evaluates the metric under, when it differs from
+  // the category (e.g. a Docker metric collected by the agent).
+  source?: AlertMetricSource
 }
 
 export const ALERT_METRICS: Record<string, AlertMetricMeta> = {
@@ -126,6 +131,13 @@ export const ALERT_METRICS: Record<string, AlertMetricMeta> = {
     badgeClass: 'bg-cyan-lt text-cyan',
     category: 'proxmox',
   },
+  proxmox_snapshot_policy_failures: {
+    label: 'Politiques de snapshot en échec',
+    unit: '',
+    icon: '\ud83d\udcf8',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
   proxmox_node_pending_updates: {
     label: 'Paquets APT en attente',
     unit: '',
@@ -161,6 +173,83 @@ export const ALERT_METRICS: Record<string, AlertMetricMeta> = {
     badgeClass: 'bg-cyan-lt text-cyan',
     category: 'proxmox',
   },
+  proxmox_ceph_health: {
+    label: 'Santé Ceph (0 OK, 1 WARN, 2 ERR)',
+    unit: '',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ceph_osds_down: {
+    label: 'OSD Ceph down',
+    unit: '',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ceph_osds_out: {
+    label: 'OSD Ceph out',
+    unit: '',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ceph_pgs_not_clean: {
+    label: 'PG Ceph non active+clean',
+    unit: '',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ceph_pool_max_percent: {
+    label: 'Pool Ceph le plus rempli',
+    unit: '%',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ceph_mons_out_of_quorum: {
+    label: 'Moniteurs Ceph hors quorum',
+    unit: '',
+    icon: '\ud83d\udc19',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ha_resources_error: {
+    label: 'Ressources HA en erreur',
+    unit: '',
+    icon: '\ud83d\udea8',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_ha_manager_errors: {
+    label: 'Gestionnaires HA (CRM/LRM) en défaut',
+    unit: '',
+    icon: '\ud83d\udea8',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  proxmox_cluster_quorum_lost: {
+    label: 'Quorum corosync perdu',
+    unit: '',
+    icon: '\ud83d\udd17',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  pbs_backup_age_hours: {
+    label: 'Âge dernière sauvegarde PBS',
+    unit: 'h',
+    icon: '\ud83d\udcbe',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
+  pbs_verify_failed: {
+    label: 'Vérifications PBS en échec',
+    unit: '',
+    icon: '\ud83d\udee1',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'proxmox',
+  },
   docker_container_state: {
     label: 'État d\'un container',
     unit: '',
@@ -175,6 +264,77 @@ export const ALERT_METRICS: Record<string, AlertMetricMeta> = {
     badgeClass: 'bg-blue-lt text-blue',
     category: 'docker',
   },
+  docker_swarm_service_missing_replicas: {
+    label: 'Réplicas Swarm manquants',
+    unit: '',
+    icon: '🐳',
+    badgeClass: 'bg-blue-lt text-blue',
+    category: 'docker',
+  },
+  docker_swarm_node_state: {
+    label: 'État d\'un noeud Swarm',
+    unit: '',
+    icon: '🐳',
+    badgeClass: 'bg-blue-lt text-blue',
+    category: 'docker',
+  },
+  docker_volume_growth_bytes_24h: {
+    label: 'Croissance volume Docker (24h)',
+    unit: ' o',
+    icon: '🐳',
+    badgeClass: 'bg-blue-lt text-blue',
+    category: 'docker',
+    source: 'agent',
+  },
+  web_domain_5xx_rate: {
+    label: 'Taux d\'erreurs 5xx par domaine',
+    unit: '%',
+    icon: '🌐',
+    badgeClass: 'bg-red-lt text-red',
+    category: 'web',
+  },
+  web_domain_p95_latency_ms: {
+    label: 'Latence p95 par domaine',
+    unit: ' ms',
+    icon: '🌐',
+    badgeClass: 'bg-orange-lt text-orange',
+    category: 'web',
+  },
+  net_interface_down: {
+    label: 'Interface réseau coupée',
+    unit: '',
+    icon: '🔌',
+    badgeClass: 'bg-red-lt text-red',
+    category: 'network',
+  },
+  net_interface_error_rate: {
+    label: 'Taux d\'erreurs par interface',
+    unit: '%',
+    icon: '📡',
+    badgeClass: 'bg-orange-lt text-orange',
+    category: 'network',
+  },
+  net_interface_utilization: {
+    label: 'Utilisation du lien par interface',
+    unit: '%',
+    icon: '📡',
+    badgeClass: 'bg-cyan-lt text-cyan',
+    category: 'network',
+  },
+  network_policy_violation: {
+    label: 'Violation de politique réseau',
+    unit: '',
+    icon: '🚧',
+    badgeClass: 'bg-red-lt text-red',
+    category: 'network',
+  },
+  crowdsec_bouncer_last_pull_minutes: {
+    label: 'Dernier pull d\'un bouncer CrowdSec',
+    unit: ' min',
+    icon: '🛡',
+    badgeClass: 'bg-orange-lt text-orange',
+    category: 'security',
+  },
   uptime_down_count: {
     label: 'Sondes uptime down',
     unit: '',
@@ -209,13 +369,35 @@ export const ALERT_METRIC_ORDER = [
   'proxmox_node_fan_rpm',
   'proxmox_guest_cpu_percent',
   'proxmox_guest_memory_percent',
+  'proxmox_snapshot_policy_failures',
   'proxmox_node_pending_updates',
   'proxmox_recent_failed_tasks_24h',
   'proxmox_auth_failures_recent',
   'proxmox_disk_failed_count',
   'proxmox_disk_min_wearout_percent',
+  'proxmox_ceph_health',
+  'proxmox_ceph_osds_down',
+  'proxmox_ceph_osds_out',
+  'proxmox_ceph_pgs_not_clean',
+  'proxmox_ceph_pool_max_percent',
+  'proxmox_ceph_mons_out_of_quorum',
+  'proxmox_ha_resources_error',
+  'proxmox_ha_manager_errors',
+  'proxmox_cluster_quorum_lost',
+  'pbs_backup_age_hours',
+  'pbs_verify_failed',
   'docker_container_state',
   'docker_compose_degraded_services',
+  'docker_swarm_service_missing_replicas',
+  'docker_swarm_node_state',
+  'docker_volume_growth_bytes_24h',
+  'web_domain_5xx_rate',
+  'web_domain_p95_latency_ms',
+  'net_interface_down',
+  'net_interface_error_rate',
+  'net_interface_utilization',
+  'network_policy_violation',
+  'crowdsec_bouncer_last_pull_minutes',
   'uptime_down_count',
   'ssl_min_days_remaining',
 ]
@@ -229,3 +411,17 @@ export function getAlertMetricMeta(metric: string): AlertMetricMeta {
     category: 'host',
   }
 }
+
+export function getAlertMetricSource(metric: string): AlertMetricSource {
+  const meta = getAlertMetricMeta(metric)
+  if (meta.source) return meta.source
+  switch (meta.category) {
+    case 'proxmox':
+    case 'synthetic':
+    case 'docker':
+      return meta.category
+    default:
+      // host and the agent-collected categories (web, network, security)
+      return 'agent'
+  }
+}
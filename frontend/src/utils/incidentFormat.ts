@@ -90,6 +90,16 @@ export function formatIncidentValue(item: ValueFields): string {
     const n = Number(value)
     return n === 1 ? '1 service dégradé' : `${n} services dégradés`
   }
+  if (metric === 'docker_swarm_service_missing_replicas') {
+    const n = Number(value)
+    return n === 1 ? '1 réplica manquant' : `${n} réplicas manquants`
+  }
+  if (metric === 'docker_swarm_node_state') {
+    const n = Number(value)
+    if (n < 0.5) return 'ready'
+    if (n < 1.5) return 'drain/pause'
+    return 'down'
+  }
   return `${Number(value).toFixed(2)}${metricUnit(metric)}`
 }
 
@@ -20,12 +20,13 @@ export function resolveIncidentHostRoute(hostId?: string | null, metric?: string
       case 'connection':
       case 'storage':
       case 'disk':
+      case 'backup_group':
       default:
         return '/proxmox'
     }
   }
 
-  if (metric && metric.startsWith('proxmox_')) {
+  if (metric && (metric.startsWith('proxmox_') || metric.startsWith('pbs_'))) {
     return '/proxmox'
   }
 
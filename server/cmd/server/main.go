@@ -5,22 +5,29 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/serversupervisor/server/internal/api"
 	"github.com/serversupervisor/server/internal/background"
+	"github.com/serversupervisor/server/internal/cluster"
 	"github.com/serversupervisor/server/internal/config"
 	"github.com/serversupervisor/server/internal/database"
 	"github.com/serversupervisor/server/internal/dispatch"
 	"github.com/serversupervisor/server/internal/events"
+	"github.com/serversupervisor/server/internal/geoip"
 	"github.com/serversupervisor/server/internal/handlers"
 	"github.com/serversupervisor/server/internal/logging"
 	"github.com/serversupervisor/server/internal/poller"
 	"github.com/serversupervisor/server/internal/scheduler"
+	agentauthsvc "github.com/serversupervisor/server/internal/services/agentauth"
 	backupsvc "github.com/serversupervisor/server/internal/services/backup"
+	ipblocksvc "github.com/serversupervisor/server/internal/services/ipblock"
 	pushsvc "github.com/serversupervisor/server/internal/services/push"
+	threatrulessvc "github.com/serversupervisor/server/internal/services/threatrules"
+	weblogssvc "github.com/serversupervisor/server/internal/services/weblogs"
 	"github.com/serversupervisor/server/internal/ws"
 )
 
@@ -99,12 +106,35 @@ func main() {
 		}
 	}
 
+	// Offline IP geolocation for web logs, CrowdSec decisions and network
+	// flows. A missing file only disables the lookups it would serve.
+	if cfg.GeoIPCityDB != "" || cfg.GeoIPASNDB != "" {
+		resolver, err := geoip.Open(cfg.GeoIPCityDB, cfg.GeoIPASNDB)
+		if err != nil {
+			slog.WarnContext(rootCtx, "geoip database not loaded", slog.Any("err", err))
+		}
+		geoip.SetDefault(resolver)
+	}
+
 	dispatcher := dispatch.New(db)
 
-	// Start task scheduler
+	// High availability (HA_ENABLED): the relay carries bus topics, agent
+	// nudges, command output, notifications and scheduler changes to the other
+	// replicas, and the elector below keeps the singleton work on one of them.
+	// On a single server relay stays nil (every Attach is a no-op) and the
+	// elector is always the leader.
+	var relay *cluster.Relay
+	leaderLock := cluster.AlwaysLeader
+	if cfg.HAEnabled {
+		relay = cluster.StartPostgresRelay(rootCtx, db, cfg.DBDSN())
+		leaderLock = cluster.AdvisoryLock(db, cluster.LeaderLockKey)
+		slog.Info("HA mode: background jobs, pollers and scheduled tasks run on the elected leader only")
+	}
+
+	// Start task scheduler. Every replica keeps the entries (NextRun answers
+	// anywhere); only the leader queues the commands.
 	sched := scheduler.New(db, dispatcher)
-	sched.Start(rootCtx)
-	defer sched.Stop()
+	relay.AttachScheduler(sched)
 
 	// Notification hub — shared between alert engine (push on fire) and WS handler
 	notifHub := ws.NewNotificationHub()
@@ -113,64 +143,102 @@ func main() {
 	// Event bus — writers publish topics; WS snapshot endpoints subscribe and push
 	// on change instead of polling the DB on a fixed timer.
 	eventBus := events.NewBus()
+	relay.AttachBus(eventBus)
+
+	// Agent certificates and request signatures, on top of the API keys.
+	agentAuth := agentauthsvc.NewService(db, cfg)
 
-	// Start background jobs (each runs in its own goroutine with panic recovery)
-	bg := background.New()
-	bg.Add(background.NewAuditCleanupJob(db, cfg))
-	if cfg.DemoMode {
-		// Demo hosts never send real heartbeats, so this job (offline after a
-		// 2-minute-stale last_seen, every 30s) would flip every seeded "online"
-		// host offline within minutes — fighting the seed's fixed fleet state
-		// instead of real network isolation, so it's gated here rather than in
-		// the network-call group above.
-		slog.Info("demo mode: skipping host-status job (seeded hosts have no real heartbeat)")
-	} else {
-		bg.Add(background.NewHostStatusJob(db, eventBus))
-	}
-	bg.Add(background.NewAlertEvalJob(db, cfg, dispatcher, notifHub, pushSvc))
-	// Metric downsampling is handled by the TimescaleDB continuous aggregate
-	// (system_metrics_5min); metric retention/compression by Timescale policies.
-	// The remaining job only trims release-tracker tag digests.
-	bg.Add(background.NewMetricsRetentionJob(db, cfg))
-	bg.Add(background.NewWebLogsRetentionJob(db, cfg))
-	bg.Add(background.NewNetworkFlowsRetentionJob(db, cfg))
-	if cfg.DemoMode {
-		slog.Info("demo mode: skipping uptime/SSL probe workers (no outbound network calls)")
-	} else {
-		bg.Add(background.NewUptimeWorkerJob(db))
-		bg.Add(background.NewSSLWorkerJob(db))
-	}
-	// Separate Service instance from the one wired into the router/completion
-	// listener — CheckStalledRuns only needs repo+notify, no HTTP-facing state.
-	backupStallSvc := backupsvc.NewService(db, dispatcher, cfg, notifHub, pushSvc)
-	bg.Add(background.NewBackupStallJob(backupStallSvc, 360))
-	bg.Start(rootCtx)
-	defer bg.Stop()
+	// Threat detection rules: built-ins refreshed for this release, then the
+	// stored set compiled. On failure the built-in rules still apply.
+	threatRules := threatrulessvc.NewService(db)
+	if err := threatRules.SyncBuiltins(rootCtx); err != nil {
+		slog.Error("threat rules: failed to sync built-in rules", slog.Any("err", err))
+	}
+	_ = threatRules.Reload(rootCtx)
+	relay.AttachThreatRules(threatRules)
 
 	// Setup router
-	router, releaseTrackerH, proxmoxH, npmH, cleanupRouter := api.SetupRouter(db, cfg, notifHub, eventBus, sched, dispatcher)
+	router, releaseTrackerH, proxmoxH, npmH, cleanupRouter := api.SetupRouter(db, cfg, notifHub, eventBus, sched, dispatcher, relay, agentAuth, threatRules)
 	defer cleanupRouter()
-	// Background pollers: the handlers expose the unit of work + a fire-and-forget
-	// ctx; the poller package owns the scheduling loop. rootCtx cancellation
-	// (SIGINT/SIGTERM) stops both loops, so no explicit Stop is needed.
-	if cfg.DemoMode {
-		slog.Info("demo mode: skipping release-tracker/docker-image-versions/proxmox/npm pollers (no outbound network calls)")
-	} else {
+	// Handlers hand fire-and-forget work (e.g. a "poll now" click) to rootCtx
+	// on whichever replica served the request.
+	if !cfg.DemoMode {
 		releaseTrackerH.SetBackgroundContext(rootCtx)
-		poller.Every(rootCtx, releaseTrackerH.PollInterval(), true, "release-tracker", releaseTrackerH.CheckAll)
-		// Ambient Docker image-version engine: one registry check per distinct
-		// image:tag running anywhere, so every container gets an up-to-date/outdated
-		// badge — not just the ones with a release tracker. Much slower cadence than
-		// the tracker poller above (it scans the whole fleet, and registries rate
-		// limit per source IP); the tracker poller reads its cache instead of
-		// calling a registry itself.
-		poller.Every(rootCtx, releaseTrackerH.DockerImagePollInterval(), true, "docker-image-versions", releaseTrackerH.RefreshDockerImageVersions)
 		proxmoxH.SetBackgroundContext(rootCtx)
-		poller.Every(rootCtx, handlers.ProxmoxPollInterval, true, "proxmox", proxmoxH.PollOnce)
 		npmH.SetBackgroundContext(rootCtx)
-		poller.Every(rootCtx, handlers.NPMPollInterval, false, "npm-sync", npmH.PollOnce)
 	}
 
+	// Singleton work — background jobs and pollers — runs for as long as this
+	// replica is the leader: ctx is cancelled when leadership is lost or on
+	// shutdown.
+	leaderWork := func(ctx context.Context) {
+		// Start background jobs (each runs in its own goroutine with panic recovery)
+		bg := background.New()
+		bg.Add(background.NewAuditCleanupJob(db, cfg))
+		if cfg.DemoMode {
+			// Demo hosts never send real heartbeats, so this job (offline after a
+			// 2-minute-stale last_seen, every 30s) would flip every seeded "online"
+			// host offline within minutes — fighting the seed's fixed fleet state
+			// instead of real network isolation, so it's gated here rather than in
+			// the network-call group above.
+			slog.Info("demo mode: skipping host-status job (seeded hosts have no real heartbeat)")
+		} else {
+			bg.Add(background.NewHostStatusJob(db, eventBus))
+		}
+		bg.Add(background.NewAlertEvalJob(db, cfg, dispatcher, notifHub, pushSvc))
+		// Metric downsampling is handled by the TimescaleDB continuous aggregate
+		// (system_metrics_5min); metric retention/compression by Timescale policies.
+		// The remaining job only trims release-tracker tag digests.
+		bg.Add(background.NewMetricsRetentionJob(db, cfg))
+		bg.Add(background.NewWebLogsRetentionJob(db, cfg))
+		bg.Add(background.NewNetworkFlowsRetentionJob(db, cfg))
+		if cfg.DemoMode {
+			slog.Info("demo mode: skipping uptime/SSL probe workers (no outbound network calls)")
+		} else {
+			bg.Add(background.NewUptimeWorkerJob(db))
+			bg.Add(background.NewSSLWorkerJob(db))
+		}
+		// Separate Service instance from the one wired into the router/completion
+		// listener — CheckStalledRuns only needs repo+notify, no HTTP-facing state.
+		backupStallSvc := backupsvc.NewService(db, dispatcher, cfg, notifHub, pushSvc)
+		bg.Add(background.NewBackupStallJob(backupStallSvc, 360))
+		// Same for the blocking policies: a private web logs service scores IPs.
+		ipBlockSvc := ipblocksvc.NewService(db, dispatcher, weblogssvc.NewService(db, dispatcher, cfg),
+			ipblocksvc.ChannelNotifier(cfg, notifHub, pushSvc))
+		bg.Add(background.NewIPBlockPolicyJob(ipBlockSvc))
+		bg.Start(ctx)
+		defer bg.Stop()
+
+		// Background pollers: the handlers expose the unit of work; the poller
+		// package owns the scheduling loop, stopped by ctx cancellation.
+		if cfg.DemoMode {
+			slog.Info("demo mode: skipping release-tracker/docker-image-versions/proxmox/pbs/npm pollers (no outbound network calls)")
+		} else {
+			poller.Every(ctx, releaseTrackerH.PollInterval(), true, "release-tracker", releaseTrackerH.CheckAll)
+			// Ambient Docker image-version engine: one registry check per distinct
+			// image:tag running anywhere, so every container gets an up-to-date/outdated
+			// badge — not just the ones with a release tracker. Much slower cadence than
+			// the tracker poller above (it scans the whole fleet, and registries rate
+			// limit per source IP); the tracker poller reads its cache instead of
+			// calling a registry itself.
+			poller.Every(ctx, releaseTrackerH.DockerImagePollInterval(), true, "docker-image-versions", releaseTrackerH.RefreshDockerImageVersions)
+			poller.Every(ctx, handlers.ProxmoxPollInterval, true, "proxmox", proxmoxH.PollOnce)
+			poller.Every(ctx, handlers.ProxmoxSnapshotPolicyInterval, false, "proxmox-snapshot-policies", proxmoxH.RunDueSnapshotPolicies)
+			poller.Every(ctx, handlers.PBSPollInterval, true, "pbs", proxmoxH.PollPBS)
+			poller.Every(ctx, handlers.ProxmoxRightsizingInterval, false, "proxmox-rightsizing", proxmoxH.RunDueRightsizing)
+			poller.Every(ctx, handlers.NPMPollInterval, false, "npm-sync", npmH.PollOnce)
+		}
+		<-ctx.Done()
+	}
+	elector := cluster.NewElector(leaderLock, leaderWork)
+	if cfg.HAEnabled {
+		sched.SetLeaderCheck(elector.IsLeader)
+	}
+	sched.Start(rootCtx)
+	defer sched.Stop()
+	elector.Start(rootCtx)
+	defer elector.Stop()
+
 	// Start server
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -187,12 +255,41 @@ func main() {
 		}
 	}()
 
+	// Agent mTLS listener: /api/agent/* only, client certificate required.
+	var mtlsSrv *http.Server
+	if agentAuth.MTLSEnabled() {
+		ca, err := agentAuth.CA(rootCtx)
+		if err != nil {
+			log.Fatalf("Failed to load the agent CA: %v", err)
+		}
+		u, err := url.Parse(cfg.AgentMTLSURL)
+		if err != nil {
+			log.Fatalf("Invalid AGENT_MTLS_URL: %v", err)
+		}
+		mtlsSrv = &http.Server{
+			Addr:        ":" + cfg.AgentMTLSPort,
+			Handler:     api.AgentListenerHandler(router),
+			TLSConfig:   ca.ServerTLSConfig([]string{u.Hostname()}),
+			ReadTimeout: 15 * time.Second,
+			IdleTimeout: 60 * time.Second,
+		}
+		go func() {
+			log.Printf("Agent mTLS listener on :%s (%s)", cfg.AgentMTLSPort, cfg.AgentMTLSURL)
+			if err := mtlsSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Agent mTLS listener failed: %v", err)
+			}
+		}()
+	}
+
 	// Graceful shutdown — wait for SIGINT/SIGTERM (already wired via signal.NotifyContext).
 	<-rootCtx.Done()
 	log.Println("Shutting down server...")
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if mtlsSrv != nil {
+		_ = mtlsSrv.Shutdown(shutdownCtx)
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
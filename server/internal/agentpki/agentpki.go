@@ -0,0 +1,261 @@
+// Package agentpki is the cryptography behind strong agent authentication: the
+// small certificate authority that issues per-host client certificates for
+// mTLS, and the canonical form of a signed agent request (the alternative for
+// agents behind a TLS-terminating proxy). It holds no state of its own —
+// internal/services/agentauth stores the CA and the enrolled credentials.
+package agentpki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	caValidity     = 10 * 365 * 24 * time.Hour
+	serverValidity = 90 * 24 * time.Hour
+	// serverRenewBefore is how long before expiry the listener certificate is
+	// reissued; it is re-checked on every handshake.
+	serverRenewBefore = 30 * 24 * time.Hour
+	// clockSkew backdates NotBefore so a host whose clock runs slightly
+	// behind accepts a certificate issued a moment ago.
+	clockSkew = 5 * time.Minute
+)
+
+// CA is the agent certificate authority.
+type CA struct {
+	cert    *x509.Certificate
+	key     crypto.Signer
+	certPEM []byte
+}
+
+// NewCA generates a fresh CA and returns it PEM-encoded, ready to be stored.
+func NewCA() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "ServerSupervisor agent CA"},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// ParseCA loads a CA produced by NewCA.
+func ParseCA(certPEM, keyPEM []byte) (*CA, error) {
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("agent CA certificate: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("agent CA key: no PEM block")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("agent CA key: %w", err)
+	}
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("agent CA key: not a signing key")
+	}
+	return &CA{cert: cert, key: signer, certPEM: certPEM}, nil
+}
+
+// CertPEM returns the CA certificate, which agents pin to reach the mTLS
+// listener.
+func (ca *CA) CertPEM() []byte { return ca.certPEM }
+
+// Pool returns a pool holding the CA certificate, for verifying client
+// certificates.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// IssuedCert is a client certificate issued for an agent.
+type IssuedCert struct {
+	PEM         []byte
+	Serial      string // hex
+	Fingerprint string // SHA-256 of the DER, hex
+	NotAfter    time.Time
+}
+
+// IssueClientCert signs the agent's certificate request. The subject is set
+// to hostID whatever the request says: the host is the one the enrollment
+// request authenticated as.
+func (ca *CA) IssueClientCert(csrPEM []byte, hostID string, ttl time.Duration) (*IssuedCert, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, errors.New("csr: expected a PEM CERTIFICATE REQUEST")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("csr: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("csr: bad signature: %w", err)
+	}
+	if err := checkKey(csr.PublicKey); err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hostID, Organization: []string{"ServerSupervisor agents"}},
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &IssuedCert{
+		PEM:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Serial:      SerialHex(serial),
+		Fingerprint: hex.EncodeToString(sum[:]),
+		NotAfter:    tmpl.NotAfter,
+	}, nil
+}
+
+// ServerTLSConfig returns the TLS configuration of the mTLS listener: a
+// certificate issued by the CA for hosts (DNS names or IPs), reissued as it
+// nears expiry, and a client certificate signed by the CA required on every
+// connection.
+func (ca *CA) ServerTLSConfig(hosts []string) *tls.Config {
+	var (
+		mu      sync.Mutex
+		current *tls.Certificate
+	)
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  ca.Pool(),
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if current == nil || time.Until(current.Leaf.NotAfter) < serverRenewBefore {
+				c, err := ca.issueServerCert(hosts)
+				if err != nil {
+					return nil, err
+				}
+				current = c
+			}
+			return current, nil
+		},
+	}
+}
+
+func (ca *CA) issueServerCert(hosts []string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "ServerSupervisor agent endpoint"},
+		NotBefore:    now.Add(-clockSkew),
+		NotAfter:     now.Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else if h != "" {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// ParseCertificatePEM parses the first certificate of a PEM bundle.
+func ParseCertificatePEM(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("expected a PEM CERTIFICATE")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// SerialHex is the form serials are stored and looked up in.
+func SerialHex(serial *big.Int) string {
+	return hex.EncodeToString(serial.Bytes())
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+}
+
+// checkKey accepts the key types a current agent can generate, refusing weak
+// RSA keys.
+func checkKey(pub any) error {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return errors.New("csr: RSA keys must be at least 2048 bits")
+		}
+		return nil
+	default:
+		return fmt.Errorf("csr: unsupported key type %T", pub)
+	}
+}
@@ -0,0 +1,138 @@
+package agentpki
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func newTestCA(t *testing.T) *CA {
+	t.Helper()
+	certPEM, keyPEM, err := NewCA()
+	if err != nil {
+		t.Fatalf("new CA: %v", err)
+	}
+	ca, err := ParseCA(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+	return ca
+}
+
+func newCSR(t *testing.T, cn string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: cn}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestIssueClientCert_ChainsToCAWithHostSubject(t *testing.T) {
+	ca := newTestCA(t)
+	// The CSR claims another host: the issued subject is the authenticated one.
+	issued, err := ca.IssueClientCert(newCSR(t, "someone-else"), "host-1", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	cert, err := ParseCertificatePEM(issued.PEM)
+	if err != nil {
+		t.Fatalf("parse issued: %v", err)
+	}
+	if cert.Subject.CommonName != "host-1" {
+		t.Errorf("CN = %q, want host-1", cert.Subject.CommonName)
+	}
+	if SerialHex(cert.SerialNumber) != issued.Serial {
+		t.Errorf("serial %s, reported %s", SerialHex(cert.SerialNumber), issued.Serial)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     ca.Pool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("issued certificate does not verify against the CA: %v", err)
+	}
+}
+
+func TestIssueClientCert_RejectsBadRequests(t *testing.T) {
+	ca := newTestCA(t)
+	if _, err := ca.IssueClientCert([]byte("not a csr"), "host-1", time.Hour); err == nil {
+		t.Error("garbage must be refused")
+	}
+	csr := newCSR(t, "host-1")
+	block, _ := pem.Decode(csr)
+	block.Bytes[len(block.Bytes)-1] ^= 0xff // break the signature
+	if _, err := ca.IssueClientCert(pem.EncodeToMemory(block), "host-1", time.Hour); err == nil {
+		t.Error("a CSR with a broken signature must be refused")
+	}
+}
+
+func TestServerTLSConfig_ServesCertForHosts(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := ca.ServerTLSConfig([]string{"supervisor.example.com", "10.0.0.5"})
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatal("the listener must require a client certificate")
+	}
+	c, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("get certificate: %v", err)
+	}
+	for _, host := range []string{"supervisor.example.com", "10.0.0.5"} {
+		if _, err := c.Leaf.Verify(x509.VerifyOptions{Roots: ca.Pool(), DNSName: host}); err != nil {
+			t.Errorf("server certificate invalid for %s: %v", host, err)
+		}
+	}
+	again, _ := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	if again != c {
+		t.Error("a fresh certificate must be reused, not reissued per handshake")
+	}
+}
+
+func TestSignature_RoundTripAndTamper(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil {
+		t.Fatalf("parse public key: %v", err)
+	}
+	body := []byte(`{"metrics":{}}`)
+	msg := SigningString("post", "/api/agent/report", "1700000000", "n1", body)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+
+	// A proxy prefix in front of the path does not matter.
+	if !VerifySignature(parsed, SigningString("POST", "/supervisor/api/agent/report", "1700000000", "n1", body), sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifySignature(parsed, SigningString("POST", "/api/agent/report", "1700000000", "n1", []byte(`{}`)), sig) {
+		t.Error("a changed body must not verify")
+	}
+	if VerifySignature(parsed, SigningString("POST", "/api/agent/report", "1700000001", "n1", body), sig) {
+		t.Error("a changed timestamp must not verify")
+	}
+	if _, err := ParsePublicKey("c2hvcnQ="); err == nil {
+		t.Error("a short key must be refused")
+	}
+}
+
+// TestSigningString pins the documented format (protocol/README.md); the
+// agent's identity test pins the same vector.
+func TestSigningString(t *testing.T) {
+	got := string(SigningString("post", "/supervisor/api/agent/report", "1700000000", "n1", []byte(`{}`)))
+	want := "ss-agent-v1\nPOST\n/api/agent/report\n1700000000\nn1\n44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+	if got != want {
+		t.Fatalf("SigningString = %q, want %q", got, want)
+	}
+}
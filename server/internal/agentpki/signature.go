@@ -0,0 +1,64 @@
+package agentpki
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Headers of a signed agent request. The agent sends them instead of its API
+// key; see protocol/README.md for the exact scheme.
+const (
+	HeaderHost      = "X-Agent-Host"
+	HeaderTimestamp = "X-Agent-Timestamp" // Unix seconds
+	HeaderNonce     = "X-Agent-Nonce"
+	HeaderSignature = "X-Agent-Signature" // base64 Ed25519 signature of SigningString
+)
+
+// signatureVersion prefixes the signed string so the scheme can evolve.
+const signatureVersion = "ss-agent-v1"
+
+// SigningString is what the agent signs for one request. path is taken from
+// "/api/agent/" on, so a reverse proxy mounting the server under a prefix does
+// not break the signature; the body is covered through its SHA-256.
+func SigningString(method, path, timestamp, nonce string, body []byte) []byte {
+	if i := strings.Index(path, "/api/agent/"); i > 0 {
+		path = path[i:]
+	}
+	sum := sha256.Sum256(body)
+	return []byte(strings.Join([]string{
+		signatureVersion,
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(sum[:]),
+	}, "\n"))
+}
+
+// ParsePublicKey decodes a base64 raw Ed25519 public key.
+func ParsePublicKey(b64 string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("public key must be a base64 raw Ed25519 key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// PublicKeyFingerprint is the SHA-256 of the raw key, hex.
+func PublicKeyFingerprint(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySignature checks a base64 signature of SigningString against pub.
+func VerifySignature(pub ed25519.PublicKey, signingString []byte, sigB64 string) bool {
+	sig, err := base64.StdEncoding.DecodeString(sigB64)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	return ed25519.Verify(pub, signingString, sig)
+}
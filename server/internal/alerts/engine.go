@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,7 +30,9 @@ type NotificationPusher interface {
 func CurrentIncidentValue(ctx context.Context, db *database.DB, rule models.AlertRule, hostID string) (float64, bool) {
 	var host models.Host
 	if strings.HasPrefix(hostID, "proxmox:") || strings.HasPrefix(hostID, "synthetic:") ||
-		strings.HasPrefix(hostID, "docker:") {
+		strings.HasPrefix(hostID, "docker:") || strings.HasPrefix(hostID, "web:") ||
+		strings.HasPrefix(hostID, "netpolicy:") || strings.HasPrefix(hostID, "netif:") ||
+		strings.HasPrefix(hostID, "bouncer:") {
 		host = models.Host{ID: hostID, Status: "online", LastSeen: time.Now()}
 	} else {
 		h, err := db.GetHost(ctx, hostID)
@@ -148,7 +152,9 @@ func EvaluateAlerts(ctx context.Context, db *database.DB, cfg *config.Config, di
 
 		for _, host := range hostsForRule {
 			evaluatedTargets[host.ID] = struct{}{}
-			if hasHostID(rule) && !isProxmoxMetric(rule.Metric) && *rule.HostID != host.ID {
+			if hasHostID(rule) && !isProxmoxMetric(rule.Metric) && !models.IsWebDomainMetric(rule.Metric) &&
+				!models.IsNetworkPolicyMetric(rule.Metric) && !models.IsNetInterfaceMetric(rule.Metric) &&
+				!models.IsCrowdSecBouncerMetric(rule.Metric) && *rule.HostID != host.ID {
 				continue
 			}
 
@@ -312,7 +318,7 @@ func correlationTargetIncidentID(ctx context.Context, db *database.DB, rule mode
 
 // correlationHostID resolves an evaluation target ID to the real agent host
 // it should be correlated against. Mirrors triggerAlertCommand's synthetic-ID
-// resolution (docker:container:/docker:compose:/proxmox:guest:) since a
+// resolution (docker:container:/docker:compose:/docker:swarm_*/proxmox:guest:) since a
 // host-down cascade shows up on exactly those target shapes; a bare agent
 // host ID resolves to itself. Proxmox non-guest scopes and synthetic probes
 // have no single owning host and return ok=false, same as triggerAlertCommand.
@@ -328,6 +334,8 @@ func correlationHostID(ctx context.Context, db *database.DB, targetID string) (h
 	case strings.HasPrefix(targetID, "docker:compose:"):
 		composeHostID, _, composeOK := parseDockerComposeScopeID(targetID)
 		return composeHostID, composeOK
+	case strings.HasPrefix(targetID, "docker:swarm_"):
+		return dockerSwarmScopeHostID(targetID)
 	case strings.HasPrefix(targetID, "proxmox:"):
 		parts := strings.SplitN(targetID, ":", 3)
 		if len(parts) != 3 || parts[1] != "guest" || parts[2] == "" {
@@ -338,6 +346,18 @@ func correlationHostID(ctx context.Context, db *database.DB, targetID string) (h
 			return "", false
 		}
 		return link.HostID, true
+	case strings.HasPrefix(targetID, "web:"):
+		webHostID, _, webOK := models.ParseWebDomainTargetID(targetID)
+		return webHostID, webOK
+	case strings.HasPrefix(targetID, "netpolicy:"):
+		policyHostID, _, policyOK := models.ParseNetworkPolicyTargetID(targetID)
+		return policyHostID, policyOK
+	case strings.HasPrefix(targetID, "netif:"):
+		ifaceHostID, _, ifaceOK := models.ParseNetInterfaceTargetID(targetID)
+		return ifaceHostID, ifaceOK
+	case strings.HasPrefix(targetID, "bouncer:"):
+		bouncerHostID, _, bouncerOK := models.ParseCrowdSecBouncerTargetID(targetID)
+		return bouncerHostID, bouncerOK
 	case strings.HasPrefix(targetID, "synthetic:"):
 		return "", false
 	default:
@@ -405,14 +425,17 @@ func isProxmoxMetric(metric string) bool {
 		"proxmox_node_fan_rpm",
 		"proxmox_guest_cpu_percent",
 		"proxmox_guest_memory_percent",
+		"proxmox_snapshot_policy_failures",
 		"proxmox_node_pending_updates",
 		"proxmox_recent_failed_tasks_24h",
 		"proxmox_auth_failures_recent",
 		"proxmox_disk_failed_count",
-		"proxmox_disk_min_wearout_percent":
+		"proxmox_disk_min_wearout_percent",
+		"pbs_backup_age_hours",
+		"pbs_verify_failed":
 		return true
 	default:
-		return false
+		return models.IsProxmoxClusterHealthMetric(metric)
 	}
 }
 
@@ -464,6 +487,10 @@ func proxmoxScopeKey(scope *models.ProxmoxMetricScope) string {
 		if scope.DiskID != "" {
 			return fmt.Sprintf("proxmox:disk:%s", scope.DiskID)
 		}
+	case "backup_group":
+		if scope.BackupGroupID != "" {
+			return fmt.Sprintf("proxmox:backup_group:%s", scope.BackupGroupID)
+		}
 	}
 	return "proxmox:global"
 }
@@ -484,6 +511,8 @@ func proxmoxScopeLabel(scope *models.ProxmoxMetricScope) string {
 		return fmt.Sprintf("Proxmox VM/LXC %s", scope.GuestID)
 	case "disk":
 		return fmt.Sprintf("Proxmox disque %s", scope.DiskID)
+	case "backup_group":
+		return fmt.Sprintf("Sauvegarde PBS %s", scope.BackupGroupID)
 	}
 	return "Proxmox global"
 }
@@ -506,6 +535,18 @@ func buildAlertEvaluationTargets(ctx context.Context, db *database.DB, rule mode
 	if isDockerMetric(rule.Metric) {
 		return buildDockerEvaluationTargets(ctx, db, rule)
 	}
+	if models.IsWebDomainMetric(rule.Metric) {
+		return buildWebDomainTargets(ctx, db, rule, hosts)
+	}
+	if models.IsNetworkPolicyMetric(rule.Metric) {
+		return buildNetworkPolicyTargets(ctx, db, rule, hosts)
+	}
+	if models.IsNetInterfaceMetric(rule.Metric) {
+		return buildNetInterfaceTargets(ctx, db, rule, hosts)
+	}
+	if models.IsCrowdSecBouncerMetric(rule.Metric) {
+		return buildCrowdSecBouncerTargets(ctx, db, rule, hosts)
+	}
 	if !isProxmoxMetric(rule.Metric) {
 		// For agent metrics, filter by HostID if set
 		if hasHostID(rule) {
@@ -539,6 +580,58 @@ func buildAlertEvaluationTargets(ctx context.Context, db *database.DB, rule mode
 	}
 }
 
+// webDomainTargetLookback is how far back a domain must have served
+// requests to stay a target: long enough for a domain whose traffic stopped
+// to be evaluated at zero and resolve its incidents.
+const webDomainTargetLookback = 24 * time.Hour
+
+// buildWebDomainTargets returns one synthetic target per domain the rule's
+// host served recently (see models.WebDomainTargetID).
+func buildWebDomainTargets(ctx context.Context, db *database.DB, rule models.AlertRule, hosts []models.Host) []models.Host {
+	if !hasHostID(rule) {
+		return []models.Host{}
+	}
+	var host *models.Host
+	for i := range hosts {
+		if hosts[i].ID == *rule.HostID {
+			host = &hosts[i]
+			break
+		}
+	}
+	if host == nil {
+		return []models.Host{}
+	}
+	return webDomainTargets(ctx, db, *host)
+}
+
+// BuildWebDomainTestTargets is the exported entry point for the test-run
+// handler: the per-domain targets of one host.
+func BuildWebDomainTestTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	return webDomainTargets(ctx, db, host)
+}
+
+func webDomainTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	domains, err := db.ListWebLogDomains(ctx, host.ID, time.Now().Add(-webDomainTargetLookback))
+	if err != nil {
+		slog.ErrorContext(ctx, "alerts: failed to list web log domains", slog.String("host", host.ID), slog.Any("err", err))
+		return []models.Host{}
+	}
+	targets := make([]models.Host, 0, len(domains))
+	for _, domain := range domains {
+		label := domain
+		if label == "" {
+			label = "(sans domaine)"
+		}
+		targets = append(targets, models.Host{
+			ID:       models.WebDomainTargetID(host.ID, domain),
+			Name:     host.Name + " — " + label,
+			Status:   "online",
+			LastSeen: time.Now(),
+		})
+	}
+	return targets
+}
+
 func isDockerMetric(metric string) bool {
 	return models.IsDockerMetric(metric)
 }
@@ -600,6 +693,51 @@ func buildDockerEvaluationTargets(ctx context.Context, db *database.DB, rule mod
 			Status:   "online",
 			LastSeen: time.Now(),
 		}}
+	case "docker_swarm_service_missing_replicas":
+		names := []string{scope.ServiceName}
+		if scope.ScopeMode == "host" {
+			services, err := db.ListDockerSwarmServices(ctx, scope.HostID)
+			if err != nil {
+				return nil
+			}
+			names = names[:0]
+			for _, s := range services {
+				names = append(names, s.Name)
+			}
+		}
+		targets := make([]models.Host, 0, len(names))
+		for _, name := range names {
+			targets = append(targets, models.Host{
+				ID:       "docker:swarm_service:" + scope.HostID + ":" + name,
+				Name:     "Service Swarm " + name,
+				Status:   "online",
+				LastSeen: time.Now(),
+			})
+		}
+		return targets
+	case "docker_swarm_node_state":
+		var nodes []models.SwarmNode
+		if scope.ScopeMode == "host" {
+			all, err := db.ListDockerSwarmNodes(ctx, scope.HostID)
+			if err != nil {
+				return nil
+			}
+			nodes = all
+		} else if n, err := db.GetDockerSwarmNode(ctx, scope.HostID, scope.NodeID); err == nil {
+			nodes = []models.SwarmNode{*n}
+		} else {
+			nodes = []models.SwarmNode{{NodeID: scope.NodeID, Hostname: scope.NodeID}}
+		}
+		targets := make([]models.Host, 0, len(nodes))
+		for _, n := range nodes {
+			targets = append(targets, models.Host{
+				ID:       "docker:swarm_node:" + scope.HostID + ":" + n.NodeID,
+				Name:     "Noeud Swarm " + n.Hostname,
+				Status:   "online",
+				LastSeen: time.Now(),
+			})
+		}
+		return targets
 	}
 	return nil
 }
@@ -621,7 +759,7 @@ func buildGlobalProxmoxEntityTargets(ctx context.Context, db *database.DB, rule
 			})
 		}
 		return targets
-	case "proxmox_guest_cpu_percent", "proxmox_guest_memory_percent":
+	case "proxmox_guest_cpu_percent", "proxmox_guest_memory_percent", "proxmox_snapshot_policy_failures":
 		guests, err := db.ListProxmoxGuests(ctx, "", "", "")
 		if err != nil {
 			return nil
@@ -682,6 +820,43 @@ func buildGlobalProxmoxEntityTargets(ctx context.Context, db *database.DB, rule
 			}
 		}
 		return targets
+	case "proxmox_ceph_health", "proxmox_ceph_osds_down", "proxmox_ceph_osds_out",
+		"proxmox_ceph_pgs_not_clean", "proxmox_ceph_pool_max_percent", "proxmox_ceph_mons_out_of_quorum",
+		"proxmox_ha_resources_error", "proxmox_ha_manager_errors", "proxmox_cluster_quorum_lost":
+		// Cluster-level facts: one incident per connection, not per node.
+		clusters, err := db.ListProxmoxClusterHealth(ctx)
+		if err != nil {
+			return nil
+		}
+		targets := make([]models.Host, 0, len(clusters))
+		for _, c := range clusters {
+			targets = append(targets, models.Host{
+				ID:       "proxmox:connection:" + c.ConnectionID,
+				Name:     fmt.Sprintf("Proxmox cluster %s", c.ConnectionName),
+				Status:   "online",
+				LastSeen: time.Now(),
+			})
+		}
+		return targets
+	case "pbs_backup_age_hours", "pbs_verify_failed":
+		groups, err := db.ListPBSBackupGroups(ctx, "", "")
+		if err != nil {
+			return nil
+		}
+		targets := make([]models.Host, 0, len(groups))
+		for _, g := range groups {
+			name := g.BackupType + "/" + g.BackupID
+			if strings.TrimSpace(g.GuestName) != "" {
+				name = g.GuestName + " (" + name + ")"
+			}
+			targets = append(targets, models.Host{
+				ID:       "proxmox:backup_group:" + g.ID,
+				Name:     fmt.Sprintf("Sauvegarde PBS %s", name),
+				Status:   "online",
+				LastSeen: time.Now(),
+			})
+		}
+		return targets
 	default:
 		return nil
 	}
@@ -703,6 +878,9 @@ func proxmoxScopedRuleForSyntheticTarget(rule models.AlertRule, targetID string)
 	entityID := parts[2]
 
 	switch entityType {
+	case "connection":
+		scope.ScopeMode = "connection"
+		scope.ConnectionID = entityID
 	case "node":
 		scope.ScopeMode = "node"
 		scope.NodeID = entityID
@@ -715,6 +893,9 @@ func proxmoxScopedRuleForSyntheticTarget(rule models.AlertRule, targetID string)
 	case "disk":
 		scope.ScopeMode = "disk"
 		scope.DiskID = entityID
+	case "backup_group":
+		scope.ScopeMode = "backup_group"
+		scope.BackupGroupID = entityID
 	default:
 		return rule, false
 	}
@@ -745,3 +926,157 @@ func resolveStaleGlobalProxmoxIncidents(ctx context.Context, db *database.DB, ch
 		chDispatch.Send(ctx, resolvedEvent(rule, models.Host{ID: inc.HostID, Name: inc.HostID}, inc))
 	}
 }
+
+// networkPolicyTargetLookback is how far back a violation must have been
+// seen to stay a target: long enough for it to be evaluated at zero once the
+// flow stops, and its incident resolved.
+const networkPolicyTargetLookback = 24 * time.Hour
+
+// maxNetworkPolicyTargets bounds the targets of one host, so a too-broad
+// allow policy cannot turn every flow into an incident at once.
+const maxNetworkPolicyTargets = 200
+
+// buildNetworkPolicyTargets returns one synthetic target per network flow
+// violation recently seen on the rule's host, or on every host when the
+// rule has none (see models.NetworkPolicyTargetID).
+func buildNetworkPolicyTargets(ctx context.Context, db *database.DB, rule models.AlertRule, hosts []models.Host) []models.Host {
+	targets := []models.Host{}
+	for _, host := range hosts {
+		if hasHostID(rule) && host.ID != *rule.HostID {
+			continue
+		}
+		targets = append(targets, networkPolicyTargets(ctx, db, host)...)
+	}
+	return targets
+}
+
+// BuildNetworkPolicyTestTargets is the exported entry point for the test-run
+// handler: the violation targets of one host.
+func BuildNetworkPolicyTestTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	return networkPolicyTargets(ctx, db, host)
+}
+
+func networkPolicyTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	violations, err := db.ListNetworkFlowViolations(ctx, host.ID, "", time.Now().Add(-networkPolicyTargetLookback), maxNetworkPolicyTargets)
+	if err != nil {
+		slog.ErrorContext(ctx, "alerts: failed to list network flow violations", slog.String("host", host.ID), slog.Any("err", err))
+		return []models.Host{}
+	}
+	targets := make([]models.Host, 0, len(violations))
+	for _, v := range violations {
+		targets = append(targets, models.Host{
+			ID:       models.NetworkPolicyTargetID(host.ID, v.ID),
+			Name:     host.Name + " — " + networkViolationLabel(v),
+			Status:   "online",
+			LastSeen: time.Now(),
+		})
+	}
+	return targets
+}
+
+// networkViolationLabel names the offending flow in the incident:
+// "conteneur: app → 203.0.113.9:25/tcp (mail.example.com) [No SMTP]".
+func networkViolationLabel(v models.NetworkFlowViolation) string {
+	who := v.ProcessName
+	if who == "" {
+		who = "processus inconnu"
+	}
+	peer := v.RemoteIP
+	if v.RemotePort > 0 {
+		peer = net.JoinHostPort(v.RemoteIP, strconv.Itoa(v.RemotePort))
+	}
+	arrow := "→"
+	if v.Direction == "inbound" {
+		arrow = "←"
+	}
+	label := fmt.Sprintf("%s %s %s/%s", who, arrow, peer, v.Protocol)
+	if v.ServerName != "" {
+		label += " (" + v.ServerName + ")"
+	}
+	return label + " [" + v.PolicyName + "]"
+}
+
+// netInterfaceTargetLookback is how far back an interface must have been up
+// to stay a target: a port that was never cabled never raises
+// net_interface_down, and an interface that went away is still evaluated
+// long enough for its incident to resolve.
+const netInterfaceTargetLookback = 24 * time.Hour
+
+// buildNetInterfaceTargets returns one synthetic target per network interface
+// recently up on the rule's host, or on every host when the rule has none
+// (see models.NetInterfaceTargetID).
+func buildNetInterfaceTargets(ctx context.Context, db *database.DB, rule models.AlertRule, hosts []models.Host) []models.Host {
+	targets := []models.Host{}
+	for _, host := range hosts {
+		if hasHostID(rule) && host.ID != *rule.HostID {
+			continue
+		}
+		targets = append(targets, netInterfaceTargets(ctx, db, host)...)
+	}
+	return targets
+}
+
+// BuildNetInterfaceTestTargets is the exported entry point for the test-run
+// handler: the interface targets of one host.
+func BuildNetInterfaceTestTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	return netInterfaceTargets(ctx, db, host)
+}
+
+func netInterfaceTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	names, err := db.ListNetInterfaceAlertTargets(ctx, host.ID, time.Now().Add(-netInterfaceTargetLookback))
+	if err != nil {
+		slog.ErrorContext(ctx, "alerts: failed to list network interfaces", slog.String("host", host.ID), slog.Any("err", err))
+		return []models.Host{}
+	}
+	targets := make([]models.Host, 0, len(names))
+	for _, name := range names {
+		targets = append(targets, models.Host{
+			ID:       models.NetInterfaceTargetID(host.ID, name),
+			Name:     host.Name + " — " + name,
+			Status:   "online",
+			LastSeen: time.Now(),
+		})
+	}
+	return targets
+}
+
+// buildCrowdSecBouncerTargets returns one synthetic target per non-revoked
+// bouncer the rule's host — or every host when the rule has none — last
+// listed (see models.CrowdSecBouncerTargetID).
+func buildCrowdSecBouncerTargets(ctx context.Context, db *database.DB, rule models.AlertRule, hosts []models.Host) []models.Host {
+	targets := []models.Host{}
+	for _, host := range hosts {
+		if hasHostID(rule) && host.ID != *rule.HostID {
+			continue
+		}
+		targets = append(targets, crowdSecBouncerTargets(ctx, db, host)...)
+	}
+	return targets
+}
+
+// BuildCrowdSecBouncerTestTargets is the exported entry point for the
+// test-run handler: the bouncer targets of one host.
+func BuildCrowdSecBouncerTestTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	return crowdSecBouncerTargets(ctx, db, host)
+}
+
+func crowdSecBouncerTargets(ctx context.Context, db *database.DB, host models.Host) []models.Host {
+	bouncers, err := db.ListCrowdSecBouncers(ctx, host.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "alerts: failed to list crowdsec bouncers", slog.String("host", host.ID), slog.Any("err", err))
+		return []models.Host{}
+	}
+	targets := make([]models.Host, 0, len(bouncers))
+	for _, b := range bouncers {
+		if b.Revoked {
+			continue
+		}
+		targets = append(targets, models.Host{
+			ID:       models.CrowdSecBouncerTargetID(host.ID, b.Name),
+			Name:     host.Name + " — " + b.Name,
+			Status:   "online",
+			LastSeen: time.Now(),
+		})
+	}
+	return targets
+}
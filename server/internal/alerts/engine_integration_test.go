@@ -662,3 +662,222 @@ func TestBuildDockerTestTargets_LegacySingleContainerScope(t *testing.T) {
 		t.Fatalf("targets = %+v, want exactly one for docker:container:c-web", targets)
 	}
 }
+
+// TestEvaluateAlerts_WebDomain5xxRate checks that a web_domain_5xx_rate rule
+// on a host opens one incident per failing domain, keyed web:<host>:<domain>,
+// and leaves the healthy domain alone.
+func TestEvaluateAlerts_WebDomain5xxRate(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "alert-host-web"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "proxy", Hostname: "proxy", Status: "online", LastSeen: time.Now(),
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	var reqs []models.WebRequest
+	for i := 0; i < 20; i++ {
+		status := 200
+		if i%2 == 0 {
+			status = 502
+		}
+		reqs = append(reqs,
+			// Distinct bytes so the fingerprint de-duplication keeps every request.
+			models.WebRequest{IP: "203.0.113.1", Method: "GET", Path: "/api", Status: status, Bytes: int64(i), Domain: "shop.test"},
+			models.WebRequest{IP: "203.0.113.2", Method: "GET", Path: "/", Status: 200, Bytes: int64(i), Domain: "blog.test"},
+		)
+	}
+	if err := db.InsertWebLogSnapshot(ctx, hostID, &models.WebLogReport{
+		Source: "nginx", Traffic: &models.TrafficSummary{}, Threats: &models.ThreatSummary{},
+		CollectedAt: time.Now().UTC(), Requests: reqs,
+	}); err != nil {
+		t.Fatalf("insert web log snapshot: %v", err)
+	}
+
+	warn := 10.0
+	rule := &models.AlertRule{
+		SourceType:    "agent",
+		HostID:        &hostID,
+		Metric:        "web_domain_5xx_rate",
+		Operator:      ">",
+		ThresholdWarn: &warn,
+		Enabled:       true,
+		Actions:       models.AlertActions{Channels: []string{"browser"}},
+	}
+	if err := db.CreateAlertRule(ctx, rule); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	alerts.EvaluateAlerts(ctx, db, &config.Config{}, dispatch.New(db), &stubPusher{}, nil)
+
+	inc, err := db.GetOpenAlertIncident(ctx, rule.ID, models.WebDomainTargetID(hostID, "shop.test"))
+	if err != nil || inc == nil {
+		t.Fatalf("expected an incident for shop.test, got %v", err)
+	}
+	if inc.Value != 50 {
+		t.Errorf("incident value = %v, want 50 (%% of 5xx)", inc.Value)
+	}
+	if inc, _ := db.GetOpenAlertIncident(ctx, rule.ID, models.WebDomainTargetID(hostID, "blog.test")); inc != nil {
+		t.Errorf("unexpected incident for the healthy domain: %+v", inc)
+	}
+}
+
+func TestEvaluateAlerts_NetworkPolicyViolation(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "alert-host-netpolicy"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "app", Hostname: "app", Status: "online", LastSeen: time.Now(),
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	p, err := db.CreateNetworkFlowPolicy(ctx, &models.NetworkFlowPolicy{
+		Name: "No SMTP", Enabled: true, Direction: "outbound", Subject: "container",
+		Mode: models.NetworkFlowPolicyDeny, Rules: []models.NetworkFlowPolicyRule{{Port: 25}},
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := db.UpsertNetworkFlowViolations(ctx, []models.NetworkFlowViolation{
+		{PolicyID: p.ID, HostID: hostID, RemoteIP: "192.0.2.25", RemotePort: 25, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app"},
+	}, now); err != nil {
+		t.Fatalf("upsert violation: %v", err)
+	}
+	if err := db.UpsertNetworkFlowViolations(ctx, []models.NetworkFlowViolation{
+		{PolicyID: p.ID, HostID: hostID, RemoteIP: "192.0.2.26", RemotePort: 25, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: old"},
+	}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("upsert violation: %v", err)
+	}
+	violations, err := db.ListNetworkFlowViolations(ctx, hostID, "", now.Add(-2*time.Hour), 10)
+	if err != nil || len(violations) != 2 {
+		t.Fatalf("violations = %+v, %v", violations, err)
+	}
+
+	warn := 1.0
+	rule := &models.AlertRule{
+		SourceType:    "agent",
+		Metric:        "network_policy_violation",
+		Operator:      ">=",
+		ThresholdWarn: &warn,
+		Enabled:       true,
+		Actions:       models.AlertActions{Channels: []string{"browser"}},
+	}
+	if err := db.CreateAlertRule(ctx, rule); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	alerts.EvaluateAlerts(ctx, db, &config.Config{}, dispatch.New(db), &stubPusher{}, nil)
+
+	// Most recently seen first: the current flow fires, the stale one does not.
+	inc, err := db.GetOpenAlertIncident(ctx, rule.ID, models.NetworkPolicyTargetID(hostID, violations[0].ID))
+	if err != nil || inc == nil {
+		t.Fatalf("expected an incident for the current violation, got %v", err)
+	}
+	if inc, _ := db.GetOpenAlertIncident(ctx, rule.ID, models.NetworkPolicyTargetID(hostID, violations[1].ID)); inc != nil {
+		t.Errorf("unexpected incident for a violation no longer seen: %+v", inc)
+	}
+}
+
+func TestEvaluateAlerts_NetInterfaceDown(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "alert-host-netif"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "nas", Hostname: "nas", Status: "online", LastSeen: time.Now(),
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := db.InsertNetInterfaceMetrics(ctx, hostID, now.Add(-10*time.Minute), []models.NetInterface{
+		{Name: "eth0", Kind: "physical", OperState: "up"},
+		{Name: "eth1", Kind: "physical", OperState: "up"},
+		{Name: "eth2", Kind: "physical", OperState: "down"},
+	}); err != nil {
+		t.Fatalf("insert interfaces: %v", err)
+	}
+	if err := db.InsertNetInterfaceMetrics(ctx, hostID, now, []models.NetInterface{
+		{Name: "eth0", Kind: "physical", OperState: "down"},
+		{Name: "eth1", Kind: "physical", OperState: "up"},
+		{Name: "eth2", Kind: "physical", OperState: "down"},
+	}); err != nil {
+		t.Fatalf("insert interfaces: %v", err)
+	}
+
+	warn := 1.0
+	rule := &models.AlertRule{
+		SourceType:    "agent",
+		HostID:        &hostID,
+		Metric:        "net_interface_down",
+		Operator:      ">=",
+		ThresholdWarn: &warn,
+		Enabled:       true,
+		Actions:       models.AlertActions{Channels: []string{"browser"}},
+	}
+	if err := db.CreateAlertRule(ctx, rule); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	alerts.EvaluateAlerts(ctx, db, &config.Config{}, dispatch.New(db), &stubPusher{}, nil)
+
+	inc, err := db.GetOpenAlertIncident(ctx, rule.ID, models.NetInterfaceTargetID(hostID, "eth0"))
+	if err != nil || inc == nil {
+		t.Fatalf("expected an incident for the link that went down, got %v", err)
+	}
+	for _, name := range []string{"eth1", "eth2"} {
+		if inc, _ := db.GetOpenAlertIncident(ctx, rule.ID, models.NetInterfaceTargetID(hostID, name)); inc != nil {
+			t.Errorf("unexpected incident for %s (up, or never cabled): %+v", name, inc)
+		}
+	}
+}
+
+func TestEvaluateAlerts_CrowdSecBouncerLastPull(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "alert-host-crowdsec"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "edge", Hostname: "edge", Status: "online", LastSeen: time.Now(),
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := db.StoreCrowdSecReport(ctx, hostID, now, &models.CrowdSecReport{
+		Bouncers: []models.CrowdSecBouncer{
+			{Name: "firewall", LastPull: now.Add(-time.Hour)},
+			{Name: "nginx", LastPull: now.Add(-10 * time.Second)},
+			{Name: "retired", LastPull: now.Add(-24 * time.Hour), Revoked: true},
+		},
+	}); err != nil {
+		t.Fatalf("store crowdsec report: %v", err)
+	}
+
+	warn := 15.0
+	rule := &models.AlertRule{
+		SourceType:    "agent",
+		HostID:        &hostID,
+		Metric:        "crowdsec_bouncer_last_pull_minutes",
+		Operator:      ">",
+		ThresholdWarn: &warn,
+		Enabled:       true,
+		Actions:       models.AlertActions{Channels: []string{"browser"}},
+	}
+	if err := db.CreateAlertRule(ctx, rule); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+
+	alerts.EvaluateAlerts(ctx, db, &config.Config{}, dispatch.New(db), &stubPusher{}, nil)
+
+	inc, err := db.GetOpenAlertIncident(ctx, rule.ID, models.CrowdSecBouncerTargetID(hostID, "firewall"))
+	if err != nil || inc == nil {
+		t.Fatalf("expected an incident for the bouncer that stopped pulling, got %v", err)
+	}
+	for _, name := range []string{"nginx", "retired"} {
+		if inc, _ := db.GetOpenAlertIncident(ctx, rule.ID, models.CrowdSecBouncerTargetID(hostID, name)); inc != nil {
+			t.Errorf("unexpected incident for %s (pulling, or revoked): %+v", name, inc)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package alerts
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"strings"
 	"time"
 
@@ -128,6 +130,8 @@ func GetMetricValue(ctx context.Context, db *database.DB, host models.Host, rule
 		return resolveProxmoxGuestCPUPercent(ctx, db, rule), true
 	case "proxmox_guest_memory_percent":
 		return resolveProxmoxGuestMemoryPercent(ctx, db, rule), true
+	case "proxmox_snapshot_policy_failures":
+		return resolveProxmoxSnapshotPolicyFailures(ctx, db, rule), true
 	case "proxmox_node_pending_updates":
 		return resolveProxmoxNodePendingUpdates(ctx, db, rule), true
 	case "proxmox_recent_failed_tasks_24h":
@@ -138,6 +142,18 @@ func GetMetricValue(ctx context.Context, db *database.DB, host models.Host, rule
 		return resolveProxmoxDiskFailedCount(ctx, db, rule), true
 	case "proxmox_disk_min_wearout_percent":
 		return resolveProxmoxDiskMinWearoutPercent(ctx, db, rule), true
+	case "proxmox_ceph_health", "proxmox_ceph_osds_down", "proxmox_ceph_osds_out",
+		"proxmox_ceph_pgs_not_clean", "proxmox_ceph_pool_max_percent", "proxmox_ceph_mons_out_of_quorum",
+		"proxmox_ha_resources_error", "proxmox_ha_manager_errors", "proxmox_cluster_quorum_lost":
+		return resolveProxmoxClusterHealth(ctx, db, rule), true
+	case "pbs_backup_age_hours":
+		return db.GetPBSBackupAgeHours(ctx, pbsBackupGroupFromRule(rule)), true
+	case "pbs_verify_failed":
+		n, err := db.CountPBSVerifyFailed(ctx, pbsBackupGroupFromRule(rule))
+		if err != nil {
+			return 0, true
+		}
+		return float64(n), true
 	case "docker_container_state":
 		// host.ID is "docker:container:<db-uuid>".
 		// Returns 0 (running/ok), 1.0 (warn state), 2.0 (crit state).
@@ -177,6 +193,33 @@ func GetMetricValue(ctx context.Context, db *database.DB, host models.Host, rule
 			degraded = 0
 		}
 		return float64(degraded), true
+	case "docker_swarm_service_missing_replicas":
+		// value = desired - running replicas, as seen by the scope's manager.
+		hostID, name, ok := parseDockerSwarmScopeID(host.ID, "docker:swarm_service:")
+		if !ok {
+			return 0, false
+		}
+		svc, err := db.GetDockerSwarmService(ctx, hostID, name)
+		if err != nil {
+			return 0, false
+		}
+		missing := svc.DesiredReplicas - svc.RunningReplicas
+		if missing < 0 {
+			missing = 0
+		}
+		return float64(missing), true
+	case "docker_swarm_node_state":
+		// 0 = ready and active, 1 = ready but drained/paused (no new tasks),
+		// 2 = down, disconnected or unknown.
+		hostID, nodeID, ok := parseDockerSwarmScopeID(host.ID, "docker:swarm_node:")
+		if !ok {
+			return 0, false
+		}
+		n, err := db.GetDockerSwarmNode(ctx, hostID, nodeID)
+		if err != nil {
+			return 0, false
+		}
+		return swarmNodeStateValue(n), true
 	case "restic_backup_age_hours":
 		// Hours since the last *successful* backup — prefers the richer
 		// ServerSupervisor-dispatched history (backup_runs), falls back to the
@@ -200,6 +243,22 @@ func GetMetricValue(ctx context.Context, db *database.DB, host models.Host, rule
 			return float64(*status.RepoSizeBytes), true
 		}
 		return 0, false
+	case "docker_volume_growth_bytes_24h":
+		// Largest growth of any single volume over the last 24h (latest minus
+		// oldest sample). No data until a volume has two samples in the window.
+		growth, _, ok, err := db.GetMaxDockerVolumeGrowth(ctx, host.ID, 24*time.Hour)
+		if err != nil || !ok {
+			return 0, false
+		}
+		return float64(growth), true
+	case "web_domain_5xx_rate", "web_domain_p95_latency_ms":
+		return webDomainMetricValue(ctx, db, host.ID, rule)
+	case "network_policy_violation":
+		return networkPolicyMetricValue(ctx, db, host.ID, rule)
+	case "net_interface_down", "net_interface_error_rate", "net_interface_utilization":
+		return netInterfaceMetricValue(ctx, db, host.ID, rule)
+	case "crowdsec_bouncer_last_pull_minutes":
+		return crowdSecBouncerMetricValue(ctx, db, host.ID)
 	case "uptime_down_count":
 		// Global: how many enabled uptime probes are currently DOWN.
 		n, err := db.CountDownProbes(ctx)
@@ -219,6 +278,151 @@ func GetMetricValue(ctx context.Context, db *database.DB, host models.Host, rule
 	return 0, false
 }
 
+const (
+	// webDomainDefaultWindow is the access-log window of the web_domain_*
+	// metrics when the rule sets no duration.
+	webDomainDefaultWindow = 5 * time.Minute
+	// webDomainMinRequests keeps a near-idle domain from firing on a single
+	// 502 or slow request: below it the metric reads 0.
+	webDomainMinRequests = 10
+)
+
+// webDomainMetricValue computes web_domain_5xx_rate (percent of requests) or
+// web_domain_p95_latency_ms for a web:<host>:<domain> target over the last
+// DurationSeconds of access logs. A domain with too little (timed) traffic
+// reads 0, so its incidents resolve once the traffic stops.
+func webDomainMetricValue(ctx context.Context, db *database.DB, targetID string, rule models.AlertRule) (float64, bool) {
+	hostID, domain, ok := models.ParseWebDomainTargetID(targetID)
+	if !ok {
+		return 0, false
+	}
+	window := time.Duration(rule.DurationSeconds) * time.Second
+	if window <= 0 {
+		window = webDomainDefaultWindow
+	}
+	stat, err := db.GetWebDomainPerformance(ctx, hostID, domain, time.Now().Add(-window))
+	if err != nil {
+		return 0, false
+	}
+	if rule.Metric == "web_domain_5xx_rate" {
+		if stat.Requests < webDomainMinRequests {
+			return 0, true
+		}
+		return stat.ErrorRate, true
+	}
+	if stat.TimedRequests < webDomainMinRequests {
+		return 0, true
+	}
+	return stat.P95Ms, true
+}
+
+// networkPolicyDefaultWindow is how recently a violation must have been seen
+// for network_policy_violation to read 1 when the rule sets no duration: a
+// few agent report cycles, so a flow that stopped resolves its incident.
+const networkPolicyDefaultWindow = 10 * time.Minute
+
+// networkPolicyMetricValue reads 1 while the violation of a
+// netpolicy:<host>:<id> target was seen over the last DurationSeconds, 0
+// once it no longer is (or was dropped with its policy).
+func networkPolicyMetricValue(ctx context.Context, db *database.DB, targetID string, rule models.AlertRule) (float64, bool) {
+	_, violationID, ok := models.ParseNetworkPolicyTargetID(targetID)
+	if !ok {
+		return 0, false
+	}
+	window := time.Duration(rule.DurationSeconds) * time.Second
+	if window <= 0 {
+		window = networkPolicyDefaultWindow
+	}
+	v, err := db.GetNetworkFlowViolation(ctx, violationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, true
+	}
+	if err != nil {
+		return 0, false
+	}
+	if time.Since(v.LastSeen) > window {
+		return 0, true
+	}
+	return 1, true
+}
+
+const (
+	// netInterfaceDefaultWindow is the sample window of the net_interface_*
+	// metrics when the rule sets no duration (~10 report cycles at the
+	// default 30s interval).
+	netInterfaceDefaultWindow = 5 * time.Minute
+	// netInterfaceMinPackets keeps a near-idle link from firing on a single
+	// bad frame: below it net_interface_error_rate reads 0.
+	netInterfaceMinPackets = 1000
+)
+
+// netInterfaceMetricValue computes, for a netif:<host>:<interface> target:
+// net_interface_down (1 while the latest sample of the window is down),
+// net_interface_error_rate (rx+tx errors in percent of packets) and
+// net_interface_utilization (busier direction in percent of the link speed)
+// over the last DurationSeconds. No sample in the window — or, for
+// utilization, no clean interval or no known link speed — is "no data".
+func netInterfaceMetricValue(ctx context.Context, db *database.DB, targetID string, rule models.AlertRule) (float64, bool) {
+	hostID, name, ok := models.ParseNetInterfaceTargetID(targetID)
+	if !ok {
+		return 0, false
+	}
+	window := time.Duration(rule.DurationSeconds) * time.Second
+	if window <= 0 {
+		window = netInterfaceDefaultWindow
+	}
+	w, err := db.GetNetInterfaceWindow(ctx, hostID, name, window)
+	if err != nil || w == nil {
+		return 0, false
+	}
+	switch rule.Metric {
+	case "net_interface_down":
+		if models.NetInterfaceDown(w.OperState) {
+			return 1, true
+		}
+		return 0, true
+	case "net_interface_error_rate":
+		if w.Packets < netInterfaceMinPackets {
+			return 0, true
+		}
+		return float64(w.Errors) * 100 / float64(w.Packets), true
+	default:
+		if w.Seconds <= 0 {
+			return 0, false
+		}
+		pct := models.NetInterfaceUtilization(float64(w.RxBytes)/w.Seconds, float64(w.TxBytes)/w.Seconds, w.SpeedMbps)
+		if pct == nil {
+			return 0, false
+		}
+		return *pct, true
+	}
+}
+
+// crowdSecBouncerMetricValue is, for a bouncer:<host>:<name> target, the
+// minutes since the bouncer last pulled decisions (since its registration if
+// it never did). A bouncer no longer listed or revoked, or a list older than
+// models.CrowdSecSnapshotMaxAge (agent or cscli silent: heartbeat and
+// diagnostics cover it), is "no data" rather than an ever-growing age.
+func crowdSecBouncerMetricValue(ctx context.Context, db *database.DB, targetID string) (float64, bool) {
+	hostID, name, ok := models.ParseCrowdSecBouncerTargetID(targetID)
+	if !ok {
+		return 0, false
+	}
+	b, err := db.GetCrowdSecBouncer(ctx, hostID, name)
+	if err != nil || b == nil || b.Revoked {
+		return 0, false
+	}
+	now := time.Now()
+	if now.Sub(b.ListedAt) > models.CrowdSecSnapshotMaxAge {
+		return 0, false
+	}
+	age, ok := models.CrowdSecBouncerPullAge(b.CrowdSecBouncer, now)
+	if !ok {
+		return 0, false
+	}
+	return age.Minutes(), true
+}
+
 // bandwidthCurrentRateWindowSeconds is the short window used as "current
 // rate" for bandwidth_vs_rolling_avg — long enough to smooth over a single
 // noisy sample at the default 30s agent report_interval (~10 samples), short
@@ -281,6 +485,41 @@ func parseDockerComposeScopeID(scopeID string) (hostID, projectName string, ok b
 	return rest[:idx], rest[idx+1:], true
 }
 
+// parseDockerSwarmScopeID splits "docker:swarm_service:<hostID>:<name>" /
+// "docker:swarm_node:<hostID>:<nodeID>". Host IDs never contain ':'.
+func parseDockerSwarmScopeID(scopeID, prefix string) (hostID, name string, ok bool) {
+	if !strings.HasPrefix(scopeID, prefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(scopeID, prefix)
+	idx := strings.Index(rest, ":")
+	if idx < 0 {
+		return "", "", false
+	}
+	return rest[:idx], rest[idx+1:], true
+}
+
+// dockerSwarmScopeHostID returns the manager host embedded in either kind of
+// Swarm scope ID.
+func dockerSwarmScopeHostID(scopeID string) (string, bool) {
+	for _, prefix := range []string{"docker:swarm_service:", "docker:swarm_node:"} {
+		if hostID, _, ok := parseDockerSwarmScopeID(scopeID, prefix); ok {
+			return hostID, true
+		}
+	}
+	return "", false
+}
+
+func swarmNodeStateValue(n *models.SwarmNode) float64 {
+	if n.State != "ready" {
+		return 2
+	}
+	if n.Availability != "active" {
+		return 1
+	}
+	return 0
+}
+
 func resolveProxmoxStoragePercent(ctx context.Context, db *database.DB, rule models.AlertRule) float64 {
 	scope := proxmoxScopeFromRule(rule)
 	if scope == nil || scope.ScopeMode == "" || scope.ScopeMode == "global" {
@@ -462,6 +701,29 @@ func resolveProxmoxGuestMemoryPercent(ctx context.Context, db *database.DB, rule
 	}
 }
 
+// resolveProxmoxSnapshotPolicyFailures counts the snapshot policies whose
+// latest run failed on the scoped guest (every guest when unscoped).
+func resolveProxmoxSnapshotPolicyFailures(ctx context.Context, db *database.DB, rule models.AlertRule) float64 {
+	guestID := ""
+	if scope := proxmoxScopeFromRule(rule); scope != nil && scope.ScopeMode == "guest" {
+		guestID = scope.GuestID
+	}
+	n, err := db.CountProxmoxSnapshotPolicyFailures(ctx, guestID)
+	if err != nil {
+		return 0
+	}
+	return float64(n)
+}
+
+// pbsBackupGroupFromRule returns the scoped PBS backup group, "" for the
+// global scope (every group).
+func pbsBackupGroupFromRule(rule models.AlertRule) string {
+	if scope := proxmoxScopeFromRule(rule); scope != nil && scope.ScopeMode == "backup_group" {
+		return scope.BackupGroupID
+	}
+	return ""
+}
+
 func resolveProxmoxNodePendingUpdates(ctx context.Context, db *database.DB, rule models.AlertRule) float64 {
 	scope := proxmoxScopeFromRule(rule)
 	if scope == nil || scope.ScopeMode == "" || scope.ScopeMode == "global" {
@@ -584,3 +846,22 @@ func resolveProxmoxDiskMinWearoutPercent(ctx context.Context, db *database.DB, r
 		return db.GetProxmoxDiskMinWearoutPercent(ctx)
 	}
 }
+
+// resolveProxmoxClusterHealth evaluates a Ceph / HA / corosync metric at the
+// rule's scope; global is the worst connection.
+func resolveProxmoxClusterHealth(ctx context.Context, db *database.DB, rule models.AlertRule) float64 {
+	var connectionID, nodeID string
+	if scope := proxmoxScopeFromRule(rule); scope != nil {
+		switch scope.ScopeMode {
+		case "connection":
+			connectionID = scope.ConnectionID
+		case "node":
+			nodeID = scope.NodeID
+		}
+	}
+	v, err := db.GetProxmoxClusterHealthValue(ctx, rule.Metric, connectionID, nodeID)
+	if err != nil {
+		return 0
+	}
+	return v
+}
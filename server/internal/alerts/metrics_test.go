@@ -1,6 +1,10 @@
 package alerts
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+)
 
 func TestParseDockerComposeScopeID(t *testing.T) {
 	tests := []struct {
@@ -46,3 +50,42 @@ func TestParseDockerComposeScopeID(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDockerSwarmScopeID(t *testing.T) {
+	hostID, name, ok := parseDockerSwarmScopeID("docker:swarm_service:host-1:site_web", "docker:swarm_service:")
+	if !ok || hostID != "host-1" || name != "site_web" {
+		t.Errorf("service scope = %q %q %v", hostID, name, ok)
+	}
+	if _, _, ok := parseDockerSwarmScopeID("docker:swarm_node:host-1:abc", "docker:swarm_service:"); ok {
+		t.Error("node scope ID must not parse as a service scope ID")
+	}
+	if _, _, ok := parseDockerSwarmScopeID("docker:swarm_node:host-1", "docker:swarm_node:"); ok {
+		t.Error("missing node ID separator must not parse")
+	}
+}
+
+func TestSwarmNodeStateValue(t *testing.T) {
+	tests := []struct {
+		state, availability string
+		want                float64
+	}{
+		{"ready", "active", 0},
+		{"ready", "drain", 1},
+		{"ready", "pause", 1},
+		{"down", "active", 2},
+		{"disconnected", "drain", 2},
+	}
+	for _, tt := range tests {
+		if got := swarmNodeStateValue(&models.SwarmNode{State: tt.state, Availability: tt.availability}); got != tt.want {
+			t.Errorf("%s/%s = %v, want %v", tt.state, tt.availability, got, tt.want)
+		}
+	}
+}
+
+func TestProxmoxScopedRuleForSyntheticTarget_Connection(t *testing.T) {
+	rule := models.AlertRule{Metric: "proxmox_ceph_health"}
+	scoped, ok := proxmoxScopedRuleForSyntheticTarget(rule, "proxmox:connection:c-1")
+	if !ok || scoped.ProxmoxScope == nil || scoped.ProxmoxScope.ScopeMode != "connection" || scoped.ProxmoxScope.ConnectionID != "c-1" {
+		t.Fatalf("got %+v, %v; want connection scope c-1", scoped.ProxmoxScope, ok)
+	}
+}
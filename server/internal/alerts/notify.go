@@ -46,6 +46,8 @@ func buildAlertMessage(rule models.AlertRule, host models.Host, value float64) s
 			metricLabel = "CPU VM/LXC Proxmox"
 		case "proxmox_guest_memory_percent":
 			metricLabel = "RAM VM/LXC Proxmox"
+		case "proxmox_snapshot_policy_failures":
+			metricLabel = "Politiques de snapshot en échec"
 		case "proxmox_node_pending_updates":
 			metricLabel = "Paquets APT en attente"
 		case "proxmox_recent_failed_tasks_24h":
@@ -56,14 +58,43 @@ func buildAlertMessage(rule models.AlertRule, host models.Host, value float64) s
 			metricLabel = "Disques physiques en échec"
 		case "proxmox_disk_min_wearout_percent":
 			metricLabel = "Usure disque min"
+		case "pbs_backup_age_hours":
+			metricLabel = "Âge dernière sauvegarde PBS"
+		case "pbs_verify_failed":
+			metricLabel = "Vérifications PBS en échec"
+		case "proxmox_ceph_health":
+			metricLabel = "Santé Ceph"
+		case "proxmox_ceph_osds_down":
+			metricLabel = "OSD Ceph down"
+		case "proxmox_ceph_osds_out":
+			metricLabel = "OSD Ceph out"
+		case "proxmox_ceph_pgs_not_clean":
+			metricLabel = "PG Ceph non active+clean"
+		case "proxmox_ceph_pool_max_percent":
+			metricLabel = "Pool Ceph le plus rempli"
+		case "proxmox_ceph_mons_out_of_quorum":
+			metricLabel = "Moniteurs Ceph hors quorum"
+		case "proxmox_ha_resources_error":
+			metricLabel = "Ressources HA en erreur"
+		case "proxmox_ha_manager_errors":
+			metricLabel = "Gestionnaires HA en défaut"
+		case "proxmox_cluster_quorum_lost":
+			metricLabel = "Quorum corosync perdu"
 		}
 		switch rule.Metric {
-		case "proxmox_node_pending_updates", "proxmox_recent_failed_tasks_24h", "proxmox_auth_failures_recent", "proxmox_disk_failed_count":
+		case "proxmox_node_pending_updates", "proxmox_recent_failed_tasks_24h", "proxmox_auth_failures_recent", "proxmox_disk_failed_count",
+			"proxmox_snapshot_policy_failures", "pbs_verify_failed",
+			"proxmox_ceph_osds_down", "proxmox_ceph_osds_out", "proxmox_ceph_pgs_not_clean", "proxmox_ceph_mons_out_of_quorum",
+			"proxmox_ha_resources_error", "proxmox_ha_manager_errors", "proxmox_cluster_quorum_lost":
 			return fmt.Sprintf("Alerte %s %s %.0f sur %s", metricLabel, rule.Operator, value, host.Name)
+		case "proxmox_ceph_health":
+			return fmt.Sprintf("Alerte %s %s sur %s", metricLabel, cephHealthLabel(value), host.Name)
 		case "proxmox_node_cpu_temperature":
 			return fmt.Sprintf("Alerte %s %s %.1f°C sur %s", metricLabel, rule.Operator, value, host.Name)
 		case "proxmox_node_fan_rpm":
 			return fmt.Sprintf("Alerte %s %s %.0f RPM sur %s", metricLabel, rule.Operator, value, host.Name)
+		case "pbs_backup_age_hours":
+			return fmt.Sprintf("Alerte %s %s %.0fh sur %s", metricLabel, rule.Operator, value, host.Name)
 		default:
 			return fmt.Sprintf("Alerte %s %s %.1f%% sur %s", metricLabel, rule.Operator, value, host.Name)
 		}
@@ -335,6 +366,15 @@ func triggerAlertCommand(ctx context.Context, dispatcher *dispatch.Dispatcher, d
 		if ctTarget == "" {
 			ctTarget = projectName
 		}
+	} else if strings.HasPrefix(host.ID, "docker:swarm_") {
+		// Swarm alerts: dispatch to the manager host the rule is scoped on.
+		// There is no Swarm agent module, so the target is left as configured.
+		hostID, ok := dockerSwarmScopeHostID(host.ID)
+		if !ok {
+			slog.WarnContext(ctx, "alerts: command_trigger skipped — malformed docker:swarm host ID", slog.Int64("rule_id", rule.ID), slog.String("host_id", host.ID))
+			return nil
+		}
+		targetHostID = hostID
 	}
 
 	if strings.HasPrefix(host.ID, "proxmox:") {
@@ -387,3 +427,15 @@ func triggerAlertCommand(ctx context.Context, dispatcher *dispatch.Dispatcher, d
 	}
 	return &result.Command.ID
 }
+
+// cephHealthLabel renders the proxmox_ceph_health value (0/1/2) as Ceph does.
+func cephHealthLabel(value float64) string {
+	switch {
+	case value >= 2:
+		return "HEALTH_ERR"
+	case value >= 1:
+		return "HEALTH_WARN"
+	default:
+		return "HEALTH_OK"
+	}
+}
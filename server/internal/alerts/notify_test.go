@@ -108,3 +108,10 @@ func TestResolvedEvent(t *testing.T) {
 		t.Errorf("resolvedEvent Push.Status = %q, want %q", ev.Push.Status, "resolved")
 	}
 }
+
+func TestBuildAlertMessage_CephHealth(t *testing.T) {
+	msg := buildAlertMessage(models.AlertRule{Metric: "proxmox_ceph_health", Operator: ">="}, models.Host{Name: "Proxmox cluster lab"}, 1)
+	if msg != "Alerte Santé Ceph HEALTH_WARN sur Proxmox cluster lab" {
+		t.Errorf("got %q", msg)
+	}
+}
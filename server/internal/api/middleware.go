@@ -1,7 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
@@ -13,12 +16,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/serversupervisor/server/internal/agentpki"
+	"github.com/serversupervisor/server/internal/apperr"
 	"github.com/serversupervisor/server/internal/config"
 	"github.com/serversupervisor/server/internal/cookies"
 	"github.com/serversupervisor/server/internal/database"
 	errs "github.com/serversupervisor/server/internal/errors"
 	"github.com/serversupervisor/server/internal/logging"
 	"github.com/serversupervisor/server/internal/safego"
+	"github.com/serversupervisor/server/internal/services/agentauth"
 	"golang.org/x/time/rate"
 )
 
@@ -479,25 +485,108 @@ func HostPermissionMiddleware(db *database.DB, requiredLevel string) gin.Handler
 	}
 }
 
-// APIKeyMiddleware validates agent API keys.
-func APIKeyMiddleware(db *database.DB, cfg *config.Config) gin.HandlerFunc {
+// maxSignedBodySize bounds the body read by AgentAuthMiddleware to check a
+// request signature — above the largest agent payload (5 MB reports).
+const maxSignedBodySize = 8 * 1024 * 1024
+
+// AgentAuthMiddleware authenticates agents, by the first credential present:
+//   - a client certificate verified by the mTLS listener's handshake,
+//   - an Ed25519 signature of the request (agentpki headers),
+//   - the API key, refused once the host enrolled one of the two above.
+//
+// It sets host_id, host and agent_auth (the agentauth.Method* used).
+func AgentAuthMiddleware(db *database.DB, cfg *config.Config, ids *agentauth.Service) gin.HandlerFunc {
+	abort := func(c *gin.Context, status int, msg string) {
+		c.JSON(status, gin.H{"error": msg})
+		c.Abort()
+	}
 	return func(c *gin.Context) {
-		apiKey := c.GetHeader(cfg.APIKeyHeader)
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing API key"})
-			c.Abort()
+		ctx := c.Request.Context()
+		var hostID, method string
+
+		switch {
+		case c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0:
+			id, err := ids.AuthenticateCertificate(ctx, c.Request.TLS.VerifiedChains[0][0])
+			if err != nil {
+				respondAgentAuthError(c, err)
+				return
+			}
+			hostID, method = id, agentauth.MethodCertificate
+
+		case c.GetHeader(agentpki.HeaderSignature) != "":
+			body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodySize))
+			if err != nil {
+				abort(c, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			hostID = c.GetHeader(agentpki.HeaderHost)
+			if err := ids.AuthenticateSignature(ctx, hostID, c.Request.Method, c.Request.URL.Path,
+				c.GetHeader(agentpki.HeaderTimestamp), c.GetHeader(agentpki.HeaderNonce),
+				c.GetHeader(agentpki.HeaderSignature), body); err != nil {
+				respondAgentAuthError(c, err)
+				return
+			}
+			method = agentauth.MethodSignature
+
+		default:
+			apiKey := c.GetHeader(cfg.APIKeyHeader)
+			if apiKey == "" {
+				abort(c, http.StatusUnauthorized, "missing API key")
+				return
+			}
+			host, err := db.GetHostByAPIKey(ctx, apiKey)
+			if err != nil {
+				abort(c, http.StatusUnauthorized, "invalid API key")
+				return
+			}
+			strong, err := ids.RequiresStrongAuth(ctx, host.ID)
+			if err != nil {
+				abort(c, http.StatusInternalServerError, "failed to check agent credentials")
+				return
+			}
+			if strong {
+				abort(c, http.StatusUnauthorized, "this host enrolled a client certificate or signing key: the API key alone is refused")
+				return
+			}
+			c.Set("host_id", host.ID)
+			c.Set("host", host)
+			c.Set("agent_auth", agentauth.MethodAPIKey)
+			c.Next()
 			return
 		}
 
-		host, err := db.GetHostByAPIKey(c.Request.Context(), apiKey)
+		host, err := db.GetHost(ctx, hostID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
-			c.Abort()
+			abort(c, http.StatusUnauthorized, "unknown host")
 			return
 		}
-
 		c.Set("host_id", host.ID)
 		c.Set("host", host)
+		c.Set("agent_auth", method)
 		c.Next()
 	}
 }
+
+// respondAgentAuthError answers a failed certificate or signature check.
+func respondAgentAuthError(c *gin.Context, err error) {
+	status, msg := http.StatusUnauthorized, err.Error()
+	var ae *apperr.Error
+	if errors.As(err, &ae) {
+		status, msg = ae.HTTPStatus, ae.Message
+	}
+	c.JSON(status, gin.H{"error": msg})
+	c.Abort()
+}
+
+// AgentListenerHandler restricts the mTLS listener to the agent API: the
+// browser UI and its API stay on the main port.
+func AgentListenerHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/agent/") {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/serversupervisor/server/internal/alerts"
+	"github.com/serversupervisor/server/internal/cluster"
 	"github.com/serversupervisor/server/internal/config"
 	"github.com/serversupervisor/server/internal/cookies"
 	"github.com/serversupervisor/server/internal/database"
@@ -16,17 +17,22 @@ import (
 	"github.com/serversupervisor/server/internal/networkview"
 	"github.com/serversupervisor/server/internal/safego"
 	"github.com/serversupervisor/server/internal/scheduler"
+	agentauthsvc "github.com/serversupervisor/server/internal/services/agentauth"
+	agentjoinsvc "github.com/serversupervisor/server/internal/services/agentjoin"
 	alertrulesvc "github.com/serversupervisor/server/internal/services/alertrule"
 	aptsvc "github.com/serversupervisor/server/internal/services/apt"
 	auditsvc "github.com/serversupervisor/server/internal/services/audit"
 	authnsvc "github.com/serversupervisor/server/internal/services/authn"
 	backupsvc "github.com/serversupervisor/server/internal/services/backup"
+	crowdsecsvc "github.com/serversupervisor/server/internal/services/crowdsec"
 	dashboardsvc "github.com/serversupervisor/server/internal/services/dashboard"
 	discoverysvc "github.com/serversupervisor/server/internal/services/discovery"
 	dockersvc "github.com/serversupervisor/server/internal/services/docker"
+	flowpolicysvc "github.com/serversupervisor/server/internal/services/flowpolicy"
 	gitwebhooksvc "github.com/serversupervisor/server/internal/services/gitwebhook"
 	hostsvc "github.com/serversupervisor/server/internal/services/host"
 	hostpermsvc "github.com/serversupervisor/server/internal/services/hostperm"
+	ipblocksvc "github.com/serversupervisor/server/internal/services/ipblock"
 	maintenancesvc "github.com/serversupervisor/server/internal/services/maintenance"
 	networksvc "github.com/serversupervisor/server/internal/services/network"
 	notifssvc "github.com/serversupervisor/server/internal/services/notifications"
@@ -38,6 +44,7 @@ import (
 	scheduledtasksvc "github.com/serversupervisor/server/internal/services/scheduledtask"
 	settingssvc "github.com/serversupervisor/server/internal/services/settings"
 	sslsvc "github.com/serversupervisor/server/internal/services/ssl"
+	threatrulessvc "github.com/serversupervisor/server/internal/services/threatrules"
 	uptimesvc "github.com/serversupervisor/server/internal/services/uptime"
 	usersvc "github.com/serversupervisor/server/internal/services/user"
 	weblogssvc "github.com/serversupervisor/server/internal/services/weblogs"
@@ -47,7 +54,7 @@ import (
 // SetupRouter wires all handlers and registers route groups.
 // The caller is responsible for starting long-running poller services after this function returns.
 // The returned cleanup func must be called on shutdown to stop background goroutines (rate limiters).
-func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationHub, bus *events.Bus, sched *scheduler.TaskScheduler, dispatcher *dispatch.Dispatcher) (*gin.Engine, *handlers.ReleaseTrackerHandler, *handlers.ProxmoxHandler, *handlers.NPMHandler, func()) {
+func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationHub, bus *events.Bus, sched *scheduler.TaskScheduler, dispatcher *dispatch.Dispatcher, relay *cluster.Relay, agentAuth *agentauthsvc.Service, threatRules *threatrulessvc.Service) (*gin.Engine, *handlers.ReleaseTrackerHandler, *handlers.ProxmoxHandler, *handlers.NPMHandler, func()) {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
@@ -64,14 +71,23 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 
 	// Instantiate handlers
 	authH := handlers.NewAuthHandler(authnsvc.NewService(db, cfg), cfg)
-	hostH := handlers.NewHostHandler(hostsvc.NewService(db, dispatcher, func() string {
+	hostService := hostsvc.NewService(db, dispatcher, func() string {
 		return handlers.ResolveLatestAgentVersion(cfg)
-	}, bus))
+	}, bus)
+	hostH := handlers.NewHostHandler(hostService)
 	wsH := ws.NewWSHandler(db, cfg, notifHub, bus, func() string {
 		return handlers.ResolveLatestAgentVersion(cfg)
 	})
+	// Agent nudges, command output and browser notifications reach the other
+	// replicas too (nil relay on a single server: no-op).
+	relay.AttachWS(wsH)
 	dispatcher.SetAgentPusher(wsH.GetAgentHub())
 	agentH := handlers.NewAgentHandler(db, cfg, wsH.GetStreamHub(), notifHub, bus)
+	wsH.SetAgentCommands(agentH.Commands())
+	// A revoked agent credential also ends the WebSocket it opened.
+	agentAuth.SetDisconnect(wsH.GetAgentHub().Disconnect)
+	agentIdentityH := handlers.NewAgentIdentityHandler(agentAuth)
+	agentJoinH := handlers.NewAgentJoinHandler(agentjoinsvc.NewService(db, hostService))
 	aptH := handlers.NewAptHandler(aptsvc.NewService(db, dispatcher), db)
 	dockerH := handlers.NewDockerHandler(dockersvc.NewService(db, dispatcher), db)
 	systemH := handlers.NewSystemHandler(db, cfg, dispatcher, wsH.GetStreamHub())
@@ -95,6 +111,18 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 		BuildDockerTargets: func(ctx context.Context, rule models.AlertRule) []models.Host {
 			return alerts.BuildDockerTestTargets(ctx, db, rule)
 		},
+		BuildWebDomainTargets: func(ctx context.Context, host models.Host) []models.Host {
+			return alerts.BuildWebDomainTestTargets(ctx, db, host)
+		},
+		BuildNetworkPolicyTargets: func(ctx context.Context, host models.Host) []models.Host {
+			return alerts.BuildNetworkPolicyTestTargets(ctx, db, host)
+		},
+		BuildNetInterfaceTargets: func(ctx context.Context, host models.Host) []models.Host {
+			return alerts.BuildNetInterfaceTestTargets(ctx, db, host)
+		},
+		BuildCrowdSecBouncerTargets: func(ctx context.Context, host models.Host) []models.Host {
+			return alerts.BuildCrowdSecBouncerTestTargets(ctx, db, host)
+		},
 		FetchProxmoxLogs: func(ctx context.Context, rule models.AlertRule) ([]string, time.Time) {
 			return alerts.FetchProxmoxAuthFailureLogs(ctx, db, rule)
 		},
@@ -110,7 +138,8 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 	maintenanceH := handlers.NewMaintenanceWindowHandler(maintenancesvc.NewService(db), db)
 	gitWebhookH := handlers.NewGitWebhookHandler(gitwebhooksvc.NewService(db, cfg, dispatcher, notifHub, pushSvc))
 	releaseTrackerH := handlers.NewReleaseTrackerHandler(releasetrackersvc.NewService(db, cfg, dispatcher, notifHub, pushSvc))
-	runbookH := handlers.NewRunbooksHandler(runbooksvc.NewService(db, dispatcher))
+	runbookService := runbooksvc.NewService(db, dispatcher)
+	runbookH := handlers.NewRunbooksHandler(runbookService)
 	backupH := handlers.NewBackupHandler(backupsvc.NewService(db, dispatcher, cfg, notifHub, pushSvc), db)
 	agentH.AddCompletionListener(gitWebhookH)
 	agentH.AddCompletionListener(releaseTrackerH)
@@ -118,11 +147,19 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 	agentH.AddCompletionListener(backupH)
 
 	proxmoxService := proxmoxsvc.NewService(db, cfg, bus)
+	runbookService.SetSnapshotter(proxmoxService)
+	proxmoxService.SetHostRegistrar(hostService)
 	proxmoxH := handlers.NewProxmoxHandler(proxmoxService)
+	wsH.SetProxmoxConsole(proxmoxService)
 	hostPermH := handlers.NewHostPermissionHandler(hostpermsvc.NewService(db))
 	uptimeH := handlers.NewUptimeHandler(uptimesvc.NewService(db))
 	sslH := handlers.NewSSLHandler(sslsvc.NewService(db))
-	webLogsH := handlers.NewWebLogsHandler(weblogssvc.NewService(db, dispatcher, cfg))
+	webLogsService := weblogssvc.NewService(db, dispatcher, cfg)
+	webLogsH := handlers.NewWebLogsHandler(webLogsService)
+	ipBlockH := handlers.NewIPBlockHandler(ipblocksvc.NewService(db, dispatcher, webLogsService, ipblocksvc.ChannelNotifier(cfg, notifHub, pushSvc)))
+	crowdSecH := handlers.NewCrowdSecHandler(crowdsecsvc.NewService(db, dispatcher))
+	threatRulesH := handlers.NewThreatRulesHandler(threatRules)
+	networkPolicyH := handlers.NewNetworkPolicyHandler(flowpolicysvc.NewService(db))
 	npmService := npmsvc.NewService(db)
 	npmH := handlers.NewNPMHandler(npmService)
 	dashboardH := handlers.NewDashboardHandler(dashboardsvc.NewService(db))
@@ -131,17 +168,25 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 	networkSvc.SetIPInventoryBuilder(func(ctx context.Context) (*models.NetworkIPInventory, error) {
 		return networkview.BuildIPInventory(ctx, db, proxmoxService, npmService)
 	})
+	networkSvc.SetDependencyBuilder(func(ctx context.Context, since, until time.Time) (*models.ServiceDependencyGraph, error) {
+		return networkview.BuildDependencies(ctx, db, proxmoxService, since, until)
+	})
 
 	registerPublicRoutes(r, authH, db)
 	registerWSRoutes(r, wsH, cfg)
-	registerAgentRoutes(r, db, cfg, agentH, wsH, agentRateLimiter)
+	registerAgentRoutes(r, db, cfg, agentH, agentIdentityH, wsH, agentAuth, agentRateLimiter)
 
 	v1 := r.Group("/api/v1")
 	v1.Use(JWTMiddleware(cfg))
 	v1.Use(cookies.CSRFMiddleware())
 	registerAuthRoutes(v1, authH)
 	registerWebLogsRoutes(v1, webLogsH)
-	registerHostRoutes(v1, hostH, agentH, discoveryH, db)
+	registerThreatRuleRoutes(v1, threatRulesH)
+	registerIPBlockRoutes(v1, ipBlockH)
+	registerCrowdSecRoutes(v1, crowdSecH)
+	registerNetworkPolicyRoutes(v1, networkPolicyH)
+	registerHostRoutes(v1, hostH, agentH, agentIdentityH, discoveryH, db)
+	registerAgentJoinRoutes(r, v1, agentJoinH, webhookRateLimiter)
 	registerDockerRoutes(v1, dockerH, systemH, networkH, agentH)
 	registerAPTRoutes(v1, aptH)
 	registerAuditRoutes(v1, auditH)
@@ -156,6 +201,7 @@ func SetupRouter(db *database.DB, cfg *config.Config, notifHub *ws.NotificationH
 	registerReleaseTrackerRoutes(v1, releaseTrackerH)
 	registerRunbookRoutes(v1, runbookH)
 	registerProxmoxRoutes(v1, proxmoxH)
+	registerProvisionSeedRoutes(r, proxmoxH, webhookRateLimiter)
 	registerHostPermissionRoutes(v1, hostPermH)
 	registerUptimeRoutes(v1, uptimeH)
 	registerSSLRoutes(v1, sslH)
@@ -206,22 +252,35 @@ func registerWSRoutes(r *gin.Engine, h *ws.WSHandler, cfg *config.Config) {
 	g.GET("/apt", h.Apt)
 	g.GET("/commands/stream/:command_id", h.CommandStream)
 	g.GET("/notifications", h.NotificationStream)
+	g.GET("/proxmox/console/:session", h.ProxmoxConsole)
 }
 
-func registerAgentRoutes(r *gin.Engine, db *database.DB, cfg *config.Config, h *handlers.AgentHandler, wsH *ws.WSHandler, rl *IPRateLimiter) {
+func registerAgentRoutes(r *gin.Engine, db *database.DB, cfg *config.Config, h *handlers.AgentHandler, identityH *handlers.AgentIdentityHandler, wsH *ws.WSHandler, agentAuth *agentauthsvc.Service, rl *IPRateLimiter) {
 	g := r.Group("/api/agent")
 	g.Use(RateLimiterMiddleware(rl))
-	g.Use(APIKeyMiddleware(db, cfg))
+	g.Use(AgentAuthMiddleware(db, cfg, agentAuth))
 	g.POST("/report", h.ReceiveReport)
 	g.POST("/command/result", h.ReportCommandResult)
 	g.POST("/command/stream", h.StreamCommandOutput)
 	g.POST("/apt-status", h.ReceiveAptStatus)
 	g.POST("/restic-status", h.ReceiveResticStatus)
+	g.POST("/backfill", h.ReceiveBackfill)
 	g.POST("/audit", h.LogAuditAction)
+	g.POST("/identity/enroll", identityH.Enroll)
 	// Optional low-latency command push channel — see ws.WSHandler.AgentChannel.
 	g.GET("/ws", wsH.AgentChannel)
 }
 
+// registerAgentJoinRoutes: token management (admin, checked by the handler)
+// and the unauthenticated self-registration endpoint, outside the agent group
+// since the caller has no API key yet — hence the stricter rate limit.
+func registerAgentJoinRoutes(r *gin.Engine, g *gin.RouterGroup, h *handlers.AgentJoinHandler, rl *IPRateLimiter) {
+	g.GET("/agent-join-tokens", h.ListTokens)
+	g.POST("/agent-join-tokens", h.CreateToken)
+	g.DELETE("/agent-join-tokens/:id", h.RevokeToken)
+	r.POST("/api/agent/join", RateLimiterMiddleware(rl), h.Join)
+}
+
 func registerAuthRoutes(g *gin.RouterGroup, h *handlers.AuthHandler) {
 	g.GET("/auth/profile", h.GetProfile)
 	g.POST("/auth/change-password", h.ChangePassword)
@@ -244,13 +303,65 @@ func registerWebLogsRoutes(g *gin.RouterGroup, h *handlers.WebLogsHandler) {
 	g.GET("/security/web-logs", h.GetWebLogsSummary)
 	g.GET("/security/web-logs/timeseries", h.GetWebLogsTimeseries)
 	g.GET("/security/web-logs/live", h.GetWebLogsLive)
+	g.GET("/security/web-logs/geo", h.GetWebLogsGeo)
+	g.GET("/security/web-logs/performance", h.GetWebLogsPerformance)
+	g.GET("/security/geoip", h.GetGeoIPStatus)
 	g.GET("/security/web-logs/ip/:ip", h.GetWebLogsIPTimeline)
 	g.POST("/security/web-logs/ip/:ip/decisions", h.BlockCrowdSecIP)
 	g.DELETE("/security/web-logs/ip/:ip/decisions", h.UnblockCrowdSecIP)
 	g.GET("/security/web-logs/domain/:domain", h.GetWebLogsDomainDetails)
 }
 
-func registerHostRoutes(g *gin.RouterGroup, h *handlers.HostHandler, agentH *handlers.AgentHandler, discoveryH *handlers.DiscoveryHandler, db *database.DB) {
+// registerThreatRuleRoutes is admin-only: the rules classify the web requests
+// of every host.
+func registerThreatRuleRoutes(g *gin.RouterGroup, h *handlers.ThreatRulesHandler) {
+	admin := g.Group("")
+	admin.Use(AdminOnlyMiddleware())
+	admin.GET("/security/threat-rules", h.ListRules)
+	admin.POST("/security/threat-rules", h.CreateRule)
+	admin.POST("/security/threat-rules/test", h.TestRule)
+	admin.POST("/security/threat-rules/import", h.ImportPack)
+	admin.PATCH("/security/threat-rules/:id", h.UpdateRule)
+	admin.DELETE("/security/threat-rules/:id", h.DeleteRule)
+}
+
+// registerIPBlockRoutes is admin-only: a policy bans IPs on every host with
+// CrowdSec.
+func registerIPBlockRoutes(g *gin.RouterGroup, h *handlers.IPBlockHandler) {
+	admin := g.Group("")
+	admin.Use(AdminOnlyMiddleware())
+	admin.GET("/security/block-policies", h.ListPolicies)
+	admin.POST("/security/block-policies", h.CreatePolicy)
+	admin.PATCH("/security/block-policies/:id", h.UpdatePolicy)
+	admin.DELETE("/security/block-policies/:id", h.DeletePolicy)
+	admin.GET("/security/block-decisions", h.ListDecisions)
+	admin.POST("/security/block-decisions/:id/revert", h.RevertDecision)
+}
+
+// registerCrowdSecRoutes is admin-only: the fleet view lists the decisions
+// of every host and bans or unbans on all of them at once.
+func registerCrowdSecRoutes(g *gin.RouterGroup, h *handlers.CrowdSecHandler) {
+	admin := g.Group("")
+	admin.Use(AdminOnlyMiddleware())
+	admin.GET("/security/crowdsec/decisions", h.ListDecisions)
+	admin.POST("/security/crowdsec/decisions", h.BanEverywhere)
+	admin.DELETE("/security/crowdsec/decisions/:ip", h.UnbanEverywhere)
+	admin.GET("/security/crowdsec/bouncers", h.ListBouncers)
+}
+
+// registerNetworkPolicyRoutes is admin-only: policies span hosts and the
+// violations name remote peers of any host.
+func registerNetworkPolicyRoutes(g *gin.RouterGroup, h *handlers.NetworkPolicyHandler) {
+	admin := g.Group("")
+	admin.Use(AdminOnlyMiddleware())
+	admin.GET("/security/network-policies", h.ListPolicies)
+	admin.POST("/security/network-policies", h.CreatePolicy)
+	admin.PATCH("/security/network-policies/:id", h.UpdatePolicy)
+	admin.DELETE("/security/network-policies/:id", h.DeletePolicy)
+	admin.GET("/security/network-policy-violations", h.ListViolations)
+}
+
+func registerHostRoutes(g *gin.RouterGroup, h *handlers.HostHandler, agentH *handlers.AgentHandler, identityH *handlers.AgentIdentityHandler, discoveryH *handlers.DiscoveryHandler, db *database.DB) {
 	g.GET("/hosts", h.ListHosts)
 	g.POST("/hosts", h.RegisterHost)
 	g.POST("/hosts/bulk", h.RegisterHostsBulk)
@@ -271,6 +382,13 @@ func registerHostRoutes(g *gin.RouterGroup, h *handlers.HostHandler, agentH *han
 	hostViewer.GET("/network/flows", h.GetNetworkFlows)
 	hostViewer.GET("/network/flows/history", h.GetNetworkFlowsHistory)
 	hostViewer.GET("/network/flows/summary", h.GetNetworkFlowsSummary)
+	hostViewer.GET("/network/flows/geo", h.GetNetworkFlowsGeo)
+	hostViewer.GET("/network/dns", h.GetNetworkDNS)
+	hostViewer.GET("/network/interfaces", h.GetNetInterfaces)
+	hostViewer.GET("/network/interfaces/history", h.GetNetInterfaceHistory)
+	hostViewer.GET("/docker/disk-usage", h.GetDockerDiskUsage)
+	hostViewer.GET("/docker/disk-usage/history", h.GetDockerDiskUsageHistory)
+	hostViewer.GET("/docker/swarm", h.GetDockerSwarm)
 	hostViewer.GET("/complete", h.GetHostComplete)
 	hostViewer.GET("/exposure", h.GetHostExposure)
 
@@ -280,6 +398,9 @@ func registerHostRoutes(g *gin.RouterGroup, h *handlers.HostHandler, agentH *han
 	hostOperator.PATCH("", h.UpdateHost)
 	hostOperator.DELETE("", h.DeleteHost)
 	hostOperator.POST("/rotate-key", h.RotateAPIKey)
+	hostOperator.GET("/agent/credentials", identityH.ListCredentials)
+	hostOperator.DELETE("/agent/credentials/:credID", identityH.RevokeCredential)
+	hostOperator.POST("/agent/credentials/reset", identityH.ResetCredentials)
 	hostOperator.POST("/agent/update", h.TriggerAgentUpdate)
 }
 
@@ -300,7 +421,9 @@ func registerDockerRoutes(g *gin.RouterGroup, dockerH *handlers.DockerHandler, s
 	g.GET("/hosts/:id/compose-projects", dockerH.ListHostComposeProjects)
 	g.GET("/docker/containers", dockerH.ListAllContainers)
 	g.GET("/docker/compose", dockerH.ListComposeProjects)
+	g.GET("/docker/volumes/growth", dockerH.ListVolumeGrowth)
 	g.POST("/docker/command", dockerH.SendDockerCommand)
+	g.POST("/docker/compose/file", dockerH.SendComposeFileCommand)
 	g.POST("/system/journalctl", systemH.SendJournalCommand)
 	g.POST("/system/service", systemH.SendSystemdCommand)
 	g.POST("/system/processes", systemH.SendProcessesCommand)
@@ -503,6 +626,24 @@ func registerProxmoxRoutes(g *gin.RouterGroup, h *handlers.ProxmoxHandler) {
 	// gated only by the PVE token's own Sys.Modify scope), this can power off
 	// a running VM/CT directly, so it's gated at the app layer too.
 	proxmoxAdmin.POST("/proxmox/guests/:id/action", h.GuestAction)
+	// Console (noVNC / xterm.js through the server): admins, or operators of
+	// the confirmed-linked host — checked by the service, audit-logged.
+	g.POST("/proxmox/guests/:id/console", h.OpenConsole)
+	// Guest snapshots — listing is read-only; create/rollback/delete are
+	// admin-only and require confirm=true in the body (rollback discards
+	// everything written since the snapshot).
+	g.GET("/proxmox/guests/:id/snapshots", h.ListGuestSnapshots)
+	proxmoxAdmin.POST("/proxmox/guests/:id/snapshots", h.CreateGuestSnapshot)
+	proxmoxAdmin.POST("/proxmox/guests/:id/snapshots/:name/rollback", h.RollbackGuestSnapshot)
+	proxmoxAdmin.DELETE("/proxmox/guests/:id/snapshots/:name", h.DeleteGuestSnapshot)
+	// Scheduled snapshot policies (executed by the server)
+	proxmoxAdmin.GET("/proxmox/snapshot-policies", h.ListSnapshotPolicies)
+	proxmoxAdmin.POST("/proxmox/snapshot-policies", h.CreateSnapshotPolicy)
+	proxmoxAdmin.GET("/proxmox/snapshot-policies/:id", h.GetSnapshotPolicy)
+	proxmoxAdmin.PUT("/proxmox/snapshot-policies/:id", h.UpdateSnapshotPolicy)
+	proxmoxAdmin.DELETE("/proxmox/snapshot-policies/:id", h.DeleteSnapshotPolicy)
+	proxmoxAdmin.POST("/proxmox/snapshot-policies/:id/run", h.RunSnapshotPolicy)
+	proxmoxAdmin.GET("/proxmox/snapshot-policies/:id/runs", h.ListSnapshotPolicyRuns)
 	// Guest ↔ host link management
 	g.GET("/proxmox/links", h.ListLinks)
 	g.POST("/proxmox/links", h.CreateLink)
@@ -520,6 +661,13 @@ func registerProxmoxRoutes(g *gin.RouterGroup, h *handlers.ProxmoxHandler) {
 	g.GET("/proxmox/nodes/:id/disks", h.ListNodeDisks)
 	g.GET("/proxmox/backup-jobs", h.ListBackupJobs)
 	g.GET("/proxmox/backup-runs", h.ListBackupRuns)
+	// Ceph / HA manager / corosync health, one entry per connection (:id)
+	g.GET("/proxmox/cluster-health", h.ListClusterHealth)
+	g.GET("/proxmox/cluster-health/:id", h.GetClusterHealth)
+	// Weekly right-sizing recommendations (migrations go through the migrate
+	// route below); an early run is admin only
+	g.GET("/proxmox/rightsizing", h.GetRightsizing)
+	proxmoxAdmin.POST("/proxmox/rightsizing/run", h.RunRightsizing)
 
 	// Node live data (proxied from PVE, not cached in DB)
 	g.GET("/proxmox/nodes/:id/status", h.GetNodeStatus)
@@ -539,6 +687,39 @@ func registerProxmoxRoutes(g *gin.RouterGroup, h *handlers.ProxmoxHandler) {
 	// Node actions (write — require Sys.Modify on the Proxmox token)
 	g.POST("/proxmox/nodes/:id/apt-refresh", h.RefreshNodeApt)
 	g.POST("/proxmox/nodes/:id/guests/:vmid/migrate", h.MigrateGuest)
+
+	// Proxmox Backup Server: stored state readable by everyone, connections admin-only
+	g.GET("/proxmox/pbs/datastores", h.ListPBSDatastores)
+	g.GET("/proxmox/pbs/groups", h.ListPBSBackupGroups)
+	g.GET("/proxmox/pbs/jobs", h.ListPBSJobs)
+	proxmoxAdmin.GET("/proxmox/pbs/instances", h.ListPBSConnections)
+	proxmoxAdmin.POST("/proxmox/pbs/instances", h.CreatePBSConnection)
+	proxmoxAdmin.GET("/proxmox/pbs/instances/:id", h.GetPBSConnection)
+	proxmoxAdmin.PUT("/proxmox/pbs/instances/:id", h.UpdatePBSConnection)
+	proxmoxAdmin.DELETE("/proxmox/pbs/instances/:id", h.DeletePBSConnection)
+	proxmoxAdmin.POST("/proxmox/pbs/instances/test", h.TestPBSConnection)
+	proxmoxAdmin.POST("/proxmox/pbs/instances/:id/test", h.TestPBSConnectionByID)
+	proxmoxAdmin.POST("/proxmox/pbs/instances/:id/poll-now", h.PollPBSNow)
+	// Storage content browser: ISOs, templates, backups, (orphaned) disk images.
+	proxmoxAdmin.GET("/proxmox/storages/:id/content", h.ListStorageContent)
+	proxmoxAdmin.POST("/proxmox/storages/:id/download-url", h.DownloadToStorage)
+	proxmoxAdmin.DELETE("/proxmox/storages/:id/content", h.DeleteStorageVolume)
+	// VM provisioning (clone + cloud-init + agent enrollment) — admin only.
+	proxmoxAdmin.GET("/proxmox/instances/:id/templates", h.ListProvisionTemplates)
+	proxmoxAdmin.GET("/proxmox/provisions", h.ListProvisions)
+	proxmoxAdmin.POST("/proxmox/provisions", h.CreateProvision)
+	proxmoxAdmin.GET("/proxmox/provisions/:id", h.GetProvision)
+	proxmoxAdmin.DELETE("/proxmox/provisions/:id", h.DeleteProvision)
+}
+
+// registerProvisionSeedRoutes exposes the cloud-init NoCloud seed fetched by
+// provisioned VMs at first boot — no JWT (the VM has none), the unguessable
+// path token is the credential; shares the stricter public-webhook limiter.
+func registerProvisionSeedRoutes(r *gin.Engine, h *handlers.ProxmoxHandler, rl *IPRateLimiter) {
+	g := r.Group("/api/provision")
+	g.Use(RateLimiterMiddleware(rl))
+	g.GET("/:token/meta-data", h.ProvisionSeedMetaData)
+	g.GET("/:token/user-data", h.ProvisionSeedUserData)
 }
 
 func registerUptimeRoutes(g *gin.RouterGroup, h *handlers.UptimeHandler) {
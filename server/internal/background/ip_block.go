@@ -0,0 +1,34 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	ipblocksvc "github.com/serversupervisor/server/internal/services/ipblock"
+)
+
+// ipBlockEvalInterval is how often the blocking policies are evaluated. A
+// policy's own window (10 minutes by default) is much wider, so an attacker is
+// caught at most a minute after crossing the threshold.
+const ipBlockEvalInterval = time.Minute
+
+// NewIPBlockPolicyJob evaluates the automatic IP blocking policies against the
+// fleet-wide threat scores and bans (or, in dry run, only reports) the IPs
+// above their threshold.
+func NewIPBlockPolicyJob(svc *ipblocksvc.Service) Job {
+	return Job{
+		Name: "ip-block-policies",
+		Run: func(ctx context.Context) {
+			ticker := time.NewTicker(ipBlockEvalInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					svc.Evaluate(ctx)
+				case <-ctx.Done():
+					return
+				}
+			}
+		},
+	}
+}
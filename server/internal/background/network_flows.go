@@ -9,7 +9,9 @@ import (
 	"github.com/serversupervisor/server/internal/database"
 )
 
-// NewNetworkFlowsRetentionJob purges old network_flow_metrics rows. Driven by
+// NewNetworkFlowsRetentionJob purges old network_flow_metrics and
+// dns_query_metrics rows, and the network flow policy violations not seen
+// over the same window. Driven by
 // an applicative job on a short default (not a fixed TimescaleDB retention
 // policy) because remote_ip is a potentially identifying value — mirrors
 // NewWebLogsRetentionJob's shape exactly.
@@ -31,6 +33,16 @@ func NewNetworkFlowsRetentionJob(db *database.DB, cfg *config.Config) Job {
 					} else if deleted > 0 {
 						slog.InfoContext(ctx, "deleted old network flow metrics", slog.String("job", "network-flows-retention"), slog.Int64("deleted", deleted), slog.Int("retention_days", days))
 					}
+					if deleted, err := db.CleanOldDNSQueryMetrics(ctx, days); err != nil {
+						slog.ErrorContext(ctx, "DNS query metrics retention failed", slog.String("job", "network-flows-retention"), slog.Any("err", err))
+					} else if deleted > 0 {
+						slog.InfoContext(ctx, "deleted old DNS query metrics", slog.String("job", "network-flows-retention"), slog.Int64("deleted", deleted), slog.Int("retention_days", days))
+					}
+					if deleted, err := db.CleanOldNetworkFlowViolations(ctx, days); err != nil {
+						slog.ErrorContext(ctx, "network flow violations retention failed", slog.String("job", "network-flows-retention"), slog.Any("err", err))
+					} else if deleted > 0 {
+						slog.InfoContext(ctx, "deleted old network flow violations", slog.String("job", "network-flows-retention"), slog.Int64("deleted", deleted), slog.Int("retention_days", days))
+					}
 				case <-ctx.Done():
 					return
 				}
@@ -0,0 +1,145 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/serversupervisor/server/internal/events"
+	"github.com/serversupervisor/server/internal/scheduler"
+	"github.com/serversupervisor/server/internal/services/threatrules"
+	"github.com/serversupervisor/server/internal/ws"
+)
+
+// Message kinds carried by the relay.
+const (
+	kindTopics          = "topics"           // []string, coalesced
+	kindAgentNotify     = "agent_notify"     // []string host IDs, coalesced
+	kindAgentConnected  = "agent_connected"  // host ID
+	kindAgentDisconnect = "agent_disconnect" // host ID
+	kindStreamChunk     = "stream_chunk"
+	kindStreamStatus    = "stream_status"
+	kindNotification    = "notification" // raw NotificationHub payload
+	kindScheduler       = "scheduler"
+	kindThreatRules     = "threat_rules"
+)
+
+// resyncTopics are woken locally after a listener reconnect. Per-host views
+// are not listed: their snapshot safety timer catches up on its own.
+var resyncTopics = []string{events.TopicDashboard, events.TopicDocker, events.TopicNetwork, events.TopicApt}
+
+type streamMessage struct {
+	CommandID string `json:"c"`
+	Chunk     string `json:"x,omitempty"`
+	Status    string `json:"s,omitempty"`
+	Output    string `json:"o,omitempty"`
+}
+
+// AttachBus forwards the bus's topics to the other replicas and delivers
+// theirs locally. No-op on a nil relay.
+func (r *Relay) AttachBus(bus *events.Bus) {
+	if r == nil || bus == nil {
+		return
+	}
+	bus.SetForward(func(topic string) { r.PublishCoalesced(kindTopics, topic) })
+	r.Handle(kindTopics, func(data json.RawMessage) {
+		var topics []string
+		if json.Unmarshal(data, &topics) == nil {
+			for _, t := range topics {
+				bus.Deliver(t)
+			}
+		}
+	})
+	r.OnResync(func() {
+		for _, t := range resyncTopics {
+			bus.Deliver(t)
+		}
+	})
+}
+
+// AttachWS wires the agent, command stream and notification hubs of h to the
+// other replicas. No-op on a nil relay.
+func (r *Relay) AttachWS(h *ws.WSHandler) {
+	if r == nil || h == nil {
+		return
+	}
+	agents, streams, notifs := h.GetAgentHub(), h.GetStreamHub(), h.GetNotificationHub()
+	r.Handle(kindAgentNotify, func(data json.RawMessage) {
+		var hostIDs []string
+		if json.Unmarshal(data, &hostIDs) == nil {
+			for _, id := range hostIDs {
+				agents.NotifyLocal(id)
+			}
+		}
+	})
+	r.Handle(kindAgentConnected, func(data json.RawMessage) {
+		var hostID string
+		if json.Unmarshal(data, &hostID) == nil {
+			agents.MarkConnectedElsewhere(hostID)
+		}
+	})
+	r.Handle(kindAgentDisconnect, func(data json.RawMessage) {
+		var hostID string
+		if json.Unmarshal(data, &hostID) == nil {
+			agents.DisconnectLocal(hostID)
+		}
+	})
+	r.Handle(kindStreamChunk, func(data json.RawMessage) {
+		var m streamMessage
+		if json.Unmarshal(data, &m) == nil {
+			streams.BroadcastLocal(m.CommandID, m.Chunk)
+		}
+	})
+	r.Handle(kindStreamStatus, func(data json.RawMessage) {
+		var m streamMessage
+		if json.Unmarshal(data, &m) == nil {
+			streams.BroadcastStatusLocal(m.CommandID, m.Status, m.Output)
+		}
+	})
+	if notifs != nil {
+		r.Handle(kindNotification, func(data json.RawMessage) {
+			notifs.BroadcastLocal(data)
+		})
+	}
+	h.SetPeers(r)
+}
+
+// AttachScheduler replays scheduled-task changes made through another
+// replica. No-op on a nil relay.
+func (r *Relay) AttachScheduler(s *scheduler.TaskScheduler) {
+	if r == nil || s == nil {
+		return
+	}
+	s.SetOnChange(func() { r.Publish(kindScheduler, nil) })
+	r.Handle(kindScheduler, func(json.RawMessage) { s.Reload() })
+	r.OnResync(s.Reload)
+}
+
+// AttachThreatRules recompiles the threat detection rules when another
+// replica changed them. No-op on a nil relay.
+func (r *Relay) AttachThreatRules(s *threatrules.Service) {
+	if r == nil || s == nil {
+		return
+	}
+	reload := func() { _ = s.Reload(context.Background()) }
+	s.SetOnChange(func() { r.Publish(kindThreatRules, nil) })
+	r.Handle(kindThreatRules, func(json.RawMessage) { reload() })
+	r.OnResync(reload)
+}
+
+// ws.Peers, set on the hubs by AttachWS.
+
+func (r *Relay) NotifyAgent(hostID string)     { r.PublishCoalesced(kindAgentNotify, hostID) }
+func (r *Relay) AgentConnected(hostID string)  { r.Publish(kindAgentConnected, hostID) }
+func (r *Relay) DisconnectAgent(hostID string) { r.Publish(kindAgentDisconnect, hostID) }
+
+func (r *Relay) StreamChunk(commandID, chunk string) {
+	r.Publish(kindStreamChunk, streamMessage{CommandID: commandID, Chunk: chunk})
+}
+
+func (r *Relay) StreamStatus(commandID, status, output string) {
+	r.Publish(kindStreamStatus, streamMessage{CommandID: commandID, Status: status, Output: output})
+}
+
+func (r *Relay) BroadcastNotification(payload interface{}) {
+	r.Publish(kindNotification, payload)
+}
@@ -0,0 +1,165 @@
+package cluster
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// LeaderLockKey is the advisory lock the leader holds ("SSLEAD").
+const LeaderLockKey int64 = 0x53534c454144
+
+const (
+	leaderRetryInterval = 5 * time.Second // followers try to take over this often
+	leaderCheckInterval = 5 * time.Second // the leader checks its lock this often
+)
+
+// Lease is a held leadership; Alive fails once it may have been lost.
+type Lease interface {
+	Alive(ctx context.Context) error
+	Release()
+}
+
+// Acquirer tries to take leadership without waiting. A nil Lease with a nil
+// error means another replica holds it.
+type Acquirer func(ctx context.Context) (Lease, error)
+
+// AdvisoryLock elects the leader with a Postgres advisory lock on key.
+func AdvisoryLock(db *database.DB, key int64) Acquirer {
+	return func(ctx context.Context) (Lease, error) {
+		l, err := db.TryAdvisoryLock(ctx, key)
+		if l == nil {
+			return nil, err
+		}
+		return l, nil
+	}
+}
+
+type soloLease struct{}
+
+func (soloLease) Alive(context.Context) error { return nil }
+func (soloLease) Release()                    {}
+
+// AlwaysLeader is the Acquirer of a single server: leadership is immediate
+// and never lost.
+func AlwaysLeader(context.Context) (Lease, error) { return soloLease{}, nil }
+
+// Elector runs work for as long as this replica is the leader. work gets a
+// context cancelled when leadership is lost (or on Stop) and must return
+// promptly once it is; it is started again on the next election won.
+//
+// A leader that loses its database session learns it at the next check, while
+// Postgres frees the lock at once: for up to leaderCheckInterval two replicas
+// may both run the jobs. Most are idempotent passes over database state, for
+// which the overlap only costs duplicated work. The others act outside the
+// database (an alert notification, an agent command) and may repeat: a job
+// whose action must not repeat claims its unit of work in the database before
+// acting, as the IP blocking policies do (ClaimIPBlockPolicyRun).
+type Elector struct {
+	acquire Acquirer
+	work    func(ctx context.Context)
+	name    string
+	leader  atomic.Bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	retry, check time.Duration
+}
+
+// NewElector returns an idle Elector; call Start.
+func NewElector(acquire Acquirer, work func(ctx context.Context)) *Elector {
+	name, _ := os.Hostname()
+	return &Elector{
+		acquire: acquire,
+		work:    work,
+		name:    name,
+		retry:   leaderRetryInterval,
+		check:   leaderCheckInterval,
+	}
+}
+
+// IsLeader reports whether this replica currently runs the leader's work.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Start campaigns in the background until parent is cancelled or Stop.
+func (e *Elector) Start(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	e.cancel = cancel
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.campaign(ctx)
+	}()
+}
+
+// Stop gives leadership up and waits for work to return.
+func (e *Elector) Stop() {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	for {
+		lease, err := e.acquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "cluster: leader election failed", slog.String("replica", e.name), slog.Any("err", err))
+		}
+		if lease != nil {
+			e.lead(ctx, lease)
+		}
+		t := time.NewTimer(e.retry)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (e *Elector) lead(ctx context.Context, lease Lease) {
+	defer lease.Release()
+	slog.InfoContext(ctx, "cluster: this replica is now the leader", slog.String("replica", e.name))
+	e.leader.Store(true)
+	defer e.leader.Store(false)
+
+	workCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer safego.Recover(workCtx, "cluster.leader-work")
+		e.work(workCtx)
+	}()
+
+	t := time.NewTicker(e.check)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-done
+			return
+		case <-done:
+			cancel()
+			slog.WarnContext(ctx, "cluster: leader work returned early, stepping down", slog.String("replica", e.name))
+			return
+		case <-t.C:
+			if err := lease.Alive(ctx); err != nil {
+				cancel()
+				<-done
+				slog.WarnContext(ctx, "cluster: leadership lost", slog.String("replica", e.name), slog.Any("err", err))
+				return
+			}
+		}
+	}
+}
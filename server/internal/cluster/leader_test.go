@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLock is a single lock shared by several electors, like the advisory
+// lock on one database.
+type fakeLock struct {
+	mu     sync.Mutex
+	holder *fakeLease
+}
+
+type fakeLease struct {
+	lock *fakeLock
+	dead atomic.Bool
+}
+
+func (l *fakeLease) Alive(context.Context) error {
+	if l.dead.Load() {
+		return errors.New("session gone")
+	}
+	return nil
+}
+
+func (l *fakeLease) Release() {
+	l.lock.mu.Lock()
+	if l.lock.holder == l {
+		l.lock.holder = nil
+	}
+	l.lock.mu.Unlock()
+}
+
+func (f *fakeLock) acquire(context.Context) (Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holder != nil && !f.holder.dead.Load() {
+		return nil, nil
+	}
+	f.holder = &fakeLease{lock: f}
+	return f.holder, nil
+}
+
+func (f *fakeLock) current() *fakeLease {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.holder
+}
+
+func newTestElector(lock *fakeLock, running *atomic.Int32) *Elector {
+	e := NewElector(lock.acquire, func(ctx context.Context) {
+		running.Add(1)
+		<-ctx.Done()
+		running.Add(-1)
+	})
+	e.retry, e.check = 10*time.Millisecond, 10*time.Millisecond
+	return e
+}
+
+func TestElector_OneLeaderAndFailover(t *testing.T) {
+	lock := &fakeLock{}
+	var running atomic.Int32
+	a, b := newTestElector(lock, &running), newTestElector(lock, &running)
+	a.Start(context.Background())
+	defer a.Stop()
+	waitFor(t, "a to lead", a.IsLeader)
+	b.Start(context.Background())
+	defer b.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if b.IsLeader() || running.Load() != 1 {
+		t.Fatalf("expected a single leader running the work, b leader=%v running=%d", b.IsLeader(), running.Load())
+	}
+
+	// a's database session dies: a steps down, b takes over.
+	lock.current().dead.Store(true)
+	waitFor(t, "b to take over", b.IsLeader)
+	waitFor(t, "a to step down", func() bool { return !a.IsLeader() })
+	waitFor(t, "a single worker", func() bool { return running.Load() == 1 })
+}
+
+func TestElector_StopReleasesAndWaitsForWork(t *testing.T) {
+	lock := &fakeLock{}
+	var running atomic.Int32
+	e := newTestElector(lock, &running)
+	e.Start(context.Background())
+	waitFor(t, "leadership", e.IsLeader)
+
+	e.Stop()
+	if running.Load() != 0 || e.IsLeader() {
+		t.Fatalf("Stop must wait for the work to return, running=%d", running.Load())
+	}
+	if lock.current() != nil {
+		t.Error("Stop must release the lock")
+	}
+}
+
+func TestAlwaysLeader(t *testing.T) {
+	var running atomic.Int32
+	e := NewElector(AlwaysLeader, func(ctx context.Context) {
+		running.Add(1)
+		<-ctx.Done()
+	})
+	e.Start(context.Background())
+	waitFor(t, "leadership", e.IsLeader)
+	e.Stop()
+	if running.Load() != 1 {
+		t.Errorf("work started %d times, want 1", running.Load())
+	}
+}
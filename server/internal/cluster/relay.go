@@ -0,0 +1,329 @@
+// Package cluster lets several server replicas run against one database.
+//
+// Two pieces, both built on Postgres so a second replica needs no extra
+// infrastructure:
+//
+//   - Relay, a cross-replica pub/sub over LISTEN/NOTIFY. The process-local
+//     hubs (events.Bus, the ws hubs, the scheduler) hand it what they
+//     publish; the other replicas replay it on their own hubs. See attach.go.
+//   - Elector, leader election over a session-level advisory lock. Only the
+//     leader runs the background jobs, the pollers and the scheduled tasks.
+//
+// Both are opt-in (HA_ENABLED): a single server keeps a nil *Relay, whose
+// Attach* methods are no-ops, and an Elector that is always the leader.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// Channel is the NOTIFY channel every replica listens on.
+const Channel = "serversupervisor_cluster"
+
+const (
+	// Postgres rejects a NOTIFY payload of 8000 bytes or more; bigger
+	// messages go out in fragments.
+	maxPayload = 7900
+	// Past this a message is dropped rather than flooding the channel
+	// (e.g. a huge command output: peers still get it from the database).
+	maxMessage = 1 << 20
+	// Delay letting a burst of coalesced keys (one agent report touches
+	// several views) leave in a single NOTIFY.
+	coalesceDelay = 50 * time.Millisecond
+	outboxSize    = 512
+	sendTimeout   = 5 * time.Second
+	// A fragmented message not completed within this window is dropped.
+	fragmentTTL = time.Minute
+)
+
+// Sender sends one NOTIFY payload (database.DB.Notify).
+type Sender func(ctx context.Context, channel, payload string) error
+
+type envelope struct {
+	From string          `json:"f"`
+	Kind string          `json:"k"`
+	Data json.RawMessage `json:"d,omitempty"`
+}
+
+type partial struct {
+	parts []string
+	got   int
+	at    time.Time
+}
+
+// Relay is a best-effort broadcast to the other replicas: a message sent while
+// a replica's listener is reconnecting is lost, which is why every hub it
+// carries already has a slower fallback (snapshot safety timer, agent poll,
+// alert history) and attachments register a resync.
+type Relay struct {
+	id     string // random per process: two replicas never share it
+	send   Sender
+	outbox chan string
+	wake   chan struct{}
+	seq    atomic.Uint64
+
+	mu       sync.RWMutex
+	handlers map[string]func(data json.RawMessage)
+	resyncs  []func()
+
+	pendingMu sync.Mutex
+	pending   map[string]map[string]struct{} // kind -> coalesced keys
+
+	partsMu sync.Mutex
+	parts   map[string]*partial // fragment key -> message being reassembled
+}
+
+// NewRelay returns a relay sending through send. Run must be running for
+// anything to leave, and Deliver must be fed with what the channel receives.
+func NewRelay(send Sender) *Relay {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return &Relay{
+		id:       hex.EncodeToString(b[:]),
+		send:     send,
+		outbox:   make(chan string, outboxSize),
+		wake:     make(chan struct{}, 1),
+		handlers: make(map[string]func(json.RawMessage)),
+		pending:  make(map[string]map[string]struct{}),
+		parts:    make(map[string]*partial),
+	}
+}
+
+// StartPostgresRelay returns a relay over db's NOTIFY and a LISTEN connection
+// opened from dsn, both running until ctx is cancelled.
+func StartPostgresRelay(ctx context.Context, db *database.DB, dsn string) *Relay {
+	r := NewRelay(db.Notify)
+	safego.Go(ctx, "cluster.relay", func() { r.Run(ctx) })
+	safego.Go(ctx, "cluster.listen", func() {
+		if err := database.ListenNotifications(ctx, dsn, Channel, r.Deliver, r.resync); err != nil {
+			slog.ErrorContext(ctx, "cluster: cannot listen for peer messages", slog.Any("err", err))
+		}
+	})
+	return r
+}
+
+// Handle registers fn for messages of kind sent by the other replicas. fn runs
+// on the listener goroutine and must be quick.
+func (r *Relay) Handle(kind string, fn func(data json.RawMessage)) {
+	r.mu.Lock()
+	r.handlers[kind] = fn
+	r.mu.Unlock()
+}
+
+// OnResync registers fn, called when the listener reconnects after messages
+// may have been lost.
+func (r *Relay) OnResync(fn func()) {
+	r.mu.Lock()
+	r.resyncs = append(r.resyncs, fn)
+	r.mu.Unlock()
+}
+
+func (r *Relay) resync() {
+	r.mu.RLock()
+	fns := append([]func(){}, r.resyncs...)
+	r.mu.RUnlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// Publish queues v for the other replicas. Never blocks: when the outbox is
+// full (database unreachable) the message is dropped.
+func (r *Relay) Publish(kind string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Error("cluster: cannot encode peer message", slog.String("kind", kind), slog.Any("err", err))
+		return
+	}
+	payload, _ := json.Marshal(envelope{From: r.id, Kind: kind, Data: data})
+	select {
+	case r.outbox <- string(payload):
+	default:
+		slog.Warn("cluster: outbox full, peer message dropped", slog.String("kind", kind))
+	}
+}
+
+// PublishCoalesced queues key for the other replicas, merged with the other
+// keys of kind published within coalesceDelay into one []string message.
+func (r *Relay) PublishCoalesced(kind, key string) {
+	r.pendingMu.Lock()
+	set := r.pending[kind]
+	if set == nil {
+		set = make(map[string]struct{})
+		r.pending[kind] = set
+	}
+	set[key] = struct{}{}
+	r.pendingMu.Unlock()
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued messages until ctx is cancelled. One goroutine sends
+// everything, so the other replicas see messages in publish order.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-r.outbox:
+			r.transmit(ctx, p)
+		case <-r.wake:
+			t := time.NewTimer(coalesceDelay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return
+			}
+			r.flushCoalesced(ctx)
+		}
+	}
+}
+
+func (r *Relay) flushCoalesced(ctx context.Context) {
+	r.pendingMu.Lock()
+	pending := r.pending
+	r.pending = make(map[string]map[string]struct{})
+	r.pendingMu.Unlock()
+
+	for kind, set := range pending {
+		keys := make([]string, 0, len(set))
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		data, _ := json.Marshal(keys)
+		payload, _ := json.Marshal(envelope{From: r.id, Kind: kind, Data: data})
+		r.transmit(ctx, string(payload))
+	}
+}
+
+func (r *Relay) transmit(ctx context.Context, payload string) {
+	if len(payload) > maxMessage {
+		slog.Warn("cluster: peer message too large, dropped", slog.Int("bytes", len(payload)))
+		return
+	}
+	pieces := []string{payload}
+	if len(payload) > maxPayload {
+		pieces = fragment(r.id+"-"+strconv.FormatUint(r.seq.Add(1), 10), payload)
+	}
+	for _, p := range pieces {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := r.send(sendCtx, Channel, p)
+		cancel()
+		if err != nil {
+			slog.Warn("cluster: cannot send peer message", slog.Any("err", err))
+			return
+		}
+	}
+}
+
+// fragment splits payload into "~<index> <count> <key>|<text>" pieces, each
+// under maxPayload and cut on a rune boundary (a NOTIFY payload must be valid
+// text).
+func fragment(key, payload string) []string {
+	budget := maxPayload - len(key) - 24
+	var texts []string
+	for len(payload) > 0 {
+		n := budget
+		if n >= len(payload) {
+			n = len(payload)
+		} else {
+			for n > 0 && !utf8.RuneStart(payload[n]) {
+				n--
+			}
+		}
+		texts = append(texts, payload[:n])
+		payload = payload[n:]
+	}
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = fmt.Sprintf("~%d %d %s|%s", i, len(texts), key, t)
+	}
+	return out
+}
+
+// Deliver hands one received payload to its handler. Messages this replica
+// sent itself (NOTIFY reaches the sender's own LISTEN too) are ignored.
+func (r *Relay) Deliver(payload string) {
+	defer safego.Recover(context.Background(), "cluster.deliver")
+	if strings.HasPrefix(payload, "~") {
+		whole, ok := r.reassemble(payload)
+		if !ok {
+			return
+		}
+		payload = whole
+	}
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		slog.Warn("cluster: malformed peer message", slog.Any("err", err))
+		return
+	}
+	if env.From == r.id {
+		return
+	}
+	r.mu.RLock()
+	fn := r.handlers[env.Kind]
+	r.mu.RUnlock()
+	if fn != nil {
+		fn(env.Data)
+	}
+}
+
+// reassemble stores one fragment and returns the whole message once every
+// piece of it arrived.
+func (r *Relay) reassemble(p string) (string, bool) {
+	head, text, ok := strings.Cut(p[1:], "|")
+	if !ok {
+		return "", false
+	}
+	var index, count int
+	var key string
+	if _, err := fmt.Sscanf(head, "%d %d %s", &index, &count, &key); err != nil || count <= 0 || index < 0 || index >= count {
+		return "", false
+	}
+	if strings.HasPrefix(key, r.id+"-") {
+		return "", false // our own
+	}
+
+	r.partsMu.Lock()
+	defer r.partsMu.Unlock()
+	now := time.Now()
+	for k, m := range r.parts {
+		if now.Sub(m.at) > fragmentTTL {
+			delete(r.parts, k)
+		}
+	}
+	m := r.parts[key]
+	if m == nil {
+		m = &partial{parts: make([]string, count), at: now}
+		r.parts[key] = m
+	}
+	if len(m.parts) != count || m.parts[index] != "" {
+		return "", false
+	}
+	m.parts[index] = text
+	m.got++
+	if m.got < count {
+		return "", false
+	}
+	delete(r.parts, key)
+	return strings.Join(m.parts, ""), true
+}
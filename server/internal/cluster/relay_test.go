@@ -0,0 +1,190 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/events"
+)
+
+// fakeChannel stands in for a NOTIFY channel: every payload sent by any
+// relay is delivered to every relay, the sender included, like Postgres.
+type fakeChannel struct {
+	mu     sync.Mutex
+	relays []*Relay
+	sent   []string
+}
+
+func (c *fakeChannel) send(_ context.Context, channel, payload string) error {
+	if channel != Channel {
+		panic("unexpected channel " + channel)
+	}
+	if len(payload) >= 8000 {
+		panic("payload over the NOTIFY limit")
+	}
+	c.mu.Lock()
+	c.sent = append(c.sent, payload)
+	relays := append([]*Relay{}, c.relays...)
+	c.mu.Unlock()
+	for _, r := range relays {
+		r.Deliver(payload)
+	}
+	return nil
+}
+
+func (c *fakeChannel) join(t *testing.T) *Relay {
+	t.Helper()
+	r := NewRelay(c.send)
+	c.mu.Lock()
+	c.relays = append(c.relays, r)
+	c.mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { r.Run(ctx); close(done) }()
+	t.Cleanup(func() { cancel(); <-done })
+	return r
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRelay_DeliversToPeersNotSelf(t *testing.T) {
+	ch := &fakeChannel{}
+	a, b := ch.join(t), ch.join(t)
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	for name, r := range map[string]*Relay{"a": a, "b": b} {
+		name := name
+		r.Handle("greeting", func(data json.RawMessage) {
+			var s string
+			_ = json.Unmarshal(data, &s)
+			mu.Lock()
+			got[name] = append(got[name], s)
+			mu.Unlock()
+		})
+	}
+
+	a.Publish("greeting", "hello")
+	waitFor(t, "b to receive", func() bool { mu.Lock(); defer mu.Unlock(); return len(got["b"]) == 1 })
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got["a"]) != 0 {
+		t.Errorf("the sender must ignore its own message, got %v", got["a"])
+	}
+	if got["b"][0] != "hello" {
+		t.Errorf("b got %v", got["b"])
+	}
+}
+
+func TestRelay_CoalescesKeysIntoOneMessage(t *testing.T) {
+	ch := &fakeChannel{}
+	a, b := ch.join(t), ch.join(t)
+	var mu sync.Mutex
+	var batches [][]string
+	b.Handle("keys", func(data json.RawMessage) {
+		var keys []string
+		_ = json.Unmarshal(data, &keys)
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+	})
+
+	for i := 0; i < 20; i++ {
+		a.PublishCoalesced("keys", "dashboard")
+		a.PublishCoalesced("keys", "docker")
+	}
+	waitFor(t, "the batch", func() bool { mu.Lock(); defer mu.Unlock(); return len(batches) > 0 })
+	time.Sleep(2 * coalesceDelay)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 1 || strings.Join(batches[0], ",") != "dashboard,docker" {
+		t.Errorf("batches = %v, want one deduplicated batch", batches)
+	}
+}
+
+func TestRelay_FragmentsLargeMessages(t *testing.T) {
+	ch := &fakeChannel{}
+	a, b := ch.join(t), ch.join(t)
+	// Multi-byte runes straddling every cut point.
+	big := strings.Repeat("é€x", 9000)
+	var mu sync.Mutex
+	var got string
+	b.Handle("big", func(data json.RawMessage) {
+		mu.Lock()
+		_ = json.Unmarshal(data, &got)
+		mu.Unlock()
+	})
+
+	a.Publish("big", big)
+	waitFor(t, "reassembly", func() bool { mu.Lock(); defer mu.Unlock(); return got != "" })
+	if got != big {
+		t.Fatalf("reassembled %d bytes, want %d", len(got), len(big))
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.sent) < 2 {
+		t.Errorf("expected several fragments, got %d", len(ch.sent))
+	}
+}
+
+func TestRelay_ReassembleRejectsGarbage(t *testing.T) {
+	r := NewRelay(nil)
+	for _, p := range []string{"~", "~x y z|a", "~3 2 k|a", "~0 0 k|a", "~0 2 k"} {
+		if _, ok := r.reassemble(p); ok {
+			t.Errorf("reassemble(%q) must fail", p)
+		}
+	}
+	r.Deliver("not json") // must not panic
+}
+
+func TestAttachBus_CrossesReplicas(t *testing.T) {
+	ch := &fakeChannel{}
+	a, b := ch.join(t), ch.join(t)
+	busA, busB := events.NewBus(), events.NewBus()
+	a.AttachBus(busA)
+	b.AttachBus(busB)
+
+	sigA, unsubA := busA.Subscribe(events.TopicDashboard)
+	defer unsubA()
+	sigB, unsubB := busB.Subscribe(events.TopicDashboard)
+	defer unsubB()
+
+	busA.Publish(events.TopicDashboard)
+	for name, sig := range map[string]<-chan struct{}{"a": sigA, "b": sigB} {
+		select {
+		case <-sig:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("replica %s was not signalled", name)
+		}
+	}
+	// b delivers locally without echoing back: a sees exactly one wake-up.
+	time.Sleep(3 * coalesceDelay)
+	select {
+	case <-sigA:
+		t.Error("the topic bounced back to the publishing replica")
+	default:
+	}
+}
+
+func TestNilRelayAttachIsNoop(t *testing.T) {
+	var r *Relay
+	bus := events.NewBus()
+	r.AttachBus(bus)
+	r.AttachWS(nil)
+	r.AttachScheduler(nil)
+	bus.Publish(events.TopicApt) // no forwarder set, must not panic
+}
@@ -35,6 +35,11 @@ type Config struct {
 	// Settings UI (that would defeat the "zero network calls" guarantee the
 	// demo/screenshot pipeline relies on).
 	DemoMode bool
+	// HAEnabled lets several replicas share the database: background jobs,
+	// pollers and scheduled tasks run on the elected leader only, and WebSocket
+	// pushes cross replicas over Postgres LISTEN/NOTIFY (internal/cluster).
+	// Env-only: every replica must agree before any of them starts.
+	HAEnabled bool
 
 	// Logging
 	LogLevel  string // debug|info|warn|error
@@ -60,6 +65,18 @@ type Config struct {
 	AdminUser              string
 	AdminPassword          string
 
+	// Agent mTLS (internal/services/agentauth): AgentMTLSURL is the URL agents
+	// reach the dedicated mTLS listener on — set it to enable certificate
+	// enrollment; the listener serves /api/agent/* only, on AgentMTLSPort,
+	// with a certificate issued by the agent CA for the URL's host. It must
+	// be reached directly: a proxy terminating TLS in front of it would strip
+	// the client certificate (agents behind one sign their requests instead).
+	// AgentCertTTL is the lifetime of an issued client certificate; agents
+	// renew at two thirds of it. Env-only.
+	AgentMTLSURL  string
+	AgentMTLSPort string
+	AgentCertTTL  time.Duration
+
 	// Rate limiting
 	RateLimitRPS        int
 	RateLimitBurst      int
@@ -78,6 +95,13 @@ type Config struct {
 	// limited per source IP.
 	DockerImagePollInterval time.Duration
 
+	// Offline IP geolocation (internal/geoip): paths to MaxMind-compatible
+	// MMDB files, e.g. DB-IP "IP to City Lite" / "IP to ASN Lite" or GeoLite2
+	// City / ASN. Either may be empty; with neither, IPs are not geolocated.
+	// Env-only: the files live on the server's disk.
+	GeoIPCityDB string
+	GeoIPASNDB  string
+
 	// Alerts
 	NotifyURL     string
 	NtfyAuthToken string
@@ -93,10 +117,11 @@ type Config struct {
 	MetricsRetentionDays int
 	AuditRetentionDays   int
 	WebLogsRetentionDays int
-	// NetworkFlowsRetentionDays governs network_flow_metrics ("top talkers"),
-	// which stores remote IPs — a potentially identifying value, hence a
-	// short applicative-job retention (internal/background/network_flows.go)
-	// rather than a fixed TimescaleDB policy, same posture as WebLogsRetentionDays.
+	// NetworkFlowsRetentionDays governs network_flow_metrics ("top talkers")
+	// and dns_query_metrics, which store remote IPs and queried names —
+	// potentially identifying values, hence a short applicative-job retention
+	// (internal/background/network_flows.go) rather than a fixed TimescaleDB
+	// policy, same posture as WebLogsRetentionDays.
 	NetworkFlowsRetentionDays int
 	// AuditRetentionDaysByCategory overrides AuditRetentionDays per audit
 	// log category (models.AuditCategories' keys) — settings-only (no env
@@ -178,6 +203,7 @@ func Load() *Config {
 		BaseURL:    getEnv("BASE_URL", "http://localhost:8080"),
 		TLSEnabled: getBoolEnv("TLS_ENABLED", false),
 		DemoMode:   getBoolEnv("DEMO_MODE", false),
+		HAEnabled:  getBoolEnv("HA_ENABLED", false),
 
 		LogLevel:  getEnv("LOG_LEVEL", "info"),
 		LogFormat: logFormatDefault(),
@@ -199,6 +225,10 @@ func Load() *Config {
 		AdminUser:              getEnv("ADMIN_USER", "admin"),
 		AdminPassword:          getEnv("ADMIN_PASSWORD", "admin"),
 
+		AgentMTLSURL:  agentMTLSURL(),
+		AgentMTLSPort: getEnv("AGENT_MTLS_PORT", "8443"),
+		AgentCertTTL:  getDurationEnv("AGENT_CERT_TTL", 30*24*time.Hour),
+
 		RateLimitRPS:        getIntEnv("RATE_LIMIT_RPS", 100),
 		RateLimitBurst:      getIntEnv("RATE_LIMIT_BURST", 200),
 		AgentRateLimitRPS:   getIntEnv("AGENT_RATE_LIMIT_RPS", 20),
@@ -209,6 +239,9 @@ func Load() *Config {
 
 		DockerImagePollInterval: getDurationEnv("DOCKER_IMAGE_POLL_INTERVAL", 6*time.Hour),
 
+		GeoIPCityDB: getEnv("GEOIP_CITY_DB", ""),
+		GeoIPASNDB:  getEnv("GEOIP_ASN_DB", ""),
+
 		NotifyURL:     getEnv("NOTIFY_URL", ""),
 		NtfyAuthToken: getEnv("NTFY_AUTH_TOKEN", ""),
 		SMTPHost:      getEnv("SMTP_HOST", ""),
@@ -369,6 +402,12 @@ func (c *Config) Validate() []string {
 	if c.NetworkFlowsRetentionDays <= 0 {
 		warnings = append(warnings, "NETWORK_FLOWS_RETENTION_DAYS must be a positive integer")
 	}
+	if os.Getenv("AGENT_MTLS_URL") != "" && c.AgentMTLSURL == "" {
+		warnings = append(warnings, "AGENT_MTLS_URL must be an https:// URL — agent mTLS stays disabled")
+	}
+	if c.AgentCertTTL < time.Hour {
+		warnings = append(warnings, "AGENT_CERT_TTL must be at least 1h — using 1h")
+	}
 	return warnings
 }
 
@@ -407,6 +446,16 @@ func (c *Config) DBDSN() string {
 		" sslmode=" + c.DBSSLMode
 }
 
+// agentMTLSURL returns AGENT_MTLS_URL without its trailing slash, or "" (mTLS
+// disabled) unless it is an https:// URL.
+func agentMTLSURL() string {
+	u := strings.TrimRight(strings.TrimSpace(os.Getenv("AGENT_MTLS_URL")), "/")
+	if !strings.HasPrefix(u, "https://") {
+		return ""
+	}
+	return u
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
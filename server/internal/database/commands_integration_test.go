@@ -56,6 +56,45 @@ func TestRemoteCommand_CreateAndClaimAtomically(t *testing.T) {
 	}
 }
 
+// TestRemoteCommand_ReleaseMakesClaimableAgain covers the push channel losing
+// its connection before the agent acked a command: the command goes back to
+// pending, but one the agent already finished is never reopened.
+func TestRemoteCommand_ReleaseMakesClaimableAgain(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	seedTestHost(t, db)
+	ctx := context.Background()
+
+	released, err := db.CreateRemoteCommand(ctx, testHostID, "docker", "restart", "nginx", "{}", "alice", nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	done, err := db.CreateRemoteCommand(ctx, testHostID, "docker", "restart", "redis", "{}", "alice", nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if claimed, err := db.ClaimPendingRemoteCommands(ctx, testHostID); err != nil || len(claimed) != 2 {
+		t.Fatalf("claim: %+v, %v", claimed, err)
+	}
+	if err := db.UpdateRemoteCommandStatus(ctx, done.ID, "completed", "ok"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	if err := db.ReleaseClaimedRemoteCommands(ctx, testHostID, []string{released.ID, done.ID}); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	again, err := db.ClaimPendingRemoteCommands(ctx, testHostID)
+	if err != nil {
+		t.Fatalf("re-claim: %v", err)
+	}
+	if len(again) != 1 || again[0].ID != released.ID {
+		t.Fatalf("only the unfinished command must be claimable again, got %+v", again)
+	}
+	got, err := db.GetRemoteCommandByID(ctx, done.ID)
+	if err != nil || got.Status != "completed" {
+		t.Fatalf("a finished command must stay finished, got %+v, %v", got, err)
+	}
+}
+
 // TestRemoteCommand_ConcurrentClaimNeverDuplicates exercises the SKIP LOCKED
 // path: with N agents calling Claim concurrently, a given command must end up
 // in exactly one of the result sets — never zero, never two.
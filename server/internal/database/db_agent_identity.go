@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// GetAgentCA returns the agent CA, or sql.ErrNoRows before its creation.
+func (db *DB) GetAgentCA(ctx context.Context) (certPEM, keyPEM string, err error) {
+	err = db.conn.QueryRowContext(ctx,
+		`SELECT cert_pem, key_pem FROM agent_ca WHERE id = 1`).Scan(&certPEM, &keyPEM)
+	return certPEM, keyPEM, err
+}
+
+// InsertAgentCAIfAbsent stores a freshly generated CA unless another replica
+// stored one first; the caller re-reads the winner with GetAgentCA.
+func (db *DB) InsertAgentCAIfAbsent(ctx context.Context, certPEM, keyPEM string) error {
+	_, err := db.conn.ExecContext(ctx,
+		`INSERT INTO agent_ca (id, cert_pem, key_pem) VALUES (1, $1, $2)
+		 ON CONFLICT (id) DO NOTHING`, certPEM, keyPEM)
+	return err
+}
+
+const agentCredentialColumns = `id, host_id, kind, serial, public_key, fingerprint, not_after, created_at, revoked_at`
+
+func scanAgentCredential(row interface{ Scan(...any) error }) (*models.AgentCredential, error) {
+	var c models.AgentCredential
+	var notAfter, revokedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.HostID, &c.Kind, &c.Serial, &c.PublicKey, &c.Fingerprint, &notAfter, &c.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if notAfter.Valid {
+		c.NotAfter = &notAfter.Time
+	}
+	if revokedAt.Valid {
+		c.RevokedAt = &revokedAt.Time
+	}
+	return &c, nil
+}
+
+func (db *DB) queryAgentCredentials(ctx context.Context, query string, args ...any) ([]models.AgentCredential, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.AgentCredential, 0)
+	for rows.Next() {
+		c, err := scanAgentCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+// CreateAgentCredential stores an enrolled credential and returns it with its
+// id and creation time.
+func (db *DB) CreateAgentCredential(ctx context.Context, c *models.AgentCredential) (*models.AgentCredential, error) {
+	return scanAgentCredential(db.conn.QueryRowContext(ctx,
+		`INSERT INTO agent_credentials (host_id, kind, serial, public_key, fingerprint, not_after)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+agentCredentialColumns,
+		c.HostID, c.Kind, c.Serial, c.PublicKey, c.Fingerprint, c.NotAfter))
+}
+
+// ListAgentCredentials returns every credential of a host, newest first,
+// revoked and expired ones included.
+func (db *DB) ListAgentCredentials(ctx context.Context, hostID string) ([]models.AgentCredential, error) {
+	return db.queryAgentCredentials(ctx,
+		`SELECT `+agentCredentialColumns+` FROM agent_credentials
+		 WHERE host_id = $1 ORDER BY created_at DESC`, hostID)
+}
+
+// GetActiveAgentCredentials returns the host's credentials of the given kind
+// that still authenticate it (not revoked, not expired).
+func (db *DB) GetActiveAgentCredentials(ctx context.Context, hostID, kind string) ([]models.AgentCredential, error) {
+	return db.queryAgentCredentials(ctx,
+		`SELECT `+agentCredentialColumns+` FROM agent_credentials
+		 WHERE host_id = $1 AND kind = $2 AND revoked_at IS NULL
+		   AND (not_after IS NULL OR not_after > NOW())`, hostID, kind)
+}
+
+// GetAgentCredentialBySerial returns the certificate credential with that
+// serial, or sql.ErrNoRows.
+func (db *DB) GetAgentCredentialBySerial(ctx context.Context, serial string) (*models.AgentCredential, error) {
+	return scanAgentCredential(db.conn.QueryRowContext(ctx,
+		`SELECT `+agentCredentialColumns+` FROM agent_credentials WHERE serial = $1`, serial))
+}
+
+// AgentStrongAuthRequired reports whether the host ever enrolled a credential
+// and was not reset by an admin since — in which case its API key alone is
+// refused, even once every credential expired.
+func (db *DB) AgentStrongAuthRequired(ctx context.Context, hostID string) (bool, error) {
+	var required bool
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT strong_auth_required FROM hosts WHERE id = $1`, hostID).Scan(&required)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return required, err
+}
+
+// RequireAgentStrongAuth marks the host as enrolled: from now on its API key
+// alone is refused.
+func (db *DB) RequireAgentStrongAuth(ctx context.Context, hostID string) error {
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE hosts SET strong_auth_required = TRUE WHERE id = $1`, hostID)
+	return err
+}
+
+// SupersedeAgentCredentials caps the expiry of the host's other active
+// credentials of a kind at until, once keepID replaced them: requests already
+// in flight with the previous credential still go through.
+func (db *DB) SupersedeAgentCredentials(ctx context.Context, hostID, kind, keepID string, until time.Time) error {
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE agent_credentials SET not_after = LEAST(COALESCE(not_after, $4), $4)
+		 WHERE host_id = $1 AND kind = $2 AND id <> $3 AND revoked_at IS NULL`,
+		hostID, kind, keepID, until)
+	return err
+}
+
+// RevokeAgentCredential revokes one credential of a host. Returns false when
+// there is no such active credential.
+func (db *DB) RevokeAgentCredential(ctx context.Context, hostID, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		`UPDATE agent_credentials SET revoked_at = NOW()
+		 WHERE host_id = $1 AND id = $2 AND revoked_at IS NULL`, hostID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ResetAgentCredentials revokes every credential of a host and puts it back on
+// its API key alone, so the agent can enroll again. Returns false when the
+// host does not exist.
+func (db *DB) ResetAgentCredentials(ctx context.Context, hostID string) (bool, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE hosts SET strong_auth_required = FALSE WHERE id = $1`, hostID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE agent_credentials SET revoked_at = NOW()
+		 WHERE host_id = $1 AND revoked_at IS NULL`, hostID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RecordAgentRequestNonce records the nonce of a signed agent request,
+// returning false when any replica already recorded it.
+func (db *DB) RecordAgentRequestNonce(ctx context.Context, key string, at time.Time) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		`INSERT INTO agent_request_nonces (nonce_key, seen_at) VALUES ($1, $2)
+		 ON CONFLICT (nonce_key) DO NOTHING`, key, at)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteAgentRequestNoncesBefore prunes the nonces recorded before t.
+func (db *DB) DeleteAgentRequestNoncesBefore(ctx context.Context, t time.Time) error {
+	_, err := db.conn.ExecContext(ctx,
+		`DELETE FROM agent_request_nonces WHERE seen_at < $1`, t)
+	return err
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const agentJoinTokenColumns = `id, name, tags, permissions, max_uses, uses, expires_at, created_by, created_at, last_used_at, revoked_at`
+
+func scanAgentJoinToken(row interface{ Scan(...any) error }) (*models.AgentJoinToken, error) {
+	var t models.AgentJoinToken
+	var tags string
+	var perms []byte
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &tags, &perms, &t.MaxUses, &t.Uses, &t.ExpiresAt,
+		&t.CreatedBy, &t.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	t.Tags = parseTags(tags)
+	if err := json.Unmarshal(perms, &t.Permissions); err != nil || t.Permissions == nil {
+		t.Permissions = []models.AgentJoinPermission{}
+	}
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return &t, nil
+}
+
+// CreateAgentJoinToken stores a join token by the SHA-256 of its secret and
+// returns it with its id and creation time.
+func (db *DB) CreateAgentJoinToken(ctx context.Context, t *models.AgentJoinToken, tokenHash string) (*models.AgentJoinToken, error) {
+	perms, err := json.Marshal(t.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	return scanAgentJoinToken(db.conn.QueryRowContext(ctx,
+		`INSERT INTO agent_join_tokens (name, token_hash, tags, permissions, max_uses, expires_at, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING `+agentJoinTokenColumns,
+		t.Name, tokenHash, marshalTags(t.Tags), perms, t.MaxUses, t.ExpiresAt, t.CreatedBy))
+}
+
+// ListAgentJoinTokens returns every join token, newest first, revoked and
+// expired ones included.
+func (db *DB) ListAgentJoinTokens(ctx context.Context) ([]models.AgentJoinToken, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+agentJoinTokenColumns+` FROM agent_join_tokens ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.AgentJoinToken, 0)
+	for rows.Next() {
+		t, err := scanAgentJoinToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// ConsumeAgentJoinToken counts one use of the token with that hash and returns
+// it, in a single statement so concurrent joins cannot exceed max_uses.
+// Returns sql.ErrNoRows when the token is unknown, revoked, expired or used up.
+func (db *DB) ConsumeAgentJoinToken(ctx context.Context, tokenHash string) (*models.AgentJoinToken, error) {
+	return scanAgentJoinToken(db.conn.QueryRowContext(ctx,
+		`UPDATE agent_join_tokens SET uses = uses + 1, last_used_at = NOW()
+		 WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		   AND (max_uses = 0 OR uses < max_uses)
+		 RETURNING `+agentJoinTokenColumns, tokenHash))
+}
+
+// ReleaseAgentJoinToken gives back the use counted by ConsumeAgentJoinToken
+// when the join it was counted for did not register a host.
+func (db *DB) ReleaseAgentJoinToken(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE agent_join_tokens SET uses = GREATEST(uses - 1, 0) WHERE id = $1`, id)
+	return err
+}
+
+// ReserveAgentJoin claims join request joinID for the token before its host is
+// registered. Returns false when another request holds it: a pending one
+// reserved since pendingSince, or one that created its host since
+// recordedSince. Older rows are taken over.
+func (db *DB) ReserveAgentJoin(ctx context.Context, joinID, tokenID string, pendingSince, recordedSince time.Time) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		`INSERT INTO agent_join_requests (join_id, token_id) VALUES ($1, $2)
+		 ON CONFLICT (join_id) DO UPDATE SET token_id = EXCLUDED.token_id, host_id = NULL, created_at = NOW()
+		 WHERE (agent_join_requests.host_id IS NULL AND agent_join_requests.created_at <= $3)
+		    OR agent_join_requests.created_at <= $4`, joinID, tokenID, pendingSince, recordedSince)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RecordAgentJoin sets the host a reserved join request created, so a retry of
+// the same request can be recognised. Returns sql.ErrNoRows when the
+// reservation is gone.
+func (db *DB) RecordAgentJoin(ctx context.Context, joinID, hostID string) error {
+	res, err := db.conn.ExecContext(ctx,
+		`UPDATE agent_join_requests SET host_id = $2 WHERE join_id = $1 AND host_id IS NULL`, joinID, hostID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReleaseAgentJoin drops the reservation of a join request that registered no
+// host, so it can be retried at once.
+func (db *DB) ReleaseAgentJoin(ctx context.Context, joinID string) error {
+	_, err := db.conn.ExecContext(ctx,
+		`DELETE FROM agent_join_requests WHERE join_id = $1 AND host_id IS NULL`, joinID)
+	return err
+}
+
+// FindAgentJoin returns the host created since the given time by the join
+// request joinID with the token of that hash, as long as the token is still
+// unrevoked and unexpired. Returns sql.ErrNoRows otherwise, including while the
+// request is only reserved.
+func (db *DB) FindAgentJoin(ctx context.Context, tokenHash, joinID string, since time.Time) (string, error) {
+	var hostID string
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT r.host_id FROM agent_join_requests r JOIN agent_join_tokens t ON t.id = r.token_id
+		 WHERE r.join_id = $1 AND t.token_hash = $2 AND r.created_at > $3 AND r.host_id IS NOT NULL
+		   AND t.revoked_at IS NULL AND t.expires_at > NOW()`, joinID, tokenHash, since).Scan(&hostID)
+	return hostID, err
+}
+
+// RevokeAgentJoinToken revokes a join token. Returns false when there is no
+// such unrevoked token.
+func (db *DB) RevokeAgentJoinToken(ctx context.Context, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		`UPDATE agent_join_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,59 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestAgentJoin_ReserveOnce: a join_id is reserved by one request only, is
+// found once its host is recorded, and a stale reservation is taken over.
+func TestAgentJoin_ReserveOnce(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	mustRegisterHost(t, db, "host-join")
+	tok, err := db.CreateAgentJoinToken(ctx, &models.AgentJoinToken{Name: "fleet", ExpiresAt: time.Now().Add(time.Hour), CreatedBy: "admin"}, "hash-1")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	pendingSince, recordedSince := time.Now().Add(-time.Minute), time.Now().Add(-time.Hour)
+
+	if ok, err := db.ReserveAgentJoin(ctx, "join-1", tok.ID, pendingSince, recordedSince); err != nil || !ok {
+		t.Fatalf("reserve: %v, %v", ok, err)
+	}
+	if ok, err := db.ReserveAgentJoin(ctx, "join-1", tok.ID, pendingSince, recordedSince); err != nil || ok {
+		t.Errorf("reserved twice: %v, %v", ok, err)
+	}
+	if _, err := db.FindAgentJoin(ctx, "hash-1", "join-1", recordedSince); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("pending join found: %v", err)
+	}
+	if err := db.RecordAgentJoin(ctx, "join-1", "host-join"); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if hostID, err := db.FindAgentJoin(ctx, "hash-1", "join-1", recordedSince); err != nil || hostID != "host-join" {
+		t.Errorf("find = %q, %v", hostID, err)
+	}
+	if err := db.RecordAgentJoin(ctx, "join-unknown", "host-join"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("record without reservation: %v", err)
+	}
+
+	// A pending reservation older than pendingSince is taken over; a
+	// released one is gone at once.
+	if ok, _ := db.ReserveAgentJoin(ctx, "join-2", tok.ID, pendingSince, recordedSince); !ok {
+		t.Fatal("reserve join-2")
+	}
+	if ok, err := db.ReserveAgentJoin(ctx, "join-2", tok.ID, time.Now().Add(time.Second), recordedSince); err != nil || !ok {
+		t.Errorf("stale reservation not taken over: %v, %v", ok, err)
+	}
+	if err := db.ReleaseAgentJoin(ctx, "join-2"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if ok, _ := db.ReserveAgentJoin(ctx, "join-2", tok.ID, pendingSince, recordedSince); !ok {
+		t.Error("released join_id not reservable")
+	}
+}
@@ -68,13 +68,27 @@ func (db *DB) ListAlertProxmoxDisks(ctx context.Context) ([]models.AlertScopeOpt
 		ORDER BY c.name, d.node_name, d.dev_path`)
 }
 
+// ListAlertPBSBackupGroups lists the Proxmox Backup Server groups for the
+// backup_group scope ("pbs / store / vm/100 (name)").
+func (db *DB) ListAlertPBSBackupGroups(ctx context.Context) ([]models.AlertScopeOption, error) {
+	return db.scopeOptions(ctx, `
+		SELECT g.id,
+		       c.name || ' / ' || g.store || ' / ' || g.backup_type || '/' || g.backup_id ||
+		       COALESCE(' (' || NULLIF(guest.name, '') || ')', '')
+		FROM pbs_backup_groups g
+		JOIN pbs_connections c ON c.id = g.connection_id`+pbsGuestNameJoin+`
+		ORDER BY c.name, g.store, g.backup_type, g.backup_id`)
+}
+
 // ListAlertDockerScopeHosts returns the hosts that currently have Docker
-// containers (id + display name), for the Docker alert scope selector.
+// containers or are Swarm managers (a drained manager may run none), id +
+// display name, for the Docker alert scope selector.
 func (db *DB) ListAlertDockerScopeHosts(ctx context.Context) ([]models.AlertScopeOption, error) {
 	return db.scopeOptions(ctx, `
-		SELECT DISTINCT dc.host_id, h.name
-		FROM docker_containers dc
-		JOIN hosts h ON h.id = dc.host_id
+		SELECT h.id, h.name
+		FROM hosts h
+		WHERE EXISTS (SELECT 1 FROM docker_containers dc WHERE dc.host_id = h.id)
+		   OR EXISTS (SELECT 1 FROM docker_swarm_clusters sc WHERE sc.host_id = h.id)
 		ORDER BY h.name`)
 }
 
@@ -121,3 +135,14 @@ func (db *DB) ProxmoxDiskLabelParts(ctx context.Context, id string) (connName, n
 		WHERE d.id = $1`, id).Scan(&connName, &nodeName, &devPath, &model)
 	return connName, nodeName, devPath, model, err
 }
+
+// PBSBackupGroupLabelParts returns the connection/datastore/group names of a
+// backup group; guestName is empty for host backups or unknown VMIDs.
+func (db *DB) PBSBackupGroupLabelParts(ctx context.Context, id string) (connName, store, group, guestName string, err error) {
+	err = db.conn.QueryRowContext(ctx, `
+		SELECT c.name, g.store, g.backup_type || '/' || g.backup_id, COALESCE(guest.name, '')
+		FROM pbs_backup_groups g
+		JOIN pbs_connections c ON c.id = g.connection_id`+pbsGuestNameJoin+`
+		WHERE g.id = $1`, id).Scan(&connName, &store, &group, &guestName)
+	return connName, store, group, guestName, err
+}
@@ -132,6 +132,14 @@ func (db *DB) ComposeProjectExists(ctx context.Context, name, hostID string) (bo
 	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM compose_projects WHERE name = $1 AND host_id = $2)`, name, hostID)
 }
 
+func (db *DB) DockerSwarmServiceExists(ctx context.Context, name, hostID string) (bool, error) {
+	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM docker_swarm_services WHERE name = $1 AND host_id = $2)`, name, hostID)
+}
+
+func (db *DB) DockerSwarmNodeExists(ctx context.Context, nodeID, hostID string) (bool, error) {
+	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM docker_swarm_nodes WHERE node_id = $1 AND host_id = $2)`, nodeID, hostID)
+}
+
 func (db *DB) ProxmoxConnectionExists(ctx context.Context, id string) (bool, error) {
 	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM proxmox_connections WHERE id = $1)`, id)
 }
@@ -151,3 +159,7 @@ func (db *DB) ProxmoxGuestExists(ctx context.Context, id string) (bool, error) {
 func (db *DB) ProxmoxDiskExists(ctx context.Context, id string) (bool, error) {
 	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM proxmox_disks WHERE id = $1)`, id)
 }
+
+func (db *DB) PBSBackupGroupExists(ctx context.Context, id string) (bool, error) {
+	return db.exists(ctx, `SELECT EXISTS(SELECT 1 FROM pbs_backup_groups WHERE id = $1)`, id)
+}
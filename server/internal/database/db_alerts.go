@@ -442,7 +442,8 @@ func (db *DB) GetAlertIncidentsByHost(ctx context.Context, hostID string, limit
 }
 
 // enrichDockerIncident fills LinkHostID and ValueLabel for incidents whose
-// host_id is a synthetic Docker identifier (docker:container: / docker:compose:).
+// host_id is a synthetic Docker identifier (docker:container: / docker:compose: /
+// docker:swarm_service: / docker:swarm_node:).
 func (db *DB) enrichDockerIncident(ctx context.Context, inc *models.AlertIncident) {
 	if strings.HasPrefix(inc.HostID, "docker:container:") {
 		uuid := strings.TrimPrefix(inc.HostID, "docker:container:")
@@ -455,10 +456,13 @@ func (db *DB) enrichDockerIncident(ctx context.Context, inc *models.AlertInciden
 		}
 		return
 	}
-	if strings.HasPrefix(inc.HostID, "docker:compose:") {
-		rest := strings.TrimPrefix(inc.HostID, "docker:compose:")
-		if idx := strings.Index(rest, ":"); idx >= 0 {
-			inc.LinkHostID = rest[:idx]
+	for _, prefix := range []string{"docker:compose:", "docker:swarm_service:", "docker:swarm_node:"} {
+		if strings.HasPrefix(inc.HostID, prefix) {
+			rest := strings.TrimPrefix(inc.HostID, prefix)
+			if idx := strings.Index(rest, ":"); idx >= 0 {
+				inc.LinkHostID = rest[:idx]
+			}
+			return
 		}
 	}
 }
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// AdvisoryLock is a session-level Postgres advisory lock, held on a connection
+// taken out of the pool for as long as the lock lives. Postgres releases it on
+// its own if that session dies, which is what lets another replica take over.
+type AdvisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// TryAdvisoryLock takes the advisory lock key without waiting. It returns
+// nil, nil when another session holds it.
+func (db *DB) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Alive checks that the session holding the lock is still up. An error means
+// the lock may already belong to someone else.
+func (l *AdvisoryLock) Alive(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, `SELECT 1`)
+	return err
+}
+
+// Release unlocks and discards the session rather than returning it to the
+// pool: closing it is the one guarantee the lock is gone even if the unlock
+// itself failed.
+func (l *AdvisoryLock) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key); err != nil {
+		slog.Warn("advisory unlock failed, dropping the session", slog.Int64("key", l.key), slog.Any("err", err))
+	}
+	_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = l.conn.Close()
+}
+
+// Notify sends payload on channel to every session LISTENing on it, this
+// process included. Postgres caps a payload at 8000 bytes.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	_, err := db.conn.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// ListenNotifications LISTENs on channel over its own connection (outside the
+// pool) and calls fn with each payload until ctx is cancelled. lib/pq
+// reconnects on its own; notifications sent while disconnected are lost, so
+// onReconnect (optional) lets the caller resync.
+func ListenNotifications(ctx context.Context, dsn, channel string, fn func(payload string), onReconnect func()) error {
+	listener := pq.NewListener(dsn, time.Second, 30*time.Second, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("notification listener disconnected", slog.String("channel", channel), slog.Any("err", err))
+		case pq.ListenerEventReconnected:
+			slog.Info("notification listener reconnected", slog.String("channel", channel))
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("notification listener reconnect failed", slog.String("channel", channel), slog.Any("err", err))
+		}
+	})
+	defer func() { _ = listener.Close() }()
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	// lib/pq recommends a periodic ping: a silent channel would otherwise
+	// never notice a dead connection.
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil { // connection re-established
+				if onReconnect != nil {
+					onReconnect()
+				}
+				continue
+			}
+			fn(n.Extra)
+		case <-ping.C:
+			_ = listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestAdvisoryLock_ExclusiveUntilReleased checks that a second session cannot
+// take a held lock and can once it is released.
+func TestAdvisoryLock_ExclusiveUntilReleased(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	const key = 4242
+
+	first, err := db.TryAdvisoryLock(ctx, key)
+	if err != nil || first == nil {
+		t.Fatalf("first lock: lock=%v err=%v", first, err)
+	}
+	if err := first.Alive(ctx); err != nil {
+		t.Fatalf("alive: %v", err)
+	}
+	if second, err := db.TryAdvisoryLock(ctx, key); err != nil || second != nil {
+		t.Fatalf("second lock while held: lock=%v err=%v", second, err)
+	}
+
+	first.Release()
+	second, err := db.TryAdvisoryLock(ctx, key)
+	if err != nil || second == nil {
+		t.Fatalf("lock after release: lock=%v err=%v", second, err)
+	}
+	second.Release()
+}
+
+// TestNotify_ReachesListener round-trips a payload through LISTEN/NOTIFY.
+func TestNotify_ReachesListener(t *testing.T) {
+	db, cfg := testutil.NewPostgresDBWithConfig(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = database.ListenNotifications(ctx, cfg.DBDSN(), "ss_test_channel", func(p string) {
+			select {
+			case got <- p:
+			default:
+			}
+		}, nil)
+	}()
+	// The listener must be gone before the test database is dropped.
+	defer func() { cancel(); <-done }()
+
+	// LISTEN is asynchronous: keep notifying until the listener is up.
+	deadline := time.After(10 * time.Second)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if err := db.Notify(ctx, "ss_test_channel", "ping"); err != nil {
+			t.Fatalf("notify: %v", err)
+		}
+		select {
+		case p := <-got:
+			if p != "ping" {
+				t.Fatalf("payload = %q", p)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no notification received")
+		case <-tick.C:
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lib/pq"
 	"github.com/serversupervisor/server/internal/models"
 )
 
@@ -53,6 +54,22 @@ func (db *DB) CreateRemoteCommand(ctx context.Context, hostID, module, action, t
 	return &cmd, nil
 }
 
+// CreateServerRemoteCommand records a command the server executes itself
+// (e.g. a runbook's Proxmox snapshot step). It is inserted directly as
+// running, so ClaimPendingRemoteCommands never hands it to an agent; the
+// caller closes it with UpdateRemoteCommandStatus.
+func (db *DB) CreateServerRemoteCommand(ctx context.Context, hostID, module, action, target, triggeredBy string) (string, error) {
+	id := newUUID()
+	_, err := db.conn.ExecContext(ctx,
+		`INSERT INTO remote_commands (id, host_id, module, action, target, triggered_by, status, started_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, 'running', NOW())`,
+		id, hostID, module, action, target, triggeredBy)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
 // ClaimPendingRemoteCommands atomically claims all pending commands for a host
 // and marks them as running before returning them. This enforces exactly-once
 // delivery semantics across report polling cycles.
@@ -89,6 +106,21 @@ RETURNING rc.id, rc.module, rc.action, rc.target, rc.payload`,
 	return cmds, nil
 }
 
+// ReleaseClaimedRemoteCommands puts commands claimed for hostID back to
+// pending, undoing ClaimPendingRemoteCommands for the ones the agent never
+// acknowledged (push connection lost before the ack). Rows the agent already
+// moved on, or that belong to another host, are left alone.
+func (db *DB) ReleaseClaimedRemoteCommands(ctx context.Context, hostID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE remote_commands SET status = 'pending', started_at = NULL
+		 WHERE host_id = $1 AND id = ANY($2) AND status = 'running'`,
+		hostID, pq.Array(ids))
+	return err
+}
+
 // TouchRunningCommandsActivity bumps last_activity_at for all of a host's running
 // commands. Called on every agent report: a report proves the agent is alive, so
 // its in-flight commands must not be reaped by the stalled-command cleanup even if
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== CrowdSec fleet sync ==========
+
+// StoreCrowdSecReport replaces a host's CrowdSec snapshot in one transaction.
+// The bouncer list is only replaced when the agent could list it
+// (BouncersError empty), so a transient cscli failure does not make every
+// bouncer of the host disappear.
+func (db *DB) StoreCrowdSecReport(ctx context.Context, hostID string, at time.Time, report *models.CrowdSecReport) error {
+	if report == nil {
+		return nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	bouncersListed := report.BouncersError == ""
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var listedAt *time.Time
+	if bouncersListed {
+		listedAt = &at
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO crowdsec_sync_status
+		   (host_id, collected_at, local_decisions, community_decisions, truncated, bouncers_listed_at, bouncers_error)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (host_id) DO UPDATE
+		   SET collected_at = EXCLUDED.collected_at,
+		       local_decisions = EXCLUDED.local_decisions,
+		       community_decisions = EXCLUDED.community_decisions,
+		       truncated = EXCLUDED.truncated,
+		       bouncers_listed_at = COALESCE(EXCLUDED.bouncers_listed_at, crowdsec_sync_status.bouncers_listed_at),
+		       bouncers_error = EXCLUDED.bouncers_error`,
+		hostID, at, len(report.Decisions), report.CommunityDecisions, report.Truncated, listedAt, report.BouncersError,
+	); err != nil {
+		return fmt.Errorf("failed to upsert crowdsec sync status: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM crowdsec_decisions WHERE host_id = $1`, hostID); err != nil {
+		return fmt.Errorf("failed to delete old crowdsec decisions: %w", err)
+	}
+	for _, d := range report.Decisions {
+		if d.Value == "" || len(d.Value) > 64 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO crowdsec_decisions
+			   (host_id, value, type, scenario, origin, country, as_name, started_at, until)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (host_id, value) DO NOTHING`,
+			hostID, d.Value, d.Type, d.Scenario, d.Origin, d.Country, d.ASName, timePtr(d.StartedAt), timePtr(d.Until),
+		); err != nil {
+			return fmt.Errorf("failed to insert crowdsec decision: %w", err)
+		}
+	}
+
+	if bouncersListed {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM crowdsec_bouncers WHERE host_id = $1`, hostID); err != nil {
+			return fmt.Errorf("failed to delete old crowdsec bouncers: %w", err)
+		}
+		for _, b := range report.Bouncers {
+			if b.Name == "" || len(b.Name) > 255 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO crowdsec_bouncers
+				   (host_id, name, ip_address, type, version, last_pull, created_at, revoked)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				 ON CONFLICT (host_id, name) DO NOTHING`,
+				hostID, b.Name, b.IPAddress, b.Type, b.Version, timePtr(b.LastPull), timePtr(b.CreatedAt), b.Revoked,
+			); err != nil {
+				return fmt.Errorf("failed to insert crowdsec bouncer: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// timePtr maps the agent's "zero means unknown" times to NULL.
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ListCrowdSecSyncStatuses returns every host that sent a CrowdSec snapshot,
+// by host name. Stale is left to the caller.
+func (db *DB) ListCrowdSecSyncStatuses(ctx context.Context) ([]models.CrowdSecHostSync, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT s.host_id, h.name, s.collected_at, s.local_decisions, s.community_decisions, s.truncated, s.bouncers_error
+		 FROM crowdsec_sync_status s
+		 JOIN hosts h ON h.id = s.host_id
+		 ORDER BY h.name ASC, s.host_id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.CrowdSecHostSync, 0)
+	for rows.Next() {
+		var s models.CrowdSecHostSync
+		if err := rows.Scan(&s.HostID, &s.HostName, &s.CollectedAt, &s.LocalDecisions, &s.CommunityDecisions, &s.Truncated, &s.BouncersError); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// ListCrowdSecDecisions returns the stored decisions of every host that have
+// not expired yet, optionally for one value (IP or range), ordered by value
+// then host name.
+func (db *DB) ListCrowdSecDecisions(ctx context.Context, value string) ([]models.CrowdSecHostDecision, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT d.host_id, h.name, d.value, d.type, d.scenario, d.origin, d.country, d.as_name, d.started_at, d.until
+		 FROM crowdsec_decisions d
+		 JOIN hosts h ON h.id = d.host_id
+		 WHERE (d.until IS NULL OR d.until > NOW()) AND ($1 = '' OR d.value = $1)
+		 ORDER BY d.value ASC, h.name ASC`,
+		value,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.CrowdSecHostDecision, 0)
+	for rows.Next() {
+		var (
+			d                models.CrowdSecHostDecision
+			startedAt, until sql.NullTime
+		)
+		if err := rows.Scan(&d.HostID, &d.HostName, &d.Value, &d.Type, &d.Scenario, &d.Origin, &d.Country, &d.ASName, &startedAt, &until); err != nil {
+			return nil, err
+		}
+		d.StartedAt = nullTimePtr(startedAt)
+		d.Until = nullTimePtr(until)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// ListCrowdSecBouncers returns the stored bouncers of one host, or of every
+// host when hostID is empty, by host name then bouncer name. The pull health
+// fields are left to the caller.
+func (db *DB) ListCrowdSecBouncers(ctx context.Context, hostID string) ([]models.CrowdSecBouncerStatus, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT b.host_id, h.name, COALESCE(s.bouncers_listed_at, s.collected_at),
+		        b.name, b.ip_address, b.type, b.version, b.last_pull, b.created_at, b.revoked
+		 FROM crowdsec_bouncers b
+		 JOIN hosts h ON h.id = b.host_id
+		 JOIN crowdsec_sync_status s ON s.host_id = b.host_id
+		 WHERE $1 = '' OR b.host_id = $1
+		 ORDER BY h.name ASC, b.host_id ASC, b.name ASC`,
+		hostID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.CrowdSecBouncerStatus, 0)
+	for rows.Next() {
+		var (
+			b                   models.CrowdSecBouncerStatus
+			lastPull, createdAt sql.NullTime
+		)
+		if err := rows.Scan(&b.HostID, &b.HostName, &b.ListedAt,
+			&b.Name, &b.IPAddress, &b.Type, &b.Version, &lastPull, &createdAt, &b.Revoked); err != nil {
+			return nil, err
+		}
+		b.LastPull = lastPull.Time
+		b.CreatedAt = createdAt.Time
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// GetCrowdSecBouncer returns one stored bouncer, or nil when the host does
+// not list it (anymore).
+func (db *DB) GetCrowdSecBouncer(ctx context.Context, hostID, name string) (*models.CrowdSecBouncerStatus, error) {
+	bouncers, err := db.ListCrowdSecBouncers(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bouncers {
+		if bouncers[i].Name == name {
+			return &bouncers[i], nil
+		}
+	}
+	return nil, nil
+}
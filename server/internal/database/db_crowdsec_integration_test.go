@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestStoreCrowdSecReport_ReplacesSnapshot stores two snapshots of one host:
+// the second replaces the decisions, an expired decision is never listed, and
+// a snapshot whose bouncers could not be listed keeps the previous bouncers.
+func TestStoreCrowdSecReport_ReplacesSnapshot(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	registerNetworkFlowsHost(t, db, ctx)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	first := &models.CrowdSecReport{
+		Decisions: []models.CrowdSecReportDecision{
+			{Value: "203.0.113.9", Type: "ban", Scenario: "crowdsecurity/ssh-bf", Origin: "crowdsec", Until: now.Add(time.Hour)},
+			{Value: "198.51.100.7", Type: "ban", Scenario: "manual", Origin: "cscli", Until: now.Add(-time.Minute)},
+		},
+		CommunityDecisions: 15000,
+		Bouncers: []models.CrowdSecBouncer{
+			{Name: "firewall", Type: "crowdsec-firewall-bouncer", LastPull: now.Add(-time.Minute)},
+			{Name: "nginx"},
+		},
+	}
+	if err := db.StoreCrowdSecReport(ctx, testNetworkFlowsHostID, now.Add(-time.Minute), first); err != nil {
+		t.Fatalf("StoreCrowdSecReport: %v", err)
+	}
+
+	decisions, err := db.ListCrowdSecDecisions(ctx, "")
+	if err != nil {
+		t.Fatalf("ListCrowdSecDecisions: %v", err)
+	}
+	if len(decisions) != 1 || decisions[0].Value != "203.0.113.9" || decisions[0].HostName != "flows-test" ||
+		decisions[0].StartedAt != nil || decisions[0].Until == nil {
+		t.Errorf("decisions = %+v, want only the active one", decisions)
+	}
+
+	second := &models.CrowdSecReport{
+		Decisions:     []models.CrowdSecReportDecision{{Value: "192.0.2.44", Type: "captcha", Origin: "crowdsec"}},
+		BouncersError: "cscli bouncers list: permission denied",
+	}
+	if err := db.StoreCrowdSecReport(ctx, testNetworkFlowsHostID, now, second); err != nil {
+		t.Fatalf("StoreCrowdSecReport: %v", err)
+	}
+	if decisions, err := db.ListCrowdSecDecisions(ctx, "203.0.113.9"); err != nil || len(decisions) != 0 {
+		t.Errorf("a replaced decision must be gone, got %+v, %v", decisions, err)
+	}
+
+	bouncers, err := db.ListCrowdSecBouncers(ctx, testNetworkFlowsHostID)
+	if err != nil {
+		t.Fatalf("ListCrowdSecBouncers: %v", err)
+	}
+	if len(bouncers) != 2 || bouncers[0].Name != "firewall" || bouncers[0].LastPull.IsZero() ||
+		!bouncers[1].LastPull.IsZero() || !bouncers[0].ListedAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("bouncers = %+v, want the first list, listed a minute ago", bouncers)
+	}
+	if b, err := db.GetCrowdSecBouncer(ctx, testNetworkFlowsHostID, "nginx"); err != nil || b == nil {
+		t.Errorf("GetCrowdSecBouncer = %+v, %v", b, err)
+	}
+	if b, err := db.GetCrowdSecBouncer(ctx, testNetworkFlowsHostID, "gone"); err != nil || b != nil {
+		t.Errorf("an unknown bouncer is nil, got %+v, %v", b, err)
+	}
+
+	statuses, err := db.ListCrowdSecSyncStatuses(ctx)
+	if err != nil {
+		t.Fatalf("ListCrowdSecSyncStatuses: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[0].CollectedAt.Equal(now) || statuses[0].LocalDecisions != 1 ||
+		statuses[0].CommunityDecisions != 0 || statuses[0].BouncersError == "" {
+		t.Errorf("statuses = %+v", statuses)
+	}
+}
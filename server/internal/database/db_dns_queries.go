@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== DNS query metrics (agent L7 capture) ==========
+
+// InsertDNSQueryMetrics persists one report cycle's DNS sample: its totals row
+// plus one row per top queried name (agent-side top 20). No-op without
+// responses.
+func (db *DB) InsertDNSQueryMetrics(ctx context.Context, hostID string, at time.Time, stats *models.DNSQueryStats) error {
+	if stats == nil || stats.Responses == 0 {
+		return nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if _, err := db.conn.ExecContext(ctx,
+		`INSERT INTO dns_query_metrics (host_id, timestamp, is_total, responses, nxdomain)
+		VALUES ($1, $2, true, $3, $4)`,
+		hostID, at, stats.Responses, stats.NXDomain,
+	); err != nil {
+		return fmt.Errorf("failed to insert DNS query totals: %w", err)
+	}
+	for _, d := range stats.TopDomains {
+		if d.Name == "" || len(d.Name) > 255 { // longer than any valid DNS name
+			continue
+		}
+		if _, err := db.conn.ExecContext(ctx,
+			`INSERT INTO dns_query_metrics (host_id, timestamp, is_total, domain, responses, nxdomain)
+			VALUES ($1, $2, false, $3, $4, $5)`,
+			hostID, at, d.Name, d.Responses, d.NXDomain,
+		); err != nil {
+			return fmt.Errorf("failed to insert DNS query domain: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetDNSQueryReport sums a host's DNS samples over a window: totals and
+// NXDOMAIN rate, the limit most queried names, the limit names failing most,
+// and the bucketed responses/NXDOMAIN series. until being zero means "open
+// ended".
+func (db *DB) GetDNSQueryReport(ctx context.Context, hostID string, since, until time.Time, limit int) (*models.DNSQueryReport, error) {
+	args := []any{hostID, since}
+	where := "host_id = $1 AND timestamp > $2"
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(" AND timestamp <= $%d", len(args))
+	}
+
+	report := &models.DNSQueryReport{}
+	if err := db.conn.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(responses), 0), COALESCE(SUM(nxdomain), 0)
+		FROM dns_query_metrics WHERE is_total = true AND `+where,
+		args...,
+	).Scan(&report.Responses, &report.NXDomain); err != nil {
+		return nil, err
+	}
+	if report.Responses > 0 {
+		report.NXDomainRate = float64(report.NXDomain) * 100 / float64(report.Responses)
+	}
+
+	var err error
+	if report.TopDomains, err = db.dnsDomainTotals(ctx, where, args, "SUM(responses) DESC", "", limit); err != nil {
+		return nil, err
+	}
+	if report.TopNXDomains, err = db.dnsDomainTotals(ctx, where, args, "SUM(nxdomain) DESC", "HAVING SUM(nxdomain) > 0", limit); err != nil {
+		return nil, err
+	}
+
+	effectiveUntil := until
+	if effectiveUntil.IsZero() {
+		effectiveUntil = time.Now()
+	}
+	seriesArgs := append(append([]any{}, args...), historyBucketInterval(effectiveUntil.Sub(since)))
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT time_bucket($%d::interval, timestamp) AS bucket,
+			COALESCE(SUM(responses), 0), COALESCE(SUM(nxdomain), 0)
+		FROM dns_query_metrics
+		WHERE is_total = true AND %s
+		GROUP BY bucket
+		ORDER BY bucket ASC`, len(seriesArgs), where),
+		seriesArgs...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	report.Points = make([]models.DNSQueryPoint, 0)
+	for rows.Next() {
+		var p models.DNSQueryPoint
+		if err := rows.Scan(&p.Timestamp, &p.Responses, &p.NXDomain); err != nil {
+			return nil, err
+		}
+		report.Points = append(report.Points, p)
+	}
+	return report, rows.Err()
+}
+
+func (db *DB) dnsDomainTotals(ctx context.Context, where string, args []any, orderBy, having string, limit int) ([]models.DNSDomainTotal, error) {
+	args = append(append([]any{}, args...), limit)
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT domain, SUM(responses), SUM(nxdomain)
+		FROM dns_query_metrics
+		WHERE is_total = false AND %s
+		GROUP BY domain
+		%s
+		ORDER BY %s, domain ASC
+		LIMIT $%d`, where, having, orderBy, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]models.DNSDomainTotal, 0)
+	for rows.Next() {
+		var d models.DNSDomainTotal
+		if err := rows.Scan(&d.Name, &d.Responses, &d.NXDomain); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// CleanOldDNSQueryMetrics deletes DNS samples older than the network flows
+// retention window — queried names are as revealing as remote IPs, see
+// migration 111.
+func (db *DB) CleanOldDNSQueryMetrics(ctx context.Context, days int) (int64, error) {
+	if days <= 0 {
+		days = 14
+	}
+	res, err := db.conn.ExecContext(ctx,
+		`DELETE FROM dns_query_metrics WHERE "timestamp" < NOW() - ($1 || ' days')::INTERVAL`, days)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
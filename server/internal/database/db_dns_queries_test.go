@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+func TestGetDNSQueryReport_SumsCyclesAndRanksDomains(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	registerNetworkFlowsHost(t, db, ctx)
+
+	now := time.Now().Truncate(time.Second)
+	cycles := []*models.DNSQueryStats{
+		{Responses: 10, NXDomain: 2, TopDomains: []models.DNSDomainStat{
+			{Name: "api.example.com", Responses: 6},
+			{Name: "typo.example.con", Responses: 2, NXDomain: 2},
+		}},
+		{Responses: 10, NXDomain: 3, TopDomains: []models.DNSDomainStat{
+			{Name: "api.example.com", Responses: 3},
+			{Name: "typo.example.con", Responses: 3, NXDomain: 3},
+			{Name: "cdn.example.net", Responses: 4},
+		}},
+	}
+	for i, stats := range cycles {
+		at := now.Add(time.Duration(i-2) * time.Minute)
+		if err := db.InsertDNSQueryMetrics(ctx, testNetworkFlowsHostID, at, stats); err != nil {
+			t.Fatalf("InsertDNSQueryMetrics: %v", err)
+		}
+	}
+	if err := db.InsertDNSQueryMetrics(ctx, testNetworkFlowsHostID, now, &models.DNSQueryStats{}); err != nil {
+		t.Fatalf("an empty sample must be a no-op: %v", err)
+	}
+
+	report, err := db.GetDNSQueryReport(ctx, testNetworkFlowsHostID, now.Add(-time.Hour), time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("GetDNSQueryReport: %v", err)
+	}
+	if report.Responses != 20 || report.NXDomain != 5 || math.Abs(report.NXDomainRate-25) > 1e-9 {
+		t.Errorf("totals = %d responses, %d NXDOMAIN, %.2f%%", report.Responses, report.NXDomain, report.NXDomainRate)
+	}
+	if len(report.TopDomains) != 3 || report.TopDomains[0].Name != "api.example.com" || report.TopDomains[0].Responses != 9 {
+		t.Errorf("top domains = %+v", report.TopDomains)
+	}
+	if len(report.TopNXDomains) != 1 || report.TopNXDomains[0].Name != "typo.example.con" || report.TopNXDomains[0].NXDomain != 5 {
+		t.Errorf("top NXDOMAIN = %+v", report.TopNXDomains)
+	}
+	var points int64
+	for _, p := range report.Points {
+		points += p.Responses
+	}
+	if points != 20 {
+		t.Errorf("series sums to %d responses, want 20: %+v", points, report.Points)
+	}
+}
@@ -18,8 +18,8 @@ func (db *DB) UpsertDockerContainers(ctx context.Context, hostID string, contain
 		volumesJSON, _ := json.Marshal(c.Volumes)
 		networksJSON, _ := json.Marshal(c.Networks)
 		_, err := db.conn.ExecContext(ctx, `
-			INSERT INTO docker_containers (id, host_id, container_id, name, image, image_tag, image_id, image_digest, state, status, created, ports, labels, env_vars, volumes, networks, net_rx_bytes, net_tx_bytes, updated_at)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,NOW())
+			INSERT INTO docker_containers (id, host_id, container_id, name, image, image_tag, image_id, image_digest, state, status, created, ports, labels, env_vars, volumes, networks, ip_addresses, net_rx_bytes, net_tx_bytes, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,NOW())
 			ON CONFLICT (id) DO UPDATE SET
 				name         = EXCLUDED.name,
 				image        = EXCLUDED.image,
@@ -34,11 +34,12 @@ func (db *DB) UpsertDockerContainers(ctx context.Context, hostID string, contain
 				env_vars     = EXCLUDED.env_vars,
 				volumes      = EXCLUDED.volumes,
 				networks     = EXCLUDED.networks,
+				ip_addresses = EXCLUDED.ip_addresses,
 				net_rx_bytes = EXCLUDED.net_rx_bytes,
 				net_tx_bytes = EXCLUDED.net_tx_bytes,
 				updated_at   = NOW()`,
 			c.ID, hostID, c.ContainerID, c.Name, c.Image, c.ImageTag, c.ImageID, c.ImageDigest, c.State, c.Status, c.Created, c.Ports,
-			string(labelsJSON), string(envVarsJSON), string(volumesJSON), string(networksJSON),
+			string(labelsJSON), string(envVarsJSON), string(volumesJSON), string(networksJSON), marshalTags(c.IPAddresses),
 			c.NetRxBytes, c.NetTxBytes,
 		)
 		if err != nil {
@@ -61,7 +62,7 @@ func (db *DB) UpsertDockerContainers(ctx context.Context, hostID string, contain
 func (db *DB) GetDockerContainers(ctx context.Context, hostID string) ([]models.DockerContainer, error) {
 	rows, err := db.conn.QueryContext(ctx, 
 		`SELECT id, host_id, container_id, name, image, image_tag, image_id, image_digest, state, status, created, ports, labels,
-		 COALESCE(env_vars::text, '{}'), COALESCE(volumes::text, '[]'), COALESCE(networks::text, '[]'),
+		 COALESCE(env_vars::text, '{}'), COALESCE(volumes::text, '[]'), COALESCE(networks::text, '[]'), ip_addresses::text,
 		 COALESCE(net_rx_bytes, 0), COALESCE(net_tx_bytes, 0), updated_at
 		 FROM docker_containers WHERE host_id = $1 ORDER BY name`, hostID,
 	)
@@ -73,9 +74,9 @@ func (db *DB) GetDockerContainers(ctx context.Context, hostID string) ([]models.
 	var containers []models.DockerContainer
 	for rows.Next() {
 		var c models.DockerContainer
-		var labelsJSON, envVarsJSON, volumesJSON, networksJSON string
+		var labelsJSON, envVarsJSON, volumesJSON, networksJSON, ipsJSON string
 		if err := rows.Scan(&c.ID, &c.HostID, &c.ContainerID, &c.Name, &c.Image, &c.ImageTag, &c.ImageID, &c.ImageDigest,
-			&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON,
+			&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON, &ipsJSON,
 			&c.NetRxBytes, &c.NetTxBytes, &c.UpdatedAt); err != nil {
 			continue
 		}
@@ -83,6 +84,7 @@ func (db *DB) GetDockerContainers(ctx context.Context, hostID string) ([]models.
 		_ = json.Unmarshal([]byte(envVarsJSON), &c.EnvVars)
 		_ = json.Unmarshal([]byte(volumesJSON), &c.Volumes)
 		_ = json.Unmarshal([]byte(networksJSON), &c.Networks)
+		c.IPAddresses = parseTags(ipsJSON)
 		containers = append(containers, c)
 	}
 	return containers, nil
@@ -92,7 +94,7 @@ func (db *DB) GetAllDockerContainers(ctx context.Context) ([]models.DockerContai
 	rows, err := db.conn.QueryContext(ctx, 
 		`SELECT dc.id, dc.host_id, h.name, dc.container_id, dc.name, dc.image, dc.image_tag, dc.image_id, dc.image_digest,
 		 dc.state, dc.status, dc.created, dc.ports, dc.labels,
-		 COALESCE(dc.env_vars::text, '{}'), COALESCE(dc.volumes::text, '[]'), COALESCE(dc.networks::text, '[]'),
+		 COALESCE(dc.env_vars::text, '{}'), COALESCE(dc.volumes::text, '[]'), COALESCE(dc.networks::text, '[]'), dc.ip_addresses::text,
 		 COALESCE(dc.net_rx_bytes, 0), COALESCE(dc.net_tx_bytes, 0), dc.updated_at
 		 FROM docker_containers dc
 		 JOIN hosts h ON dc.host_id = h.id
@@ -106,9 +108,9 @@ func (db *DB) GetAllDockerContainers(ctx context.Context) ([]models.DockerContai
 	var containers []models.DockerContainer
 	for rows.Next() {
 		var c models.DockerContainer
-		var labelsJSON, envVarsJSON, volumesJSON, networksJSON string
+		var labelsJSON, envVarsJSON, volumesJSON, networksJSON, ipsJSON string
 		if err := rows.Scan(&c.ID, &c.HostID, &c.Hostname, &c.ContainerID, &c.Name, &c.Image, &c.ImageTag, &c.ImageID, &c.ImageDigest,
-			&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON,
+			&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON, &ipsJSON,
 			&c.NetRxBytes, &c.NetTxBytes, &c.UpdatedAt); err != nil {
 			continue
 		}
@@ -116,6 +118,7 @@ func (db *DB) GetAllDockerContainers(ctx context.Context) ([]models.DockerContai
 		_ = json.Unmarshal([]byte(envVarsJSON), &c.EnvVars)
 		_ = json.Unmarshal([]byte(volumesJSON), &c.Volumes)
 		_ = json.Unmarshal([]byte(networksJSON), &c.Networks)
+		c.IPAddresses = parseTags(ipsJSON)
 		containers = append(containers, c)
 	}
 	return containers, nil
@@ -168,14 +171,14 @@ func (db *DB) GetDockerNetworksByHost(ctx context.Context, hostID string) ([]mod
 // GetDockerContainerByID returns a single container by its DB UUID.
 func (db *DB) GetDockerContainerByID(ctx context.Context, id string) (*models.DockerContainer, error) {
 	var c models.DockerContainer
-	var labelsJSON, envVarsJSON, volumesJSON, networksJSON string
+	var labelsJSON, envVarsJSON, volumesJSON, networksJSON, ipsJSON string
 	err := db.conn.QueryRowContext(ctx,
 		`SELECT id, host_id, container_id, name, image, image_tag, image_id, image_digest, state, status, created, ports, labels,
-		 COALESCE(env_vars::text, '{}'), COALESCE(volumes::text, '[]'), COALESCE(networks::text, '[]'),
+		 COALESCE(env_vars::text, '{}'), COALESCE(volumes::text, '[]'), COALESCE(networks::text, '[]'), ip_addresses::text,
 		 COALESCE(net_rx_bytes, 0), COALESCE(net_tx_bytes, 0), updated_at
 		 FROM docker_containers WHERE id = $1`, id,
 	).Scan(&c.ID, &c.HostID, &c.ContainerID, &c.Name, &c.Image, &c.ImageTag, &c.ImageID, &c.ImageDigest,
-		&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON,
+		&c.State, &c.Status, &c.Created, &c.Ports, &labelsJSON, &envVarsJSON, &volumesJSON, &networksJSON, &ipsJSON,
 		&c.NetRxBytes, &c.NetTxBytes, &c.UpdatedAt)
 	if err != nil {
 		return nil, err
@@ -184,6 +187,7 @@ func (db *DB) GetDockerContainerByID(ctx context.Context, id string) (*models.Do
 	_ = json.Unmarshal([]byte(envVarsJSON), &c.EnvVars)
 	_ = json.Unmarshal([]byte(volumesJSON), &c.Volumes)
 	_ = json.Unmarshal([]byte(networksJSON), &c.Networks)
+	c.IPAddresses = parseTags(ipsJSON)
 	return &c, nil
 }
 
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Docker disk usage (`docker system df -v`) ==========
+
+// StoreDockerDiskUsage replaces a host's current top-consumers snapshot and
+// appends one history point per total and per reported volume/container, all
+// in one transaction so the snapshot and its history never disagree. Up to
+// ~150 item rows per call (agent-side top-N per kind), every ~15 minutes — a
+// per-row insert is plenty at that volume.
+func (db *DB) StoreDockerDiskUsage(ctx context.Context, hostID string, report *models.DockerDiskUsageReport) error {
+	if report == nil {
+		return nil
+	}
+	ts := report.CollectedAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	items := dockerDiskUsageItems(report, ts)
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM docker_disk_usage_items WHERE host_id = $1`, hostID); err != nil {
+		return fmt.Errorf("failed to delete old docker disk usage items: %w", err)
+	}
+	for _, it := range items {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docker_disk_usage_items (host_id, kind, name, detail, compose_project, size_bytes, collected_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (host_id, kind, name) DO UPDATE
+			   SET size_bytes = docker_disk_usage_items.size_bytes + EXCLUDED.size_bytes`,
+			hostID, it.Kind, it.Name, it.Detail, it.ComposeProject, it.SizeBytes, ts,
+		); err != nil {
+			return fmt.Errorf("failed to insert docker disk usage item: %w", err)
+		}
+		if it.Kind == models.DockerDiskKindImage {
+			continue // images never grow in place, see migration 096
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docker_disk_usage_metrics (host_id, "timestamp", kind, name, size_bytes)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			hostID, ts, it.Kind, it.Name, it.SizeBytes,
+		); err != nil {
+			return fmt.Errorf("failed to insert docker disk usage metric: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// dockerDiskUsageItems flattens a report into item rows: the four per-kind
+// totals (empty name) followed by every reported image, container and volume.
+// Volumes whose driver reports no size (-1) are skipped — they would only
+// pollute the ranking and the growth history. Dangling images all share the
+// "<none>" name and are summed into one row by the insert's ON CONFLICT.
+func dockerDiskUsageItems(r *models.DockerDiskUsageReport, ts time.Time) []models.DockerDiskUsageItem {
+	items := []models.DockerDiskUsageItem{
+		{Kind: models.DockerDiskKindImagesTotal, SizeBytes: r.ImagesSize, CollectedAt: ts},
+		{Kind: models.DockerDiskKindContainersTotal, SizeBytes: r.ContainersSize, CollectedAt: ts},
+		{Kind: models.DockerDiskKindVolumesTotal, SizeBytes: r.VolumesSize, CollectedAt: ts},
+		{Kind: models.DockerDiskKindBuildCacheTotal, SizeBytes: r.BuildCacheSize, Detail: fmt.Sprintf("%d", r.BuildCacheReclaimable), CollectedAt: ts},
+	}
+	for _, img := range r.Images {
+		items = append(items, models.DockerDiskUsageItem{
+			Kind: models.DockerDiskKindImage, Name: img.Image,
+			Detail: img.ImageID, SizeBytes: img.SizeBytes, CollectedAt: ts,
+		})
+	}
+	for _, c := range r.Containers {
+		items = append(items, models.DockerDiskUsageItem{
+			Kind: models.DockerDiskKindContainer, Name: c.Name, Detail: c.Image,
+			ComposeProject: c.ComposeProject, SizeBytes: c.SizeRwBytes, CollectedAt: ts,
+		})
+	}
+	for _, v := range r.Volumes {
+		if v.SizeBytes < 0 {
+			continue
+		}
+		items = append(items, models.DockerDiskUsageItem{
+			Kind: models.DockerDiskKindVolume, Name: v.Name, Detail: v.Driver,
+			ComposeProject: v.ComposeProject, SizeBytes: v.SizeBytes, CollectedAt: ts,
+		})
+	}
+	return items
+}
+
+// GetDockerDiskUsageSummary returns a host's current per-kind totals and its
+// `limit` largest images/containers/volumes. CollectedAt is nil when the host
+// never reported disk usage.
+func (db *DB) GetDockerDiskUsageSummary(ctx context.Context, hostID string, limit int) (*models.DockerDiskUsageSummary, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT kind, name, detail, compose_project, size_bytes, collected_at
+		 FROM docker_disk_usage_items
+		 WHERE host_id = $1
+		 ORDER BY size_bytes DESC`,
+		hostID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	summary := &models.DockerDiskUsageSummary{HostID: hostID, TopConsumers: []models.DockerDiskUsageItem{}}
+	for rows.Next() {
+		var it models.DockerDiskUsageItem
+		if err := rows.Scan(&it.Kind, &it.Name, &it.Detail, &it.ComposeProject, &it.SizeBytes, &it.CollectedAt); err != nil {
+			return nil, err
+		}
+		if summary.CollectedAt == nil {
+			at := it.CollectedAt
+			summary.CollectedAt = &at
+		}
+		switch it.Kind {
+		case models.DockerDiskKindImagesTotal:
+			summary.ImagesSize = it.SizeBytes
+		case models.DockerDiskKindContainersTotal:
+			summary.ContainersSize = it.SizeBytes
+		case models.DockerDiskKindVolumesTotal:
+			summary.VolumesSize = it.SizeBytes
+		case models.DockerDiskKindBuildCacheTotal:
+			summary.BuildCacheSize = it.SizeBytes
+			_, _ = fmt.Sscanf(it.Detail, "%d", &summary.BuildCacheReclaimable)
+		default:
+			if len(summary.TopConsumers) < limit {
+				summary.TopConsumers = append(summary.TopConsumers, it)
+			}
+		}
+	}
+	return summary, rows.Err()
+}
+
+// GetDockerDiskUsageHistory returns one kind total (empty name) or one
+// volume/container's size over time, bucketed with the same adaptive
+// granularity as the network-flow charts. Sizes are absolute, so each bucket
+// keeps its MAX. until being zero means "open ended".
+func (db *DB) GetDockerDiskUsageHistory(ctx context.Context, hostID, kind, name string, since, until time.Time) ([]models.DockerDiskUsagePoint, error) {
+	effectiveUntil := until
+	if effectiveUntil.IsZero() {
+		effectiveUntil = time.Now()
+	}
+
+	args := []any{historyBucketInterval(effectiveUntil.Sub(since)), hostID, kind, name, since}
+	where := `host_id = $2 AND kind = $3 AND name = $4 AND "timestamp" > $5`
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(` AND "timestamp" <= $%d`, len(args))
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT time_bucket($1::interval, "timestamp") AS bucket, MAX(size_bytes)
+		FROM docker_disk_usage_metrics
+		WHERE %s
+		GROUP BY bucket
+		ORDER BY bucket ASC`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var points []models.DockerDiskUsagePoint
+	for rows.Next() {
+		var p models.DockerDiskUsagePoint
+		if err := rows.Scan(&p.Timestamp, &p.SizeBytes); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// GetMaxDockerVolumeGrowth returns the largest growth (latest size minus
+// oldest size within the window) of any single volume on the host, and that
+// volume's name. ok is false when no volume has at least two samples in the
+// window yet — the alert engine's "no data" signal.
+func (db *DB) GetMaxDockerVolumeGrowth(ctx context.Context, hostID string, window time.Duration) (growth int64, volume string, ok bool, err error) {
+	err = db.conn.QueryRowContext(ctx,
+		`SELECT name,
+		        (ARRAY_AGG(size_bytes ORDER BY "timestamp" DESC))[1]
+		      - (ARRAY_AGG(size_bytes ORDER BY "timestamp" ASC))[1] AS growth
+		 FROM docker_disk_usage_metrics
+		 WHERE host_id = $1 AND kind = $2 AND "timestamp" > $3
+		 GROUP BY name
+		 HAVING COUNT(*) >= 2
+		 ORDER BY growth DESC
+		 LIMIT 1`,
+		hostID, models.DockerDiskKindVolume, time.Now().Add(-window),
+	).Scan(&volume, &growth)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	return growth, volume, true, nil
+}
+
+// GetTopDockerVolumeGrowth returns the `limit` volumes that grew the most
+// (latest size minus oldest size) across the fleet between since and until
+// (zero until = now), largest growth first. Volumes that shrank, stayed flat
+// or have a single sample in the window are left out.
+func (db *DB) GetTopDockerVolumeGrowth(ctx context.Context, since, until time.Time, limit int) ([]models.DockerVolumeGrowth, error) {
+	args := []any{models.DockerDiskKindVolume, since, limit}
+	where := `kind = $1 AND "timestamp" > $2`
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(` AND "timestamp" <= $%d`, len(args))
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT g.host_id, COALESCE(h.name, ''), g.name, COALESCE(i.compose_project, ''),
+		        g.size_bytes, g.growth
+		 FROM (
+		     SELECT host_id, name,
+		            (ARRAY_AGG(size_bytes ORDER BY "timestamp" DESC))[1] AS size_bytes,
+		            (ARRAY_AGG(size_bytes ORDER BY "timestamp" DESC))[1]
+		          - (ARRAY_AGG(size_bytes ORDER BY "timestamp" ASC))[1] AS growth
+		     FROM docker_disk_usage_metrics
+		     WHERE %s
+		     GROUP BY host_id, name
+		     HAVING COUNT(*) >= 2
+		 ) g
+		 LEFT JOIN hosts h ON h.id = g.host_id
+		 LEFT JOIN docker_disk_usage_items i ON i.host_id = g.host_id AND i.kind = $1 AND i.name = g.name
+		 WHERE g.growth > 0
+		 ORDER BY g.growth DESC
+		 LIMIT $3`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []models.DockerVolumeGrowth
+	for rows.Next() {
+		var g models.DockerVolumeGrowth
+		if err := rows.Scan(&g.HostID, &g.Hostname, &g.Name, &g.ComposeProject, &g.SizeBytes, &g.GrowthBytes); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,105 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestDockerDiskUsage_RoundTrip stores two measurements and checks that the
+// snapshot is replaced (not accumulated), dangling images are summed into one
+// row, unknown-size volumes are skipped, and the volume growth alert value is
+// last - first.
+func TestDockerDiskUsage_RoundTrip(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	const hostID = "host-docker-disk-test"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "disk-test", Hostname: "disk.local", Status: "online",
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+
+	if _, _, ok, err := db.GetMaxDockerVolumeGrowth(ctx, hostID, 24*time.Hour); err != nil || ok {
+		t.Fatalf("growth before any data: ok=%v err=%v", ok, err)
+	}
+
+	report := func(at time.Time, dataSize int64) *models.DockerDiskUsageReport {
+		return &models.DockerDiskUsageReport{
+			CollectedAt: at, ImagesSize: 3000, ContainersSize: 50, VolumesSize: dataSize,
+			BuildCacheSize: 1000, BuildCacheReclaimable: 700,
+			Images: []models.DockerImageUsage{
+				{ImageID: "sha256:a", Image: "<none>", SizeBytes: 2000},
+				{ImageID: "sha256:b", Image: "<none>", SizeBytes: 500},
+				{ImageID: "sha256:c", Image: "nginx:1.25", SizeBytes: 500},
+			},
+			Containers: []models.DockerContainerUsage{{Name: "web", Image: "nginx:1.25", SizeRwBytes: 50}},
+			Volumes: []models.DockerVolumeUsage{
+				{Name: "site_data", Driver: "local", ComposeProject: "site", SizeBytes: dataSize},
+				{Name: "nfs", Driver: "nfs", SizeBytes: -1},
+			},
+		}
+	}
+	first := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	if err := db.StoreDockerDiskUsage(ctx, hostID, report(first, 1000)); err != nil {
+		t.Fatalf("store #1: %v", err)
+	}
+	if err := db.StoreDockerDiskUsage(ctx, hostID, report(first.Add(time.Hour), 5000)); err != nil {
+		t.Fatalf("store #2: %v", err)
+	}
+
+	summary, err := db.GetDockerDiskUsageSummary(ctx, hostID, 10)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.CollectedAt == nil || summary.VolumesSize != 5000 || summary.BuildCacheReclaimable != 700 {
+		t.Fatalf("summary totals = %+v", summary)
+	}
+	if len(summary.TopConsumers) != 4 {
+		t.Fatalf("top consumers = %d rows, want 4 (nfs skipped, <none> merged): %+v", len(summary.TopConsumers), summary.TopConsumers)
+	}
+	if top := summary.TopConsumers[0]; top.Kind != models.DockerDiskKindVolume || top.Name != "site_data" {
+		t.Errorf("largest consumer = %+v", top)
+	}
+	for _, it := range summary.TopConsumers {
+		if it.Name == "<none>" && it.SizeBytes != 2500 {
+			t.Errorf("dangling images = %d, want 2500", it.SizeBytes)
+		}
+	}
+
+	points, err := db.GetDockerDiskUsageHistory(ctx, hostID, models.DockerDiskKindVolume, "site_data", first.Add(-time.Minute), time.Time{})
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("history = %d points, want 2", len(points))
+	}
+
+	growth, volume, ok, err := db.GetMaxDockerVolumeGrowth(ctx, hostID, 24*time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("growth: ok=%v err=%v", ok, err)
+	}
+	if growth != 4000 || volume != "site_data" {
+		t.Errorf("growth = %d on %q, want 4000 on site_data", growth, volume)
+	}
+
+	top, err := db.GetTopDockerVolumeGrowth(ctx, time.Now().Add(-24*time.Hour), time.Time{}, 50)
+	if err != nil {
+		t.Fatalf("top growth: %v", err)
+	}
+	found := false
+	for _, g := range top {
+		if g.HostID == hostID {
+			found = true
+			if g.Name != "site_data" || g.GrowthBytes != 4000 || g.SizeBytes != 5000 || g.ComposeProject != "site" || g.Hostname != "disk-test" {
+				t.Errorf("top growth row = %+v", g)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("site_data missing from the fleet top growth: %+v", top)
+	}
+}
@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Docker Swarm ==========
+
+// StoreDockerSwarm replaces a manager host's Swarm snapshot (services, tasks,
+// nodes) in one transaction. A report with Manager false deletes the host's
+// cluster row, which cascades to everything else — the host was demoted, left
+// the cluster, or never was a manager.
+func (db *DB) StoreDockerSwarm(ctx context.Context, hostID string, report *models.DockerSwarmReport) error {
+	if report == nil {
+		return nil
+	}
+	if !report.Manager {
+		_, err := db.conn.ExecContext(ctx, `DELETE FROM docker_swarm_clusters WHERE host_id = $1`, hostID)
+		return err
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO docker_swarm_clusters (host_id, cluster_id, node_id, updated_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (host_id) DO UPDATE
+		   SET cluster_id = EXCLUDED.cluster_id, node_id = EXCLUDED.node_id, updated_at = NOW()`,
+		hostID, report.ClusterID, report.NodeID,
+	); err != nil {
+		return fmt.Errorf("failed to upsert swarm cluster: %w", err)
+	}
+	for _, table := range []string{"docker_swarm_services", "docker_swarm_tasks", "docker_swarm_nodes"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE host_id = $1`, hostID); err != nil {
+			return fmt.Errorf("failed to delete old %s: %w", table, err)
+		}
+	}
+
+	for _, s := range report.Services {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docker_swarm_services
+			   (host_id, service_id, name, image, mode, stack, desired_replicas, running_replicas, update_state)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (host_id, service_id) DO NOTHING`,
+			hostID, s.ServiceID, s.Name, s.Image, s.Mode, s.Stack, s.DesiredReplicas, s.RunningReplicas, s.UpdateState,
+		); err != nil {
+			return fmt.Errorf("failed to insert swarm service: %w", err)
+		}
+	}
+	for _, t := range report.Tasks {
+		var updatedAt *time.Time
+		if !t.UpdatedAt.IsZero() {
+			updatedAt = &t.UpdatedAt
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docker_swarm_tasks
+			   (host_id, task_id, service_id, service_name, node_id, slot, state, desired_state, message, error, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			 ON CONFLICT (host_id, task_id) DO NOTHING`,
+			hostID, t.TaskID, t.ServiceID, t.ServiceName, t.NodeID, t.Slot, t.State, t.DesiredState, t.Message, t.Error, updatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to insert swarm task: %w", err)
+		}
+	}
+	for _, n := range report.Nodes {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO docker_swarm_nodes
+			   (host_id, node_id, hostname, role, availability, state, leader, reachability, addr, engine_version)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			 ON CONFLICT (host_id, node_id) DO NOTHING`,
+			hostID, n.NodeID, n.Hostname, n.Role, n.Availability, n.State, n.Leader, n.Reachability, n.Addr, n.EngineVersion,
+		); err != nil {
+			return fmt.Errorf("failed to insert swarm node: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDockerSwarmState returns the Swarm cluster as last reported by a manager
+// host. Lists are never nil; UpdatedAt is nil when the host is not a manager.
+func (db *DB) GetDockerSwarmState(ctx context.Context, hostID string) (*models.DockerSwarmState, error) {
+	state := &models.DockerSwarmState{
+		HostID:   hostID,
+		Services: []models.SwarmService{},
+		Tasks:    []models.SwarmTask{},
+		Nodes:    []models.SwarmNode{},
+	}
+	var updatedAt time.Time
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT cluster_id, node_id, updated_at FROM docker_swarm_clusters WHERE host_id = $1`, hostID,
+	).Scan(&state.ClusterID, &state.NodeID, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state.UpdatedAt = &updatedAt
+
+	if state.Services, err = db.ListDockerSwarmServices(ctx, hostID); err != nil {
+		return nil, err
+	}
+	if state.Nodes, err = db.ListDockerSwarmNodes(ctx, hostID); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT task_id, service_id, service_name, node_id, slot, state, desired_state, message, error,
+		        COALESCE(updated_at, 'epoch'::timestamptz)
+		 FROM docker_swarm_tasks WHERE host_id = $1
+		 ORDER BY service_name, slot, updated_at DESC`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var t models.SwarmTask
+		if err := rows.Scan(&t.TaskID, &t.ServiceID, &t.ServiceName, &t.NodeID, &t.Slot, &t.State,
+			&t.DesiredState, &t.Message, &t.Error, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		state.Tasks = append(state.Tasks, t)
+	}
+	return state, rows.Err()
+}
+
+// ListDockerSwarmServices returns a manager host's Swarm services by name (never nil).
+func (db *DB) ListDockerSwarmServices(ctx context.Context, hostID string) ([]models.SwarmService, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT service_id, name, image, mode, stack, desired_replicas, running_replicas, update_state
+		 FROM docker_swarm_services WHERE host_id = $1 ORDER BY name`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	services := []models.SwarmService{}
+	for rows.Next() {
+		var s models.SwarmService
+		if err := rows.Scan(&s.ServiceID, &s.Name, &s.Image, &s.Mode, &s.Stack,
+			&s.DesiredReplicas, &s.RunningReplicas, &s.UpdateState); err != nil {
+			return nil, err
+		}
+		services = append(services, s)
+	}
+	return services, rows.Err()
+}
+
+// ListDockerSwarmNodes returns the Swarm nodes seen by a manager host, by
+// hostname (never nil).
+func (db *DB) ListDockerSwarmNodes(ctx context.Context, hostID string) ([]models.SwarmNode, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT node_id, hostname, role, availability, state, leader, reachability, addr, engine_version
+		 FROM docker_swarm_nodes WHERE host_id = $1 ORDER BY hostname`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	nodes := []models.SwarmNode{}
+	for rows.Next() {
+		var n models.SwarmNode
+		if err := rows.Scan(&n.NodeID, &n.Hostname, &n.Role, &n.Availability, &n.State,
+			&n.Leader, &n.Reachability, &n.Addr, &n.EngineVersion); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// GetDockerSwarmService returns one service of a manager host by name.
+func (db *DB) GetDockerSwarmService(ctx context.Context, hostID, name string) (*models.SwarmService, error) {
+	var s models.SwarmService
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT service_id, name, image, mode, stack, desired_replicas, running_replicas, update_state
+		 FROM docker_swarm_services WHERE host_id = $1 AND name = $2`, hostID, name,
+	).Scan(&s.ServiceID, &s.Name, &s.Image, &s.Mode, &s.Stack, &s.DesiredReplicas, &s.RunningReplicas, &s.UpdateState)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetDockerSwarmNode returns one Swarm node as seen by a manager host.
+func (db *DB) GetDockerSwarmNode(ctx context.Context, hostID, nodeID string) (*models.SwarmNode, error) {
+	var n models.SwarmNode
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT node_id, hostname, role, availability, state, leader, reachability, addr, engine_version
+		 FROM docker_swarm_nodes WHERE host_id = $1 AND node_id = $2`, hostID, nodeID,
+	).Scan(&n.NodeID, &n.Hostname, &n.Role, &n.Availability, &n.State, &n.Leader, &n.Reachability, &n.Addr, &n.EngineVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
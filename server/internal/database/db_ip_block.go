@@ -0,0 +1,211 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const ipBlockPolicyColumns = `id, name, enabled, min_score, window_minutes, ban_duration, dry_run, allowlist::text, notify_channels::text, created_by, created_at, updated_at, last_run_at`
+
+func scanIPBlockPolicy(row interface{ Scan(...any) error }) (*models.IPBlockPolicy, error) {
+	var p models.IPBlockPolicy
+	var allowlist, channels string
+	var lastRun sql.NullTime
+	if err := row.Scan(&p.ID, &p.Name, &p.Enabled, &p.MinScore, &p.WindowMinutes, &p.BanDuration, &p.DryRun,
+		&allowlist, &channels, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt, &lastRun); err != nil {
+		return nil, err
+	}
+	p.Allowlist = parseTags(allowlist)
+	p.NotifyChannels = parseTags(channels)
+	if lastRun.Valid {
+		p.LastRunAt = &lastRun.Time
+	}
+	return &p, nil
+}
+
+// ListIPBlockPolicies returns every IP blocking policy, oldest first.
+func (db *DB) ListIPBlockPolicies(ctx context.Context) ([]models.IPBlockPolicy, error) {
+	rows, err := db.conn.QueryContext(ctx, `SELECT `+ipBlockPolicyColumns+` FROM ip_block_policies ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.IPBlockPolicy, 0)
+	for rows.Next() {
+		p, err := scanIPBlockPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// GetIPBlockPolicy returns one policy, or sql.ErrNoRows.
+func (db *DB) GetIPBlockPolicy(ctx context.Context, id string) (*models.IPBlockPolicy, error) {
+	return scanIPBlockPolicy(db.conn.QueryRowContext(ctx,
+		`SELECT `+ipBlockPolicyColumns+` FROM ip_block_policies WHERE id = $1`, id))
+}
+
+// CreateIPBlockPolicy inserts a policy and returns it with its id.
+func (db *DB) CreateIPBlockPolicy(ctx context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error) {
+	return scanIPBlockPolicy(db.conn.QueryRowContext(ctx,
+		`INSERT INTO ip_block_policies (name, enabled, min_score, window_minutes, ban_duration, dry_run, allowlist, notify_channels, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING `+ipBlockPolicyColumns,
+		p.Name, p.Enabled, p.MinScore, p.WindowMinutes, p.BanDuration, p.DryRun,
+		marshalTags(p.Allowlist), marshalTags(p.NotifyChannels), p.CreatedBy))
+}
+
+// UpdateIPBlockPolicy saves every editable field of p. Returns sql.ErrNoRows
+// when the policy no longer exists.
+func (db *DB) UpdateIPBlockPolicy(ctx context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error) {
+	return scanIPBlockPolicy(db.conn.QueryRowContext(ctx,
+		`UPDATE ip_block_policies
+		 SET name = $2, enabled = $3, min_score = $4, window_minutes = $5, ban_duration = $6, dry_run = $7,
+		     allowlist = $8, notify_channels = $9, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+ipBlockPolicyColumns,
+		p.ID, p.Name, p.Enabled, p.MinScore, p.WindowMinutes, p.BanDuration, p.DryRun,
+		marshalTags(p.Allowlist), marshalTags(p.NotifyChannels)))
+}
+
+// DeleteIPBlockPolicy removes a policy and reports whether it existed. Its
+// decisions stay in the trail, under the policy name.
+func (db *DB) DeleteIPBlockPolicy(ctx context.Context, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, `DELETE FROM ip_block_policies WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClaimIPBlockPolicyRun records that the policy is being evaluated at `at`,
+// unless it already ran after notBefore. The conditional UPDATE is atomic
+// (a concurrent claim re-checks last_run_at once the first commits), so of
+// two overlapping leaders only one evaluates the policy; false means another
+// replica just did.
+func (db *DB) ClaimIPBlockPolicyRun(ctx context.Context, id string, at, notBefore time.Time) (bool, error) {
+	res, err := db.conn.ExecContext(ctx,
+		`UPDATE ip_block_policies SET last_run_at = $2
+		 WHERE id = $1 AND (last_run_at IS NULL OR last_run_at <= $3)`, id, at, notBefore)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListCrowdSecHostIDs returns the hosts whose agent reported it can apply
+// CrowdSec decisions.
+func (db *DB) ListCrowdSecHostIDs(ctx context.Context) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id FROM hosts WHERE (collectors->>'crowdsec')::boolean IS TRUE ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// HasOpenIPBlockDecision reports whether ip already has a decision that has
+// not expired: any policy's ban (even reverted, so an admin's revert is not
+// undone by the next run) or a dry run of this policy. A failed decision
+// banned nothing, so the next run retries it.
+func (db *DB) HasOpenIPBlockDecision(ctx context.Context, ip, policyID string, now time.Time) (bool, error) {
+	var exists bool
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT EXISTS (
+		   SELECT 1 FROM ip_block_decisions
+		   WHERE ip = $1 AND expires_at > $3
+		     AND status <> 'failed'
+		     AND (status <> 'dry_run' OR policy_id = $2)
+		 )`, ip, policyID, now).Scan(&exists)
+	return exists, err
+}
+
+const ipBlockDecisionColumns = `id, COALESCE(policy_id::text, ''), policy_name, ip, score, level, hits, unique_paths, host_count, duration, status, error, target_host_ids::text, command_ids::text, created_at, expires_at, reverted_at, reverted_by`
+
+func scanIPBlockDecision(row interface{ Scan(...any) error }) (*models.IPBlockDecision, error) {
+	var d models.IPBlockDecision
+	var targets, commands string
+	var reverted sql.NullTime
+	if err := row.Scan(&d.ID, &d.PolicyID, &d.PolicyName, &d.IP, &d.Score, &d.Level, &d.Hits, &d.UniquePaths, &d.HostCount,
+		&d.Duration, &d.Status, &d.Error, &targets, &commands, &d.CreatedAt, &d.ExpiresAt, &reverted, &d.RevertedBy); err != nil {
+		return nil, err
+	}
+	d.TargetHostIDs = parseTags(targets)
+	d.CommandIDs = parseTags(commands)
+	if reverted.Valid {
+		d.RevertedAt = &reverted.Time
+	}
+	return &d, nil
+}
+
+// CreateIPBlockDecision records an automatic decision.
+func (db *DB) CreateIPBlockDecision(ctx context.Context, d *models.IPBlockDecision) (*models.IPBlockDecision, error) {
+	return scanIPBlockDecision(db.conn.QueryRowContext(ctx,
+		`INSERT INTO ip_block_decisions (policy_id, policy_name, ip, score, level, hits, unique_paths, host_count, duration, status, error, target_host_ids, command_ids, created_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		 RETURNING `+ipBlockDecisionColumns,
+		d.PolicyID, d.PolicyName, d.IP, d.Score, d.Level, d.Hits, d.UniquePaths, d.HostCount, d.Duration, d.Status, d.Error,
+		marshalTags(d.TargetHostIDs), marshalTags(d.CommandIDs), d.CreatedAt, d.ExpiresAt))
+}
+
+// ListIPBlockDecisions returns the latest decisions, newest first, optionally
+// for one IP.
+func (db *DB) ListIPBlockDecisions(ctx context.Context, ip string, limit int) ([]models.IPBlockDecision, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+ipBlockDecisionColumns+` FROM ip_block_decisions
+		 WHERE ($1 = '' OR ip = $1)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $2`, ip, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.IPBlockDecision, 0)
+	for rows.Next() {
+		d, err := scanIPBlockDecision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *d)
+	}
+	return out, rows.Err()
+}
+
+// GetIPBlockDecision returns one decision, or sql.ErrNoRows.
+func (db *DB) GetIPBlockDecision(ctx context.Context, id int64) (*models.IPBlockDecision, error) {
+	return scanIPBlockDecision(db.conn.QueryRowContext(ctx,
+		`SELECT `+ipBlockDecisionColumns+` FROM ip_block_decisions WHERE id = $1`, id))
+}
+
+// MarkIPBlockDecisionReverted flags an applied decision as reverted, once.
+// failures, when not empty, replaces the decision's error with the hosts the
+// unban could not be dispatched to. Returns sql.ErrNoRows when it is not (or
+// no longer) applied.
+func (db *DB) MarkIPBlockDecisionReverted(ctx context.Context, id int64, by string, commandIDs []string, failures string) (*models.IPBlockDecision, error) {
+	return scanIPBlockDecision(db.conn.QueryRowContext(ctx,
+		`UPDATE ip_block_decisions
+		 SET status = 'reverted', reverted_at = NOW(), reverted_by = $2,
+		     command_ids = command_ids || $3::jsonb,
+		     error = CASE WHEN $4 = '' THEN error ELSE $4 END
+		 WHERE id = $1 AND status = 'applied'
+		 RETURNING `+ipBlockDecisionColumns, id, by, marshalTags(commandIDs), failures))
+}
@@ -0,0 +1,80 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestIPBlock_DecisionTrail checks the CrowdSec host selection, the open
+// decision lookup that keeps policies from re-banning, and that only an
+// applied decision can be reverted.
+func TestIPBlock_DecisionTrail(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"cs-host", "plain-host"} {
+		if err := db.RegisterHost(ctx, &models.Host{
+			ID: id, Name: id, Hostname: id + ".local", IPAddress: "10.0.0.30", Status: "online",
+		}); err != nil {
+			t.Fatalf("register host: %v", err)
+		}
+	}
+	if err := db.UpdateHostCollectors(ctx, "cs-host", `{"crowdsec":true,"web_logs":true}`); err != nil {
+		t.Fatalf("update collectors: %v", err)
+	}
+	if ids, err := db.ListCrowdSecHostIDs(ctx); err != nil || len(ids) != 1 || ids[0] != "cs-host" {
+		t.Fatalf("crowdsec hosts = %v, %v", ids, err)
+	}
+
+	p, err := db.CreateIPBlockPolicy(ctx, &models.IPBlockPolicy{
+		Name: "Scanners", Enabled: true, MinScore: 50, WindowMinutes: 10, BanDuration: "4h",
+		Allowlist: []string{"192.0.2.0/24"}, NotifyChannels: []string{"browser"}, CreatedBy: "admin",
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	if len(p.Allowlist) != 1 || p.DryRun {
+		t.Errorf("policy = %+v", p)
+	}
+
+	now := time.Now().UTC()
+	d, err := db.CreateIPBlockDecision(ctx, &models.IPBlockDecision{
+		PolicyID: p.ID, PolicyName: p.Name, IP: "203.0.113.9", Score: 80, Level: "critical",
+		Duration: "4h", Status: models.IPBlockStatusApplied,
+		TargetHostIDs: []string{"cs-host"}, CommandIDs: []string{"cmd-1"},
+		CreatedAt: now, ExpiresAt: now.Add(4 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("create decision: %v", err)
+	}
+	if open, err := db.HasOpenIPBlockDecision(ctx, "203.0.113.9", "another-policy", now); err != nil || !open {
+		t.Errorf("open decision for another policy = %v, %v; want true", open, err)
+	}
+	if open, _ := db.HasOpenIPBlockDecision(ctx, "203.0.113.9", p.ID, now.Add(5*time.Hour)); open {
+		t.Error("an expired decision must not block a new one")
+	}
+
+	r, err := db.MarkIPBlockDecisionReverted(ctx, d.ID, "alice", []string{"cmd-2"}, "")
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if r.Status != models.IPBlockStatusReverted || r.RevertedAt == nil || len(r.CommandIDs) != 2 {
+		t.Errorf("reverted = %+v", r)
+	}
+	if _, err := db.MarkIPBlockDecisionReverted(ctx, d.ID, "alice", nil, ""); err == nil {
+		t.Error("reverting twice must fail")
+	}
+
+	// Deleting the policy keeps its decisions in the trail.
+	if ok, err := db.DeleteIPBlockPolicy(ctx, p.ID); err != nil || !ok {
+		t.Fatalf("delete policy: %v, %v", ok, err)
+	}
+	list, err := db.ListIPBlockDecisions(ctx, "203.0.113.9", 10)
+	if err != nil || len(list) != 1 || list[0].PolicyID != "" || list[0].PolicyName != "Scanners" {
+		t.Errorf("trail = %+v, %v", list, err)
+	}
+}
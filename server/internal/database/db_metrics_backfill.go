@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// GetMetricsTimestamps returns the system_metrics timestamps stored for hostID
+// between from and to (inclusive), as Unix microseconds — the precision
+// Postgres keeps. Used to make a replayed backfill batch idempotent.
+func (db *DB) GetMetricsTimestamps(ctx context.Context, hostID string, from, to time.Time) (map[int64]bool, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT timestamp FROM system_metrics
+		 WHERE host_id = $1 AND timestamp BETWEEN $2 AND $3`,
+		hostID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[int64]bool)
+	for rows.Next() {
+		var ts time.Time
+		if err := rows.Scan(&ts); err != nil {
+			return nil, err
+		}
+		out[ts.UnixMicro()] = true
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Per-interface network metrics ==========
+
+// netInterfaceDownStates is the SQL form of models.NetInterfaceDown.
+const netInterfaceDownStates = `('down', 'lowerlayerdown', 'notpresent')`
+
+// netInterfaceDeltas is a CTE body turning net_interface_metrics' cumulative
+// counters into per-sample deltas against the interface's previous sample in
+// the selection (d_* columns, secs). valid is false for an interface's first
+// sample and across a counter reset, whose interval carries no usable delta.
+func netInterfaceDeltas(where string) string {
+	return `s AS (
+			SELECT *,
+				rx_bytes - LAG(rx_bytes) OVER w AS d_rx_bytes,
+				tx_bytes - LAG(tx_bytes) OVER w AS d_tx_bytes,
+				rx_packets - LAG(rx_packets) OVER w AS d_rx_packets,
+				tx_packets - LAG(tx_packets) OVER w AS d_tx_packets,
+				(rx_errors + tx_errors) - LAG(rx_errors + tx_errors) OVER w AS d_errors,
+				(rx_dropped + tx_dropped) - LAG(rx_dropped + tx_dropped) OVER w AS d_drops,
+				EXTRACT(EPOCH FROM "timestamp" - LAG("timestamp") OVER w)::float8 AS secs
+			FROM net_interface_metrics
+			WHERE ` + where + `
+			WINDOW w AS (PARTITION BY name ORDER BY "timestamp")
+		), d AS (
+			SELECT *, COALESCE(secs > 0 AND d_rx_bytes >= 0 AND d_tx_bytes >= 0 AND d_rx_packets >= 0
+				AND d_tx_packets >= 0 AND d_errors >= 0 AND d_drops >= 0, false) AS valid
+			FROM s
+		)`
+}
+
+// InsertNetInterfaceMetrics persists one report cycle's interfaces, all under
+// the same timestamp so the latest cycle can be read back as a whole.
+func (db *DB) InsertNetInterfaceMetrics(ctx context.Context, hostID string, at time.Time, ifaces []models.NetInterface) error {
+	if len(ifaces) == 0 {
+		return nil
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	for _, i := range ifaces {
+		if i.Name == "" || len(i.Name) > 64 {
+			continue
+		}
+		members := i.Members
+		if members == nil {
+			members = []string{}
+		}
+		if _, err := db.conn.ExecContext(ctx,
+			`INSERT INTO net_interface_metrics (
+				host_id, "timestamp", name, kind, operstate, speed_mbps, duplex, mtu,
+				master, parent, vlan_id, members,
+				rx_bytes, tx_bytes, rx_packets, tx_packets, rx_errors, tx_errors, rx_dropped, tx_dropped
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+			hostID, at, i.Name, i.Kind, i.OperState, i.SpeedMbps, i.Duplex, i.MTU,
+			i.Master, i.Parent, i.VLANID, pq.Array(members),
+			int64(i.RxBytes), int64(i.TxBytes), int64(i.RxPackets), int64(i.TxPackets),
+			int64(i.RxErrors), int64(i.TxErrors), int64(i.RxDropped), int64(i.TxDropped),
+		); err != nil {
+			return fmt.Errorf("failed to insert net interface metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetLatestNetInterfaces returns the interfaces of a host's latest report
+// cycle, with rates against each one's previous sample (looked up to an hour
+// back).
+func (db *DB) GetLatestNetInterfaces(ctx context.Context, hostID string) ([]models.NetInterfaceStatus, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`WITH latest AS (
+			SELECT MAX("timestamp") AS ts FROM net_interface_metrics WHERE host_id = $1
+		), `+netInterfaceDeltas(`host_id = $1 AND "timestamp" > (SELECT ts FROM latest) - INTERVAL '1 hour'`)+`
+		SELECT name, kind, operstate, speed_mbps, duplex, mtu, master, parent, vlan_id, members,
+			rx_bytes, tx_bytes, rx_packets, tx_packets, rx_errors, tx_errors, rx_dropped, tx_dropped,
+			"timestamp", valid,
+			COALESCE(d_rx_bytes, 0), COALESCE(d_tx_bytes, 0), COALESCE(d_rx_packets, 0),
+			COALESCE(d_tx_packets, 0), COALESCE(d_errors, 0), COALESCE(d_drops, 0), COALESCE(secs, 0)
+		FROM d
+		WHERE "timestamp" = (SELECT ts FROM latest)
+		ORDER BY name ASC`,
+		hostID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.NetInterfaceStatus, 0)
+	for rows.Next() {
+		var (
+			s                                        models.NetInterfaceStatus
+			valid                                    bool
+			dRx, dTx, dRxPkts, dTxPkts, dErrs, dDrop int64
+			secs                                     float64
+		)
+		if err := rows.Scan(&s.Name, &s.Kind, &s.OperState, &s.SpeedMbps, &s.Duplex, &s.MTU,
+			&s.Master, &s.Parent, &s.VLANID, pq.Array(&s.Members),
+			&s.RxBytes, &s.TxBytes, &s.RxPackets, &s.TxPackets, &s.RxErrors, &s.TxErrors, &s.RxDropped, &s.TxDropped,
+			&s.Timestamp, &valid, &dRx, &dTx, &dRxPkts, &dTxPkts, &dErrs, &dDrop, &secs); err != nil {
+			return nil, err
+		}
+		if valid {
+			s.RxBytesPerSec = float64(dRx) / secs
+			s.TxBytesPerSec = float64(dTx) / secs
+			s.RxPacketsPerSec = float64(dRxPkts) / secs
+			s.TxPacketsPerSec = float64(dTxPkts) / secs
+			s.ErrorsPerSec = float64(dErrs) / secs
+			s.DropsPerSec = float64(dDrop) / secs
+			s.UtilizationPercent = models.NetInterfaceUtilization(s.RxBytesPerSec, s.TxBytesPerSec, s.SpeedMbps)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// GetNetInterfaceHistory buckets one interface's rates over a window. until
+// being zero means "open ended".
+func (db *DB) GetNetInterfaceHistory(ctx context.Context, hostID, name string, since, until time.Time) (*models.NetInterfaceHistory, error) {
+	args := []any{hostID, name, since}
+	where := `host_id = $1 AND name = $2 AND "timestamp" > $3`
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(` AND "timestamp" <= $%d`, len(args))
+	}
+	effectiveUntil := until
+	if effectiveUntil.IsZero() {
+		effectiveUntil = time.Now()
+	}
+	args = append(args, historyBucketInterval(effectiveUntil.Sub(since)))
+
+	rows, err := db.conn.QueryContext(ctx,
+		`WITH `+netInterfaceDeltas(where)+fmt.Sprintf(`
+		SELECT time_bucket($%d::interval, "timestamp") AS bucket,
+			COALESCE(SUM(secs) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_rx_bytes) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_tx_bytes) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_rx_packets) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_tx_packets) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_errors) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_drops) FILTER (WHERE valid), 0),
+			MAX(speed_mbps),
+			BOOL_OR(operstate IN %s)
+		FROM d
+		GROUP BY bucket
+		ORDER BY bucket ASC`, len(args), netInterfaceDownStates),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	history := &models.NetInterfaceHistory{Name: name, Points: make([]models.NetInterfacePoint, 0)}
+	for rows.Next() {
+		var (
+			p                                        models.NetInterfacePoint
+			secs                                     float64
+			dRx, dTx, dRxPkts, dTxPkts, dErrs, dDrop int64
+			speed                                    int
+		)
+		if err := rows.Scan(&p.Timestamp, &secs, &dRx, &dTx, &dRxPkts, &dTxPkts, &dErrs, &dDrop, &speed, &p.Down); err != nil {
+			return nil, err
+		}
+		if secs > 0 {
+			p.RxBytesPerSec = float64(dRx) / secs
+			p.TxBytesPerSec = float64(dTx) / secs
+			p.RxPacketsPerSec = float64(dRxPkts) / secs
+			p.TxPacketsPerSec = float64(dTxPkts) / secs
+			p.ErrorsPerSec = float64(dErrs) / secs
+			p.DropsPerSec = float64(dDrop) / secs
+			p.UtilizationPercent = models.NetInterfaceUtilization(p.RxBytesPerSec, p.TxBytesPerSec, speed)
+		}
+		history.Points = append(history.Points, p)
+	}
+	return history, rows.Err()
+}
+
+// GetNetInterfaceWindow sums one interface's counter deltas over the last
+// window and reports its latest operstate and speed. Returns nil when the
+// interface has no sample in the window.
+func (db *DB) GetNetInterfaceWindow(ctx context.Context, hostID, name string, window time.Duration) (*models.NetInterfaceWindow, error) {
+	var (
+		w                      models.NetInterfaceWindow
+		dRx, dTx, dPkts, dErrs int64
+		samples                int
+		operState              sql.NullString
+		speed                  sql.NullInt64
+	)
+	err := db.conn.QueryRowContext(ctx,
+		`WITH `+netInterfaceDeltas(`host_id = $1 AND name = $2 AND "timestamp" > NOW() - INTERVAL '1 second' * $3`)+`
+		SELECT COUNT(*),
+			COALESCE(SUM(secs) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_rx_bytes) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_tx_bytes) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_rx_packets + d_tx_packets) FILTER (WHERE valid), 0),
+			COALESCE(SUM(d_errors) FILTER (WHERE valid), 0),
+			(ARRAY_AGG(operstate ORDER BY "timestamp" DESC))[1],
+			(ARRAY_AGG(speed_mbps ORDER BY "timestamp" DESC))[1]
+		FROM d`,
+		hostID, name, int64(window.Seconds()),
+	).Scan(&samples, &w.Seconds, &dRx, &dTx, &dPkts, &dErrs, &operState, &speed)
+	if err != nil {
+		return nil, err
+	}
+	if samples == 0 {
+		return nil, nil
+	}
+	w.RxBytes, w.TxBytes, w.Packets, w.Errors = uint64(dRx), uint64(dTx), uint64(dPkts), uint64(dErrs)
+	w.OperState = operState.String
+	w.SpeedMbps = int(speed.Int64)
+	return &w, nil
+}
+
+// ListNetInterfaceAlertTargets returns the names of a host's interfaces that
+// were up at least once since the given time: the per-interface alert
+// targets. A port that was never cabled never raises "interface down", and a
+// removed interface keeps being evaluated long enough to resolve.
+func (db *DB) ListNetInterfaceAlertTargets(ctx context.Context, hostID string, since time.Time) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT DISTINCT name FROM net_interface_metrics
+		WHERE host_id = $1 AND "timestamp" > $2 AND operstate = 'up'
+		ORDER BY name ASC`,
+		hostID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
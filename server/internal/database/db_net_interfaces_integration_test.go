@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestNetInterfaceMetrics_RatesAcrossReset inserts three cycles of a
+// 1 Gbit/s link whose counters reset between the second and third (driver
+// reload): the latest view has no rate, and the window sums only the clean
+// interval.
+func TestNetInterfaceMetrics_RatesAcrossReset(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	registerNetworkFlowsHost(t, db, ctx)
+
+	now := time.Now().Truncate(time.Second)
+	cycles := []struct {
+		at    time.Time
+		iface models.NetInterface
+	}{
+		{now.Add(-2 * time.Minute), models.NetInterface{Name: "eth0", Kind: "physical", OperState: "up", SpeedMbps: 1000,
+			RxBytes: 1_000, TxBytes: 1_000, RxPackets: 100, TxPackets: 100}},
+		{now.Add(-time.Minute), models.NetInterface{Name: "eth0", Kind: "physical", OperState: "up", SpeedMbps: 1000,
+			RxBytes: 1_000 + 60*12_500_000, TxBytes: 1_000 + 60*1_000, RxPackets: 300, TxPackets: 300, RxErrors: 4}},
+		{now, models.NetInterface{Name: "eth0", Kind: "physical", OperState: "down", Members: []string{"x"},
+			RxBytes: 10, TxBytes: 10, RxPackets: 1, TxPackets: 1}},
+	}
+	for _, c := range cycles {
+		if err := db.InsertNetInterfaceMetrics(ctx, testNetworkFlowsHostID, c.at, []models.NetInterface{c.iface}); err != nil {
+			t.Fatalf("InsertNetInterfaceMetrics: %v", err)
+		}
+	}
+
+	latest, err := db.GetLatestNetInterfaces(ctx, testNetworkFlowsHostID)
+	if err != nil {
+		t.Fatalf("GetLatestNetInterfaces: %v", err)
+	}
+	if len(latest) != 1 || latest[0].OperState != "down" || latest[0].RxBytesPerSec != 0 ||
+		latest[0].UtilizationPercent != nil || len(latest[0].Members) != 1 {
+		t.Errorf("latest = %+v", latest)
+	}
+
+	w, err := db.GetNetInterfaceWindow(ctx, testNetworkFlowsHostID, "eth0", 10*time.Minute)
+	if err != nil || w == nil {
+		t.Fatalf("GetNetInterfaceWindow = %+v, %v", w, err)
+	}
+	if math.Abs(w.Seconds-60) > 1e-6 || w.RxBytes != 60*12_500_000 || w.Packets != 400 || w.Errors != 4 || w.OperState != "down" {
+		t.Errorf("window = %+v", w)
+	}
+	if w, err := db.GetNetInterfaceWindow(ctx, testNetworkFlowsHostID, "eth9", 10*time.Minute); err != nil || w != nil {
+		t.Errorf("an unknown interface has no window, got %+v, %v", w, err)
+	}
+
+	history, err := db.GetNetInterfaceHistory(ctx, testNetworkFlowsHostID, "eth0", now.Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("GetNetInterfaceHistory: %v", err)
+	}
+	var down, utilized bool
+	for _, p := range history.Points {
+		down = down || p.Down
+		if p.UtilizationPercent != nil && math.Abs(*p.UtilizationPercent-10) < 1e-6 {
+			utilized = true
+		}
+	}
+	if !down || !utilized {
+		t.Errorf("history should show the 10%% utilization and the down sample: %+v", history.Points)
+	}
+
+	targets, err := db.ListNetInterfaceAlertTargets(ctx, testNetworkFlowsHostID, now.Add(-time.Hour))
+	if err != nil || len(targets) != 1 || targets[0] != "eth0" {
+		t.Errorf("targets = %v, %v", targets, err)
+	}
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const networkFlowPolicyColumns = `id, name, description, enabled, host_ids::text, tags::text, direction, subject, mode, rules::text, created_by, created_at, updated_at`
+
+func scanNetworkFlowPolicy(row interface{ Scan(...any) error }) (*models.NetworkFlowPolicy, error) {
+	var p models.NetworkFlowPolicy
+	var hostIDs, tags, rules string
+	if err := row.Scan(&p.ID, &p.Name, &p.Description, &p.Enabled, &hostIDs, &tags, &p.Direction, &p.Subject, &p.Mode,
+		&rules, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	p.HostIDs = parseTags(hostIDs)
+	p.Tags = parseTags(tags)
+	p.Rules = []models.NetworkFlowPolicyRule{}
+	if rules != "" {
+		if err := json.Unmarshal([]byte(rules), &p.Rules); err != nil {
+			return nil, fmt.Errorf("invalid rules of network flow policy %s: %w", p.ID, err)
+		}
+	}
+	return &p, nil
+}
+
+func marshalNetworkFlowRules(rules []models.NetworkFlowPolicyRule) (string, error) {
+	if rules == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(rules)
+	return string(data), err
+}
+
+// ListNetworkFlowPolicies returns the network flow policies, oldest first,
+// only the enabled ones when enabledOnly is set.
+func (db *DB) ListNetworkFlowPolicies(ctx context.Context, enabledOnly bool) ([]models.NetworkFlowPolicy, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+networkFlowPolicyColumns+` FROM network_flow_policies
+		 WHERE (NOT $1 OR enabled)
+		 ORDER BY created_at`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.NetworkFlowPolicy, 0)
+	for rows.Next() {
+		p, err := scanNetworkFlowPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// GetNetworkFlowPolicy returns one policy, or sql.ErrNoRows.
+func (db *DB) GetNetworkFlowPolicy(ctx context.Context, id string) (*models.NetworkFlowPolicy, error) {
+	return scanNetworkFlowPolicy(db.conn.QueryRowContext(ctx,
+		`SELECT `+networkFlowPolicyColumns+` FROM network_flow_policies WHERE id = $1`, id))
+}
+
+// CreateNetworkFlowPolicy inserts a policy and returns it with its id.
+func (db *DB) CreateNetworkFlowPolicy(ctx context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error) {
+	rules, err := marshalNetworkFlowRules(p.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return scanNetworkFlowPolicy(db.conn.QueryRowContext(ctx,
+		`INSERT INTO network_flow_policies (name, description, enabled, host_ids, tags, direction, subject, mode, rules, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING `+networkFlowPolicyColumns,
+		p.Name, p.Description, p.Enabled, marshalTags(p.HostIDs), marshalTags(p.Tags), p.Direction, p.Subject, p.Mode,
+		rules, p.CreatedBy))
+}
+
+// UpdateNetworkFlowPolicy saves every editable field of p. Returns
+// sql.ErrNoRows when the policy no longer exists.
+func (db *DB) UpdateNetworkFlowPolicy(ctx context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error) {
+	rules, err := marshalNetworkFlowRules(p.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return scanNetworkFlowPolicy(db.conn.QueryRowContext(ctx,
+		`UPDATE network_flow_policies
+		 SET name = $2, description = $3, enabled = $4, host_ids = $5, tags = $6, direction = $7, subject = $8,
+		     mode = $9, rules = $10, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+networkFlowPolicyColumns,
+		p.ID, p.Name, p.Description, p.Enabled, marshalTags(p.HostIDs), marshalTags(p.Tags), p.Direction, p.Subject,
+		p.Mode, rules))
+}
+
+// DeleteNetworkFlowPolicy removes a policy, and its violations with it, and
+// reports whether it existed.
+func (db *DB) DeleteNetworkFlowPolicy(ctx context.Context, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, `DELETE FROM network_flow_policies WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpsertNetworkFlowViolations records the offending flows of one report
+// cycle: a new flow is inserted, a known one has its last sighting moved to
+// seenAt and the cycle's connections and bytes added up.
+func (db *DB) UpsertNetworkFlowViolations(ctx context.Context, violations []models.NetworkFlowViolation, seenAt time.Time) error {
+	for _, v := range violations {
+		_, err := db.conn.ExecContext(ctx,
+			`INSERT INTO network_flow_violations (
+				policy_id, host_id, remote_ip, remote_port, protocol, direction, process_name, server_name,
+				connections, rx_bytes, tx_bytes, first_seen, last_seen
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+			ON CONFLICT (policy_id, host_id, remote_ip, remote_port, protocol, direction, process_name) DO UPDATE SET
+				server_name = COALESCE(NULLIF(EXCLUDED.server_name, ''), network_flow_violations.server_name),
+				connections = network_flow_violations.connections + EXCLUDED.connections,
+				rx_bytes    = network_flow_violations.rx_bytes + EXCLUDED.rx_bytes,
+				tx_bytes    = network_flow_violations.tx_bytes + EXCLUDED.tx_bytes,
+				last_seen   = GREATEST(network_flow_violations.last_seen, EXCLUDED.last_seen)`,
+			v.PolicyID, v.HostID, v.RemoteIP, v.RemotePort, v.Protocol, v.Direction, v.ProcessName, v.ServerName,
+			v.Connections, v.RxBytes, v.TxBytes, seenAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to upsert network flow violation: %w", err)
+		}
+	}
+	return nil
+}
+
+const networkFlowViolationColumns = `v.id, v.policy_id, p.name, p.mode, v.host_id, COALESCE(h.name, ''), v.remote_ip, v.remote_port,
+	v.protocol, v.direction, v.process_name, v.server_name, v.connections, v.rx_bytes, v.tx_bytes, v.first_seen, v.last_seen`
+
+const networkFlowViolationFrom = `network_flow_violations v
+	JOIN network_flow_policies p ON p.id = v.policy_id
+	LEFT JOIN hosts h ON h.id = v.host_id`
+
+func scanNetworkFlowViolation(row interface{ Scan(...any) error }) (*models.NetworkFlowViolation, error) {
+	var v models.NetworkFlowViolation
+	if err := row.Scan(&v.ID, &v.PolicyID, &v.PolicyName, &v.Mode, &v.HostID, &v.HostName, &v.RemoteIP, &v.RemotePort,
+		&v.Protocol, &v.Direction, &v.ProcessName, &v.ServerName, &v.Connections, &v.RxBytes, &v.TxBytes,
+		&v.FirstSeen, &v.LastSeen); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListNetworkFlowViolations returns the violations seen since the given
+// time, most recently seen first, optionally for one host and/or one policy.
+func (db *DB) ListNetworkFlowViolations(ctx context.Context, hostID, policyID string, since time.Time, limit int) ([]models.NetworkFlowViolation, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+networkFlowViolationColumns+` FROM `+networkFlowViolationFrom+`
+		 WHERE ($1 = '' OR v.host_id = $1)
+		   AND ($2 = '' OR v.policy_id::text = $2)
+		   AND v.last_seen >= $3
+		 ORDER BY v.last_seen DESC, v.id DESC
+		 LIMIT $4`, hostID, policyID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.NetworkFlowViolation, 0)
+	for rows.Next() {
+		v, err := scanNetworkFlowViolation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+// GetNetworkFlowViolation returns one violation, or sql.ErrNoRows.
+func (db *DB) GetNetworkFlowViolation(ctx context.Context, id int64) (*models.NetworkFlowViolation, error) {
+	return scanNetworkFlowViolation(db.conn.QueryRowContext(ctx,
+		`SELECT `+networkFlowViolationColumns+` FROM `+networkFlowViolationFrom+` WHERE v.id = $1`, id))
+}
+
+// CleanOldNetworkFlowViolations deletes the violations not seen for the
+// given number of days, alongside the network_flow_metrics retention.
+func (db *DB) CleanOldNetworkFlowViolations(ctx context.Context, days int) (int64, error) {
+	if days <= 0 {
+		days = 14
+	}
+	res, err := db.conn.ExecContext(ctx,
+		`DELETE FROM network_flow_violations WHERE last_seen < NOW() - ($1 || ' days')::INTERVAL`, days)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,68 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestNetworkFlowPolicies_ViolationUpsert checks the policy round trip and
+// that a flow seen again refreshes its violation instead of adding one.
+func TestNetworkFlowPolicies_ViolationUpsert(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: "db-1", Name: "db-1", Hostname: "db-1.local", IPAddress: "10.0.0.40", Status: "online",
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	p, err := db.CreateNetworkFlowPolicy(ctx, &models.NetworkFlowPolicy{
+		Name: "DB egress", Enabled: true, Tags: []string{"db"}, Direction: "outbound", Subject: "any",
+		Mode:  models.NetworkFlowPolicyAllow,
+		Rules: []models.NetworkFlowPolicyRule{{CIDR: "10.0.0.0/24", Port: 5432, Protocol: "tcp"}},
+	})
+	if err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	if len(p.Rules) != 1 || p.Rules[0].Port != 5432 || len(p.Tags) != 1 || len(p.HostIDs) != 0 {
+		t.Errorf("policy = %+v", p)
+	}
+
+	v := models.NetworkFlowViolation{
+		PolicyID: p.ID, HostID: "db-1", RemoteIP: "203.0.113.9", RemotePort: 443, Protocol: "tcp",
+		Direction: "outbound", ProcessName: "conteneur: backup", Connections: 2, RxBytes: 100, TxBytes: 50,
+	}
+	first := time.Now().UTC().Add(-time.Minute)
+	if err := db.UpsertNetworkFlowViolations(ctx, []models.NetworkFlowViolation{v}, first); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	v.ServerName = "backup.example.com"
+	if err := db.UpsertNetworkFlowViolations(ctx, []models.NetworkFlowViolation{v}, first.Add(30*time.Second)); err != nil {
+		t.Fatalf("upsert again: %v", err)
+	}
+
+	list, err := db.ListNetworkFlowViolations(ctx, "db-1", "", first.Add(-time.Minute), 10)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("violations = %+v, %v", list, err)
+	}
+	got := list[0]
+	if got.Connections != 4 || got.RxBytes != 200 || got.ServerName != "backup.example.com" ||
+		got.PolicyName != "DB egress" || got.HostName != "db-1" || !got.LastSeen.After(got.FirstSeen) {
+		t.Errorf("violation = %+v", got)
+	}
+	if one, err := db.GetNetworkFlowViolation(ctx, got.ID); err != nil || one.RemoteIP != "203.0.113.9" {
+		t.Errorf("get violation = %+v, %v", one, err)
+	}
+
+	// Deleting the policy takes its violations with it.
+	if ok, err := db.DeleteNetworkFlowPolicy(ctx, p.ID); err != nil || !ok {
+		t.Fatalf("delete policy: %v, %v", ok, err)
+	}
+	if list, _ := db.ListNetworkFlowViolations(ctx, "", "", time.Time{}, 10); len(list) != 0 {
+		t.Errorf("violations left after delete = %+v", list)
+	}
+}
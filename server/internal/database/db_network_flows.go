@@ -173,6 +173,42 @@ func (db *DB) GetNetworkFlowsSummary(ctx context.Context, hostID string, since,
 	return scanNetworkFlowSummary(rows)
 }
 
+// GetNetworkFlowsPeerTotals sums each remote IP's traffic over a window
+// (busiest first, at most limit peers), for the per-country/ASN summary.
+// until being zero means "open ended".
+func (db *DB) GetNetworkFlowsPeerTotals(ctx context.Context, hostID string, since, until time.Time, limit int) ([]models.NetworkFlowPeerTotal, error) {
+	args := []any{hostID, since, limit}
+	where := "host_id = $1 AND is_others = false AND timestamp > $2"
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(" AND timestamp <= $%d", len(args))
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT remote_ip, COALESCE(SUM(rx_bytes), 0), COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(connections), 0)
+		FROM network_flow_metrics
+		WHERE %s
+		GROUP BY remote_ip
+		ORDER BY SUM(rx_bytes + tx_bytes) DESC
+		LIMIT $3`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.NetworkFlowPeerTotal, 0)
+	for rows.Next() {
+		var p models.NetworkFlowPeerTotal
+		if err := rows.Scan(&p.RemoteIP, &p.RxBytes, &p.TxBytes, &p.Connections); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 func scanNetworkFlowSummary(rows *sql.Rows) ([]models.NetworkFlowSummaryPoint, error) {
 	var points []models.NetworkFlowSummaryPoint
 	for rows.Next() {
@@ -200,3 +236,48 @@ func (db *DB) CleanOldNetworkFlowMetrics(ctx context.Context, days int) (int64,
 	}
 	return res.RowsAffected()
 }
+
+// ListNetworkFlowObservations sums every host's talkers over a window, one
+// row per (host, remote IP, port, protocol, direction, process), busiest
+// first and at most limit rows — the input of the service dependency map.
+// For inbound talkers the remote port is the client's ephemeral port, so it
+// is folded to 0 to keep one row per client. until being zero means "open
+// ended".
+func (db *DB) ListNetworkFlowObservations(ctx context.Context, since, until time.Time, limit int) ([]models.NetworkFlowObservation, error) {
+	args := []any{since, limit}
+	where := "is_others = false AND timestamp > $1"
+	if !until.IsZero() {
+		args = append(args, until)
+		where += fmt.Sprintf(" AND timestamp <= $%d", len(args))
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT host_id, COALESCE(remote_ip, ''),
+			CASE WHEN direction = 'inbound' THEN 0 ELSE remote_port END AS port,
+			COALESCE(protocol, ''), COALESCE(direction, ''), COALESCE(process_name, ''),
+			COALESCE(MAX(NULLIF(server_name, '')), ''),
+			COALESCE(SUM(rx_bytes), 0), COALESCE(SUM(tx_bytes), 0), COALESCE(SUM(connections), 0),
+			MIN(timestamp), MAX(timestamp)
+		FROM network_flow_metrics
+		WHERE %s
+		GROUP BY 1, 2, 3, 4, 5, 6
+		ORDER BY SUM(rx_bytes + tx_bytes) DESC
+		LIMIT $2`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.NetworkFlowObservation, 0)
+	for rows.Next() {
+		var o models.NetworkFlowObservation
+		if err := rows.Scan(&o.HostID, &o.RemoteIP, &o.RemotePort, &o.Protocol, &o.Direction, &o.ProcessName,
+			&o.ServerName, &o.RxBytes, &o.TxBytes, &o.Connections, &o.FirstSeen, &o.LastSeen); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
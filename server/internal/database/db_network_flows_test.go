@@ -248,3 +248,43 @@ func TestGetNetworkFlowsSummary_UntilExcludesPointsPastTheUpperBound(t *testing.
 		t.Errorf("expected both cycles with an open-ended until, got total rx=%d across %+v", openTotalRx, openEnded)
 	}
 }
+
+// TestGetNetworkFlowsPeerTotals_SumsPerRemoteIP guards the input of the
+// per-country/ASN summary: one row per remote IP across ports, protocols and
+// cycles, busiest first, without the "others" rollup.
+func TestGetNetworkFlowsPeerTotals_SumsPerRemoteIP(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	registerNetworkFlowsHost(t, db, ctx)
+
+	now := time.Now().Truncate(time.Second)
+	for i, at := range []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute)} {
+		report := &models.NetworkFlowsReport{
+			Available: true,
+			TopTalkers: []models.NetworkFlowTalker{
+				{RemoteIP: "1.2.3.4", RemotePort: 443, Protocol: "tcp", Direction: "outbound", RxBytes: 100, TxBytes: 100, Connections: 1},
+				{RemoteIP: "1.2.3.4", RemotePort: 53, Protocol: "udp", Direction: "outbound", RxBytes: 10, TxBytes: 10, Connections: 1},
+				{RemoteIP: "5.6.7.8", RemotePort: 22, Protocol: "tcp", Direction: "inbound", RxBytes: uint64(1000 * (i + 1)), TxBytes: 0, Connections: 1},
+			},
+			Others:      &models.NetworkFlowBucket{Connections: 40, RxBytes: 99999, TxBytes: 99999},
+			CollectedAt: at,
+		}
+		if err := db.InsertNetworkFlowMetrics(ctx, testNetworkFlowsHostID, report); err != nil {
+			t.Fatalf("InsertNetworkFlowMetrics: %v", err)
+		}
+	}
+
+	peers, err := db.GetNetworkFlowsPeerTotals(ctx, testNetworkFlowsHostID, now.Add(-time.Hour), time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("GetNetworkFlowsPeerTotals: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers (others excluded), got %+v", peers)
+	}
+	if peers[0].RemoteIP != "5.6.7.8" || peers[0].RxBytes != 3000 {
+		t.Errorf("expected the busiest peer first, got %+v", peers[0])
+	}
+	if peers[1].RemoteIP != "1.2.3.4" || peers[1].RxBytes != 220 || peers[1].Connections != 4 {
+		t.Errorf("expected ports and cycles summed, got %+v", peers[1])
+	}
+}
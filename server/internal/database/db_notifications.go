@@ -197,6 +197,31 @@ func (db *DB) enrichNotificationSource(ctx context.Context, item *models.Notific
 		return
 	}
 
+	for prefix, label := range map[string]string{
+		"docker:swarm_service:": "Service Swarm",
+		"docker:swarm_node:":    "Noeud Swarm",
+	} {
+		if !strings.HasPrefix(item.HostID, prefix) {
+			continue
+		}
+		item.SourceType = "docker"
+		rest := strings.TrimPrefix(item.HostID, prefix)
+		if idx := strings.Index(rest, ":"); idx >= 0 {
+			item.LinkHostID = rest[:idx]
+			item.HostName = rest[idx+1:]
+		}
+		if prefix == "docker:swarm_node:" && item.LinkHostID != "" {
+			var hostname string
+			if err := db.conn.QueryRowContext(ctx,
+				`SELECT hostname FROM docker_swarm_nodes WHERE host_id = $1 AND node_id = $2`, item.LinkHostID, item.HostName,
+			).Scan(&hostname); err == nil && hostname != "" {
+				item.HostName = hostname
+			}
+		}
+		item.SourceLabel = label
+		return
+	}
+
 	item.SourceType = "agent"
 	item.SourceLabel = item.HostName
 
@@ -238,6 +263,11 @@ func (db *DB) enrichNotificationSource(ctx context.Context, item *models.Notific
 		item.HostName = name
 		item.SourceLabel = ctx
 		return
+	case "backup_group":
+		name, ctx := db.resolvePBSBackupGroupInfo(ctx, rawID)
+		item.HostName = name
+		item.SourceLabel = ctx
+		return
 	case "connection":
 		label := db.resolveProxmoxConnectionLabel(ctx, rawID)
 		item.HostName = label
@@ -441,6 +471,21 @@ func (db *DB) resolveProxmoxDiskInfo(ctx context.Context, diskID string) (name,
 	return
 }
 
+func (db *DB) resolvePBSBackupGroupInfo(ctx context.Context, groupID string) (name, context string) {
+	if groupID == "" {
+		return "Sauvegarde PBS", "Proxmox Backup Server"
+	}
+	connName, store, group, guestName, err := db.PBSBackupGroupLabelParts(ctx, groupID)
+	if err != nil {
+		return "Groupe " + groupID, "Proxmox Backup Server"
+	}
+	name = group
+	if strings.TrimSpace(guestName) != "" {
+		name = guestName + " (" + group + ")"
+	}
+	return name, "Sauvegarde PBS sur " + connName + " / " + store
+}
+
 // resolveProxmoxGlobalLikelySource picks the most representative Proxmox
 // object for a "global" alert scope, so the user sees e.g. "the node that
 // triggered the cluster-wide CPU alarm" instead of just "Cluster Proxmox".
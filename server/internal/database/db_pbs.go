@@ -0,0 +1,383 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ===== Proxmox Backup Server connections =====
+
+// CreatePBSConnection inserts a new PBS connection and returns its ID.
+func (db *DB) CreatePBSConnection(ctx context.Context, name, apiURL, tokenID, tokenSecret string, insecureSkipVerify, enabled bool, pollIntervalSec int) (string, error) {
+	if pollIntervalSec <= 0 {
+		pollIntervalSec = 300
+	}
+	var id string
+	err := db.conn.QueryRowContext(ctx, `
+		INSERT INTO pbs_connections (name, api_url, token_id, token_secret, insecure_skip_verify, enabled, poll_interval_sec)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id`,
+		name, apiURL, tokenID, tokenSecret, insecureSkipVerify, enabled, pollIntervalSec,
+	).Scan(&id)
+	return id, err
+}
+
+// ListPBSConnections returns all PBS connections without secrets.
+// Datastore and backup group counts are joined.
+func (db *DB) ListPBSConnections(ctx context.Context) ([]models.PBSConnection, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT
+			c.id, c.name, c.api_url, c.token_id,
+			c.insecure_skip_verify, c.enabled, c.poll_interval_sec, c.version,
+			c.last_error, c.last_error_at, c.last_success_at,
+			c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM pbs_datastores d WHERE d.connection_id = c.id),
+			(SELECT COUNT(*) FROM pbs_backup_groups g WHERE g.connection_id = c.id)
+		FROM pbs_connections c
+		ORDER BY c.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	conns := []models.PBSConnection{}
+	for rows.Next() {
+		var c models.PBSConnection
+		var lastErrAt, lastSuccAt sql.NullTime
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.APIURL, &c.TokenID,
+			&c.InsecureSkipVerify, &c.Enabled, &c.PollIntervalSec, &c.Version,
+			&c.LastError, &lastErrAt, &lastSuccAt,
+			&c.CreatedAt, &c.UpdatedAt,
+			&c.DatastoreCount, &c.GroupCount,
+		); err != nil {
+			return nil, err
+		}
+		c.LastErrorAt = nullTimePtr(lastErrAt)
+		c.LastSuccessAt = nullTimePtr(lastSuccAt)
+		conns = append(conns, c)
+	}
+	return conns, rows.Err()
+}
+
+// GetPBSConnectionByID returns a PBS connection without secret, nil when missing.
+func (db *DB) GetPBSConnectionByID(ctx context.Context, id string) (*models.PBSConnection, error) {
+	var c models.PBSConnection
+	var lastErrAt, lastSuccAt sql.NullTime
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT id, name, api_url, token_id, insecure_skip_verify, enabled, poll_interval_sec, version,
+		       last_error, last_error_at, last_success_at, created_at, updated_at
+		FROM pbs_connections WHERE id = $1`, id).Scan(
+		&c.ID, &c.Name, &c.APIURL, &c.TokenID,
+		&c.InsecureSkipVerify, &c.Enabled, &c.PollIntervalSec, &c.Version,
+		&c.LastError, &lastErrAt, &lastSuccAt,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.LastErrorAt = nullTimePtr(lastErrAt)
+	c.LastSuccessAt = nullTimePtr(lastSuccAt)
+	return &c, nil
+}
+
+// PBSConnectionFull is a PBS connection record including its token secret.
+// Reserved for the poller — never returned to API clients.
+type PBSConnectionFull struct {
+	models.PBSConnection
+	TokenSecret string
+}
+
+// GetEnabledPBSConnections returns enabled PBS connections WITH their secrets (for the poller).
+func (db *DB) GetEnabledPBSConnections(ctx context.Context) ([]PBSConnectionFull, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, name, api_url, token_id, token_secret,
+		       insecure_skip_verify, enabled, poll_interval_sec, version,
+		       last_error, last_error_at, last_success_at, created_at, updated_at
+		FROM pbs_connections WHERE enabled = TRUE ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var conns []PBSConnectionFull
+	for rows.Next() {
+		var c PBSConnectionFull
+		var lastErrAt, lastSuccAt sql.NullTime
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.APIURL, &c.TokenID, &c.TokenSecret,
+			&c.InsecureSkipVerify, &c.Enabled, &c.PollIntervalSec, &c.Version,
+			&c.LastError, &lastErrAt, &lastSuccAt,
+			&c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		c.LastErrorAt = nullTimePtr(lastErrAt)
+		c.LastSuccessAt = nullTimePtr(lastSuccAt)
+		conns = append(conns, c)
+	}
+	return conns, rows.Err()
+}
+
+// UpdatePBSConnection updates mutable fields.
+// If tokenSecret is empty the existing secret is preserved.
+func (db *DB) UpdatePBSConnection(ctx context.Context, id, name, apiURL, tokenID, tokenSecret string, insecureSkipVerify, enabled bool, pollIntervalSec int) error {
+	if pollIntervalSec <= 0 {
+		pollIntervalSec = 300
+	}
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE pbs_connections
+		SET name=$2, api_url=$3, token_id=$4,
+		    token_secret=COALESCE(NULLIF($5, ''), token_secret),
+		    insecure_skip_verify=$6, enabled=$7, poll_interval_sec=$8, updated_at=NOW()
+		WHERE id=$1`,
+		id, name, apiURL, tokenID, tokenSecret, insecureSkipVerify, enabled, pollIntervalSec)
+	return err
+}
+
+// DeletePBSConnection removes a connection (cascade deletes datastores/groups/jobs).
+func (db *DB) DeletePBSConnection(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM pbs_connections WHERE id = $1`, id)
+	return err
+}
+
+// UpdatePBSConnectionSuccess records a successful poll and the server version.
+func (db *DB) UpdatePBSConnectionSuccess(ctx context.Context, id, version string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE pbs_connections SET version=$2, last_error='', last_error_at=NULL, last_success_at=NOW(), updated_at=NOW()
+		WHERE id=$1`, id, version)
+	return err
+}
+
+// UpdatePBSConnectionError records a poll error.
+func (db *DB) UpdatePBSConnectionError(ctx context.Context, id, errMsg string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE pbs_connections SET last_error=$2, last_error_at=NOW(), updated_at=NOW()
+		WHERE id=$1`, id, errMsg)
+	return err
+}
+
+// GetPBSTokenSecret returns only the token secret for a connection.
+func (db *DB) GetPBSTokenSecret(ctx context.Context, id string) (string, error) {
+	var secret string
+	err := db.conn.QueryRowContext(ctx, `SELECT token_secret FROM pbs_connections WHERE id=$1`, id).Scan(&secret)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return secret, err
+}
+
+// ===== poller writes =====
+
+// UpsertPBSDatastore records the usage of one datastore.
+func (db *DB) UpsertPBSDatastore(ctx context.Context, connectionID, store string, total, used, avail int64, lastError string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO pbs_datastores (connection_id, store, total, used, avail, last_error, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (connection_id, store) DO UPDATE SET
+			total = EXCLUDED.total, used = EXCLUDED.used, avail = EXCLUDED.avail,
+			last_error = EXCLUDED.last_error, last_seen_at = NOW()`,
+		connectionID, store, total, used, avail, lastError)
+	return err
+}
+
+// UpsertPBSBackupGroup records one backup group with its latest backup and
+// verification state.
+func (db *DB) UpsertPBSBackupGroup(ctx context.Context, g models.PBSBackupGroup) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO pbs_backup_groups (connection_id, store, backup_type, backup_id, backup_count,
+			last_backup_at, owner, comment, last_verify_state, last_verify_at, verify_failed_count, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+		ON CONFLICT (connection_id, store, backup_type, backup_id) DO UPDATE SET
+			backup_count = EXCLUDED.backup_count, last_backup_at = EXCLUDED.last_backup_at,
+			owner = EXCLUDED.owner, comment = EXCLUDED.comment,
+			last_verify_state = EXCLUDED.last_verify_state, last_verify_at = EXCLUDED.last_verify_at,
+			verify_failed_count = EXCLUDED.verify_failed_count, last_seen_at = NOW()`,
+		g.ConnectionID, g.Store, g.BackupType, g.BackupID, g.BackupCount,
+		g.LastBackupAt, g.Owner, g.Comment, g.LastVerifyState, g.LastVerifyAt, g.VerifyFailedCount)
+	return err
+}
+
+// UpsertPBSJob records the status of one GC/prune/verify/sync job.
+func (db *DB) UpsertPBSJob(ctx context.Context, j models.PBSJob) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO pbs_jobs (connection_id, job_type, job_id, store, schedule, last_run_state,
+			last_run_at, last_run_upid, next_run_at, remote, remote_store, removed_bytes, pending_bytes, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+		ON CONFLICT (connection_id, job_type, job_id) DO UPDATE SET
+			store = EXCLUDED.store, schedule = EXCLUDED.schedule, last_run_state = EXCLUDED.last_run_state,
+			last_run_at = EXCLUDED.last_run_at, last_run_upid = EXCLUDED.last_run_upid,
+			next_run_at = EXCLUDED.next_run_at, remote = EXCLUDED.remote, remote_store = EXCLUDED.remote_store,
+			removed_bytes = EXCLUDED.removed_bytes, pending_bytes = EXCLUDED.pending_bytes, last_seen_at = NOW()`,
+		j.ConnectionID, j.JobType, j.JobID, j.Store, j.Schedule, j.LastRunState,
+		j.LastRunAt, j.LastRunUPID, j.NextRunAt, j.Remote, j.RemoteStore, j.RemovedBytes, j.PendingBytes)
+	return err
+}
+
+// DeleteStalePBSRows removes the datastores, groups and jobs of a connection
+// that were not seen since cutoff (removed on the PBS side).
+func (db *DB) DeleteStalePBSRows(ctx context.Context, connectionID string, cutoff time.Time) error {
+	for _, table := range []string{"pbs_datastores", "pbs_backup_groups", "pbs_jobs"} {
+		if _, err := db.conn.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE connection_id = $1 AND last_seen_at < $2`, connectionID, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===== reads =====
+
+// ListPBSDatastores returns the datastores (optionally of one connection) with
+// their last GC result and group count.
+func (db *DB) ListPBSDatastores(ctx context.Context, connectionID string) ([]models.PBSDatastore, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT d.id, d.connection_id, c.name, d.store, d.total, d.used, d.avail, d.last_error,
+		       COALESCE(j.last_run_state, ''), j.last_run_at,
+		       (SELECT COUNT(*) FROM pbs_backup_groups g WHERE g.connection_id = d.connection_id AND g.store = d.store),
+		       d.last_seen_at
+		FROM pbs_datastores d
+		JOIN pbs_connections c ON c.id = d.connection_id
+		LEFT JOIN pbs_jobs j ON j.connection_id = d.connection_id AND j.job_type = 'gc' AND j.job_id = d.store
+		WHERE ($1 = '' OR d.connection_id::text = $1)
+		ORDER BY c.name, d.store`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.PBSDatastore{}
+	for rows.Next() {
+		var d models.PBSDatastore
+		var gcAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.ConnectionID, &d.ConnectionName, &d.Store, &d.Total, &d.Used, &d.Avail, &d.LastError,
+			&d.GCState, &gcAt, &d.GroupCount, &d.LastSeenAt); err != nil {
+			return nil, err
+		}
+		d.GCLastRunAt = nullTimePtr(gcAt)
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// pbsGuestNameJoin resolves the PVE guest name of a vm/ct backup group by
+// VMID (the newest matching guest when several clusters reuse the VMID).
+const pbsGuestNameJoin = `
+		LEFT JOIN LATERAL (
+			SELECT pg.name FROM proxmox_guests pg
+			WHERE g.backup_type IN ('vm', 'ct')
+			  AND pg.vmid::text = g.backup_id
+			  AND pg.guest_type = CASE g.backup_type WHEN 'ct' THEN 'lxc' ELSE 'vm' END
+			ORDER BY pg.last_seen_at DESC LIMIT 1
+		) guest ON TRUE`
+
+// ListPBSBackupGroups returns the backup groups, optionally filtered by
+// connection and datastore.
+func (db *DB) ListPBSBackupGroups(ctx context.Context, connectionID, store string) ([]models.PBSBackupGroup, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT g.id, g.connection_id, c.name, g.store, g.backup_type, g.backup_id, COALESCE(guest.name, ''),
+		       g.backup_count, g.last_backup_at, g.owner, g.comment,
+		       g.last_verify_state, g.last_verify_at, g.verify_failed_count, g.last_seen_at
+		FROM pbs_backup_groups g
+		JOIN pbs_connections c ON c.id = g.connection_id`+pbsGuestNameJoin+`
+		WHERE ($1 = '' OR g.connection_id::text = $1)
+		  AND ($2 = '' OR g.store = $2)
+		ORDER BY c.name, g.store, g.backup_type, g.backup_id`, connectionID, store)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.PBSBackupGroup{}
+	for rows.Next() {
+		var g models.PBSBackupGroup
+		var lastBackup, lastVerify sql.NullTime
+		if err := rows.Scan(&g.ID, &g.ConnectionID, &g.ConnectionName, &g.Store, &g.BackupType, &g.BackupID, &g.GuestName,
+			&g.BackupCount, &lastBackup, &g.Owner, &g.Comment,
+			&g.LastVerifyState, &lastVerify, &g.VerifyFailedCount, &g.LastSeenAt); err != nil {
+			return nil, err
+		}
+		g.LastBackupAt = nullTimePtr(lastBackup)
+		g.LastVerifyAt = nullTimePtr(lastVerify)
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// ListPBSJobs returns the GC/prune/verify/sync jobs, optionally of one connection.
+func (db *DB) ListPBSJobs(ctx context.Context, connectionID string) ([]models.PBSJob, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT j.id, j.connection_id, c.name, j.job_type, j.job_id, j.store, j.schedule, j.last_run_state,
+		       j.last_run_at, j.last_run_upid, j.next_run_at, j.remote, j.remote_store,
+		       j.removed_bytes, j.pending_bytes, j.last_seen_at
+		FROM pbs_jobs j
+		JOIN pbs_connections c ON c.id = j.connection_id
+		WHERE ($1 = '' OR j.connection_id::text = $1)
+		ORDER BY c.name, j.job_type, j.job_id`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.PBSJob{}
+	for rows.Next() {
+		var j models.PBSJob
+		var lastRun, nextRun sql.NullTime
+		if err := rows.Scan(&j.ID, &j.ConnectionID, &j.ConnectionName, &j.JobType, &j.JobID, &j.Store, &j.Schedule, &j.LastRunState,
+			&lastRun, &j.LastRunUPID, &nextRun, &j.Remote, &j.RemoteStore,
+			&j.RemovedBytes, &j.PendingBytes, &j.LastSeenAt); err != nil {
+			return nil, err
+		}
+		j.LastRunAt = nullTimePtr(lastRun)
+		j.NextRunAt = nullTimePtr(nextRun)
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// ===== alert metrics =====
+// Only groups of enabled connections count: a disabled connection is no
+// longer polled, so its rows would age forever.
+
+// GetPBSBackupAgeHours returns the hours since the last backup of one group,
+// or the maximum over every group when groupID is empty.
+func (db *DB) GetPBSBackupAgeHours(ctx context.Context, groupID string) float64 {
+	var hours sql.NullFloat64
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT MAX(EXTRACT(EPOCH FROM (NOW() - g.last_backup_at)) / 3600)
+		FROM pbs_backup_groups g
+		JOIN pbs_connections c ON c.id = g.connection_id AND c.enabled
+		WHERE g.last_backup_at IS NOT NULL
+		  AND ($1 = '' OR g.id::text = $1)`, groupID).Scan(&hours)
+	if err != nil || !hours.Valid {
+		return 0
+	}
+	return hours.Float64
+}
+
+// CountPBSVerifyFailed counts the groups whose newest verified snapshot failed
+// verification (only the scoped group when groupID is set).
+func (db *DB) CountPBSVerifyFailed(ctx context.Context, groupID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM pbs_backup_groups g
+		JOIN pbs_connections c ON c.id = g.connection_id AND c.enabled
+		WHERE g.last_verify_state = 'failed'
+		  AND ($1 = '' OR g.id::text = $1)`, groupID).Scan(&n)
+	return n, err
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
@@ -0,0 +1,330 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Proxmox Ceph / HA / corosync health ==========
+
+// UpsertProxmoxClusterHealth replaces the health summary of a connection.
+// The per-object lists of h (OSDs, pools, HA entries) are ignored.
+func (db *DB) UpsertProxmoxClusterHealth(ctx context.Context, h models.ProxmoxClusterHealth) error {
+	checks, err := json.Marshal(nonNilSlice(h.CephChecks))
+	if err != nil {
+		return err
+	}
+	pgStates, err := json.Marshal(nonNilSlice(h.PGStates))
+	if err != nil {
+		return err
+	}
+	mons, err := json.Marshal(nonNilSlice(h.Mons))
+	if err != nil {
+		return err
+	}
+	_, err = db.conn.ExecContext(ctx, `
+		INSERT INTO proxmox_cluster_health (connection_id, quorate, cluster_nodes, cluster_nodes_online,
+			ceph_available, ceph_health, ceph_checks, ceph_error, osds_total, osds_up, osds_in,
+			pgs_total, pgs_active_clean, pg_states, ceph_bytes_used, ceph_bytes_total,
+			mons_total, mons_in_quorum, ceph_mons, ha_enabled, ha_quorate, ha_master_node, ha_error, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,NOW())
+		ON CONFLICT (connection_id) DO UPDATE SET
+			quorate = EXCLUDED.quorate, cluster_nodes = EXCLUDED.cluster_nodes,
+			cluster_nodes_online = EXCLUDED.cluster_nodes_online,
+			ceph_available = EXCLUDED.ceph_available, ceph_health = EXCLUDED.ceph_health,
+			ceph_checks = EXCLUDED.ceph_checks, ceph_error = EXCLUDED.ceph_error,
+			osds_total = EXCLUDED.osds_total, osds_up = EXCLUDED.osds_up, osds_in = EXCLUDED.osds_in,
+			pgs_total = EXCLUDED.pgs_total, pgs_active_clean = EXCLUDED.pgs_active_clean,
+			pg_states = EXCLUDED.pg_states, ceph_bytes_used = EXCLUDED.ceph_bytes_used,
+			ceph_bytes_total = EXCLUDED.ceph_bytes_total, mons_total = EXCLUDED.mons_total,
+			mons_in_quorum = EXCLUDED.mons_in_quorum, ceph_mons = EXCLUDED.ceph_mons,
+			ha_enabled = EXCLUDED.ha_enabled, ha_quorate = EXCLUDED.ha_quorate,
+			ha_master_node = EXCLUDED.ha_master_node, ha_error = EXCLUDED.ha_error,
+			updated_at = NOW()`,
+		h.ConnectionID, h.Quorate, h.ClusterNodes, h.ClusterNodesOnline,
+		h.CephAvailable, h.CephHealth, checks, h.CephError, h.OSDsTotal, h.OSDsUp, h.OSDsIn,
+		h.PGsTotal, h.PGsActiveClean, pgStates, h.CephBytesUsed, h.CephBytesTotal,
+		h.MonsTotal, h.MonsInQuorum, mons, h.HAEnabled, h.HAQuorate, h.HAMasterNode, h.HAError,
+	)
+	return err
+}
+
+func nonNilSlice[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func (db *DB) UpsertProxmoxCephOSD(ctx context.Context, connectionID string, o models.ProxmoxCephOSD) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO proxmox_ceph_osds (connection_id, osd_id, name, host, status, is_in, device_class, percent_used, last_seen_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW())
+		ON CONFLICT (connection_id, osd_id) DO UPDATE SET
+			name = EXCLUDED.name, host = EXCLUDED.host, status = EXCLUDED.status, is_in = EXCLUDED.is_in,
+			device_class = EXCLUDED.device_class, percent_used = EXCLUDED.percent_used, last_seen_at = NOW()`,
+		connectionID, o.OSDID, o.Name, o.Host, o.Status, o.In, o.DeviceClass, o.PercentUsed)
+	return err
+}
+
+func (db *DB) UpsertProxmoxCephPool(ctx context.Context, connectionID string, p models.ProxmoxCephPool) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO proxmox_ceph_pools (connection_id, name, size, min_size, pg_num, bytes_used, percent_used, last_seen_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())
+		ON CONFLICT (connection_id, name) DO UPDATE SET
+			size = EXCLUDED.size, min_size = EXCLUDED.min_size, pg_num = EXCLUDED.pg_num,
+			bytes_used = EXCLUDED.bytes_used, percent_used = EXCLUDED.percent_used, last_seen_at = NOW()`,
+		connectionID, p.Name, p.Size, p.MinSize, p.PGNum, p.BytesUsed, p.PercentUsed)
+	return err
+}
+
+func (db *DB) UpsertProxmoxHAEntry(ctx context.Context, connectionID string, e models.ProxmoxHAEntry) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO proxmox_ha_status (connection_id, entry_id, entry_type, node_name, sid, state, request_state, status, healthy, last_seen_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NOW())
+		ON CONFLICT (connection_id, entry_id) DO UPDATE SET
+			entry_type = EXCLUDED.entry_type, node_name = EXCLUDED.node_name, sid = EXCLUDED.sid,
+			state = EXCLUDED.state, request_state = EXCLUDED.request_state, status = EXCLUDED.status,
+			healthy = EXCLUDED.healthy, last_seen_at = NOW()`,
+		connectionID, e.EntryID, e.EntryType, e.NodeName, e.SID, e.State, e.RequestState, e.Status, e.Healthy)
+	return err
+}
+
+// DeleteStaleProxmoxClusterObjects removes OSDs, pools and HA entries not seen
+// since the cutoff.
+func (db *DB) DeleteStaleProxmoxClusterObjects(ctx context.Context, connectionID string, cutoff time.Time) error {
+	for _, table := range []string{"proxmox_ceph_osds", "proxmox_ceph_pools", "proxmox_ha_status"} {
+		if _, err := db.conn.ExecContext(ctx,
+			`DELETE FROM `+table+` WHERE connection_id = $1 AND last_seen_at < $2`, connectionID, cutoff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteProxmoxCephObjects drops the OSDs and pools of a connection right away
+// (Ceph removed, or no longer readable by the token).
+func (db *DB) DeleteProxmoxCephObjects(ctx context.Context, connectionID string) error {
+	if _, err := db.conn.ExecContext(ctx, `DELETE FROM proxmox_ceph_osds WHERE connection_id = $1`, connectionID); err != nil {
+		return err
+	}
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM proxmox_ceph_pools WHERE connection_id = $1`, connectionID)
+	return err
+}
+
+const proxmoxClusterHealthColumns = `
+	h.connection_id, COALESCE(c.name, ''), h.quorate, h.cluster_nodes, h.cluster_nodes_online,
+	h.ceph_available, h.ceph_health, h.ceph_checks, h.ceph_error, h.osds_total, h.osds_up, h.osds_in,
+	h.pgs_total, h.pgs_active_clean, h.pg_states, h.ceph_bytes_used, h.ceph_bytes_total,
+	h.mons_total, h.mons_in_quorum, h.ceph_mons, h.ha_enabled, h.ha_quorate, h.ha_master_node, h.ha_error,
+	h.updated_at`
+
+func scanProxmoxClusterHealth(row interface{ Scan(...any) error }) (*models.ProxmoxClusterHealth, error) {
+	var h models.ProxmoxClusterHealth
+	var checks, pgStates, mons []byte
+	if err := row.Scan(&h.ConnectionID, &h.ConnectionName, &h.Quorate, &h.ClusterNodes, &h.ClusterNodesOnline,
+		&h.CephAvailable, &h.CephHealth, &checks, &h.CephError, &h.OSDsTotal, &h.OSDsUp, &h.OSDsIn,
+		&h.PGsTotal, &h.PGsActiveClean, &pgStates, &h.CephBytesUsed, &h.CephBytesTotal,
+		&h.MonsTotal, &h.MonsInQuorum, &mons, &h.HAEnabled, &h.HAQuorate, &h.HAMasterNode, &h.HAError,
+		&h.UpdatedAt); err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(checks, &h.CephChecks)
+	_ = json.Unmarshal(pgStates, &h.PGStates)
+	_ = json.Unmarshal(mons, &h.Mons)
+	h.CephChecks = nonNilSlice(h.CephChecks)
+	h.PGStates = nonNilSlice(h.PGStates)
+	h.Mons = nonNilSlice(h.Mons)
+	return &h, nil
+}
+
+// ListProxmoxClusterHealth returns the health summary of every connection
+// (without the per-object lists).
+func (db *DB) ListProxmoxClusterHealth(ctx context.Context) ([]models.ProxmoxClusterHealth, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+proxmoxClusterHealthColumns+`
+		FROM proxmox_cluster_health h
+		JOIN proxmox_connections c ON c.id = h.connection_id
+		ORDER BY c.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxClusterHealth{}
+	for rows.Next() {
+		h, err := scanProxmoxClusterHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *h)
+	}
+	return out, rows.Err()
+}
+
+// GetProxmoxClusterHealth returns the health of one connection with its OSDs,
+// pools and HA entries, or sql.ErrNoRows before the first poll.
+func (db *DB) GetProxmoxClusterHealth(ctx context.Context, connectionID string) (*models.ProxmoxClusterHealth, error) {
+	h, err := scanProxmoxClusterHealth(db.conn.QueryRowContext(ctx, `
+		SELECT `+proxmoxClusterHealthColumns+`
+		FROM proxmox_cluster_health h
+		JOIN proxmox_connections c ON c.id = h.connection_id
+		WHERE h.connection_id = $1`, connectionID))
+	if err != nil {
+		return nil, err
+	}
+	if h.OSDs, err = db.listProxmoxCephOSDs(ctx, connectionID); err != nil {
+		return nil, err
+	}
+	if h.Pools, err = db.listProxmoxCephPools(ctx, connectionID); err != nil {
+		return nil, err
+	}
+	if h.HAEntries, err = db.listProxmoxHAEntries(ctx, connectionID); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+func (db *DB) listProxmoxCephOSDs(ctx context.Context, connectionID string) ([]models.ProxmoxCephOSD, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT osd_id, name, host, status, is_in, device_class, percent_used, last_seen_at
+		FROM proxmox_ceph_osds WHERE connection_id = $1 ORDER BY host, osd_id`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxCephOSD{}
+	for rows.Next() {
+		var o models.ProxmoxCephOSD
+		if err := rows.Scan(&o.OSDID, &o.Name, &o.Host, &o.Status, &o.In, &o.DeviceClass, &o.PercentUsed, &o.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+func (db *DB) listProxmoxCephPools(ctx context.Context, connectionID string) ([]models.ProxmoxCephPool, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT name, size, min_size, pg_num, bytes_used, percent_used, last_seen_at
+		FROM proxmox_ceph_pools WHERE connection_id = $1 ORDER BY name`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxCephPool{}
+	for rows.Next() {
+		var p models.ProxmoxCephPool
+		if err := rows.Scan(&p.Name, &p.Size, &p.MinSize, &p.PGNum, &p.BytesUsed, &p.PercentUsed, &p.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (db *DB) listProxmoxHAEntries(ctx context.Context, connectionID string) ([]models.ProxmoxHAEntry, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT entry_id, entry_type, node_name, sid, state, request_state, status, healthy, last_seen_at
+		FROM proxmox_ha_status WHERE connection_id = $1
+		ORDER BY CASE entry_type WHEN 'master' THEN 0 WHEN 'lrm' THEN 1 ELSE 2 END, entry_id`, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxHAEntry{}
+	for rows.Next() {
+		var e models.ProxmoxHAEntry
+		if err := rows.Scan(&e.EntryID, &e.EntryType, &e.NodeName, &e.SID, &e.State, &e.RequestState, &e.Status, &e.Healthy, &e.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// proxmoxClusterMetricSQL maps each cluster health metric to its value for one
+// connection (over h = proxmox_cluster_health) and for one node (over h and
+// n = proxmox_nodes). Cluster-wide facts (Ceph health, PGs, pools, corosync)
+// evaluate the same at node scope, except that an offline node has lost quorum.
+var proxmoxClusterMetricSQL = map[string]struct{ conn, node string }{
+	"proxmox_ceph_health": {
+		conn: `CASE WHEN NOT h.ceph_available OR h.ceph_health = 'HEALTH_OK' THEN 0
+			WHEN h.ceph_health = 'HEALTH_WARN' THEN 1 ELSE 2 END`,
+	},
+	"proxmox_ceph_osds_down": {
+		conn: `CASE WHEN h.ceph_available THEN GREATEST(h.osds_total - h.osds_up, 0) ELSE 0 END`,
+		node: `(SELECT COUNT(*) FROM proxmox_ceph_osds o
+			WHERE o.connection_id = n.connection_id AND o.host = n.node_name AND o.status <> 'up')`,
+	},
+	"proxmox_ceph_osds_out": {
+		conn: `CASE WHEN h.ceph_available THEN GREATEST(h.osds_total - h.osds_in, 0) ELSE 0 END`,
+		node: `(SELECT COUNT(*) FROM proxmox_ceph_osds o
+			WHERE o.connection_id = n.connection_id AND o.host = n.node_name AND NOT o.is_in)`,
+	},
+	"proxmox_ceph_pgs_not_clean": {
+		conn: `CASE WHEN h.ceph_available THEN GREATEST(h.pgs_total - h.pgs_active_clean, 0) ELSE 0 END`,
+	},
+	"proxmox_ceph_pool_max_percent": {
+		conn: `(SELECT COALESCE(MAX(p.percent_used), 0) FROM proxmox_ceph_pools p WHERE p.connection_id = h.connection_id)`,
+	},
+	"proxmox_ceph_mons_out_of_quorum": {
+		conn: `CASE WHEN h.ceph_available THEN GREATEST(h.mons_total - h.mons_in_quorum, 0) ELSE 0 END`,
+		node: `(SELECT COUNT(*) FROM jsonb_to_recordset(h.ceph_mons) AS m(name TEXT, host TEXT, in_quorum BOOLEAN)
+			WHERE h.ceph_available AND m.host = n.node_name AND NOT m.in_quorum)`,
+	},
+	"proxmox_ha_resources_error": {
+		conn: `(SELECT COUNT(*) FROM proxmox_ha_status s
+			WHERE s.connection_id = h.connection_id AND s.entry_type = 'service' AND NOT s.healthy)`,
+		node: `(SELECT COUNT(*) FROM proxmox_ha_status s
+			WHERE s.connection_id = n.connection_id AND s.node_name = n.node_name AND s.entry_type = 'service' AND NOT s.healthy)`,
+	},
+	"proxmox_ha_manager_errors": {
+		conn: `(SELECT COUNT(*) FROM proxmox_ha_status s
+			WHERE s.connection_id = h.connection_id AND s.entry_type IN ('master', 'lrm') AND NOT s.healthy)`,
+		node: `(SELECT COUNT(*) FROM proxmox_ha_status s
+			WHERE s.connection_id = n.connection_id AND s.node_name = n.node_name AND s.entry_type IN ('master', 'lrm') AND NOT s.healthy)`,
+	},
+	"proxmox_cluster_quorum_lost": {
+		conn: `CASE WHEN h.cluster_nodes > 0 AND NOT h.quorate THEN 1 ELSE 0 END`,
+		node: `CASE WHEN (h.cluster_nodes > 0 AND NOT h.quorate) OR n.status <> 'online' THEN 1 ELSE 0 END`,
+	},
+}
+
+// GetProxmoxClusterHealthValue evaluates a cluster health alert metric for a
+// node (nodeID set), a connection (connectionID set) or globally — the worst
+// connection. Connections never polled yet evaluate to 0.
+func (db *DB) GetProxmoxClusterHealthValue(ctx context.Context, metric, connectionID, nodeID string) (float64, error) {
+	q, ok := proxmoxClusterMetricSQL[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown Proxmox cluster health metric %q", metric)
+	}
+	var v sql.NullFloat64
+	var err error
+	switch {
+	case nodeID != "":
+		expr := q.node
+		if expr == "" {
+			expr = q.conn
+		}
+		err = db.conn.QueryRowContext(ctx, `
+			SELECT MAX(`+expr+`)::float8
+			FROM proxmox_nodes n
+			JOIN proxmox_cluster_health h ON h.connection_id = n.connection_id
+			WHERE n.id = $1`, nodeID).Scan(&v)
+	case connectionID != "":
+		err = db.conn.QueryRowContext(ctx, `
+			SELECT MAX(`+q.conn+`)::float8 FROM proxmox_cluster_health h WHERE h.connection_id = $1`, connectionID).Scan(&v)
+	default:
+		err = db.conn.QueryRowContext(ctx, `
+			SELECT MAX(`+q.conn+`)::float8 FROM proxmox_cluster_health h`).Scan(&v)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return v.Float64, nil
+}
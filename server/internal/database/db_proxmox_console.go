@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// CreateProxmoxConsoleSession parks a console session until it is attached,
+// purging the expired ones left by sessions never attached.
+func (db *DB) CreateProxmoxConsoleSession(ctx context.Context, s *models.ProxmoxPendingConsole) error {
+	if _, err := db.conn.ExecContext(ctx, `DELETE FROM proxmox_console_sessions WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+	_, err := db.conn.ExecContext(ctx,
+		`INSERT INTO proxmox_console_sessions (id, username, client_ip, kind, guest_id, host_id, ticket, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		s.ID, s.Username, s.ClientIP, s.Kind, s.GuestID, s.HostID, s.Ticket, s.ExpiresAt)
+	return err
+}
+
+// ClaimProxmoxConsoleSession removes and returns the session id opened by
+// username, or nil when there is none. The delete makes the claim atomic
+// across replicas: a session is attached at most once. Expiry is left to the
+// caller.
+func (db *DB) ClaimProxmoxConsoleSession(ctx context.Context, id, username string) (*models.ProxmoxPendingConsole, error) {
+	var s models.ProxmoxPendingConsole
+	err := db.conn.QueryRowContext(ctx,
+		`DELETE FROM proxmox_console_sessions WHERE id = $1 AND username = $2
+		 RETURNING id, username, client_ip, kind, guest_id::text, host_id, ticket, expires_at`,
+		id, username,
+	).Scan(&s.ID, &s.Username, &s.ClientIP, &s.Kind, &s.GuestID, &s.HostID, &s.Ticket, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,52 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestProxmoxConsoleSession_ClaimOnce: a parked session is returned once, to
+// its owner only, and opening a new one purges the expired leftovers.
+func TestProxmoxConsoleSession_ClaimOnce(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+	connID, _ := seedProxmoxConnNode(t, db)
+	var guestID string
+	if err := db.QueryRow(ctx,
+		`INSERT INTO proxmox_guests (connection_id, node_name, guest_type, vmid, name, status)
+		 VALUES ($1, 'pve1', 'qemu', 100, 'vm100', 'running') RETURNING id`, connID).Scan(&guestID); err != nil {
+		t.Fatalf("seed guest: %v", err)
+	}
+
+	expired := &models.ProxmoxPendingConsole{ID: "old", Username: "alice", Kind: "vnc", GuestID: guestID, Ticket: "{}",
+		ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := db.CreateProxmoxConsoleSession(ctx, expired); err != nil {
+		t.Fatalf("create expired: %v", err)
+	}
+	sess := &models.ProxmoxPendingConsole{ID: "s1", Username: "alice", ClientIP: "10.0.0.1", Kind: "term", GuestID: guestID,
+		HostID: "host-1", Ticket: `{"ticket":"PVE:x"}`, ExpiresAt: time.Now().Add(15 * time.Second)}
+	if err := db.CreateProxmoxConsoleSession(ctx, sess); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if got, err := db.ClaimProxmoxConsoleSession(ctx, "old", "alice"); err != nil || got != nil {
+		t.Errorf("expired session not purged: %+v, %v", got, err)
+	}
+	if got, err := db.ClaimProxmoxConsoleSession(ctx, "s1", "bob"); err != nil || got != nil {
+		t.Errorf("claimed by another user: %+v, %v", got, err)
+	}
+	got, err := db.ClaimProxmoxConsoleSession(ctx, "s1", "alice")
+	if err != nil || got == nil {
+		t.Fatalf("claim: %+v, %v", got, err)
+	}
+	if got.GuestID != guestID || got.Kind != "term" || got.HostID != "host-1" || got.ClientIP != "10.0.0.1" || got.Ticket != sess.Ticket {
+		t.Errorf("claimed = %+v", got)
+	}
+	if again, err := db.ClaimProxmoxConsoleSession(ctx, "s1", "alice"); err != nil || again != nil {
+		t.Errorf("claimed twice: %+v, %v", again, err)
+	}
+}
@@ -135,11 +135,11 @@ func (db *DB) DeleteStaleProxmoxNodes(ctx context.Context, connectionID string,
 }
 
 // UpsertProxmoxStorage inserts or updates a storage record.
-func (db *DB) UpsertProxmoxStorage(ctx context.Context, connectionID, nodeName, storageName, storageType string, total, used, avail int64, enabled, active, shared bool) error {
+func (db *DB) UpsertProxmoxStorage(ctx context.Context, connectionID, nodeName, storageName, storageType string, total, used, avail int64, enabled, active, shared bool, content string) error {
 	_, err := db.conn.ExecContext(ctx, `
 		INSERT INTO proxmox_storages
-		    (connection_id, node_name, storage_name, storage_type, total, used, avail, enabled, active, shared, last_seen_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,NOW())
+		    (connection_id, node_name, storage_name, storage_type, total, used, avail, enabled, active, shared, content, last_seen_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,NOW())
 		ON CONFLICT (connection_id, node_name, storage_name) DO UPDATE SET
 		    storage_type = EXCLUDED.storage_type,
 		    total        = EXCLUDED.total,
@@ -148,8 +148,9 @@ func (db *DB) UpsertProxmoxStorage(ctx context.Context, connectionID, nodeName,
 		    enabled      = EXCLUDED.enabled,
 		    active       = EXCLUDED.active,
 		    shared       = EXCLUDED.shared,
+		    content      = EXCLUDED.content,
 		    last_seen_at = NOW()`,
-		connectionID, nodeName, storageName, storageType, total, used, avail, enabled, active, shared,
+		connectionID, nodeName, storageName, storageType, total, used, avail, enabled, active, shared, content,
 	)
 	return err
 }
@@ -158,7 +159,7 @@ func (db *DB) UpsertProxmoxStorage(ctx context.Context, connectionID, nodeName,
 func (db *DB) ListProxmoxStoragesByNode(ctx context.Context, connectionID, nodeName string) ([]models.ProxmoxStorage, error) {
 	rows, err := db.conn.QueryContext(ctx, `
 		SELECT id, connection_id, node_name, storage_name, storage_type,
-		       total, used, avail, enabled, active, shared, last_seen_at
+		       total, used, avail, enabled, active, shared, content, last_seen_at
 		FROM proxmox_storages
 		WHERE connection_id=$1 AND node_name=$2
 		ORDER BY storage_name`, connectionID, nodeName)
@@ -169,13 +170,31 @@ func (db *DB) ListProxmoxStoragesByNode(ctx context.Context, connectionID, nodeN
 	return scanStorages(rows)
 }
 
+// GetProxmoxStorageByID returns one storage row, or nil when it does not exist.
+func (db *DB) GetProxmoxStorageByID(ctx context.Context, id string) (*models.ProxmoxStorage, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, connection_id, node_name, storage_name, storage_type,
+		       total, used, avail, enabled, active, shared, content, last_seen_at
+		FROM proxmox_storages
+		WHERE id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	storages, err := scanStorages(rows)
+	if err != nil || len(storages) == 0 {
+		return nil, err
+	}
+	return &storages[0], nil
+}
+
 func scanStorages(rows *sql.Rows) ([]models.ProxmoxStorage, error) {
 	var storages []models.ProxmoxStorage
 	for rows.Next() {
 		var s models.ProxmoxStorage
 		if err := rows.Scan(
 			&s.ID, &s.ConnectionID, &s.NodeName, &s.StorageName, &s.StorageType,
-			&s.Total, &s.Used, &s.Avail, &s.Enabled, &s.Active, &s.Shared, &s.LastSeenAt,
+			&s.Total, &s.Used, &s.Avail, &s.Enabled, &s.Active, &s.Shared, &s.Content, &s.LastSeenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -100,13 +100,14 @@ func scanProxmoxSummary(rows *sql.Rows) ([]models.ProxmoxNodeMetricsSummary, err
 
 // ─── Guest metrics ────────────────────────────────────────────────────────────
 
-// InsertProxmoxGuestMetric stores a point-in-time snapshot of a guest's CPU/RAM.
+// InsertProxmoxGuestMetric stores a point-in-time snapshot of a guest's CPU/RAM,
+// balloon size and CPU pressure (0 when PVE does not report them).
 // Called by the poller after each successful UpsertProxmoxGuest for running guests.
-func (db *DB) InsertProxmoxGuestMetric(ctx context.Context, guestID string, cpuUsage float64, memTotal, memUsed int64) error {
+func (db *DB) InsertProxmoxGuestMetric(ctx context.Context, guestID string, cpuUsage float64, memTotal, memUsed, balloon int64, cpuPressure float64) error {
 	_, err := db.conn.ExecContext(ctx, `
-		INSERT INTO proxmox_guest_metrics (guest_id, cpu_usage, mem_total, mem_used, timestamp)
-		VALUES ($1, $2, $3, $4, NOW())`,
-		guestID, cpuUsage, memTotal, memUsed,
+		INSERT INTO proxmox_guest_metrics (guest_id, cpu_usage, mem_total, mem_used, balloon, cpu_pressure, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
+		guestID, cpuUsage, memTotal, memUsed, balloon, cpuPressure,
 	)
 	return err
 }
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Proxmox VM provisioning ==========
+
+const proxmoxProvisionColumns = `
+	p.id, p.connection_id, COALESCE(c.name, ''), p.node_name, p.template_vmid, p.vmid, p.name,
+	p.cores, p.memory_mb, p.disk, p.disk_size_gb, p.bridge, p.vlan_tag, p.ip_config, p.ssh_authorized_keys,
+	p.host_id, p.guest_id::text, p.seed_fetched_at, p.status, p.error, p.created_by,
+	p.created_at, p.updated_at, p.linked_at`
+
+func scanProxmoxProvision(row interface{ Scan(...any) error }) (*models.ProxmoxProvision, error) {
+	var p models.ProxmoxProvision
+	var hostID, guestID sql.NullString
+	var seedFetched, linked sql.NullTime
+	if err := row.Scan(&p.ID, &p.ConnectionID, &p.ConnectionName, &p.NodeName, &p.TemplateVMID, &p.VMID, &p.Name,
+		&p.Cores, &p.MemoryMB, &p.Disk, &p.DiskSizeGB, &p.Bridge, &p.VLANTag, &p.IPConfig, &p.SSHAuthorizedKeys,
+		&hostID, &guestID, &seedFetched, &p.Status, &p.Error, &p.CreatedBy,
+		&p.CreatedAt, &p.UpdatedAt, &linked); err != nil {
+		return nil, err
+	}
+	if hostID.Valid {
+		p.HostID = &hostID.String
+	}
+	if guestID.Valid {
+		p.GuestID = &guestID.String
+	}
+	p.SeedFetchedAt = nullTimePtr(seedFetched)
+	p.LinkedAt = nullTimePtr(linked)
+	return &p, nil
+}
+
+// CreateProxmoxProvision inserts a provision in the 'pending' state.
+// seedTokenHash is the SHA-256 (hex) of the cloud-init seed token.
+func (db *DB) CreateProxmoxProvision(ctx context.Context, p models.ProxmoxProvision, seedTokenHash string) (string, error) {
+	var id string
+	err := db.conn.QueryRowContext(ctx, `
+		INSERT INTO proxmox_provisions (connection_id, node_name, template_vmid, vmid, name, cores, memory_mb,
+			disk, disk_size_gb, bridge, vlan_tag, ip_config, ssh_authorized_keys, host_id, seed_token_hash, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id`,
+		p.ConnectionID, p.NodeName, p.TemplateVMID, p.VMID, p.Name, p.Cores, p.MemoryMB,
+		p.Disk, p.DiskSizeGB, p.Bridge, p.VLANTag, p.IPConfig, p.SSHAuthorizedKeys, p.HostID, seedTokenHash, p.CreatedBy,
+	).Scan(&id)
+	return id, err
+}
+
+// GetProxmoxProvision returns one provision, or sql.ErrNoRows.
+func (db *DB) GetProxmoxProvision(ctx context.Context, id string) (*models.ProxmoxProvision, error) {
+	return scanProxmoxProvision(db.conn.QueryRowContext(ctx, `
+		SELECT `+proxmoxProvisionColumns+`
+		FROM proxmox_provisions p
+		LEFT JOIN proxmox_connections c ON c.id = p.connection_id
+		WHERE p.id = $1`, id))
+}
+
+// GetProxmoxProvisionBySeedToken returns the provision owning a cloud-init
+// seed token (looked up by its SHA-256), or sql.ErrNoRows.
+func (db *DB) GetProxmoxProvisionBySeedToken(ctx context.Context, seedTokenHash string) (*models.ProxmoxProvision, error) {
+	return scanProxmoxProvision(db.conn.QueryRowContext(ctx, `
+		SELECT `+proxmoxProvisionColumns+`
+		FROM proxmox_provisions p
+		LEFT JOIN proxmox_connections c ON c.id = p.connection_id
+		WHERE p.seed_token_hash = $1`, seedTokenHash))
+}
+
+// ListProxmoxProvisions returns the most recent provisions, newest first.
+func (db *DB) ListProxmoxProvisions(ctx context.Context, limit int) ([]models.ProxmoxProvision, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT `+proxmoxProvisionColumns+`
+		FROM proxmox_provisions p
+		LEFT JOIN proxmox_connections c ON c.id = p.connection_id
+		ORDER BY p.created_at DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxProvision{}
+	for rows.Next() {
+		p, err := scanProxmoxProvision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// UpdateProxmoxProvisionStatus moves a provision to status, recording errMsg
+// (cleared when empty).
+func (db *DB) UpdateProxmoxProvisionStatus(ctx context.Context, id, status, errMsg string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE proxmox_provisions SET status = $2, error = $3, updated_at = NOW() WHERE id = $1`,
+		id, status, errMsg)
+	return err
+}
+
+// SetProxmoxProvisionVMID records the VMID allocated for the clone.
+func (db *DB) SetProxmoxProvisionVMID(ctx context.Context, id string, vmid int) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE proxmox_provisions SET vmid = $2, updated_at = NOW() WHERE id = $1`, id, vmid)
+	return err
+}
+
+// MarkProxmoxProvisionSeedFetched stamps the last cloud-init user-data fetch.
+func (db *DB) MarkProxmoxProvisionSeedFetched(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE proxmox_provisions SET seed_fetched_at = NOW(), updated_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+// DeleteProxmoxProvision removes a provision record (the VM and host are kept).
+func (db *DB) DeleteProxmoxProvision(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM proxmox_provisions WHERE id = $1`, id)
+	return err
+}
+
+// LinkProvisionedGuests confirms the guest↔host link of every provision
+// waiting for its agent once both sides exist: the host has reported (status
+// online) and the poller has seen the new VMID. hostID restricts the pass to
+// one host ("" = all). Returns the number of links created.
+func (db *DB) LinkProvisionedGuests(ctx context.Context, hostID string) (int, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT p.id, p.host_id, g.id
+		FROM proxmox_provisions p
+		JOIN hosts h          ON h.id = p.host_id AND h.status = 'online'
+		JOIN proxmox_guests g ON g.connection_id = p.connection_id AND g.vmid = p.vmid AND g.guest_type = 'vm'
+		WHERE p.status = 'waiting_agent'
+		  AND ($1 = '' OR p.host_id = $1)`, hostID)
+	if err != nil {
+		return 0, err
+	}
+	type ready struct{ id, hostID, guestID string }
+	var pending []ready
+	for rows.Next() {
+		var r ready
+		if err := rows.Scan(&r.id, &r.hostID, &r.guestID); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	linked := 0
+	for _, r := range pending {
+		if _, err := db.UpsertProxmoxGuestLink(ctx, r.guestID, r.hostID, "confirmed", "auto"); err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("proxmox provision %s: link guest %s to host %s: %v", r.id, r.guestID, r.hostID, err))
+			continue
+		}
+		if _, err := db.conn.ExecContext(ctx, `
+			UPDATE proxmox_provisions
+			SET status = 'linked', guest_id = $2, linked_at = NOW(), updated_at = NOW()
+			WHERE id = $1`, r.id, r.guestID); err != nil {
+			return linked, err
+		}
+		linked++
+	}
+	return linked, nil
+}
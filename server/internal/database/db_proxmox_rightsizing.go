@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ProxmoxGuestUsage is a running guest's usage over the right-sizing window,
+// next to its current allocation. Ratios are shares of the allocation (0-1).
+// Reserved for the right-sizing analysis — never returned to API clients.
+type ProxmoxGuestUsage struct {
+	models.ProxmoxGuest
+	Samples      int
+	Since        time.Time // oldest sample in the window
+	MemP95       float64
+	MemUsedP95   int64
+	CPUP95       float64
+	BalloonRatio float64 // share of samples with the balloon below the allocation
+	CPUPressure  float64 // average CPU pressure stall, %
+}
+
+// GetProxmoxGuestUsage aggregates proxmox_guest_metrics since the given time
+// for every guest currently running. Reads the raw hypertable: percentiles
+// cannot be rebuilt from the 5-minute aggregate's averages.
+func (db *DB) GetProxmoxGuestUsage(ctx context.Context, since time.Time) ([]ProxmoxGuestUsage, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT g.id, g.connection_id, g.node_name, g.guest_type, g.vmid, g.name, g.status,
+		       g.cpu_alloc, g.cpu_usage, g.mem_alloc, g.mem_usage,
+		       m.samples, m.since, m.mem_p95, m.mem_used_p95, m.cpu_p95, m.balloon_ratio, m.cpu_pressure
+		FROM proxmox_guests g
+		JOIN (
+			SELECT guest_id,
+			       COUNT(*) AS samples,
+			       MIN(timestamp) AS since,
+			       percentile_cont(0.95) WITHIN GROUP (
+			           ORDER BY CASE WHEN mem_total > 0 THEN mem_used::float / mem_total ELSE 0 END) AS mem_p95,
+			       percentile_cont(0.95) WITHIN GROUP (ORDER BY mem_used) AS mem_used_p95,
+			       percentile_cont(0.95) WITHIN GROUP (ORDER BY cpu_usage) AS cpu_p95,
+			       AVG(CASE WHEN balloon > 0 AND balloon < mem_total THEN 1.0 ELSE 0.0 END) AS balloon_ratio,
+			       AVG(cpu_pressure) AS cpu_pressure
+			FROM proxmox_guest_metrics
+			WHERE timestamp > $1
+			GROUP BY guest_id
+		) m ON m.guest_id = g.id
+		WHERE g.status = 'running'
+		ORDER BY g.connection_id, g.node_name, g.vmid`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []ProxmoxGuestUsage
+	for rows.Next() {
+		var u ProxmoxGuestUsage
+		var memUsedP95 float64
+		if err := rows.Scan(&u.ID, &u.ConnectionID, &u.NodeName, &u.GuestType, &u.VMID, &u.Name, &u.Status,
+			&u.CPUAlloc, &u.CPUUsage, &u.MemAlloc, &u.MemUsage,
+			&u.Samples, &u.Since, &u.MemP95, &memUsedP95, &u.CPUP95, &u.BalloonRatio, &u.CPUPressure); err != nil {
+			return nil, err
+		}
+		u.MemUsedP95 = int64(memUsedP95)
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// CreateProxmoxRightsizingRun opens a run row.
+func (db *DB) CreateProxmoxRightsizingRun(ctx context.Context) (string, error) {
+	var id string
+	err := db.conn.QueryRowContext(ctx,
+		`INSERT INTO proxmox_rightsizing_runs DEFAULT VALUES RETURNING id`).Scan(&id)
+	return id, err
+}
+
+// FinishProxmoxRightsizingRun stores a run's recommendations and outcome,
+// then keeps only the 10 most recent runs.
+func (db *DB) FinishProxmoxRightsizingRun(ctx context.Context, run models.ProxmoxRightsizingRun, recs []models.ProxmoxRightsizingRecommendation) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range recs {
+		var guestID sql.NullString
+		if r.GuestID != "" {
+			guestID = sql.NullString{String: r.GuestID, Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO proxmox_rightsizing_recommendations
+			  (run_id, connection_id, guest_id, node_name, kind, severity,
+			   current_cpus, current_mem, suggested_cpus, suggested_mem, target_node, reason,
+			   mem_p95, cpu_p95, balloon_ratio, cpu_pressure)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`,
+			run.ID, r.ConnectionID, guestID, r.NodeName, r.Kind, r.Severity,
+			r.CurrentCPUs, r.CurrentMem, r.SuggestedCPUs, r.SuggestedMem, r.TargetNode, r.Reason,
+			r.MemP95, r.CPUP95, r.BalloonRatio, r.CPUPressure,
+		); err != nil {
+			return fmt.Errorf("insert rightsizing recommendation: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE proxmox_rightsizing_runs
+		SET finished_at=NOW(), status=$2, guests_analyzed=$3, nodes_analyzed=$4, recommendations=$5, error=$6
+		WHERE id=$1`,
+		run.ID, run.Status, run.GuestsAnalyzed, run.NodesAnalyzed, len(recs), run.Error,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM proxmox_rightsizing_runs
+		WHERE id NOT IN (SELECT id FROM proxmox_rightsizing_runs ORDER BY started_at DESC LIMIT 10)`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetLatestProxmoxRightsizingRun returns the most recent run, or nil when
+// none ever ran.
+func (db *DB) GetLatestProxmoxRightsizingRun(ctx context.Context) (*models.ProxmoxRightsizingRun, error) {
+	var r models.ProxmoxRightsizingRun
+	var finished sql.NullTime
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT id, started_at, finished_at, status, guests_analyzed, nodes_analyzed, recommendations, error
+		FROM proxmox_rightsizing_runs
+		ORDER BY started_at DESC
+		LIMIT 1`).Scan(&r.ID, &r.StartedAt, &finished, &r.Status,
+		&r.GuestsAnalyzed, &r.NodesAnalyzed, &r.Recommendations, &r.Error)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if finished.Valid {
+		r.FinishedAt = &finished.Time
+	}
+	return &r, nil
+}
+
+// ListProxmoxRightsizingRecommendations returns the recommendations of the
+// latest successful run, most severe first. Guest and node details are read
+// live, so a guest migrated since the run shows its new node.
+func (db *DB) ListProxmoxRightsizingRecommendations(ctx context.Context) ([]models.ProxmoxRightsizingRecommendation, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT r.id, r.connection_id, c.name, COALESCE(r.guest_id::text, ''),
+		       COALESCE(g.name, ''), COALESCE(g.guest_type, ''), COALESCE(g.vmid, 0),
+		       COALESCE(g.node_name, r.node_name), COALESCE(n.id::text, ''),
+		       r.kind, r.severity, r.current_cpus, r.current_mem, r.suggested_cpus, r.suggested_mem,
+		       r.target_node, r.reason, r.mem_p95, r.cpu_p95, r.balloon_ratio, r.cpu_pressure
+		FROM proxmox_rightsizing_recommendations r
+		JOIN proxmox_connections c ON c.id = r.connection_id
+		LEFT JOIN proxmox_guests g ON g.id = r.guest_id
+		LEFT JOIN proxmox_nodes n ON n.connection_id = r.connection_id
+		                         AND n.node_name = COALESCE(g.node_name, r.node_name)
+		WHERE r.run_id = (
+			SELECT id FROM proxmox_rightsizing_runs
+			WHERE status = 'success'
+			ORDER BY started_at DESC
+			LIMIT 1)
+		ORDER BY CASE r.severity WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END,
+		         c.name, r.node_name, COALESCE(g.vmid, 0)`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := []models.ProxmoxRightsizingRecommendation{}
+	for rows.Next() {
+		var r models.ProxmoxRightsizingRecommendation
+		if err := rows.Scan(&r.ID, &r.ConnectionID, &r.ConnectionName, &r.GuestID,
+			&r.GuestName, &r.GuestType, &r.VMID, &r.NodeName, &r.NodeID,
+			&r.Kind, &r.Severity, &r.CurrentCPUs, &r.CurrentMem, &r.SuggestedCPUs, &r.SuggestedMem,
+			&r.TargetNode, &r.Reason, &r.MemP95, &r.CPUP95, &r.BalloonRatio, &r.CPUPressure); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
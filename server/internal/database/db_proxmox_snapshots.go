@@ -0,0 +1,228 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ========== Proxmox snapshot policies ==========
+
+const proxmoxSnapshotPolicyColumns = `
+	p.id, p.name, p.enabled, p.schedule, p.keep_last, p.keep_daily, p.keep_weekly, p.keep_monthly,
+	p.vmstate, p.last_run_at, p.last_status, p.last_error, p.next_run_at, p.created_at, p.updated_at,
+	COALESCE((SELECT array_agg(pg.guest_id::text ORDER BY pg.guest_id)
+	          FROM proxmox_snapshot_policy_guests pg WHERE pg.policy_id = p.id), '{}')`
+
+func scanProxmoxSnapshotPolicy(row interface{ Scan(...any) error }) (*models.ProxmoxSnapshotPolicy, error) {
+	var p models.ProxmoxSnapshotPolicy
+	var lastRun, nextRun sql.NullTime
+	var guestIDs pq.StringArray
+	if err := row.Scan(&p.ID, &p.Name, &p.Enabled, &p.Schedule, &p.KeepLast, &p.KeepDaily, &p.KeepWeekly, &p.KeepMonthly,
+		&p.VMState, &lastRun, &p.LastStatus, &p.LastError, &nextRun, &p.CreatedAt, &p.UpdatedAt, &guestIDs); err != nil {
+		return nil, err
+	}
+	if lastRun.Valid {
+		p.LastRunAt = &lastRun.Time
+	}
+	if nextRun.Valid {
+		p.NextRunAt = &nextRun.Time
+	}
+	p.GuestIDs = []string(guestIDs)
+	if p.GuestIDs == nil {
+		p.GuestIDs = []string{}
+	}
+	return &p, nil
+}
+
+func (db *DB) queryProxmoxSnapshotPolicies(ctx context.Context, where string, args ...any) ([]models.ProxmoxSnapshotPolicy, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+proxmoxSnapshotPolicyColumns+` FROM proxmox_snapshot_policies p `+where+` ORDER BY p.name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxSnapshotPolicy{}
+	for rows.Next() {
+		p, err := scanProxmoxSnapshotPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// ListProxmoxSnapshotPolicies returns every policy with its guest IDs.
+func (db *DB) ListProxmoxSnapshotPolicies(ctx context.Context) ([]models.ProxmoxSnapshotPolicy, error) {
+	return db.queryProxmoxSnapshotPolicies(ctx, "")
+}
+
+// ListDueProxmoxSnapshotPolicies returns the enabled policies whose next run
+// is at or before now.
+func (db *DB) ListDueProxmoxSnapshotPolicies(ctx context.Context, now time.Time) ([]models.ProxmoxSnapshotPolicy, error) {
+	return db.queryProxmoxSnapshotPolicies(ctx, "WHERE p.enabled AND p.next_run_at <= $1", now)
+}
+
+// GetProxmoxSnapshotPolicy returns one policy, or sql.ErrNoRows.
+func (db *DB) GetProxmoxSnapshotPolicy(ctx context.Context, id string) (*models.ProxmoxSnapshotPolicy, error) {
+	row := db.conn.QueryRowContext(ctx,
+		`SELECT `+proxmoxSnapshotPolicyColumns+` FROM proxmox_snapshot_policies p WHERE p.id = $1`, id)
+	return scanProxmoxSnapshotPolicy(row)
+}
+
+// CreateProxmoxSnapshotPolicy inserts a policy and its guest set.
+func (db *DB) CreateProxmoxSnapshotPolicy(ctx context.Context, p models.ProxmoxSnapshotPolicy) (*models.ProxmoxSnapshotPolicy, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var id string
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO proxmox_snapshot_policies
+		  (name, enabled, schedule, keep_last, keep_daily, keep_weekly, keep_monthly, vmstate, next_run_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		RETURNING id`,
+		p.Name, p.Enabled, p.Schedule, p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.VMState, p.NextRunAt,
+	).Scan(&id); err != nil {
+		return nil, fmt.Errorf("create snapshot policy: %w", err)
+	}
+	if err := replaceProxmoxSnapshotPolicyGuests(ctx, tx, id, p.GuestIDs); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return db.GetProxmoxSnapshotPolicy(ctx, id)
+}
+
+// UpdateProxmoxSnapshotPolicy rewrites a policy's settings and guest set.
+// Run bookkeeping (last_run_at, last_status…) is left untouched.
+func (db *DB) UpdateProxmoxSnapshotPolicy(ctx context.Context, p models.ProxmoxSnapshotPolicy) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE proxmox_snapshot_policies
+		SET name=$2, enabled=$3, schedule=$4, keep_last=$5, keep_daily=$6, keep_weekly=$7, keep_monthly=$8,
+		    vmstate=$9, next_run_at=$10, updated_at=NOW()
+		WHERE id=$1`,
+		p.ID, p.Name, p.Enabled, p.Schedule, p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.VMState, p.NextRunAt,
+	); err != nil {
+		return fmt.Errorf("update snapshot policy: %w", err)
+	}
+	if err := replaceProxmoxSnapshotPolicyGuests(ctx, tx, p.ID, p.GuestIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceProxmoxSnapshotPolicyGuests(ctx context.Context, tx *sql.Tx, policyID string, guestIDs []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM proxmox_snapshot_policy_guests WHERE policy_id=$1`, policyID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO proxmox_snapshot_policy_guests (policy_id, guest_id)
+		SELECT $1, g::uuid FROM unnest($2::text[]) AS g
+		ON CONFLICT DO NOTHING`, policyID, pq.Array(guestIDs)); err != nil {
+		return fmt.Errorf("set snapshot policy guests: %w", err)
+	}
+	return nil
+}
+
+// DeleteProxmoxSnapshotPolicy removes a policy (guests and runs cascade).
+func (db *DB) DeleteProxmoxSnapshotPolicy(ctx context.Context, id string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM proxmox_snapshot_policies WHERE id=$1`, id)
+	return err
+}
+
+// FinishProxmoxSnapshotPolicy records the outcome of a whole policy run,
+// schedules the next one and trims run rows older than 90 days.
+func (db *DB) FinishProxmoxSnapshotPolicy(ctx context.Context, id string, ranAt time.Time, status, lastError string, nextRunAt *time.Time) error {
+	if _, err := db.conn.ExecContext(ctx, `
+		UPDATE proxmox_snapshot_policies
+		SET last_run_at=$2, last_status=$3, last_error=$4, next_run_at=$5
+		WHERE id=$1`, id, ranAt, status, lastError, nextRunAt); err != nil {
+		return err
+	}
+	_, err := db.conn.ExecContext(ctx, `
+		DELETE FROM proxmox_snapshot_policy_runs
+		WHERE policy_id=$1 AND started_at < NOW() - INTERVAL '90 days'`, id)
+	return err
+}
+
+// CreateProxmoxSnapshotRun opens a run row for one guest of a policy run.
+func (db *DB) CreateProxmoxSnapshotRun(ctx context.Context, policyID, guestID string) (int64, error) {
+	var id int64
+	err := db.conn.QueryRowContext(ctx, `
+		INSERT INTO proxmox_snapshot_policy_runs (policy_id, guest_id) VALUES ($1,$2) RETURNING id`,
+		policyID, guestID).Scan(&id)
+	return id, err
+}
+
+// FinishProxmoxSnapshotRun closes a run row.
+func (db *DB) FinishProxmoxSnapshotRun(ctx context.Context, id int64, status, snapshotName string, pruned int, errMsg string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE proxmox_snapshot_policy_runs
+		SET finished_at=NOW(), status=$2, snapshot_name=$3, pruned=$4, error=$5
+		WHERE id=$1`, id, status, snapshotName, pruned, errMsg)
+	return err
+}
+
+// ListProxmoxSnapshotRuns returns a policy's most recent guest runs, newest first.
+func (db *DB) ListProxmoxSnapshotRuns(ctx context.Context, policyID string, limit int) ([]models.ProxmoxSnapshotPolicyRun, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT r.id, r.policy_id, r.guest_id, COALESCE(g.name, ''), COALESCE(g.vmid, 0),
+		       r.started_at, r.finished_at, r.status, r.snapshot_name, r.pruned, r.error
+		FROM proxmox_snapshot_policy_runs r
+		LEFT JOIN proxmox_guests g ON g.id = r.guest_id
+		WHERE r.policy_id = $1
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT $2`, policyID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	out := []models.ProxmoxSnapshotPolicyRun{}
+	for rows.Next() {
+		var r models.ProxmoxSnapshotPolicyRun
+		var finished sql.NullTime
+		if err := rows.Scan(&r.ID, &r.PolicyID, &r.GuestID, &r.GuestName, &r.VMID,
+			&r.StartedAt, &finished, &r.Status, &r.SnapshotName, &r.Pruned, &r.Error); err != nil {
+			return nil, err
+		}
+		if finished.Valid {
+			r.FinishedAt = &finished.Time
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CountProxmoxSnapshotPolicyFailures counts the (enabled policy, guest) pairs
+// whose latest finished run failed — the proxmox_snapshot_policy_failures
+// alert metric. guestID "" counts across every guest. A guest removed from a
+// policy no longer counts, even though its old runs are kept.
+func (db *DB) CountProxmoxSnapshotPolicyFailures(ctx context.Context, guestID string) (int, error) {
+	var n int
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT DISTINCT ON (r.policy_id, r.guest_id) r.status
+			FROM proxmox_snapshot_policy_runs r
+			JOIN proxmox_snapshot_policy_guests pg ON pg.policy_id = r.policy_id AND pg.guest_id = r.guest_id
+			JOIN proxmox_snapshot_policies p ON p.id = r.policy_id AND p.enabled
+			WHERE r.finished_at IS NOT NULL AND ($1 = '' OR r.guest_id::text = $1)
+			ORDER BY r.policy_id, r.guest_id, r.started_at DESC
+		) latest
+		WHERE latest.status = 'failed'`, guestID).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const threatRuleColumns = `id, name, description, category, severity, method_pattern, path_pattern, query_pattern, user_agent_pattern, status_pattern, enabled, source, created_by, created_at, updated_at`
+
+func scanThreatRule(row interface{ Scan(...any) error }) (*models.ThreatRule, error) {
+	var r models.ThreatRule
+	if err := row.Scan(&r.ID, &r.Name, &r.Description, &r.Category, &r.Severity,
+		&r.MethodPattern, &r.PathPattern, &r.QueryPattern, &r.UserAgentPattern, &r.StatusPattern,
+		&r.Enabled, &r.Source, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListThreatRules returns every threat rule, built-ins first, without hit
+// counters (see ThreatRuleHits).
+func (db *DB) ListThreatRules(ctx context.Context) ([]models.ThreatRule, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT `+threatRuleColumns+` FROM threat_rules
+		 ORDER BY (source = 'builtin') DESC, source, created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.ThreatRule, 0)
+	for rows.Next() {
+		r, err := scanThreatRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+// GetThreatRule returns one rule, or sql.ErrNoRows.
+func (db *DB) GetThreatRule(ctx context.Context, id string) (*models.ThreatRule, error) {
+	return scanThreatRule(db.conn.QueryRowContext(ctx,
+		`SELECT `+threatRuleColumns+` FROM threat_rules WHERE id = $1`, id))
+}
+
+// CreateThreatRule inserts a rule with the id chosen by the caller.
+func (db *DB) CreateThreatRule(ctx context.Context, r *models.ThreatRule) (*models.ThreatRule, error) {
+	return scanThreatRule(db.conn.QueryRowContext(ctx,
+		`INSERT INTO threat_rules (id, name, description, category, severity, method_pattern, path_pattern, query_pattern, user_agent_pattern, status_pattern, enabled, source, created_by)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING `+threatRuleColumns,
+		r.ID, r.Name, r.Description, r.Category, r.Severity,
+		r.MethodPattern, r.PathPattern, r.QueryPattern, r.UserAgentPattern, r.StatusPattern,
+		r.Enabled, r.Source, r.CreatedBy))
+}
+
+// UpdateThreatRule saves every editable field of r. Returns sql.ErrNoRows
+// when the rule no longer exists.
+func (db *DB) UpdateThreatRule(ctx context.Context, r *models.ThreatRule) (*models.ThreatRule, error) {
+	return scanThreatRule(db.conn.QueryRowContext(ctx,
+		`UPDATE threat_rules
+		 SET name = $2, description = $3, category = $4, severity = $5,
+		     method_pattern = $6, path_pattern = $7, query_pattern = $8, user_agent_pattern = $9, status_pattern = $10,
+		     enabled = $11, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+threatRuleColumns,
+		r.ID, r.Name, r.Description, r.Category, r.Severity,
+		r.MethodPattern, r.PathPattern, r.QueryPattern, r.UserAgentPattern, r.StatusPattern,
+		r.Enabled))
+}
+
+// DeleteThreatRule removes a rule and reports whether it existed. Requests it
+// already classified keep their category and rule_id.
+func (db *DB) DeleteThreatRule(ctx context.Context, id string) (bool, error) {
+	res, err := db.conn.ExecContext(ctx, `DELETE FROM threat_rules WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UpsertThreatRules inserts the given built-in or pack rules, or refreshes
+// their definition when they already exist — keeping the enabled state an
+// admin chose. Returns how many were created and updated.
+func (db *DB) UpsertThreatRules(ctx context.Context, rules []models.ThreatRule) (created, updated int, err error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, r := range rules {
+		var inserted bool
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO threat_rules (id, name, description, category, severity, method_pattern, path_pattern, query_pattern, user_agent_pattern, status_pattern, enabled, source, created_by)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			 ON CONFLICT (id) DO UPDATE
+			 SET name = EXCLUDED.name, description = EXCLUDED.description,
+			     category = EXCLUDED.category, severity = EXCLUDED.severity,
+			     method_pattern = EXCLUDED.method_pattern, path_pattern = EXCLUDED.path_pattern,
+			     query_pattern = EXCLUDED.query_pattern, user_agent_pattern = EXCLUDED.user_agent_pattern,
+			     status_pattern = EXCLUDED.status_pattern, source = EXCLUDED.source, updated_at = NOW()
+			 RETURNING (xmax = 0)`,
+			r.ID, r.Name, r.Description, r.Category, r.Severity,
+			r.MethodPattern, r.PathPattern, r.QueryPattern, r.UserAgentPattern, r.StatusPattern,
+			r.Enabled, r.Source, r.CreatedBy,
+		).Scan(&inserted); err != nil {
+			return 0, 0, err
+		}
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+	return created, updated, tx.Commit()
+}
+
+// ThreatRuleHits counts, per rule id, the stored requests each rule
+// classified over the last 24 hours and 7 days, with the latest hit.
+func (db *DB) ThreatRuleHits(ctx context.Context) (map[string]models.ThreatRuleHits, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT rule_id,
+		        COUNT(*) FILTER (WHERE captured_at >= NOW() - INTERVAL '24 hours'),
+		        COUNT(*),
+		        MAX(captured_at)
+		 FROM web_log_requests
+		 WHERE rule_id IS NOT NULL AND captured_at >= NOW() - INTERVAL '7 days'
+		 GROUP BY rule_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make(map[string]models.ThreatRuleHits)
+	for rows.Next() {
+		var id string
+		var h models.ThreatRuleHits
+		var last sql.NullTime
+		if err := rows.Scan(&id, &h.Hits24h, &h.Hits7d, &last); err != nil {
+			return nil, err
+		}
+		if last.Valid {
+			h.LastHitAt = &last.Time
+		}
+		out[id] = h
+	}
+	return out, rows.Err()
+}
+
+// RecentWebRequestsForRuleTest returns the latest stored requests since the
+// given time, newest first and capped at limit, for replaying a draft rule.
+func (db *DB) RecentWebRequestsForRuleTest(ctx context.Context, since time.Time, limit int) ([]models.ThreatRuleSample, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT method, path, COALESCE(query, ''), COALESCE(user_agent, ''), status
+		 FROM web_log_requests
+		 WHERE captured_at >= $1
+		 ORDER BY captured_at DESC
+		 LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.ThreatRuleSample, 0)
+	for rows.Next() {
+		var s models.ThreatRuleSample
+		if err := rows.Scan(&s.Method, &s.Path, &s.Query, &s.UserAgent, &s.Status); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
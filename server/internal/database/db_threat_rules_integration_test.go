@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+	"github.com/serversupervisor/server/internal/threatdetect"
+)
+
+// TestThreatRules_UpsertKeepsEnabledAndCountsHits checks that refreshing the
+// built-in rules keeps an admin's choice to disable one, and that stored
+// requests record the rule that classified them for the hit counters.
+func TestThreatRules_UpsertKeepsEnabledAndCountsHits(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	created, _, err := db.UpsertThreatRules(ctx, threatdetect.BuiltinRules())
+	if err != nil || created != len(threatdetect.BuiltinRules()) {
+		t.Fatalf("first upsert: created=%d err=%v", created, err)
+	}
+	r, err := db.GetThreatRule(ctx, "builtin-admin-panel")
+	if err != nil {
+		t.Fatalf("get rule: %v", err)
+	}
+	r.Enabled = false
+	if _, err := db.UpdateThreatRule(ctx, r); err != nil {
+		t.Fatalf("disable rule: %v", err)
+	}
+	created, updated, err := db.UpsertThreatRules(ctx, threatdetect.BuiltinRules())
+	if err != nil || created != 0 || updated != len(threatdetect.BuiltinRules()) {
+		t.Fatalf("second upsert: created=%d updated=%d err=%v", created, updated, err)
+	}
+	if r, _ := db.GetThreatRule(ctx, "builtin-admin-panel"); r == nil || r.Enabled {
+		t.Error("upsert re-enabled a disabled rule")
+	}
+
+	hostID := "rules-host"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "web", Hostname: "web.local", IPAddress: "10.0.0.22", Status: "online",
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+	now := time.Now().UTC()
+	if err := db.InsertWebLogSnapshot(ctx, hostID, &models.WebLogReport{
+		Source:      "nginx",
+		Traffic:     &models.TrafficSummary{},
+		Threats:     &models.ThreatSummary{},
+		CollectedAt: now,
+		Requests: []models.WebRequest{
+			{IP: "1.1.1.1", Method: "GET", Path: "/wp-login.php", Query: "redirect=1", Status: 404},
+			{IP: "1.1.1.2", Method: "GET", Path: "/", Status: 200},
+		},
+	}); err != nil {
+		t.Fatalf("insert web log snapshot: %v", err)
+	}
+
+	hits, err := db.ThreatRuleHits(ctx)
+	if err != nil {
+		t.Fatalf("ThreatRuleHits: %v", err)
+	}
+	if h := hits["builtin-wordpress"]; h.Hits24h != 1 || h.Hits7d != 1 || h.LastHitAt == nil {
+		t.Errorf("builtin-wordpress hits = %+v, want 1/1", h)
+	}
+	samples, err := db.RecentWebRequestsForRuleTest(ctx, now.Add(-time.Hour), 10)
+	if err != nil || len(samples) != 2 {
+		t.Fatalf("replay samples = %v, %v", samples, err)
+	}
+}
@@ -123,8 +123,8 @@ func (db *DB) InsertWebLogSnapshot(ctx context.Context, hostID string, report *m
 		suspicious := req.Category != ""
 		fingerprint := webLogFingerprint(hostID, report.Source, ts, req, suspicious)
 		if _, err := tx.ExecContext(ctx,
-			`INSERT INTO web_log_requests (snapshot_id, host_id, captured_at, source, ip, method, path, status, bytes, user_agent, domain, category, suspicious, fingerprint, blocked, blocked_source, blocked_reason, blocked_at, blocked_until)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+			`INSERT INTO web_log_requests (snapshot_id, host_id, captured_at, source, ip, method, path, status, bytes, user_agent, domain, category, suspicious, fingerprint, blocked, blocked_source, blocked_reason, blocked_at, blocked_until, response_time_ms, upstream_time_ms, upstream_status, upstream, query, rule_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
 			 ON CONFLICT (host_id, source, fingerprint) DO UPDATE
 			 SET blocked = EXCLUDED.blocked,
 			     blocked_source = EXCLUDED.blocked_source,
@@ -151,6 +151,13 @@ func (db *DB) InsertWebLogSnapshot(ctx context.Context, hostID string, report *m
 			req.BlockedReason,
 			req.BlockedAt,
 			req.BlockedUntil,
+			// nil means "not logged by this format", stored as NULL.
+			req.ResponseTimeMs,
+			req.UpstreamTimeMs,
+			sql.NullInt64{Int64: int64(req.UpstreamStatus), Valid: req.UpstreamStatus > 0},
+			sql.NullString{String: req.Upstream, Valid: req.Upstream != ""},
+			sql.NullString{String: req.Query, Valid: req.Query != ""},
+			sql.NullString{String: req.RuleID, Valid: req.RuleID != ""},
 		); err != nil {
 			return err
 		}
@@ -28,7 +28,7 @@ func (db *DB) GetIPTimeline(ctx context.Context, ip string, since, until time.Ti
 	args = append(args, limit)
 
 	rows, err := db.conn.QueryContext(ctx,
-		fmt.Sprintf(`SELECT r.captured_at, r.host_id, h.name, r.source, r.ip, r.method, r.path, r.status, r.bytes, COALESCE(r.user_agent,''), COALESCE(r.domain,''), COALESCE(r.category,''), r.blocked, COALESCE(r.blocked_source,''), COALESCE(r.blocked_reason,''), r.blocked_at, r.blocked_until
+		fmt.Sprintf(`SELECT r.captured_at, r.host_id, h.name, r.source, r.ip, r.method, r.path, r.status, r.bytes, COALESCE(r.user_agent,''), COALESCE(r.domain,''), COALESCE(r.category,''), r.blocked, COALESCE(r.blocked_source,''), COALESCE(r.blocked_reason,''), r.blocked_at, r.blocked_until, r.response_time_ms, r.upstream_status, COALESCE(r.upstream,'')
 		FROM web_log_requests r
 		JOIN hosts h ON h.id = r.host_id
 		WHERE %s
@@ -44,7 +44,7 @@ func (db *DB) GetIPTimeline(ctx context.Context, ip string, since, until time.Ti
 	out := make([]models.WebLogIPTimelineRow, 0)
 	for rows.Next() {
 		var row models.WebLogIPTimelineRow
-		if err := rows.Scan(&row.Timestamp, &row.HostID, &row.HostName, &row.Source, &row.IP, &row.Method, &row.Path, &row.Status, &row.Bytes, &row.UserAgent, &row.Domain, &row.Category, &row.Blocked, &row.BlockedSource, &row.BlockedReason, &row.BlockedAt, &row.BlockedUntil); err != nil {
+		if err := rows.Scan(&row.Timestamp, &row.HostID, &row.HostName, &row.Source, &row.IP, &row.Method, &row.Path, &row.Status, &row.Bytes, &row.UserAgent, &row.Domain, &row.Category, &row.Blocked, &row.BlockedSource, &row.BlockedReason, &row.BlockedAt, &row.BlockedUntil, &row.ResponseTimeMs, &row.UpstreamStatus, &row.Upstream); err != nil {
 			return nil, err
 		}
 		out = append(out, row)
@@ -329,7 +329,7 @@ func (db *DB) GetWebLogsLive(ctx context.Context, hostID string, source string,
 	args = append(args, limit)
 
 	rows, err := db.conn.QueryContext(ctx,
-		fmt.Sprintf(`SELECT r.captured_at, r.host_id, h.name, r.source, r.ip, r.method, r.path, r.status, r.bytes, COALESCE(r.user_agent,''), COALESCE(r.domain,''), COALESCE(r.category,''), r.suspicious
+		fmt.Sprintf(`SELECT r.captured_at, r.host_id, h.name, r.source, r.ip, r.method, r.path, r.status, r.bytes, COALESCE(r.user_agent,''), COALESCE(r.domain,''), COALESCE(r.category,''), r.suspicious, r.response_time_ms, r.upstream_status, COALESCE(r.upstream,'')
 		FROM web_log_requests r
 		JOIN hosts h ON h.id = r.host_id
 		WHERE %s
@@ -349,10 +349,13 @@ func (db *DB) GetWebLogsLive(ctx context.Context, hostID string, source string,
 		var status int
 		var bytes int64
 		var suspicious bool
-		if err := rows.Scan(&capturedAt, &rowHostID, &rowHostName, &rowSource, &ip, &method, &path, &status, &bytes, &userAgent, &domain, &category, &suspicious); err != nil {
+		var responseTimeMs sql.NullFloat64
+		var upstreamStatus sql.NullInt64
+		var upstream string
+		if err := rows.Scan(&capturedAt, &rowHostID, &rowHostName, &rowSource, &ip, &method, &path, &status, &bytes, &userAgent, &domain, &category, &suspicious, &responseTimeMs, &upstreamStatus, &upstream); err != nil {
 			return nil, err
 		}
-		out = append(out, map[string]any{
+		entry := map[string]any{
 			"timestamp":  capturedAt,
 			"host_id":    rowHostID,
 			"host_name":  rowHostName,
@@ -366,7 +369,17 @@ func (db *DB) GetWebLogsLive(ctx context.Context, hostID string, source string,
 			"domain":     domain,
 			"category":   category,
 			"suspicious": suspicious,
-		})
+		}
+		if responseTimeMs.Valid {
+			entry["response_time_ms"] = responseTimeMs.Float64
+		}
+		if upstreamStatus.Valid {
+			entry["upstream_status"] = upstreamStatus.Int64
+		}
+		if upstream != "" {
+			entry["upstream"] = upstream
+		}
+		out = append(out, entry)
 	}
 
 	return out, nil
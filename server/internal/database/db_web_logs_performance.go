@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// webRouteExpr normalizes a request path into a route: the query string is
+// dropped and numeric, UUID and long hex segments collapse to ":id", so
+// /users/42/orders and /users/7/orders are aggregated together.
+const webRouteExpr = `regexp_replace(split_part(path, '?', 1),
+	'/([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})(?=/|$)',
+	'/:id', 'g')`
+
+// webPerformanceColumns are the aggregates behind models.WebPerformanceStat.
+// The ordered-set percentiles skip the NULL response times of formats that
+// do not log them.
+const webPerformanceColumns = `COUNT(*), COUNT(response_time_ms),
+	COALESCE(SUM(CASE WHEN status BETWEEN 400 AND 499 THEN 1 ELSE 0 END),0),
+	COALESCE(SUM(CASE WHEN status >= 500 THEN 1 ELSE 0 END),0),
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time_ms),
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time_ms),
+	percentile_cont(0.99) WITHIN GROUP (ORDER BY response_time_ms)`
+
+// GetWebLogsPerformance returns the latency percentiles and error counts per
+// domain and per normalized route (busiest first, at most routeLimit routes)
+// over the window.
+func (db *DB) GetWebLogsPerformance(ctx context.Context, since, until time.Time, hostID, source string, routeLimit int) (domains, routes []models.WebPerformanceStat, err error) {
+	where, args := buildWebLogsWhere(since, until, hostID, source)
+
+	domains, err = db.queryWebPerformance(ctx,
+		fmt.Sprintf(`SELECT COALESCE(domain,''), '', %s
+		FROM web_log_requests
+		WHERE %s
+		GROUP BY 1
+		ORDER BY 3 DESC`, webPerformanceColumns, where),
+		args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args = append(args, routeLimit)
+	routes, err = db.queryWebPerformance(ctx,
+		fmt.Sprintf(`SELECT COALESCE(domain,''), %s, %s
+		FROM web_log_requests
+		WHERE %s
+		GROUP BY 1, 2
+		ORDER BY 3 DESC
+		LIMIT $%d`, webRouteExpr, webPerformanceColumns, where, len(args)),
+		args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return domains, routes, nil
+}
+
+// ListWebLogDomains returns the domains a host served requests for since the
+// given time, the targets of the per-domain alert metrics.
+func (db *DB) ListWebLogDomains(ctx context.Context, hostID string, since time.Time) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT DISTINCT COALESCE(domain,'') FROM web_log_requests
+		WHERE host_id = $1 AND captured_at >= $2
+		ORDER BY 1`,
+		hostID, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	domains := make([]string, 0)
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return nil, err
+		}
+		domains = append(domains, d)
+	}
+	return domains, rows.Err()
+}
+
+// GetWebDomainPerformance returns one domain's stats on a host since the
+// given time (all zero when it served nothing).
+func (db *DB) GetWebDomainPerformance(ctx context.Context, hostID, domain string, since time.Time) (models.WebPerformanceStat, error) {
+	stats, err := db.queryWebPerformance(ctx,
+		fmt.Sprintf(`SELECT $2::text, '', %s
+		FROM web_log_requests
+		WHERE host_id = $1 AND COALESCE(domain,'') = $2 AND captured_at >= $3`, webPerformanceColumns),
+		hostID, domain, since)
+	if err != nil || len(stats) == 0 {
+		return models.WebPerformanceStat{Domain: domain}, err
+	}
+	return stats[0], nil
+}
+
+func (db *DB) queryWebPerformance(ctx context.Context, query string, args ...any) ([]models.WebPerformanceStat, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]models.WebPerformanceStat, 0)
+	for rows.Next() {
+		var s models.WebPerformanceStat
+		var p50, p95, p99 sql.NullFloat64
+		if err := rows.Scan(&s.Domain, &s.Route, &s.Requests, &s.TimedRequests, &s.Errors4xx, &s.Errors5xx, &p50, &p95, &p99); err != nil {
+			return nil, err
+		}
+		s.P50Ms, s.P95Ms, s.P99Ms = p50.Float64, p95.Float64, p99.Float64
+		if s.Requests > 0 {
+			s.ErrorRate = float64(s.Errors5xx) * 100 / float64(s.Requests)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestGetWebLogsPerformance checks route normalization (numeric and UUID
+// segments collapse to :id, the query string is dropped), the percentiles,
+// and that untimed requests count as traffic but not in the percentiles, while
+// a request timed at 0 does.
+func TestGetWebLogsPerformance(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "perf-host"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "proxy", Hostname: "proxy.local", IPAddress: "10.0.0.30", Status: "online",
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+
+	now := time.Now().UTC()
+	report := &models.WebLogReport{
+		Source:      "nginx",
+		Traffic:     &models.TrafficSummary{},
+		Threats:     &models.ThreatSummary{},
+		CollectedAt: now,
+		Requests: []models.WebRequest{
+			{IP: "1.1.1.1", Method: "GET", Path: "/users/42/orders", Status: 200, ResponseTimeMs: msPtr(100), Domain: "shop.test"},
+			{IP: "1.1.1.1", Method: "GET", Path: "/users/7/orders?page=2", Status: 200, ResponseTimeMs: msPtr(200), Domain: "shop.test"},
+			{IP: "1.1.1.1", Method: "GET", Path: "/users/3f2b8c1e-5d4a-4e6f-9a7b-1c2d3e4f5a6b/orders", Status: 500, ResponseTimeMs: msPtr(300), Domain: "shop.test"},
+			{IP: "1.1.1.1", Method: "GET", Path: "/users/42/orders", Status: 503, Domain: "shop.test"},
+			{IP: "1.1.1.2", Method: "GET", Path: "/", Status: 404, ResponseTimeMs: msPtr(10), Domain: "blog.test"},
+			{IP: "1.1.1.2", Method: "GET", Path: "/", Status: 200, ResponseTimeMs: msPtr(0), Domain: "blog.test"},
+		},
+	}
+	if err := db.InsertWebLogSnapshot(ctx, hostID, report); err != nil {
+		t.Fatalf("insert web log snapshot: %v", err)
+	}
+
+	domains, routes, err := db.GetWebLogsPerformance(ctx, now.Add(-time.Hour), time.Time{}, hostID, "", 50)
+	if err != nil {
+		t.Fatalf("GetWebLogsPerformance: %v", err)
+	}
+	if len(domains) != 2 || domains[0].Domain != "shop.test" {
+		t.Fatalf("domains = %+v", domains)
+	}
+	shop := domains[0]
+	if shop.Requests != 4 || shop.TimedRequests != 3 || shop.Errors5xx != 2 || shop.ErrorRate != 50 {
+		t.Errorf("shop.test = %+v", shop)
+	}
+	if shop.P50Ms != 200 {
+		t.Errorf("p50 = %v, want 200 (untimed request excluded)", shop.P50Ms)
+	}
+	if len(routes) != 2 || routes[0].Route != "/users/:id/orders" || routes[0].Requests != 4 {
+		t.Errorf("routes = %+v", routes)
+	}
+
+	stat, err := db.GetWebDomainPerformance(ctx, hostID, "blog.test", now.Add(-time.Hour))
+	if err != nil || stat.Requests != 2 || stat.Errors4xx != 1 || stat.P50Ms != 5 {
+		t.Errorf("blog.test = %+v, %v, want p50 5 (request timed at 0 counted)", stat, err)
+	}
+	names, err := db.ListWebLogDomains(ctx, hostID, now.Add(-time.Hour))
+	if err != nil || len(names) != 2 || names[0] != "blog.test" {
+		t.Errorf("domains = %v, %v", names, err)
+	}
+}
+
+func msPtr(ms float64) *float64 { return &ms }
@@ -86,10 +86,11 @@ func (db *DB) GetWebLogsSummary(ctx context.Context, since, until time.Time, hos
 	var blockedRequests, blockedIPs int64
 	var blockedOK bool
 	var topDomains, topEndpoints, topHosts []map[string]any
+	var upstream map[string]any
 	threatsLocal := map[string]any{}
 	crowdSecLocal := map[string]any{}
 
-	wg.Add(7)
+	wg.Add(8)
 
 	go func() {
 		defer wg.Done()
@@ -154,6 +155,17 @@ func (db *DB) GetWebLogsSummary(ctx context.Context, since, until time.Time, hos
 		topHosts = th
 	}()
 
+	go func() {
+		defer wg.Done()
+		defer safego.Recover(ctx, "weblogs.summary.upstream")
+		u, err := db.upstreamTraffic(ctx, where, args)
+		if err != nil {
+			errs.set(err)
+			return
+		}
+		upstream = u
+	}()
+
 	go func() {
 		defer wg.Done()
 		defer safego.Recover(ctx, "weblogs.summary.threats")
@@ -214,6 +226,7 @@ func (db *DB) GetWebLogsSummary(ctx context.Context, since, until time.Time, hos
 	}
 	traffic["top_proxy_hosts"] = topProxyHosts
 	traffic["top_hosts"] = topHosts
+	traffic["upstream"] = upstream
 
 	for k, v := range crowdSecLocal {
 		threatsLocal[k] = v
@@ -375,6 +388,64 @@ func (db *DB) topHostTraffic(ctx context.Context, where string, args []any) ([]m
 	return topHosts, rows.Err()
 }
 
+// upstreamTraffic aggregates the timing and upstream fields, only logged by
+// some formats (see migration 106): averages cover the requests that carry a
+// timing, and upstream_errors_5xx counts upstream answers, which can differ
+// from what the proxy returned (retries, custom error pages).
+func (db *DB) upstreamTraffic(ctx context.Context, where string, args []any) (map[string]any, error) {
+	var timed, upstream5xx int64
+	var avgResponse, avgUpstream sql.NullFloat64
+	if err := db.conn.QueryRowContext(ctx,
+		fmt.Sprintf(`SELECT COUNT(response_time_ms), AVG(response_time_ms), AVG(upstream_time_ms),
+		COALESCE(SUM(CASE WHEN upstream_status >= 500 THEN 1 ELSE 0 END),0)
+		FROM web_log_requests WHERE %s`, where),
+		args...,
+	).Scan(&timed, &avgResponse, &avgUpstream, &upstream5xx); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.QueryContext(ctx,
+		fmt.Sprintf(`SELECT upstream, COUNT(*) AS hits, AVG(upstream_time_ms),
+		COALESCE(SUM(CASE WHEN COALESCE(upstream_status, status) >= 500 THEN 1 ELSE 0 END),0)
+		FROM web_log_requests
+		WHERE %s AND upstream IS NOT NULL
+		GROUP BY upstream
+		ORDER BY hits DESC
+		LIMIT 20`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	topUpstreams := make([]map[string]any, 0)
+	for rows.Next() {
+		var name string
+		var hits, errors5xx int64
+		var avg sql.NullFloat64
+		if err := rows.Scan(&name, &hits, &avg, &errors5xx); err != nil {
+			return nil, err
+		}
+		topUpstreams = append(topUpstreams, map[string]any{
+			"upstream":             name,
+			"hits":                 hits,
+			"avg_upstream_time_ms": avg.Float64,
+			"errors_5xx":           errors5xx,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"timed_requests":       timed,
+		"avg_response_time_ms": avgResponse.Float64,
+		"avg_upstream_time_ms": avgUpstream.Float64,
+		"upstream_errors_5xx":  upstream5xx,
+		"top_upstreams":        topUpstreams,
+	}, nil
+}
+
 // GetWebLogsThreats computes only the threats portion of the summary (suspicious
 // activity + CrowdSec decisions + a blocked-IP count). It deliberately skips the
 // traffic aggregates — which are unindexed full-table scans over the window —
@@ -460,7 +531,8 @@ func (db *DB) fillWebLogsThreats(ctx context.Context, threats map[string]any, wh
 	// the service re-ranks by computed score and keeps the top 25, so this
 	// must stay well above 25 or a low-hit-but-high-severity IP could be cut
 	// before it ever gets scored. Category literals must match the
-	// threatdetect.Category* constants.
+	// threatdetect.Category* constants; anything else comes from a custom
+	// rule and is counted as "other".
 	ipRows, err := db.conn.QueryContext(ctx,
 		fmt.Sprintf(`SELECT ip,
 		COUNT(*) AS hits,
@@ -478,6 +550,7 @@ func (db *DB) fillWebLogsThreats(ctx context.Context, threats map[string]any, wh
 		COALESCE(SUM(CASE WHEN category = 'PathTraversal' THEN 1 ELSE 0 END),0) AS cat_pathtraversal,
 		COALESCE(SUM(CASE WHEN category = 'KnownScanner' THEN 1 ELSE 0 END),0) AS cat_knownscanner,
 		COALESCE(SUM(CASE WHEN category = 'SuspiciousMethod' THEN 1 ELSE 0 END),0) AS cat_suspiciousmethod,
+		COALESCE(SUM(CASE WHEN category NOT IN ('WordPress','AdminPanel','PathTraversal','KnownScanner','SuspiciousMethod') THEN 1 ELSE 0 END),0) AS cat_other,
 		COALESCE(SUM(CASE WHEN status BETWEEN 200 AND 299 THEN 1 ELSE 0 END),0) AS status_2xx,
 		COALESCE(SUM(CASE WHEN status BETWEEN 300 AND 399 THEN 1 ELSE 0 END),0) AS status_3xx,
 		COALESCE(SUM(CASE WHEN status = 404 THEN 1 ELSE 0 END),0) AS status_404,
@@ -510,7 +583,7 @@ func (db *DB) fillWebLogsThreats(ctx context.Context, threats map[string]any, wh
 		var cat threatdetect.CategoryCounts
 		var st threatdetect.StatusCounts
 		if err := ipRows.Scan(&ip, &hits, &uniquePaths, &hostCount, &firstSeen, &lastSeen, &blockedSource, &blockedReason, &blockedAt, &blockedUntil, &isBlocked,
-			&cat.WordPress, &cat.AdminPanel, &cat.PathTraversal, &cat.KnownScanner, &cat.SuspiciousMethod,
+			&cat.WordPress, &cat.AdminPanel, &cat.PathTraversal, &cat.KnownScanner, &cat.SuspiciousMethod, &cat.Other,
 			&st.Status2xx, &st.Status3xx, &st.Status404, &st.Status4xxOther, &st.Status5xx,
 		); err != nil {
 			return err
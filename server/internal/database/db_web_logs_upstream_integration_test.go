@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/testutil"
+)
+
+// TestGetWebLogsSummary_Upstream checks that timing/upstream fields reach the
+// summary, and that requests from formats without timing are left out of the
+// averages instead of counting as 0 ms.
+func TestGetWebLogsSummary_Upstream(t *testing.T) {
+	db := testutil.NewPostgresDB(t)
+	ctx := context.Background()
+
+	hostID := "lb-host"
+	if err := db.RegisterHost(ctx, &models.Host{
+		ID: hostID, Name: "lb", Hostname: "lb.local", IPAddress: "10.0.0.21", Status: "online",
+	}); err != nil {
+		t.Fatalf("register host: %v", err)
+	}
+
+	now := time.Now().UTC()
+	report := &models.WebLogReport{
+		Source:      "haproxy",
+		Traffic:     &models.TrafficSummary{},
+		Threats:     &models.ThreatSummary{},
+		CollectedAt: now,
+		Requests: []models.WebRequest{
+			{IP: "1.1.1.1", Method: "GET", Path: "/a", Status: 200, ResponseTimeMs: msPtr(100), UpstreamTimeMs: msPtr(80), UpstreamStatus: 200, Upstream: "app/srv1"},
+			{IP: "1.1.1.2", Method: "GET", Path: "/b", Status: 502, ResponseTimeMs: msPtr(300), UpstreamTimeMs: msPtr(280), UpstreamStatus: 503, Upstream: "app/srv1"},
+			{IP: "1.1.1.3", Method: "GET", Path: "/c", Status: 200},
+		},
+	}
+	if err := db.InsertWebLogSnapshot(ctx, hostID, report); err != nil {
+		t.Fatalf("insert web log snapshot: %v", err)
+	}
+
+	summary, err := db.GetWebLogsSummary(ctx, now.Add(-time.Hour), time.Time{}, hostID, "")
+	if err != nil {
+		t.Fatalf("GetWebLogsSummary: %v", err)
+	}
+	upstream := summary["traffic"].(map[string]any)["upstream"].(map[string]any)
+	if got := upstream["timed_requests"].(int64); got != 2 {
+		t.Errorf("timed_requests = %d, want 2", got)
+	}
+	if got := upstream["avg_response_time_ms"].(float64); got != 200 {
+		t.Errorf("avg_response_time_ms = %v, want 200 (untimed request excluded)", got)
+	}
+	if got := upstream["upstream_errors_5xx"].(int64); got != 1 {
+		t.Errorf("upstream_errors_5xx = %d, want 1", got)
+	}
+	top := upstream["top_upstreams"].([]map[string]any)
+	if len(top) != 1 || top[0]["upstream"] != "app/srv1" || top[0]["hits"].(int64) != 2 {
+		t.Errorf("top_upstreams = %v", top)
+	}
+}
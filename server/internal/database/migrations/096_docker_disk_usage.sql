@@ -0,0 +1,65 @@
+-- Migration 096: Docker disk usage accounting (`docker system df -v`
+-- equivalent), reported by the agent every ~15 minutes (see
+-- agent/internal/collector/docker_disk.go — /system/df walks every volume and
+-- writable layer, so it is deliberately not collected on every report).
+--
+-- docker_disk_usage_items is the CURRENT snapshot for the "top consumers"
+-- view: replaced wholesale per host on each measurement, same as
+-- compose_projects. Besides the image/container/volume rows (agent top-N per
+-- kind), it carries one row per *_total kind with an empty name, so the page
+-- never has to sum a truncated list.
+--
+-- docker_disk_usage_metrics is the HISTORY: one row per (measurement, kind,
+-- name) for the totals and for every reported volume and container writable
+-- layer (images are content-addressed and never grow in place, so their
+-- history is not kept). Backs the per-volume growth chart and the
+-- docker_volume_growth_bytes_24h alert metric. Sizes are absolute values, not
+-- deltas — growth is last - first over a window.
+
+CREATE TABLE IF NOT EXISTS docker_disk_usage_items (
+    host_id         VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    kind            VARCHAR(32) NOT NULL,
+    name            VARCHAR(255) NOT NULL DEFAULT '',
+    detail          TEXT NOT NULL DEFAULT '',
+    compose_project VARCHAR(255) NOT NULL DEFAULT '',
+    size_bytes      BIGINT NOT NULL DEFAULT 0,
+    collected_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (host_id, kind, name)
+);
+
+CREATE TABLE IF NOT EXISTS docker_disk_usage_metrics (
+    host_id     VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    "timestamp" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    kind        VARCHAR(32) NOT NULL,
+    name        VARCHAR(255) NOT NULL DEFAULT '',
+    size_bytes  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_docker_disk_usage_metrics_host_kind_name_ts
+    ON docker_disk_usage_metrics (host_id, kind, name, "timestamp" DESC);
+
+-- Same TimescaleDB gating as migration 092. No RGPD concern here (volume and
+-- container names only), so a fixed retention policy like disk_metrics is fine.
+DO $$
+DECLARE
+  tsdb_available BOOLEAN := FALSE;
+BEGIN
+  SELECT EXISTS(SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')
+    INTO tsdb_available;
+
+  IF NOT tsdb_available THEN
+    RAISE NOTICE 'TimescaleDB not available; docker_disk_usage_metrics stays a plain table.';
+    RETURN;
+  END IF;
+
+  CREATE EXTENSION IF NOT EXISTS timescaledb CASCADE;
+
+  IF NOT EXISTS (SELECT 1 FROM timescaledb_information.hypertables
+                 WHERE hypertable_name = 'docker_disk_usage_metrics') THEN
+    PERFORM create_hypertable('docker_disk_usage_metrics', 'timestamp', migrate_data => true);
+    ALTER TABLE docker_disk_usage_metrics
+      SET (timescaledb.compress, timescaledb.compress_segmentby = 'host_id');
+    PERFORM add_compression_policy('docker_disk_usage_metrics', INTERVAL '3 days');
+    PERFORM add_retention_policy('docker_disk_usage_metrics', INTERVAL '90 days');
+  END IF;
+END $$;
@@ -0,0 +1,64 @@
+-- Migration 097: Docker Swarm state, reported by the agent of every Swarm
+-- manager (agent/internal/collector/docker_swarm.go).
+--
+-- Same model as compose_projects: the CURRENT snapshot, replaced wholesale per
+-- host on every report. Each manager reports the whole cluster, so a cluster
+-- with three managing agents appears three times, once per host_id — alert
+-- scopes and the UI pick one manager host, exactly like a compose project is
+-- picked through its host.
+--
+-- docker_swarm_clusters holds one row per host that is currently a manager;
+-- the row (and, by cascade, its services/tasks/nodes) is deleted as soon as
+-- the host reports it is no longer one.
+
+CREATE TABLE IF NOT EXISTS docker_swarm_clusters (
+    host_id          VARCHAR(64) PRIMARY KEY REFERENCES hosts(id) ON DELETE CASCADE,
+    cluster_id       VARCHAR(64) NOT NULL DEFAULT '',
+    node_id          VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS docker_swarm_services (
+    host_id          VARCHAR(64) NOT NULL REFERENCES docker_swarm_clusters(host_id) ON DELETE CASCADE,
+    service_id       VARCHAR(64) NOT NULL,
+    name             VARCHAR(255) NOT NULL,
+    image            TEXT NOT NULL DEFAULT '',
+    mode             VARCHAR(32) NOT NULL DEFAULT '',
+    stack            VARCHAR(255) NOT NULL DEFAULT '',
+    desired_replicas INTEGER NOT NULL DEFAULT 0,
+    running_replicas INTEGER NOT NULL DEFAULT 0,
+    update_state     VARCHAR(32) NOT NULL DEFAULT '',
+    PRIMARY KEY (host_id, service_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_docker_swarm_services_host_name
+    ON docker_swarm_services (host_id, name);
+
+CREATE TABLE IF NOT EXISTS docker_swarm_tasks (
+    host_id       VARCHAR(64) NOT NULL REFERENCES docker_swarm_clusters(host_id) ON DELETE CASCADE,
+    task_id       VARCHAR(64) NOT NULL,
+    service_id    VARCHAR(64) NOT NULL DEFAULT '',
+    service_name  VARCHAR(255) NOT NULL DEFAULT '',
+    node_id       VARCHAR(64) NOT NULL DEFAULT '',
+    slot          INTEGER NOT NULL DEFAULT 0,
+    state         VARCHAR(32) NOT NULL DEFAULT '',
+    desired_state VARCHAR(32) NOT NULL DEFAULT '',
+    message       TEXT NOT NULL DEFAULT '',
+    error         TEXT NOT NULL DEFAULT '',
+    updated_at    TIMESTAMPTZ,
+    PRIMARY KEY (host_id, task_id)
+);
+
+CREATE TABLE IF NOT EXISTS docker_swarm_nodes (
+    host_id        VARCHAR(64) NOT NULL REFERENCES docker_swarm_clusters(host_id) ON DELETE CASCADE,
+    node_id        VARCHAR(64) NOT NULL,
+    hostname       VARCHAR(255) NOT NULL DEFAULT '',
+    role           VARCHAR(16) NOT NULL DEFAULT '',
+    availability   VARCHAR(16) NOT NULL DEFAULT '',
+    state          VARCHAR(16) NOT NULL DEFAULT '',
+    leader         BOOLEAN NOT NULL DEFAULT FALSE,
+    reachability   VARCHAR(32) NOT NULL DEFAULT '',
+    addr           VARCHAR(255) NOT NULL DEFAULT '',
+    engine_version VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (host_id, node_id)
+);
@@ -0,0 +1,58 @@
+-- Migration 098: scheduled Proxmox guest snapshot policies, executed by the
+-- server (internal/services/proxmox/snapshots.go).
+--
+-- A policy snapshots each of its guests on a cron schedule, then prunes the
+-- snapshots IT created (recognised by their "auto_<policy>_" name prefix)
+-- down to the keep_last / keep_daily / keep_weekly / keep_monthly retention.
+-- Manual snapshots are never pruned.
+--
+-- proxmox_snapshot_policy_runs keeps one row per (policy run, guest): the
+-- latest row of each pair feeds the proxmox_snapshot_policy_failures alert
+-- metric. Rows older than 90 days are trimmed after every run.
+
+CREATE TABLE IF NOT EXISTS proxmox_snapshot_policies (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          VARCHAR(255) NOT NULL,
+    enabled       BOOLEAN NOT NULL DEFAULT TRUE,
+    schedule      VARCHAR(100) NOT NULL,
+    keep_last     INTEGER NOT NULL DEFAULT 0,
+    keep_daily    INTEGER NOT NULL DEFAULT 0,
+    keep_weekly   INTEGER NOT NULL DEFAULT 0,
+    keep_monthly  INTEGER NOT NULL DEFAULT 0,
+    vmstate       BOOLEAN NOT NULL DEFAULT FALSE,
+    last_run_at   TIMESTAMPTZ,
+    last_status   VARCHAR(20) NOT NULL DEFAULT '',
+    last_error    TEXT NOT NULL DEFAULT '',
+    next_run_at   TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT proxmox_snapshot_policies_keeps_something
+        CHECK (keep_last + keep_daily + keep_weekly + keep_monthly > 0)
+);
+
+CREATE TABLE IF NOT EXISTS proxmox_snapshot_policy_guests (
+    policy_id  UUID NOT NULL REFERENCES proxmox_snapshot_policies(id) ON DELETE CASCADE,
+    guest_id   UUID NOT NULL REFERENCES proxmox_guests(id) ON DELETE CASCADE,
+    PRIMARY KEY (policy_id, guest_id)
+);
+
+CREATE TABLE IF NOT EXISTS proxmox_snapshot_policy_runs (
+    id             BIGSERIAL PRIMARY KEY,
+    policy_id      UUID NOT NULL REFERENCES proxmox_snapshot_policies(id) ON DELETE CASCADE,
+    guest_id       UUID NOT NULL REFERENCES proxmox_guests(id) ON DELETE CASCADE,
+    started_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at    TIMESTAMPTZ,
+    status         VARCHAR(20) NOT NULL DEFAULT 'running', -- running | success | failed
+    snapshot_name  VARCHAR(64) NOT NULL DEFAULT '',
+    pruned         INTEGER NOT NULL DEFAULT 0,
+    error          TEXT NOT NULL DEFAULT ''
+);
+
+-- Due-policy lookup of the scheduler tick.
+CREATE INDEX IF NOT EXISTS idx_proxmox_snapshot_policies_next_run
+    ON proxmox_snapshot_policies (next_run_at) WHERE enabled;
+-- "Latest run per (policy, guest)" for the alert metric and the run history.
+CREATE INDEX IF NOT EXISTS idx_proxmox_snapshot_policy_runs_latest
+    ON proxmox_snapshot_policy_runs (policy_id, guest_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxmox_snapshot_policy_runs_guest
+    ON proxmox_snapshot_policy_runs (guest_id, started_at DESC);
@@ -0,0 +1,77 @@
+-- Migration 099: Proxmox Backup Server supervision, polled by the server
+-- (internal/services/proxmox/pbs_poller.go).
+--
+-- pbs_connections mirrors proxmox_connections (API token, poll interval,
+-- last error/success). The other tables are the CURRENT state of each
+-- connection, upserted on every poll; rows not seen for 3 poll intervals are
+-- deleted, like the PVE read models.
+--
+-- pbs_backup_groups keeps one row per (datastore, backup group): the time of
+-- the newest snapshot and the state of the newest VERIFIED snapshot. They feed
+-- the pbs_backup_age_hours and pbs_verify_failed alert metrics (Proxmox alert
+-- scope mode "backup_group").
+
+CREATE TABLE IF NOT EXISTS pbs_connections (
+    id                    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name                  TEXT NOT NULL,
+    api_url               TEXT NOT NULL,
+    token_id              TEXT NOT NULL,
+    token_secret          TEXT NOT NULL,
+    insecure_skip_verify  BOOLEAN NOT NULL DEFAULT FALSE,
+    enabled               BOOLEAN NOT NULL DEFAULT TRUE,
+    poll_interval_sec     INTEGER NOT NULL DEFAULT 300,
+    version               TEXT NOT NULL DEFAULT '',
+    last_error            TEXT NOT NULL DEFAULT '',
+    last_error_at         TIMESTAMPTZ,
+    last_success_at       TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS pbs_datastores (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id  UUID NOT NULL REFERENCES pbs_connections(id) ON DELETE CASCADE,
+    store          TEXT NOT NULL,
+    total          BIGINT NOT NULL DEFAULT 0,
+    used           BIGINT NOT NULL DEFAULT 0,
+    avail          BIGINT NOT NULL DEFAULT 0,
+    last_error     TEXT NOT NULL DEFAULT '',
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, store)
+);
+
+CREATE TABLE IF NOT EXISTS pbs_backup_groups (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id       UUID NOT NULL REFERENCES pbs_connections(id) ON DELETE CASCADE,
+    store               TEXT NOT NULL,
+    backup_type         VARCHAR(8) NOT NULL,          -- vm | ct | host
+    backup_id           TEXT NOT NULL,
+    backup_count        INTEGER NOT NULL DEFAULT 0,
+    last_backup_at      TIMESTAMPTZ,
+    owner               TEXT NOT NULL DEFAULT '',
+    comment             TEXT NOT NULL DEFAULT '',
+    last_verify_state   VARCHAR(16) NOT NULL DEFAULT '', -- ok | failed | '' (never verified)
+    last_verify_at      TIMESTAMPTZ,                     -- backup time of that snapshot
+    verify_failed_count INTEGER NOT NULL DEFAULT 0,
+    last_seen_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, store, backup_type, backup_id)
+);
+
+CREATE TABLE IF NOT EXISTS pbs_jobs (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id     UUID NOT NULL REFERENCES pbs_connections(id) ON DELETE CASCADE,
+    job_type          VARCHAR(8) NOT NULL,           -- gc | prune | verify | sync
+    job_id            TEXT NOT NULL,                 -- store name for gc
+    store             TEXT NOT NULL DEFAULT '',
+    schedule          TEXT NOT NULL DEFAULT '',
+    last_run_state    TEXT NOT NULL DEFAULT '',      -- "ok", a warning/error text, or '' (never ran)
+    last_run_at       TIMESTAMPTZ,
+    last_run_upid     TEXT NOT NULL DEFAULT '',
+    next_run_at       TIMESTAMPTZ,
+    remote            TEXT NOT NULL DEFAULT '',
+    remote_store      TEXT NOT NULL DEFAULT '',
+    removed_bytes     BIGINT NOT NULL DEFAULT 0,
+    pending_bytes     BIGINT NOT NULL DEFAULT 0,
+    last_seen_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, job_type, job_id)
+);
@@ -0,0 +1,40 @@
+-- Migration 100: Proxmox VM provisioning (internal/services/proxmox/provision.go).
+--
+-- A provision clones a QEMU template, applies CPU/memory/disk/network, points
+-- cloud-init at a NoCloud seed served by ServerSupervisor (the token's
+-- SHA-256 is stored, never the token) and starts the VM. The seed's user-data
+-- installs the agent with a freshly minted API key of host_id; once that host
+-- reports, the guest is linked to it (status 'linked').
+--
+-- status: pending → cloning → configuring → starting → waiting_agent → linked,
+-- or failed (error holds the reason).
+
+CREATE TABLE IF NOT EXISTS proxmox_provisions (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id       UUID NOT NULL REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    node_name           VARCHAR(255) NOT NULL,
+    template_vmid       INTEGER NOT NULL,
+    vmid                INTEGER NOT NULL DEFAULT 0,
+    name                VARCHAR(63) NOT NULL,
+    cores               INTEGER NOT NULL DEFAULT 0,
+    memory_mb           INTEGER NOT NULL DEFAULT 0,
+    disk                VARCHAR(16) NOT NULL DEFAULT '',
+    disk_size_gb        INTEGER NOT NULL DEFAULT 0,
+    bridge              VARCHAR(64) NOT NULL DEFAULT '',
+    vlan_tag            INTEGER NOT NULL DEFAULT 0,
+    ip_config           VARCHAR(255) NOT NULL DEFAULT '',
+    ssh_authorized_keys TEXT NOT NULL DEFAULT '',
+    host_id             VARCHAR(64) REFERENCES hosts(id) ON DELETE SET NULL,
+    guest_id            UUID REFERENCES proxmox_guests(id) ON DELETE SET NULL,
+    seed_token_hash     VARCHAR(64) NOT NULL UNIQUE,
+    seed_fetched_at     TIMESTAMPTZ,
+    status              VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error               TEXT NOT NULL DEFAULT '',
+    created_by          VARCHAR(255) NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    linked_at           TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_proxmox_provisions_waiting
+    ON proxmox_provisions (host_id) WHERE status = 'waiting_agent';
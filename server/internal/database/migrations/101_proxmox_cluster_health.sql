@@ -0,0 +1,85 @@
+-- Migration 101: Ceph, HA manager and corosync health of each Proxmox
+-- connection, polled with the rest of the cluster (internal/services/proxmox/
+-- cluster_health.go).
+--
+-- proxmox_cluster_health holds one row per connection (a connection is a
+-- cluster, or a standalone node). proxmox_ceph_osds, proxmox_ceph_pools and
+-- proxmox_ha_status are the CURRENT state, upserted on every poll; rows not
+-- seen for 3 poll intervals are deleted, like the other PVE read models.
+--
+-- They feed the proxmox_ceph_*, proxmox_ha_* and proxmox_cluster_quorum_lost
+-- alert metrics (Proxmox alert scope modes global / connection / node).
+
+CREATE TABLE IF NOT EXISTS proxmox_cluster_health (
+    connection_id        UUID PRIMARY KEY REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    -- corosync (GET /cluster/status); cluster_nodes = 0 on a standalone node
+    quorate              BOOLEAN NOT NULL DEFAULT TRUE,
+    cluster_nodes        INTEGER NOT NULL DEFAULT 0,
+    cluster_nodes_online INTEGER NOT NULL DEFAULT 0,
+    -- Ceph (GET /cluster/ceph/status); ceph_available = FALSE when the
+    -- cluster does not run Ceph or the token cannot read it
+    ceph_available       BOOLEAN NOT NULL DEFAULT FALSE,
+    ceph_health          VARCHAR(20) NOT NULL DEFAULT '', -- HEALTH_OK | HEALTH_WARN | HEALTH_ERR | HEALTH_UNKNOWN
+    ceph_checks          JSONB NOT NULL DEFAULT '[]',      -- [{code, severity, message, muted}]
+    ceph_error           TEXT NOT NULL DEFAULT '',
+    osds_total           INTEGER NOT NULL DEFAULT 0,
+    osds_up              INTEGER NOT NULL DEFAULT 0,
+    osds_in              INTEGER NOT NULL DEFAULT 0,
+    pgs_total            INTEGER NOT NULL DEFAULT 0,
+    pgs_active_clean     INTEGER NOT NULL DEFAULT 0,
+    pg_states            JSONB NOT NULL DEFAULT '[]',      -- [{state, count}]
+    ceph_bytes_used      BIGINT NOT NULL DEFAULT 0,
+    ceph_bytes_total     BIGINT NOT NULL DEFAULT 0,
+    mons_total           INTEGER NOT NULL DEFAULT 0,
+    mons_in_quorum       INTEGER NOT NULL DEFAULT 0,
+    ceph_mons            JSONB NOT NULL DEFAULT '[]',      -- [{name, host, in_quorum}]
+    -- HA manager (GET /cluster/ha/status/current)
+    ha_enabled           BOOLEAN NOT NULL DEFAULT FALSE,   -- at least one HA resource
+    ha_quorate           BOOLEAN NOT NULL DEFAULT TRUE,
+    ha_master_node       VARCHAR(255) NOT NULL DEFAULT '',
+    ha_error             TEXT NOT NULL DEFAULT '',
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS proxmox_ceph_osds (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id  UUID NOT NULL REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    osd_id         INTEGER NOT NULL,
+    name           VARCHAR(64) NOT NULL,
+    host           VARCHAR(255) NOT NULL DEFAULT '',
+    status         VARCHAR(16) NOT NULL DEFAULT '', -- up | down
+    is_in          BOOLEAN NOT NULL DEFAULT FALSE,
+    device_class   VARCHAR(32) NOT NULL DEFAULT '',
+    percent_used   DOUBLE PRECISION NOT NULL DEFAULT -1,
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, osd_id)
+);
+
+CREATE TABLE IF NOT EXISTS proxmox_ceph_pools (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id  UUID NOT NULL REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    name           VARCHAR(255) NOT NULL,
+    size           INTEGER NOT NULL DEFAULT 0,
+    min_size       INTEGER NOT NULL DEFAULT 0,
+    pg_num         INTEGER NOT NULL DEFAULT 0,
+    bytes_used     BIGINT NOT NULL DEFAULT 0,
+    percent_used   DOUBLE PRECISION NOT NULL DEFAULT 0, -- 0–100
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, name)
+);
+
+-- One row per LRM (one per node), the CRM master and each HA resource.
+CREATE TABLE IF NOT EXISTS proxmox_ha_status (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    connection_id  UUID NOT NULL REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    entry_id       VARCHAR(255) NOT NULL,           -- master | lrm:<node> | service:<sid>
+    entry_type     VARCHAR(16) NOT NULL,            -- master | lrm | service
+    node_name      VARCHAR(255) NOT NULL DEFAULT '',
+    sid            VARCHAR(64) NOT NULL DEFAULT '', -- vm:100 / ct:101 (services)
+    state          VARCHAR(32) NOT NULL DEFAULT '',
+    request_state  VARCHAR(32) NOT NULL DEFAULT '',
+    status         TEXT NOT NULL DEFAULT '',
+    healthy        BOOLEAN NOT NULL DEFAULT TRUE,
+    last_seen_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (connection_id, entry_id)
+);
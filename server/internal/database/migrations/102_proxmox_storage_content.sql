@@ -0,0 +1,5 @@
+-- Migration 102: content types a Proxmox storage accepts (PVE's comma-separated
+-- "content" field: iso,vztmpl,backup,images,rootdir,snippets…). The storage
+-- content browser (internal/services/proxmox/storage_content.go) uses it to
+-- offer the ISO/template download only on storages that can hold one.
+ALTER TABLE proxmox_storages ADD COLUMN IF NOT EXISTS content TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,62 @@
+-- Migration 103: Proxmox right-sizing recommendations, computed weekly by the
+-- server from the guest metrics history (internal/services/proxmox/
+-- rightsizing.go).
+--
+-- proxmox_guest_metrics gains the two signals of an underprovisioned guest
+-- that CPU%/RAM% alone do not show:
+--   balloon       current balloon size of a QEMU VM in bytes (0 = no balloon
+--                 device, or an LXC); below mem_total the host is taking
+--                 memory back from the guest
+--   cpu_pressure  CPU pressure stall of the guest's cgroup in % (time spent
+--                 waiting for a host CPU — the hypervisor side of "steal");
+--                 0 before PVE 9, which does not report it
+-- ADD COLUMN with a constant default is metadata-only, so this is safe on the
+-- hypertable; rows collected before this migration read as "no signal".
+--
+-- A run analyses the last 7 days and replaces the current recommendations:
+-- only the latest run's rows are served, older runs are trimmed to the last
+-- 10 after each run (their recommendations go with them).
+
+ALTER TABLE proxmox_guest_metrics
+    ADD COLUMN IF NOT EXISTS balloon BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cpu_pressure DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS proxmox_rightsizing_runs (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at      TIMESTAMPTZ,
+    status           VARCHAR(20) NOT NULL DEFAULT 'running', -- running | success | failed
+    guests_analyzed  INTEGER NOT NULL DEFAULT 0,
+    nodes_analyzed   INTEGER NOT NULL DEFAULT 0,
+    recommendations  INTEGER NOT NULL DEFAULT 0,
+    error            TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS proxmox_rightsizing_recommendations (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id          UUID NOT NULL REFERENCES proxmox_rightsizing_runs(id) ON DELETE CASCADE,
+    connection_id   UUID NOT NULL REFERENCES proxmox_connections(id) ON DELETE CASCADE,
+    -- NULL for a node-level finding (node_failover)
+    guest_id        UUID REFERENCES proxmox_guests(id) ON DELETE CASCADE,
+    node_name       VARCHAR(255) NOT NULL,
+    -- overprovisioned_memory | underprovisioned_memory | underprovisioned_cpu
+    -- | cpu_contention | node_failover | rebalance
+    kind            VARCHAR(32) NOT NULL,
+    severity        VARCHAR(16) NOT NULL DEFAULT 'info', -- info | warning | critical
+    current_cpus    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    current_mem     BIGINT NOT NULL DEFAULT 0,
+    suggested_cpus  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    suggested_mem   BIGINT NOT NULL DEFAULT 0,
+    -- migration target ('' when the fix is a resize only)
+    target_node     VARCHAR(255) NOT NULL DEFAULT '',
+    reason          TEXT NOT NULL DEFAULT '',
+    mem_p95         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    cpu_p95         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    balloon_ratio   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    cpu_pressure    DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_proxmox_rightsizing_runs_started
+    ON proxmox_rightsizing_runs (started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proxmox_rightsizing_recommendations_run
+    ON proxmox_rightsizing_recommendations (run_id);
@@ -0,0 +1,40 @@
+-- Migration 104: strong agent authentication (internal/services/agentauth).
+--
+-- agent_ca holds the small certificate authority the server runs for agent
+-- mTLS: created on first use, it signs the per-host client certificates and
+-- the certificate of the dedicated mTLS listener. A single row; every replica
+-- of an HA deployment loads the same one.
+--
+-- agent_credentials are the credentials a host enrolled besides its API key:
+--   certificate  a client certificate issued by agent_ca (serial, not_after)
+--   ed25519      a public key the agent signs its request bodies with
+-- Once a host has an active credential (not revoked, not expired), its API key
+-- alone is refused: a leaked key can no longer be replayed. Revoking every
+-- credential puts the host back on its API key, so it can enroll again.
+
+CREATE TABLE IF NOT EXISTS agent_ca (
+    id          SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    cert_pem    TEXT NOT NULL,
+    key_pem     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS agent_credentials (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    host_id      VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    kind         VARCHAR(16) NOT NULL, -- certificate | ed25519
+    -- certificate: hex serial; ed25519: '' (the key is in public_key)
+    serial       VARCHAR(64) NOT NULL DEFAULT '',
+    -- ed25519: base64 raw public key; certificate: ''
+    public_key   TEXT NOT NULL DEFAULT '',
+    -- SHA-256 of the certificate (DER) or of the raw public key, hex
+    fingerprint  VARCHAR(64) NOT NULL,
+    not_after    TIMESTAMPTZ,           -- NULL for ed25519 keys
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_credentials_host
+    ON agent_credentials (host_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_agent_credentials_serial
+    ON agent_credentials (serial) WHERE serial <> '';
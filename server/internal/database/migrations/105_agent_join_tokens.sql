@@ -0,0 +1,22 @@
+-- Migration 105: zero-touch agent enrollment (internal/services/agentjoin).
+--
+-- A join token lets an agent started with --join-token register its own host
+-- (POST /api/agent/join) and receive that host's API key, without anyone
+-- copying a key from the UI — for fleets built by Ansible or cloud-init. It is
+-- short-lived and multi-use: every host it creates gets its tags and its
+-- default per-host permissions. Only the SHA-256 of the token is stored.
+
+CREATE TABLE IF NOT EXISTS agent_join_tokens (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          VARCHAR(100) NOT NULL,
+    token_hash    VARCHAR(64) NOT NULL UNIQUE,
+    tags          TEXT NOT NULL DEFAULT '[]',  -- JSON array, same format as hosts.tags
+    permissions   JSONB NOT NULL DEFAULT '[]', -- [{"username":…,"level":"viewer"|"operator"}]
+    max_uses      INTEGER NOT NULL DEFAULT 0,  -- 0 = unlimited until expires_at
+    uses          INTEGER NOT NULL DEFAULT 0,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_by    VARCHAR(255) NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ
+);
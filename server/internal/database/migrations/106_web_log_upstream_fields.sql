@@ -0,0 +1,10 @@
+-- Migration 106: timing and upstream fields on web_log_requests, reported by
+-- the agent for the access log formats that carry them (nginx_timed, Caddy
+-- JSON, Traefik, HAProxy, custom regex — see agent/internal/collector/
+-- web_log_formats.go). NULL when the format does not log the value: a
+-- request without timing must not count as a 0 ms response in averages.
+ALTER TABLE web_log_requests
+    ADD COLUMN IF NOT EXISTS response_time_ms DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS upstream_time_ms DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS upstream_status  INTEGER,
+    ADD COLUMN IF NOT EXISTS upstream         TEXT;
@@ -0,0 +1,34 @@
+-- Migration 107: admin-managed threat detection rules (internal/threatdetect,
+-- internal/services/threatrules).
+--
+-- Replaces the needle lists compiled into threatdetect.Classify. Built-in
+-- rules are upserted at startup (source = 'builtin': only "enabled" is
+-- editable); imported packs use the pack name as source and "<pack>:<id>" as
+-- rule id, so importing a newer version updates them in place.
+CREATE TABLE IF NOT EXISTS threat_rules (
+    id                 TEXT PRIMARY KEY,
+    name               VARCHAR(200) NOT NULL,
+    description        TEXT NOT NULL DEFAULT '',
+    category           VARCHAR(50) NOT NULL,
+    severity           VARCHAR(10) NOT NULL,
+    method_pattern     TEXT NOT NULL DEFAULT '',
+    path_pattern       TEXT NOT NULL DEFAULT '',
+    query_pattern      TEXT NOT NULL DEFAULT '',
+    user_agent_pattern TEXT NOT NULL DEFAULT '',
+    status_pattern     TEXT NOT NULL DEFAULT '',
+    enabled            BOOLEAN NOT NULL DEFAULT TRUE,
+    source             VARCHAR(100) NOT NULL DEFAULT 'custom',
+    created_by         VARCHAR(255) NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The query string is now reported by the agent so rules can match it, and
+-- each suspicious request records the rule that classified it: the per-rule
+-- hit counters are computed from it.
+ALTER TABLE web_log_requests
+    ADD COLUMN IF NOT EXISTS query   TEXT,
+    ADD COLUMN IF NOT EXISTS rule_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_web_log_requests_rule_captured
+    ON web_log_requests (rule_id, captured_at DESC) WHERE rule_id IS NOT NULL;
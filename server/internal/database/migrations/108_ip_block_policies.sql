@@ -0,0 +1,46 @@
+-- Migration 108: automated IP blocking (internal/services/ipblock).
+--
+-- A policy bans, on every host whose agent can apply CrowdSec decisions
+-- (hosts.collectors->>'crowdsec'), the IPs whose threat score over a sliding
+-- window exceeds a threshold. Every decision — applied, dry run or failed —
+-- is kept in ip_block_decisions as the audit trail, and can be reverted.
+CREATE TABLE IF NOT EXISTS ip_block_policies (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name            VARCHAR(100) NOT NULL,
+    enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    min_score       DOUBLE PRECISION NOT NULL,
+    window_minutes  INTEGER NOT NULL DEFAULT 10,
+    ban_duration    VARCHAR(20) NOT NULL DEFAULT '4h',
+    dry_run         BOOLEAN NOT NULL DEFAULT TRUE,
+    allowlist       JSONB NOT NULL DEFAULT '[]',  -- IPs / CIDRs never blocked
+    notify_channels JSONB NOT NULL DEFAULT '["browser"]',
+    created_by      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_run_at     TIMESTAMPTZ
+);
+
+-- policy_name is copied so the trail stays readable after a policy is deleted.
+CREATE TABLE IF NOT EXISTS ip_block_decisions (
+    id              BIGSERIAL PRIMARY KEY,
+    policy_id       UUID REFERENCES ip_block_policies(id) ON DELETE SET NULL,
+    policy_name     VARCHAR(100) NOT NULL,
+    ip              TEXT NOT NULL,
+    score           DOUBLE PRECISION NOT NULL,
+    level           VARCHAR(10) NOT NULL,
+    hits            BIGINT NOT NULL DEFAULT 0,
+    unique_paths    BIGINT NOT NULL DEFAULT 0,
+    host_count      BIGINT NOT NULL DEFAULT 0,
+    duration        VARCHAR(20) NOT NULL,
+    status          VARCHAR(20) NOT NULL,  -- applied | dry_run | failed | reverted
+    error           TEXT NOT NULL DEFAULT '',
+    target_host_ids JSONB NOT NULL DEFAULT '[]',
+    command_ids     JSONB NOT NULL DEFAULT '[]',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    reverted_at     TIMESTAMPTZ,
+    reverted_by     VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_ip_block_decisions_ip_expires ON ip_block_decisions (ip, expires_at DESC);
+CREATE INDEX IF NOT EXISTS idx_ip_block_decisions_created ON ip_block_decisions (created_at DESC);
@@ -0,0 +1,46 @@
+-- Migration 109: network flow policies (internal/netpolicy).
+--
+-- A policy states which peers the hosts in its scope (host ids and/or tags,
+-- every host when both are empty) are expected to talk to — "allow" mode —
+-- or must never talk to — "deny" mode. Incoming talkers are checked on
+-- every agent report; each offending flow is one row of
+-- network_flow_violations, refreshed while it keeps being seen, and is
+-- alerted on through the network_policy_violation metric.
+CREATE TABLE IF NOT EXISTS network_flow_policies (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+    host_ids    JSONB NOT NULL DEFAULT '[]',
+    tags        JSONB NOT NULL DEFAULT '[]',
+    direction   VARCHAR(10) NOT NULL DEFAULT 'outbound',  -- outbound | inbound | any
+    subject     VARCHAR(10) NOT NULL DEFAULT 'any',       -- any | container | host
+    mode        VARCHAR(10) NOT NULL,                     -- allow | deny
+    rules       JSONB NOT NULL DEFAULT '[]',
+    created_by  VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- remote_port is 0 for inbound flows: the agent reports the client's
+-- ephemeral port there, which would turn one client into many violations.
+CREATE TABLE IF NOT EXISTS network_flow_violations (
+    id           BIGSERIAL PRIMARY KEY,
+    policy_id    UUID NOT NULL REFERENCES network_flow_policies(id) ON DELETE CASCADE,
+    host_id      VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    remote_ip    TEXT NOT NULL,
+    remote_port  INTEGER NOT NULL,
+    protocol     VARCHAR(10) NOT NULL,
+    direction    VARCHAR(10) NOT NULL,
+    process_name TEXT NOT NULL DEFAULT '',
+    server_name  TEXT NOT NULL DEFAULT '',
+    connections  BIGINT NOT NULL DEFAULT 0,
+    rx_bytes     BIGINT NOT NULL DEFAULT 0,
+    tx_bytes     BIGINT NOT NULL DEFAULT 0,
+    first_seen   TIMESTAMPTZ NOT NULL,
+    last_seen    TIMESTAMPTZ NOT NULL,
+    UNIQUE (policy_id, host_id, remote_ip, remote_port, protocol, direction, process_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_network_flow_violations_host_seen ON network_flow_violations (host_id, last_seen DESC);
+CREATE INDEX IF NOT EXISTS idx_network_flow_violations_seen ON network_flow_violations (last_seen DESC);
@@ -0,0 +1,6 @@
+-- Migration 110: persist the Docker-assigned container addresses.
+--
+-- The agent already reports them (agent/internal/collector/container_ips.go);
+-- the service dependency map (internal/networkview/dependencies.go) needs them
+-- at rest to resolve a flow's remote IP to the container it reaches.
+ALTER TABLE docker_containers ADD COLUMN IF NOT EXISTS ip_addresses JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,50 @@
+-- Migration 111: dns_query_metrics — per-host DNS lookups sampled by the
+-- agent's optional L7 capture (agent/internal/collector/network_flows_dns.go).
+-- One row per (host, report cycle, top queried name), plus a single totals row
+-- per cycle (is_total = true, domain '') carrying every response the capture
+-- saw, ranked or not — the NXDOMAIN rate is computed from those.
+--
+-- responses/nxdomain are per-cycle samples (the capture window), so history
+-- aggregation SUMs them, like network_flow_metrics' per-cycle deltas.
+--
+-- Queried names reveal what a host talks to as much as remote_ip does, so
+-- retention follows network_flow_metrics' applicative job
+-- (NetworkFlowsRetentionDays) rather than a fixed Timescale policy — see
+-- migration 092. Same TimescaleDB gating as 092.
+
+CREATE TABLE IF NOT EXISTS dns_query_metrics (
+    id          BIGSERIAL,
+    host_id     VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    "timestamp" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    is_total    BOOLEAN NOT NULL DEFAULT false,
+    domain      VARCHAR(255) NOT NULL DEFAULT '',
+    responses   INTEGER NOT NULL DEFAULT 0,
+    nxdomain    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (id, "timestamp")
+);
+
+CREATE INDEX IF NOT EXISTS idx_dns_query_metrics_host_ts
+    ON dns_query_metrics (host_id, "timestamp" DESC);
+
+DO $$
+DECLARE
+  tsdb_available BOOLEAN := FALSE;
+BEGIN
+  SELECT EXISTS(SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')
+    INTO tsdb_available;
+
+  IF NOT tsdb_available THEN
+    RAISE NOTICE 'TimescaleDB not available; dns_query_metrics stays a plain table.';
+    RETURN;
+  END IF;
+
+  CREATE EXTENSION IF NOT EXISTS timescaledb CASCADE;
+
+  IF NOT EXISTS (SELECT 1 FROM timescaledb_information.hypertables
+                 WHERE hypertable_name = 'dns_query_metrics') THEN
+    PERFORM create_hypertable('dns_query_metrics', 'timestamp', migrate_data => true);
+    ALTER TABLE dns_query_metrics
+      SET (timescaledb.compress, timescaledb.compress_segmentby = 'host_id');
+    PERFORM add_compression_policy('dns_query_metrics', INTERVAL '3 days');
+  END IF;
+END $$;
@@ -0,0 +1,63 @@
+-- Migration 112: net_interface_metrics — per-interface link state and
+-- counters reported by the agent every cycle (see
+-- agent/internal/collector/net_interfaces.go). One row per (host, report
+-- cycle, interface): physical NICs, bonds, bridges and VLANs; loopback and
+-- container veth pairs are skipped agent-side.
+--
+-- Counters are the kernel's cumulative since-boot values, like
+-- system_metrics' network_rx_bytes/network_tx_bytes: history and alert rates
+-- come from consecutive-sample deltas, dropping the interval across a reset.
+-- The link attributes (kind, speed, master...) are repeated on every row so
+-- the latest sample alone describes the interface; compression absorbs it.
+
+CREATE TABLE IF NOT EXISTS net_interface_metrics (
+    host_id     VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    "timestamp" TIMESTAMPTZ NOT NULL DEFAULT now(),
+    name        VARCHAR(64) NOT NULL,
+    kind        VARCHAR(16) NOT NULL DEFAULT '',
+    operstate   VARCHAR(16) NOT NULL DEFAULT '',
+    speed_mbps  INTEGER NOT NULL DEFAULT 0,
+    duplex      VARCHAR(16) NOT NULL DEFAULT '',
+    mtu         INTEGER NOT NULL DEFAULT 0,
+    master      VARCHAR(64) NOT NULL DEFAULT '',
+    parent      VARCHAR(64) NOT NULL DEFAULT '',
+    vlan_id     INTEGER NOT NULL DEFAULT 0,
+    members     TEXT[] NOT NULL DEFAULT '{}',
+    rx_bytes    BIGINT NOT NULL DEFAULT 0,
+    tx_bytes    BIGINT NOT NULL DEFAULT 0,
+    rx_packets  BIGINT NOT NULL DEFAULT 0,
+    tx_packets  BIGINT NOT NULL DEFAULT 0,
+    rx_errors   BIGINT NOT NULL DEFAULT 0,
+    tx_errors   BIGINT NOT NULL DEFAULT 0,
+    rx_dropped  BIGINT NOT NULL DEFAULT 0,
+    tx_dropped  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_net_interface_metrics_host_name_ts
+    ON net_interface_metrics (host_id, name, "timestamp" DESC);
+
+-- Same TimescaleDB gating as migration 092. Interface names and counters
+-- carry nothing personal, so a fixed retention policy like disk_metrics.
+DO $$
+DECLARE
+  tsdb_available BOOLEAN := FALSE;
+BEGIN
+  SELECT EXISTS(SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')
+    INTO tsdb_available;
+
+  IF NOT tsdb_available THEN
+    RAISE NOTICE 'TimescaleDB not available; net_interface_metrics stays a plain table.';
+    RETURN;
+  END IF;
+
+  CREATE EXTENSION IF NOT EXISTS timescaledb CASCADE;
+
+  IF NOT EXISTS (SELECT 1 FROM timescaledb_information.hypertables
+                 WHERE hypertable_name = 'net_interface_metrics') THEN
+    PERFORM create_hypertable('net_interface_metrics', 'timestamp', migrate_data => true);
+    ALTER TABLE net_interface_metrics
+      SET (timescaledb.compress, timescaledb.compress_segmentby = 'host_id, name');
+    PERFORM add_compression_policy('net_interface_metrics', INTERVAL '3 days');
+    PERFORM add_retention_policy('net_interface_metrics', INTERVAL '90 days');
+  END IF;
+END $$;
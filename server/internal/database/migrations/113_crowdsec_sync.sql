@@ -0,0 +1,47 @@
+-- Migration 113: CrowdSec fleet sync — each agent with collect_crowdsec
+-- reports the decisions of its local CrowdSec API and the bouncers registered
+-- on it (see agent/internal/collector/crowdsec_sync.go). The server only keeps
+-- the latest snapshot per host: the fleet view deduplicates decisions across
+-- hosts and the crowdsec_bouncer_last_pull_minutes alert reads last_pull.
+--
+-- crowdsec_sync_status records when each host last sent a snapshot, so a
+-- host whose agent or LAPI went silent is shown as stale instead of silently
+-- keeping old decisions. bouncers_listed_at moves only when cscli actually
+-- listed the bouncers (the previous list is kept when it fails).
+
+CREATE TABLE IF NOT EXISTS crowdsec_sync_status (
+    host_id             VARCHAR(64) PRIMARY KEY REFERENCES hosts(id) ON DELETE CASCADE,
+    collected_at        TIMESTAMPTZ NOT NULL,
+    local_decisions     INTEGER NOT NULL DEFAULT 0,
+    community_decisions INTEGER NOT NULL DEFAULT 0,
+    truncated           BOOLEAN NOT NULL DEFAULT FALSE,
+    bouncers_listed_at  TIMESTAMPTZ,
+    bouncers_error      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS crowdsec_decisions (
+    host_id    VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    value      VARCHAR(64) NOT NULL,
+    type       VARCHAR(32) NOT NULL DEFAULT '',
+    scenario   TEXT NOT NULL DEFAULT '',
+    origin     VARCHAR(64) NOT NULL DEFAULT '',
+    country    VARCHAR(8) NOT NULL DEFAULT '',
+    as_name    TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ,
+    until      TIMESTAMPTZ,
+    PRIMARY KEY (host_id, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_crowdsec_decisions_value ON crowdsec_decisions (value);
+
+CREATE TABLE IF NOT EXISTS crowdsec_bouncers (
+    host_id    VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    name       VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    type       VARCHAR(128) NOT NULL DEFAULT '',
+    version    VARCHAR(64) NOT NULL DEFAULT '',
+    last_pull  TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    revoked    BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (host_id, name)
+);
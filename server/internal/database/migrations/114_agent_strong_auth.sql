@@ -0,0 +1,12 @@
+-- Migration 114: strong agent authentication is sticky (internal/services/agentauth).
+--
+-- Whether a host's API key alone is refused used to depend on the host having
+-- an unexpired credential: a certificate left to expire (agent down longer than
+-- AGENT_CERT_TTL, renewal failing) put the host back on its API key, which
+-- could then enroll again. strong_auth_required is set by the first enrollment
+-- and only cleared by an admin reset of the host's credentials.
+
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS strong_auth_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE hosts SET strong_auth_required = TRUE
+WHERE id IN (SELECT host_id FROM agent_credentials WHERE revoked_at IS NULL);
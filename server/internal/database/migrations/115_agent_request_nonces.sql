@@ -0,0 +1,15 @@
+-- Migration 115: nonces of signed agent requests, shared by the replicas of an
+-- HA deployment (internal/services/agentauth).
+--
+-- A single server remembers the nonces it accepted in memory. With HA_ENABLED
+-- every replica records them here instead, so a captured signed request cannot
+-- be replayed to another replica within the 5-minute signature window. Rows
+-- older than twice that window are pruned by the replicas themselves.
+
+CREATE TABLE IF NOT EXISTS agent_request_nonces (
+    nonce_key  TEXT PRIMARY KEY, -- <host_id>:<nonce>
+    seen_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_request_nonces_seen_at
+    ON agent_request_nonces (seen_at);
@@ -0,0 +1,14 @@
+-- Migration 116: idempotent agent joins (internal/services/agentjoin).
+--
+-- The agent sends a random join_id with POST /api/agent/join and keeps it until
+-- the issued API key is written to its config. A retry after a lost response
+-- carries the same join_id: the server finds the host that join already
+-- created and re-issues its key instead of registering a duplicate host and
+-- spending another use of the token.
+
+CREATE TABLE IF NOT EXISTS agent_join_requests (
+    join_id     VARCHAR(64) PRIMARY KEY,
+    token_id    UUID NOT NULL REFERENCES agent_join_tokens(id) ON DELETE CASCADE,
+    host_id     VARCHAR(64) NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,24 @@
+-- Migration 117: Proxmox console sessions opened but not attached yet (see
+-- internal/services/proxmox/console.go). They used to live in the memory of
+-- the replica that opened them; in HA mode the websocket attach may land on
+-- another replica, so the session is parked here instead.
+--
+-- A row lives at most consoleSessionTTL (15s): it is deleted when attached
+-- (DELETE ... RETURNING, so each session is attached at most once) and the
+-- expired leftovers are purged on every open. ticket is the PVE
+-- vncproxy/termproxy answer (JSON), a one-time credential only usable with
+-- the connection's API token, which this database already holds.
+
+CREATE TABLE IF NOT EXISTS proxmox_console_sessions (
+    id         VARCHAR(64) PRIMARY KEY,
+    username   VARCHAR(255) NOT NULL,
+    client_ip  VARCHAR(64) NOT NULL DEFAULT '',
+    kind       VARCHAR(8) NOT NULL,
+    guest_id   UUID NOT NULL REFERENCES proxmox_guests(id) ON DELETE CASCADE,
+    host_id    VARCHAR(64) NOT NULL DEFAULT '',
+    ticket     TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_proxmox_console_sessions_expires_at
+    ON proxmox_console_sessions (expires_at);
@@ -0,0 +1,10 @@
+-- Migration 118: reserve the join_id before the host is registered (see
+-- internal/services/agentjoin).
+--
+-- The row used to be written after the host was created, and only logged when
+-- that failed: a retry then registered a duplicate host, and two concurrent
+-- requests with the same join_id could both miss the row. The row is now
+-- inserted first (ON CONFLICT DO NOTHING decides which request owns the join)
+-- with a NULL host_id, filled in once the host exists.
+
+ALTER TABLE agent_join_requests ALTER COLUMN host_id DROP NOT NULL;
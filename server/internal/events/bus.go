@@ -8,6 +8,9 @@
 // background jobs, pollers) can publish without import cycles. All methods are
 // nil-safe: a nil *Bus publishes nothing and hands out a never-firing
 // subscription, so call sites (and tests) that don't wire a bus keep working.
+//
+// With several server replicas, a forwarder (cluster.Relay) carries each
+// publish to the other replicas, which Deliver it to their own subscribers.
 package events
 
 import "sync"
@@ -33,8 +36,9 @@ type subscription struct {
 // and sends are non-blocking, so a burst of publishes between two reads collapses
 // into a single wake-up (coalescing).
 type Bus struct {
-	mu   sync.Mutex
-	subs map[*subscription]struct{}
+	mu      sync.Mutex
+	subs    map[*subscription]struct{}
+	forward func(topic string)
 }
 
 func NewBus() *Bus {
@@ -65,9 +69,36 @@ func (b *Bus) Subscribe(topics ...string) (<-chan struct{}, func()) {
 	}
 }
 
-// Publish signals every subscriber that registered the given topic. It never
-// blocks: a subscriber whose buffer is already full keeps its pending wake-up.
+// SetForward registers fn to receive every topic published on this bus, after
+// the local subscribers were signalled. fn must not block. Topics received
+// from elsewhere go through Deliver, which does not forward them again.
+func (b *Bus) SetForward(fn func(topic string)) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.forward = fn
+	b.mu.Unlock()
+}
+
+// Publish signals every subscriber that registered the given topic, then hands
+// it to the forwarder if one is set. It never blocks: a subscriber whose buffer
+// is already full keeps its pending wake-up.
 func (b *Bus) Publish(topic string) {
+	if b == nil {
+		return
+	}
+	b.Deliver(topic)
+	b.mu.Lock()
+	fn := b.forward
+	b.mu.Unlock()
+	if fn != nil {
+		fn(topic)
+	}
+}
+
+// Deliver signals the local subscribers of topic without forwarding it.
+func (b *Bus) Deliver(topic string) {
 	if b == nil {
 		return
 	}
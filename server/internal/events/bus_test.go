@@ -105,3 +105,23 @@ func TestNilBusIsSafe(t *testing.T) {
 		t.Fatal("nil-bus subscription must never fire")
 	}
 }
+
+func TestPublishForwardsButDeliverDoesNot(t *testing.T) {
+	b := NewBus()
+	ch, unsub := b.Subscribe(TopicApt)
+	defer unsub()
+	var forwarded []string
+	b.SetForward(func(topic string) { forwarded = append(forwarded, topic) })
+
+	b.Publish(TopicApt)
+	if !recv(t, ch) {
+		t.Fatal("expected a local signal on Publish")
+	}
+	b.Deliver(TopicApt)
+	if !recv(t, ch) {
+		t.Fatal("expected a local signal on Deliver")
+	}
+	if len(forwarded) != 1 || forwarded[0] != TopicApt {
+		t.Fatalf("forwarded = %v, want only the published topic", forwarded)
+	}
+}
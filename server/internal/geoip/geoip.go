@@ -0,0 +1,254 @@
+// Package geoip geolocates IPs offline from MaxMind-compatible (MMDB)
+// databases — DB-IP lite or GeoLite2 files dropped on the server — instead of
+// sending every visitor IP to a third-party API. A city (or country) database
+// gives the country and city, an ASN database the autonomous system; both are
+// optional, and lookups simply return nothing when neither is configured.
+//
+// Files are reloaded when they change on disk (e.g. after geoipupdate), at
+// most every reloadCheckInterval.
+package geoip
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const reloadCheckInterval = 5 * time.Minute
+
+// Group-by keys of the geo summaries.
+const (
+	GroupByCountry = "country"
+	GroupByASN     = "asn"
+)
+
+// database is one MMDB file, swapped atomically when the file changes. An
+// empty path means an in-memory reader, never reloaded.
+type database struct {
+	path    string
+	reader  atomic.Pointer[Reader]
+	mu      sync.Mutex
+	modTime time.Time
+	checked time.Time
+	err     error
+}
+
+func openDatabase(path string) (*database, error) {
+	db := &database{path: path}
+	if err := db.load(time.Now()); err != nil {
+		return db, err
+	}
+	return db, nil
+}
+
+// load (re)reads the file if its modification time changed. Caller holds mu
+// or owns db.
+func (db *database) load(now time.Time) error {
+	db.checked = now
+	fi, err := os.Stat(db.path)
+	if err != nil {
+		db.err = err
+		return err
+	}
+	if db.reader.Load() != nil && fi.ModTime().Equal(db.modTime) {
+		return nil
+	}
+	r, err := OpenReader(db.path)
+	if err != nil {
+		db.err = err
+		return err
+	}
+	db.reader.Store(r)
+	db.modTime = fi.ModTime()
+	db.err = nil
+	return nil
+}
+
+func (db *database) get() *Reader {
+	if db == nil {
+		return nil
+	}
+	now := time.Now()
+	if db.path != "" && db.mu.TryLock() {
+		if now.Sub(db.checked) >= reloadCheckInterval {
+			prev := db.reader.Load()
+			if err := db.load(now); err != nil {
+				slog.Warn("geoip: reload failed, keeping the previous database", slog.String("path", db.path), slog.Any("err", err))
+			} else if prev != nil && db.reader.Load() != prev {
+				slog.Info("geoip: database reloaded", slog.String("path", db.path))
+			}
+		}
+		db.mu.Unlock()
+	}
+	return db.reader.Load()
+}
+
+func (db *database) status() *models.GeoIPDatabase {
+	if db == nil {
+		return nil
+	}
+	st := &models.GeoIPDatabase{Path: db.path}
+	if r := db.reader.Load(); r != nil {
+		st.Loaded = true
+		st.DatabaseType = r.meta.DatabaseType
+		if r.meta.BuildEpoch > 0 {
+			t := time.Unix(int64(r.meta.BuildEpoch), 0).UTC()
+			st.BuildDate = &t
+		}
+	}
+	db.mu.Lock()
+	if db.err != nil {
+		st.Error = db.err.Error()
+	}
+	db.mu.Unlock()
+	return st
+}
+
+// Resolver looks IPs up in a city and an ASN database, either of which may be
+// nil.
+type Resolver struct {
+	city *database
+	asn  *database
+}
+
+// Open loads the databases at cityPath and asnPath (empty to skip one). A
+// file that fails to load is reported but kept configured: it is picked up
+// once it becomes readable.
+func Open(cityPath, asnPath string) (*Resolver, error) {
+	r := &Resolver{}
+	var errs []string
+	if cityPath != "" {
+		db, err := openDatabase(cityPath)
+		r.city = db
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if asnPath != "" {
+		db, err := openDatabase(asnPath)
+		r.asn = db
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return r, fmt.Errorf("geoip: %s", strings.Join(errs, "; "))
+	}
+	return r, nil
+}
+
+// NewResolver wraps already loaded readers (no reload).
+func NewResolver(city, asn *Reader) *Resolver {
+	r := &Resolver{}
+	if city != nil {
+		r.city = &database{}
+		r.city.reader.Store(city)
+	}
+	if asn != nil {
+		r.asn = &database{}
+		r.asn.reader.Store(asn)
+	}
+	return r
+}
+
+// Lookup geolocates ip. Private, loopback and unparsable addresses return an
+// empty GeoInfo.
+func (r *Resolver) Lookup(ip string) models.GeoInfo {
+	var info models.GeoInfo
+	if r == nil {
+		return info
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil || IsLocal(addr) {
+		return info
+	}
+	if rd := r.city.get(); rd != nil {
+		if rec, err := rd.Lookup(addr); err == nil && rec != nil {
+			fillLocation(&info, rec)
+			fillASN(&info, rec) // some commercial databases carry both
+		}
+	}
+	if rd := r.asn.get(); rd != nil && info.ASN == 0 {
+		if rec, err := rd.Lookup(addr); err == nil && rec != nil {
+			fillASN(&info, rec)
+		}
+	}
+	return info
+}
+
+// Status describes the configured databases.
+func (r *Resolver) Status() models.GeoIPStatus {
+	if r == nil {
+		return models.GeoIPStatus{}
+	}
+	st := models.GeoIPStatus{City: r.city.status(), ASN: r.asn.status()}
+	st.Enabled = (st.City != nil && st.City.Loaded) || (st.ASN != nil && st.ASN.Loaded)
+	return st
+}
+
+func fillLocation(info *models.GeoInfo, rec map[string]any) {
+	country := "country"
+	if path(rec, country) == nil {
+		country = "registered_country"
+	}
+	info.CountryCode = strings.ToUpper(asString(path(rec, country, "iso_code")))
+	info.Country = asString(path(rec, country, "names", "en"))
+	info.City = asString(path(rec, "city", "names", "en"))
+}
+
+func fillASN(info *models.GeoInfo, rec map[string]any) {
+	if n := asUint(path(rec, "autonomous_system_number")); n > 0 && n <= 1<<32-1 {
+		info.ASN = uint32(n)
+		info.ASOrg = asString(path(rec, "autonomous_system_organization"))
+	}
+}
+
+// IsLocal reports addresses no public database locates.
+func IsLocal(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified()
+}
+
+// GroupKey returns the group-by key and label of info: the ISO country code
+// and country name, or "AS<n>" and the organization. ok is false when info
+// has nothing for that grouping.
+func GroupKey(info models.GeoInfo, groupBy string) (key, label string, ok bool) {
+	if groupBy == GroupByASN {
+		if info.ASN == 0 {
+			return "", "", false
+		}
+		key = fmt.Sprintf("AS%d", info.ASN)
+		label = info.ASOrg
+		if label == "" {
+			label = key
+		}
+		return key, label, true
+	}
+	if info.CountryCode == "" {
+		return "", "", false
+	}
+	label = info.Country
+	if label == "" {
+		label = info.CountryCode
+	}
+	return info.CountryCode, label, true
+}
+
+var active atomic.Pointer[Resolver]
+
+// SetDefault installs the resolver used by the package-level Lookup. Called
+// once at startup (nil disables geolocation).
+func SetDefault(r *Resolver) { active.Store(r) }
+
+// Lookup geolocates ip with the default resolver.
+func Lookup(ip string) models.GeoInfo { return active.Load().Lookup(ip) }
+
+// Status describes the default resolver's databases.
+func Status() models.GeoIPStatus { return active.Load().Status() }
@@ -0,0 +1,393 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of every MaxMind DB
+// file (https://maxmind.github.io/MaxMind-DB/).
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSectionSeparator is the 16 zero bytes between the search tree and the
+// data section.
+const dataSectionSeparator = 16
+
+// maxDecodeDepth bounds nested maps/arrays so a corrupt file cannot recurse
+// forever.
+const maxDecodeDepth = 32
+
+// Metadata is the subset of the MMDB metadata the reader needs or reports.
+type Metadata struct {
+	DatabaseType string
+	IPVersion    int
+	NodeCount    uint32
+	RecordSize   int
+	BuildEpoch   uint64
+}
+
+// Reader is a MaxMind DB (MMDB) reader, the format of the GeoLite2 and DB-IP
+// lite databases. The whole file is kept in memory; lookups are read-only and
+// safe for concurrent use.
+type Reader struct {
+	buf       []byte
+	data      []byte // data section
+	meta      Metadata
+	nodeBytes int
+	ipv4Start uint32 // node of ::/96, where IPv4 lookups start in an IPv6 tree
+}
+
+// OpenReader loads the MMDB file at path.
+func OpenReader(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(buf)
+}
+
+// NewReader parses an MMDB file held in buf.
+func NewReader(buf []byte) (*Reader, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, errors.New("mmdb: metadata marker not found")
+	}
+	metaStart := i + len(metadataMarker)
+	d := decoder{data: buf[metaStart:]}
+	raw, _, err := d.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("mmdb: metadata: %w", err)
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("mmdb: metadata is not a map")
+	}
+	meta := Metadata{
+		DatabaseType: asString(m["database_type"]),
+		IPVersion:    int(asUint(m["ip_version"])),
+		NodeCount:    uint32(asUint(m["node_count"])),
+		RecordSize:   int(asUint(m["record_size"])),
+		BuildEpoch:   asUint(m["build_epoch"]),
+	}
+	switch meta.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("mmdb: unsupported record size %d", meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return nil, fmt.Errorf("mmdb: unsupported ip version %d", meta.IPVersion)
+	}
+	r := &Reader{buf: buf, meta: meta, nodeBytes: meta.RecordSize / 4}
+	treeSize := int(meta.NodeCount) * r.nodeBytes
+	if treeSize+dataSectionSeparator > i {
+		return nil, errors.New("mmdb: search tree larger than the file")
+	}
+	r.data = buf[treeSize+dataSectionSeparator : i]
+
+	if meta.IPVersion == 6 {
+		node := uint32(0)
+		for j := 0; j < 96 && node < meta.NodeCount; j++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Metadata returns the database metadata.
+func (r *Reader) Metadata() Metadata { return r.meta }
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (r *Reader) record(node uint32, bit int) uint32 {
+	off := int(node) * r.nodeBytes
+	b := r.buf[off : off+r.nodeBytes]
+	switch r.meta.RecordSize {
+	case 24:
+		if bit == 0 {
+			return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3])<<16 | uint32(b[4])<<8 | uint32(b[5])
+	case 28:
+		if bit == 0 {
+			return uint32(b[3]&0xf0)<<20 | uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+		}
+		return uint32(b[3]&0x0f)<<24 | uint32(b[4])<<16 | uint32(b[5])<<8 | uint32(b[6])
+	default:
+		if bit == 0 {
+			return binary.BigEndian.Uint32(b[0:4])
+		}
+		return binary.BigEndian.Uint32(b[4:8])
+	}
+}
+
+// Lookup returns the record of the network containing addr, or nil when the
+// database has none.
+func (r *Reader) Lookup(addr netip.Addr) (map[string]any, error) {
+	addr = addr.Unmap()
+	node := uint32(0)
+	var ip []byte
+	if addr.Is4() {
+		a := addr.As4()
+		ip = a[:]
+		if r.meta.IPVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else {
+		if r.meta.IPVersion == 4 {
+			return nil, nil
+		}
+		a := addr.As16()
+		ip = a[:]
+	}
+	for i := 0; i < len(ip)*8 && node < r.meta.NodeCount; i++ {
+		bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.meta.NodeCount {
+		return nil, nil
+	}
+	if node < r.meta.NodeCount {
+		return nil, errors.New("mmdb: invalid search tree")
+	}
+	offset := int(node-r.meta.NodeCount) - dataSectionSeparator
+	if offset < 0 || offset >= len(r.data) {
+		return nil, errors.New("mmdb: data pointer out of range")
+	}
+	d := decoder{data: r.data}
+	v, _, err := d.decode(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]any)
+	return m, nil
+}
+
+// MMDB data types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+var errCorrupt = errors.New("mmdb: corrupt data section")
+
+// decoder reads values from a data section; pointers are offsets into data.
+type decoder struct {
+	data []byte
+}
+
+// decode returns the value at offset and the offset right after it.
+func (d *decoder) decode(offset, depth int) (any, int, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errCorrupt
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ == typePointer {
+		ptr, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(ptr, depth+1)
+		return v, next, err
+	}
+	return d.value(typ, size, offset, depth)
+}
+
+// control reads a control byte (and its extensions). For pointers, size holds
+// the raw control byte.
+func (d *decoder) control(offset int) (typ, size, next int, err error) {
+	if offset >= len(d.data) {
+		return 0, 0, 0, errCorrupt
+	}
+	ctrl := int(d.data[offset])
+	offset++
+	typ = ctrl >> 5
+	if typ == typePointer {
+		return typ, ctrl, offset, nil
+	}
+	if typ == typeExtended {
+		if offset >= len(d.data) {
+			return 0, 0, 0, errCorrupt
+		}
+		typ = 7 + int(d.data[offset])
+		offset++
+	}
+	size = ctrl & 0x1f
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(d.data) {
+			return 0, 0, 0, errCorrupt
+		}
+		v := 0
+		for _, b := range d.data[offset : offset+n] {
+			v = v<<8 | int(b)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + v
+		case 2:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+	return typ, size, offset, nil
+}
+
+func (d *decoder) pointer(ctrl, offset int) (ptr, next int, err error) {
+	n := (ctrl>>3)&0x3 + 1
+	if offset+n > len(d.data) {
+		return 0, 0, errCorrupt
+	}
+	b := d.data[offset : offset+n]
+	vvv := ctrl & 0x7
+	switch n {
+	case 1:
+		ptr = vvv<<8 | int(b[0])
+	case 2:
+		ptr = (vvv<<16 | int(b[0])<<8 | int(b[1])) + 2048
+	case 3:
+		ptr = (vvv<<24 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])) + 526336
+	default:
+		ptr = int(binary.BigEndian.Uint32(b))
+	}
+	return ptr, offset + n, nil
+}
+
+func (d *decoder) value(typ, size, offset, depth int) (any, int, error) {
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := 0; i < size; i++ {
+			k, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	if offset+size > len(d.data) {
+		return nil, 0, errCorrupt
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		if size == 4 {
+			return int64(int32(v)), next, nil
+		}
+		return int64(v), next, nil
+	case typeUint128:
+		// Never used by the geolocation databases; kept as raw bytes.
+		return append([]byte(nil), b...), next, nil
+	}
+	return nil, 0, fmt.Errorf("mmdb: unknown data type %d", typ)
+}
+
+func asString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func asUint(v any) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n > 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
+
+// path walks nested maps: path(rec, "country", "names", "en").
+func path(rec map[string]any, keys ...string) any {
+	var cur any = rec
+	for _, k := range keys {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[k]
+	}
+	return cur
+}
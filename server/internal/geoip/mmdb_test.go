@@ -0,0 +1,243 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// mmdbWriter builds a small IPv6 MMDB (24-bit records) in memory, enough to
+// exercise the reader: IPv4 networks are inserted under ::/96 as in the real
+// databases.
+type mmdbWriter struct {
+	nodes  [][2]int // child node index, or -(data offset + 1), or 0 (empty)
+	data   bytes.Buffer
+	dbType string
+}
+
+func newMMDBWriter(dbType string) *mmdbWriter {
+	return &mmdbWriter{nodes: [][2]int{{0, 0}}, dbType: dbType}
+}
+
+func (w *mmdbWriter) insert(t *testing.T, cidr string, rec map[string]any) {
+	t.Helper()
+	prefix := netip.MustParsePrefix(cidr)
+	bits := prefix.Bits()
+	var ip [16]byte
+	if prefix.Addr().Is4() {
+		a := prefix.Addr().As4()
+		copy(ip[12:], a[:])
+		bits += 96
+	} else {
+		ip = prefix.Addr().As16()
+	}
+	offset := w.data.Len()
+	encode(&w.data, rec)
+
+	node := 0
+	for i := 0; i < bits; i++ {
+		bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+		if i == bits-1 {
+			w.nodes[node][bit] = -(offset + 1)
+			return
+		}
+		next := w.nodes[node][bit]
+		if next <= 0 {
+			w.nodes = append(w.nodes, [2]int{})
+			next = len(w.nodes) - 1
+			w.nodes[node][bit] = next
+		}
+		node = next
+	}
+}
+
+func (w *mmdbWriter) bytes() []byte {
+	var out bytes.Buffer
+	count := len(w.nodes)
+	for _, n := range w.nodes {
+		for _, rec := range n {
+			v := count // empty
+			if rec > 0 {
+				v = rec
+			} else if rec < 0 {
+				v = count + dataSectionSeparator + (-rec - 1)
+			}
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, dataSectionSeparator))
+	out.Write(w.data.Bytes())
+	out.Write(metadataMarker)
+	encode(&out, map[string]any{
+		"node_count":    uint32(count),
+		"record_size":   uint16(24),
+		"ip_version":    uint16(6),
+		"database_type": w.dbType,
+		"build_epoch":   uint64(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC).Unix()),
+	})
+	return out.Bytes()
+}
+
+func writeControl(buf *bytes.Buffer, typ, size int) {
+	ctrl := 0
+	ext := -1
+	if typ > 7 {
+		ext = typ - 7
+	} else {
+		ctrl = typ << 5
+	}
+	if size < 29 {
+		buf.WriteByte(byte(ctrl | size))
+	} else {
+		buf.WriteByte(byte(ctrl | 29))
+	}
+	if ext >= 0 {
+		buf.WriteByte(byte(ext))
+	}
+	if size >= 29 {
+		buf.WriteByte(byte(size - 29))
+	}
+}
+
+func encodeUint(buf *bytes.Buffer, typ int, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	trimmed := bytes.TrimLeft(b[:], "\x00")
+	writeControl(buf, typ, len(trimmed))
+	buf.Write(trimmed)
+}
+
+func encode(buf *bytes.Buffer, v any) {
+	switch x := v.(type) {
+	case string:
+		writeControl(buf, typeString, len(x))
+		buf.WriteString(x)
+	case uint16:
+		encodeUint(buf, typeUint16, uint64(x))
+	case uint32:
+		encodeUint(buf, typeUint32, uint64(x))
+	case uint64:
+		encodeUint(buf, typeUint64, x)
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeControl(buf, typeMap, len(keys))
+		for _, k := range keys {
+			encode(buf, k)
+			encode(buf, x[k])
+		}
+	default:
+		panic("unsupported test value")
+	}
+}
+
+func names(en string) map[string]any { return map[string]any{"names": map[string]any{"en": en}} }
+
+func testResolver(t *testing.T) *Resolver {
+	t.Helper()
+	city := newMMDBWriter("DBIP-City-Lite")
+	city.insert(t, "203.0.113.0/24", map[string]any{
+		"country": map[string]any{"iso_code": "FR", "names": map[string]any{"en": "France"}},
+		"city":    names("Paris"),
+	})
+	city.insert(t, "2001:db8::/32", map[string]any{
+		"country": map[string]any{"iso_code": "DE", "names": map[string]any{"en": "Germany"}},
+	})
+	asn := newMMDBWriter("DBIP-ASN-Lite")
+	asn.insert(t, "203.0.113.0/25", map[string]any{
+		"autonomous_system_number":       uint32(64500),
+		"autonomous_system_organization": "Example Hosting",
+	})
+	cityReader, err := NewReader(city.bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	asnReader, err := NewReader(asn.bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewResolver(cityReader, asnReader)
+}
+
+func TestResolverLookup(t *testing.T) {
+	r := testResolver(t)
+	cases := map[string]models.GeoInfo{
+		"203.0.113.7":        {CountryCode: "FR", Country: "France", City: "Paris", ASN: 64500, ASOrg: "Example Hosting"},
+		"::ffff:203.0.113.7": {CountryCode: "FR", Country: "France", City: "Paris", ASN: 64500, ASOrg: "Example Hosting"},
+		"203.0.113.200":      {CountryCode: "FR", Country: "France", City: "Paris"},
+		"2001:db8::1":        {CountryCode: "DE", Country: "Germany"},
+		"198.51.100.1":       {},
+		"10.0.0.1":           {},
+		"not-an-ip":          {},
+	}
+	for ip, want := range cases {
+		if got := r.Lookup(ip); got != want {
+			t.Errorf("Lookup(%s) = %+v, want %+v", ip, got, want)
+		}
+	}
+	if got := (*Resolver)(nil).Lookup("203.0.113.7"); got != (models.GeoInfo{}) {
+		t.Errorf("nil resolver = %+v", got)
+	}
+}
+
+func TestOpenReloadsChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "city.mmdb")
+	w := newMMDBWriter("GeoLite2-Country")
+	w.insert(t, "203.0.113.0/24", map[string]any{"country": map[string]any{"iso_code": "FR"}})
+	if err := os.WriteFile(p, w.bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(p, filepath.Join(dir, "missing.mmdb"))
+	if err == nil {
+		t.Error("expected the missing ASN database to be reported")
+	}
+	if got := r.Lookup("203.0.113.1").CountryCode; got != "FR" {
+		t.Fatalf("country = %q, want FR", got)
+	}
+	st := r.Status()
+	if !st.Enabled || st.City.DatabaseType != "GeoLite2-Country" || st.City.BuildDate == nil || st.ASN.Loaded || st.ASN.Error == "" {
+		t.Errorf("status = %+v / %+v / %+v", st, st.City, st.ASN)
+	}
+
+	w = newMMDBWriter("GeoLite2-Country")
+	w.insert(t, "203.0.113.0/24", map[string]any{"country": map[string]any{"iso_code": "BE"}})
+	if err := os.WriteFile(p, w.bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(p, later, later)
+	r.city.checked = time.Time{} // force the periodic check
+	if got := r.Lookup("203.0.113.1").CountryCode; got != "BE" {
+		t.Errorf("country after reload = %q, want BE", got)
+	}
+}
+
+func TestNewReaderRejectsGarbage(t *testing.T) {
+	if _, err := NewReader([]byte("not a database")); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	info := models.GeoInfo{CountryCode: "FR", Country: "France", ASN: 64500}
+	if k, l, ok := GroupKey(info, GroupByCountry); !ok || k != "FR" || l != "France" {
+		t.Errorf("country key = %q %q %v", k, l, ok)
+	}
+	if k, l, ok := GroupKey(info, GroupByASN); !ok || k != "AS64500" || l != "AS64500" {
+		t.Errorf("asn key = %q %q %v", k, l, ok)
+	}
+	if _, _, ok := GroupKey(models.GeoInfo{}, GroupByASN); ok {
+		t.Error("empty info must not be grouped")
+	}
+}
@@ -26,6 +26,12 @@ func NewAgentHandler(db *database.DB, cfg *config.Config, streamHub *ws.CommandS
 	return &AgentHandler{svc: agentsvc.NewService(db, cfg, streamHub, notifPusher, bus)}
 }
 
+// Commands exposes the agent service to the agent WebSocket channel, which
+// carries commands and their results for protocol v2 agents.
+func (h *AgentHandler) Commands() ws.AgentCommands {
+	return h.svc
+}
+
 // AddCompletionListener registers a listener notified on terminal command states.
 func (h *AgentHandler) AddCompletionListener(listener agentsvc.CommandCompletionListener) {
 	h.svc.AddCompletionListener(listener)
@@ -153,6 +159,31 @@ func (h *AgentHandler) ReceiveResticStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// ReceiveBackfill stores metric samples the agent buffered while the server
+// was unreachable, with their original timestamps.
+func (h *AgentHandler) ReceiveBackfill(c *gin.Context) {
+	hostID := c.GetString("host_id")
+	if hostID == "" {
+		respondError(c, apperr.Unauthorized("host not identified"))
+		return
+	}
+
+	const maxBackfillSize = 5 * 1024 * 1024
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackfillSize)
+	var req models.BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+
+	res, err := h.svc.Backfill(c.Request.Context(), hostID, req.Samples)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
 // GetHostCommandHistory returns all recent commands for a host across all modules.
 func (h *AgentHandler) GetHostCommandHistory(c *gin.Context) {
 	hostID := c.Param("id")
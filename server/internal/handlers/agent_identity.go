@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/agentauth"
+)
+
+// AgentIdentityHandler translates HTTP to the agent authentication service:
+// enrollment for agents, credential listing and revocation for admins.
+type AgentIdentityHandler struct {
+	svc *agentauth.Service
+}
+
+func NewAgentIdentityHandler(svc *agentauth.Service) *AgentIdentityHandler {
+	return &AgentIdentityHandler{svc: svc}
+}
+
+// Enroll issues a client certificate or registers a signing key for the
+// calling agent. First enrollment is authenticated by the API key, renewals by
+// the current credential (see AgentAuthMiddleware).
+func (h *AgentIdentityHandler) Enroll(c *gin.Context) {
+	var req models.AgentEnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	resp, err := h.svc.Enroll(c.Request.Context(), c.GetString("host_id"), c.GetString("agent_auth"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListCredentials returns the certificates and signing keys a host enrolled
+// (admin only).
+func (h *AgentIdentityHandler) ListCredentials(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	creds, err := h.svc.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	strong, err := h.svc.RequiresStrongAuth(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"credentials": creds, "mtls_enabled": h.svc.MTLSEnabled(), "strong_auth_required": strong})
+}
+
+// RevokeCredential revokes a certificate or signing key (admin only).
+func (h *AgentIdentityHandler) RevokeCredential(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	if err := h.svc.Revoke(c.Request.Context(), c.Param("id"), c.Param("credID"), c.GetString("username"), c.ClientIP()); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// ResetCredentials revokes every credential of a host and lets its agent
+// enroll again with its API key (admin only).
+func (h *AgentIdentityHandler) ResetCredentials(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	if err := h.svc.Reset(c.Request.Context(), c.Param("id"), c.GetString("username"), c.ClientIP()); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "reset"})
+}
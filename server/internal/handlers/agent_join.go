@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/agentjoin"
+)
+
+// AgentJoinHandler translates HTTP to the join-token service: token management
+// for admins, self-registration for agents started with --join-token.
+type AgentJoinHandler struct {
+	svc *agentjoin.Service
+}
+
+func NewAgentJoinHandler(svc *agentjoin.Service) *AgentJoinHandler {
+	return &AgentJoinHandler{svc: svc}
+}
+
+// ListTokens returns every join token (admin only).
+func (h *AgentJoinHandler) ListTokens(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	tokens, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates a join token and returns it once (admin only).
+func (h *AgentJoinHandler) CreateToken(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	var req models.AgentJoinTokenCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	token, stored, err := h.svc.Create(c.Request.Context(), req, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"join_token": stored,
+		"message":    "Join token created. Pass it to the agent with --join-token. It will not be shown again.",
+	})
+}
+
+// RevokeToken revokes a join token (admin only).
+func (h *AgentJoinHandler) RevokeToken(c *gin.Context) {
+	if c.GetString("role") != models.RoleAdmin {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	if err := h.svc.Revoke(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// Join registers the calling agent's host with a join token and returns its
+// API key. Unauthenticated: the token is the credential.
+func (h *AgentJoinHandler) Join(c *gin.Context) {
+	var req models.AgentJoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	resp, err := h.svc.Join(c.Request.Context(), req, c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
@@ -43,6 +43,10 @@ func TestResolvableHostID(t *testing.T) {
 		{"unresolved docker prefix hides", "docker:compose:x", "", ""},
 		{"unresolved proxmox prefix hides", "proxmox:node:x", "", ""},
 		{"unresolved synthetic prefix hides", "synthetic:probe-1", "", ""},
+		{"web domain target resolves to its host", "web:real-host-1:shop.example:8443", "", "real-host-1"},
+		{"network policy target resolves to its host", "netpolicy:real-host-1:42", "", "real-host-1"},
+		{"network interface target resolves to its host", "netif:real-host-1:eth0", "", "real-host-1"},
+		{"crowdsec bouncer target resolves to its host", "bouncer:real-host-1:firewall", "", "real-host-1"},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/crowdsec"
+)
+
+// CrowdSecHandler translates HTTP to the CrowdSec fleet service. Every route
+// is admin only (see registerCrowdSecRoutes).
+type CrowdSecHandler struct {
+	svc *crowdsec.Service
+}
+
+func NewCrowdSecHandler(svc *crowdsec.Service) *CrowdSecHandler {
+	return &CrowdSecHandler{svc: svc}
+}
+
+// ListDecisions returns the active decisions of every host, deduplicated by
+// IP or range (?value= for one of them).
+func (h *CrowdSecHandler) ListDecisions(c *gin.Context) {
+	decisions, err := h.svc.Decisions(c.Request.Context(), c.Query("value"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, decisions)
+}
+
+// BanEverywhere bans an IP on every CrowdSec-capable host.
+func (h *CrowdSecHandler) BanEverywhere(c *gin.Context) {
+	var in models.CrowdSecFleetActionInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	res, err := h.svc.BanEverywhere(c.Request.Context(), in, c.GetString("username"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// UnbanEverywhere removes the decisions on an IP from every CrowdSec-capable
+// host.
+func (h *CrowdSecHandler) UnbanEverywhere(c *gin.Context) {
+	res, err := h.svc.UnbanEverywhere(c.Request.Context(), c.Param("ip"), c.GetString("username"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ListBouncers returns the bouncers registered on every host with their last
+// pull.
+func (h *CrowdSecHandler) ListBouncers(c *gin.Context) {
+	bouncers, err := h.svc.Bouncers(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, bouncers)
+}
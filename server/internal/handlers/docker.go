@@ -74,6 +74,29 @@ func (h *DockerHandler) SendDockerCommand(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"command_id": id, "status": "pending"})
 }
 
+// SendComposeFileCommand creates a pending compose file editor command
+// (read / validate / apply / rollback) for an agent to execute.
+func (h *DockerHandler) SendComposeFileCommand(c *gin.Context) {
+	if role := c.GetString("role"); role != models.RoleAdmin && role != models.RoleOperator {
+		respondError(c, apperr.Forbidden("insufficient permissions"))
+		return
+	}
+	var req models.ComposeFileCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	if !requireHostAccess(c, h.db, req.HostID, "operator") {
+		return
+	}
+	id, err := h.svc.SendComposeFileCommand(c.Request.Context(), req, c.GetString("username"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"command_id": id, "status": "pending"})
+}
+
 // ListComposeProjects returns all Docker Compose projects across all hosts.
 func (h *DockerHandler) ListComposeProjects(c *gin.Context) {
 	projects, err := h.svc.ComposeProjects(c.Request.Context())
@@ -84,6 +107,26 @@ func (h *DockerHandler) ListComposeProjects(c *gin.Context) {
 	c.JSON(http.StatusOK, projects)
 }
 
+// ListVolumeGrowth returns the volumes that grew the most across the fleet
+// over the window (default 24h, ?period or ?from/?to). Accepts an optional
+// ?limit (default 10, max 100).
+func (h *DockerHandler) ListVolumeGrowth(c *gin.Context) {
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+	rows, err := h.svc.TopVolumeGrowth(c.Request.Context(), since, until, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
 // ListHostComposeProjects returns Docker Compose projects for a specific host.
 func (h *DockerHandler) ListHostComposeProjects(c *gin.Context) {
 	projects, err := h.svc.HostComposeProjects(c.Request.Context(), c.Param("id"))
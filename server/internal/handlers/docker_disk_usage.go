@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// dockerDiskHistoryKinds lists the kinds that have a history (images are
+// content-addressed and never grow in place, see migration 096).
+var dockerDiskHistoryKinds = map[string]bool{
+	models.DockerDiskKindContainer:       true,
+	models.DockerDiskKindVolume:          true,
+	models.DockerDiskKindImagesTotal:     true,
+	models.DockerDiskKindContainersTotal: true,
+	models.DockerDiskKindVolumesTotal:    true,
+	models.DockerDiskKindBuildCacheTotal: true,
+}
+
+// GetDockerDiskUsage retourne l'occupation disque Docker d'un hôte (totaux par type et plus gros consommateurs).
+func (h *HostHandler) GetDockerDiskUsage(c *gin.Context) {
+	summary, err := h.svc.DockerDiskUsage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetDockerDiskUsageHistory retourne l'évolution de la taille d'un volume, d'un conteneur ou d'un total par type.
+func (h *HostHandler) GetDockerDiskUsageHistory(c *gin.Context) {
+	kind := c.Query("kind")
+	name := c.Query("name")
+	if !dockerDiskHistoryKinds[kind] {
+		respondError(c, apperr.Validation("kind must be one of volume, container, images_total, containers_total, volumes_total, build_cache_total"))
+		return
+	}
+	if (kind == models.DockerDiskKindVolume || kind == models.DockerDiskKindContainer) && name == "" {
+		respondError(c, apperr.Validation("name query parameter required for volume and container history"))
+		return
+	}
+	since, until, ok := parseTimeRange(c, "168h")
+	if !ok {
+		return
+	}
+	points, err := h.svc.DockerDiskUsageHistory(c.Request.Context(), c.Param("id"), kind, name, since, until)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":  since,
+		"kind":   kind,
+		"name":   name,
+		"points": points,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
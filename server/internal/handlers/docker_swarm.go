@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDockerSwarm retourne l'état Swarm (services, tâches, noeuds) vu par un hôte manager.
+func (h *HostHandler) GetDockerSwarm(c *gin.Context) {
+	state, err := h.svc.DockerSwarm(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
@@ -91,6 +91,18 @@ func resolvableHostID(hostID, linkHostID string) string {
 	if linkHostID != "" {
 		return linkHostID
 	}
+	if webHostID, _, ok := models.ParseWebDomainTargetID(hostID); ok {
+		return webHostID
+	}
+	if policyHostID, _, ok := models.ParseNetworkPolicyTargetID(hostID); ok {
+		return policyHostID
+	}
+	if ifaceHostID, _, ok := models.ParseNetInterfaceTargetID(hostID); ok {
+		return ifaceHostID
+	}
+	if bouncerHostID, _, ok := models.ParseCrowdSecBouncerTargetID(hostID); ok {
+		return bouncerHostID
+	}
 	if strings.HasPrefix(hostID, "docker:") || strings.HasPrefix(hostID, "proxmox:") || strings.HasPrefix(hostID, "synthetic:") {
 		return ""
 	}
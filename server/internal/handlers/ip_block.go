@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/ipblock"
+)
+
+// IPBlockHandler translates HTTP to the automated IP blocking service. Every
+// route is admin only (see registerIPBlockRoutes).
+type IPBlockHandler struct {
+	svc *ipblock.Service
+}
+
+func NewIPBlockHandler(svc *ipblock.Service) *IPBlockHandler {
+	return &IPBlockHandler{svc: svc}
+}
+
+// ListPolicies returns every blocking policy.
+func (h *IPBlockHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.svc.ListPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy adds a blocking policy (dry run by default).
+func (h *IPBlockHandler) CreatePolicy(c *gin.Context) {
+	var in models.IPBlockPolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.CreatePolicy(c.Request.Context(), in, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// UpdatePolicy edits the fields present in the body.
+func (h *IPBlockHandler) UpdatePolicy(c *gin.Context) {
+	var in models.IPBlockPolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.UpdatePolicy(c.Request.Context(), c.Param("id"), in)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DeletePolicy removes a policy; its bans run until they expire.
+func (h *IPBlockHandler) DeletePolicy(c *gin.Context) {
+	if err := h.svc.DeletePolicy(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListDecisions returns the automatic decisions trail (?ip=, ?limit=).
+func (h *IPBlockHandler) ListDecisions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	decisions, err := h.svc.ListDecisions(c.Request.Context(), c.Query("ip"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, decisions)
+}
+
+// RevertDecision unbans the IP of an applied decision.
+func (h *IPBlockHandler) RevertDecision(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, apperr.NotFound("décision introuvable"))
+		return
+	}
+	d, err := h.svc.Revert(c.Request.Context(), id, c.GetString("username"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
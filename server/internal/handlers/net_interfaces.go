@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+)
+
+// GetNetInterfaces retourne l'état et les débits courants de chaque interface réseau d'un hôte.
+func (h *HostHandler) GetNetInterfaces(c *gin.Context) {
+	ifaces, err := h.svc.NetInterfaces(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ifaces)
+}
+
+// GetNetInterfaceHistory retourne l'historique d'une interface : débits, erreurs, pertes, utilisation et coupures.
+func (h *HostHandler) GetNetInterfaceHistory(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		respondError(c, apperr.Validation("name query parameter required"))
+		return
+	}
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	history, err := h.svc.NetInterfaceHistory(c.Request.Context(), c.Param("id"), name, since, until)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":  since,
+		"name":   name,
+		"points": history.Points,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/serversupervisor/server/internal/apperr"
@@ -51,9 +52,29 @@ func (h *NetworkHandler) SaveTopologyConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
-// GetTopologySnapshot returns topology with config.
+// GetTopologySnapshot returns topology with config. ?layers=dependencies adds
+// the service dependency graph built from the network flows of the window
+// (period, or from/to; last hour by default).
 func (h *NetworkHandler) GetTopologySnapshot(c *gin.Context) {
-	snapshot, err := h.svc.TopologySnapshot(c.Request.Context())
+	var opts networksvc.TopologyOptions
+	for _, layer := range strings.Split(c.Query("layers"), ",") {
+		switch strings.TrimSpace(layer) {
+		case "":
+		case "dependencies":
+			opts.Dependencies = true
+		default:
+			respondError(c, apperr.Validation("unknown layer: "+layer))
+			return
+		}
+	}
+	if opts.Dependencies {
+		since, until, ok := parseTimeRange(c, "1h")
+		if !ok {
+			return
+		}
+		opts.Since, opts.Until = since, until
+	}
+	snapshot, err := h.svc.TopologySnapshot(c.Request.Context(), opts)
 	if err != nil {
 		respondError(c, err)
 		return
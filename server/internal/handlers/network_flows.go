@@ -69,3 +69,53 @@ func (h *HostHandler) GetNetworkFlowsSummary(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// GetNetworkFlowsGeo retourne le trafic d'un hôte regroupé par pays ou par AS distant (?group_by=country|asn).
+func (h *HostHandler) GetNetworkFlowsGeo(c *gin.Context) {
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "country")
+	groups, err := h.svc.NetworkFlowsGeo(c.Request.Context(), c.Param("id"), since, until, groupBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":    since,
+		"group_by": groupBy,
+		"groups":   groups,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetNetworkDNS retourne les domaines les plus résolus par un hôte, ceux en
+// échec (NXDOMAIN) et l'évolution du taux de NXDOMAIN (capture L7 de l'agent).
+func (h *HostHandler) GetNetworkDNS(c *gin.Context) {
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	report, err := h.svc.DNSQueries(c.Request.Context(), c.Param("id"), since, until)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":         since,
+		"responses":     report.Responses,
+		"nxdomain":      report.NXDomain,
+		"nxdomain_rate": report.NXDomainRate,
+		"top_domains":   report.TopDomains,
+		"top_nxdomains": report.TopNXDomains,
+		"points":        report.Points,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/flowpolicy"
+)
+
+// NetworkPolicyHandler translates HTTP to the network flow policy service.
+// Every route is admin only (see registerNetworkPolicyRoutes).
+type NetworkPolicyHandler struct {
+	svc *flowpolicy.Service
+}
+
+func NewNetworkPolicyHandler(svc *flowpolicy.Service) *NetworkPolicyHandler {
+	return &NetworkPolicyHandler{svc: svc}
+}
+
+// ListPolicies returns every network flow policy.
+func (h *NetworkPolicyHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.svc.ListPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+// CreatePolicy adds a network flow policy.
+func (h *NetworkPolicyHandler) CreatePolicy(c *gin.Context) {
+	var in models.NetworkFlowPolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.CreatePolicy(c.Request.Context(), in, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// UpdatePolicy edits the fields present in the body.
+func (h *NetworkPolicyHandler) UpdatePolicy(c *gin.Context) {
+	var in models.NetworkFlowPolicyInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.UpdatePolicy(c.Request.Context(), c.Param("id"), in)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DeletePolicy removes a policy and its violations.
+func (h *NetworkPolicyHandler) DeletePolicy(c *gin.Context) {
+	if err := h.svc.DeletePolicy(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListViolations returns the recent violations (?host_id=, ?policy_id=,
+// ?hours=, ?limit=).
+func (h *NetworkPolicyHandler) ListViolations(c *gin.Context) {
+	hours, _ := strconv.Atoi(c.Query("hours"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	violations, err := h.svc.ListViolations(c.Request.Context(), c.Query("host_id"), c.Query("policy_id"), hours, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, violations)
+}
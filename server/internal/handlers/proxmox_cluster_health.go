@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListClusterHealth returns the Ceph / HA / corosync summary of every connection.
+func (h *ProxmoxHandler) ListClusterHealth(c *gin.Context) {
+	out, err := h.svc.ListClusterHealth(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GetClusterHealth returns one connection's health with OSDs, pools and HA entries.
+func (h *ProxmoxHandler) GetClusterHealth(c *gin.Context) {
+	out, err := h.svc.GetClusterHealth(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// OpenConsole requests a PVE console ticket for a guest (body kind=vnc|term)
+// and returns the session the browser then attaches over
+// /ws/proxmox/console/:session. Who may open it is decided by the service
+// (admins, or operators of the linked host), which also audit-logs it.
+func (h *ProxmoxHandler) OpenConsole(c *gin.Context) {
+	var req models.ProxmoxConsoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	sess, err := h.svc.OpenConsole(c.Request.Context(), c.Param("id"), req.Kind,
+		c.GetString("username"), c.GetString("role"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sess)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// PBSPollInterval is the Proxmox Backup Server collection tick (respects
+// per-connection poll_interval_sec inside the service).
+const PBSPollInterval = time.Minute
+
+// PollPBS collects all enabled PBS connections once (scheduling owned by
+// poller.Every).
+func (h *ProxmoxHandler) PollPBS(ctx context.Context) {
+	h.svc.PollAllPBS(ctx)
+}
+
+// ─── CRUD: PBS connections ───────────────────────────────────────────────────
+
+func (h *ProxmoxHandler) ListPBSConnections(c *gin.Context) {
+	conns, err := h.svc.ListPBSConnections(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conns)
+}
+
+func (h *ProxmoxHandler) CreatePBSConnection(c *gin.Context) {
+	var req models.PBSConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	conn, err := h.svc.CreatePBSConnection(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, conn)
+}
+
+func (h *ProxmoxHandler) GetPBSConnection(c *gin.Context) {
+	conn, err := h.svc.GetPBSConnection(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conn)
+}
+
+func (h *ProxmoxHandler) UpdatePBSConnection(c *gin.Context) {
+	var req models.PBSConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	conn, err := h.svc.UpdatePBSConnection(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conn)
+}
+
+func (h *ProxmoxHandler) DeletePBSConnection(c *gin.Context) {
+	if err := h.svc.DeletePBSConnection(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "connection deleted"})
+}
+
+func (h *ProxmoxHandler) TestPBSConnection(c *gin.Context) {
+	var req struct {
+		APIURL             string `json:"api_url" binding:"required"`
+		TokenID            string `json:"token_id" binding:"required"`
+		TokenSecret        string `json:"token_secret" binding:"required"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	if ok, errMsg := h.svc.TestPBSConnection(req.APIURL, req.TokenID, req.TokenSecret, req.InsecureSkipVerify); !ok {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": errMsg})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *ProxmoxHandler) TestPBSConnectionByID(c *gin.Context) {
+	ok, errMsg, err := h.svc.TestPBSConnectionByID(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"success": false, "error": errMsg})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// PollPBSNow triggers an immediate poll for one PBS connection.
+func (h *ProxmoxHandler) PollPBSNow(c *gin.Context) {
+	if err := h.svc.TriggerPBSPollByID(c.Request.Context(), h.pollerCtx, c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "poll triggered"})
+}
+
+// ─── Read-only PBS state ─────────────────────────────────────────────────────
+
+func (h *ProxmoxHandler) ListPBSDatastores(c *gin.Context) {
+	stores, err := h.svc.ListPBSDatastores(c.Request.Context(), c.Query("connection_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stores)
+}
+
+func (h *ProxmoxHandler) ListPBSBackupGroups(c *gin.Context) {
+	groups, err := h.svc.ListPBSBackupGroups(c.Request.Context(), c.Query("connection_id"), c.Query("store"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func (h *ProxmoxHandler) ListPBSJobs(c *gin.Context) {
+	jobs, err := h.svc.ListPBSJobs(c.Request.Context(), c.Query("connection_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ─── VM provisioning (admin) ─────────────────────────────────────────────────
+
+// ListProvisionTemplates returns the QEMU templates of a connection, live from PVE.
+func (h *ProxmoxHandler) ListProvisionTemplates(c *gin.Context) {
+	templates, err := h.svc.ListProvisionTemplates(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *ProxmoxHandler) ListProvisions(c *gin.Context) {
+	provisions, err := h.svc.ListProvisions(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, provisions)
+}
+
+func (h *ProxmoxHandler) GetProvision(c *gin.Context) {
+	p, err := h.svc.GetProvision(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// CreateProvision registers the host and starts the clone → configure → start
+// sequence in the background; poll GetProvision for progress.
+func (h *ProxmoxHandler) CreateProvision(c *gin.Context) {
+	var req models.ProxmoxProvisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.CreateProvision(c.Request.Context(), h.pollerCtx, req, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, p)
+}
+
+func (h *ProxmoxHandler) DeleteProvision(c *gin.Context) {
+	if err := h.svc.DeleteProvision(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "provision deleted"})
+}
+
+// ─── Cloud-init NoCloud seed (public, token-authenticated) ───────────────────
+// cloud-init fetches <seed>/meta-data and <seed>/user-data; the 64-hex token in
+// the path is the only credential and dies once the guest is linked.
+
+func (h *ProxmoxHandler) ProvisionSeedMetaData(c *gin.Context) {
+	body, err := h.svc.SeedMetaData(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(body))
+}
+
+func (h *ProxmoxHandler) ProvisionSeedUserData(c *gin.Context) {
+	body, err := h.svc.SeedUserData(c.Request.Context(), c.Param("token"), c.ClientIP())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/cloud-config; charset=utf-8", []byte(body))
+}
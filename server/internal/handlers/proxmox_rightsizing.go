@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProxmoxRightsizingInterval is how often the scheduler checks whether the
+// weekly right-sizing run is due (the service compares with the last run).
+const ProxmoxRightsizingInterval = time.Hour
+
+// RunDueRightsizing runs the right-sizing analysis when a week has passed
+// since the last one (scheduling owned by poller.Every).
+func (h *ProxmoxHandler) RunDueRightsizing(ctx context.Context) {
+	h.svc.RunDueRightsizing(ctx)
+}
+
+// GetRightsizing returns the latest right-sizing run and its recommendations.
+func (h *ProxmoxHandler) GetRightsizing(c *gin.Context) {
+	report, err := h.svc.GetRightsizingReport(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// RunRightsizing runs the analysis now instead of waiting for the weekly run.
+func (h *ProxmoxHandler) RunRightsizing(c *gin.Context) {
+	run, err := h.svc.RunRightsizing(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
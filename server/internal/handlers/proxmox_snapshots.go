@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ProxmoxSnapshotPolicyInterval is the scheduler tick for snapshot policies;
+// each policy carries its own cron schedule (next_run_at).
+const ProxmoxSnapshotPolicyInterval = time.Minute
+
+// RunDueSnapshotPolicies runs the snapshot policies that are due (scheduling
+// owned by poller.Every).
+func (h *ProxmoxHandler) RunDueSnapshotPolicies(ctx context.Context) {
+	h.svc.RunDueSnapshotPolicies(ctx)
+}
+
+// ─── Guest snapshots ─────────────────────────────────────────────────────────
+// URL param :id = the internal proxmox_guests row ID, like GuestAction.
+
+// ListGuestSnapshots returns a guest's snapshots, live from PVE.
+func (h *ProxmoxHandler) ListGuestSnapshots(c *gin.Context) {
+	snaps, err := h.svc.ListGuestSnapshots(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snaps)
+}
+
+// CreateGuestSnapshot takes a snapshot; the body must carry confirm=true.
+func (h *ProxmoxHandler) CreateGuestSnapshot(c *gin.Context) {
+	var req models.ProxmoxSnapshotCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	name, upid, err := h.svc.CreateGuestSnapshot(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upid": upid, "name": name, "message": fmt.Sprintf("Snapshot %q lancé", name)})
+}
+
+// RollbackGuestSnapshot reverts a guest to a snapshot; the body must carry
+// confirm=true.
+func (h *ProxmoxHandler) RollbackGuestSnapshot(c *gin.Context) {
+	var req models.ProxmoxSnapshotAction
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation("confirmation requise"))
+		return
+	}
+	name := c.Param("name")
+	upid, err := h.svc.RollbackGuestSnapshot(c.Request.Context(), c.Param("id"), name, req.Confirm)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upid": upid, "message": fmt.Sprintf("Restauration du snapshot %q lancée", name)})
+}
+
+// DeleteGuestSnapshot removes a snapshot; the body must carry confirm=true.
+func (h *ProxmoxHandler) DeleteGuestSnapshot(c *gin.Context) {
+	var req models.ProxmoxSnapshotAction
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation("confirmation requise"))
+		return
+	}
+	name := c.Param("name")
+	upid, err := h.svc.DeleteGuestSnapshot(c.Request.Context(), c.Param("id"), name, req.Confirm)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upid": upid, "message": fmt.Sprintf("Suppression du snapshot %q lancée", name)})
+}
+
+// ─── Snapshot policies ───────────────────────────────────────────────────────
+
+func (h *ProxmoxHandler) ListSnapshotPolicies(c *gin.Context) {
+	policies, err := h.svc.ListSnapshotPolicies(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policies)
+}
+
+func (h *ProxmoxHandler) GetSnapshotPolicy(c *gin.Context) {
+	p, err := h.svc.GetSnapshotPolicy(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *ProxmoxHandler) CreateSnapshotPolicy(c *gin.Context) {
+	var req models.ProxmoxSnapshotPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.CreateSnapshotPolicy(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *ProxmoxHandler) UpdateSnapshotPolicy(c *gin.Context) {
+	var req models.ProxmoxSnapshotPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	p, err := h.svc.UpdateSnapshotPolicy(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *ProxmoxHandler) DeleteSnapshotPolicy(c *gin.Context) {
+	if err := h.svc.DeleteSnapshotPolicy(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "politique supprimée"})
+}
+
+// RunSnapshotPolicy runs a policy now, in the background (detached ctx).
+func (h *ProxmoxHandler) RunSnapshotPolicy(c *gin.Context) {
+	if err := h.svc.RunSnapshotPolicyNow(c.Request.Context(), h.pollerCtx, c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "exécution lancée"})
+}
+
+// ListSnapshotPolicyRuns returns a policy's per-guest run history.
+func (h *ProxmoxHandler) ListSnapshotPolicyRuns(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	runs, err := h.svc.ListSnapshotPolicyRuns(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
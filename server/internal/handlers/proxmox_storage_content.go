@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// ─── Storage content browser ─────────────────────────────────────────────────
+// URL param :id = the internal proxmox_storages row ID (one node's view of a
+// storage). Admin only: backups and disk images name every guest.
+
+// ListStorageContent returns a storage's volumes live from PVE
+// (?content=iso|vztmpl|backup|images|rootdir|snippets, ?orphaned=true).
+func (h *ProxmoxHandler) ListStorageContent(c *gin.Context) {
+	vols, err := h.svc.ListStorageContent(c.Request.Context(), c.Param("id"), c.Query("content"), c.Query("orphaned") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, vols)
+}
+
+// DownloadToStorage starts an ISO / container template download onto the
+// storage; the body must carry confirm=true.
+func (h *ProxmoxHandler) DownloadToStorage(c *gin.Context) {
+	var req models.ProxmoxStorageDownloadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	filename, upid, err := h.svc.DownloadToStorage(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upid": upid, "filename": filename, "message": fmt.Sprintf("Téléchargement de %q lancé", filename)})
+}
+
+// DeleteStorageVolume removes one volume; the body must carry the volid and
+// confirm=true.
+func (h *ProxmoxHandler) DeleteStorageVolume(c *gin.Context) {
+	var req models.ProxmoxStorageVolumeDelete
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation("confirmation requise"))
+		return
+	}
+	upid, err := h.svc.DeleteStorageVolume(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upid": upid, "message": fmt.Sprintf("Suppression de %q lancée", req.VolID)})
+}
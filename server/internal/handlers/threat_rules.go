@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/threatrules"
+)
+
+// ThreatRulesHandler translates HTTP to the threat rule service. Every route
+// is admin only (see registerThreatRuleRoutes).
+type ThreatRulesHandler struct {
+	svc *threatrules.Service
+}
+
+func NewThreatRulesHandler(svc *threatrules.Service) *ThreatRulesHandler {
+	return &ThreatRulesHandler{svc: svc}
+}
+
+// ListRules returns every rule with its hit counters.
+func (h *ThreatRulesHandler) ListRules(c *gin.Context) {
+	rules, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateRule adds a custom rule.
+func (h *ThreatRulesHandler) CreateRule(c *gin.Context) {
+	var in models.ThreatRuleInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	rule, err := h.svc.Create(c.Request.Context(), in, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule edits a rule; only enabled for a built-in one.
+func (h *ThreatRulesHandler) UpdateRule(c *gin.Context) {
+	var in models.ThreatRuleInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	rule, err := h.svc.Update(c.Request.Context(), c.Param("id"), in)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule removes a custom or imported rule.
+func (h *ThreatRulesHandler) DeleteRule(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// TestRule tries a draft rule against samples and recent stored requests.
+func (h *ThreatRulesHandler) TestRule(c *gin.Context) {
+	var req models.ThreatRuleTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	res, err := h.svc.Test(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ImportPack imports a bundled or uploaded rule pack.
+func (h *ThreatRulesHandler) ImportPack(c *gin.Context) {
+	var req models.ThreatRuleImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, apperr.Validation(err.Error()))
+		return
+	}
+	res, err := h.svc.Import(c.Request.Context(), req, c.GetString("username"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -272,6 +272,86 @@ func (h *WebLogsHandler) GetWebLogsTimeseries(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// GetWebLogsGeo groups the period's busiest client IPs by country or by
+// autonomous system (?group_by=country|asn), from the offline GeoIP databases.
+func (h *WebLogsHandler) GetWebLogsGeo(c *gin.Context) {
+	if !h.requireWebLogsAdmin(c) {
+		return
+	}
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	hostID := strings.TrimSpace(c.Query("host_id"))
+	source := strings.ToLower(strings.TrimSpace(c.Query("source")))
+	if !validWebLogSource(source) {
+		respondError(c, apperr.Validation("invalid source"))
+		return
+	}
+	groupBy := strings.ToLower(strings.TrimSpace(c.DefaultQuery("group_by", "country")))
+	groups, err := h.svc.GeoSummary(c.Request.Context(), since, until, hostID, source, groupBy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":    since,
+		"host_id":  hostID,
+		"source":   source,
+		"group_by": groupBy,
+		"groups":   groups,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetWebLogsPerformance returns p50/p95/p99 latency and error rates per
+// domain and per normalized route, with the error-rate and slow-endpoint
+// rankings.
+func (h *WebLogsHandler) GetWebLogsPerformance(c *gin.Context) {
+	if !h.requireWebLogsAdmin(c) {
+		return
+	}
+	since, until, ok := parseTimeRange(c, "24h")
+	if !ok {
+		return
+	}
+	hostID := strings.TrimSpace(c.Query("host_id"))
+	source := strings.ToLower(strings.TrimSpace(c.Query("source")))
+	if !validWebLogSource(source) {
+		respondError(c, apperr.Validation("invalid source"))
+		return
+	}
+	report, err := h.svc.Performance(c.Request.Context(), since, until, hostID, source)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	resp := gin.H{
+		"since":          since,
+		"host_id":        hostID,
+		"source":         source,
+		"domains":        report.Domains,
+		"routes":         report.Routes,
+		"error_routes":   report.ErrorRoutes,
+		"slowest_routes": report.SlowestRoutes,
+	}
+	if !until.IsZero() {
+		resp["until"] = until
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGeoIPStatus reports which GeoIP databases are loaded.
+func (h *WebLogsHandler) GetGeoIPStatus(c *gin.Context) {
+	if !h.requireWebLogsAdmin(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.svc.GeoIPStatus())
+}
+
 func (h *WebLogsHandler) GetWebLogsLive(c *gin.Context) {
 	if !h.requireWebLogsAdmin(c) {
 		return
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Agent credential kinds: a client certificate for mTLS, or an Ed25519 key
+// the agent signs its requests with.
+const (
+	AgentCredentialCertificate = "certificate"
+	AgentCredentialEd25519     = "ed25519"
+)
+
+// AgentCredential is a credential a host enrolled besides its API key. Once a
+// host has an active one, its API key alone is refused.
+type AgentCredential struct {
+	ID          string     `json:"id"`
+	HostID      string     `json:"host_id"`
+	Kind        string     `json:"kind"`
+	Serial      string     `json:"serial,omitempty"`
+	PublicKey   string     `json:"public_key,omitempty"`
+	Fingerprint string     `json:"fingerprint"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the credential still authenticates its host.
+func (c *AgentCredential) Active(now time.Time) bool {
+	return c.RevokedAt == nil && (c.NotAfter == nil || c.NotAfter.After(now))
+}
+
+// AgentEnrollRequest is the body of POST /api/agent/identity/enroll. A host
+// without any active credential enrolls with its API key; a renewal (new
+// certificate before expiry, or new signing key) is authenticated by the
+// current credential.
+type AgentEnrollRequest struct {
+	Kind string `json:"kind" binding:"required,oneof=certificate ed25519"`
+	// CSR is a PEM certificate request (kind certificate).
+	CSR string `json:"csr,omitempty"`
+	// PublicKey is a base64 raw Ed25519 public key (kind ed25519).
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// AgentEnrollResponse carries the enrolled credential back to the agent.
+type AgentEnrollResponse struct {
+	CredentialID string `json:"credential_id"`
+	Kind         string `json:"kind"`
+	// Certificate, CACertificate and MTLSURL are set for kind certificate:
+	// the issued client certificate, the CA to pin, and the URL of the mTLS
+	// listener the agent must use from now on.
+	Certificate   string     `json:"certificate,omitempty"`
+	CACertificate string     `json:"ca_certificate,omitempty"`
+	MTLSURL       string     `json:"mtls_url,omitempty"`
+	NotAfter      *time.Time `json:"not_after,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// AgentJoinToken lets agents started with --join-token register their own
+// host. Short-lived and multi-use: every host it creates gets Tags and the
+// Permissions below. The token itself is only shown once, at creation.
+type AgentJoinToken struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Tags        []string              `json:"tags"`
+	Permissions []AgentJoinPermission `json:"permissions"`
+	MaxUses     int                   `json:"max_uses"` // 0 = unlimited until ExpiresAt
+	Uses        int                   `json:"uses"`
+	ExpiresAt   time.Time             `json:"expires_at"`
+	CreatedBy   string                `json:"created_by"`
+	CreatedAt   time.Time             `json:"created_at"`
+	LastUsedAt  *time.Time            `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time            `json:"revoked_at,omitempty"`
+}
+
+// AgentJoinPermission is a per-host permission granted on every host a join
+// token creates (see HostPermission).
+type AgentJoinPermission struct {
+	Username string `json:"username"`
+	Level    string `json:"level"` // "viewer" | "operator"
+}
+
+// AgentJoinTokenCreate is the body of POST /api/v1/agent-join-tokens.
+type AgentJoinTokenCreate struct {
+	Name        string                `json:"name" binding:"required"`
+	Tags        []string              `json:"tags"`
+	Permissions []AgentJoinPermission `json:"permissions"`
+	MaxUses     int                   `json:"max_uses"`
+	// ExpiresInHours defaults to 24.
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// AgentJoinRequest is the body of POST /api/agent/join, sent by the agent.
+type AgentJoinRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Hostname string `json:"hostname"`
+	// IPAddress is the agent's own address; the caller's address when empty.
+	IPAddress string `json:"ip_address"`
+	// JoinID identifies this join across retries (16 to 64 characters): a
+	// retry after a lost response gets the host the first attempt created.
+	JoinID string `json:"join_id,omitempty"`
+}
+
+// AgentJoinResponse carries the new host's credential back to the agent.
+type AgentJoinResponse struct {
+	HostID string `json:"host_id"`
+	APIKey string `json:"api_key"`
+}
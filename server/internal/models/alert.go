@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,7 +18,8 @@ type CommandTrigger struct {
 }
 
 // ProxmoxMetricScope defines how a Proxmox metric should be evaluated.
-// ScopeMode can be one of: global, connection, node, storage.
+// ScopeMode can be one of: global, connection, node, storage, guest, disk,
+// backup_group.
 type ProxmoxMetricScope struct {
 	ScopeMode    string `json:"scope_mode,omitempty"`
 	ConnectionID string `json:"connection_id,omitempty"`
@@ -25,11 +27,14 @@ type ProxmoxMetricScope struct {
 	StorageID    string `json:"storage_id,omitempty"`
 	GuestID      string `json:"guest_id,omitempty"`
 	DiskID       string `json:"disk_id,omitempty"`
+	// BackupGroupID is a pbs_backup_groups row (Proxmox Backup Server).
+	BackupGroupID string `json:"backup_group_id,omitempty"`
 }
 
 // DockerMetricScope defines how a Docker metric should be evaluated.
-// ScopeMode can be one of: host, container, compose_project.
-// HostID is always required. ContainerID or ProjectName are required for their respective modes.
+// ScopeMode can be one of: host, container, compose_project, swarm_service, swarm_node.
+// HostID is always required (for Swarm metrics, the manager host whose view is used).
+// ContainerID, ProjectName, ServiceName or NodeID are required for their respective modes.
 // WarnStates and CritStates are used by docker_container_state to select which container states trigger each severity.
 type DockerMetricScope struct {
 	ScopeMode string `json:"scope_mode"`
@@ -41,6 +46,8 @@ type DockerMetricScope struct {
 	ContainerID  string   `json:"container_id,omitempty"`  // DB UUID of docker_containers row
 	ContainerIDs []string `json:"container_ids,omitempty"` // DB UUIDs of docker_containers rows
 	ProjectName  string   `json:"project_name,omitempty"`  // compose project name
+	ServiceName  string   `json:"service_name,omitempty"`  // Swarm service name
+	NodeID       string   `json:"node_id,omitempty"`       // Swarm node ID
 	WarnStates   []string `json:"warn_states,omitempty"`   // container states triggering warn
 	CritStates   []string `json:"crit_states,omitempty"`   // container states triggering crit
 }
@@ -109,6 +116,8 @@ type AlertProxmoxScope struct {
 	Storages    []AlertScopeOption `json:"storages"`
 	Guests      []AlertScopeOption `json:"guests"`
 	Disks       []AlertScopeOption `json:"disks"`
+	// BackupGroups lists the Proxmox Backup Server groups (backup_group mode).
+	BackupGroups []AlertScopeOption `json:"backup_groups"`
 }
 
 // AlertSplitCapabilities is the Proxmox capabilities response (metrics + scope).
@@ -132,12 +141,22 @@ type AlertDockerScopeProject struct {
 	Services []string `json:"services"`
 }
 
-// AlertDockerHostScope groups a host's container + compose-project scope options.
+// AlertDockerScopeSwarmNode is a Swarm node option for a Docker alert scope.
+type AlertDockerScopeSwarmNode struct {
+	NodeID   string `json:"node_id"`
+	Hostname string `json:"hostname"`
+	Role     string `json:"role"`
+}
+
+// AlertDockerHostScope groups a host's container + compose-project scope
+// options, plus its Swarm services and nodes when the host is a manager.
 type AlertDockerHostScope struct {
-	HostID     string                      `json:"host_id"`
-	HostName   string                      `json:"host_name"`
-	Containers []AlertDockerScopeContainer `json:"containers"`
-	Projects   []AlertDockerScopeProject   `json:"projects"`
+	HostID        string                      `json:"host_id"`
+	HostName      string                      `json:"host_name"`
+	Containers    []AlertDockerScopeContainer `json:"containers"`
+	Projects      []AlertDockerScopeProject   `json:"projects"`
+	SwarmServices []string                    `json:"swarm_services"`
+	SwarmNodes    []AlertDockerScopeSwarmNode `json:"swarm_nodes"`
 }
 
 // AlertDockerCapabilities is the Docker capabilities response (metrics + hosts).
@@ -402,7 +421,8 @@ type AlertRuleUpdate struct {
 
 func IsDockerMetric(metric string) bool {
 	switch metric {
-	case "docker_container_state", "docker_compose_degraded_services":
+	case "docker_container_state", "docker_compose_degraded_services",
+		"docker_swarm_service_missing_replicas", "docker_swarm_node_state":
 		return true
 	default:
 		return false
@@ -414,16 +434,85 @@ func IsProxmoxMetric(metric string) bool {
 	case "proxmox_storage_percent", "proxmox_node_cpu_percent", "proxmox_node_memory_percent",
 		"proxmox_node_cpu_temperature", "proxmox_node_fan_rpm",
 		"proxmox_guest_cpu_percent", "proxmox_guest_memory_percent",
+		"proxmox_snapshot_policy_failures",
 		"proxmox_node_pending_updates",
 		"proxmox_recent_failed_tasks_24h",
 		"proxmox_auth_failures_recent",
-		"proxmox_disk_failed_count", "proxmox_disk_min_wearout_percent":
+		"proxmox_disk_failed_count", "proxmox_disk_min_wearout_percent",
+		"pbs_backup_age_hours", "pbs_verify_failed":
+		return true
+	default:
+		return IsProxmoxClusterHealthMetric(metric)
+	}
+}
+
+// IsProxmoxClusterHealthMetric reports the Ceph / HA / corosync metrics: one
+// value per cluster (connection), refined per node where it makes sense.
+// They accept the global, connection and node scopes.
+func IsProxmoxClusterHealthMetric(metric string) bool {
+	switch metric {
+	case "proxmox_ceph_health", "proxmox_ceph_osds_down", "proxmox_ceph_osds_out",
+		"proxmox_ceph_pgs_not_clean", "proxmox_ceph_pool_max_percent", "proxmox_ceph_mons_out_of_quorum",
+		"proxmox_ha_resources_error", "proxmox_ha_manager_errors", "proxmox_cluster_quorum_lost":
 		return true
 	default:
 		return false
 	}
 }
 
+// IsWebDomainMetric reports the access-log metrics evaluated per domain of
+// the rule's host: each domain is its own target (see WebDomainTargetID).
+func IsWebDomainMetric(metric string) bool {
+	return metric == "web_domain_5xx_rate" || metric == "web_domain_p95_latency_ms"
+}
+
+// WebDomainTargetID is the alert target of a domain served by a host:
+// "web:<host_id>:<domain>".
+func WebDomainTargetID(hostID, domain string) string {
+	return "web:" + hostID + ":" + domain
+}
+
+// ParseWebDomainTargetID splits a WebDomainTargetID. Host IDs never contain
+// a colon, so the domain keeps any port suffix.
+func ParseWebDomainTargetID(id string) (hostID, domain string, ok bool) {
+	rest, found := strings.CutPrefix(id, "web:")
+	if !found {
+		return "", "", false
+	}
+	hostID, domain, ok = strings.Cut(rest, ":")
+	return hostID, domain, ok && hostID != ""
+}
+
+// IsNetworkPolicyMetric reports the network_policy_violation metric,
+// evaluated per recorded network flow violation: each offending flow is its
+// own target (see NetworkPolicyTargetID), so each one raises its incident.
+func IsNetworkPolicyMetric(metric string) bool {
+	return metric == "network_policy_violation"
+}
+
+// NetworkPolicyTargetID is the alert target of a network flow violation:
+// "netpolicy:<host_id>:<violation_id>".
+func NetworkPolicyTargetID(hostID string, violationID int64) string {
+	return "netpolicy:" + hostID + ":" + strconv.FormatInt(violationID, 10)
+}
+
+// ParseNetworkPolicyTargetID splits a NetworkPolicyTargetID.
+func ParseNetworkPolicyTargetID(id string) (hostID string, violationID int64, ok bool) {
+	rest, found := strings.CutPrefix(id, "netpolicy:")
+	if !found {
+		return "", 0, false
+	}
+	hostID, raw, found := strings.Cut(rest, ":")
+	if !found || hostID == "" {
+		return "", 0, false
+	}
+	violationID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return hostID, violationID, true
+}
+
 func InferAlertSourceType(metric string) AlertSourceType {
 	if IsDockerMetric(metric) {
 		return AlertSourceDocker
@@ -434,6 +523,23 @@ func InferAlertSourceType(metric string) AlertSourceType {
 	return AlertSourceAgent
 }
 
+// isProxmoxGuestMetric reports the metrics evaluated per VM/LXC: the only
+// ones accepting the guest scope, and never the connection/node scopes.
+func isProxmoxGuestMetric(metric string) bool {
+	switch metric {
+	case "proxmox_guest_cpu_percent", "proxmox_guest_memory_percent", "proxmox_snapshot_policy_failures":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPBSMetric reports the Proxmox Backup Server metrics: evaluated per backup
+// group, they only accept the global and backup_group scopes.
+func IsPBSMetric(metric string) bool {
+	return metric == "pbs_backup_age_hours" || metric == "pbs_verify_failed"
+}
+
 func (ps *ProxmoxMetricScope) Validate(metric string) error {
 	if ps == nil {
 		return fmt.Errorf("le scope Proxmox est requis")
@@ -444,7 +550,7 @@ func (ps *ProxmoxMetricScope) Validate(metric string) error {
 		ps.ScopeMode = "global"
 	}
 
-	validModes := map[string]bool{"global": true, "connection": true, "node": true, "storage": true, "guest": true, "disk": true}
+	validModes := map[string]bool{"global": true, "connection": true, "node": true, "storage": true, "guest": true, "disk": true, "backup_group": true}
 	if !validModes[ps.ScopeMode] {
 		return fmt.Errorf("scope Proxmox invalide")
 	}
@@ -454,17 +560,22 @@ func (ps *ProxmoxMetricScope) Validate(metric string) error {
 	ps.StorageID = strings.TrimSpace(ps.StorageID)
 	ps.GuestID = strings.TrimSpace(ps.GuestID)
 	ps.DiskID = strings.TrimSpace(ps.DiskID)
+	ps.BackupGroupID = strings.TrimSpace(ps.BackupGroupID)
+
+	if IsPBSMetric(metric) && ps.ScopeMode != "global" && ps.ScopeMode != "backup_group" {
+		return fmt.Errorf("les metriques Proxmox Backup Server ne supportent que les scopes global et groupe de sauvegarde")
+	}
 
 	switch ps.ScopeMode {
 	case "connection":
-		if metric == "proxmox_guest_cpu_percent" || metric == "proxmox_guest_memory_percent" {
+		if isProxmoxGuestMetric(metric) {
 			return fmt.Errorf("les metriques VM/LXC Proxmox ne supportent pas le scope connexion")
 		}
 		if ps.ConnectionID == "" {
 			return fmt.Errorf("le scope connexion requiert une connexion Proxmox")
 		}
 	case "node":
-		if metric == "proxmox_guest_cpu_percent" || metric == "proxmox_guest_memory_percent" {
+		if isProxmoxGuestMetric(metric) {
 			return fmt.Errorf("les metriques VM/LXC Proxmox ne supportent pas le scope noeud")
 		}
 		if ps.NodeID == "" {
@@ -478,7 +589,7 @@ func (ps *ProxmoxMetricScope) Validate(metric string) error {
 			return fmt.Errorf("le scope stockage requiert un stockage Proxmox")
 		}
 	case "guest":
-		if metric != "proxmox_guest_cpu_percent" && metric != "proxmox_guest_memory_percent" {
+		if !isProxmoxGuestMetric(metric) {
 			return fmt.Errorf("le scope guest n'est disponible que pour les metriques VM/LXC Proxmox")
 		}
 		if ps.GuestID == "" {
@@ -491,6 +602,13 @@ func (ps *ProxmoxMetricScope) Validate(metric string) error {
 		if ps.DiskID == "" {
 			return fmt.Errorf("le scope disque requiert un disque physique Proxmox")
 		}
+	case "backup_group":
+		if !IsPBSMetric(metric) {
+			return fmt.Errorf("le scope groupe de sauvegarde n'est disponible que pour les metriques Proxmox Backup Server")
+		}
+		if ps.BackupGroupID == "" {
+			return fmt.Errorf("le scope groupe de sauvegarde requiert un groupe PBS")
+		}
 	}
 
 	return nil
@@ -505,12 +623,14 @@ func (ds *DockerMetricScope) Validate(metric string) error {
 	ds.HostID = strings.TrimSpace(ds.HostID)
 	ds.ContainerID = strings.TrimSpace(ds.ContainerID)
 	ds.ProjectName = strings.TrimSpace(ds.ProjectName)
+	ds.ServiceName = strings.TrimSpace(ds.ServiceName)
+	ds.NodeID = strings.TrimSpace(ds.NodeID)
 
 	if ds.HostID == "" {
 		return fmt.Errorf("le scope Docker requiert un hôte")
 	}
 
-	validModes := map[string]bool{"host": true, "container": true, "compose_project": true}
+	validModes := map[string]bool{"host": true, "container": true, "compose_project": true, "swarm_service": true, "swarm_node": true}
 	if ds.ScopeMode == "" {
 		ds.ScopeMode = "host"
 	}
@@ -520,8 +640,8 @@ func (ds *DockerMetricScope) Validate(metric string) error {
 
 	switch metric {
 	case "docker_container_state":
-		if ds.ScopeMode == "compose_project" {
-			return fmt.Errorf("docker_container_state ne supporte pas le scope compose_project")
+		if ds.ScopeMode != "host" && ds.ScopeMode != "container" {
+			return fmt.Errorf("docker_container_state ne supporte pas le scope %s", ds.ScopeMode)
 		}
 		if len(ds.WarnStates) == 0 && len(ds.CritStates) == 0 {
 			return fmt.Errorf("docker_container_state requiert au moins un état à surveiller")
@@ -530,6 +650,14 @@ func (ds *DockerMetricScope) Validate(metric string) error {
 		if ds.ScopeMode != "compose_project" {
 			return fmt.Errorf("docker_compose_degraded_services requiert le scope compose_project")
 		}
+	case "docker_swarm_service_missing_replicas":
+		if ds.ScopeMode != "host" && ds.ScopeMode != "swarm_service" {
+			return fmt.Errorf("docker_swarm_service_missing_replicas requiert le scope host ou swarm_service")
+		}
+	case "docker_swarm_node_state":
+		if ds.ScopeMode != "host" && ds.ScopeMode != "swarm_node" {
+			return fmt.Errorf("docker_swarm_node_state requiert le scope host ou swarm_node")
+		}
 	}
 
 	switch ds.ScopeMode {
@@ -544,6 +672,14 @@ func (ds *DockerMetricScope) Validate(metric string) error {
 		if ds.ProjectName == "" {
 			return fmt.Errorf("le scope compose_project requiert un nom de projet")
 		}
+	case "swarm_service":
+		if ds.ServiceName == "" {
+			return fmt.Errorf("le scope swarm_service requiert un service Swarm")
+		}
+	case "swarm_node":
+		if ds.NodeID == "" {
+			return fmt.Errorf("le scope swarm_node requiert un noeud Swarm")
+		}
 	}
 
 	return nil
@@ -595,3 +731,55 @@ func (ar *AlertRule) Validate() error {
 
 	return nil
 }
+
+// IsNetInterfaceMetric reports the link health metrics evaluated per network
+// interface of the rule's host: each interface is its own target (see
+// NetInterfaceTargetID).
+func IsNetInterfaceMetric(metric string) bool {
+	switch metric {
+	case "net_interface_down", "net_interface_error_rate", "net_interface_utilization":
+		return true
+	default:
+		return false
+	}
+}
+
+// NetInterfaceTargetID is the alert target of a host's network interface:
+// "netif:<host_id>:<interface>".
+func NetInterfaceTargetID(hostID, name string) string {
+	return "netif:" + hostID + ":" + name
+}
+
+// ParseNetInterfaceTargetID splits a NetInterfaceTargetID.
+func ParseNetInterfaceTargetID(id string) (hostID, name string, ok bool) {
+	rest, found := strings.CutPrefix(id, "netif:")
+	if !found {
+		return "", "", false
+	}
+	hostID, name, ok = strings.Cut(rest, ":")
+	return hostID, name, ok && hostID != "" && name != ""
+}
+
+// IsCrowdSecBouncerMetric reports the metric evaluated per CrowdSec bouncer
+// registered on the rule's host: each bouncer is its own target (see
+// CrowdSecBouncerTargetID).
+func IsCrowdSecBouncerMetric(metric string) bool {
+	return metric == "crowdsec_bouncer_last_pull_minutes"
+}
+
+// CrowdSecBouncerTargetID is the alert target of a bouncer registered on a
+// host's CrowdSec local API: "bouncer:<host_id>:<name>".
+func CrowdSecBouncerTargetID(hostID, name string) string {
+	return "bouncer:" + hostID + ":" + name
+}
+
+// ParseCrowdSecBouncerTargetID splits a CrowdSecBouncerTargetID. The bouncer
+// name may itself contain colons.
+func ParseCrowdSecBouncerTargetID(id string) (hostID, name string, ok bool) {
+	rest, found := strings.CutPrefix(id, "bouncer:")
+	if !found {
+		return "", "", false
+	}
+	hostID, name, ok = strings.Cut(rest, ":")
+	return hostID, name, ok && hostID != "" && name != ""
+}
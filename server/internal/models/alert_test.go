@@ -42,3 +42,93 @@ func TestAlertRuleDisplayName(t *testing.T) {
 		})
 	}
 }
+
+func TestDockerMetricScopeValidate_Swarm(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric  string
+		scope   DockerMetricScope
+		wantErr bool
+	}{
+		{"service metric on whole manager", "docker_swarm_service_missing_replicas", DockerMetricScope{HostID: "h1", ScopeMode: "host"}, false},
+		{"service metric on one service", "docker_swarm_service_missing_replicas", DockerMetricScope{HostID: "h1", ScopeMode: "swarm_service", ServiceName: "web"}, false},
+		{"service scope without name", "docker_swarm_service_missing_replicas", DockerMetricScope{HostID: "h1", ScopeMode: "swarm_service"}, true},
+		{"service metric on node scope", "docker_swarm_service_missing_replicas", DockerMetricScope{HostID: "h1", ScopeMode: "swarm_node", NodeID: "n1"}, true},
+		{"node metric on one node", "docker_swarm_node_state", DockerMetricScope{HostID: "h1", ScopeMode: "swarm_node", NodeID: "n1"}, false},
+		{"node metric on compose scope", "docker_swarm_node_state", DockerMetricScope{HostID: "h1", ScopeMode: "compose_project", ProjectName: "p"}, true},
+		{"container metric on swarm scope", "docker_container_state", DockerMetricScope{HostID: "h1", ScopeMode: "swarm_service", ServiceName: "web", CritStates: []string{"exited"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scope.Validate(tt.metric)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if !IsDockerMetric("docker_swarm_node_state") || !IsDockerMetric("docker_swarm_service_missing_replicas") {
+		t.Error("swarm metrics must be Docker-source metrics")
+	}
+}
+
+func TestWebDomainTargetID(t *testing.T) {
+	id := WebDomainTargetID("h1", "shop.example:8443")
+	host, domain, ok := ParseWebDomainTargetID(id)
+	if !ok || host != "h1" || domain != "shop.example:8443" {
+		t.Errorf("ParseWebDomainTargetID(%q) = %q, %q, %v", id, host, domain, ok)
+	}
+	if _, _, ok := ParseWebDomainTargetID("web:h1"); ok {
+		t.Error("a target without domain separator must not parse")
+	}
+	if _, _, ok := ParseWebDomainTargetID("docker:container:x"); ok {
+		t.Error("a non-web target must not parse")
+	}
+	if InferAlertSourceType("web_domain_p95_latency_ms") != AlertSourceAgent {
+		t.Error("web domain metrics are agent-source metrics")
+	}
+}
+
+func TestNetworkPolicyTargetID(t *testing.T) {
+	id := NetworkPolicyTargetID("h1", 42)
+	host, violation, ok := ParseNetworkPolicyTargetID(id)
+	if !ok || host != "h1" || violation != 42 {
+		t.Errorf("ParseNetworkPolicyTargetID(%q) = %q, %d, %v", id, host, violation, ok)
+	}
+	for _, bad := range []string{"netpolicy:h1", "netpolicy::42", "netpolicy:h1:x", "web:h1:42"} {
+		if _, _, ok := ParseNetworkPolicyTargetID(bad); ok {
+			t.Errorf("ParseNetworkPolicyTargetID(%q) must fail", bad)
+		}
+	}
+}
+
+func TestNetInterfaceTargetID(t *testing.T) {
+	id := NetInterfaceTargetID("h1", "bond0.100")
+	host, name, ok := ParseNetInterfaceTargetID(id)
+	if !ok || host != "h1" || name != "bond0.100" {
+		t.Errorf("ParseNetInterfaceTargetID(%q) = %q, %q, %v", id, host, name, ok)
+	}
+	for _, bad := range []string{"netif:h1", "netif::eth0", "netif:h1:", "web:h1:eth0"} {
+		if _, _, ok := ParseNetInterfaceTargetID(bad); ok {
+			t.Errorf("ParseNetInterfaceTargetID(%q) must fail", bad)
+		}
+	}
+	if !IsNetInterfaceMetric("net_interface_utilization") || IsNetInterfaceMetric("network_rx_bytes") {
+		t.Error("IsNetInterfaceMetric misclassifies")
+	}
+}
+
+func TestCrowdSecBouncerTargetID(t *testing.T) {
+	id := CrowdSecBouncerTargetID("h1", "cs-firewall-bouncer@10.0.0.1:8080")
+	host, name, ok := ParseCrowdSecBouncerTargetID(id)
+	if !ok || host != "h1" || name != "cs-firewall-bouncer@10.0.0.1:8080" {
+		t.Errorf("ParseCrowdSecBouncerTargetID(%q) = %q, %q, %v", id, host, name, ok)
+	}
+	for _, bad := range []string{"bouncer:h1", "bouncer::fw", "bouncer:h1:", "netif:h1:fw"} {
+		if _, _, ok := ParseCrowdSecBouncerTargetID(bad); ok {
+			t.Errorf("ParseCrowdSecBouncerTargetID(%q) must fail", bad)
+		}
+	}
+	if !IsCrowdSecBouncerMetric("crowdsec_bouncer_last_pull_minutes") || IsCrowdSecBouncerMetric("net_interface_down") {
+		t.Error("IsCrowdSecBouncerMetric misclassifies")
+	}
+}
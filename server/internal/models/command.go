@@ -31,6 +31,20 @@ type DockerCommandRequest struct {
 	WorkingDir    string `json:"working_dir"` // required for compose_* actions
 }
 
+// ComposeFileCommandRequest drives the compose file editor: read returns the
+// masked compose file and .env, validate runs `docker compose config` on the
+// edited content and returns a diff, apply snapshots + writes + redeploys, and
+// rollback restores the snapshot of the last apply. Compose/Env are ignored by
+// read and rollback; a nil Env leaves the host's .env untouched.
+type ComposeFileCommandRequest struct {
+	HostID           string  `json:"host_id" binding:"required"`
+	Project          string  `json:"project" binding:"required"`
+	Action           string  `json:"action" binding:"required,oneof=read validate apply rollback"`
+	Compose          string  `json:"compose"`
+	Env              *string `json:"env,omitempty"`
+	HealthTimeoutSec int     `json:"healthcheck_timeout_sec" binding:"min=0,max=600"`
+}
+
 // ========== Commands (server → agent) ==========
 
 type PendingCommand struct {
@@ -0,0 +1,148 @@
+package models
+
+import "time"
+
+const (
+	// CrowdSecSnapshotMaxAge is how old a host's CrowdSec snapshot may get
+	// before the fleet view stops trusting it: the agent re-sends it every
+	// minute, so this is several missed cycles (agent down, LAPI
+	// unreachable).
+	CrowdSecSnapshotMaxAge = 15 * time.Minute
+	// CrowdSecBouncerStaleAfter flags a bouncer in the fleet view. Bouncers
+	// pull every few seconds by default; the crowdsec_bouncer_last_pull_minutes
+	// alert lets each rule pick its own threshold.
+	CrowdSecBouncerStaleAfter = 10 * time.Minute
+)
+
+// CrowdSecReport is a host's CrowdSec state as sent by the agent (see
+// agent/internal/collector/crowdsec_sync.go). Mirrors
+// agent/internal/collector.CrowdSecReport.
+type CrowdSecReport struct {
+	// Decisions are the local ones; community blocklists (CAPI, lists) are
+	// only counted.
+	Decisions          []CrowdSecReportDecision `json:"decisions"`
+	CommunityDecisions int                      `json:"community_decisions"`
+	Truncated          bool                     `json:"truncated,omitempty"`
+	Bouncers           []CrowdSecBouncer        `json:"bouncers"`
+	// BouncersError is set when cscli could not list the bouncers: the
+	// previously stored list is kept.
+	BouncersError string `json:"bouncers_error,omitempty"`
+}
+
+// CrowdSecReportDecision is one active decision of a host's local API.
+type CrowdSecReportDecision struct {
+	Value     string    `json:"value"` // IP or range
+	Type      string    `json:"type"`  // ban, captcha...
+	Scenario  string    `json:"scenario"`
+	Origin    string    `json:"origin"`
+	Country   string    `json:"country,omitempty"`
+	ASName    string    `json:"as_name,omitempty"`
+	StartedAt time.Time `json:"started_at"` // zero when unknown
+	Until     time.Time `json:"until"`      // zero when unknown
+}
+
+// CrowdSecBouncer is one bouncer registered on a host's local API.
+type CrowdSecBouncer struct {
+	Name      string    `json:"name"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	LastPull  time.Time `json:"last_pull"` // zero when it never pulled
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// CrowdSecHostSync is the freshness of one host's stored CrowdSec snapshot.
+type CrowdSecHostSync struct {
+	HostID             string    `json:"host_id"`
+	HostName           string    `json:"host_name"`
+	CollectedAt        time.Time `json:"collected_at"`
+	LocalDecisions     int       `json:"local_decisions"`
+	CommunityDecisions int       `json:"community_decisions"`
+	Truncated          bool      `json:"truncated"`
+	BouncersError      string    `json:"bouncers_error,omitempty"`
+	// Stale is true once the snapshot is older than CrowdSecSnapshotMaxAge.
+	Stale bool `json:"stale"`
+}
+
+// CrowdSecHostDecision is one stored decision of one host.
+type CrowdSecHostDecision struct {
+	HostID    string     `json:"host_id"`
+	HostName  string     `json:"host_name"`
+	Value     string     `json:"-"`
+	Type      string     `json:"type"`
+	Scenario  string     `json:"scenario"`
+	Origin    string     `json:"origin"`
+	Country   string     `json:"-"`
+	ASName    string     `json:"-"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+}
+
+// CrowdSecFleetDecision is every active decision on one IP or range across
+// the fleet.
+type CrowdSecFleetDecision struct {
+	Value     string   `json:"value"`
+	Types     []string `json:"types"`
+	Scenarios []string `json:"scenarios"`
+	Origins   []string `json:"origins"`
+	Country   string   `json:"country,omitempty"`
+	ASName    string   `json:"as_name,omitempty"`
+	// StartedAt is the earliest known start, Until the latest expiry.
+	StartedAt *time.Time             `json:"started_at,omitempty"`
+	Until     *time.Time             `json:"until,omitempty"`
+	Hosts     []CrowdSecHostDecision `json:"hosts"`
+	// MissingHostIDs are the hosts with a fresh snapshot that do not enforce
+	// this decision: where a fleet-wide ban would add it.
+	MissingHostIDs []string `json:"missing_host_ids"`
+}
+
+// CrowdSecFleetDecisions is the deduplicated fleet-wide decision list.
+type CrowdSecFleetDecisions struct {
+	Hosts     []CrowdSecHostSync      `json:"hosts"`
+	Decisions []CrowdSecFleetDecision `json:"decisions"`
+}
+
+// CrowdSecBouncerStatus is a stored bouncer with its host and pull health.
+type CrowdSecBouncerStatus struct {
+	CrowdSecBouncer `tstype:",extends"`
+	HostID          string `json:"host_id"`
+	HostName        string `json:"host_name"`
+	// ListedAt is when the agent last listed this host's bouncers.
+	ListedAt time.Time `json:"listed_at"`
+	// MinutesSinceLastPull counts from the last pull, or from the
+	// registration of a bouncer that never pulled; nil when neither is known.
+	MinutesSinceLastPull *float64 `json:"minutes_since_last_pull,omitempty"`
+	// Stale is true for a non-revoked bouncer past CrowdSecBouncerStaleAfter.
+	Stale bool `json:"stale"`
+}
+
+// CrowdSecBouncerPullAge is how long ago b last pulled — or was registered,
+// if it never pulled. ok is false when neither time is known.
+func CrowdSecBouncerPullAge(b CrowdSecBouncer, now time.Time) (age time.Duration, ok bool) {
+	ref := b.LastPull
+	if ref.IsZero() {
+		ref = b.CreatedAt
+	}
+	if ref.IsZero() {
+		return 0, false
+	}
+	return max(now.Sub(ref), 0), true
+}
+
+// CrowdSecFleetActionInput is the body of a fleet-wide ban.
+type CrowdSecFleetActionInput struct {
+	IP       string `json:"ip" binding:"required"`
+	Duration string `json:"duration"`
+}
+
+// CrowdSecFleetAction is the outcome of a fleet-wide ban or unban: one agent
+// command per CrowdSec-capable host.
+type CrowdSecFleetAction struct {
+	IP         string   `json:"ip"`
+	Action     string   `json:"action"` // "ban" or "unban"
+	Duration   string   `json:"duration,omitempty"`
+	HostIDs    []string `json:"host_ids"`
+	CommandIDs []string `json:"command_ids"`
+	Failures   []string `json:"failures"`
+}
@@ -25,8 +25,8 @@ type DockerContainer struct {
 	// IPAddresses are the container's Docker-assigned addresses across its
 	// attached networks. Reported by the agent (which needs them locally to
 	// attribute container traffic in the network-flow collector — see
-	// agent/internal/collector/container_ips.go) and accepted here as container
-	// metadata; not persisted, same as Labels/EnvVars/Volumes above.
+	// agent/internal/collector/container_ips.go) and persisted so the service
+	// dependency map can resolve a flow's remote IP to a container.
 	IPAddresses []string  `json:"ip_addresses" db:"-"`
 	NetRxBytes  uint64    `json:"net_rx_bytes" db:"net_rx_bytes"`
 	NetTxBytes  uint64    `json:"net_tx_bytes" db:"net_tx_bytes"`
@@ -53,6 +53,175 @@ type ComposeProject struct {
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ========== Docker disk usage (`docker system df -v`) ==========
+
+// DockerImageUsage mirrors agent/internal/collector.DockerImageUsage.
+type DockerImageUsage struct {
+	ImageID    string `json:"image_id"`
+	Image      string `json:"image"`
+	SizeBytes  int64  `json:"size_bytes"`
+	SharedSize int64  `json:"shared_size"`
+	Containers int64  `json:"containers"`
+}
+
+// DockerContainerUsage mirrors agent/internal/collector.DockerContainerUsage.
+type DockerContainerUsage struct {
+	ContainerID    string `json:"container_id"`
+	Name           string `json:"name"`
+	Image          string `json:"image"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	SizeRwBytes    int64  `json:"size_rw_bytes"`
+	SizeRootFs     int64  `json:"size_root_fs_bytes"`
+}
+
+// DockerVolumeUsage mirrors agent/internal/collector.DockerVolumeUsage.
+// SizeBytes is -1 when the volume driver does not report usage.
+type DockerVolumeUsage struct {
+	Name           string `json:"name"`
+	Driver         string `json:"driver"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	SizeBytes      int64  `json:"size_bytes"`
+	RefCount       int64  `json:"ref_count"`
+}
+
+// DockerDiskUsageReport mirrors agent/internal/collector.DockerDiskUsageReport:
+// per-kind totals plus the agent's top-N largest items of each kind. Only sent
+// on the report cycles where the agent actually re-measured (every ~15 min).
+type DockerDiskUsageReport struct {
+	CollectedAt           time.Time              `json:"collected_at"`
+	LayersSize            int64                  `json:"layers_size"`
+	ImagesSize            int64                  `json:"images_size"`
+	ContainersSize        int64                  `json:"containers_size"`
+	VolumesSize           int64                  `json:"volumes_size"`
+	BuildCacheSize        int64                  `json:"build_cache_size"`
+	BuildCacheReclaimable int64                  `json:"build_cache_reclaimable"`
+	Images                []DockerImageUsage     `json:"images"`
+	Containers            []DockerContainerUsage `json:"containers"`
+	Volumes               []DockerVolumeUsage    `json:"volumes"`
+}
+
+// Docker disk usage item kinds, shared by docker_disk_usage_items (current top
+// consumers) and docker_disk_usage_metrics (history). The *Total kinds carry
+// the per-host sum under an empty name.
+const (
+	DockerDiskKindImage           = "image"
+	DockerDiskKindContainer       = "container"
+	DockerDiskKindVolume          = "volume"
+	DockerDiskKindImagesTotal     = "images_total"
+	DockerDiskKindContainersTotal = "containers_total"
+	DockerDiskKindVolumesTotal    = "volumes_total"
+	DockerDiskKindBuildCacheTotal = "build_cache_total"
+)
+
+// DockerDiskUsageItem is one current top consumer of a host's Docker disk
+// space (an image, a container's writable layer or a volume), flattened for
+// the "top consumers" view.
+type DockerDiskUsageItem struct {
+	Kind           string    `json:"kind"`
+	Name           string    `json:"name"`
+	Detail         string    `json:"detail"` // image ref for containers, driver for volumes, image ID for images
+	ComposeProject string    `json:"compose_project,omitempty"`
+	SizeBytes      int64     `json:"size_bytes"`
+	CollectedAt    time.Time `json:"collected_at"`
+}
+
+// DockerDiskUsageSummary is a host's current Docker disk usage: per-kind
+// totals plus the largest items across all kinds.
+type DockerDiskUsageSummary struct {
+	HostID                string                `json:"host_id"`
+	CollectedAt           *time.Time            `json:"collected_at,omitempty"`
+	ImagesSize            int64                 `json:"images_size"`
+	ContainersSize        int64                 `json:"containers_size"`
+	VolumesSize           int64                 `json:"volumes_size"`
+	BuildCacheSize        int64                 `json:"build_cache_size"`
+	BuildCacheReclaimable int64                 `json:"build_cache_reclaimable"`
+	TopConsumers          []DockerDiskUsageItem `json:"top_consumers"`
+}
+
+// DockerDiskUsagePoint is one bucketed history sample of a kind total or of a
+// single volume/container.
+type DockerDiskUsagePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	SizeBytes int64     `json:"size_bytes"`
+}
+
+// DockerVolumeGrowth is one row of the fleet-wide "top volume growth" view:
+// a volume's latest size and how much it grew over the requested window.
+type DockerVolumeGrowth struct {
+	HostID         string `json:"host_id"`
+	Hostname       string `json:"hostname"`
+	Name           string `json:"name"`
+	ComposeProject string `json:"compose_project,omitempty"`
+	SizeBytes      int64  `json:"size_bytes"`
+	GrowthBytes    int64  `json:"growth_bytes"`
+}
+
+// ========== Docker Swarm ==========
+
+// SwarmService mirrors agent/internal/collector.SwarmService. For global
+// services DesiredReplicas is the number of eligible nodes.
+type SwarmService struct {
+	ServiceID       string `json:"service_id"`
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	Mode            string `json:"mode"`
+	Stack           string `json:"stack,omitempty"`
+	DesiredReplicas int    `json:"desired_replicas"`
+	RunningReplicas int    `json:"running_replicas"`
+	UpdateState     string `json:"update_state,omitempty"`
+}
+
+// SwarmTask mirrors agent/internal/collector.SwarmTask.
+type SwarmTask struct {
+	TaskID       string    `json:"task_id"`
+	ServiceID    string    `json:"service_id"`
+	ServiceName  string    `json:"service_name"`
+	NodeID       string    `json:"node_id"`
+	Slot         int       `json:"slot,omitempty"`
+	State        string    `json:"state"`
+	DesiredState string    `json:"desired_state"`
+	Message      string    `json:"message,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SwarmNode mirrors agent/internal/collector.SwarmNode.
+type SwarmNode struct {
+	NodeID        string `json:"node_id"`
+	Hostname      string `json:"hostname"`
+	Role          string `json:"role"`
+	Availability  string `json:"availability"`
+	State         string `json:"state"`
+	Leader        bool   `json:"leader,omitempty"`
+	Reachability  string `json:"reachability,omitempty"`
+	Addr          string `json:"addr,omitempty"`
+	EngineVersion string `json:"engine_version,omitempty"`
+}
+
+// DockerSwarmReport mirrors agent/internal/collector.DockerSwarmReport. Sent
+// by every Docker host; only managers (Manager true) carry the lists.
+type DockerSwarmReport struct {
+	LocalNodeState string         `json:"local_node_state"`
+	Manager        bool           `json:"manager"`
+	ClusterID      string         `json:"cluster_id,omitempty"`
+	NodeID         string         `json:"node_id,omitempty"`
+	Services       []SwarmService `json:"services,omitempty"`
+	Tasks          []SwarmTask    `json:"tasks,omitempty"`
+	Nodes          []SwarmNode    `json:"nodes,omitempty"`
+}
+
+// DockerSwarmState is the Swarm cluster as last seen by one manager host.
+// UpdatedAt is nil when the host is not (or never was) a Swarm manager.
+type DockerSwarmState struct {
+	HostID    string         `json:"host_id"`
+	ClusterID string         `json:"cluster_id"`
+	NodeID    string         `json:"node_id"`
+	UpdatedAt *time.Time     `json:"updated_at,omitempty"`
+	Services  []SwarmService `json:"services"`
+	Tasks     []SwarmTask    `json:"tasks"`
+	Nodes     []SwarmNode    `json:"nodes"`
+}
+
 // ========== Docker image version cache (ambient update detection) ==========
 
 // DockerImageRef is a normalized (image, tag) pair — the cache key of the
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// GeoInfo is the offline geolocation of an IP (see internal/geoip). Empty for
+// private addresses and when no database is configured.
+type GeoInfo struct {
+	CountryCode string `json:"country_code,omitempty"`
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	ASOrg       string `json:"as_org,omitempty"`
+}
+
+// GeoGroup is one row of a group-by-country or group-by-ASN summary. Hits is
+// set for web logs, RxBytes/TxBytes for network flows.
+type GeoGroup struct {
+	Key         string `json:"key"` // ISO country code or "AS<n>"
+	Label       string `json:"label"`
+	CountryCode string `json:"country_code,omitempty"`
+	ASN         uint32 `json:"asn,omitempty"`
+	IPs         int    `json:"ips"`
+	Hits        int64  `json:"hits,omitempty"`
+	RxBytes     uint64 `json:"rx_bytes,omitempty"`
+	TxBytes     uint64 `json:"tx_bytes,omitempty"`
+}
+
+// GeoIPDatabase describes one loaded MMDB file.
+type GeoIPDatabase struct {
+	Path         string     `json:"path"`
+	Loaded       bool       `json:"loaded"`
+	DatabaseType string     `json:"database_type,omitempty"`
+	BuildDate    *time.Time `json:"build_date,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// GeoIPStatus reports which geolocation databases the server uses.
+type GeoIPStatus struct {
+	Enabled bool           `json:"enabled"`
+	City    *GeoIPDatabase `json:"city,omitempty"`
+	ASN     *GeoIPDatabase `json:"asn,omitempty"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// IP block decision statuses.
+const (
+	IPBlockStatusApplied  = "applied"  // CrowdSec bans dispatched
+	IPBlockStatusDryRun   = "dry_run"  // notified only
+	IPBlockStatusFailed   = "failed"   // no ban could be dispatched
+	IPBlockStatusReverted = "reverted" // unbanned by an admin
+)
+
+// IPBlockPolicy bans, on every host with CrowdSec, the IPs whose threat score
+// over the last WindowMinutes (all hosts together) exceeds MinScore. In
+// DryRun mode it only records and notifies the decision.
+type IPBlockPolicy struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Enabled        bool       `json:"enabled"`
+	MinScore       float64    `json:"min_score"`
+	WindowMinutes  int        `json:"window_minutes"`
+	BanDuration    string     `json:"ban_duration"`
+	DryRun         bool       `json:"dry_run"`
+	Allowlist      []string   `json:"allowlist"` // IPs or CIDRs never blocked
+	NotifyChannels []string   `json:"notify_channels"`
+	CreatedBy      string     `json:"created_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+}
+
+// IPBlockPolicyInput is the create/update payload. Omitted fields take their
+// defaults on create (window 10 min, ban 4h, dry run on, browser
+// notifications) and keep their value on update.
+type IPBlockPolicyInput struct {
+	Name           string   `json:"name"`
+	Enabled        *bool    `json:"enabled"`
+	MinScore       *float64 `json:"min_score"`
+	WindowMinutes  *int     `json:"window_minutes"`
+	BanDuration    *string  `json:"ban_duration"`
+	DryRun         *bool    `json:"dry_run"`
+	Allowlist      []string `json:"allowlist"`
+	NotifyChannels []string `json:"notify_channels"`
+}
+
+// IPBlockDecision is the audit record of one automatic decision.
+// TargetHostIDs/CommandIDs are the hosts banned and their crowdsec commands.
+type IPBlockDecision struct {
+	ID            int64      `json:"id"`
+	PolicyID      string     `json:"policy_id"`
+	PolicyName    string     `json:"policy_name"`
+	IP            string     `json:"ip"`
+	Score         float64    `json:"score"`
+	Level         string     `json:"level"`
+	Hits          int64      `json:"hits"`
+	UniquePaths   int64      `json:"unique_paths"`
+	HostCount     int64      `json:"host_count"`
+	Duration      string     `json:"duration"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	TargetHostIDs []string   `json:"target_host_ids"`
+	CommandIDs    []string   `json:"command_ids"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevertedAt    *time.Time `json:"reverted_at,omitempty"`
+	RevertedBy    string     `json:"reverted_by,omitempty"`
+
+	// Geo is the offline geolocation of IP, added on read.
+	Geo *GeoInfo `json:"geo,omitempty"`
+}
+
+// IPThreatScore is one IP's threat score over a window, all hosts together.
+type IPThreatScore struct {
+	IP          string  `json:"ip"`
+	Score       float64 `json:"score"`
+	Level       string  `json:"level"`
+	Hits        int64   `json:"hits"`
+	UniquePaths int64   `json:"unique_paths"`
+	HostCount   int64   `json:"host_count"`
+}
@@ -0,0 +1,107 @@
+package models
+
+import "time"
+
+// NetInterface is one network interface as reported by the agent for a report
+// cycle: link state plus the kernel's cumulative since-boot counters (see
+// agent/internal/collector/net_interfaces.go). Mirrors
+// agent/internal/collector.NetInterface. SystemMetrics' network_rx_bytes /
+// network_tx_bytes stay the host-wide sum; this is the per-link view.
+type NetInterface struct {
+	Name string `json:"name"`
+	// Kind is "physical", "bond", "bridge", "vlan" or "virtual".
+	Kind string `json:"kind"`
+	// OperState is the kernel's operstate verbatim (up, down,
+	// lowerlayerdown, dormant, unknown...). See NetInterfaceDown.
+	OperState string `json:"operstate"`
+	// SpeedMbps is 0 when the driver reports no link speed (virtual links,
+	// links that are down) — utilization is then unknown.
+	SpeedMbps int      `json:"speed_mbps,omitempty"`
+	Duplex    string   `json:"duplex,omitempty"`
+	MTU       int      `json:"mtu,omitempty"`
+	Master    string   `json:"master,omitempty"`  // bond or bridge this interface is enslaved to
+	Parent    string   `json:"parent,omitempty"`  // 802.1Q parent of a VLAN sub-interface
+	VLANID    int      `json:"vlan_id,omitempty"` // 802.1Q tag of a VLAN sub-interface
+	Members   []string `json:"members,omitempty"` // a bond's slaves or a bridge's ports
+	RxBytes   uint64   `json:"rx_bytes"`
+	TxBytes   uint64   `json:"tx_bytes"`
+	RxPackets uint64   `json:"rx_packets"`
+	TxPackets uint64   `json:"tx_packets"`
+	RxErrors  uint64   `json:"rx_errors"`
+	TxErrors  uint64   `json:"tx_errors"`
+	RxDropped uint64   `json:"rx_dropped"`
+	TxDropped uint64   `json:"tx_dropped"`
+}
+
+// NetInterfaceDown reports whether an operstate means the link is down.
+// "unknown" is not down: tun devices and some virtual links never report a
+// state yet carry traffic.
+func NetInterfaceDown(operState string) bool {
+	switch operState {
+	case "down", "lowerlayerdown", "notpresent":
+		return true
+	default:
+		return false
+	}
+}
+
+// NetInterfaceStatus is an interface's latest sample, with rates derived from
+// the sample before it. Rates are zero when there is no previous sample or the
+// counters were reset in between (reboot, driver reload).
+type NetInterfaceStatus struct {
+	NetInterface    `tstype:",extends"`
+	Timestamp       time.Time `json:"timestamp"`
+	RxBytesPerSec   float64   `json:"rx_bytes_per_sec"`
+	TxBytesPerSec   float64   `json:"tx_bytes_per_sec"`
+	RxPacketsPerSec float64   `json:"rx_packets_per_sec"`
+	TxPacketsPerSec float64   `json:"tx_packets_per_sec"`
+	ErrorsPerSec    float64   `json:"errors_per_sec"`
+	DropsPerSec     float64   `json:"drops_per_sec"`
+	// UtilizationPercent is max(rx, tx) against the link speed; nil when the
+	// speed is unknown.
+	UtilizationPercent *float64 `json:"utilization_percent,omitempty"`
+}
+
+// NetInterfacePoint is one time bucket of an interface's history.
+type NetInterfacePoint struct {
+	Timestamp          time.Time `json:"timestamp"`
+	RxBytesPerSec      float64   `json:"rx_bytes_per_sec"`
+	TxBytesPerSec      float64   `json:"tx_bytes_per_sec"`
+	RxPacketsPerSec    float64   `json:"rx_packets_per_sec"`
+	TxPacketsPerSec    float64   `json:"tx_packets_per_sec"`
+	ErrorsPerSec       float64   `json:"errors_per_sec"`
+	DropsPerSec        float64   `json:"drops_per_sec"`
+	UtilizationPercent *float64  `json:"utilization_percent,omitempty"`
+	// Down is true when any sample of the bucket saw the link down.
+	Down bool `json:"down"`
+}
+
+// NetInterfaceHistory is the bucketed history of one interface.
+type NetInterfaceHistory struct {
+	Name   string              `json:"name"`
+	Points []NetInterfacePoint `json:"points"`
+}
+
+// NetInterfaceWindow sums an interface's counter deltas over an alert
+// evaluation window. Intervals across a counter reset are left out, so
+// Seconds can be shorter than the window.
+type NetInterfaceWindow struct {
+	Seconds   float64
+	RxBytes   uint64
+	TxBytes   uint64
+	Packets   uint64
+	Errors    uint64
+	SpeedMbps int
+	OperState string
+}
+
+// NetInterfaceUtilization is the busier direction's share of the link speed,
+// in percent; nil when the speed is unknown.
+func NetInterfaceUtilization(rxBytesPerSec, txBytesPerSec float64, speedMbps int) *float64 {
+	if speedMbps <= 0 {
+		return nil
+	}
+	busiest := max(rxBytesPerSec, txBytesPerSec)
+	pct := busiest * 8 / (float64(speedMbps) * 1e6) * 100
+	return &pct
+}
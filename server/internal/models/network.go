@@ -73,7 +73,10 @@ type TopologySnapshot struct {
 	Hosts      []NetworkHost          `json:"hosts"`
 	Containers []NetworkContainer     `json:"containers"`
 	Config     *NetworkTopologyConfig `json:"config,omitempty"`
-	UpdatedAt  time.Time              `json:"updated_at"`
+	// Dependencies is the flow-derived service dependency layer, only built
+	// when requested (?layers=dependencies).
+	Dependencies *ServiceDependencyGraph `json:"dependencies,omitempty"`
+	UpdatedAt    time.Time               `json:"updated_at"`
 }
 
 // ========== Network IP Inventory (Proxmox guests + NPM domains) ==========
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// ========== Service dependency map (from network flows) ==========
+
+// Service dependency node types.
+const (
+	DependencyNodeHost         = "host"
+	DependencyNodeContainer    = "container"
+	DependencyNodeProxmoxGuest = "proxmox_guest"
+	DependencyNodeExternal     = "external"
+)
+
+// NetworkFlowObservation is one talker of one host summed over a window:
+// the raw material of the dependency map, before its IPs are resolved.
+type NetworkFlowObservation struct {
+	HostID      string    `json:"host_id"`
+	RemoteIP    string    `json:"remote_ip"`
+	RemotePort  int       `json:"remote_port"`
+	Protocol    string    `json:"protocol"`
+	Direction   string    `json:"direction"`
+	ProcessName string    `json:"process_name,omitempty"`
+	ServerName  string    `json:"server_name,omitempty"`
+	RxBytes     uint64    `json:"rx_bytes"`
+	TxBytes     uint64    `json:"tx_bytes"`
+	Connections int64     `json:"connections"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ServiceDependencyNode is an endpoint of the dependency map: an agented
+// host, a container, a Proxmox guest without agent, or an unknown IP.
+type ServiceDependencyNode struct {
+	ID     string `json:"id"`   // "host:<id>", "container:<host_id>:<name>", "proxmox_guest:<id>", "ip:<addr>"
+	Type   string `json:"type"` // host | container | proxmox_guest | external
+	Name   string `json:"name"`
+	HostID string `json:"host_id,omitempty"` // the host running a container
+	IP     string `json:"ip,omitempty"`
+}
+
+// ServiceDependencyEdge is "Source calls Target on Port": every observation
+// of the same call summed over the window. Port is 0 when the call was only
+// seen from the callee side, which does not know the service port. Sent and
+// received bytes are from the caller's side.
+type ServiceDependencyEdge struct {
+	Source        string    `json:"source"`
+	Target        string    `json:"target"`
+	Port          int       `json:"port"`
+	Protocol      string    `json:"protocol"`
+	Processes     []string  `json:"processes"`
+	ServerNames   []string  `json:"server_names"`
+	SentBytes     uint64    `json:"sent_bytes"`
+	ReceivedBytes uint64    `json:"received_bytes"`
+	Connections   int64     `json:"connections"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	// Observations are the talkers behind the edge; each one's history is
+	// GET /hosts/:host_id/network/flows/history.
+	Observations []ServiceDependencyObservation `json:"observations"`
+}
+
+// ServiceDependencyObservation identifies a talker of a host.
+type ServiceDependencyObservation struct {
+	HostID     string `json:"host_id"`
+	RemoteIP   string `json:"remote_ip"`
+	RemotePort int    `json:"remote_port"`
+	Protocol   string `json:"protocol"`
+	Direction  string `json:"direction"`
+}
+
+// ServiceDependencyGraph is the "who calls whom" layer of the topology over
+// [Since, Until].
+type ServiceDependencyGraph struct {
+	Since time.Time               `json:"since"`
+	Until time.Time               `json:"until"`
+	Nodes []ServiceDependencyNode `json:"nodes"`
+	Edges []ServiceDependencyEdge `json:"edges"`
+}
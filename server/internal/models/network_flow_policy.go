@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Network flow policy modes.
+const (
+	NetworkFlowPolicyAllow = "allow" // only the listed peers are expected
+	NetworkFlowPolicyDeny  = "deny"  // the listed peers are forbidden
+)
+
+// NetworkFlowPolicy states which peers the hosts in its scope talk to. In
+// allow mode a talker matching none of the rules is a violation ("db hosts
+// only talk to 10.0.0.0/24:5432"); in deny mode a talker matching any rule
+// is ("no container may reach port 25"). Only the talkers of Direction and
+// Subject are checked.
+type NetworkFlowPolicy struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Enabled     bool                    `json:"enabled"`
+	HostIDs     []string                `json:"host_ids"`  // scope, with Tags; both empty = every host
+	Tags        []string                `json:"tags"`      // hosts with any of these tags
+	Direction   string                  `json:"direction"` // outbound | inbound | any
+	Subject     string                  `json:"subject"`   // any | container | host (non-container processes)
+	Mode        string                  `json:"mode"`      // allow | deny
+	Rules       []NetworkFlowPolicyRule `json:"rules"`
+	CreatedBy   string                  `json:"created_by"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+}
+
+// NetworkFlowPolicyRule matches a peer. Empty fields match anything. Port
+// is the remote port and only applies to outbound flows: on an inbound flow
+// the remote port is the client's ephemeral port.
+type NetworkFlowPolicyRule struct {
+	CIDR       string `json:"cidr,omitempty"`        // IP or CIDR
+	Port       int    `json:"port,omitempty"`        // 1-65535
+	Protocol   string `json:"protocol,omitempty"`    // tcp | udp
+	ServerName string `json:"server_name,omitempty"` // SNI or resolved name from the L7 capture, "*.example.com" allowed
+}
+
+// NetworkFlowPolicyInput is the create/update payload. Omitted fields take
+// their defaults on create (outbound, any subject) and keep their value on
+// update; a list, when present, replaces the stored one.
+type NetworkFlowPolicyInput struct {
+	Name        string                  `json:"name"`
+	Description *string                 `json:"description"`
+	Enabled     *bool                   `json:"enabled"`
+	HostIDs     []string                `json:"host_ids"`
+	Tags        []string                `json:"tags"`
+	Direction   *string                 `json:"direction"`
+	Subject     *string                 `json:"subject"`
+	Mode        *string                 `json:"mode"`
+	Rules       []NetworkFlowPolicyRule `json:"rules"`
+}
+
+// NetworkFlowViolation is one offending flow of a host under a policy,
+// refreshed on every report it is seen in. Connections and bytes add up the
+// report cycles; Container is the container name when ProcessName carries
+// the agent's "conteneur: <name>" attribution.
+type NetworkFlowViolation struct {
+	ID          int64     `json:"id"`
+	PolicyID    string    `json:"policy_id"`
+	PolicyName  string    `json:"policy_name"`
+	Mode        string    `json:"mode"`
+	HostID      string    `json:"host_id"`
+	HostName    string    `json:"host_name"`
+	RemoteIP    string    `json:"remote_ip"`
+	RemotePort  int       `json:"remote_port"` // 0 for inbound flows
+	Protocol    string    `json:"protocol"`
+	Direction   string    `json:"direction"`
+	ProcessName string    `json:"process_name,omitempty"`
+	Container   string    `json:"container,omitempty"`
+	ServerName  string    `json:"server_name,omitempty"`
+	Connections int64     `json:"connections"`
+	RxBytes     uint64    `json:"rx_bytes"`
+	TxBytes     uint64    `json:"tx_bytes"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	// Geo is the offline geolocation of RemoteIP, added on read.
+	Geo *GeoInfo `json:"geo,omitempty"`
+}
@@ -15,11 +15,14 @@ type NetworkFlowsReport struct {
 	// collection disabled in agent.yaml) — never an error, a capability flag.
 	Available bool `json:"available"`
 	// Reason is an actionable message when Available is false.
-	Reason      string              `json:"reason,omitempty"`
-	TopTalkers  []NetworkFlowTalker `json:"top_talkers,omitempty"`
-	Others      *NetworkFlowBucket  `json:"others,omitempty"`
-	TotalFlows  int                 `json:"total_flows"`
-	CollectedAt time.Time           `json:"collected_at"`
+	Reason     string              `json:"reason,omitempty"`
+	TopTalkers []NetworkFlowTalker `json:"top_talkers,omitempty"`
+	Others     *NetworkFlowBucket  `json:"others,omitempty"`
+	TotalFlows int                 `json:"total_flows"`
+	// DNS is what the agent's optional L7 capture saw of the host's DNS
+	// lookups this cycle; nil when the capture is off or saw none.
+	DNS         *DNSQueryStats `json:"dns,omitempty"`
+	CollectedAt time.Time      `json:"collected_at"`
 }
 
 // NetworkFlowTalker is one aggregated remote peer for a report cycle.
@@ -40,8 +43,9 @@ type NetworkFlowTalker struct {
 	// (PID stays 0 — a container PID is meaningless outside its namespace).
 	ProcessName string `json:"process_name,omitempty"`
 	PID         int    `json:"pid,omitempty"`
-	// ServerName is the TLS SNI hostname observed on an outbound flow. Only
-	// ever populated when the agent's optional, off-by-default L7 capture is
+	// ServerName is the TLS SNI hostname observed on an outbound flow, else
+	// the name the host resolved the remote IP from (DNS answer). Only ever
+	// populated when the agent's optional, off-by-default L7 capture is
 	// enabled (network_flows_l7_capture, needs CAP_NET_RAW); empty otherwise,
 	// in which case the frontend falls back to its own well-known-port guess.
 	ServerName  string `json:"server_name,omitempty"`
@@ -78,6 +82,19 @@ type NetworkFlowMetric struct {
 	TxBytes     uint64    `json:"tx_bytes" db:"tx_bytes"`
 	Packets     uint64    `json:"packets" db:"packets"`
 	Connections int       `json:"connections" db:"connections"`
+
+	// Geo is the offline geolocation of RemoteIP (internal/geoip), added on
+	// read — never stored.
+	Geo *GeoInfo `json:"geo,omitempty" db:"-"`
+}
+
+// NetworkFlowPeerTotal is one remote IP's traffic summed over a window, all
+// ports and protocols together — the input of the per-country/ASN summary.
+type NetworkFlowPeerTotal struct {
+	RemoteIP    string `json:"remote_ip"`
+	RxBytes     uint64 `json:"rx_bytes"`
+	TxBytes     uint64 `json:"tx_bytes"`
+	Connections int64  `json:"connections"`
 }
 
 // NetworkFlowSummaryPoint is one time-bucketed point of a host's total tracked
@@ -87,3 +104,46 @@ type NetworkFlowSummaryPoint struct {
 	RxBytes   uint64    `json:"rx_bytes"`
 	TxBytes   uint64    `json:"tx_bytes"`
 }
+
+// DNSQueryStats is the agent-reported summary of the DNS responses its L7
+// capture sampled over one cycle: totals plus the busiest queried names
+// (agent-side top 20). Mirrors agent/internal/collector.DNSQueryStats.
+type DNSQueryStats struct {
+	Responses  int             `json:"responses"`
+	NXDomain   int             `json:"nxdomain"`
+	TopDomains []DNSDomainStat `json:"top_domains,omitempty"`
+}
+
+// DNSDomainStat is one queried name's responses, NXDOMAIN included.
+type DNSDomainStat struct {
+	Name      string `json:"name"`
+	Responses int    `json:"responses"`
+	NXDomain  int    `json:"nxdomain"`
+}
+
+// DNSDomainTotal is one queried name summed over a window.
+type DNSDomainTotal struct {
+	Name      string `json:"name"`
+	Responses int64  `json:"responses"`
+	NXDomain  int64  `json:"nxdomain"`
+}
+
+// DNSQueryPoint is one time-bucketed point of a host's DNS responses.
+type DNSQueryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Responses int64     `json:"responses"`
+	NXDomain  int64     `json:"nxdomain"`
+}
+
+// DNSQueryReport is a host's DNS activity over a window: the most queried
+// names, the names failing most (NXDOMAIN) and the NXDOMAIN rate over time.
+// Counts are samples (the capture window of each cycle), so rates and
+// rankings are meaningful, absolute volumes only relatively.
+type DNSQueryReport struct {
+	Responses    int64            `json:"responses"`
+	NXDomain     int64            `json:"nxdomain"`
+	NXDomainRate float64          `json:"nxdomain_rate"` // percent, 0 without responses
+	TopDomains   []DNSDomainTotal `json:"top_domains"`
+	TopNXDomains []DNSDomainTotal `json:"top_nxdomains"`
+	Points       []DNSQueryPoint  `json:"points"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// PBSConnection stores configuration for one Proxmox Backup Server endpoint.
+// Token secret is never exposed via API.
+type PBSConnection struct {
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	APIURL             string     `json:"api_url"`
+	TokenID            string     `json:"token_id"`
+	InsecureSkipVerify bool       `json:"insecure_skip_verify"`
+	Enabled            bool       `json:"enabled"`
+	PollIntervalSec    int        `json:"poll_interval_sec"`
+	Version            string     `json:"version"`
+	LastError          string     `json:"last_error"`
+	LastErrorAt        *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt      *time.Time `json:"last_success_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	// Computed stats (joined, not stored)
+	DatastoreCount int `json:"datastore_count,omitempty"`
+	GroupCount     int `json:"group_count,omitempty"`
+}
+
+// PBSConnectionRequest is the body for create/update endpoints.
+// TokenSecret is optional on update (empty means "keep existing").
+type PBSConnectionRequest struct {
+	Name               string `json:"name" binding:"required"`
+	APIURL             string `json:"api_url" binding:"required"`
+	TokenID            string `json:"token_id" binding:"required"`
+	TokenSecret        string `json:"token_secret"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	Enabled            bool   `json:"enabled"`
+	PollIntervalSec    int    `json:"poll_interval_sec"`
+}
+
+// PBSDatastore is the usage of one datastore, plus its last GC result.
+type PBSDatastore struct {
+	ID             string     `json:"id"`
+	ConnectionID   string     `json:"connection_id"`
+	ConnectionName string     `json:"connection_name"`
+	Store          string     `json:"store"`
+	Total          int64      `json:"total"`
+	Used           int64      `json:"used"`
+	Avail          int64      `json:"avail"`
+	LastError      string     `json:"last_error,omitempty"`
+	GCState        string     `json:"gc_state"`
+	GCLastRunAt    *time.Time `json:"gc_last_run_at,omitempty"`
+	GroupCount     int        `json:"group_count"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+}
+
+// PBSBackupGroup is one backup group (vm/100, ct/101, host/name) of a datastore.
+// GuestName is resolved from the PVE inventory when the VMID is known.
+type PBSBackupGroup struct {
+	ID                string     `json:"id"`
+	ConnectionID      string     `json:"connection_id"`
+	ConnectionName    string     `json:"connection_name"`
+	Store             string     `json:"store"`
+	BackupType        string     `json:"backup_type"` // vm | ct | host
+	BackupID          string     `json:"backup_id"`
+	GuestName         string     `json:"guest_name,omitempty"`
+	BackupCount       int        `json:"backup_count"`
+	LastBackupAt      *time.Time `json:"last_backup_at,omitempty"`
+	Owner             string     `json:"owner,omitempty"`
+	Comment           string     `json:"comment,omitempty"`
+	LastVerifyState   string     `json:"last_verify_state"` // ok | failed | "" (never verified)
+	LastVerifyAt      *time.Time `json:"last_verify_at,omitempty"`
+	VerifyFailedCount int        `json:"verify_failed_count"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+}
+
+// PBSJob is the status of one GC, prune, verify or sync job.
+type PBSJob struct {
+	ID             string     `json:"id"`
+	ConnectionID   string     `json:"connection_id"`
+	ConnectionName string     `json:"connection_name"`
+	JobType        string     `json:"job_type"` // gc | prune | verify | sync
+	JobID          string     `json:"job_id"`
+	Store          string     `json:"store"`
+	Schedule       string     `json:"schedule"`
+	LastRunState   string     `json:"last_run_state"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	LastRunUPID    string     `json:"last_run_upid,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	Remote         string     `json:"remote,omitempty"`
+	RemoteStore    string     `json:"remote_store,omitempty"`
+	RemovedBytes   int64      `json:"removed_bytes,omitempty"`
+	PendingBytes   int64      `json:"pending_bytes,omitempty"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+}
@@ -85,6 +85,7 @@ type ProxmoxStorage struct {
 	Enabled      bool      `json:"enabled"`
 	Active       bool      `json:"active"`
 	Shared       bool      `json:"shared"`
+	Content      string    `json:"content"` // comma-separated PVE content types
 	LastSeenAt   time.Time `json:"last_seen_at"`
 }
 
@@ -235,3 +236,352 @@ type ProxmoxGuestLinkUpdate struct {
 	Status        *string `json:"status"`
 	MetricsSource *string `json:"metrics_source"`
 }
+
+// ─── Snapshots ────────────────────────────────────────────────────────────────
+
+// ProxmoxSnapshot is one snapshot of a guest, read live from PVE.
+// Automatic marks snapshots created (and pruned) by a snapshot policy.
+type ProxmoxSnapshot struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	SnapTime    *time.Time `json:"snap_time,omitempty"`
+	Parent      string     `json:"parent,omitempty"`
+	VMState     bool       `json:"vmstate"`
+	Automatic   bool       `json:"automatic"`
+}
+
+// ProxmoxSnapshotCreate is the body for POST /proxmox/guests/:id/snapshots.
+// Name defaults to "manual_<timestamp>". Confirm must be true: the UI only
+// sets it after the admin confirmed the action.
+type ProxmoxSnapshotCreate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	VMState     bool   `json:"vmstate"`
+	Confirm     bool   `json:"confirm"`
+}
+
+// ProxmoxSnapshotAction is the body for the rollback and delete routes.
+type ProxmoxSnapshotAction struct {
+	Confirm bool `json:"confirm"`
+}
+
+// ProxmoxSnapshotPolicy snapshots its guests on a cron schedule (server local
+// time) and prunes the snapshots it created, restic-style: a snapshot is kept
+// when any keep_* rule selects it.
+type ProxmoxSnapshotPolicy struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Enabled     bool       `json:"enabled"`
+	Schedule    string     `json:"schedule"` // 5-field cron expression
+	KeepLast    int        `json:"keep_last"`
+	KeepDaily   int        `json:"keep_daily"`
+	KeepWeekly  int        `json:"keep_weekly"`
+	KeepMonthly int        `json:"keep_monthly"`
+	VMState     bool       `json:"vmstate"`
+	GuestIDs    []string   `json:"guest_ids"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastStatus  string     `json:"last_status"` // "" (never ran) | success | partial | failed
+	LastError   string     `json:"last_error,omitempty"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ProxmoxSnapshotPolicyRequest is the body for creating/updating a policy.
+type ProxmoxSnapshotPolicyRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Enabled     *bool    `json:"enabled"` // defaults to true
+	Schedule    string   `json:"schedule" binding:"required"`
+	KeepLast    int      `json:"keep_last"`
+	KeepDaily   int      `json:"keep_daily"`
+	KeepWeekly  int      `json:"keep_weekly"`
+	KeepMonthly int      `json:"keep_monthly"`
+	VMState     bool     `json:"vmstate"`
+	GuestIDs    []string `json:"guest_ids" binding:"required,min=1"`
+}
+
+// ProxmoxSnapshotPolicyRun is the outcome of one policy run on one guest.
+type ProxmoxSnapshotPolicyRun struct {
+	ID           int64      `json:"id"`
+	PolicyID     string     `json:"policy_id"`
+	GuestID      string     `json:"guest_id"`
+	GuestName    string     `json:"guest_name"`
+	VMID         int        `json:"vmid"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+	Status       string     `json:"status"` // running | success | failed
+	SnapshotName string     `json:"snapshot_name"`
+	Pruned       int        `json:"pruned"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// ProxmoxProvision is one clone-from-template request and its progress.
+// Status: pending → cloning → configuring → starting → waiting_agent → linked,
+// or failed.
+type ProxmoxProvision struct {
+	ID                string     `json:"id"`
+	ConnectionID      string     `json:"connection_id"`
+	ConnectionName    string     `json:"connection_name"`
+	NodeName          string     `json:"node_name"`
+	TemplateVMID      int        `json:"template_vmid"`
+	VMID              int        `json:"vmid"`
+	Name              string     `json:"name"`
+	Cores             int        `json:"cores"`
+	MemoryMB          int        `json:"memory_mb"`
+	Disk              string     `json:"disk"`
+	DiskSizeGB        int        `json:"disk_size_gb"`
+	Bridge            string     `json:"bridge"`
+	VLANTag           int        `json:"vlan_tag"`
+	IPConfig          string     `json:"ip_config"` // PVE ipconfig0 value, "" = dhcp
+	SSHAuthorizedKeys string     `json:"ssh_authorized_keys"`
+	HostID            *string    `json:"host_id,omitempty"`
+	GuestID           *string    `json:"guest_id,omitempty"`
+	SeedFetchedAt     *time.Time `json:"seed_fetched_at,omitempty"`
+	Status            string     `json:"status"`
+	Error             string     `json:"error,omitempty"`
+	CreatedBy         string     `json:"created_by"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	LinkedAt          *time.Time `json:"linked_at,omitempty"`
+}
+
+// ProxmoxProvisionRequest is the body of POST /proxmox/provisions.
+type ProxmoxProvisionRequest struct {
+	ConnectionID string `json:"connection_id" binding:"required"`
+	NodeName     string `json:"node_name" binding:"required"`
+	TemplateVMID int    `json:"template_vmid" binding:"required"`
+	VMID         int    `json:"vmid"` // 0 = next free VMID
+	Name         string `json:"name" binding:"required"`
+	FullClone    bool   `json:"full_clone"`
+	Storage      string `json:"storage"` // full clone target, "" = template's
+	Cores        int    `json:"cores"`
+	MemoryMB     int    `json:"memory_mb"`
+	Disk         string `json:"disk"` // disk to grow, defaults to scsi0
+	DiskSizeGB   int    `json:"disk_size_gb"`
+	Bridge       string `json:"bridge"`
+	VLANTag      int    `json:"vlan_tag"`
+	// IPConfig is a PVE ipconfig0 value ("ip=10.0.0.20/24,gw=10.0.0.1");
+	// empty means DHCP.
+	IPConfig          string   `json:"ip_config"`
+	Nameserver        string   `json:"nameserver"`
+	SSHAuthorizedKeys string   `json:"ssh_authorized_keys"`
+	Tags              []string `json:"tags"`
+}
+
+// ProxmoxTemplate is a QEMU template that can be cloned.
+type ProxmoxTemplate struct {
+	NodeName string `json:"node_name"`
+	VMID     int    `json:"vmid"`
+	Name     string `json:"name"`
+}
+
+// ProxmoxClusterHealth is the Ceph / HA / corosync state of one connection,
+// refreshed on every poll. The per-object lists are filled by the detail read.
+type ProxmoxClusterHealth struct {
+	ConnectionID       string `json:"connection_id"`
+	ConnectionName     string `json:"connection_name"`
+	Quorate            bool   `json:"quorate"`
+	ClusterNodes       int    `json:"cluster_nodes"` // 0 = standalone node
+	ClusterNodesOnline int    `json:"cluster_nodes_online"`
+
+	CephAvailable  bool                 `json:"ceph_available"`
+	CephHealth     string               `json:"ceph_health"` // HEALTH_OK | HEALTH_WARN | HEALTH_ERR | HEALTH_UNKNOWN
+	CephChecks     []ProxmoxCephCheck   `json:"ceph_checks"`
+	CephError      string               `json:"ceph_error,omitempty"`
+	OSDsTotal      int                  `json:"osds_total"`
+	OSDsUp         int                  `json:"osds_up"`
+	OSDsIn         int                  `json:"osds_in"`
+	PGsTotal       int                  `json:"pgs_total"`
+	PGsActiveClean int                  `json:"pgs_active_clean"`
+	PGStates       []ProxmoxCephPGState `json:"pg_states"`
+	CephBytesUsed  int64                `json:"ceph_bytes_used"`
+	CephBytesTotal int64                `json:"ceph_bytes_total"`
+	MonsTotal      int                  `json:"mons_total"`
+	MonsInQuorum   int                  `json:"mons_in_quorum"`
+	Mons           []ProxmoxCephMon     `json:"mons"`
+
+	HAEnabled    bool   `json:"ha_enabled"`
+	HAQuorate    bool   `json:"ha_quorate"`
+	HAMasterNode string `json:"ha_master_node"`
+	HAError      string `json:"ha_error,omitempty"`
+
+	OSDs      []ProxmoxCephOSD  `json:"osds,omitempty"`
+	Pools     []ProxmoxCephPool `json:"pools,omitempty"`
+	HAEntries []ProxmoxHAEntry  `json:"ha_entries,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProxmoxCephCheck is one active Ceph health check (OSD_DOWN, PG_DEGRADED…).
+type ProxmoxCephCheck struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Muted    bool   `json:"muted"`
+}
+
+// ProxmoxCephPGState counts the placement groups in one state.
+type ProxmoxCephPGState struct {
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+// ProxmoxCephMon is one Ceph monitor and whether it is part of the quorum.
+type ProxmoxCephMon struct {
+	Name     string `json:"name"`
+	Host     string `json:"host"`
+	InQuorum bool   `json:"in_quorum"`
+}
+
+// ProxmoxCephOSD is one OSD and the node hosting it.
+type ProxmoxCephOSD struct {
+	OSDID       int       `json:"osd_id"`
+	Name        string    `json:"name"`
+	Host        string    `json:"host"`
+	Status      string    `json:"status"` // up | down
+	In          bool      `json:"in"`
+	DeviceClass string    `json:"device_class"`
+	PercentUsed float64   `json:"percent_used"` // -1 if unknown
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// ProxmoxCephPool is one Ceph pool with its usage.
+type ProxmoxCephPool struct {
+	Name        string    `json:"name"`
+	Size        int       `json:"size"`
+	MinSize     int       `json:"min_size"`
+	PGNum       int       `json:"pg_num"`
+	BytesUsed   int64     `json:"bytes_used"`
+	PercentUsed float64   `json:"percent_used"` // 0–100
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// ProxmoxHAEntry is the CRM master, one node's LRM or one HA resource.
+type ProxmoxHAEntry struct {
+	EntryID      string    `json:"entry_id"`
+	EntryType    string    `json:"entry_type"` // master | lrm | service
+	NodeName     string    `json:"node_name"`
+	SID          string    `json:"sid,omitempty"`
+	State        string    `json:"state"`
+	RequestState string    `json:"request_state,omitempty"`
+	Status       string    `json:"status"`
+	Healthy      bool      `json:"healthy"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+}
+
+// ProxmoxStorageVolume is one item of a storage's content (ISO, container
+// template, backup or disk image), read live from PVE.
+type ProxmoxStorageVolume struct {
+	VolID     string     `json:"volid"`
+	Content   string     `json:"content"` // iso | vztmpl | backup | images | rootdir | snippets
+	Format    string     `json:"format"`
+	Size      int64      `json:"size"`
+	Used      int64      `json:"used,omitempty"`
+	VMID      int        `json:"vmid,omitempty"`
+	GuestName string     `json:"guest_name,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+	Protected bool       `json:"protected"`
+	// Orphaned marks a disk image whose VMID no longer exists in the cluster.
+	Orphaned bool `json:"orphaned"`
+}
+
+// ProxmoxStorageDownloadRequest is the body for
+// POST /proxmox/storages/:id/download-url. Filename defaults to the last
+// segment of the URL; Checksum is optional (hex, with its algorithm).
+type ProxmoxStorageDownloadRequest struct {
+	URL               string `json:"url"`
+	Filename          string `json:"filename"`
+	Content           string `json:"content"` // iso | vztmpl
+	Checksum          string `json:"checksum"`
+	ChecksumAlgorithm string `json:"checksum_algorithm"`
+	SkipCertVerify    bool   `json:"skip_cert_verify"`
+	Confirm           bool   `json:"confirm"`
+}
+
+// ProxmoxStorageVolumeDelete is the body for DELETE /proxmox/storages/:id/content.
+type ProxmoxStorageVolumeDelete struct {
+	VolID   string `json:"volid"`
+	Confirm bool   `json:"confirm"`
+}
+
+// ProxmoxConsoleRequest is the body for POST /proxmox/guests/:id/console.
+type ProxmoxConsoleRequest struct {
+	Kind string `json:"kind"` // vnc (noVNC, QEMU only) | term (xterm.js)
+}
+
+// ProxmoxConsoleSession is a console opened on a guest, to be attached through
+// the /ws/proxmox/console/:session websocket before ExpiresAt. Password is the
+// one-time PVE ticket noVNC answers the VNC auth with; it is useless without
+// a PVE session, which only the server holds.
+type ProxmoxConsoleSession struct {
+	SessionID string    `json:"session_id"`
+	Kind      string    `json:"kind"`
+	GuestName string    `json:"guest_name"`
+	Password  string    `json:"password,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ProxmoxPendingConsole is a console session opened on one replica and not
+// attached yet (table proxmox_console_sessions). Ticket is the PVE
+// vncproxy/termproxy answer, JSON-encoded. Not exposed over the API.
+type ProxmoxPendingConsole struct {
+	ID        string
+	Username  string
+	ClientIP  string
+	Kind      string
+	GuestID   string
+	HostID    string
+	Ticket    string
+	ExpiresAt time.Time
+}
+
+// ProxmoxRightsizingRun is one weekly right-sizing analysis.
+type ProxmoxRightsizingRun struct {
+	ID              string     `json:"id"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	Status          string     `json:"status"` // running | success | failed
+	GuestsAnalyzed  int        `json:"guests_analyzed"`
+	NodesAnalyzed   int        `json:"nodes_analyzed"`
+	Recommendations int        `json:"recommendations"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// ProxmoxRightsizingRecommendation is one finding of a right-sizing run: a
+// guest to resize or migrate, or a node whose failure the rest of its
+// cluster could not absorb. Suggested sizes are advice (applied in PVE);
+// a TargetNode can be applied with the guest migration endpoint, from
+// NodeID (the guest's current node).
+type ProxmoxRightsizingRecommendation struct {
+	ID             string  `json:"id"`
+	ConnectionID   string  `json:"connection_id"`
+	ConnectionName string  `json:"connection_name"`
+	GuestID        string  `json:"guest_id,omitempty"`
+	GuestName      string  `json:"guest_name,omitempty"`
+	GuestType      string  `json:"guest_type,omitempty"` // vm | lxc
+	VMID           int     `json:"vmid,omitempty"`
+	NodeName       string  `json:"node_name"`
+	NodeID         string  `json:"node_id,omitempty"`
+	Kind           string  `json:"kind"`     // overprovisioned_memory | underprovisioned_memory | underprovisioned_cpu | cpu_contention | node_failover | rebalance
+	Severity       string  `json:"severity"` // info | warning | critical
+	CurrentCPUs    float64 `json:"current_cpus"`
+	CurrentMem     int64   `json:"current_mem"`
+	SuggestedCPUs  float64 `json:"suggested_cpus"`
+	SuggestedMem   int64   `json:"suggested_mem"`
+	TargetNode     string  `json:"target_node,omitempty"`
+	Reason         string  `json:"reason"`
+	// Observed over the analysis window: p95 of RAM and CPU as a share of
+	// the allocation (0-1), share of samples with the balloon inflated
+	// (0-1), average CPU pressure (%).
+	MemP95       float64 `json:"mem_p95"`
+	CPUP95       float64 `json:"cpu_p95"`
+	BalloonRatio float64 `json:"balloon_ratio"`
+	CPUPressure  float64 `json:"cpu_pressure"`
+}
+
+// ProxmoxRightsizingReport is the latest run with its recommendations.
+type ProxmoxRightsizingReport struct {
+	Run             *ProxmoxRightsizingRun             `json:"run"`
+	Recommendations []ProxmoxRightsizingRecommendation `json:"recommendations"`
+}
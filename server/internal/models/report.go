@@ -17,6 +17,9 @@ type AgentCapabilities struct {
 	// NetworkFlows only reflects whether the collector ran, not whether the
 	// kernel could actually provide byte counters — see NetworkFlowsReport.Available.
 	NetworkFlows bool `json:"network_flows"`
+	// CrowdSec is set when the agent has CrowdSec machine credentials, i.e.
+	// can apply crowdsec ban/unban commands.
+	CrowdSec bool `json:"crowdsec"`
 }
 
 // DiagnosticIssue mirrors agent/internal/collector.DiagnosticIssue — one
@@ -67,6 +70,7 @@ type AgentReport struct {
 	ComposeProjects []ComposeProject    `json:"compose_projects,omitempty"`
 	DiskMetrics     []DiskMetrics       `json:"disk_metrics,omitempty"`
 	DiskHealth      []DiskHealth        `json:"disk_health,omitempty"`
+	NetInterfaces   []NetInterface      `json:"net_interfaces,omitempty"`
 	CustomTasks     []CustomTaskSummary `json:"custom_tasks,omitempty"`
 	TasksConfigYAML string              `json:"tasks_config_yaml,omitempty"`
 	Restic          *ResticStatus       `json:"restic,omitempty"`
@@ -74,4 +78,34 @@ type AgentReport struct {
 	ResticGroups    []string            `json:"restic_groups,omitempty"`
 	NetworkFlows    *NetworkFlowsReport `json:"network_flows,omitempty"`
 	Timestamp       time.Time           `json:"timestamp"`
+
+	// DockerDiskUsage is only present on the cycles where the agent re-measured
+	// (see agent collector.CollectDockerDiskUsage) — each one is a history point.
+	DockerDiskUsage *DockerDiskUsageReport `json:"docker_disk_usage,omitempty"`
+	// DockerSwarm is present whenever the agent could read the engine's
+	// /info; Manager false means "drop this host's Swarm state".
+	DockerSwarm *DockerSwarmReport `json:"docker_swarm,omitempty"`
+	// CrowdSec is only present on the cycles where the agent queried its
+	// local API (see agent collector.CollectCrowdSec).
+	CrowdSec *CrowdSecReport `json:"crowdsec,omitempty"`
+}
+
+// BackfillSample is the compact, metrics-only form of a report the agent could
+// not deliver, replayed later with its original collection time.
+type BackfillSample struct {
+	Timestamp   time.Time      `json:"timestamp"`
+	Metrics     *SystemMetrics `json:"metrics"`
+	DiskMetrics []DiskMetrics  `json:"disk_metrics,omitempty"`
+}
+
+// BackfillRequest is the body of POST /api/agent/backfill, oldest sample first.
+type BackfillRequest struct {
+	Samples []BackfillSample `json:"samples"`
+}
+
+// BackfillResult tells the agent how many samples were stored; skipped ones
+// (already stored, too old, or not older than the live data) are dropped too.
+type BackfillResult struct {
+	Accepted int `json:"accepted"`
+	Skipped  int `json:"skipped"`
 }
@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+// Threat rule severities, highest first. When several enabled rules match a
+// request, the most severe one classifies it.
+const (
+	ThreatSeverityCritical = "critical"
+	ThreatSeverityHigh     = "high"
+	ThreatSeverityMedium   = "medium"
+	ThreatSeverityLow      = "low"
+)
+
+// Threat rule sources: the signatures shipped with the server, the ones an
+// admin wrote, and otherwise the name of the imported rule pack.
+const (
+	ThreatRuleSourceBuiltin = "builtin"
+	ThreatRuleSourceCustom  = "custom"
+)
+
+// ThreatRule is a web-request signature used by internal/threatdetect. Every
+// non-empty pattern is a Go regexp that must match (AND); Query is matched
+// without the leading "?", Status against the decimal code.
+type ThreatRule struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Description      string    `json:"description"`
+	Category         string    `json:"category"`
+	Severity         string    `json:"severity"`
+	MethodPattern    string    `json:"method_pattern"`
+	PathPattern      string    `json:"path_pattern"`
+	QueryPattern     string    `json:"query_pattern"`
+	UserAgentPattern string    `json:"user_agent_pattern"`
+	StatusPattern    string    `json:"status_pattern"`
+	Enabled          bool      `json:"enabled"`
+	Source           string    `json:"source"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Hit counters, computed from the stored web_log_requests.
+	Hits24h   int64      `json:"hits_24h"`
+	Hits7d    int64      `json:"hits_7d"`
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+}
+
+// ThreatRuleHits are the hit counters of one rule.
+type ThreatRuleHits struct {
+	Hits24h   int64
+	Hits7d    int64
+	LastHitAt *time.Time
+}
+
+// ThreatRuleInput is the create/update payload. On update, a built-in rule
+// only takes Enabled into account.
+type ThreatRuleInput struct {
+	Name             string `json:"name" binding:"required"`
+	Description      string `json:"description"`
+	Category         string `json:"category" binding:"required"`
+	Severity         string `json:"severity" binding:"required"`
+	MethodPattern    string `json:"method_pattern"`
+	PathPattern      string `json:"path_pattern"`
+	QueryPattern     string `json:"query_pattern"`
+	UserAgentPattern string `json:"user_agent_pattern"`
+	StatusPattern    string `json:"status_pattern"`
+	Enabled          *bool  `json:"enabled"`
+}
+
+// ThreatRuleSample is a request to try a rule against.
+type ThreatRuleSample struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Query     string `json:"query"`
+	UserAgent string `json:"user_agent"`
+	Status    int    `json:"status"`
+}
+
+// ThreatRuleTestRequest tries a draft rule against samples and against the
+// requests stored over the last Hours (default 24; negative skips the
+// replay).
+type ThreatRuleTestRequest struct {
+	Rule    ThreatRuleInput    `json:"rule"`
+	Samples []ThreatRuleSample `json:"samples"`
+	Hours   int                `json:"hours"`
+}
+
+// ThreatRuleTestResult reports what a draft rule matches. Scanned/Matched
+// cover the replay over stored requests (the most recent ones, capped), with
+// a few matching examples to spot false positives.
+type ThreatRuleTestResult struct {
+	SampleMatches []bool             `json:"sample_matches"`
+	Scanned       int                `json:"scanned"`
+	Matched       int                `json:"matched"`
+	Examples      []ThreatRuleSample `json:"examples"`
+}
+
+// ThreatRulePackRule is one signature of an imported rule pack; ID is unique
+// within the pack.
+type ThreatRulePackRule struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	Category         string `json:"category"`
+	Severity         string `json:"severity"`
+	MethodPattern    string `json:"method_pattern"`
+	PathPattern      string `json:"path_pattern"`
+	QueryPattern     string `json:"query_pattern"`
+	UserAgentPattern string `json:"user_agent_pattern"`
+	StatusPattern    string `json:"status_pattern"`
+}
+
+// ThreatRulePack is an importable set of signatures. Importing the same pack
+// again updates its rules and keeps their enabled state.
+type ThreatRulePack struct {
+	Name  string               `json:"name"`
+	Rules []ThreatRulePackRule `json:"rules"`
+}
+
+// ThreatRuleImportRequest imports either a pack shipped with the server
+// (Bundled, e.g. "crs-lite") or the given Pack.
+type ThreatRuleImportRequest struct {
+	Bundled string          `json:"bundled"`
+	Pack    *ThreatRulePack `json:"pack"`
+}
+
+// ThreatRuleImportResult counts the rules an import added and updated.
+type ThreatRuleImportResult struct {
+	Pack    string `json:"pack"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+}
@@ -20,6 +20,19 @@ type WebRequest struct {
 	BlockedReason string     `json:"blocked_reason,omitempty"`
 	BlockedAt     *time.Time `json:"blocked_at,omitempty"`
 	BlockedUntil  *time.Time `json:"blocked_until,omitempty"`
+
+	// Timing and upstream fields, absent when the agent's log format does not
+	// carry them. Times are in milliseconds; 0 is a timed response faster
+	// than the log's resolution.
+	ResponseTimeMs *float64 `json:"response_time_ms,omitempty"`
+	UpstreamTimeMs *float64 `json:"upstream_time_ms,omitempty"`
+	UpstreamStatus int      `json:"upstream_status,omitempty"`
+	Upstream       string   `json:"upstream,omitempty"`
+
+	// Query is the raw query string, without the leading "?". RuleID is the
+	// threat rule that set Category, filled in server-side with it.
+	Query  string `json:"query,omitempty"`
+	RuleID string `json:"rule_id,omitempty"`
 }
 
 type NPMPathHit struct {
@@ -93,4 +106,36 @@ type WebLogIPTimelineRow struct {
 	BlockedReason string     `json:"blocked_reason,omitempty"`
 	BlockedAt     *time.Time `json:"blocked_at,omitempty"`
 	BlockedUntil  *time.Time `json:"blocked_until,omitempty"`
+
+	// Nil when the log format does not carry them (see WebRequest).
+	ResponseTimeMs *float64 `json:"response_time_ms,omitempty"`
+	UpstreamStatus *int     `json:"upstream_status,omitempty"`
+	Upstream       string   `json:"upstream,omitempty"`
+}
+
+// WebPerformanceStat is the latency and error profile of a domain, or of one
+// normalized route of a domain (Route empty on domain rows). Percentiles only
+// cover the requests whose log format carries a response time: they are 0
+// when TimedRequests is 0.
+type WebPerformanceStat struct {
+	Domain        string  `json:"domain"`
+	Route         string  `json:"route,omitempty"`
+	Requests      int64   `json:"requests"`
+	TimedRequests int64   `json:"timed_requests"`
+	Errors4xx     int64   `json:"errors_4xx"`
+	Errors5xx     int64   `json:"errors_5xx"`
+	ErrorRate     float64 `json:"error_rate"` // share of 5xx, in percent
+	P50Ms         float64 `json:"p50_ms"`
+	P95Ms         float64 `json:"p95_ms"`
+	P99Ms         float64 `json:"p99_ms"`
+}
+
+// WebPerformanceReport is the performance view of the access logs: every
+// domain, the busiest routes, and the routes ranked by error rate and by p95
+// latency.
+type WebPerformanceReport struct {
+	Domains       []WebPerformanceStat `json:"domains"`
+	Routes        []WebPerformanceStat `json:"routes"`
+	ErrorRoutes   []WebPerformanceStat `json:"error_routes"`
+	SlowestRoutes []WebPerformanceStat `json:"slowest_routes"`
 }
@@ -170,6 +170,13 @@ type WSBackupRunMessage struct {
 	Notification WSBackupNotification `json:"notification"`
 }
 
+// WSIPBlockMessage is pushed when an IP blocking policy makes a decision,
+// dry runs included (type "ip_block").
+type WSIPBlockMessage struct {
+	Type         string          `json:"type"`
+	Notification IPBlockDecision `json:"notification"`
+}
+
 // WSReleaseTrackerNotification is the nested payload of release-tracker messages.
 // version/release_url/release_name/label are populated only for
 // "release_tracker_detected".
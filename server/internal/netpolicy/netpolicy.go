@@ -0,0 +1,202 @@
+// Package netpolicy checks the talkers of a network flow report (see
+// models.NetworkFlowsReport) against the admin-defined network flow
+// policies: which peers a host, or every host with a tag, may or may not
+// talk to. It is pure matching — storing the violations and alerting on them
+// is the caller's job (internal/services/agent and internal/alerts).
+package netpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// containerLabelPrefix is the agent's process attribution for a talker of a
+// Docker container (agent/internal/collector/network_flows.go).
+const containerLabelPrefix = "conteneur: "
+
+// ContainerName returns the container of a talker's process attribution, or
+// "" for a host process.
+func ContainerName(processName string) string {
+	name, ok := strings.CutPrefix(processName, containerLabelPrefix)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(name)
+}
+
+// NormalizeRule validates r and puts it in canonical form (masked CIDR,
+// lower-case protocol and server name).
+func NormalizeRule(r *models.NetworkFlowPolicyRule) error {
+	r.CIDR = strings.TrimSpace(r.CIDR)
+	if r.CIDR != "" {
+		prefix, err := parsePrefix(r.CIDR)
+		if err != nil {
+			return fmt.Errorf("%q n'est ni une IP ni un CIDR", r.CIDR)
+		}
+		r.CIDR = prefix.String()
+	}
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("port %d invalide", r.Port)
+	}
+	r.Protocol = strings.ToLower(strings.TrimSpace(r.Protocol))
+	if r.Protocol != "" && r.Protocol != "tcp" && r.Protocol != "udp" {
+		return errors.New("protocol : tcp ou udp")
+	}
+	r.ServerName = strings.ToLower(strings.TrimSpace(r.ServerName))
+	if strings.Contains(strings.TrimPrefix(r.ServerName, "*."), "*") {
+		return fmt.Errorf("server_name %q : seul un préfixe « *. » est accepté", r.ServerName)
+	}
+	if r.CIDR == "" && r.Port == 0 && r.Protocol == "" && r.ServerName == "" {
+		return errors.New("une règle doit préciser au moins cidr, port, protocol ou server_name")
+	}
+	return nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Applies reports whether host is in the policy's scope.
+func Applies(p models.NetworkFlowPolicy, host models.Host) bool {
+	if len(p.HostIDs) == 0 && len(p.Tags) == 0 {
+		return true
+	}
+	for _, id := range p.HostIDs {
+		if id == host.ID {
+			return true
+		}
+	}
+	for _, tag := range p.Tags {
+		for _, ht := range host.Tags {
+			if strings.EqualFold(tag, ht) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checked reports whether the policy looks at talker at all (direction and
+// subject).
+func checked(p models.NetworkFlowPolicy, t models.NetworkFlowTalker) bool {
+	if p.Direction != "" && p.Direction != "any" && p.Direction != t.Direction {
+		return false
+	}
+	container := ContainerName(t.ProcessName) != ""
+	switch p.Subject {
+	case "container":
+		return container
+	case "host":
+		return !container
+	}
+	return true
+}
+
+// Matches reports whether talker matches rule. A rule whose CIDR no longer
+// parses never matches.
+func Matches(r models.NetworkFlowPolicyRule, t models.NetworkFlowTalker) bool {
+	if r.CIDR != "" {
+		prefix, err := parsePrefix(r.CIDR)
+		if err != nil {
+			return false
+		}
+		addr, err := netip.ParseAddr(t.RemoteIP)
+		if err != nil || !prefix.Contains(addr.Unmap()) {
+			return false
+		}
+	}
+	if r.Port != 0 && t.Direction == "outbound" && r.Port != t.RemotePort {
+		return false
+	}
+	if r.Protocol != "" && !strings.EqualFold(r.Protocol, t.Protocol) {
+		return false
+	}
+	if r.ServerName != "" && !serverNameMatches(r.ServerName, t.ServerName) {
+		return false
+	}
+	return true
+}
+
+// serverNameMatches compares a flow's server name (SNI, else the name its
+// IP was resolved from) with a pattern, "*.example.com" matching any
+// subdomain of example.com. An unnamed flow (L7 capture off, or neither TLS
+// nor a captured lookup) matches no pattern.
+func serverNameMatches(pattern, sni string) bool {
+	sni = strings.ToLower(strings.TrimSuffix(sni, "."))
+	if sni == "" {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(sni, suffix)
+	}
+	return sni == pattern
+}
+
+// Violates reports whether talker breaks the policy.
+func Violates(p models.NetworkFlowPolicy, t models.NetworkFlowTalker) bool {
+	if !checked(p, t) {
+		return false
+	}
+	matched := false
+	for _, r := range p.Rules {
+		if Matches(r, t) {
+			matched = true
+			break
+		}
+	}
+	if p.Mode == models.NetworkFlowPolicyDeny {
+		return matched
+	}
+	return !matched
+}
+
+// Check returns the violations of host's talkers under the enabled policies
+// that apply to it.
+func Check(policies []models.NetworkFlowPolicy, host models.Host, talkers []models.NetworkFlowTalker) []models.NetworkFlowViolation {
+	var out []models.NetworkFlowViolation
+	for _, p := range policies {
+		if !p.Enabled || !Applies(p, host) {
+			continue
+		}
+		for _, t := range talkers {
+			if !Violates(p, t) {
+				continue
+			}
+			port := t.RemotePort
+			if t.Direction != "outbound" {
+				port = 0
+			}
+			out = append(out, models.NetworkFlowViolation{
+				PolicyID:    p.ID,
+				PolicyName:  p.Name,
+				Mode:        p.Mode,
+				HostID:      host.ID,
+				HostName:    host.Name,
+				RemoteIP:    t.RemoteIP,
+				RemotePort:  port,
+				Protocol:    t.Protocol,
+				Direction:   t.Direction,
+				ProcessName: t.ProcessName,
+				Container:   ContainerName(t.ProcessName),
+				ServerName:  t.ServerName,
+				Connections: int64(t.Connections),
+				RxBytes:     t.RxBytes,
+				TxBytes:     t.TxBytes,
+			})
+		}
+	}
+	return out
+}
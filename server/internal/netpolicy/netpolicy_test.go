@@ -0,0 +1,117 @@
+package netpolicy
+
+import (
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+func TestCheck_AllowPolicyFlagsUnlistedPeers(t *testing.T) {
+	p := models.NetworkFlowPolicy{
+		ID: "p1", Name: "db", Enabled: true, Tags: []string{"db"},
+		Direction: "outbound", Subject: "any", Mode: models.NetworkFlowPolicyAllow,
+		Rules: []models.NetworkFlowPolicyRule{{CIDR: "10.0.0.0/24", Port: 5432, Protocol: "tcp"}},
+	}
+	host := models.Host{ID: "h1", Name: "db-1", Tags: []string{"DB"}}
+	talkers := []models.NetworkFlowTalker{
+		{RemoteIP: "10.0.0.12", RemotePort: 5432, Protocol: "tcp", Direction: "outbound", ProcessName: "postgres"},
+		{RemoteIP: "10.0.0.12", RemotePort: 22, Protocol: "tcp", Direction: "outbound", ProcessName: "ssh"},
+		{RemoteIP: "203.0.113.9", RemotePort: 5432, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: backup"},
+		{RemoteIP: "198.51.100.3", RemotePort: 51234, Protocol: "tcp", Direction: "inbound", ProcessName: "postgres"},
+	}
+	got := Check([]models.NetworkFlowPolicy{p}, host, talkers)
+	if len(got) != 2 {
+		t.Fatalf("got %d violations, want 2: %+v", len(got), got)
+	}
+	if got[0].RemotePort != 22 || got[0].ProcessName != "ssh" || got[0].Container != "" {
+		t.Errorf("first violation = %+v", got[0])
+	}
+	if got[1].Container != "backup" || got[1].PolicyName != "db" || got[1].HostName != "db-1" {
+		t.Errorf("second violation = %+v", got[1])
+	}
+
+	if v := Check([]models.NetworkFlowPolicy{p}, models.Host{ID: "h2", Tags: []string{"web"}}, talkers); len(v) != 0 {
+		t.Errorf("a host outside the scope got %d violations", len(v))
+	}
+	p.Enabled = false
+	if v := Check([]models.NetworkFlowPolicy{p}, host, talkers); len(v) != 0 {
+		t.Errorf("a disabled policy raised %d violations", len(v))
+	}
+}
+
+func TestCheck_DenyPolicyOnContainers(t *testing.T) {
+	p := models.NetworkFlowPolicy{
+		ID: "p2", Name: "no smtp", Enabled: true,
+		Direction: "outbound", Subject: "container", Mode: models.NetworkFlowPolicyDeny,
+		Rules: []models.NetworkFlowPolicyRule{{Port: 25}},
+	}
+	talkers := []models.NetworkFlowTalker{
+		{RemoteIP: "192.0.2.1", RemotePort: 25, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app"},
+		{RemoteIP: "192.0.2.1", RemotePort: 25, Protocol: "tcp", Direction: "outbound", ProcessName: "postfix"},
+		{RemoteIP: "192.0.2.1", RemotePort: 443, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app"},
+	}
+	got := Check([]models.NetworkFlowPolicy{p}, models.Host{ID: "h1"}, talkers)
+	if len(got) != 1 || got[0].Container != "app" || got[0].RemotePort != 25 {
+		t.Fatalf("violations = %+v", got)
+	}
+}
+
+func TestCheck_InboundIgnoresRemotePort(t *testing.T) {
+	p := models.NetworkFlowPolicy{
+		ID: "p3", Enabled: true, HostIDs: []string{"h1"},
+		Direction: "inbound", Mode: models.NetworkFlowPolicyAllow,
+		Rules: []models.NetworkFlowPolicyRule{{CIDR: "10.0.0.0/8", Port: 5432}},
+	}
+	talkers := []models.NetworkFlowTalker{
+		{RemoteIP: "10.1.2.3", RemotePort: 50000, Protocol: "tcp", Direction: "inbound"},
+		{RemoteIP: "203.0.113.7", RemotePort: 50001, Protocol: "tcp", Direction: "inbound"},
+	}
+	got := Check([]models.NetworkFlowPolicy{p}, models.Host{ID: "h1"}, talkers)
+	if len(got) != 1 || got[0].RemoteIP != "203.0.113.7" || got[0].RemotePort != 0 {
+		t.Fatalf("violations = %+v", got)
+	}
+}
+
+func TestMatches_ServerName(t *testing.T) {
+	r := models.NetworkFlowPolicyRule{ServerName: "*.example.com"}
+	cases := map[string]bool{
+		"api.example.com":   true,
+		"a.b.example.com":   true,
+		"API.Example.com.":  true,
+		"example.com":       false,
+		"badexample.com":    false,
+		"":                  false,
+		"api.example.com.x": false,
+	}
+	for sni, want := range cases {
+		tk := models.NetworkFlowTalker{RemoteIP: "192.0.2.1", Direction: "outbound", ServerName: sni}
+		if got := Matches(r, tk); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", sni, got, want)
+		}
+	}
+}
+
+func TestNormalizeRule(t *testing.T) {
+	r := models.NetworkFlowPolicyRule{CIDR: " 10.0.0.7/24 ", Protocol: "TCP", ServerName: "*.Example.com"}
+	if err := NormalizeRule(&r); err != nil {
+		t.Fatal(err)
+	}
+	if r.CIDR != "10.0.0.0/24" || r.Protocol != "tcp" || r.ServerName != "*.example.com" {
+		t.Errorf("normalized = %+v", r)
+	}
+	single := models.NetworkFlowPolicyRule{CIDR: "2001:db8::1"}
+	if err := NormalizeRule(&single); err != nil || single.CIDR != "2001:db8::1/128" {
+		t.Errorf("single IP = %+v, %v", single, err)
+	}
+	for _, bad := range []models.NetworkFlowPolicyRule{
+		{},
+		{CIDR: "10.0.0.300"},
+		{Port: 70000},
+		{Protocol: "icmp"},
+		{ServerName: "api.*.example.com"},
+	} {
+		if err := NormalizeRule(&bad); err == nil {
+			t.Errorf("NormalizeRule(%+v) accepted", bad)
+		}
+	}
+}
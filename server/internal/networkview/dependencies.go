@@ -0,0 +1,284 @@
+package networkview
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/netpolicy"
+)
+
+// maxDependencyObservations bounds the talkers the dependency map folds
+// (busiest first), whatever the window.
+const maxDependencyObservations = 5000
+
+// DependencyInventory is what the dependency map resolves flow IPs against.
+type DependencyInventory struct {
+	Hosts         []models.Host
+	Containers    []models.DockerContainer
+	ProxmoxGuests []models.NetworkProxmoxGuestIP
+}
+
+// BuildDependencies correlates the network flows every agented host reported
+// over [since, until] into the service dependency graph. Proxmox guests are
+// resolved from their live-fetched IPs when proxmoxSvc is set; a Proxmox
+// failure only drops them from the resolution, it does not fail the map.
+func BuildDependencies(ctx context.Context, db *database.DB, proxmoxSvc GuestNetworksProvider, since, until time.Time) (*models.ServiceDependencyGraph, error) {
+	observations, err := db.ListNetworkFlowObservations(ctx, since, until, maxDependencyObservations)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := db.GetAllHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := db.GetAllDockerContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inv := DependencyInventory{Hosts: hosts, Containers: containers}
+	if proxmoxSvc != nil {
+		hostNames := make(map[string]string, len(hosts))
+		for _, h := range hosts {
+			hostNames[h.ID] = displayHostName(h.Name, h.Hostname)
+		}
+		guests, err := listProxmoxGuestIPs(ctx, db, proxmoxSvc, hostNames)
+		if err != nil {
+			slog.WarnContext(ctx, "dependency map: Proxmox guests not resolved", slog.Any("err", err))
+		} else {
+			inv.ProxmoxGuests = guests
+		}
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+	return BuildDependencyGraph(inv, observations, since, until), nil
+}
+
+// BuildDependencyGraph turns per-host observations into caller → callee
+// edges between resolved nodes:
+//
+//   - an outbound talker is "local process → remote IP:port";
+//   - an inbound talker is "remote IP → local process" with an unknown port,
+//     kept only when the caller does not report flows itself (its outbound
+//     side already describes the call, with the port).
+//
+// A talker attributed to "conteneur: <name>" is that container; a remote IP
+// resolves, in order, to a container of the observing host, an agented host
+// (or the container publishing the port on it), a container IP unique across
+// hosts, a Proxmox guest (its host when linked), else an external node.
+func BuildDependencyGraph(inv DependencyInventory, observations []models.NetworkFlowObservation, since, until time.Time) *models.ServiceDependencyGraph {
+	r := newDependencyResolver(inv)
+	reporters := make(map[string]bool)
+	for _, o := range observations {
+		reporters[o.HostID] = true
+	}
+
+	edges := make(map[string]*models.ServiceDependencyEdge)
+	var order []string
+	for _, o := range observations {
+		if o.RemoteIP == "" {
+			continue
+		}
+		local := r.local(o.HostID, o.ProcessName)
+		outbound := o.Direction != "inbound"
+		remote, owner := r.remote(o.HostID, o.RemoteIP, o.RemotePort, o.Protocol, outbound)
+
+		source, target, port := local, remote, o.RemotePort
+		sent, received := o.TxBytes, o.RxBytes
+		if !outbound {
+			if owner != "" && reporters[owner] {
+				continue
+			}
+			source, target, port = remote, local, 0
+			sent, received = o.RxBytes, o.TxBytes
+		}
+		if source.ID == target.ID {
+			continue
+		}
+		r.keep(source)
+		r.keep(target)
+
+		key := source.ID + "|" + target.ID + "|" + o.Protocol + "|" + strconv.Itoa(port)
+		e, ok := edges[key]
+		if !ok {
+			e = &models.ServiceDependencyEdge{
+				Source: source.ID, Target: target.ID, Port: port, Protocol: o.Protocol,
+				Processes: []string{}, ServerNames: []string{},
+				FirstSeen: o.FirstSeen, LastSeen: o.LastSeen,
+			}
+			edges[key] = e
+			order = append(order, key)
+		}
+		e.SentBytes += sent
+		e.ReceivedBytes += received
+		e.Connections += o.Connections
+		if o.FirstSeen.Before(e.FirstSeen) {
+			e.FirstSeen = o.FirstSeen
+		}
+		if o.LastSeen.After(e.LastSeen) {
+			e.LastSeen = o.LastSeen
+		}
+		e.Processes = appendUnique(e.Processes, o.ProcessName)
+		e.ServerNames = appendUnique(e.ServerNames, o.ServerName)
+		e.Observations = append(e.Observations, models.ServiceDependencyObservation{
+			HostID: o.HostID, RemoteIP: o.RemoteIP, RemotePort: o.RemotePort, Protocol: o.Protocol, Direction: o.Direction,
+		})
+	}
+
+	graph := &models.ServiceDependencyGraph{
+		Since: since,
+		Until: until,
+		Nodes: make([]models.ServiceDependencyNode, 0, len(r.kept)),
+		Edges: make([]models.ServiceDependencyEdge, 0, len(order)),
+	}
+	for _, key := range order {
+		graph.Edges = append(graph.Edges, *edges[key])
+	}
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		return graph.Edges[i].SentBytes+graph.Edges[i].ReceivedBytes > graph.Edges[j].SentBytes+graph.Edges[j].ReceivedBytes
+	})
+	for _, n := range r.kept {
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	return graph
+}
+
+type dependencyResolver struct {
+	hostByID          map[string]*models.Host
+	hostByIP          map[string]*models.Host
+	containersByHost  map[string][]models.DockerContainer
+	containerByHostIP map[string]map[string]*models.DockerContainer
+	containersByIP    map[string][]*models.DockerContainer
+	guestByIP         map[string]*models.NetworkProxmoxGuestIP
+	kept              map[string]models.ServiceDependencyNode
+}
+
+func newDependencyResolver(inv DependencyInventory) *dependencyResolver {
+	r := &dependencyResolver{
+		hostByID:          make(map[string]*models.Host, len(inv.Hosts)),
+		hostByIP:          make(map[string]*models.Host, len(inv.Hosts)),
+		containersByHost:  make(map[string][]models.DockerContainer),
+		containerByHostIP: make(map[string]map[string]*models.DockerContainer),
+		containersByIP:    make(map[string][]*models.DockerContainer),
+		guestByIP:         make(map[string]*models.NetworkProxmoxGuestIP),
+		kept:              make(map[string]models.ServiceDependencyNode),
+	}
+	for i := range inv.Hosts {
+		h := &inv.Hosts[i]
+		r.hostByID[h.ID] = h
+		if h.IPAddress != "" {
+			r.hostByIP[h.IPAddress] = h
+		}
+	}
+	for _, c := range inv.Containers {
+		r.containersByHost[c.HostID] = append(r.containersByHost[c.HostID], c)
+	}
+	for hostID, containers := range r.containersByHost {
+		for i := range containers {
+			c := &containers[i]
+			for _, ip := range c.IPAddresses {
+				if r.containerByHostIP[hostID] == nil {
+					r.containerByHostIP[hostID] = make(map[string]*models.DockerContainer)
+				}
+				r.containerByHostIP[hostID][ip] = c
+				r.containersByIP[ip] = append(r.containersByIP[ip], c)
+			}
+		}
+	}
+	for i := range inv.ProxmoxGuests {
+		g := &inv.ProxmoxGuests[i]
+		for _, ip := range g.IPAddresses {
+			r.guestByIP[ip] = g
+		}
+	}
+	return r
+}
+
+func (r *dependencyResolver) keep(n models.ServiceDependencyNode) {
+	r.kept[n.ID] = n
+}
+
+// local is the observing side of a talker: the container named by the
+// agent's attribution, else the host.
+func (r *dependencyResolver) local(hostID, processName string) models.ServiceDependencyNode {
+	if name := netpolicy.ContainerName(processName); name != "" {
+		return r.containerNode(hostID, name)
+	}
+	return r.hostNode(hostID)
+}
+
+// remote resolves a talker's remote IP, and returns the host owning the
+// resolved node ("" for a guest without agent or an external IP).
+func (r *dependencyResolver) remote(observerID, ip string, port int, protocol string, outbound bool) (models.ServiceDependencyNode, string) {
+	if c, ok := r.containerByHostIP[observerID][ip]; ok {
+		return r.containerNode(c.HostID, c.Name), c.HostID
+	}
+	if h, ok := r.hostByIP[ip]; ok {
+		if outbound && port > 0 {
+			if c := r.publishing(h.ID, port, protocol); c != nil {
+				return r.containerNode(h.ID, c.Name), h.ID
+			}
+		}
+		return r.hostNode(h.ID), h.ID
+	}
+	if cs := r.containersByIP[ip]; len(cs) == 1 {
+		return r.containerNode(cs[0].HostID, cs[0].Name), cs[0].HostID
+	}
+	if g, ok := r.guestByIP[ip]; ok {
+		if _, linked := r.hostByID[g.HostID]; linked {
+			return r.hostNode(g.HostID), g.HostID
+		}
+		return models.ServiceDependencyNode{
+			ID: "proxmox_guest:" + g.GuestID, Type: models.DependencyNodeProxmoxGuest, Name: g.Name, IP: ip,
+		}, ""
+	}
+	return models.ServiceDependencyNode{ID: "ip:" + ip, Type: models.DependencyNodeExternal, Name: ip, IP: ip}, ""
+}
+
+// publishing returns the container of hostID publishing port/protocol on
+// the host, if any.
+func (r *dependencyResolver) publishing(hostID string, port int, protocol string) *models.DockerContainer {
+	containers := r.containersByHost[hostID]
+	for i := range containers {
+		for _, m := range parseDockerPorts(containers[i].Ports) {
+			if m.HostPort == port && strings.EqualFold(m.Protocol, protocol) {
+				return &containers[i]
+			}
+		}
+	}
+	return nil
+}
+
+func (r *dependencyResolver) hostNode(hostID string) models.ServiceDependencyNode {
+	n := models.ServiceDependencyNode{ID: "host:" + hostID, Type: models.DependencyNodeHost, Name: hostID, HostID: hostID}
+	if h, ok := r.hostByID[hostID]; ok {
+		n.Name = displayHostName(h.Name, h.Hostname)
+		n.IP = h.IPAddress
+	}
+	return n
+}
+
+func (r *dependencyResolver) containerNode(hostID, name string) models.ServiceDependencyNode {
+	return models.ServiceDependencyNode{
+		ID: "container:" + hostID + ":" + name, Type: models.DependencyNodeContainer, Name: name, HostID: hostID,
+	}
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
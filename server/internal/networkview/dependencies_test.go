@@ -0,0 +1,103 @@
+package networkview
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+func TestBuildDependencyGraph_ResolvesAndFolds(t *testing.T) {
+	inv := DependencyInventory{
+		Hosts: []models.Host{
+			{ID: "web", Name: "web-1", IPAddress: "10.0.0.10"},
+			{ID: "db", Name: "db-1", IPAddress: "10.0.0.20"},
+			{ID: "legacy", Name: "legacy", IPAddress: "10.0.0.30"},
+		},
+		Containers: []models.DockerContainer{
+			{HostID: "web", Name: "app", IPAddresses: []string{"172.18.0.2"}},
+			{HostID: "web", Name: "redis", IPAddresses: []string{"172.18.0.3"}},
+			{HostID: "db", Name: "postgres", Ports: "0.0.0.0:5432->5432/tcp", IPAddresses: []string{"172.17.0.2"}},
+		},
+		ProxmoxGuests: []models.NetworkProxmoxGuestIP{
+			{GuestID: "g1", Name: "nas", IPAddresses: []string{"10.0.0.50"}},
+		},
+	}
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	obs := []models.NetworkFlowObservation{
+		// app → postgres published on db-1, seen by two talkers of web-1.
+		{HostID: "web", RemoteIP: "10.0.0.20", RemotePort: 5432, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app", TxBytes: 100, RxBytes: 1000, Connections: 2, FirstSeen: t0, LastSeen: t0.Add(time.Minute)},
+		{HostID: "web", RemoteIP: "10.0.0.20", RemotePort: 5432, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app", TxBytes: 50, RxBytes: 500, Connections: 1, FirstSeen: t0.Add(-time.Minute), LastSeen: t0},
+		// app → redis on the same bridge.
+		{HostID: "web", RemoteIP: "172.18.0.3", RemotePort: 6379, Protocol: "tcp", Direction: "outbound", ProcessName: "conteneur: app", TxBytes: 10, RxBytes: 10},
+		// The callee side of app → postgres: db-1 sees web-1 calling in.
+		{HostID: "db", RemoteIP: "10.0.0.10", Protocol: "tcp", Direction: "inbound", ProcessName: "conteneur: postgres", TxBytes: 1500, RxBytes: 150},
+		// legacy does not report flows: only db-1 sees it calling in.
+		{HostID: "db", RemoteIP: "10.0.0.30", Protocol: "tcp", Direction: "inbound", ProcessName: "conteneur: postgres", TxBytes: 40, RxBytes: 4},
+		// Host process to a Proxmox guest without agent, and to the Internet.
+		{HostID: "db", RemoteIP: "10.0.0.50", RemotePort: 2049, Protocol: "tcp", Direction: "outbound", ProcessName: "rsync", TxBytes: 5000},
+		{HostID: "web", RemoteIP: "203.0.113.9", RemotePort: 443, Protocol: "tcp", Direction: "outbound", ProcessName: "curl", ServerName: "api.example.com", TxBytes: 1},
+	}
+
+	g := BuildDependencyGraph(inv, obs, t0.Add(-time.Hour), t0)
+
+	edges := make(map[string]models.ServiceDependencyEdge)
+	for _, e := range g.Edges {
+		edges[e.Source+" > "+e.Target] = e
+	}
+	if len(edges) != 5 {
+		t.Fatalf("edges = %+v", g.Edges)
+	}
+	pg := edges["container:web:app > container:db:postgres"]
+	if pg.Port != 5432 || pg.SentBytes != 150 || pg.ReceivedBytes != 1500 || pg.Connections != 3 ||
+		!pg.FirstSeen.Equal(t0.Add(-time.Minute)) || !pg.LastSeen.Equal(t0.Add(time.Minute)) || len(pg.Observations) != 2 {
+		t.Errorf("app → postgres = %+v", pg)
+	}
+	if e, ok := edges["container:web:app > container:web:redis"]; !ok || e.Port != 6379 {
+		t.Errorf("app → redis = %+v, %v", e, ok)
+	}
+	if e, ok := edges["host:legacy > container:db:postgres"]; !ok || e.Port != 0 || e.SentBytes != 4 || e.ReceivedBytes != 40 {
+		t.Errorf("legacy → postgres = %+v, %v", e, ok)
+	}
+	if _, ok := edges["host:db > proxmox_guest:g1"]; !ok {
+		t.Error("missing db-1 → nas (Proxmox guest)")
+	}
+	if e, ok := edges["host:web > ip:203.0.113.9"]; !ok || len(e.ServerNames) != 1 || e.ServerNames[0] != "api.example.com" {
+		t.Errorf("web-1 → internet = %+v, %v", e, ok)
+	}
+	if _, ok := edges["host:web > container:db:postgres"]; ok {
+		t.Error("the callee-side view of a call its caller reports must not add an edge")
+	}
+	if g.Edges[0].Target != "proxmox_guest:g1" {
+		t.Errorf("edges not sorted by volume: first is %+v", g.Edges[0])
+	}
+
+	names := make(map[string]models.ServiceDependencyNode)
+	for _, n := range g.Nodes {
+		names[n.ID] = n
+	}
+	if n := names["host:web"]; n.Name != "web-1" || n.Type != models.DependencyNodeHost || n.IP != "10.0.0.10" {
+		t.Errorf("host node = %+v", n)
+	}
+	if n := names["container:db:postgres"]; n.Type != models.DependencyNodeContainer || n.HostID != "db" {
+		t.Errorf("container node = %+v", n)
+	}
+	if n := names["ip:203.0.113.9"]; n.Type != models.DependencyNodeExternal {
+		t.Errorf("external node = %+v", n)
+	}
+}
+
+func TestBuildDependencyGraph_LinkedGuestIsItsHost(t *testing.T) {
+	inv := DependencyInventory{
+		Hosts:         []models.Host{{ID: "a", Name: "a", IPAddress: "10.0.0.1"}, {ID: "b", Name: "b", IPAddress: "10.0.0.2"}},
+		ProxmoxGuests: []models.NetworkProxmoxGuestIP{{GuestID: "g", Name: "vm-b", HostID: "b", IPAddresses: []string{"192.168.1.2"}}},
+	}
+	obs := []models.NetworkFlowObservation{
+		{HostID: "a", RemoteIP: "192.168.1.2", RemotePort: 22, Protocol: "tcp", Direction: "outbound"},
+		{HostID: "a", RemoteIP: "10.0.0.1", RemotePort: 80, Protocol: "tcp", Direction: "outbound"},
+	}
+	g := BuildDependencyGraph(inv, obs, time.Time{}, time.Time{})
+	if len(g.Edges) != 1 || g.Edges[0].Source != "host:a" || g.Edges[0].Target != "host:b" {
+		t.Errorf("edges = %+v (self-calls dropped, linked guest resolved to its host)", g.Edges)
+	}
+}
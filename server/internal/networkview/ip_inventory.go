@@ -37,15 +37,71 @@ type ProxyHostLister interface {
 // when possible). Nothing here is cached or written to the database — every
 // call re-fetches guest network interfaces live from Proxmox.
 func BuildIPInventory(ctx context.Context, db *database.DB, proxmoxSvc GuestNetworksProvider, npmSvc ProxyHostLister) (*models.NetworkIPInventory, error) {
-	guests, err := db.ListProxmoxGuests(ctx, "", "", "")
+	hosts, err := db.GetAllHosts(ctx)
 	if err != nil {
 		return nil, err
 	}
-	links, err := db.ListProxmoxGuestLinks(ctx, "confirmed")
+
+	hostIDToName := make(map[string]string, len(hosts))
+	ipToHost := make(map[string]*models.Host, len(hosts))
+	for i := range hosts {
+		h := &hosts[i]
+		hostIDToName[h.ID] = displayHostName(h.Name, h.Hostname)
+		if h.IPAddress != "" {
+			ipToHost[h.IPAddress] = h
+		}
+	}
+
+	proxmoxGuests, err := listProxmoxGuestIPs(ctx, db, proxmoxSvc, hostIDToName)
 	if err != nil {
 		return nil, err
 	}
-	hosts, err := db.GetAllHosts(ctx)
+	ipToGuest := make(map[string]*models.NetworkProxmoxGuestIP)
+	for i := range proxmoxGuests {
+		for _, ip := range proxmoxGuests[i].IPAddresses {
+			ipToGuest[ip] = &proxmoxGuests[i]
+		}
+	}
+
+	npmProxyHosts, err := npmSvc.ListAllProxyHosts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	npmEntries := make([]models.NetworkNPMEntry, 0, len(npmProxyHosts))
+	for _, p := range npmProxyHosts {
+		entry := models.NetworkNPMEntry{
+			ProxyHostID: p.NPMID,
+			DomainNames: p.DomainNames,
+			ForwardHost: p.ForwardHost,
+			ForwardPort: p.ForwardPort,
+		}
+		if h, ok := ipToHost[p.ForwardHost]; ok {
+			entry.MatchedType = "host"
+			entry.MatchedID = h.ID
+			entry.MatchedName = displayHostName(h.Name, h.Hostname)
+		} else if g, ok := ipToGuest[p.ForwardHost]; ok {
+			entry.MatchedType = "proxmox_guest"
+			entry.MatchedID = g.GuestID
+			entry.MatchedName = g.Name
+		}
+		npmEntries = append(npmEntries, entry)
+	}
+
+	return &models.NetworkIPInventory{
+		ProxmoxGuests: proxmoxGuests,
+		NPMHosts:      npmEntries,
+	}, nil
+}
+
+// listProxmoxGuestIPs returns every Proxmox guest with its live-fetched
+// routable IPs and, when a confirmed link exists, the host it runs (named
+// from hostNames).
+func listProxmoxGuestIPs(ctx context.Context, db *database.DB, proxmoxSvc GuestNetworksProvider, hostNames map[string]string) ([]models.NetworkProxmoxGuestIP, error) {
+	guests, err := db.ListProxmoxGuests(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	links, err := db.ListProxmoxGuestLinks(ctx, "confirmed")
 	if err != nil {
 		return nil, err
 	}
@@ -58,16 +114,6 @@ func BuildIPInventory(ctx context.Context, db *database.DB, proxmoxSvc GuestNetw
 		return nil, err
 	}
 
-	hostIDToName := make(map[string]string, len(hosts))
-	ipToHost := make(map[string]*models.Host, len(hosts))
-	for i := range hosts {
-		h := &hosts[i]
-		hostIDToName[h.ID] = displayHostName(h.Name, h.Hostname)
-		if h.IPAddress != "" {
-			ipToHost[h.IPAddress] = h
-		}
-	}
-
 	linkByGuestID := make(map[string]models.ProxmoxGuestLink, len(links))
 	for _, l := range links {
 		linkByGuestID[l.GuestID] = l
@@ -82,7 +128,6 @@ func BuildIPInventory(ctx context.Context, db *database.DB, proxmoxSvc GuestNetw
 	}
 
 	proxmoxGuests := make([]models.NetworkProxmoxGuestIP, 0, len(guests))
-	ipToGuest := make(map[string]*models.NetworkProxmoxGuestIP)
 	for _, g := range guests {
 		entry := models.NetworkProxmoxGuestIP{
 			GuestID:     g.ID,
@@ -95,7 +140,7 @@ func BuildIPInventory(ctx context.Context, db *database.DB, proxmoxSvc GuestNetw
 		}
 		if link, ok := linkByGuestID[g.ID]; ok {
 			entry.HostID = link.HostID
-			entry.HostName = hostIDToName[link.HostID]
+			entry.HostName = hostNames[link.HostID]
 		}
 		guestNodeKey := g.ConnectionID + "|" + g.NodeName
 		for nodeID, nodeNets := range netsByNode {
@@ -109,40 +154,8 @@ func BuildIPInventory(ctx context.Context, db *database.DB, proxmoxSvc GuestNetw
 			entry.IPAddresses = extractRoutableIPs(ifaces)
 		}
 		proxmoxGuests = append(proxmoxGuests, entry)
-		idx := len(proxmoxGuests) - 1
-		for _, ip := range entry.IPAddresses {
-			ipToGuest[ip] = &proxmoxGuests[idx]
-		}
 	}
-
-	npmProxyHosts, err := npmSvc.ListAllProxyHosts(ctx)
-	if err != nil {
-		return nil, err
-	}
-	npmEntries := make([]models.NetworkNPMEntry, 0, len(npmProxyHosts))
-	for _, p := range npmProxyHosts {
-		entry := models.NetworkNPMEntry{
-			ProxyHostID: p.NPMID,
-			DomainNames: p.DomainNames,
-			ForwardHost: p.ForwardHost,
-			ForwardPort: p.ForwardPort,
-		}
-		if h, ok := ipToHost[p.ForwardHost]; ok {
-			entry.MatchedType = "host"
-			entry.MatchedID = h.ID
-			entry.MatchedName = displayHostName(h.Name, h.Hostname)
-		} else if g, ok := ipToGuest[p.ForwardHost]; ok {
-			entry.MatchedType = "proxmox_guest"
-			entry.MatchedID = g.GuestID
-			entry.MatchedName = g.Name
-		}
-		npmEntries = append(npmEntries, entry)
-	}
-
-	return &models.NetworkIPInventory{
-		ProxmoxGuests: proxmoxGuests,
-		NPMHosts:      npmEntries,
-	}, nil
+	return proxmoxGuests, nil
 }
 
 // extractRoutableIPs keeps only the "ethX" interface(s), strips the CIDR
@@ -0,0 +1,178 @@
+// Package pbsclient provides a minimal HTTP client for the Proxmox Backup
+// Server REST API. Authentication is done via API token (PBSAPIToken header);
+// all responses are unwrapped from the {"data": ...} envelope, like
+// proxmoxclient.
+package pbsclient
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to one Proxmox Backup Server instance.
+type Client struct {
+	baseURL     string
+	tokenID     string
+	tokenSecret string
+	httpClient  *http.Client
+}
+
+// New creates a Client. baseURL is the API root, e.g.
+// https://pbs.lan:8007/api2/json.
+// insecureSkipVerify should only be true for self-signed certificates.
+func New(baseURL, tokenID, tokenSecret string, insecureSkipVerify bool) *Client {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify}, //nolint:gosec
+	}
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		tokenID:     tokenID,
+		tokenSecret: tokenSecret,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   20 * time.Second,
+		},
+	}
+}
+
+// get performs a GET request and unmarshals the {"data": ...} envelope into result.
+func (c *Client) get(path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	// PBS separates token ID and secret with ':' (PVE uses '=').
+	req.Header.Set("Authorization", fmt.Sprintf("PBSAPIToken=%s:%s", c.tokenID, c.tokenSecret))
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response from %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		snippet := string(body)
+		if len(snippet) > 300 {
+			snippet = snippet[:300]
+		}
+		return fmt.Errorf("API %s returned HTTP %d: %s", path, resp.StatusCode, snippet)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("parse envelope from %s: %w", path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return fmt.Errorf("parse data from %s: %w", path, err)
+	}
+	return nil
+}
+
+// ─── PBS API response structs ────────────────────────────────────────────────
+
+// Version is returned by GET /version.
+type Version struct {
+	Version string `json:"version"`
+	Release string `json:"release"`
+}
+
+// DatastoreUsage is an element of GET /status/datastore-usage.
+type DatastoreUsage struct {
+	Store string `json:"store"`
+	Total int64  `json:"total"`
+	Used  int64  `json:"used"`
+	Avail int64  `json:"avail"`
+	Error string `json:"error,omitempty"`
+}
+
+// BackupGroup is an element of GET /admin/datastore/{store}/groups.
+type BackupGroup struct {
+	BackupType  string `json:"backup-type"` // vm | ct | host
+	BackupID    string `json:"backup-id"`
+	LastBackup  int64  `json:"last-backup"` // unix seconds
+	BackupCount int    `json:"backup-count"`
+	Owner       string `json:"owner,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// Verification is the verify state attached to a snapshot.
+type Verification struct {
+	State string `json:"state"` // ok | failed
+	UPID  string `json:"upid"`
+}
+
+// Snapshot is an element of GET /admin/datastore/{store}/snapshots.
+type Snapshot struct {
+	BackupType   string        `json:"backup-type"`
+	BackupID     string        `json:"backup-id"`
+	BackupTime   int64         `json:"backup-time"`
+	Size         int64         `json:"size,omitempty"`
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+// JobStatus is an element of the GET /admin/{gc,prune,verify,sync} job lists.
+// The GC list has no job ID: it reports one entry per datastore.
+type JobStatus struct {
+	ID             string `json:"id,omitempty"`
+	Store          string `json:"store"`
+	Schedule       string `json:"schedule,omitempty"`
+	LastRunState   string `json:"last-run-state,omitempty"`
+	LastRunEndtime int64  `json:"last-run-endtime,omitempty"`
+	LastRunUPID    string `json:"last-run-upid,omitempty"`
+	NextRun        int64  `json:"next-run,omitempty"`
+	Remote         string `json:"remote,omitempty"`
+	RemoteStore    string `json:"remote-store,omitempty"`
+	// GC only: bytes removed / still pending on the last run.
+	RemovedBytes int64 `json:"removed-bytes,omitempty"`
+	PendingBytes int64 `json:"pending-bytes,omitempty"`
+}
+
+// ─── API methods ─────────────────────────────────────────────────────────────
+
+// GetVersion returns the server version (also used as connection test).
+func (c *Client) GetVersion() (Version, error) {
+	var v Version
+	err := c.get("/version", &v)
+	return v, err
+}
+
+// GetDatastoreUsage returns usage for every datastore the token can see.
+func (c *Client) GetDatastoreUsage() ([]DatastoreUsage, error) {
+	var out []DatastoreUsage
+	err := c.get("/status/datastore-usage", &out)
+	return out, err
+}
+
+// GetGroups returns the backup groups of a datastore.
+func (c *Client) GetGroups(store string) ([]BackupGroup, error) {
+	var out []BackupGroup
+	err := c.get("/admin/datastore/"+url.PathEscape(store)+"/groups", &out)
+	return out, err
+}
+
+// GetSnapshots returns all snapshots of a datastore.
+func (c *Client) GetSnapshots(store string) ([]Snapshot, error) {
+	var out []Snapshot
+	err := c.get("/admin/datastore/"+url.PathEscape(store)+"/snapshots", &out)
+	return out, err
+}
+
+// GetJobs returns the job status list of one job kind: gc, prune, verify or sync.
+func (c *Client) GetJobs(kind string) ([]JobStatus, error) {
+	var out []JobStatus
+	err := c.get("/admin/"+url.PathEscape(kind), &out)
+	return out, err
+}
@@ -0,0 +1,61 @@
+package pbsclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGet_PBSTokenHeader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "PBSAPIToken=monitor@pbs!ss:s3cret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_, _ = io.WriteString(w, `{"data":{"version":"3.2","release":"7"}}`)
+	}))
+	defer srv.Close()
+
+	v, err := New(srv.URL, "monitor@pbs!ss", "s3cret", false).GetVersion()
+	if err != nil {
+		t.Fatalf("GetVersion: %v", err)
+	}
+	if v.Version != "3.2" {
+		t.Errorf("version = %q", v.Version)
+	}
+}
+
+func TestGetSnapshots_Verification(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/datastore/main/snapshots" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"data":[
+			{"backup-type":"vm","backup-id":"100","backup-time":1700000000,"verification":{"state":"failed","upid":"UPID:x"}},
+			{"backup-type":"ct","backup-id":"101","backup-time":1700000500}
+		]}`)
+	}))
+	defer srv.Close()
+
+	snaps, err := New(srv.URL, "id", "secret", false).GetSnapshots("main")
+	if err != nil {
+		t.Fatalf("GetSnapshots: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0].Verification == nil || snaps[0].Verification.State != "failed" {
+		t.Fatalf("got %+v", snaps)
+	}
+	if snaps[1].Verification != nil {
+		t.Errorf("unverified snapshot should have nil verification")
+	}
+}
+
+func TestGetJobs_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "permission check failed", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if _, err := New(srv.URL, "id", "secret", false).GetJobs("sync"); err == nil {
+		t.Fatal("expected an error on HTTP 403")
+	}
+}
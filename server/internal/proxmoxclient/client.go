@@ -38,6 +38,20 @@ func (f *FlexInt) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// FlexFloat is FlexInt for decimal values (pressure stall percentages).
+type FlexFloat float64
+
+func (f *FlexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		*f = 0
+		return nil
+	}
+	*f = FlexFloat(v)
+	return nil
+}
+
 // Client talks to one Proxmox VE instance.
 type Client struct {
 	baseURL     string
@@ -132,6 +146,15 @@ type PVEGuest struct {
 	Disk    int64   `json:"disk,omitempty"` // actual used bytes — reliable for LXC, usually 0 for a QEMU VM without guest agent
 	Tags    string  `json:"tags,omitempty"`
 	Uptime  int64   `json:"uptime,omitempty"`
+	// Template is 1 for a QEMU template (clone source), absent otherwise.
+	Template FlexInt `json:"template,omitempty"`
+	// Balloon is the current balloon size in bytes of a running QEMU VM with
+	// a balloon device (full status only); below MaxMem, the host is
+	// reclaiming memory from the guest.
+	Balloon int64 `json:"balloon,omitempty"`
+	// PressureCPUSome is the guest cgroup's CPU pressure stall (share of time
+	// some task waited for a CPU, %), reported by PVE 9 and later.
+	PressureCPUSome FlexFloat `json:"pressurecpusome,omitempty"`
 	// Present only when fetched via /cluster/resources
 	Node string `json:"node,omitempty"`
 	Type string `json:"type,omitempty"` // qemu | lxc
@@ -147,6 +170,7 @@ type PVEStorage struct {
 	Enabled int    `json:"enabled"` // 0 or 1
 	Active  int    `json:"active"`  // 0 or 1
 	Shared  int    `json:"shared"`  // 0 or 1
+	Content string `json:"content"` // iso,vztmpl,backup,images,…
 }
 
 // PVEClusterStatus is an element from GET /cluster/status.
@@ -154,6 +178,11 @@ type PVEClusterStatus struct {
 	Name string `json:"name"`
 	Type string `json:"type"` // cluster | node
 	ID   string `json:"id"`
+	// Quorate and Nodes are set on the "cluster" entry (corosync view),
+	// Online on each "node" entry.
+	Quorate FlexInt `json:"quorate,omitempty"`
+	Nodes   int     `json:"nodes,omitempty"`
+	Online  FlexInt `json:"online,omitempty"`
 }
 
 // PVEVersion is from GET /nodes/{node}/version.
@@ -193,7 +222,10 @@ func (c *Client) GetClusterStatus() ([]PVEClusterStatus, error) {
 // GetNodeQemu returns all QEMU VMs on the given node.
 func (c *Client) GetNodeQemu(node string) ([]PVEGuest, error) {
 	var guests []PVEGuest
-	if err := c.get(fmt.Sprintf("/nodes/%s/qemu", node), &guests); err != nil {
+	// full=1 adds the balloon statistics, and the memory figure the guest
+	// reports through its balloon driver (what the PVE UI shows) instead of
+	// the QEMU process's.
+	if err := c.get(fmt.Sprintf("/nodes/%s/qemu?full=1", node), &guests); err != nil {
 		return nil, err
 	}
 	return guests, nil
@@ -0,0 +1,241 @@
+package proxmoxclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ─── Ceph ────────────────────────────────────────────────────────────────────
+
+// CephStatus is the subset of GET /cluster/ceph/status (`ceph status -f json`)
+// the poller keeps.
+type CephStatus struct {
+	Health struct {
+		Status string               `json:"status"` // HEALTH_OK | HEALTH_WARN | HEALTH_ERR
+		Checks map[string]CephCheck `json:"checks"`
+	} `json:"health"`
+	QuorumNames []string   `json:"quorum_names"`
+	MonMap      cephMonMap `json:"monmap"`
+	OSDMap      CephOSDMap `json:"osdmap"`
+	PGMap       CephPGMap  `json:"pgmap"`
+}
+
+// CephCheck is one entry of health.checks, keyed by its code (OSD_DOWN, …).
+type CephCheck struct {
+	Severity string `json:"severity"`
+	Summary  struct {
+		Message string `json:"message"`
+	} `json:"summary"`
+	Muted bool `json:"muted,omitempty"`
+}
+
+type cephMonMap struct {
+	NumMons int `json:"num_mons"`
+	Mons    []struct {
+		Name string `json:"name"`
+	} `json:"mons"`
+}
+
+// CephOSDMap holds the OSD counters. Ceph < 15 nests them one level deeper
+// ("osdmap": {"osdmap": {...}}); UnmarshalJSON accepts both shapes.
+type CephOSDMap struct {
+	NumOSDs   int `json:"num_osds"`
+	NumUpOSDs int `json:"num_up_osds"`
+	NumInOSDs int `json:"num_in_osds"`
+}
+
+func (m *CephOSDMap) UnmarshalJSON(b []byte) error {
+	type flat CephOSDMap
+	var wrapped struct {
+		OSDMap *flat `json:"osdmap"`
+	}
+	if err := json.Unmarshal(b, &wrapped); err == nil && wrapped.OSDMap != nil {
+		*m = CephOSDMap(*wrapped.OSDMap)
+		return nil
+	}
+	var f flat
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*m = CephOSDMap(f)
+	return nil
+}
+
+// CephPGMap holds the placement group states.
+type CephPGMap struct {
+	NumPGs     int           `json:"num_pgs"`
+	PGsByState []CephPGState `json:"pgs_by_state"`
+	BytesUsed  int64         `json:"bytes_used"`
+	BytesTotal int64         `json:"bytes_total"`
+}
+
+// CephPGState is one "state_name: count" pair (e.g. "active+clean": 128).
+type CephPGState struct {
+	StateName string `json:"state_name"`
+	Count     int    `json:"count"`
+}
+
+// ActiveClean returns the number of PGs exactly in the active+clean state.
+func (p CephPGMap) ActiveClean() int {
+	for _, s := range p.PGsByState {
+		if s.StateName == "active+clean" {
+			return s.Count
+		}
+	}
+	return 0
+}
+
+// MonCount returns the number of monitors in the monmap (Ceph ≥ 17 only
+// reports num_mons).
+func (s *CephStatus) MonCount() int {
+	if len(s.MonMap.Mons) > 0 {
+		return len(s.MonMap.Mons)
+	}
+	return s.MonMap.NumMons
+}
+
+// CephMon is an element of GET /nodes/{node}/ceph/mon.
+type CephMon struct {
+	Name   string  `json:"name"`
+	Host   string  `json:"host,omitempty"`
+	Addr   string  `json:"addr,omitempty"`
+	Quorum FlexInt `json:"quorum,omitempty"`
+	State  string  `json:"state,omitempty"`
+}
+
+// CephOSD is one OSD flattened out of the GET /nodes/{node}/ceph/osd tree.
+type CephOSD struct {
+	ID          int
+	Name        string
+	Host        string
+	Status      string // up | down
+	In          bool
+	DeviceClass string
+	// PercentUsed is 0–100, -1 when unknown.
+	PercentUsed float64
+}
+
+type cephOSDTreeNode struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Host        string            `json:"host,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	In          FlexInt           `json:"in,omitempty"`
+	DeviceClass string            `json:"device_class,omitempty"`
+	PercentUsed *float64          `json:"percent_used,omitempty"`
+	Children    []cephOSDTreeNode `json:"children,omitempty"`
+}
+
+// CephPool is an element of GET /nodes/{node}/ceph/pool.
+type CephPool struct {
+	Name    string `json:"pool_name"`
+	Size    int    `json:"size"`
+	MinSize int    `json:"min_size"`
+	PGNum   int    `json:"pg_num"`
+	// BytesUsed and PercentUsed (0–1 fraction) come from `ceph df`.
+	BytesUsed   int64   `json:"bytes_used"`
+	PercentUsed float64 `json:"percent_used"`
+}
+
+// GetCephStatus returns the cluster-wide Ceph status. On a cluster without
+// Ceph, PVE answers 500 ("binary not installed" / "not initialized"): see
+// CephNotConfigured.
+func (c *Client) GetCephStatus() (*CephStatus, error) {
+	var st CephStatus
+	if err := c.get("/cluster/ceph/status", &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// GetCephMons returns the monitors as seen from node.
+func (c *Client) GetCephMons(node string) ([]CephMon, error) {
+	var mons []CephMon
+	if err := c.get(fmt.Sprintf("/nodes/%s/ceph/mon", node), &mons); err != nil {
+		return nil, err
+	}
+	return mons, nil
+}
+
+// GetCephOSDs returns every OSD of the cluster (the tree is cluster-wide; node
+// only selects which PVE node answers), with the host each one lives on.
+func (c *Client) GetCephOSDs(node string) ([]CephOSD, error) {
+	var tree struct {
+		Root cephOSDTreeNode `json:"root"`
+	}
+	if err := c.get(fmt.Sprintf("/nodes/%s/ceph/osd", node), &tree); err != nil {
+		return nil, err
+	}
+	var out []CephOSD
+	var walk func(n cephOSDTreeNode, host string)
+	walk = func(n cephOSDTreeNode, host string) {
+		if n.Type == "host" {
+			host = n.Name
+		}
+		if n.Type == "osd" {
+			osd := CephOSD{ID: n.ID, Name: n.Name, Host: n.Host, Status: n.Status, In: n.In != 0, DeviceClass: n.DeviceClass, PercentUsed: -1}
+			if osd.Host == "" {
+				osd.Host = host
+			}
+			if n.PercentUsed != nil {
+				osd.PercentUsed = *n.PercentUsed
+			}
+			out = append(out, osd)
+		}
+		for _, child := range n.Children {
+			walk(child, host)
+		}
+	}
+	walk(tree.Root, "")
+	return out, nil
+}
+
+// GetCephPools returns the pools with their usage.
+func (c *Client) GetCephPools(node string) ([]CephPool, error) {
+	var pools []CephPool
+	if err := c.get(fmt.Sprintf("/nodes/%s/ceph/pool", node), &pools); err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// CephNotConfigured reports whether a Ceph API error means "this cluster does
+// not run Ceph" rather than "Ceph is broken".
+func CephNotConfigured(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "not installed") || strings.Contains(msg, "not initialized") ||
+		strings.Contains(msg, "HTTP 501")
+}
+
+// ─── HA manager ──────────────────────────────────────────────────────────────
+
+// HAStatusEntry is an element of GET /cluster/ha/status/current. Type is one
+// of quorum | master | lrm | service; Status is a human-readable line
+// ("pve1 (active, Tue Oct 14 …)"), State the machine state of LRMs and
+// services.
+type HAStatusEntry struct {
+	ID           string  `json:"id"`
+	Type         string  `json:"type"`
+	Node         string  `json:"node,omitempty"`
+	Status       string  `json:"status,omitempty"`
+	Quorate      FlexInt `json:"quorate,omitempty"`
+	SID          string  `json:"sid,omitempty"`
+	State        string  `json:"state,omitempty"`
+	CRMState     string  `json:"crm_state,omitempty"`
+	RequestState string  `json:"request_state,omitempty"`
+}
+
+// GetHAStatus returns the current HA manager status: quorum, CRM master, one
+// LRM per node and one entry per HA resource.
+func (c *Client) GetHAStatus() ([]HAStatusEntry, error) {
+	var entries []HAStatusEntry
+	if err := c.get("/cluster/ha/status/current", &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
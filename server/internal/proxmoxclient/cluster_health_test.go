@@ -0,0 +1,53 @@
+package proxmoxclient
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCephOSDMap_BothShapes(t *testing.T) {
+	for name, raw := range map[string]string{
+		"flat":   `{"num_osds":6,"num_up_osds":5,"num_in_osds":6}`,
+		"nested": `{"osdmap":{"num_osds":6,"num_up_osds":5,"num_in_osds":6}}`,
+	} {
+		var m CephOSDMap
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if m.NumOSDs != 6 || m.NumUpOSDs != 5 || m.NumInOSDs != 6 {
+			t.Errorf("%s: got %+v", name, m)
+		}
+	}
+}
+
+func TestGetCephOSDs_FlattensTreeWithHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes/pve1/ceph/osd" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"data":{"root":{"name":"default","type":"root","children":[
+			{"name":"pve1","type":"host","children":[
+				{"id":0,"name":"osd.0","type":"osd","status":"up","in":1,"device_class":"ssd","percent_used":41.5}]},
+			{"name":"pve2","type":"host","children":[
+				{"id":1,"name":"osd.1","type":"osd","status":"down","in":0}]}
+		]}}}`)
+	}))
+	defer srv.Close()
+
+	osds, err := New(srv.URL, "id", "secret", false).GetCephOSDs("pve1")
+	if err != nil {
+		t.Fatalf("GetCephOSDs: %v", err)
+	}
+	if len(osds) != 2 {
+		t.Fatalf("got %d OSDs, want 2", len(osds))
+	}
+	if o := osds[0]; o.Host != "pve1" || !o.In || o.PercentUsed != 41.5 {
+		t.Errorf("osd.0 = %+v", o)
+	}
+	if o := osds[1]; o.Host != "pve2" || o.In || o.Status != "down" || o.PercentUsed != -1 {
+		t.Errorf("osd.1 = %+v", o)
+	}
+}
@@ -0,0 +1,70 @@
+package proxmoxclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestConsole_TermProxyThenDial(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{"binary"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nodes/pve1/lxc/101/termproxy":
+			if r.Method != http.MethodPost {
+				t.Errorf("termproxy method = %s", r.Method)
+			}
+			_, _ = io.WriteString(w, `{"data":{"port":"5900","ticket":"PVEVNC:abc","user":"supervisor@pve!ro","upid":"UPID:pve1:termproxy"}}`)
+		case "/nodes/pve1/lxc/101/vncwebsocket":
+			if got := r.URL.Query().Get("port"); got != "5900" {
+				t.Errorf("port = %q", got)
+			}
+			if got := r.URL.Query().Get("vncticket"); got != "PVEVNC:abc" {
+				t.Errorf("vncticket = %q", got)
+			}
+			if got := r.Header.Get("Authorization"); got != "PVEAPIToken=id=secret" {
+				t.Errorf("Authorization = %q", got)
+			}
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				t.Errorf("upgrade: %v", err)
+				return
+			}
+			defer func() { _ = conn.Close() }()
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Errorf("read login: %v", err)
+				return
+			}
+			_ = conn.WriteMessage(websocket.BinaryMessage, msg)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "id", "secret", false)
+	ticket, err := c.OpenTermProxy("pve1", 101, "lxc")
+	if err != nil {
+		t.Fatalf("OpenTermProxy: %v", err)
+	}
+	if ticket.Port != 5900 || ticket.User != "supervisor@pve!ro" {
+		t.Fatalf("ticket = %+v", ticket)
+	}
+
+	conn, err := c.DialConsole("pve1", 101, "lxc", ticket)
+	if err != nil {
+		t.Fatalf("DialConsole: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if err := conn.WriteMessage(websocket.BinaryMessage, TermProxyLogin(ticket)); err != nil {
+		t.Fatal(err)
+	}
+	_, echo, err := conn.ReadMessage()
+	if err != nil || string(echo) != "supervisor@pve!ro:PVEVNC:abc\n" {
+		t.Fatalf("login echo = %q, %v", echo, err)
+	}
+}
@@ -0,0 +1,96 @@
+package proxmoxclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CloneVMOptions are the parameters of POST /nodes/{node}/qemu/{vmid}/clone.
+type CloneVMOptions struct {
+	NewID int
+	Name  string
+	// Full makes an independent copy; false makes a linked clone (template
+	// only, same storage).
+	Full bool
+	// Storage is the target storage of a full clone ("" = same as template).
+	Storage string
+}
+
+// NextVMID returns the next free VMID of the cluster (GET /cluster/nextid).
+func (c *Client) NextVMID() (int, error) {
+	var raw FlexInt
+	if err := c.get("/cluster/nextid", &raw); err != nil {
+		return 0, err
+	}
+	return int(raw), nil
+}
+
+// CloneVM clones QEMU VM (or template) vmid on node. Requires VM.Clone on the
+// source and VM.Allocate on the target. Returns the task UPID.
+func (c *Client) CloneVM(node string, vmid int, opts CloneVMOptions) (string, error) {
+	form := url.Values{"newid": {strconv.Itoa(opts.NewID)}}
+	if opts.Name != "" {
+		form.Set("name", opts.Name)
+	}
+	if opts.Full {
+		form.Set("full", "1")
+		if opts.Storage != "" {
+			form.Set("storage", opts.Storage)
+		}
+	} else {
+		form.Set("full", "0")
+	}
+	return c.doTask(http.MethodPost, guestAPIPath(node, vmid, "vm")+"/clone", form)
+}
+
+// UpdateVMConfig sets QEMU config keys (cores, memory, net0, ipconfig0,
+// smbios1…) through the asynchronous POST /config. Requires VM.Config.*.
+// Returns the task UPID.
+func (c *Client) UpdateVMConfig(node string, vmid int, params map[string]string) (string, error) {
+	form := url.Values{}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	return c.doTask(http.MethodPost, guestAPIPath(node, vmid, "vm")+"/config", form)
+}
+
+// ResizeVMDisk grows disk (e.g. "scsi0") to size (e.g. "32G"; PVE refuses to
+// shrink). Requires VM.Config.Disk. Returns the task UPID, or "" on PVE
+// versions where the resize is synchronous.
+func (c *Client) ResizeVMDisk(node string, vmid int, disk, size string) (string, error) {
+	form := url.Values{"disk": {disk}, "size": {size}}
+	return c.doTask(http.MethodPut, guestAPIPath(node, vmid, "vm")+"/resize", form)
+}
+
+// SMBIOSWithSerial returns the smbios1 value that keeps the uuid of current
+// (the one PVE generated for the clone) and sets serial. The serial is
+// base64-encoded so that cloud-init seeds like "ds=nocloud;s=http://…/"
+// survive PVE's property-string parsing; with base64=1 every other free-form
+// field would have to be encoded too, so only the uuid is carried over.
+func SMBIOSWithSerial(current, serial string) string {
+	var parts []string
+	for _, kv := range strings.Split(current, ",") {
+		if strings.HasPrefix(kv, "uuid=") {
+			parts = append(parts, kv)
+		}
+	}
+	parts = append(parts, "serial="+base64.StdEncoding.EncodeToString([]byte(serial)), "base64=1")
+	return strings.Join(parts, ",")
+}
+
+// VMConfigString returns config key as a string ("" when absent), for values
+// such as smbios1 read through GetVMConfig.
+func VMConfigString(cfg map[string]any, key string) string {
+	v, ok := cfg[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
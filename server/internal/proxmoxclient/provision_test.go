@@ -0,0 +1,61 @@
+package proxmoxclient
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCloneVM_Form(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/nodes/pve1/qemu/9000/clone" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("newid") != "123" || r.PostForm.Get("name") != "web-01" ||
+			r.PostForm.Get("full") != "1" || r.PostForm.Get("storage") != "local-lvm" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		_, _ = io.WriteString(w, `{"data":"UPID:pve1:0002:qmclone"}`)
+	}))
+	defer srv.Close()
+
+	upid, err := New(srv.URL, "id", "secret", false).CloneVM("pve1", 9000, CloneVMOptions{
+		NewID: 123, Name: "web-01", Full: true, Storage: "local-lvm",
+	})
+	if err != nil {
+		t.Fatalf("CloneVM: %v", err)
+	}
+	if upid != "UPID:pve1:0002:qmclone" {
+		t.Errorf("upid = %q", upid)
+	}
+}
+
+func TestNextVMID_QuotedNumber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":"105"}`)
+	}))
+	defer srv.Close()
+
+	id, err := New(srv.URL, "id", "secret", false).NextVMID()
+	if err != nil || id != 105 {
+		t.Fatalf("NextVMID = %d, %v; want 105", id, err)
+	}
+}
+
+func TestSMBIOSWithSerial_KeepsUUID(t *testing.T) {
+	got := SMBIOSWithSerial("uuid=5f1c-aa,manufacturer=QUJD,base64=1", "ds=nocloud;s=http://ss/seed/")
+	if !strings.HasPrefix(got, "uuid=5f1c-aa,serial=") || !strings.HasSuffix(got, ",base64=1") {
+		t.Fatalf("got %q", got)
+	}
+	enc := strings.TrimSuffix(strings.TrimPrefix(got, "uuid=5f1c-aa,serial="), ",base64=1")
+	dec, _ := base64.StdEncoding.DecodeString(enc)
+	if string(dec) != "ds=nocloud;s=http://ss/seed/" {
+		t.Errorf("serial decodes to %q", dec)
+	}
+}
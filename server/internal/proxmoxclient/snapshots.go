@@ -0,0 +1,147 @@
+package proxmoxclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PVESnapshot is an element from GET /nodes/{node}/{qemu|lxc}/{vmid}/snapshot.
+// PVE always appends a pseudo-entry named "current" (the live state, whose
+// Parent is the most recent snapshot); ListGuestSnapshots filters it out.
+type PVESnapshot struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	SnapTime    FlexInt `json:"snaptime,omitempty"` // unix seconds
+	Parent      string  `json:"parent,omitempty"`
+	VMState     FlexInt `json:"vmstate,omitempty"` // 1 when RAM was saved (QEMU only)
+}
+
+// PVETaskStatus is GET /nodes/{node}/tasks/{upid}/status.
+type PVETaskStatus struct {
+	UPID       string `json:"upid"`
+	Status     string `json:"status"`               // running | stopped
+	ExitStatus string `json:"exitstatus,omitempty"` // OK | error message, once stopped
+}
+
+// guestAPIPath returns the /nodes/{node}/{qemu|lxc}/{vmid} prefix; guestType
+// is "vm" or "lxc", like GuestAction.
+func guestAPIPath(node string, vmid int, guestType string) string {
+	if guestType == "lxc" {
+		return fmt.Sprintf("/nodes/%s/lxc/%d", node, vmid)
+	}
+	return fmt.Sprintf("/nodes/%s/qemu/%d", node, vmid)
+}
+
+// ListGuestSnapshots returns the snapshots of a VM or LXC container, without
+// PVE's "current" pseudo-entry. Requires VM.Audit.
+func (c *Client) ListGuestSnapshots(node string, vmid int, guestType string) ([]PVESnapshot, error) {
+	var all []PVESnapshot
+	if err := c.get(guestAPIPath(node, vmid, guestType)+"/snapshot", &all); err != nil {
+		return nil, err
+	}
+	out := make([]PVESnapshot, 0, len(all))
+	for _, s := range all {
+		if s.Name == "current" {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// CreateGuestSnapshot takes a snapshot named name. vmstate also saves the RAM
+// of a running QEMU VM (ignored for LXC). Requires VM.Snapshot. Returns the
+// UPID of the snapshot task.
+func (c *Client) CreateGuestSnapshot(node string, vmid int, guestType, name, description string, vmstate bool) (string, error) {
+	form := url.Values{"snapname": {name}}
+	if description != "" {
+		form.Set("description", description)
+	}
+	if vmstate && guestType != "lxc" {
+		form.Set("vmstate", "1")
+	}
+	return c.doTask(http.MethodPost, guestAPIPath(node, vmid, guestType)+"/snapshot", form)
+}
+
+// RollbackGuestSnapshot reverts a guest to snapshot name. Everything written
+// since is lost. Requires VM.Snapshot.Rollback. Returns the task UPID.
+func (c *Client) RollbackGuestSnapshot(node string, vmid int, guestType, name string) (string, error) {
+	return c.doTask(http.MethodPost, guestAPIPath(node, vmid, guestType)+"/snapshot/"+url.PathEscape(name)+"/rollback", nil)
+}
+
+// DeleteGuestSnapshot removes snapshot name. Requires VM.Snapshot. Returns the
+// task UPID.
+func (c *Client) DeleteGuestSnapshot(node string, vmid int, guestType, name string) (string, error) {
+	return c.doTask(http.MethodDelete, guestAPIPath(node, vmid, guestType)+"/snapshot/"+url.PathEscape(name), nil)
+}
+
+// GetTaskStatus returns the state of one task, used to wait for an async
+// action (snapshot, rollback…) before chaining the next one.
+func (c *Client) GetTaskStatus(node, upid string) (*PVETaskStatus, error) {
+	var st PVETaskStatus
+	if err := c.get(fmt.Sprintf("/nodes/%s/tasks/%s/status", node, url.PathEscape(upid)), &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// doTask performs a write request (form-encoded when form is non-nil) whose
+// response data is the UPID of the task PVE started.
+func (c *Client) doTask(method, apiPath string, form url.Values) (string, error) {
+	var upid string
+	if err := c.doWrite(method, apiPath, form, &upid); err != nil {
+		return "", err
+	}
+	return upid, nil
+}
+
+// doWrite performs a write request (form-encoded when form is non-nil) and
+// unmarshals the response data into result.
+func (c *Client) doWrite(method, apiPath string, form url.Values, result any) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, c.baseURL+apiPath, body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.tokenID, c.tokenSecret))
+	req.Header.Set("Accept", "application/json")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		snippet := string(raw)
+		if len(snippet) > 300 {
+			snippet = snippet[:300]
+		}
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, snippet)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, result); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package proxmoxclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListGuestSnapshots_DropsCurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes/pve1/lxc/101/snapshot" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"data":[
+			{"name":"before-upgrade","snaptime":1700000000,"description":"x"},
+			{"name":"current","parent":"before-upgrade","running":1}
+		]}`)
+	}))
+	defer srv.Close()
+
+	snaps, err := New(srv.URL, "id", "secret", false).ListGuestSnapshots("pve1", 101, "lxc")
+	if err != nil {
+		t.Fatalf("ListGuestSnapshots: %v", err)
+	}
+	if len(snaps) != 1 || snaps[0].Name != "before-upgrade" || snaps[0].SnapTime != 1700000000 {
+		t.Errorf("got %+v, want only before-upgrade", snaps)
+	}
+}
+
+func TestCreateGuestSnapshot_Form(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/nodes/pve1/qemu/100/snapshot" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("snapname") != "pre_apt" || r.PostForm.Get("vmstate") != "1" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		_, _ = io.WriteString(w, `{"data":"UPID:pve1:0001:qmsnapshot"}`)
+	}))
+	defer srv.Close()
+
+	upid, err := New(srv.URL, "id", "secret", false).CreateGuestSnapshot("pve1", 100, "vm", "pre_apt", "", true)
+	if err != nil {
+		t.Fatalf("CreateGuestSnapshot: %v", err)
+	}
+	if upid != "UPID:pve1:0001:qmsnapshot" {
+		t.Errorf("upid = %q", upid)
+	}
+}
+
+func TestDeleteGuestSnapshot_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s, want DELETE", r.Method)
+		}
+		http.Error(w, `{"errors":{"snapname":"snapshot 'x' does not exist"}}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if _, err := New(srv.URL, "id", "secret", false).DeleteGuestSnapshot("pve1", 100, "vm", "x"); err == nil {
+		t.Error("expected an error on HTTP 500")
+	}
+}
@@ -0,0 +1,97 @@
+package proxmoxclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PVEStorageVolume is an element from GET /nodes/{node}/storage/{storage}/content.
+type PVEStorageVolume struct {
+	VolID   string `json:"volid"`   // "<storage>:<path>", e.g. local:iso/debian-12.iso
+	Content string `json:"content"` // iso | vztmpl | backup | images | rootdir | snippets
+	Format  string `json:"format"`  // iso, tgz, raw, qcow2, vma.zst, subvol…
+	Size    int64  `json:"size"`
+	// Used is the space actually taken by a thin image (0 when unknown).
+	Used int64 `json:"used,omitempty"`
+	// VMID owns disk images and backups; absent for ISOs and templates.
+	VMID      FlexInt `json:"vmid,omitempty"`
+	CTime     FlexInt `json:"ctime,omitempty"` // unix seconds
+	Notes     string  `json:"notes,omitempty"`
+	Protected FlexInt `json:"protected,omitempty"` // 1 = backup protected from pruning/removal
+	// Parent is the base image of a linked clone (images only).
+	Parent string `json:"parent,omitempty"`
+}
+
+// DownloadURLOptions are the parameters of POST
+// /nodes/{node}/storage/{storage}/download-url.
+type DownloadURLOptions struct {
+	URL      string
+	Filename string
+	Content  string // iso | vztmpl
+	// Checksum and ChecksumAlgorithm (md5, sha1, sha224, sha256, sha384,
+	// sha512) make PVE verify the file once downloaded; both or neither.
+	Checksum          string
+	ChecksumAlgorithm string
+	// VerifyCertificates is false only for self-signed mirrors.
+	VerifyCertificates bool
+}
+
+// ListStorageContent returns the volumes of storage as seen from node.
+// content filters on one content type ("" = all). Requires Datastore.Audit
+// (and VM.Audit on the owner to see its images/backups).
+func (c *Client) ListStorageContent(node, storage, content string) ([]PVEStorageVolume, error) {
+	path := fmt.Sprintf("/nodes/%s/storage/%s/content", node, url.PathEscape(storage))
+	if content != "" {
+		path += "?content=" + url.QueryEscape(content)
+	}
+	var vols []PVEStorageVolume
+	if err := c.get(path, &vols); err != nil {
+		return nil, err
+	}
+	return vols, nil
+}
+
+// DownloadURLToStorage makes node download an ISO or container template into
+// storage. Requires Sys.Audit and Sys.Modify on / plus
+// Datastore.AllocateTemplate on the storage. Returns the task UPID.
+func (c *Client) DownloadURLToStorage(node, storage string, opts DownloadURLOptions) (string, error) {
+	form := url.Values{
+		"url":      {opts.URL},
+		"filename": {opts.Filename},
+		"content":  {opts.Content},
+	}
+	if opts.Checksum != "" {
+		form.Set("checksum", opts.Checksum)
+		form.Set("checksum-algorithm", opts.ChecksumAlgorithm)
+	}
+	if !opts.VerifyCertificates {
+		form.Set("verify-certificates", "0")
+	}
+	return c.doTask(http.MethodPost, fmt.Sprintf("/nodes/%s/storage/%s/download-url", node, url.PathEscape(storage)), form)
+}
+
+// DeleteStorageVolume removes volid from storage. Requires Datastore.Allocate
+// on the storage (VM.Allocate on the owner for an image or backup). PVE
+// returns a UPID for storages that delete asynchronously, "" otherwise.
+func (c *Client) DeleteStorageVolume(node, storage, volid string) (string, error) {
+	return c.doTask(http.MethodDelete,
+		fmt.Sprintf("/nodes/%s/storage/%s/content/%s", node, url.PathEscape(storage), url.PathEscape(volid)), nil)
+}
+
+// ListClusterVMIDs returns the VMID of every VM, container and template of
+// the cluster (GET /cluster/resources?type=vm), live rather than from the
+// last poll so a just-created guest never looks orphaned.
+func (c *Client) ListClusterVMIDs() (map[int]bool, error) {
+	var res []struct {
+		VMID int `json:"vmid"`
+	}
+	if err := c.get("/cluster/resources?type=vm", &res); err != nil {
+		return nil, err
+	}
+	out := make(map[int]bool, len(res))
+	for _, r := range res {
+		out[r.VMID] = true
+	}
+	return out, nil
+}
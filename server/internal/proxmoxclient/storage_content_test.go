@@ -0,0 +1,60 @@
+package proxmoxclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadURLToStorage_Form(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/nodes/pve1/storage/local/download-url" {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			"url": "https://example.org/debian.iso", "filename": "debian.iso", "content": "iso",
+			"checksum": "abc", "checksum-algorithm": "sha256",
+		}
+		for k, v := range want {
+			if got := r.PostForm.Get(k); got != v {
+				t.Errorf("%s = %q, want %q", k, got, v)
+			}
+		}
+		if r.PostForm.Has("verify-certificates") {
+			t.Error("verify-certificates should be left to PVE's default")
+		}
+		_, _ = io.WriteString(w, `{"data":"UPID:pve1:download"}`)
+	}))
+	defer srv.Close()
+
+	upid, err := New(srv.URL, "id", "secret", false).DownloadURLToStorage("pve1", "local", DownloadURLOptions{
+		URL: "https://example.org/debian.iso", Filename: "debian.iso", Content: "iso",
+		Checksum: "abc", ChecksumAlgorithm: "sha256", VerifyCertificates: true,
+	})
+	if err != nil || upid != "UPID:pve1:download" {
+		t.Fatalf("got %q, %v", upid, err)
+	}
+}
+
+func TestDeleteStorageVolume_EscapesVolID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s", r.Method)
+		}
+		if want := "/nodes/pve1/storage/local/content/local:backup%2Fvzdump-qemu-100.vma.zst"; r.URL.EscapedPath() != want {
+			t.Errorf("path = %s, want %s", r.URL.EscapedPath(), want)
+		}
+		// Synchronous deletes answer data: null.
+		_, _ = io.WriteString(w, `{"data":null}`)
+	}))
+	defer srv.Close()
+
+	upid, err := New(srv.URL, "id", "secret", false).DeleteStorageVolume("pve1", "local", "local:backup/vzdump-qemu-100.vma.zst")
+	if err != nil || upid != "" {
+		t.Fatalf("got %q, %v", upid, err)
+	}
+}
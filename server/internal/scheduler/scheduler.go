@@ -1,6 +1,10 @@
 // Package scheduler manages cron-based scheduled task execution.
 // It maintains an in-memory map of cron.EntryID keyed by scheduled_task UUID,
 // allowing tasks to be added, updated, and removed without restarting the server.
+//
+// With several server replicas every replica keeps the full set of entries (so
+// NextRun answers on any of them) but only the leader queues commands, and a
+// change made through one replica's API is replayed on the others via Reload.
 package scheduler
 
 import (
@@ -29,6 +33,8 @@ type TaskScheduler struct {
 	jobs       map[string]cron.EntryID // scheduled_task.id → cron entry
 	mu         sync.Mutex
 	ctx        context.Context // root ctx for cron jobs' DB calls
+	isLeader   func() bool     // nil: single server, always runs
+	onChange   func()          // nil: single server, nothing to tell
 }
 
 // New creates a TaskScheduler. Call Start() to begin scheduling.
@@ -60,6 +66,45 @@ func (s *TaskScheduler) Start(ctx context.Context) {
 	s.c.Start()
 }
 
+// SetLeaderCheck gates every run on fn: a replica that is not the leader keeps
+// its entries but skips the runs. Must be called before Start.
+func (s *TaskScheduler) SetLeaderCheck(fn func() bool) {
+	s.isLeader = fn
+}
+
+// SetOnChange registers fn, called after every Add/Remove/Update so the other
+// replicas can Reload. Must be called before Start.
+func (s *TaskScheduler) SetOnChange(fn func()) {
+	s.onChange = fn
+}
+
+func (s *TaskScheduler) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// Reload replaces every entry with the enabled tasks currently in the database
+// — how a replica picks up a change made through another replica's API.
+func (s *TaskScheduler) Reload() {
+	tasks, err := s.db.GetAllScheduledTasks(s.ctx)
+	if err != nil {
+		slog.ErrorContext(s.ctx, "scheduler: failed to reload tasks", slog.Any("err", err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, entryID := range s.jobs {
+		s.c.Remove(entryID)
+		delete(s.jobs, id)
+	}
+	for _, t := range tasks {
+		if err := s.add(t); err != nil {
+			slog.ErrorContext(s.ctx, "scheduler: failed to register task", slog.String("task_id", t.ID), slog.String("task_name", t.Name), slog.Any("err", err))
+		}
+	}
+}
+
 // Stop gracefully shuts down the cron runner.
 func (s *TaskScheduler) Stop() {
 	s.c.Stop()
@@ -68,22 +113,26 @@ func (s *TaskScheduler) Stop() {
 // Add registers a new scheduled task. Safe to call concurrently.
 func (s *TaskScheduler) Add(t models.ScheduledTask) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.add(t)
+	err := s.add(t)
+	s.mu.Unlock()
+	s.changed()
+	return err
 }
 
 // Remove unregisters a scheduled task by its UUID.
 func (s *TaskScheduler) Remove(taskID string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	if entryID, ok := s.jobs[taskID]; ok {
 		s.c.Remove(entryID)
 		delete(s.jobs, taskID)
 	}
+	s.mu.Unlock()
+	s.changed()
 }
 
 // Update replaces an existing cron entry (remove + re-add).
 func (s *TaskScheduler) Update(t models.ScheduledTask) error {
+	defer s.changed()
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if entryID, ok := s.jobs[t.ID]; ok {
@@ -120,6 +169,9 @@ func (s *TaskScheduler) add(t models.ScheduledTask) error {
 // makeJob returns the cron.FuncJob for a task.
 func (s *TaskScheduler) makeJob(t models.ScheduledTask) func() {
 	return func() {
+		if s.isLeader != nil && !s.isLeader() {
+			return
+		}
 		payload := t.Payload
 		if payload == "" {
 			payload = "{}"
@@ -199,3 +199,50 @@ func TestStart_SkipsTaskWithInvalidCronButRegistersOthers(t *testing.T) {
 		t.Error("valid task after a bad one was not registered — one bad entry should not block the rest")
 	}
 }
+
+func TestReload_ReplacesEntriesWithDatabaseState(t *testing.T) {
+	db := &fakeDB{}
+	s := newStartedTestScheduler(t, db)
+	if err := s.Add(models.ScheduledTask{ID: "gone", CronExpression: safeCron, Enabled: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Another replica deleted "gone" and created "new".
+	db.mu.Lock()
+	db.tasks = []models.ScheduledTask{{ID: "new", CronExpression: safeCron, Enabled: true}}
+	db.mu.Unlock()
+	s.Reload()
+
+	if !s.NextRun("gone").IsZero() {
+		t.Error("expected the task missing from the database to be dropped")
+	}
+	if s.NextRun("new").IsZero() {
+		t.Error("expected the task added elsewhere to be registered")
+	}
+}
+
+func TestOnChange_FiresOnEveryMutation(t *testing.T) {
+	s := newStartedTestScheduler(t, &fakeDB{})
+	calls := 0
+	s.SetOnChange(func() { calls++ })
+
+	task := models.ScheduledTask{ID: "t1", CronExpression: safeCron, Enabled: true}
+	_ = s.Add(task)
+	_ = s.Update(task)
+	s.Remove("t1")
+	if calls != 3 {
+		t.Errorf("onChange calls = %d, want 3", calls)
+	}
+}
+
+func TestMakeJob_SkipsWhenNotLeader(t *testing.T) {
+	db := &fakeDB{}
+	// dispatch.New(nil) would panic on Create: reaching it fails the test.
+	s := New(db, dispatch.New(nil))
+	s.SetLeaderCheck(func() bool { return false })
+
+	s.makeJob(models.ScheduledTask{ID: "t1", HostID: "h1", Module: "apt", Action: "update"})()
+	if len(db.runs) != 0 {
+		t.Errorf("a follower must not run tasks, got %v", db.runs)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/serversupervisor/server/internal/config"
 	"github.com/serversupervisor/server/internal/events"
 	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/netpolicy"
 	"github.com/serversupervisor/server/internal/notify"
 )
 
@@ -46,13 +47,17 @@ type Repository interface {
 	TouchRunningCommandsActivity(ctx context.Context, hostID string) error
 	CleanupHostStalledCommands(ctx context.Context, hostID string, timeoutMinutes int) error
 	ClaimPendingRemoteCommands(ctx context.Context, hostID string) ([]models.PendingCommand, error)
+	ReleaseClaimedRemoteCommands(ctx context.Context, hostID string, ids []string) error
 	GetProxmoxGuestLinkByHost(ctx context.Context, hostID string) (*models.ProxmoxGuestLink, error)
 	IsProxmoxGuestDataFresh(ctx context.Context, hostID string) (bool, error)
+	LinkProvisionedGuests(ctx context.Context, hostID string) (int, error)
 	IsHostUsedAsProxmoxCPUTempSource(ctx context.Context, hostID string) bool
 	IsHostUsedAsProxmoxFanRPMSource(ctx context.Context, hostID string) bool
 	UpdateHost(ctx context.Context, id string, update *models.HostUpdate) error
 	InsertUptimeMetrics(ctx context.Context, hostID string, uptime uint64, hostname string) error
 	InsertMetrics(ctx context.Context, m *models.SystemMetrics) (int64, error)
+	GetLatestMetrics(ctx context.Context, hostID string) (*models.SystemMetrics, error)
+	GetMetricsTimestamps(ctx context.Context, hostID string, from, to time.Time) (map[int64]bool, error)
 	UpsertDockerContainers(ctx context.Context, hostID string, containers []models.DockerContainer) error
 	UpsertUUStatus(ctx context.Context, hostID string, s models.UnattendedUpgradesStatus) error
 	InsertUURunIfNew(ctx context.Context, hostID string, run models.UURun) (bool, error)
@@ -62,9 +67,16 @@ type Repository interface {
 	GetHost(ctx context.Context, id string) (*models.Host, error)
 	UpsertDockerNetworks(ctx context.Context, hostID string, networks []models.DockerNetwork) error
 	UpsertComposeProjects(ctx context.Context, hostID string, projects []models.ComposeProject) error
+	StoreDockerDiskUsage(ctx context.Context, hostID string, report *models.DockerDiskUsageReport) error
+	StoreDockerSwarm(ctx context.Context, hostID string, report *models.DockerSwarmReport) error
+	StoreCrowdSecReport(ctx context.Context, hostID string, at time.Time, report *models.CrowdSecReport) error
 	InsertDiskMetrics(ctx context.Context, metrics []models.DiskMetrics) error
 	InsertDiskHealth(ctx context.Context, healthData []models.DiskHealth) error
+	InsertNetInterfaceMetrics(ctx context.Context, hostID string, at time.Time, ifaces []models.NetInterface) error
 	InsertNetworkFlowMetrics(ctx context.Context, hostID string, report *models.NetworkFlowsReport) error
+	InsertDNSQueryMetrics(ctx context.Context, hostID string, at time.Time, stats *models.DNSQueryStats) error
+	ListNetworkFlowPolicies(ctx context.Context, enabledOnly bool) ([]models.NetworkFlowPolicy, error)
+	UpsertNetworkFlowViolations(ctx context.Context, violations []models.NetworkFlowViolation, seenAt time.Time) error
 	UpdateHostCustomTasks(ctx context.Context, hostID, tasksJSON string) error
 	UpdateHostTasksConfigYAML(ctx context.Context, hostID, yaml string) error
 	UpdateHostResticProfiles(ctx context.Context, hostID, profilesJSON string) error
@@ -133,6 +145,12 @@ func (s *Service) ReceiveReport(ctx context.Context, hostID, safeHostID string,
 		if err := s.repo.FailRunningCommandsOnAgentReconnect(ctx, hostID); err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to cleanup running commands on reconnect for host %s: %v", safeHostID, err))
 		}
+		// A host created by a Proxmox provision starts offline: its first
+		// report links it to the cloned guest (if the poller has seen it yet;
+		// otherwise the next Proxmox poll does).
+		if _, err := s.repo.LinkProvisionedGuests(ctx, hostID); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to link provisioned guest for host %s: %v", safeHostID, err))
+		}
 	}
 
 	// This report proves the agent is alive: keep its in-flight commands fresh so the
@@ -171,6 +189,86 @@ func (s *Service) ReceiveReport(ctx context.Context, hostID, safeHostID string,
 	return &ReportResult{Commands: commands, SkipMetrics: proxmoxIsMetricsSource}, nil
 }
 
+// Backfill limits: a batch is what one replay request may carry, and samples
+// older than the 5-minute and hourly aggregates' refresh window (30 days)
+// would never show up in the graphs anyway.
+const (
+	MaxBackfillSamples = 500
+	backfillMaxAge     = 30 * 24 * time.Hour
+)
+
+// Backfill stores metric samples the agent buffered while the server was
+// unreachable, with their original timestamps. It never touches the host's
+// status or last-seen time, and a sample is only kept if it is older than the
+// host's latest stored metrics: the alert engine reads the latest sample
+// only, so a replayed one can neither fire nor resolve an alert. Samples
+// already stored (a replay whose response was lost) are skipped.
+func (s *Service) Backfill(ctx context.Context, hostID string, samples []models.BackfillSample) (*models.BackfillResult, error) {
+	if len(samples) > MaxBackfillSamples {
+		return nil, apperr.Validation(fmt.Sprintf("too many samples (max %d)", MaxBackfillSamples))
+	}
+	res := &models.BackfillResult{}
+	if len(samples) == 0 {
+		return res, nil
+	}
+	// With Proxmox as the metrics source the agent's own CPU/RAM are not
+	// stored, live or replayed.
+	if s.proxmoxIsMetricsSource(ctx, hostID) {
+		res.Skipped = len(samples)
+		return res, nil
+	}
+
+	now := time.Now()
+	before := now
+	if latest, err := s.repo.GetLatestMetrics(ctx, hostID); err == nil && latest != nil {
+		before = latest.Timestamp
+	}
+	oldest := now.Add(-backfillMaxAge)
+
+	from, to := before, oldest
+	for i := range samples {
+		ts := samples[i].Timestamp.Truncate(time.Microsecond)
+		samples[i].Timestamp = ts
+		if ts.Before(from) {
+			from = ts
+		}
+		if ts.After(to) {
+			to = ts
+		}
+	}
+	stored, err := s.repo.GetMetricsTimestamps(ctx, hostID, from, to)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	for _, sample := range samples {
+		ts := sample.Timestamp
+		if sample.Metrics == nil || ts.Before(oldest) || !ts.Before(before) || stored[ts.UnixMicro()] {
+			res.Skipped++
+			continue
+		}
+		sample.Metrics.HostID = hostID
+		sample.Metrics.Timestamp = ts
+		if _, err := s.repo.InsertMetrics(ctx, sample.Metrics); err != nil {
+			return nil, apperr.Internal(err)
+		}
+		stored[ts.UnixMicro()] = true
+		for i := range sample.DiskMetrics {
+			sample.DiskMetrics[i].HostID = hostID
+			sample.DiskMetrics[i].Timestamp = ts
+		}
+		if err := s.repo.InsertDiskMetrics(ctx, sample.DiskMetrics); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to backfill disk metrics for host %s: %v", hostID, err))
+		}
+		res.Accepted++
+	}
+
+	if res.Accepted > 0 {
+		s.bus.Publish(events.HostTopic(hostID))
+	}
+	return res, nil
+}
+
 // ReportCommandResult records a command's terminal result, updates linked records,
 // streams the status and fans out to completion listeners.
 func (s *Service) ReportCommandResult(ctx context.Context, hostID string, result models.CommandResult) error {
@@ -178,6 +276,12 @@ func (s *Service) ReportCommandResult(ctx context.Context, hostID string, result
 	if err != nil || cmd.HostID != hostID {
 		return apperr.Forbidden("command does not belong to host")
 	}
+	// An agent whose result ack was lost resends it (push channel resume, then
+	// the HTTP fallback): a terminal result already recorded as is must not
+	// fan out to the completion listeners a second time.
+	if isTerminalStatus(result.Status) && cmd.Status == result.Status && cmd.Output == result.Output {
+		return nil
+	}
 	if err := s.repo.UpdateRemoteCommandStatus(ctx, result.CommandID, result.Status, result.Output); err != nil {
 		return apperr.Failed("failed to update command")
 	}
@@ -251,6 +355,22 @@ func (s *Service) ReportCommandResult(ctx context.Context, hostID string, result
 	return nil
 }
 
+// ClaimCommands claims hostID's pending commands for delivery over its push
+// connection, exactly like a report would.
+func (s *Service) ClaimCommands(ctx context.Context, hostID string) ([]models.PendingCommand, error) {
+	return s.repo.ClaimPendingRemoteCommands(ctx, hostID)
+}
+
+// ReleaseCommands returns claimed commands the agent never acknowledged to
+// pending, for the next push or report to deliver them again.
+func (s *Service) ReleaseCommands(ctx context.Context, hostID string, ids []string) error {
+	return s.repo.ReleaseClaimedRemoteCommands(ctx, hostID, ids)
+}
+
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed"
+}
+
 // StreamCommandOutput relays a live output chunk after verifying host ownership.
 func (s *Service) StreamCommandOutput(ctx context.Context, hostID, commandID, chunk string) error {
 	cmd, err := s.repo.GetRemoteCommandByID(ctx, commandID)
@@ -446,6 +566,31 @@ func (s *Service) storeMetrics(ctx context.Context, hostID string, report *model
 	return nil
 }
 
+// checkNetworkFlowPolicies records the talkers of the report that break an
+// enabled network flow policy applying to the host (internal/netpolicy).
+func (s *Service) checkNetworkFlowPolicies(ctx context.Context, hostID string, report *models.NetworkFlowsReport) error {
+	if len(report.TopTalkers) == 0 {
+		return nil
+	}
+	policies, err := s.repo.ListNetworkFlowPolicies(ctx, true)
+	if err != nil || len(policies) == 0 {
+		return err
+	}
+	host, err := s.repo.GetHost(ctx, hostID)
+	if err != nil || host == nil {
+		return err
+	}
+	violations := netpolicy.Check(policies, *host, report.TopTalkers)
+	if len(violations) == 0 {
+		return nil
+	}
+	seenAt := report.CollectedAt
+	if seenAt.IsZero() {
+		seenAt = time.Now()
+	}
+	return s.repo.UpsertNetworkFlowViolations(ctx, violations, seenAt)
+}
+
 // applyUUReport upserts a host's unattended-upgrades status and inserts any
 // newly-seen runs (cursor-based, so already-recorded runs are skipped).
 // Shared by both places a fresh UnattendedUpgradesStatus can arrive: every
@@ -533,6 +678,26 @@ func (s *Service) storeContainersAndPackages(ctx context.Context, hostID, safeHo
 		}
 	}
 
+	// Only present when the agent re-measured (every ~15 min).
+	if report.DockerDiskUsage != nil {
+		if err := s.repo.StoreDockerDiskUsage(ctx, hostID, report.DockerDiskUsage); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store docker disk usage for host %s: %v", safeHostID, err))
+		}
+	}
+
+	if report.DockerSwarm != nil {
+		if err := s.repo.StoreDockerSwarm(ctx, hostID, report.DockerSwarm); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store docker swarm state for host %s: %v", safeHostID, err))
+		}
+	}
+
+	// Only present when the agent queried its local CrowdSec API (every minute).
+	if report.CrowdSec != nil {
+		if err := s.repo.StoreCrowdSecReport(ctx, hostID, time.Now(), report.CrowdSec); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store crowdsec snapshot for host %s: %v", safeHostID, err))
+		}
+	}
+
 	if report.Restic != nil {
 		if err := s.repo.UpsertResticStatus(ctx, hostID, report.Restic); err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store restic status for host %s: %v", safeHostID, err))
@@ -562,6 +727,12 @@ func (s *Service) storeDiskAndMetadata(ctx context.Context, hostID, safeHostID s
 		}
 	}
 
+	if len(report.NetInterfaces) > 0 {
+		if err := s.repo.InsertNetInterfaceMetrics(ctx, hostID, time.Now(), report.NetInterfaces); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store network interface metrics for host %s: %v", safeHostID, err))
+		}
+	}
+
 	// report.NetworkFlows is always sent when collect_network_flows is enabled
 	// (even Available=false — a capability flag, not an error), but only ever
 	// worth a write when it actually carries talkers/others for this cycle.
@@ -569,6 +740,12 @@ func (s *Service) storeDiskAndMetadata(ctx context.Context, hostID, safeHostID s
 		if err := s.repo.InsertNetworkFlowMetrics(ctx, hostID, report.NetworkFlows); err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store network flow metrics for host %s: %v", safeHostID, err))
 		}
+		if err := s.checkNetworkFlowPolicies(ctx, hostID, report.NetworkFlows); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to check network flow policies for host %s: %v", safeHostID, err))
+		}
+		if err := s.repo.InsertDNSQueryMetrics(ctx, hostID, report.NetworkFlows.CollectedAt, report.NetworkFlows.DNS); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Warning: failed to store DNS query metrics for host %s: %v", safeHostID, err))
+		}
 	}
 
 	if report.CustomTasks != nil {
@@ -32,6 +32,9 @@ type fakeRepo struct {
 	updatedSchedStatus string
 	createdCompleted   bool
 	createdAuditAction string
+	insertedMetrics    []models.SystemMetrics
+	latestMetrics      *models.SystemMetrics
+	storedTimestamps   []time.Time
 }
 
 func (f *fakeRepo) GetHostStatus(context.Context, string) string                      { return "online" }
@@ -42,12 +45,16 @@ func (f *fakeRepo) CleanupHostStalledCommands(context.Context, string, int) erro
 func (f *fakeRepo) ClaimPendingRemoteCommands(context.Context, string) ([]models.PendingCommand, error) {
 	return nil, nil
 }
+func (f *fakeRepo) ReleaseClaimedRemoteCommands(context.Context, string, []string) error {
+	return nil
+}
 func (f *fakeRepo) GetProxmoxGuestLinkByHost(context.Context, string) (*models.ProxmoxGuestLink, error) {
 	return f.guestLink, nil
 }
 func (f *fakeRepo) IsProxmoxGuestDataFresh(context.Context, string) (bool, error) {
 	return f.dataFresh, nil
 }
+func (f *fakeRepo) LinkProvisionedGuests(context.Context, string) (int, error) { return 0, nil }
 func (f *fakeRepo) IsHostUsedAsProxmoxCPUTempSource(context.Context, string) bool {
 	return f.cpuTempSource
 }
@@ -56,9 +63,23 @@ func (f *fakeRepo) IsHostUsedAsProxmoxFanRPMSource(context.Context, string) bool
 }
 func (f *fakeRepo) UpdateHost(context.Context, string, *models.HostUpdate) error      { return nil }
 func (f *fakeRepo) InsertUptimeMetrics(context.Context, string, uint64, string) error { return nil }
-func (f *fakeRepo) InsertMetrics(context.Context, *models.SystemMetrics) (int64, error) {
+func (f *fakeRepo) InsertMetrics(_ context.Context, m *models.SystemMetrics) (int64, error) {
+	f.insertedMetrics = append(f.insertedMetrics, *m)
 	return 1, nil
 }
+func (f *fakeRepo) GetLatestMetrics(context.Context, string) (*models.SystemMetrics, error) {
+	if f.latestMetrics == nil {
+		return nil, apperr.NotFound("no metrics")
+	}
+	return f.latestMetrics, nil
+}
+func (f *fakeRepo) GetMetricsTimestamps(context.Context, string, time.Time, time.Time) (map[int64]bool, error) {
+	out := map[int64]bool{}
+	for _, ts := range f.storedTimestamps {
+		out[ts.UnixMicro()] = true
+	}
+	return out, nil
+}
 func (f *fakeRepo) UpsertDockerContainers(context.Context, string, []models.DockerContainer) error {
 	return nil
 }
@@ -82,11 +103,32 @@ func (f *fakeRepo) UpsertDockerNetworks(context.Context, string, []models.Docker
 func (f *fakeRepo) UpsertComposeProjects(context.Context, string, []models.ComposeProject) error {
 	return nil
 }
+func (f *fakeRepo) StoreDockerDiskUsage(context.Context, string, *models.DockerDiskUsageReport) error {
+	return nil
+}
+func (f *fakeRepo) StoreDockerSwarm(context.Context, string, *models.DockerSwarmReport) error {
+	return nil
+}
+func (f *fakeRepo) StoreCrowdSecReport(context.Context, string, time.Time, *models.CrowdSecReport) error {
+	return nil
+}
 func (f *fakeRepo) InsertDiskMetrics(context.Context, []models.DiskMetrics) error { return nil }
 func (f *fakeRepo) InsertDiskHealth(context.Context, []models.DiskHealth) error   { return nil }
+func (f *fakeRepo) InsertNetInterfaceMetrics(context.Context, string, time.Time, []models.NetInterface) error {
+	return nil
+}
 func (f *fakeRepo) InsertNetworkFlowMetrics(context.Context, string, *models.NetworkFlowsReport) error {
 	return nil
 }
+func (f *fakeRepo) InsertDNSQueryMetrics(context.Context, string, time.Time, *models.DNSQueryStats) error {
+	return nil
+}
+func (f *fakeRepo) ListNetworkFlowPolicies(context.Context, bool) ([]models.NetworkFlowPolicy, error) {
+	return nil, nil
+}
+func (f *fakeRepo) UpsertNetworkFlowViolations(context.Context, []models.NetworkFlowViolation, time.Time) error {
+	return nil
+}
 func (f *fakeRepo) UpdateHostCustomTasks(context.Context, string, string) error     { return nil }
 func (f *fakeRepo) UpdateHostTasksConfigYAML(context.Context, string, string) error { return nil }
 func (f *fakeRepo) UpdateHostResticProfiles(context.Context, string, string) error  { return nil }
@@ -475,3 +517,70 @@ func TestLogAuditAction_WithoutModuleNoCommand(t *testing.T) {
 type listenerFunc func(commandID, status string)
 
 func (f listenerFunc) HandleCommandCompletion(commandID, status string) { f(commandID, status) }
+
+func TestReportCommandResult_IgnoresResentTerminalResult(t *testing.T) {
+	// The agent resends a result whose ack it never got; the server already
+	// recorded it, so nothing may run twice.
+	hub := &recordingStreamHub{}
+	repo := &fakeRepo{cmd: &models.RemoteCommand{HostID: "h1", Module: "docker", Status: "completed", Output: "ok"}}
+	s := newSvc(repo, hub)
+	if err := s.ReportCommandResult(context.Background(), "h1", models.CommandResult{CommandID: "c1", Status: "completed", Output: "ok"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updatedCmdStatus != "" || hub.statusBroadcasts != 0 {
+		t.Errorf("a duplicate result was applied again: status=%q broadcasts=%d", repo.updatedCmdStatus, hub.statusBroadcasts)
+	}
+
+	// A different terminal result (e.g. the agent's own after the stalled
+	// reaper failed the command) still lands.
+	if err := s.ReportCommandResult(context.Background(), "h1", models.CommandResult{CommandID: "c1", Status: "failed", Output: "boom"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.updatedCmdStatus != "failed" {
+		t.Errorf("status = %q, want failed", repo.updatedCmdStatus)
+	}
+}
+
+func TestBackfill_KeepsOnlyOlderUnstoredSamples(t *testing.T) {
+	now := time.Now()
+	latest := now.Add(-time.Minute)
+	already := now.Add(-20 * time.Minute).Truncate(time.Microsecond)
+	repo := &fakeRepo{
+		latestMetrics:    &models.SystemMetrics{Timestamp: latest},
+		storedTimestamps: []time.Time{already},
+	}
+	s := newSvc(repo, &recordingStreamHub{})
+
+	sample := func(ts time.Time) models.BackfillSample {
+		return models.BackfillSample{Timestamp: ts, Metrics: &models.SystemMetrics{CPUUsagePercent: 50},
+			DiskMetrics: []models.DiskMetrics{{MountPoint: "/"}}}
+	}
+	res, err := s.Backfill(context.Background(), "h1", []models.BackfillSample{
+		sample(now.Add(-30 * time.Minute)),     // kept
+		sample(already),                        // replayed twice
+		sample(now.Add(-10 * time.Minute)),     // kept
+		sample(now),                            // not older than the live data
+		sample(now.Add(-40 * 24 * time.Hour)),  // beyond the aggregates' window
+		{Timestamp: now.Add(-5 * time.Minute)}, // no metrics
+	})
+	if err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if res.Accepted != 2 || res.Skipped != 4 {
+		t.Fatalf("accepted %d skipped %d, want 2 and 4", res.Accepted, res.Skipped)
+	}
+	for _, m := range repo.insertedMetrics {
+		if m.HostID != "h1" || !m.Timestamp.Before(latest) {
+			t.Errorf("stored %+v, want host h1 and its original, older timestamp", m)
+		}
+	}
+}
+
+func TestBackfill_RejectsOversizedBatch(t *testing.T) {
+	s := newSvc(&fakeRepo{}, &recordingStreamHub{})
+	_, err := s.Backfill(context.Background(), "h1", make([]models.BackfillSample, MaxBackfillSamples+1))
+	var ae *apperr.Error
+	if !errors.As(err, &ae) || ae.HTTPStatus != 400 {
+		t.Fatalf("want a validation error, got %v", err)
+	}
+}
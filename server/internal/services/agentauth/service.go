@@ -0,0 +1,375 @@
+// Package agentauth is the application/service layer for strong agent
+// authentication, on top of the per-host API key: per-host client
+// certificates for mTLS (issued, renewed and revoked by the server's own CA,
+// see internal/agentpki) and Ed25519 request signatures for agents behind a
+// TLS-terminating proxy. Once a host has enrolled either, its API key alone is
+// refused until an admin resets its credentials — even once they expired — so
+// a leaked key can no longer be replayed.
+package agentauth
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/agentpki"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/config"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// How an agent request was authenticated; the middleware stores it in the gin
+// context under "agent_auth".
+const (
+	MethodAPIKey      = "api_key"
+	MethodCertificate = "mtls"
+	MethodSignature   = "signature"
+)
+
+const (
+	// supersededGrace is how long a credential stays valid once the host
+	// enrolled its replacement, for the requests already in flight.
+	supersededGrace = 10 * time.Minute
+	// signatureWindow bounds the clock difference accepted on a signed
+	// request, and how long its nonce is remembered against replays.
+	signatureWindow = 5 * time.Minute
+	maxNonceLength  = 64
+)
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	GetAgentCA(ctx context.Context) (certPEM, keyPEM string, err error)
+	InsertAgentCAIfAbsent(ctx context.Context, certPEM, keyPEM string) error
+	CreateAgentCredential(ctx context.Context, c *models.AgentCredential) (*models.AgentCredential, error)
+	ListAgentCredentials(ctx context.Context, hostID string) ([]models.AgentCredential, error)
+	GetActiveAgentCredentials(ctx context.Context, hostID, kind string) ([]models.AgentCredential, error)
+	GetAgentCredentialBySerial(ctx context.Context, serial string) (*models.AgentCredential, error)
+	AgentStrongAuthRequired(ctx context.Context, hostID string) (bool, error)
+	RequireAgentStrongAuth(ctx context.Context, hostID string) error
+	ResetAgentCredentials(ctx context.Context, hostID string) (bool, error)
+	RecordAgentRequestNonce(ctx context.Context, key string, at time.Time) (bool, error)
+	DeleteAgentRequestNoncesBefore(ctx context.Context, t time.Time) error
+	SupersedeAgentCredentials(ctx context.Context, hostID, kind, keepID string, until time.Time) error
+	RevokeAgentCredential(ctx context.Context, hostID, id string) (bool, error)
+	CreateAuditLog(ctx context.Context, username, action, hostID, ipAddress, details, status string) (int64, error)
+}
+
+// Service holds the agent authentication use-cases.
+type Service struct {
+	repo       Repository
+	mtlsURL    string
+	certTTL    time.Duration
+	disconnect func(hostID string)
+	now        func() time.Time
+
+	caMu sync.Mutex
+	ca   *agentpki.CA
+
+	// sharedNonces records nonces in the database rather than in nonces, so
+	// that every HA replica sees them.
+	sharedNonces bool
+	nonces       nonceCache
+}
+
+// NewService wires the service. mTLS enrollment is available only when
+// AGENT_MTLS_URL is set (the listener the issued certificates are for). With
+// HA_ENABLED, the nonces of signed requests are shared through the database.
+func NewService(repo Repository, cfg *config.Config) *Service {
+	return &Service{
+		repo:    repo,
+		mtlsURL: cfg.AgentMTLSURL,
+		certTTL: max(cfg.AgentCertTTL, time.Hour),
+		now:     time.Now,
+
+		sharedNonces: cfg.HAEnabled,
+		nonces:       nonceCache{seen: make(map[string]time.Time)},
+	}
+}
+
+// SetDisconnect registers how to drop a host's live agent connection, so a
+// revocation also ends the WebSocket it authenticated.
+func (s *Service) SetDisconnect(fn func(hostID string)) {
+	s.disconnect = fn
+}
+
+// MTLSEnabled reports whether certificate enrollment is available.
+func (s *Service) MTLSEnabled() bool {
+	return s.mtlsURL != ""
+}
+
+// CA returns the agent CA, creating it on first use. Concurrent replicas
+// racing to create it all end up with the one stored first.
+func (s *Service) CA(ctx context.Context) (*agentpki.CA, error) {
+	s.caMu.Lock()
+	defer s.caMu.Unlock()
+	if s.ca != nil {
+		return s.ca, nil
+	}
+	certPEM, keyPEM, err := s.repo.GetAgentCA(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		newCert, newKey, genErr := agentpki.NewCA()
+		if genErr != nil {
+			return nil, genErr
+		}
+		if err := s.repo.InsertAgentCAIfAbsent(ctx, string(newCert), string(newKey)); err != nil {
+			return nil, err
+		}
+		certPEM, keyPEM, err = s.repo.GetAgentCA(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ca, err := agentpki.ParseCA([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, err
+	}
+	s.ca = ca
+	return ca, nil
+}
+
+// Enroll registers a new credential for hostID. With method MethodAPIKey this
+// is the first enrollment, refused once the host enrolled (until an admin
+// Reset); with a strong method it is a renewal, and the credentials of the
+// same kind it replaces expire after a short grace period.
+func (s *Service) Enroll(ctx context.Context, hostID, method string, req models.AgentEnrollRequest) (*models.AgentEnrollResponse, error) {
+	if method == MethodAPIKey {
+		enrolled, err := s.repo.AgentStrongAuthRequired(ctx, hostID)
+		if err != nil {
+			return nil, apperr.Internal(err)
+		}
+		if enrolled {
+			return nil, apperr.Conflict("cet hôte a déjà enrôlé un certificat ou une clé de signature : le renouvellement doit être authentifié par celui-ci, ou un admin doit réinitialiser ses identifiants")
+		}
+	}
+
+	cred := &models.AgentCredential{HostID: hostID, Kind: req.Kind}
+	resp := &models.AgentEnrollResponse{Kind: req.Kind}
+	switch req.Kind {
+	case models.AgentCredentialCertificate:
+		if !s.MTLSEnabled() {
+			return nil, apperr.Validation("mTLS non activé sur ce serveur (AGENT_MTLS_URL)")
+		}
+		ca, err := s.CA(ctx)
+		if err != nil {
+			return nil, apperr.Internal(err)
+		}
+		issued, err := ca.IssueClientCert([]byte(req.CSR), hostID, s.certTTL)
+		if err != nil {
+			return nil, apperr.Validation(err.Error())
+		}
+		cred.Serial = issued.Serial
+		cred.Fingerprint = issued.Fingerprint
+		cred.NotAfter = &issued.NotAfter
+		resp.Certificate = string(issued.PEM)
+		resp.CACertificate = string(ca.CertPEM())
+		resp.MTLSURL = s.mtlsURL
+		resp.NotAfter = &issued.NotAfter
+	case models.AgentCredentialEd25519:
+		pub, err := agentpki.ParsePublicKey(req.PublicKey)
+		if err != nil {
+			return nil, apperr.Validation(err.Error())
+		}
+		cred.PublicKey = req.PublicKey
+		cred.Fingerprint = agentpki.PublicKeyFingerprint(pub)
+	default:
+		return nil, apperr.Validation("kind doit être 'certificate' ou 'ed25519'")
+	}
+
+	stored, err := s.repo.CreateAgentCredential(ctx, cred)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := s.repo.RequireAgentStrongAuth(ctx, hostID); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if err := s.repo.SupersedeAgentCredentials(ctx, hostID, req.Kind, stored.ID, s.now().Add(supersededGrace)); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	resp.CredentialID = stored.ID
+	return resp, nil
+}
+
+// AuthenticateCertificate returns the host a verified client certificate
+// belongs to. The chain was checked by the TLS handshake; this checks the
+// certificate was issued to that host and is neither revoked nor superseded.
+func (s *Service) AuthenticateCertificate(ctx context.Context, cert *x509.Certificate) (string, error) {
+	cred, err := s.repo.GetAgentCredentialBySerial(ctx, agentpki.SerialHex(cert.SerialNumber))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", apperr.Unauthorized("unknown client certificate")
+		}
+		return "", apperr.Internal(err)
+	}
+	if cred.Kind != models.AgentCredentialCertificate || cred.HostID != cert.Subject.CommonName {
+		return "", apperr.Unauthorized("client certificate does not match its host")
+	}
+	if !cred.Active(s.now()) {
+		return "", apperr.Unauthorized("client certificate revoked or expired")
+	}
+	return cred.HostID, nil
+}
+
+// AuthenticateSignature checks a signed agent request: a timestamp within the
+// window, a nonce not seen in it, and a signature by one of the host's active
+// Ed25519 keys.
+func (s *Service) AuthenticateSignature(ctx context.Context, hostID, method, path, timestamp, nonce, signature string, body []byte) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return apperr.Unauthorized("invalid signature timestamp")
+	}
+	now := s.now()
+	if d := now.Sub(time.Unix(ts, 0)); d > signatureWindow || d < -signatureWindow {
+		return apperr.Unauthorized("signature timestamp outside the accepted window (check the agent's clock)")
+	}
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return apperr.Unauthorized("invalid signature nonce")
+	}
+	creds, err := s.repo.GetActiveAgentCredentials(ctx, hostID, models.AgentCredentialEd25519)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	msg := agentpki.SigningString(method, path, timestamp, nonce, body)
+	for _, c := range creds {
+		pub, err := agentpki.ParsePublicKey(c.PublicKey)
+		if err != nil || !agentpki.VerifySignature(pub, msg, signature) {
+			continue
+		}
+		// Only a valid signature consumes the nonce, so garbage cannot
+		// poison the cache.
+		fresh, err := s.recordNonce(ctx, hostID+":"+nonce, now)
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		if !fresh {
+			return apperr.Unauthorized("replayed request")
+		}
+		return nil
+	}
+	return apperr.Unauthorized("invalid signature")
+}
+
+// RequiresStrongAuth reports whether hostID must authenticate with its
+// certificate or signature rather than its API key alone: it enrolled once
+// and was not reset since, whether or not a credential is still valid.
+func (s *Service) RequiresStrongAuth(ctx context.Context, hostID string) (bool, error) {
+	return s.repo.AgentStrongAuthRequired(ctx, hostID)
+}
+
+// List returns the host's credentials, newest first (never nil).
+func (s *Service) List(ctx context.Context, hostID string) ([]models.AgentCredential, error) {
+	creds, err := s.repo.ListAgentCredentials(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = []models.AgentCredential{}
+	}
+	return creds, nil
+}
+
+// Revoke revokes one credential and drops the host's live agent connection.
+// The host still refuses its API key alone: only Reset lets it enroll again.
+func (s *Service) Revoke(ctx context.Context, hostID, id, username, clientIP string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.NotFound("identifiant introuvable")
+	}
+	ok, err := s.repo.RevokeAgentCredential(ctx, hostID, id)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("identifiant introuvable ou déjà révoqué")
+	}
+	if s.disconnect != nil {
+		s.disconnect(hostID)
+	}
+	s.audit(ctx, username, "agent_credential_revoke", hostID, clientIP, "credential "+id+" revoked")
+	return nil
+}
+
+// Reset revokes every credential of the host and puts it back on its API key
+// alone, so its agent can enroll again — the way back for a host whose
+// certificate expired or whose signing key was lost. Rotate the API key too if
+// it may have leaked.
+func (s *Service) Reset(ctx context.Context, hostID, username, clientIP string) error {
+	ok, err := s.repo.ResetAgentCredentials(ctx, hostID)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("hôte introuvable")
+	}
+	if s.disconnect != nil {
+		s.disconnect(hostID)
+	}
+	s.audit(ctx, username, "agent_credentials_reset", hostID, clientIP, "all credentials revoked, API key enrollment allowed again")
+	return nil
+}
+
+func (s *Service) audit(ctx context.Context, username, action, hostID, clientIP, details string) {
+	if _, err := s.repo.CreateAuditLog(ctx, username, action, hostID, clientIP, details, "success"); err != nil {
+		slog.WarnContext(ctx, "agent auth: failed to write audit log", slog.String("action", action), slog.Any("err", err))
+	}
+}
+
+// recordNonce records the nonce of a valid signed request, returning false
+// when it was already used in the window: in the database shared by the
+// replicas with HA, in memory otherwise.
+func (s *Service) recordNonce(ctx context.Context, key string, now time.Time) (bool, error) {
+	if !s.sharedNonces {
+		return s.nonces.add(key, now), nil
+	}
+	if s.nonces.duePrune(now) {
+		if err := s.repo.DeleteAgentRequestNoncesBefore(ctx, now.Add(-2*signatureWindow)); err != nil {
+			return false, err
+		}
+	}
+	return s.repo.RecordAgentRequestNonce(ctx, key, now)
+}
+
+// nonceCache remembers the nonces of signed requests for signatureWindow on a
+// single server. With HA the nonces are in the database and the cache only
+// paces their pruning.
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+// add records key, returning false when it was already seen in the window.
+func (c *nonceCache) add(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastPrune) > time.Minute {
+		for k, at := range c.seen {
+			if now.Sub(at) > 2*signatureWindow {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPrune = now
+	}
+	if _, dup := c.seen[key]; dup {
+		return false
+	}
+	c.seen[key] = now
+	return true
+}
+
+// duePrune reports, at most once a minute, that the shared nonces are due for
+// pruning.
+func (c *nonceCache) duePrune(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastPrune) <= time.Minute {
+		return false
+	}
+	c.lastPrune = now
+	return true
+}
@@ -0,0 +1,356 @@
+package agentauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/agentpki"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/config"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// fakeRepo keeps the CA and credentials in memory, with the same activity
+// rules as the SQL queries.
+type fakeRepo struct {
+	caCert, caKey string
+	creds         []*models.AgentCredential
+	strong        map[string]bool
+	nonces        map[string]time.Time
+	audits        []string
+}
+
+func (f *fakeRepo) GetAgentCA(context.Context) (string, string, error) {
+	if f.caCert == "" {
+		return "", "", sql.ErrNoRows
+	}
+	return f.caCert, f.caKey, nil
+}
+
+func (f *fakeRepo) InsertAgentCAIfAbsent(_ context.Context, certPEM, keyPEM string) error {
+	if f.caCert == "" {
+		f.caCert, f.caKey = certPEM, keyPEM
+	}
+	return nil
+}
+
+func (f *fakeRepo) CreateAgentCredential(_ context.Context, c *models.AgentCredential) (*models.AgentCredential, error) {
+	stored := *c
+	stored.ID = uuid.NewString()
+	stored.CreatedAt = time.Now()
+	f.creds = append(f.creds, &stored)
+	return &stored, nil
+}
+
+func (f *fakeRepo) ListAgentCredentials(_ context.Context, hostID string) ([]models.AgentCredential, error) {
+	var out []models.AgentCredential
+	for _, c := range f.creds {
+		if c.HostID == hostID {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) GetActiveAgentCredentials(_ context.Context, hostID, kind string) ([]models.AgentCredential, error) {
+	var out []models.AgentCredential
+	for _, c := range f.creds {
+		if c.HostID == hostID && c.Kind == kind && c.Active(time.Now()) {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) GetAgentCredentialBySerial(_ context.Context, serial string) (*models.AgentCredential, error) {
+	for _, c := range f.creds {
+		if c.Serial == serial {
+			cp := *c
+			return &cp, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) AgentStrongAuthRequired(_ context.Context, hostID string) (bool, error) {
+	return f.strong[hostID], nil
+}
+
+func (f *fakeRepo) RequireAgentStrongAuth(_ context.Context, hostID string) error {
+	if f.strong == nil {
+		f.strong = map[string]bool{}
+	}
+	f.strong[hostID] = true
+	return nil
+}
+
+func (f *fakeRepo) ResetAgentCredentials(_ context.Context, hostID string) (bool, error) {
+	delete(f.strong, hostID)
+	now := time.Now()
+	for _, c := range f.creds {
+		if c.HostID == hostID && c.RevokedAt == nil {
+			c.RevokedAt = &now
+		}
+	}
+	return true, nil
+}
+
+func (f *fakeRepo) SupersedeAgentCredentials(_ context.Context, hostID, kind, keepID string, until time.Time) error {
+	for _, c := range f.creds {
+		if c.HostID == hostID && c.Kind == kind && c.ID != keepID && c.RevokedAt == nil {
+			if c.NotAfter == nil || c.NotAfter.After(until) {
+				u := until
+				c.NotAfter = &u
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) RevokeAgentCredential(_ context.Context, hostID, id string) (bool, error) {
+	for _, c := range f.creds {
+		if c.HostID == hostID && c.ID == id && c.RevokedAt == nil {
+			now := time.Now()
+			c.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRepo) CreateAuditLog(_ context.Context, username, action, hostID, ipAddress, _, _ string) (int64, error) {
+	f.audits = append(f.audits, strings.Join([]string{username, action, hostID, ipAddress}, " "))
+	return int64(len(f.audits)), nil
+}
+
+func (f *fakeRepo) RecordAgentRequestNonce(_ context.Context, key string, at time.Time) (bool, error) {
+	if _, dup := f.nonces[key]; dup {
+		return false, nil
+	}
+	if f.nonces == nil {
+		f.nonces = map[string]time.Time{}
+	}
+	f.nonces[key] = at
+	return true, nil
+}
+
+func (f *fakeRepo) DeleteAgentRequestNoncesBefore(_ context.Context, t time.Time) error {
+	for k, at := range f.nonces {
+		if at.Before(t) {
+			delete(f.nonces, k)
+		}
+	}
+	return nil
+}
+
+func newTestService(repo *fakeRepo) *Service {
+	return NewService(repo, &config.Config{AgentMTLSURL: "https://supervisor.example.com:8443", AgentCertTTL: 24 * time.Hour})
+}
+
+func newCSR(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "agent"}}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var ae *apperr.Error
+	if !errors.As(err, &ae) || ae.HTTPStatus != status {
+		t.Fatalf("err = %v, want HTTP %d", err, status)
+	}
+}
+
+func TestEnroll_CertificateLifecycle(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+	ctx := context.Background()
+	var disconnected []string
+	s.SetDisconnect(func(hostID string) { disconnected = append(disconnected, hostID) })
+
+	resp, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	if err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	if resp.MTLSURL == "" || resp.CACertificate == "" {
+		t.Fatalf("response misses the mTLS URL or the CA: %+v", resp)
+	}
+	cert, err := agentpki.ParseCertificatePEM([]byte(resp.Certificate))
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	if host, err := s.AuthenticateCertificate(ctx, cert); err != nil || host != "host-1" {
+		t.Fatalf("authenticate: host=%q err=%v", host, err)
+	}
+
+	// Enrolled: the API key can no longer enroll again.
+	if strong, _ := s.RequiresStrongAuth(ctx, "host-1"); !strong {
+		t.Fatal("an enrolled host must require strong auth")
+	}
+	_, err = s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	wantStatus(t, err, 409)
+
+	// Renewal over mTLS: the previous certificate only gets a grace period.
+	renewed, err := s.Enroll(ctx, "host-1", MethodCertificate, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	if err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	old, _ := repo.GetAgentCredentialBySerial(ctx, agentpki.SerialHex(cert.SerialNumber))
+	if old.NotAfter == nil || time.Until(*old.NotAfter) > supersededGrace {
+		t.Errorf("superseded certificate expires at %v, want within the grace period", old.NotAfter)
+	}
+
+	if err := s.Revoke(ctx, "host-1", renewed.CredentialID, "admin", "10.0.0.1"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if len(disconnected) != 1 {
+		t.Error("a revocation must drop the live agent connection")
+	}
+	if len(repo.audits) != 1 || repo.audits[0] != "admin agent_credential_revoke host-1 10.0.0.1" {
+		t.Errorf("audit = %q, want the revocation logged", repo.audits)
+	}
+	newCert, _ := agentpki.ParseCertificatePEM([]byte(renewed.Certificate))
+	_, err = s.AuthenticateCertificate(ctx, newCert)
+	wantStatus(t, err, 401)
+	wantStatus(t, s.Revoke(ctx, "host-1", renewed.CredentialID, "admin", "10.0.0.1"), 404)
+}
+
+// TestEnroll_ExpiredCredentialKeepsAPIKeyRefused: a host whose certificate
+// expired without renewal must not fall back to its API key; only an admin
+// reset lets it enroll again.
+func TestEnroll_ExpiredCredentialKeepsAPIKeyRefused(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+	ctx := context.Background()
+	var disconnected []string
+	s.SetDisconnect(func(hostID string) { disconnected = append(disconnected, hostID) })
+
+	if _, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)}); err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	expired := time.Now().Add(-time.Hour)
+	repo.creds[0].NotAfter = &expired
+
+	if strong, _ := s.RequiresStrongAuth(ctx, "host-1"); !strong {
+		t.Fatal("an expired certificate must not put the host back on its API key")
+	}
+	_, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	wantStatus(t, err, 409)
+
+	if err := s.Reset(ctx, "host-1", "admin", "10.0.0.1"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if len(disconnected) != 1 || repo.creds[0].RevokedAt == nil {
+		t.Error("a reset must revoke every credential and drop the live agent connection")
+	}
+	if len(repo.audits) != 1 || repo.audits[0] != "admin agent_credentials_reset host-1 10.0.0.1" {
+		t.Errorf("audit = %q, want the reset logged", repo.audits)
+	}
+	if strong, _ := s.RequiresStrongAuth(ctx, "host-1"); strong {
+		t.Fatal("a reset host is back on its API key")
+	}
+	if _, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)}); err != nil {
+		t.Fatalf("enroll after reset: %v", err)
+	}
+}
+
+func TestEnroll_CertificateNeedsMTLS(t *testing.T) {
+	s := NewService(&fakeRepo{}, &config.Config{AgentCertTTL: time.Hour})
+	_, err := s.Enroll(context.Background(), "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	wantStatus(t, err, 400)
+}
+
+func TestAuthenticateCertificate_RejectsAnotherHostsSerial(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+	ctx := context.Background()
+	resp, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{Kind: models.AgentCredentialCertificate, CSR: newCSR(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := agentpki.ParseCertificatePEM([]byte(resp.Certificate))
+	cert.Subject.CommonName = "host-2"
+	_, err = s.AuthenticateCertificate(ctx, cert)
+	wantStatus(t, err, 401)
+}
+
+func TestAuthenticateSignature(t *testing.T) {
+	repo := &fakeRepo{}
+	s := newTestService(repo)
+	ctx := context.Background()
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := s.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{
+		Kind: models.AgentCredentialEd25519, PublicKey: base64.StdEncoding.EncodeToString(pub),
+	}); err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+
+	body := []byte(`{"metrics":{}}`)
+	sign := func(ts time.Time, nonce string) (string, string) {
+		stamp := strconv.FormatInt(ts.Unix(), 10)
+		msg := agentpki.SigningString("POST", "/api/agent/report", stamp, nonce, body)
+		return stamp, base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+	}
+
+	stamp, sig := sign(time.Now(), "n1")
+	if err := s.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stamp, "n1", sig, body); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	// The very same request again is a replay.
+	wantStatus(t, s.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stamp, "n1", sig, body), 401)
+
+	stale, staleSig := sign(time.Now().Add(-time.Hour), "n2")
+	wantStatus(t, s.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stale, "n2", staleSig, body), 401)
+
+	stamp, sig = sign(time.Now(), "n3")
+	wantStatus(t, s.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stamp, "n3", sig, []byte(`{}`)), 401)
+	wantStatus(t, s.AuthenticateSignature(ctx, "host-2", "POST", "/api/agent/report", stamp, "n3", sig, body), 401)
+}
+
+// TestAuthenticateSignature_SharedNoncesAcrossReplicas: with HA, a signed
+// request accepted by one replica is a replay on another.
+func TestAuthenticateSignature_SharedNoncesAcrossReplicas(t *testing.T) {
+	repo := &fakeRepo{}
+	cfg := &config.Config{AgentCertTTL: time.Hour, HAEnabled: true}
+	first, second := NewService(repo, cfg), NewService(repo, cfg)
+	ctx := context.Background()
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := first.Enroll(ctx, "host-1", MethodAPIKey, models.AgentEnrollRequest{
+		Kind: models.AgentCredentialEd25519, PublicKey: base64.StdEncoding.EncodeToString(pub),
+	}); err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+
+	body := []byte(`{}`)
+	stamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, agentpki.SigningString("POST", "/api/agent/report", stamp, "n1", body)))
+	if err := first.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stamp, "n1", sig, body); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	wantStatus(t, second.AuthenticateSignature(ctx, "host-1", "POST", "/api/agent/report", stamp, "n1", sig, body), 401)
+	if len(repo.nonces) != 1 {
+		t.Errorf("nonces = %v, want the accepted one recorded", repo.nonces)
+	}
+}
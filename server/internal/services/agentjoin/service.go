@@ -0,0 +1,298 @@
+// Package agentjoin is the application/service layer for zero-touch agent
+// enrollment: admins create short-lived, multi-use join tokens, and an agent
+// started with --join-token registers its own host with one (tags and default
+// per-host permissions taken from the token) and gets that host's API key
+// back. Logic sits behind Repository and HostRegistrar ports so it is
+// unit-testable without a database.
+package agentjoin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const (
+	defaultTTL = 24 * time.Hour
+	maxTTL     = 30 * 24 * time.Hour
+	// maxHostnameLength bounds the host name an agent reports for itself.
+	maxHostnameLength = 255
+	// joinRetryWindow is how long a join request can be retried to get the
+	// key of the host it created back.
+	joinRetryWindow = time.Hour
+	// joinReservationTTL is how long a join_id reserved by a request that
+	// never recorded its host blocks other requests with that join_id.
+	joinReservationTTL = time.Minute
+	minJoinIDLength    = 16
+	maxJoinIDLength    = 64
+)
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	CreateAgentJoinToken(ctx context.Context, t *models.AgentJoinToken, tokenHash string) (*models.AgentJoinToken, error)
+	ListAgentJoinTokens(ctx context.Context) ([]models.AgentJoinToken, error)
+	ConsumeAgentJoinToken(ctx context.Context, tokenHash string) (*models.AgentJoinToken, error)
+	ReleaseAgentJoinToken(ctx context.Context, id string) error
+	ReserveAgentJoin(ctx context.Context, joinID, tokenID string, pendingSince, recordedSince time.Time) (bool, error)
+	RecordAgentJoin(ctx context.Context, joinID, hostID string) error
+	ReleaseAgentJoin(ctx context.Context, joinID string) error
+	FindAgentJoin(ctx context.Context, tokenHash, joinID string, since time.Time) (string, error)
+	RevokeAgentJoinToken(ctx context.Context, id string) (bool, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	SetHostPermission(ctx context.Context, username, hostID, level string) error
+	CreateAuditLog(ctx context.Context, username, action, hostID, ipAddress, details, status string) (int64, error)
+}
+
+// HostRegistrar is the host port used to create the joining host and mint its
+// API key. *host.Service satisfies it.
+type HostRegistrar interface {
+	Register(ctx context.Context, req models.HostRegistration) (id, plainKey string, err error)
+	RotateKey(ctx context.Context, id string) (string, error)
+}
+
+// Service holds the join-token use-cases.
+type Service struct {
+	repo  Repository
+	hosts HostRegistrar
+	now   func() time.Time
+}
+
+func NewService(repo Repository, hosts HostRegistrar) *Service {
+	return &Service{repo: repo, hosts: hosts, now: time.Now}
+}
+
+// newJoinToken returns a random token and the hash it is stored under.
+func newJoinToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, hashJoinToken(token), nil
+}
+
+func hashJoinToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create validates the request and stores a new token. Returns the plain
+// token (shown once) and the stored token.
+func (s *Service) Create(ctx context.Context, req models.AgentJoinTokenCreate, createdBy string) (string, *models.AgentJoinToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return "", nil, apperr.Validation("name requis (100 caractères max)")
+	}
+	if req.MaxUses < 0 {
+		return "", nil, apperr.Validation("max_uses doit être positif (0 = illimité)")
+	}
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	if req.ExpiresInHours == 0 {
+		ttl = defaultTTL
+	}
+	if ttl <= 0 || ttl > maxTTL {
+		return "", nil, apperr.Validation(fmt.Sprintf("expires_in_hours doit être compris entre 1 et %d", int(maxTTL.Hours())))
+	}
+	perms := make([]models.AgentJoinPermission, 0, len(req.Permissions))
+	seen := map[string]bool{}
+	for _, p := range req.Permissions {
+		if p.Level != "viewer" && p.Level != "operator" {
+			return "", nil, apperr.Validation("level doit être 'viewer' ou 'operator'")
+		}
+		if seen[p.Username] {
+			return "", nil, apperr.Validation(fmt.Sprintf("utilisateur %q en double", p.Username))
+		}
+		if _, err := s.repo.GetUserByUsername(ctx, p.Username); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", nil, apperr.Validation(fmt.Sprintf("utilisateur %q introuvable", p.Username))
+			}
+			return "", nil, apperr.Internal(err)
+		}
+		seen[p.Username] = true
+		perms = append(perms, p)
+	}
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	token, hash, err := newJoinToken()
+	if err != nil {
+		return "", nil, apperr.Internal(err)
+	}
+	stored, err := s.repo.CreateAgentJoinToken(ctx, &models.AgentJoinToken{
+		Name:        name,
+		Tags:        tags,
+		Permissions: perms,
+		MaxUses:     req.MaxUses,
+		ExpiresAt:   s.now().Add(ttl),
+		CreatedBy:   createdBy,
+	}, hash)
+	if err != nil {
+		return "", nil, apperr.Internal(err)
+	}
+	return token, stored, nil
+}
+
+// List returns every token, newest first (never nil).
+func (s *Service) List(ctx context.Context) ([]models.AgentJoinToken, error) {
+	tokens, err := s.repo.ListAgentJoinTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []models.AgentJoinToken{}
+	}
+	return tokens, nil
+}
+
+// Revoke stops a token from registering any more host. Hosts it already
+// registered keep their API key.
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.NotFound("jeton introuvable")
+	}
+	ok, err := s.repo.RevokeAgentJoinToken(ctx, id)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("jeton introuvable ou déjà révoqué")
+	}
+	return nil
+}
+
+// Join registers the calling agent's host with a join token: the host gets
+// the token's tags and default permissions, and its API key is returned.
+// clientIP is used when the agent did not report a valid address of its own.
+//
+// A join that fails to register the host gives its use of the token back. A
+// retry of a join that did register one (same join_id, within
+// joinRetryWindow) re-issues that host's key instead of creating another host.
+// The join_id is reserved before the host is registered, so a request sent
+// while another one with the same join_id is still in flight gets a conflict.
+func (s *Service) Join(ctx context.Context, req models.AgentJoinRequest, clientIP string) (*models.AgentJoinResponse, error) {
+	tokenHash := hashJoinToken(strings.TrimSpace(req.Token))
+	joinID := strings.TrimSpace(req.JoinID)
+	if joinID != "" && (len(joinID) < minJoinIDLength || len(joinID) > maxJoinIDLength) {
+		return nil, apperr.Validation(fmt.Sprintf("join_id doit faire entre %d et %d caractères", minJoinIDLength, maxJoinIDLength))
+	}
+	if joinID != "" {
+		resp, err := s.rejoin(ctx, tokenHash, joinID, clientIP)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
+	tok, err := s.repo.ConsumeAgentJoinToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.Unauthorized("invalid, expired, revoked or exhausted join token")
+		}
+		return nil, apperr.Internal(err)
+	}
+
+	ip := strings.TrimSpace(req.IPAddress)
+	if net.ParseIP(ip) == nil {
+		ip = clientIP
+	}
+	name := strings.TrimSpace(req.Hostname)
+	if len(name) > maxHostnameLength {
+		name = name[:maxHostnameLength]
+	}
+	if name == "" {
+		name = ip
+	}
+
+	if joinID != "" {
+		now := s.now()
+		ok, err := s.repo.ReserveAgentJoin(ctx, joinID, tok.ID, now.Add(-joinReservationTTL), now.Add(-joinRetryWindow))
+		if err != nil {
+			s.releaseToken(ctx, tok.ID)
+			return nil, apperr.Internal(err)
+		}
+		if !ok {
+			s.releaseToken(ctx, tok.ID)
+			// The other request may have registered its host meanwhile.
+			if resp, err := s.rejoin(ctx, tokenHash, joinID, clientIP); resp != nil || err != nil {
+				return resp, err
+			}
+			return nil, apperr.Conflict("une demande d'enrôlement avec ce join_id est déjà en cours, réessayez")
+		}
+	}
+
+	hostID, apiKey, err := s.hosts.Register(ctx, models.HostRegistration{Name: name, IPAddress: ip, Tags: tok.Tags})
+	if err != nil {
+		s.releaseToken(ctx, tok.ID)
+		if joinID != "" {
+			if rerr := s.repo.ReleaseAgentJoin(ctx, joinID); rerr != nil {
+				slog.WarnContext(ctx, "join token: failed to release join request",
+					slog.String("token", tok.ID), slog.Any("err", rerr))
+			}
+		}
+		return nil, err
+	}
+	if joinID != "" {
+		// The reservation is kept: a retry gets a conflict until it expires
+		// rather than registering a second host right away.
+		if err := s.repo.RecordAgentJoin(ctx, joinID, hostID); err != nil {
+			return nil, apperr.Internal(fmt.Errorf("record join request %s for host %s: %w", joinID, hostID, err))
+		}
+	}
+	for _, p := range tok.Permissions {
+		// A user deleted since the token was created only loses this grant.
+		if err := s.repo.SetHostPermission(ctx, p.Username, hostID, p.Level); err != nil {
+			slog.WarnContext(ctx, "join token: failed to grant default host permission",
+				slog.String("token", tok.ID), slog.String("host_id", hostID), slog.String("username", p.Username), slog.Any("err", err))
+		}
+	}
+	details := fmt.Sprintf("host %q (%s) joined with token %q (%s)", name, ip, tok.Name, tok.ID)
+	if _, err := s.repo.CreateAuditLog(ctx, "agent", "agent_join", hostID, clientIP, details, "success"); err != nil {
+		slog.WarnContext(ctx, "join token: failed to write audit log", slog.Any("err", err))
+	}
+	return &models.AgentJoinResponse{HostID: hostID, APIKey: apiKey}, nil
+}
+
+// releaseToken gives back the use of the token counted for a join that
+// registered no host.
+func (s *Service) releaseToken(ctx context.Context, tokenID string) {
+	if err := s.repo.ReleaseAgentJoinToken(ctx, tokenID); err != nil {
+		slog.WarnContext(ctx, "join token: failed to give back the use of a failed join",
+			slog.String("token", tokenID), slog.Any("err", err))
+	}
+}
+
+// rejoin answers a retried join request with a new key for the host the
+// first attempt created. Returns nil, nil when joinID created no host (or
+// the retry window or the token is over), so the join proceeds as a new one.
+func (s *Service) rejoin(ctx context.Context, tokenHash, joinID, clientIP string) (*models.AgentJoinResponse, error) {
+	hostID, err := s.repo.FindAgentJoin(ctx, tokenHash, joinID, s.now().Add(-joinRetryWindow))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	apiKey, err := s.hosts.RotateKey(ctx, hostID)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	details := fmt.Sprintf("join %s retried: API key re-issued", joinID)
+	if _, err := s.repo.CreateAuditLog(ctx, "agent", "agent_join", hostID, clientIP, details, "success"); err != nil {
+		slog.WarnContext(ctx, "join token: failed to write audit log", slog.Any("err", err))
+	}
+	return &models.AgentJoinResponse{HostID: hostID, APIKey: apiKey}, nil
+}
@@ -0,0 +1,369 @@
+package agentjoin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+type storedToken struct {
+	models.AgentJoinToken
+	hash string
+}
+
+// fakeRepo keeps tokens in memory with the same consumption rules as the SQL
+// UPDATE.
+type fakeRepo struct {
+	tokens    []*storedToken
+	joins     map[string]fakeJoin
+	recordErr error
+	users     map[string]bool
+	perms     []models.HostPermission
+	audits    []string
+}
+
+func (f *fakeRepo) CreateAgentJoinToken(_ context.Context, t *models.AgentJoinToken, hash string) (*models.AgentJoinToken, error) {
+	st := &storedToken{AgentJoinToken: *t, hash: hash}
+	st.ID = uuid.NewString()
+	st.CreatedAt = time.Now()
+	f.tokens = append(f.tokens, st)
+	out := st.AgentJoinToken
+	return &out, nil
+}
+
+func (f *fakeRepo) ListAgentJoinTokens(context.Context) ([]models.AgentJoinToken, error) {
+	var out []models.AgentJoinToken
+	for _, t := range f.tokens {
+		out = append(out, t.AgentJoinToken)
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) ConsumeAgentJoinToken(_ context.Context, hash string) (*models.AgentJoinToken, error) {
+	for _, t := range f.tokens {
+		if t.hash == hash && t.RevokedAt == nil && t.ExpiresAt.After(time.Now()) && (t.MaxUses == 0 || t.Uses < t.MaxUses) {
+			t.Uses++
+			out := t.AgentJoinToken
+			return &out, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeRepo) ReleaseAgentJoinToken(_ context.Context, id string) error {
+	for _, t := range f.tokens {
+		if t.ID == id && t.Uses > 0 {
+			t.Uses--
+		}
+	}
+	return nil
+}
+
+type fakeJoin struct {
+	tokenID, hostID string
+	at              time.Time
+}
+
+func (f *fakeRepo) ReserveAgentJoin(_ context.Context, joinID, tokenID string, pendingSince, recordedSince time.Time) (bool, error) {
+	if f.joins == nil {
+		f.joins = map[string]fakeJoin{}
+	}
+	if j, ok := f.joins[joinID]; ok && !(j.hostID == "" && !j.at.After(pendingSince)) && j.at.After(recordedSince) {
+		return false, nil
+	}
+	f.joins[joinID] = fakeJoin{tokenID: tokenID, at: time.Now()}
+	return true, nil
+}
+
+func (f *fakeRepo) RecordAgentJoin(_ context.Context, joinID, hostID string) error {
+	if f.recordErr != nil {
+		return f.recordErr
+	}
+	j, ok := f.joins[joinID]
+	if !ok || j.hostID != "" {
+		return sql.ErrNoRows
+	}
+	j.hostID = hostID
+	f.joins[joinID] = j
+	return nil
+}
+
+func (f *fakeRepo) ReleaseAgentJoin(_ context.Context, joinID string) error {
+	if j, ok := f.joins[joinID]; ok && j.hostID == "" {
+		delete(f.joins, joinID)
+	}
+	return nil
+}
+
+func (f *fakeRepo) FindAgentJoin(_ context.Context, hash, joinID string, since time.Time) (string, error) {
+	j, ok := f.joins[joinID]
+	if !ok || j.hostID == "" || !j.at.After(since) {
+		return "", sql.ErrNoRows
+	}
+	for _, t := range f.tokens {
+		if t.ID == j.tokenID && t.hash == hash && t.RevokedAt == nil && t.ExpiresAt.After(time.Now()) {
+			return j.hostID, nil
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+func (f *fakeRepo) RevokeAgentJoinToken(_ context.Context, id string) (bool, error) {
+	for _, t := range f.tokens {
+		if t.ID == id && t.RevokedAt == nil {
+			now := time.Now()
+			t.RevokedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRepo) GetUserByUsername(_ context.Context, username string) (*models.User, error) {
+	if !f.users[username] {
+		return nil, sql.ErrNoRows
+	}
+	return &models.User{Username: username}, nil
+}
+
+func (f *fakeRepo) SetHostPermission(_ context.Context, username, hostID, level string) error {
+	f.perms = append(f.perms, models.HostPermission{Username: username, HostID: hostID, Level: level})
+	return nil
+}
+
+func (f *fakeRepo) CreateAuditLog(_ context.Context, _, action, _, _, _, _ string) (int64, error) {
+	f.audits = append(f.audits, action)
+	return 1, nil
+}
+
+type fakeHosts struct {
+	registered []models.HostRegistration
+	rotated    []string
+	fail       error
+}
+
+func (f *fakeHosts) Register(_ context.Context, req models.HostRegistration) (string, string, error) {
+	if f.fail != nil {
+		return "", "", f.fail
+	}
+	f.registered = append(f.registered, req)
+	id := uuid.NewString()
+	return id, id + ".secret", nil
+}
+
+func (f *fakeHosts) RotateKey(_ context.Context, id string) (string, error) {
+	f.rotated = append(f.rotated, id)
+	return id + ".rotated", nil
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var ae *apperr.Error
+	if !errors.As(err, &ae) || ae.HTTPStatus != status {
+		t.Fatalf("err = %v, want HTTP %d", err, status)
+	}
+}
+
+func TestJoin_RegistersHostWithTokenDefaults(t *testing.T) {
+	repo := &fakeRepo{users: map[string]bool{"alice": true}}
+	hosts := &fakeHosts{}
+	s := NewService(repo, hosts)
+	ctx := context.Background()
+
+	token, stored, err := s.Create(ctx, models.AgentJoinTokenCreate{
+		Name:        "web fleet",
+		Tags:        []string{"web", "prod"},
+		Permissions: []models.AgentJoinPermission{{Username: "alice", Level: "operator"}},
+		MaxUses:     2,
+	}, "admin")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if time.Until(stored.ExpiresAt) > defaultTTL || time.Until(stored.ExpiresAt) < defaultTTL-time.Minute {
+		t.Errorf("expires_at = %v, want the default TTL", stored.ExpiresAt)
+	}
+
+	resp, err := s.Join(ctx, models.AgentJoinRequest{Token: token, Hostname: "web-01", IPAddress: "not-an-ip"}, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if resp.APIKey == "" || resp.HostID == "" {
+		t.Fatalf("join response: %+v", resp)
+	}
+	reg := hosts.registered[0]
+	if reg.Name != "web-01" || reg.IPAddress != "10.0.0.5" || len(reg.Tags) != 2 {
+		t.Errorf("registered %+v, want the token's tags and the caller's address", reg)
+	}
+	if len(repo.perms) != 1 || repo.perms[0] != (models.HostPermission{Username: "alice", HostID: resp.HostID, Level: "operator"}) {
+		t.Errorf("permissions = %+v", repo.perms)
+	}
+	if len(repo.audits) != 1 {
+		t.Error("a join must be audit-logged")
+	}
+
+	// Second use allowed, third refused (max_uses 2).
+	if _, err := s.Join(ctx, models.AgentJoinRequest{Token: token, Hostname: "web-02", IPAddress: "10.0.0.6"}, "10.0.0.6"); err != nil {
+		t.Fatalf("second join: %v", err)
+	}
+	_, err = s.Join(ctx, models.AgentJoinRequest{Token: token, Hostname: "web-03", IPAddress: "10.0.0.7"}, "10.0.0.7")
+	wantStatus(t, err, 401)
+}
+
+func TestJoin_RefusesRevokedAndUnknownTokens(t *testing.T) {
+	repo := &fakeRepo{}
+	s := NewService(repo, &fakeHosts{})
+	ctx := context.Background()
+
+	token, stored, err := s.Create(ctx, models.AgentJoinTokenCreate{Name: "lab"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(ctx, stored.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	_, err = s.Join(ctx, models.AgentJoinRequest{Token: token, IPAddress: "10.0.0.5"}, "10.0.0.5")
+	wantStatus(t, err, 401)
+	_, err = s.Join(ctx, models.AgentJoinRequest{Token: "nope", IPAddress: "10.0.0.5"}, "10.0.0.5")
+	wantStatus(t, err, 401)
+	wantStatus(t, s.Revoke(ctx, stored.ID), 404)
+	wantStatus(t, s.Revoke(ctx, "not-a-uuid"), 404)
+}
+
+func TestJoin_FailedRegistrationGivesTheUseBack(t *testing.T) {
+	repo := &fakeRepo{}
+	hosts := &fakeHosts{fail: apperr.Validation("invalid IP address format")}
+	s := NewService(repo, hosts)
+	ctx := context.Background()
+
+	token, _, err := s.Create(ctx, models.AgentJoinTokenCreate{Name: "single", MaxUses: 1}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Join(ctx, models.AgentJoinRequest{Token: token}, "")
+	wantStatus(t, err, 400)
+	if repo.tokens[0].Uses != 0 {
+		t.Fatalf("uses = %d after a failed registration, want 0", repo.tokens[0].Uses)
+	}
+	hosts.fail = nil
+	if _, err := s.Join(ctx, models.AgentJoinRequest{Token: token}, "10.0.0.5"); err != nil {
+		t.Fatalf("join after failed attempt: %v", err)
+	}
+}
+
+// TestJoin_RetryReissuesTheKeyOfTheSameHost: a retry after a lost response
+// (same join_id) gets a new key for the host the first attempt created,
+// without another host nor another use of the token.
+func TestJoin_RetryReissuesTheKeyOfTheSameHost(t *testing.T) {
+	repo := &fakeRepo{}
+	hosts := &fakeHosts{}
+	s := NewService(repo, hosts)
+	ctx := context.Background()
+
+	token, stored, err := s.Create(ctx, models.AgentJoinTokenCreate{Name: "single", MaxUses: 1}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := models.AgentJoinRequest{Token: token, Hostname: "web-01", IPAddress: "10.0.0.5", JoinID: "3b4c9e2a-join-retry"}
+	first, err := s.Join(ctx, req, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	retry, err := s.Join(ctx, req, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("retried join: %v", err)
+	}
+	if retry.HostID != first.HostID || retry.APIKey == first.APIKey || len(hosts.registered) != 1 {
+		t.Errorf("retry = %+v after %+v, %d hosts registered; want the same host with a new key", retry, first, len(hosts.registered))
+	}
+	if repo.tokens[0].Uses != 1 {
+		t.Errorf("uses = %d, want 1", repo.tokens[0].Uses)
+	}
+
+	// Another join_id is a new join: the single use is spent.
+	req.JoinID = "7d1f0c55-other-host"
+	_, err = s.Join(ctx, req, "10.0.0.6")
+	wantStatus(t, err, 401)
+
+	// Past the retry window, or once the token is revoked, a retry no longer
+	// gets a key.
+	req.JoinID = "3b4c9e2a-join-retry"
+	s.now = func() time.Time { return time.Now().Add(joinRetryWindow + time.Minute) }
+	_, err = s.Join(ctx, req, "10.0.0.5")
+	wantStatus(t, err, 401)
+	s.now = time.Now
+	if err := s.Revoke(ctx, stored.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Join(ctx, req, "10.0.0.5")
+	wantStatus(t, err, 401)
+
+	req.JoinID = "short"
+	_, err = s.Join(ctx, req, "10.0.0.5")
+	wantStatus(t, err, 400)
+}
+
+// TestJoin_InFlightJoinIDConflicts: a request whose join_id is reserved by
+// another one still registering its host neither registers a second host nor
+// spends a use; a join whose record fails reports it.
+func TestJoin_InFlightJoinIDConflicts(t *testing.T) {
+	repo := &fakeRepo{}
+	hosts := &fakeHosts{}
+	s := NewService(repo, hosts)
+	ctx := context.Background()
+
+	token, stored, err := s.Create(ctx, models.AgentJoinTokenCreate{Name: "fleet"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := models.AgentJoinRequest{Token: token, Hostname: "web-01", IPAddress: "10.0.0.5", JoinID: "3b4c9e2a-in-flight"}
+	if ok, _ := repo.ReserveAgentJoin(ctx, req.JoinID, stored.ID, time.Now().Add(-joinReservationTTL), time.Now().Add(-joinRetryWindow)); !ok {
+		t.Fatal("reserve")
+	}
+	_, err = s.Join(ctx, req, "10.0.0.5")
+	wantStatus(t, err, 409)
+	if len(hosts.registered) != 0 || repo.tokens[0].Uses != 0 {
+		t.Errorf("%d hosts registered, uses = %d; want none while the join is in flight", len(hosts.registered), repo.tokens[0].Uses)
+	}
+
+	// A reservation left by a request that never recorded its host expires.
+	s.now = func() time.Time { return time.Now().Add(joinReservationTTL + time.Second) }
+	if _, err := s.Join(ctx, req, "10.0.0.5"); err != nil {
+		t.Fatalf("join after the reservation expired: %v", err)
+	}
+	s.now = time.Now
+
+	repo.recordErr = errors.New("connection reset")
+	req.JoinID = "7d1f0c55-record-fails"
+	_, err = s.Join(ctx, req, "10.0.0.6")
+	wantStatus(t, err, 500)
+	_, err = s.Join(ctx, req, "10.0.0.6")
+	wantStatus(t, err, 409)
+	if len(hosts.registered) != 2 {
+		t.Errorf("%d hosts registered, want no duplicate after the failed record", len(hosts.registered))
+	}
+}
+
+func TestCreate_Validation(t *testing.T) {
+	s := NewService(&fakeRepo{users: map[string]bool{"alice": true}}, &fakeHosts{})
+	ctx := context.Background()
+	cases := []models.AgentJoinTokenCreate{
+		{Name: " "},
+		{Name: "x", MaxUses: -1},
+		{Name: "x", ExpiresInHours: -1},
+		{Name: "x", ExpiresInHours: 24*30 + 1},
+		{Name: "x", Permissions: []models.AgentJoinPermission{{Username: "alice", Level: "admin"}}},
+		{Name: "x", Permissions: []models.AgentJoinPermission{{Username: "bob", Level: "viewer"}}},
+		{Name: "x", Permissions: []models.AgentJoinPermission{{Username: "alice", Level: "viewer"}, {Username: "alice", Level: "operator"}}},
+	}
+	for _, req := range cases {
+		_, _, err := s.Create(ctx, req, "admin")
+		wantStatus(t, err, 400)
+	}
+}
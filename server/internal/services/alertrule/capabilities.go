@@ -26,6 +26,14 @@ func (s *Service) AgentCapabilities() []models.AlertMetricCapability {
 		{Metric: "disk_temperature", Label: "Temp. disque", Unit: "°C", Icon: "\U0001f321", BadgeClass: "bg-orange-lt text-orange", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
 		{Metric: "restic_backup_age_hours", Label: "Ancienneté backup Restic", Unit: "h", Icon: "\U0001f4be", BadgeClass: "bg-lime-lt text-lime", SupportsThreshold: true, SupportsDuration: false, SupportsHostFilter: true},
 		{Metric: "restic_repo_size_bytes", Label: "Taille dépôt Restic", Unit: " o", Icon: "\U0001f5c4", BadgeClass: "bg-lime-lt text-lime", SupportsThreshold: true, SupportsDuration: false, SupportsHostFilter: true},
+		{Metric: "docker_volume_growth_bytes_24h", Label: "Croissance volume Docker (24h)", Unit: " o", Icon: "🐳", BadgeClass: "bg-blue-lt text-blue", SupportsThreshold: true, SupportsDuration: false, SupportsHostFilter: true},
+		{Metric: "web_domain_5xx_rate", Label: "Taux d'erreurs 5xx par domaine", Unit: "%", Icon: "\U0001f310", BadgeClass: "bg-red-lt text-red", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "web_domain_p95_latency_ms", Label: "Latence p95 par domaine", Unit: " ms", Icon: "\U0001f310", BadgeClass: "bg-orange-lt text-orange", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "network_policy_violation", Label: "Violation de politique réseau", Unit: "", Icon: "\U0001f6a7", BadgeClass: "bg-red-lt text-red", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "net_interface_down", Label: "Interface réseau coupée", Unit: "", Icon: "\U0001f50c", BadgeClass: "bg-red-lt text-red", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "net_interface_error_rate", Label: "Taux d'erreurs par interface", Unit: "%", Icon: "\U0001f4e1", BadgeClass: "bg-orange-lt text-orange", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "net_interface_utilization", Label: "Utilisation du lien par interface", Unit: "%", Icon: "\U0001f4e1", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: true},
+		{Metric: "crowdsec_bouncer_last_pull_minutes", Label: "Dernier pull d'un bouncer CrowdSec", Unit: " min", Icon: "\U0001f6e1", BadgeClass: "bg-orange-lt text-orange", SupportsThreshold: true, SupportsDuration: false, SupportsHostFilter: true},
 	}
 }
 
@@ -39,11 +47,23 @@ func proxmoxMetrics() []models.AlertMetricCapability {
 		{Metric: "proxmox_node_fan_rpm", Label: "Proxmox RPM ventilateurs noeud", Unit: " RPM", Icon: "\U0001f300", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_guest_cpu_percent", Label: "CPU VM/LXC Proxmox", Unit: "%", Icon: "\U0001f9e0", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_guest_memory_percent", Label: "RAM VM/LXC Proxmox", Unit: "%", Icon: "\U0001f4ca", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_snapshot_policy_failures", Label: "Politiques de snapshot en échec", Unit: "", Icon: "\U0001f4f8", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_node_pending_updates", Label: "Paquets APT en attente", Unit: "", Icon: "\U0001f504", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_recent_failed_tasks_24h", Label: "Tâches Proxmox échouées (24h)", Unit: "", Icon: "\U0001f552", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_auth_failures_recent", Label: "Echecs auth Proxmox (logs)", Unit: "", Icon: "\U0001f512", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_disk_failed_count", Label: "Disques physiques en échec", Unit: "", Icon: "\U0001f4a5", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "proxmox_disk_min_wearout_percent", Label: "Usure disque min", Unit: "%", Icon: "\U0001f6e0", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_health", Label: "Santé Ceph (0 OK, 1 WARN, 2 ERR)", Unit: "", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_osds_down", Label: "OSD Ceph down", Unit: "", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_osds_out", Label: "OSD Ceph out", Unit: "", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_pgs_not_clean", Label: "PG Ceph non active+clean", Unit: "", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_pool_max_percent", Label: "Pool Ceph le plus rempli", Unit: "%", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ceph_mons_out_of_quorum", Label: "Moniteurs Ceph hors quorum", Unit: "", Icon: "\U0001f419", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ha_resources_error", Label: "Ressources HA en erreur", Unit: "", Icon: "\U0001f6a8", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_ha_manager_errors", Label: "Gestionnaires HA (CRM/LRM) en défaut", Unit: "", Icon: "\U0001f6a8", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "proxmox_cluster_quorum_lost", Label: "Quorum corosync perdu", Unit: "", Icon: "\U0001f517", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "pbs_backup_age_hours", Label: "Âge dernière sauvegarde PBS", Unit: "h", Icon: "\U0001f4be", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "pbs_verify_failed", Label: "Vérifications PBS en échec", Unit: "", Icon: "\U0001f6e1", BadgeClass: "bg-cyan-lt text-cyan", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 	}
 }
 
@@ -59,6 +79,8 @@ func dockerMetrics() []models.AlertMetricCapability {
 	return []models.AlertMetricCapability{
 		{Metric: "docker_container_state", Label: "État d'un container", Unit: "", Icon: "🐳", BadgeClass: "bg-blue-lt text-blue", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 		{Metric: "docker_compose_degraded_services", Label: "Services Compose dégradés", Unit: "", Icon: "🐳", BadgeClass: "bg-blue-lt text-blue", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "docker_swarm_service_missing_replicas", Label: "Réplicas Swarm manquants", Unit: "", Icon: "🐳", BadgeClass: "bg-blue-lt text-blue", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
+		{Metric: "docker_swarm_node_state", Label: "État d'un noeud Swarm", Unit: "", Icon: "🐳", BadgeClass: "bg-blue-lt text-blue", SupportsThreshold: true, SupportsDuration: true, SupportsHostFilter: false},
 	}
 }
 
@@ -70,12 +92,13 @@ func (s *Service) ProxmoxCapabilities(ctx context.Context) (models.AlertSplitCap
 		AgentMetrics:   []models.AlertMetricCapability{},
 		ProxmoxMetrics: proxmoxMetrics(),
 	}
-	resp.ProxmoxScope.Modes = []string{"global", "connection", "node", "storage", "guest", "disk"}
+	resp.ProxmoxScope.Modes = []string{"global", "connection", "node", "storage", "guest", "disk", "backup_group"}
 	resp.ProxmoxScope.Connections, _ = s.repo.ListAlertProxmoxConnections(ctx)
 	resp.ProxmoxScope.Nodes, _ = s.repo.ListAlertProxmoxNodes(ctx)
 	resp.ProxmoxScope.Storages, _ = s.repo.ListAlertProxmoxStorages(ctx)
 	resp.ProxmoxScope.Guests, _ = s.repo.ListAlertProxmoxGuests(ctx)
 	resp.ProxmoxScope.Disks, _ = s.repo.ListAlertProxmoxDisks(ctx)
+	resp.ProxmoxScope.BackupGroups, _ = s.repo.ListAlertPBSBackupGroups(ctx)
 	return resp, nil
 }
 
@@ -85,10 +108,12 @@ func (s *Service) DockerCapabilities(ctx context.Context) (models.AlertDockerCap
 	hostOpts, _ := s.repo.ListAlertDockerScopeHosts(ctx)
 	for _, ho := range hostOpts {
 		scope := models.AlertDockerHostScope{
-			HostID:     ho.ID,
-			HostName:   ho.Label,
-			Containers: []models.AlertDockerScopeContainer{},
-			Projects:   []models.AlertDockerScopeProject{},
+			HostID:        ho.ID,
+			HostName:      ho.Label,
+			Containers:    []models.AlertDockerScopeContainer{},
+			Projects:      []models.AlertDockerScopeProject{},
+			SwarmServices: []string{},
+			SwarmNodes:    []models.AlertDockerScopeSwarmNode{},
 		}
 		containers, _ := s.repo.GetDockerContainers(ctx, ho.ID)
 		for _, ct := range containers {
@@ -108,6 +133,14 @@ func (s *Service) DockerCapabilities(ctx context.Context) (models.AlertDockerCap
 			}
 			scope.Projects = append(scope.Projects, models.AlertDockerScopeProject{Name: p.Name, Services: services})
 		}
+		swarmServices, _ := s.repo.ListDockerSwarmServices(ctx, ho.ID)
+		for _, svc := range swarmServices {
+			scope.SwarmServices = append(scope.SwarmServices, svc.Name)
+		}
+		swarmNodes, _ := s.repo.ListDockerSwarmNodes(ctx, ho.ID)
+		for _, n := range swarmNodes {
+			scope.SwarmNodes = append(scope.SwarmNodes, models.AlertDockerScopeSwarmNode{NodeID: n.NodeID, Hostname: n.Hostname, Role: n.Role})
+		}
 		hosts = append(hosts, scope)
 	}
 	return models.AlertDockerCapabilities{Metrics: dockerMetrics(), Hosts: hosts}, nil
@@ -135,11 +168,12 @@ func filterMetricsByCollectors(all []models.AlertMetricCapability, collectors ma
 		"bandwidth_vs_rolling_avg": true,
 	}
 	requiresCollector := map[string]string{
-		"cpu_temperature":         "cpu_temp",
-		"disk_smart_status":       "smart",
-		"disk_temperature":        "smart",
-		"restic_backup_age_hours": "restic",
-		"restic_repo_size_bytes":  "restic",
+		"cpu_temperature":                "cpu_temp",
+		"disk_smart_status":              "smart",
+		"disk_temperature":               "smart",
+		"restic_backup_age_hours":        "restic",
+		"restic_repo_size_bytes":         "restic",
+		"docker_volume_growth_bytes_24h": "docker",
 	}
 	var filtered []models.AlertMetricCapability
 	for _, metric := range all {
@@ -221,6 +255,17 @@ func (s *Service) ProxmoxScopeTestTarget(ctx context.Context, scope *models.Prox
 			return "proxmox:disk:" + scope.DiskID, "Disque: " + nodeName + " / " + detail
 		}
 		return "proxmox:disk:" + scope.DiskID, "Disque: " + scope.DiskID
+	case "backup_group":
+		if scope.BackupGroupID == "" {
+			return globalID, globalLabel
+		}
+		if connName, store, group, guestName, err := s.repo.PBSBackupGroupLabelParts(ctx, scope.BackupGroupID); err == nil {
+			if strings.TrimSpace(guestName) != "" {
+				group += " (" + guestName + ")"
+			}
+			return "proxmox:backup_group:" + scope.BackupGroupID, "Sauvegarde PBS: " + connName + " / " + store + " / " + group
+		}
+		return "proxmox:backup_group:" + scope.BackupGroupID, "Sauvegarde PBS: " + scope.BackupGroupID
 	default:
 		return globalID, globalLabel
 	}
@@ -25,11 +25,14 @@ type Repository interface {
 	HostExists(ctx context.Context, id string) (bool, error)
 	DockerContainerExists(ctx context.Context, id, hostID string) (bool, error)
 	ComposeProjectExists(ctx context.Context, name, hostID string) (bool, error)
+	DockerSwarmServiceExists(ctx context.Context, name, hostID string) (bool, error)
+	DockerSwarmNodeExists(ctx context.Context, nodeID, hostID string) (bool, error)
 	ProxmoxConnectionExists(ctx context.Context, id string) (bool, error)
 	ProxmoxNodeExists(ctx context.Context, id string) (bool, error)
 	ProxmoxStorageExists(ctx context.Context, id string) (bool, error)
 	ProxmoxGuestExists(ctx context.Context, id string) (bool, error)
 	ProxmoxDiskExists(ctx context.Context, id string) (bool, error)
+	PBSBackupGroupExists(ctx context.Context, id string) (bool, error)
 	ResolveOpenAlertIncidentsByRule(ctx context.Context, ruleID int64) (int64, error)
 	ResolveAlertIncident(ctx context.Context, id int64) error
 	AcknowledgeAlertIncident(ctx context.Context, id int64, username string) error
@@ -47,17 +50,21 @@ type Repository interface {
 	GetHost(ctx context.Context, id string) (*models.Host, error)
 	GetDockerContainers(ctx context.Context, hostID string) ([]models.DockerContainer, error)
 	GetComposeProjectsByHost(ctx context.Context, hostID string) ([]models.ComposeProject, error)
+	ListDockerSwarmServices(ctx context.Context, hostID string) ([]models.SwarmService, error)
+	ListDockerSwarmNodes(ctx context.Context, hostID string) ([]models.SwarmNode, error)
 	ListAlertProxmoxConnections(ctx context.Context) ([]models.AlertScopeOption, error)
 	ListAlertProxmoxNodes(ctx context.Context) ([]models.AlertScopeOption, error)
 	ListAlertProxmoxStorages(ctx context.Context) ([]models.AlertScopeOption, error)
 	ListAlertProxmoxGuests(ctx context.Context) ([]models.AlertScopeOption, error)
 	ListAlertProxmoxDisks(ctx context.Context) ([]models.AlertScopeOption, error)
+	ListAlertPBSBackupGroups(ctx context.Context) ([]models.AlertScopeOption, error)
 	ListAlertDockerScopeHosts(ctx context.Context) ([]models.AlertScopeOption, error)
 	ProxmoxConnectionName(ctx context.Context, id string) (string, error)
 	ProxmoxNodeLabelParts(ctx context.Context, id string) (connName, nodeName string, err error)
 	ProxmoxStorageLabelParts(ctx context.Context, id string) (connName, nodeName, storageName string, err error)
 	ProxmoxGuestLabelParts(ctx context.Context, id string) (connName, nodeName, guestName, guestType string, vmid int, err error)
 	ProxmoxDiskLabelParts(ctx context.Context, id string) (connName, nodeName, devPath, model string, err error)
+	PBSBackupGroupLabelParts(ctx context.Context, id string) (connName, store, group, guestName string, err error)
 }
 
 // Service holds the alert-rule use-cases.
@@ -323,6 +330,16 @@ func (s *Service) validateDockerScope(ctx context.Context, scope *models.DockerM
 			return apperr.Validation("Projet Compose introuvable pour ce scope.")
 		}
 	}
+	if scope.ScopeMode == "swarm_service" {
+		if ok, _ := s.repo.DockerSwarmServiceExists(ctx, scope.ServiceName, scope.HostID); !ok {
+			return apperr.Validation("Service Swarm introuvable pour ce scope.")
+		}
+	}
+	if scope.ScopeMode == "swarm_node" {
+		if ok, _ := s.repo.DockerSwarmNodeExists(ctx, scope.NodeID, scope.HostID); !ok {
+			return apperr.Validation("Noeud Swarm introuvable pour ce scope.")
+		}
+	}
 	return nil
 }
 
@@ -351,6 +368,10 @@ func (s *Service) validateProxmoxScope(ctx context.Context, scope *models.Proxmo
 		if ok, _ := s.repo.ProxmoxDiskExists(ctx, scope.DiskID); !ok {
 			return apperr.Validation("Disque physique Proxmox introuvable pour ce scope.")
 		}
+	case "backup_group":
+		if ok, _ := s.repo.PBSBackupGroupExists(ctx, scope.BackupGroupID); !ok {
+			return apperr.Validation("Groupe de sauvegarde PBS introuvable pour ce scope.")
+		}
 	}
 	return nil
 }
@@ -384,14 +405,23 @@ var validAlertMetrics = map[string]bool{
 	"cpu_temperature": true, "disk_smart_status": true, "disk_temperature": true, "proxmox_storage_percent": true,
 	"proxmox_node_cpu_percent": true, "proxmox_node_memory_percent": true,
 	"proxmox_node_cpu_temperature": true, "proxmox_node_fan_rpm": true,
-	"proxmox_guest_cpu_percent": true, "proxmox_guest_memory_percent": true,
+	"proxmox_guest_cpu_percent": true, "proxmox_guest_memory_percent": true, "proxmox_snapshot_policy_failures": true,
 	"proxmox_node_pending_updates":    true,
 	"proxmox_recent_failed_tasks_24h": true,
 	"proxmox_auth_failures_recent":    true,
 	"proxmox_disk_failed_count":       true, "proxmox_disk_min_wearout_percent": true,
-	"docker_container_state": true, "docker_compose_degraded_services": true,
+	"pbs_backup_age_hours": true, "pbs_verify_failed": true,
+	"proxmox_ceph_health": true, "proxmox_ceph_osds_down": true, "proxmox_ceph_osds_out": true,
+	"proxmox_ceph_pgs_not_clean": true, "proxmox_ceph_pool_max_percent": true, "proxmox_ceph_mons_out_of_quorum": true,
+	"proxmox_ha_resources_error": true, "proxmox_ha_manager_errors": true, "proxmox_cluster_quorum_lost": true,
+	"docker_container_state": true, "docker_compose_degraded_services": true, "docker_volume_growth_bytes_24h": true,
+	"docker_swarm_service_missing_replicas": true, "docker_swarm_node_state": true,
 	"restic_backup_age_hours": true, "restic_repo_size_bytes": true,
 	"bandwidth_vs_rolling_avg": true,
+	"web_domain_5xx_rate":      true, "web_domain_p95_latency_ms": true,
+	"network_policy_violation": true,
+	"net_interface_down":       true, "net_interface_error_rate": true, "net_interface_utilization": true,
+	"crowdsec_bouncer_last_pull_minutes": true,
 }
 
 func validateAlertRuleMetricOperator(metric, operator string) error {
@@ -45,11 +45,18 @@ func (f *fakeRepo) DockerContainerExists(_ context.Context, containerID, _ strin
 func (f *fakeRepo) ComposeProjectExists(context.Context, string, string) (bool, error) {
 	return true, nil
 }
+func (f *fakeRepo) DockerSwarmServiceExists(context.Context, string, string) (bool, error) {
+	return true, nil
+}
+func (f *fakeRepo) DockerSwarmNodeExists(context.Context, string, string) (bool, error) {
+	return true, nil
+}
 func (f *fakeRepo) ProxmoxConnectionExists(context.Context, string) (bool, error) { return true, nil }
 func (f *fakeRepo) ProxmoxNodeExists(context.Context, string) (bool, error)       { return true, nil }
 func (f *fakeRepo) ProxmoxStorageExists(context.Context, string) (bool, error)    { return true, nil }
 func (f *fakeRepo) ProxmoxGuestExists(context.Context, string) (bool, error)      { return true, nil }
 func (f *fakeRepo) ProxmoxDiskExists(context.Context, string) (bool, error)       { return true, nil }
+func (f *fakeRepo) PBSBackupGroupExists(context.Context, string) (bool, error)    { return true, nil }
 func (f *fakeRepo) ResolveOpenAlertIncidentsByRule(context.Context, int64) (int64, error) {
 	return 0, nil
 }
@@ -83,6 +90,12 @@ func (f *fakeRepo) GetDockerContainers(context.Context, string) ([]models.Docker
 func (f *fakeRepo) GetComposeProjectsByHost(context.Context, string) ([]models.ComposeProject, error) {
 	return nil, nil
 }
+func (f *fakeRepo) ListDockerSwarmServices(context.Context, string) ([]models.SwarmService, error) {
+	return []models.SwarmService{}, nil
+}
+func (f *fakeRepo) ListDockerSwarmNodes(context.Context, string) ([]models.SwarmNode, error) {
+	return []models.SwarmNode{}, nil
+}
 func (f *fakeRepo) ListAlertProxmoxConnections(context.Context) ([]models.AlertScopeOption, error) {
 	return nil, nil
 }
@@ -98,6 +111,9 @@ func (f *fakeRepo) ListAlertProxmoxGuests(context.Context) ([]models.AlertScopeO
 func (f *fakeRepo) ListAlertProxmoxDisks(context.Context) ([]models.AlertScopeOption, error) {
 	return nil, nil
 }
+func (f *fakeRepo) ListAlertPBSBackupGroups(context.Context) ([]models.AlertScopeOption, error) {
+	return nil, nil
+}
 func (f *fakeRepo) ListAlertDockerScopeHosts(context.Context) ([]models.AlertScopeOption, error) {
 	return nil, nil
 }
@@ -114,6 +130,9 @@ func (f *fakeRepo) ProxmoxGuestLabelParts(context.Context, string) (string, stri
 func (f *fakeRepo) ProxmoxDiskLabelParts(context.Context, string) (string, string, string, string, error) {
 	return "", "", "", "", nil
 }
+func (f *fakeRepo) PBSBackupGroupLabelParts(context.Context, string) (string, string, string, string, error) {
+	return "", "", "", "", nil
+}
 
 func newSvc(repo Repository) *Service {
 	return NewService(repo, func(models.AlertRule) {}, EngineFuncs{})
@@ -15,7 +15,19 @@ type EngineFuncs struct {
 	MetricValue        func(ctx context.Context, host models.Host, rule models.AlertRule) (float64, bool)
 	MatchRule          func(rule models.AlertRule, host models.Host, value float64) bool
 	BuildDockerTargets func(ctx context.Context, rule models.AlertRule) []models.Host
-	FetchProxmoxLogs   func(ctx context.Context, rule models.AlertRule) ([]string, time.Time)
+	// BuildWebDomainTargets returns a host's per-domain targets of the
+	// web_domain_* metrics.
+	BuildWebDomainTargets func(ctx context.Context, host models.Host) []models.Host
+	// BuildNetworkPolicyTargets returns a host's per-violation targets of
+	// network_policy_violation.
+	BuildNetworkPolicyTargets func(ctx context.Context, host models.Host) []models.Host
+	// BuildNetInterfaceTargets returns a host's per-interface targets of the
+	// net_interface_* metrics.
+	BuildNetInterfaceTargets func(ctx context.Context, host models.Host) []models.Host
+	// BuildCrowdSecBouncerTargets returns a host's per-bouncer targets of
+	// crowdsec_bouncer_last_pull_minutes.
+	BuildCrowdSecBouncerTargets func(ctx context.Context, host models.Host) []models.Host
+	FetchProxmoxLogs            func(ctx context.Context, rule models.AlertRule) ([]string, time.Time)
 }
 
 // TestRunInput is the payload for the preview endpoints (also reused for the
@@ -99,9 +111,13 @@ func (s *Service) TestRun(ctx context.Context, in TestRunInput) ([]TestRunResult
 	}
 
 	// Staleness only applies to the auth-failures metric; everything else is
-	// evaluated against the latest value regardless of duration.
+	// evaluated against the latest value regardless of duration. The
+	// web_domain_* metrics read their duration as the log window, like it,
+	// network_policy_violation as how recently the flow was seen, and the
+	// net_interface_* metrics as their sample window.
 	ruleNoStaleness := rule
-	if rule.Metric != "proxmox_auth_failures_recent" {
+	if rule.Metric != "proxmox_auth_failures_recent" && !models.IsWebDomainMetric(rule.Metric) &&
+		!models.IsNetworkPolicyMetric(rule.Metric) && !models.IsNetInterfaceMetric(rule.Metric) {
 		ruleNoStaleness.DurationSeconds = 0
 	}
 
@@ -140,6 +156,30 @@ func (s *Service) TestRun(ctx context.Context, in TestRunInput) ([]TestRunResult
 			if rule.HostID != nil && *rule.HostID != host.ID {
 				continue
 			}
+			if models.IsWebDomainMetric(rule.Metric) {
+				for _, target := range s.engine.BuildWebDomainTargets(ctx, host) {
+					eval(target)
+				}
+				continue
+			}
+			if models.IsNetworkPolicyMetric(rule.Metric) {
+				for _, target := range s.engine.BuildNetworkPolicyTargets(ctx, host) {
+					eval(target)
+				}
+				continue
+			}
+			if models.IsNetInterfaceMetric(rule.Metric) {
+				for _, target := range s.engine.BuildNetInterfaceTargets(ctx, host) {
+					eval(target)
+				}
+				continue
+			}
+			if models.IsCrowdSecBouncerMetric(rule.Metric) {
+				for _, target := range s.engine.BuildCrowdSecBouncerTargets(ctx, host) {
+					eval(target)
+				}
+				continue
+			}
 			eval(host)
 		}
 	}
@@ -20,6 +20,27 @@ func newEngineStub(value float64, hasData, fires bool) EngineFuncs {
 		BuildDockerTargets: func(context.Context, models.AlertRule) []models.Host {
 			return []models.Host{{ID: "c1", Name: "nginx"}}
 		},
+		BuildWebDomainTargets: func(_ context.Context, host models.Host) []models.Host {
+			return []models.Host{
+				{ID: models.WebDomainTargetID(host.ID, "a.test"), Name: host.Name + " — a.test"},
+				{ID: models.WebDomainTargetID(host.ID, "b.test"), Name: host.Name + " — b.test"},
+			}
+		},
+		BuildNetworkPolicyTargets: func(_ context.Context, host models.Host) []models.Host {
+			if host.ID != "h2" {
+				return nil
+			}
+			return []models.Host{{ID: models.NetworkPolicyTargetID(host.ID, 7), Name: host.Name + " — smtp"}}
+		},
+		BuildNetInterfaceTargets: func(_ context.Context, host models.Host) []models.Host {
+			return []models.Host{
+				{ID: models.NetInterfaceTargetID(host.ID, "eth0"), Name: host.Name + " — eth0"},
+				{ID: models.NetInterfaceTargetID(host.ID, "bond0"), Name: host.Name + " — bond0"},
+			}
+		},
+		BuildCrowdSecBouncerTargets: func(_ context.Context, host models.Host) []models.Host {
+			return []models.Host{{ID: models.CrowdSecBouncerTargetID(host.ID, "firewall"), Name: host.Name + " — firewall"}}
+		},
 	}
 }
 
@@ -101,3 +122,91 @@ func TestRunLogs_RejectsNonAuthFailureMetric(t *testing.T) {
 		t.Fatalf("want 400 validation for non-auth-failure metric, got %v", err)
 	}
 }
+
+func TestRun_WebDomainMetric_EvaluatesEachDomain(t *testing.T) {
+	repo := &fakeRepo{allHosts: []models.Host{
+		{ID: "h1", Name: "alpha"},
+		{ID: "h2", Name: "beta"},
+	}}
+	s := NewService(repo, nil, newEngineStub(12, true, true))
+
+	target := "h1"
+	results, _, err := s.TestRun(context.Background(), TestRunInput{
+		HostID:        &target,
+		Metric:        "web_domain_5xx_rate",
+		Operator:      ">",
+		ThresholdWarn: 5,
+		ThresholdCrit: 20,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].HostID != "web:h1:a.test" || results[1].HostID != "web:h1:b.test" {
+		t.Errorf("results = %+v, want one per domain of h1", results)
+	}
+}
+
+func TestRun_NetworkPolicyMetric_EvaluatesEachViolation(t *testing.T) {
+	repo := &fakeRepo{allHosts: []models.Host{
+		{ID: "h1", Name: "alpha"},
+		{ID: "h2", Name: "beta"},
+	}}
+	s := NewService(repo, nil, newEngineStub(1, true, true))
+
+	results, anyFires, err := s.TestRun(context.Background(), TestRunInput{
+		Metric:        "network_policy_violation",
+		Operator:      ">=",
+		ThresholdWarn: 1,
+		ThresholdCrit: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].HostID != "netpolicy:h2:7" || !anyFires {
+		t.Errorf("results = %+v, want the one violation of h2", results)
+	}
+}
+
+func TestRun_NetInterfaceMetric_EvaluatesEachInterface(t *testing.T) {
+	repo := &fakeRepo{allHosts: []models.Host{
+		{ID: "h1", Name: "alpha"},
+		{ID: "h2", Name: "beta"},
+	}}
+	s := NewService(repo, nil, newEngineStub(1, true, true))
+	hostID := "h2"
+
+	results, anyFires, err := s.TestRun(context.Background(), TestRunInput{
+		Metric:        "net_interface_down",
+		HostID:        &hostID,
+		Operator:      ">=",
+		ThresholdWarn: 1,
+		ThresholdCrit: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].HostID != "netif:h2:eth0" || results[1].HostID != "netif:h2:bond0" || !anyFires {
+		t.Errorf("results = %+v, want one per interface of h2", results)
+	}
+}
+
+func TestRun_CrowdSecBouncerMetric_EvaluatesEachBouncer(t *testing.T) {
+	repo := &fakeRepo{allHosts: []models.Host{
+		{ID: "h1", Name: "alpha"},
+		{ID: "h2", Name: "beta"},
+	}}
+	s := NewService(repo, nil, newEngineStub(42, true, true))
+
+	results, anyFires, err := s.TestRun(context.Background(), TestRunInput{
+		Metric:        "crowdsec_bouncer_last_pull_minutes",
+		Operator:      ">",
+		ThresholdWarn: 10,
+		ThresholdCrit: 30,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].HostID != "bouncer:h1:firewall" || results[1].HostID != "bouncer:h2:firewall" || !anyFires {
+		t.Errorf("results = %+v, want one bouncer per host", results)
+	}
+}
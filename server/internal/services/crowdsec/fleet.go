@@ -0,0 +1,84 @@
+package crowdsec
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/dispatch"
+)
+
+// Ban durations accepted on every path that bans an IP on the fleet: the
+// fleet-wide ban of this package and the automated blocking policies
+// (internal/services/ipblock).
+const (
+	DefaultBanDuration = "4h"
+	minBanDuration     = time.Minute
+	maxBanDuration     = 30 * 24 * time.Hour
+)
+
+// ValidateBanDuration checks a CrowdSec ban duration and returns it parsed.
+// field names the offending input in the validation error.
+func ValidateBanDuration(field, duration string) (time.Duration, error) {
+	d, err := time.ParseDuration(duration)
+	if err != nil || d < minBanDuration || d > maxBanDuration {
+		return 0, apperr.Validation(field + " invalide (entre 1m et 720h, ex: 1h, 4h, 24h)")
+	}
+	return d, nil
+}
+
+// HostAction is one crowdsec command (ban or unban of an IP) to send to
+// several hosts, with the audit trail of each dispatch.
+type HostAction struct {
+	Action       string // "ban" or "unban"
+	IP           string
+	Duration     string // ban only
+	TriggeredBy  string
+	ClientIP     string
+	AuditAction  string
+	AuditDetails string
+}
+
+// HostDispatch is the outcome of DispatchToHosts: the hosts a command was
+// created for, in order, and one "<host>: <error>" per host it was not.
+type HostDispatch struct {
+	HostIDs    []string
+	CommandIDs []string
+	Failures   []string
+}
+
+// DispatchToHosts sends a to every host in hostIDs. A host whose dispatch
+// fails is reported in Failures and does not stop the others; deciding
+// whether the action failed as a whole is left to the caller.
+func DispatchToHosts(ctx context.Context, d Dispatcher, hostIDs []string, a HostAction) HostDispatch {
+	payload := "{}"
+	if a.Action == "ban" {
+		payload = fmt.Sprintf(`{"duration":%q}`, a.Duration)
+	}
+	out := HostDispatch{HostIDs: []string{}, CommandIDs: []string{}, Failures: []string{}}
+	for _, hostID := range hostIDs {
+		r, err := d.Create(ctx, dispatch.Request{
+			HostID:      hostID,
+			Module:      "crowdsec",
+			Action:      a.Action,
+			Target:      a.IP,
+			Payload:     payload,
+			TriggeredBy: a.TriggeredBy,
+			Audit: &dispatch.AuditLogRequest{
+				Username:  a.TriggeredBy,
+				Action:    a.AuditAction,
+				HostID:    hostID,
+				IPAddress: a.ClientIP,
+				Details:   a.AuditDetails,
+			},
+		})
+		if err != nil {
+			out.Failures = append(out.Failures, hostID+": "+err.Error())
+			continue
+		}
+		out.HostIDs = append(out.HostIDs, hostID)
+		out.CommandIDs = append(out.CommandIDs, r.Command.ID)
+	}
+	return out
+}
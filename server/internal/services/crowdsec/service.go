@@ -0,0 +1,202 @@
+// Package crowdsec is the application/service layer of the CrowdSec fleet
+// view: every agent reports the decisions of its own local API and the
+// bouncers pulling them, and this package aggregates them — one deduplicated
+// decision list across hosts, the bouncers' pull health — and propagates a
+// ban or unban to every CrowdSec-capable host in one action. Logic sits
+// behind Repository and Dispatcher ports so it is unit-testable without a
+// database.
+package crowdsec
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/dispatch"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	ListCrowdSecHostIDs(ctx context.Context) ([]string, error)
+	ListCrowdSecSyncStatuses(ctx context.Context) ([]models.CrowdSecHostSync, error)
+	ListCrowdSecDecisions(ctx context.Context, value string) ([]models.CrowdSecHostDecision, error)
+	ListCrowdSecBouncers(ctx context.Context, hostID string) ([]models.CrowdSecBouncerStatus, error)
+}
+
+// Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
+type Dispatcher interface {
+	Create(ctx context.Context, req dispatch.Request) (*dispatch.Result, error)
+}
+
+// Service holds the CrowdSec fleet use-cases.
+type Service struct {
+	repo       Repository
+	dispatcher Dispatcher
+	now        func() time.Time
+}
+
+func NewService(repo Repository, dispatcher Dispatcher) *Service {
+	return &Service{repo: repo, dispatcher: dispatcher, now: time.Now}
+}
+
+// Decisions returns the active decisions of every host grouped by IP or
+// range, optionally for one value, with the freshness of each host's
+// snapshot.
+func (s *Service) Decisions(ctx context.Context, value string) (*models.CrowdSecFleetDecisions, error) {
+	statuses, err := s.repo.ListCrowdSecSyncStatuses(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	rows, err := s.repo.ListCrowdSecDecisions(ctx, strings.TrimSpace(value))
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	now := s.now()
+	fresh := make([]string, 0, len(statuses))
+	for i := range statuses {
+		statuses[i].Stale = now.Sub(statuses[i].CollectedAt) > models.CrowdSecSnapshotMaxAge
+		if !statuses[i].Stale {
+			fresh = append(fresh, statuses[i].HostID)
+		}
+	}
+	return &models.CrowdSecFleetDecisions{Hosts: statuses, Decisions: groupDecisions(rows, fresh)}, nil
+}
+
+// groupDecisions folds per-host rows, ordered by value, into one entry per
+// value. Decisions are listed by how many hosts enforce them, most first.
+func groupDecisions(rows []models.CrowdSecHostDecision, freshHostIDs []string) []models.CrowdSecFleetDecision {
+	out := make([]models.CrowdSecFleetDecision, 0)
+	for _, r := range rows {
+		if len(out) == 0 || out[len(out)-1].Value != r.Value {
+			out = append(out, models.CrowdSecFleetDecision{
+				Value: r.Value, Types: []string{}, Scenarios: []string{}, Origins: []string{},
+				Hosts: []models.CrowdSecHostDecision{}, MissingHostIDs: []string{},
+			})
+		}
+		d := &out[len(out)-1]
+		d.Types = appendUnique(d.Types, r.Type)
+		d.Scenarios = appendUnique(d.Scenarios, r.Scenario)
+		d.Origins = appendUnique(d.Origins, r.Origin)
+		if d.Country == "" {
+			d.Country = r.Country
+		}
+		if d.ASName == "" {
+			d.ASName = r.ASName
+		}
+		if r.StartedAt != nil && (d.StartedAt == nil || r.StartedAt.Before(*d.StartedAt)) {
+			d.StartedAt = r.StartedAt
+		}
+		if r.Until != nil && (d.Until == nil || r.Until.After(*d.Until)) {
+			d.Until = r.Until
+		}
+		d.Hosts = append(d.Hosts, r)
+	}
+	for i := range out {
+		for _, hostID := range freshHostIDs {
+			if !slices.ContainsFunc(out[i].Hosts, func(h models.CrowdSecHostDecision) bool { return h.HostID == hostID }) {
+				out[i].MissingHostIDs = append(out[i].MissingHostIDs, hostID)
+			}
+		}
+	}
+	slices.SortStableFunc(out, func(a, b models.CrowdSecFleetDecision) int { return len(b.Hosts) - len(a.Hosts) })
+	return out
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" || slices.Contains(list, v) {
+		return list
+	}
+	return append(list, v)
+}
+
+// Bouncers returns every stored bouncer with its pull age.
+func (s *Service) Bouncers(ctx context.Context) ([]models.CrowdSecBouncerStatus, error) {
+	bouncers, err := s.repo.ListCrowdSecBouncers(ctx, "")
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	now := s.now()
+	for i := range bouncers {
+		age, ok := models.CrowdSecBouncerPullAge(bouncers[i].CrowdSecBouncer, now)
+		if !ok {
+			continue
+		}
+		minutes := age.Minutes()
+		bouncers[i].MinutesSinceLastPull = &minutes
+		bouncers[i].Stale = !bouncers[i].Revoked && age > models.CrowdSecBouncerStaleAfter
+	}
+	return bouncers, nil
+}
+
+// BanEverywhere bans ip on every host able to apply a CrowdSec decision.
+func (s *Service) BanEverywhere(ctx context.Context, in models.CrowdSecFleetActionInput, username, clientIP string) (*models.CrowdSecFleetAction, error) {
+	ip, err := parseFleetIP(in.IP)
+	if err != nil {
+		return nil, err
+	}
+	duration := strings.TrimSpace(in.Duration)
+	if duration == "" {
+		duration = DefaultBanDuration
+	}
+	if _, err := ValidateBanDuration("duration", duration); err != nil {
+		return nil, err
+	}
+	return s.dispatchEverywhere(ctx, HostAction{
+		Action: "ban", IP: ip, Duration: duration, TriggeredBy: username, ClientIP: clientIP,
+		AuditAction:  "crowdsec_fleet_ban",
+		AuditDetails: fmt.Sprintf(`{"ip":%q,"duration":%q,"fleet":true}`, ip, duration),
+	})
+}
+
+// UnbanEverywhere removes every decision on ip from every host able to apply
+// a CrowdSec decision.
+func (s *Service) UnbanEverywhere(ctx context.Context, rawIP, username, clientIP string) (*models.CrowdSecFleetAction, error) {
+	ip, err := parseFleetIP(rawIP)
+	if err != nil {
+		return nil, err
+	}
+	return s.dispatchEverywhere(ctx, HostAction{
+		Action: "unban", IP: ip, TriggeredBy: username, ClientIP: clientIP,
+		AuditAction:  "crowdsec_fleet_unban",
+		AuditDetails: fmt.Sprintf(`{"ip":%q,"fleet":true}`, ip),
+	})
+}
+
+func parseFleetIP(raw string) (string, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(raw))
+	if err != nil {
+		return "", apperr.Validation("invalid IP address")
+	}
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsUnspecified() {
+		return "", apperr.Validation("cette adresse ne peut pas être bannie")
+	}
+	return addr.String(), nil
+}
+
+// dispatchEverywhere sends a to every capable host. A host whose dispatch
+// fails is reported in Failures; the action only fails as a whole when no host
+// could be reached.
+func (s *Service) dispatchEverywhere(ctx context.Context, a HostAction) (*models.CrowdSecFleetAction, error) {
+	hosts, err := s.repo.ListCrowdSecHostIDs(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if len(hosts) == 0 {
+		return nil, apperr.Conflict("aucun hôte ne peut appliquer de décision CrowdSec")
+	}
+	sent := DispatchToHosts(ctx, s.dispatcher, hosts, a)
+	if len(sent.CommandIDs) == 0 {
+		return nil, apperr.Failed("aucune commande CrowdSec n'a pu être envoyée : " + strings.Join(sent.Failures, "; "))
+	}
+	return &models.CrowdSecFleetAction{
+		IP: a.IP, Action: a.Action, Duration: a.Duration,
+		HostIDs: sent.HostIDs, CommandIDs: sent.CommandIDs, Failures: sent.Failures,
+	}, nil
+}
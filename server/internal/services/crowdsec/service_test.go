@@ -0,0 +1,184 @@
+package crowdsec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/dispatch"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type fakeRepo struct {
+	hosts     []string
+	statuses  []models.CrowdSecHostSync
+	decisions []models.CrowdSecHostDecision
+	bouncers  []models.CrowdSecBouncerStatus
+}
+
+func (f *fakeRepo) ListCrowdSecHostIDs(context.Context) ([]string, error) { return f.hosts, nil }
+
+func (f *fakeRepo) ListCrowdSecSyncStatuses(context.Context) ([]models.CrowdSecHostSync, error) {
+	return f.statuses, nil
+}
+
+func (f *fakeRepo) ListCrowdSecDecisions(_ context.Context, value string) ([]models.CrowdSecHostDecision, error) {
+	out := []models.CrowdSecHostDecision{}
+	for _, d := range f.decisions {
+		if value == "" || d.Value == value {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) ListCrowdSecBouncers(context.Context, string) ([]models.CrowdSecBouncerStatus, error) {
+	return f.bouncers, nil
+}
+
+type fakeDispatcher struct {
+	reqs []dispatch.Request
+	fail map[string]bool
+}
+
+func (f *fakeDispatcher) Create(_ context.Context, req dispatch.Request) (*dispatch.Result, error) {
+	f.reqs = append(f.reqs, req)
+	if f.fail[req.HostID] {
+		return nil, errors.New("host offline")
+	}
+	return &dispatch.Result{Command: &models.RemoteCommand{ID: fmt.Sprintf("cmd-%d", len(f.reqs))}}, nil
+}
+
+func newTestService(repo *fakeRepo, d *fakeDispatcher) *Service {
+	s := NewService(repo, d)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+func at(d time.Duration) *time.Time {
+	t := testNow.Add(d)
+	return &t
+}
+
+func TestDecisions_DeduplicatesAcrossHosts(t *testing.T) {
+	repo := &fakeRepo{
+		statuses: []models.CrowdSecHostSync{
+			{HostID: "h1", HostName: "alpha", CollectedAt: testNow.Add(-time.Minute)},
+			{HostID: "h2", HostName: "beta", CollectedAt: testNow.Add(-2 * time.Minute)},
+			{HostID: "h3", HostName: "gamma", CollectedAt: testNow.Add(-time.Hour)},
+		},
+		// Ordered by value, like the repository.
+		decisions: []models.CrowdSecHostDecision{
+			{HostID: "h1", Value: "198.51.100.7", Type: "ban", Scenario: "manual", Origin: "cscli", Until: at(time.Hour)},
+			{HostID: "h1", Value: "203.0.113.9", Type: "ban", Scenario: "crowdsecurity/ssh-bf", Origin: "crowdsec",
+				Country: "FR", StartedAt: at(-time.Hour), Until: at(time.Hour)},
+			{HostID: "h2", Value: "203.0.113.9", Type: "ban", Scenario: "crowdsecurity/http-probing", Origin: "crowdsec",
+				StartedAt: at(-2 * time.Hour), Until: at(3 * time.Hour)},
+		},
+	}
+	got, err := newTestService(repo, &fakeDispatcher{}).Decisions(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Hosts) != 3 || got.Hosts[0].Stale || got.Hosts[1].Stale || !got.Hosts[2].Stale {
+		t.Errorf("hosts = %+v, want only gamma stale", got.Hosts)
+	}
+	if len(got.Decisions) != 2 {
+		t.Fatalf("decisions = %+v, want 2 values", got.Decisions)
+	}
+	d := got.Decisions[0]
+	if d.Value != "203.0.113.9" || len(d.Hosts) != 2 || len(d.Scenarios) != 2 || len(d.Types) != 1 || d.Country != "FR" {
+		t.Errorf("most shared decision first, merged: %+v", d)
+	}
+	if !d.StartedAt.Equal(*at(-2 * time.Hour)) || !d.Until.Equal(*at(3 * time.Hour)) {
+		t.Errorf("span = %v → %v, want earliest start and latest expiry", d.StartedAt, d.Until)
+	}
+	if len(d.MissingHostIDs) != 0 {
+		t.Errorf("a stale host is never missing: %v", d.MissingHostIDs)
+	}
+	if m := got.Decisions[1].MissingHostIDs; len(m) != 1 || m[0] != "h2" {
+		t.Errorf("missing = %v, want h2", m)
+	}
+}
+
+func TestBouncers_PullAge(t *testing.T) {
+	repo := &fakeRepo{bouncers: []models.CrowdSecBouncerStatus{
+		{CrowdSecBouncer: models.CrowdSecBouncer{Name: "fw", LastPull: testNow.Add(-30 * time.Second)}},
+		{CrowdSecBouncer: models.CrowdSecBouncer{Name: "nginx", LastPull: testNow.Add(-time.Hour)}},
+		{CrowdSecBouncer: models.CrowdSecBouncer{Name: "new", CreatedAt: testNow.Add(-20 * time.Minute)}},
+		{CrowdSecBouncer: models.CrowdSecBouncer{Name: "old", LastPull: testNow.Add(-time.Hour), Revoked: true}},
+		{CrowdSecBouncer: models.CrowdSecBouncer{Name: "unknown"}},
+	}}
+	got, err := newTestService(repo, &fakeDispatcher{}).Bouncers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStale := []bool{false, true, true, false, false}
+	for i, b := range got {
+		if b.Stale != wantStale[i] {
+			t.Errorf("%s: stale = %v, want %v", b.Name, b.Stale, wantStale[i])
+		}
+	}
+	if m := got[1].MinutesSinceLastPull; m == nil || *m != 60 {
+		t.Errorf("nginx minutes = %v, want 60", m)
+	}
+	if m := got[2].MinutesSinceLastPull; m == nil || *m != 20 {
+		t.Errorf("a bouncer that never pulled counts from its registration, got %v", m)
+	}
+	if got[4].MinutesSinceLastPull != nil {
+		t.Error("no time known means no age")
+	}
+}
+
+func TestBanEverywhere(t *testing.T) {
+	repo := &fakeRepo{hosts: []string{"h1", "h2", "h3"}}
+	d := &fakeDispatcher{fail: map[string]bool{"h2": true}}
+	svc := newTestService(repo, d)
+
+	res, err := svc.BanEverywhere(context.Background(), models.CrowdSecFleetActionInput{IP: " 203.0.113.9 "}, "admin", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.reqs) != 3 || d.reqs[0].Action != "ban" || d.reqs[0].Target != "203.0.113.9" ||
+		d.reqs[0].Payload != `{"duration":"4h"}` || d.reqs[0].Audit.Action != "crowdsec_fleet_ban" {
+		t.Errorf("requests = %+v", d.reqs)
+	}
+	if len(res.HostIDs) != 2 || res.HostIDs[1] != "h3" || len(res.Failures) != 1 || res.Duration != "4h" {
+		t.Errorf("result = %+v, want h1 and h3 applied, h2 failed", res)
+	}
+
+	for _, in := range []models.CrowdSecFleetActionInput{
+		{IP: "not-an-ip"},
+		{IP: "127.0.0.1"},
+		{IP: "203.0.113.9", Duration: "10s"},
+		{IP: "203.0.113.9", Duration: "9999h"},
+	} {
+		if _, err := svc.BanEverywhere(context.Background(), in, "admin", ""); err == nil || apperr.From(err).Code != "validation" {
+			t.Errorf("%+v: err = %v, want validation", in, err)
+		}
+	}
+}
+
+func TestUnbanEverywhere(t *testing.T) {
+	d := &fakeDispatcher{}
+	res, err := newTestService(&fakeRepo{hosts: []string{"h1"}}, d).UnbanEverywhere(context.Background(), "::ffff:203.0.113.9", "admin", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IP != "203.0.113.9" || len(d.reqs) != 1 || d.reqs[0].Action != "unban" || d.reqs[0].Payload != "{}" {
+		t.Errorf("result = %+v, requests = %+v", res, d.reqs)
+	}
+
+	if _, err := newTestService(&fakeRepo{}, d).UnbanEverywhere(context.Background(), "203.0.113.9", "admin", ""); err == nil || apperr.From(err).Code != "conflict" {
+		t.Errorf("no capable host: err = %v, want conflict", err)
+	}
+	all := &fakeDispatcher{fail: map[string]bool{"h1": true}}
+	if _, err := newTestService(&fakeRepo{hosts: []string{"h1"}}, all).UnbanEverywhere(context.Background(), "203.0.113.9", "admin", ""); err == nil || apperr.From(err).Code != "failed" {
+		t.Errorf("every dispatch failed: err = %v, want failed", err)
+	}
+}
@@ -6,9 +6,12 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/serversupervisor/server/internal/apperr"
 	"github.com/serversupervisor/server/internal/dispatch"
@@ -21,6 +24,7 @@ type Repository interface {
 	GetAllDockerContainers(ctx context.Context) ([]models.DockerContainer, error)
 	GetAllComposeProjects(ctx context.Context) ([]models.ComposeProject, error)
 	GetComposeProjectsByHost(ctx context.Context, hostID string) ([]models.ComposeProject, error)
+	GetTopDockerVolumeGrowth(ctx context.Context, since, until time.Time, limit int) ([]models.DockerVolumeGrowth, error)
 }
 
 // Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
@@ -38,6 +42,24 @@ func NewService(repo Repository, dispatcher Dispatcher) *Service {
 	return &Service{repo: repo, dispatcher: dispatcher}
 }
 
+// validComposeProject mirrors the agent's allow-list for compose project names
+// (agent/internal/dispatcher validComposeName) so a bad name is rejected with a
+// 400 here instead of a failed command later.
+var validComposeProject = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// maxComposeFileBytes bounds the edited compose file (and .env) carried in a
+// command payload. Real compose files are a few KB; this only stops abuse.
+const maxComposeFileBytes = 256 * 1024
+
+// composeFileAgentActions maps the editor actions to the agent's compose module
+// actions.
+var composeFileAgentActions = map[string]string{
+	"read":     "read_file",
+	"validate": "validate_file",
+	"apply":    "apply_file",
+	"rollback": "rollback_file",
+}
+
 // isValidWorkingDir returns true when p is empty or an absolute path that does
 // not escape its root via ".." components.
 func isValidWorkingDir(p string) bool {
@@ -103,6 +125,61 @@ func (s *Service) SendCommand(ctx context.Context, req models.DockerCommandReque
 	return result.Command.ID, nil
 }
 
+// SendComposeFileCommand validates a compose file editor request and dispatches
+// it as a module=compose command, returning the queued command id. The file
+// content never reaches the audit log — only its size — since it may carry
+// secrets the operator typed in.
+func (s *Service) SendComposeFileCommand(ctx context.Context, req models.ComposeFileCommandRequest, username, clientIP string) (string, error) {
+	if !validComposeProject.MatchString(req.Project) {
+		return "", apperr.Validation("invalid compose project name")
+	}
+	agentAction, ok := composeFileAgentActions[req.Action]
+	if !ok {
+		return "", apperr.Validation("invalid action")
+	}
+	payload := "{}"
+	switch req.Action {
+	case "validate", "apply":
+		if strings.TrimSpace(req.Compose) == "" {
+			return "", apperr.Validation("compose content is required")
+		}
+		if len(req.Compose) > maxComposeFileBytes || (req.Env != nil && len(*req.Env) > maxComposeFileBytes) {
+			return "", apperr.Validation(fmt.Sprintf("compose file and .env must each be at most %d bytes", maxComposeFileBytes))
+		}
+		b, err := json.Marshal(map[string]any{
+			"compose":                 req.Compose,
+			"env":                     req.Env,
+			"healthcheck_timeout_sec": req.HealthTimeoutSec,
+		})
+		if err != nil {
+			return "", err
+		}
+		payload = string(b)
+	case "rollback":
+		payload = fmt.Sprintf(`{"healthcheck_timeout_sec":%d}`, req.HealthTimeoutSec)
+	}
+
+	result, err := s.dispatcher.Create(ctx, dispatch.Request{
+		HostID:      req.HostID,
+		Module:      "compose",
+		Action:      agentAction,
+		Target:      req.Project,
+		Payload:     payload,
+		TriggeredBy: username,
+		Audit: &dispatch.AuditLogRequest{
+			Username:  username,
+			Action:    "compose_file_" + req.Action,
+			HostID:    req.HostID,
+			IPAddress: clientIP,
+			Details:   fmt.Sprintf(`{"project":%q,"action":%q,"compose_bytes":%d}`, req.Project, req.Action, len(req.Compose)),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Command.ID, nil
+}
+
 // ComposeProjects returns all compose projects across hosts (never nil).
 func (s *Service) ComposeProjects(ctx context.Context) ([]models.ComposeProject, error) {
 	projects, err := s.repo.GetAllComposeProjects(ctx)
@@ -121,6 +198,19 @@ func (s *Service) HostComposeProjects(ctx context.Context, hostID string) ([]mod
 	return nonNilProjects(projects), nil
 }
 
+// TopVolumeGrowth returns the volumes that grew the most across the fleet
+// between since and until (never nil).
+func (s *Service) TopVolumeGrowth(ctx context.Context, since, until time.Time, limit int) ([]models.DockerVolumeGrowth, error) {
+	rows, err := s.repo.GetTopDockerVolumeGrowth(ctx, since, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return []models.DockerVolumeGrowth{}, nil
+	}
+	return rows, nil
+}
+
 func nonNilContainers(v []models.DockerContainer) []models.DockerContainer {
 	if v == nil {
 		return []models.DockerContainer{}
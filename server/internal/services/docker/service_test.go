@@ -2,9 +2,12 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/serversupervisor/server/internal/apperr"
 	"github.com/serversupervisor/server/internal/dispatch"
@@ -27,6 +30,9 @@ func (f *fakeRepo) GetAllComposeProjects(context.Context) ([]models.ComposeProje
 func (f *fakeRepo) GetComposeProjectsByHost(context.Context, string) ([]models.ComposeProject, error) {
 	return nil, nil
 }
+func (f *fakeRepo) GetTopDockerVolumeGrowth(context.Context, time.Time, time.Time, int) ([]models.DockerVolumeGrowth, error) {
+	return nil, nil
+}
 
 type fakeDispatcher struct{ req dispatch.Request }
 
@@ -90,6 +96,54 @@ func TestSendCommand_Dispatches(t *testing.T) {
 	}
 }
 
+func TestSendComposeFileCommand_Validation(t *testing.T) {
+	cases := []models.ComposeFileCommandRequest{
+		{HostID: "h1", Project: "--force", Action: "read"},
+		{HostID: "h1", Project: "web", Action: "delete"},
+		{HostID: "h1", Project: "web", Action: "apply", Compose: "  "},
+		{HostID: "h1", Project: "web", Action: "validate", Compose: strings.Repeat("x", maxComposeFileBytes+1)},
+	}
+	for _, req := range cases {
+		disp := &fakeDispatcher{}
+		_, err := NewService(&fakeRepo{}, disp).SendComposeFileCommand(context.Background(), req, "alice", "1.2.3.4")
+		var ae *apperr.Error
+		if !errors.As(err, &ae) || ae.HTTPStatus != 400 {
+			t.Errorf("%+v: want apperr 400, got %v", req.Action, err)
+		}
+		if disp.req.Module != "" {
+			t.Errorf("%s: must not dispatch when validation fails", req.Action)
+		}
+	}
+}
+
+func TestSendComposeFileCommand_Dispatches(t *testing.T) {
+	disp := &fakeDispatcher{}
+	env := "PORT=80"
+	_, err := NewService(&fakeRepo{}, disp).SendComposeFileCommand(context.Background(),
+		models.ComposeFileCommandRequest{HostID: "h1", Project: "web", Action: "apply", Compose: "services: {}", Env: &env, HealthTimeoutSec: 30},
+		"alice", "1.2.3.4")
+	if err != nil {
+		t.Fatalf("SendComposeFileCommand: %v", err)
+	}
+	if disp.req.Module != "compose" || disp.req.Action != "apply_file" || disp.req.Target != "web" {
+		t.Errorf("unexpected dispatch: %+v", disp.req)
+	}
+	var p struct {
+		Compose string  `json:"compose"`
+		Env     *string `json:"env"`
+		Timeout int     `json:"healthcheck_timeout_sec"`
+	}
+	if err := json.Unmarshal([]byte(disp.req.Payload), &p); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if p.Compose != "services: {}" || p.Env == nil || *p.Env != env || p.Timeout != 30 {
+		t.Errorf("unexpected payload: %s", disp.req.Payload)
+	}
+	if strings.Contains(disp.req.Audit.Details, "services") {
+		t.Errorf("audit details must not carry file content: %s", disp.req.Audit.Details)
+	}
+}
+
 func TestAllContainers_Paginates(t *testing.T) {
 	all := make([]models.DockerContainer, 5)
 	svc := NewService(&fakeRepo{all: all}, &fakeDispatcher{})
@@ -0,0 +1,243 @@
+// Package flowpolicy is the application/service layer for the network flow
+// policies checked by internal/netpolicy: admins scope a policy to hosts or
+// tags and list the peers they are expected to talk to (allow) or must not
+// reach (deny). The agent service records the violations on ingestion; this
+// package edits the policies and lists the violations. Logic sits behind a
+// Repository port so it is unit-testable without a database.
+package flowpolicy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/geoip"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/netpolicy"
+)
+
+const (
+	maxRules          = 200
+	maxScopeEntries   = 200
+	defaultListLimit  = 200
+	maxListLimit      = 1000
+	defaultListWindow = 24 * time.Hour
+	maxListWindow     = 14 * 24 * time.Hour
+)
+
+var (
+	directions = map[string]bool{"outbound": true, "inbound": true, "any": true}
+	subjects   = map[string]bool{"any": true, "container": true, "host": true}
+	modes      = map[string]bool{models.NetworkFlowPolicyAllow: true, models.NetworkFlowPolicyDeny: true}
+)
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	ListNetworkFlowPolicies(ctx context.Context, enabledOnly bool) ([]models.NetworkFlowPolicy, error)
+	GetNetworkFlowPolicy(ctx context.Context, id string) (*models.NetworkFlowPolicy, error)
+	CreateNetworkFlowPolicy(ctx context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error)
+	UpdateNetworkFlowPolicy(ctx context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error)
+	DeleteNetworkFlowPolicy(ctx context.Context, id string) (bool, error)
+	ListNetworkFlowViolations(ctx context.Context, hostID, policyID string, since time.Time, limit int) ([]models.NetworkFlowViolation, error)
+}
+
+// Service holds the network flow policy use-cases.
+type Service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// ListPolicies returns every policy (never nil).
+func (s *Service) ListPolicies(ctx context.Context) ([]models.NetworkFlowPolicy, error) {
+	policies, err := s.repo.ListNetworkFlowPolicies(ctx, false)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if policies == nil {
+		policies = []models.NetworkFlowPolicy{}
+	}
+	return policies, nil
+}
+
+// applyInput validates in and copies the fields it sets onto p.
+func applyInput(p *models.NetworkFlowPolicy, in models.NetworkFlowPolicyInput) error {
+	if in.Name != "" || p.Name == "" {
+		name := strings.TrimSpace(in.Name)
+		if name == "" || len(name) > 100 {
+			return apperr.Validation("name requis (100 caractères max)")
+		}
+		p.Name = name
+	}
+	if in.Description != nil {
+		p.Description = strings.TrimSpace(*in.Description)
+	}
+	if in.Enabled != nil {
+		p.Enabled = *in.Enabled
+	}
+	if in.HostIDs != nil {
+		ids, err := cleanList("host_ids", in.HostIDs)
+		if err != nil {
+			return err
+		}
+		p.HostIDs = ids
+	}
+	if in.Tags != nil {
+		tags, err := cleanList("tags", in.Tags)
+		if err != nil {
+			return err
+		}
+		p.Tags = tags
+	}
+	if in.Direction != nil {
+		p.Direction = strings.ToLower(strings.TrimSpace(*in.Direction))
+	}
+	if !directions[p.Direction] {
+		return apperr.Validation("direction : outbound, inbound ou any")
+	}
+	if in.Subject != nil {
+		p.Subject = strings.ToLower(strings.TrimSpace(*in.Subject))
+	}
+	if !subjects[p.Subject] {
+		return apperr.Validation("subject : any, container ou host")
+	}
+	if in.Mode != nil {
+		p.Mode = strings.ToLower(strings.TrimSpace(*in.Mode))
+	}
+	if !modes[p.Mode] {
+		return apperr.Validation("mode : allow ou deny")
+	}
+	if in.Rules != nil {
+		if len(in.Rules) > maxRules {
+			return apperr.Validation(fmt.Sprintf("rules : %d règles max", maxRules))
+		}
+		rules := make([]models.NetworkFlowPolicyRule, len(in.Rules))
+		for i, r := range in.Rules {
+			if err := netpolicy.NormalizeRule(&r); err != nil {
+				return apperr.Validation(fmt.Sprintf("règle %d : %v", i+1, err))
+			}
+			rules[i] = r
+		}
+		p.Rules = rules
+	}
+	// An empty deny list forbids nothing; an empty allow list flags every
+	// flow, which is almost always a mistake rather than an intent.
+	if len(p.Rules) == 0 {
+		return apperr.Validation("au moins une règle est requise")
+	}
+	return nil
+}
+
+func cleanList(field string, in []string) ([]string, error) {
+	if len(in) > maxScopeEntries {
+		return nil, apperr.Validation(fmt.Sprintf("%s : %d entrées max", field, maxScopeEntries))
+	}
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// CreatePolicy adds a policy. Omitted direction and subject default to
+// outbound flows of any process.
+func (s *Service) CreatePolicy(ctx context.Context, in models.NetworkFlowPolicyInput, createdBy string) (*models.NetworkFlowPolicy, error) {
+	p := &models.NetworkFlowPolicy{
+		Enabled:   true,
+		HostIDs:   []string{},
+		Tags:      []string{},
+		Direction: "outbound",
+		Subject:   "any",
+		CreatedBy: createdBy,
+	}
+	if err := applyInput(p, in); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateNetworkFlowPolicy(ctx, p)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return created, nil
+}
+
+// UpdatePolicy edits the fields set in the input.
+func (s *Service) UpdatePolicy(ctx context.Context, id string, in models.NetworkFlowPolicyInput) (*models.NetworkFlowPolicy, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperr.NotFound("politique introuvable")
+	}
+	p, err := s.repo.GetNetworkFlowPolicy(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("politique introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	if err := applyInput(p, in); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateNetworkFlowPolicy(ctx, p)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("politique introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	return updated, nil
+}
+
+// DeletePolicy removes a policy and its violations.
+func (s *Service) DeletePolicy(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.NotFound("politique introuvable")
+	}
+	ok, err := s.repo.DeleteNetworkFlowPolicy(ctx, id)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("politique introuvable")
+	}
+	return nil
+}
+
+// ListViolations returns the violations seen over the last hours (24 by
+// default, 14 days max), most recent first, optionally for one host and/or
+// one policy.
+func (s *Service) ListViolations(ctx context.Context, hostID, policyID string, hours, limit int) ([]models.NetworkFlowViolation, error) {
+	window := time.Duration(hours) * time.Hour
+	if window <= 0 {
+		window = defaultListWindow
+	}
+	if window > maxListWindow {
+		window = maxListWindow
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	violations, err := s.repo.ListNetworkFlowViolations(ctx, strings.TrimSpace(hostID), strings.TrimSpace(policyID),
+		s.now().Add(-window), limit)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	for i := range violations {
+		violations[i].Container = netpolicy.ContainerName(violations[i].ProcessName)
+		if geo := geoip.Lookup(violations[i].RemoteIP); geo != (models.GeoInfo{}) {
+			violations[i].Geo = &geo
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,152 @@
+package flowpolicy
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const policyID = "0b6d4c1a-5f2e-4a8b-9c3d-7e1f2a3b4c5d"
+
+type fakeRepo struct {
+	policies   map[string]models.NetworkFlowPolicy
+	violations []models.NetworkFlowViolation
+	since      time.Time
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{policies: map[string]models.NetworkFlowPolicy{}}
+}
+
+func (f *fakeRepo) ListNetworkFlowPolicies(_ context.Context, enabledOnly bool) ([]models.NetworkFlowPolicy, error) {
+	out := make([]models.NetworkFlowPolicy, 0, len(f.policies))
+	for _, p := range f.policies {
+		if p.Enabled || !enabledOnly {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) GetNetworkFlowPolicy(_ context.Context, id string) (*models.NetworkFlowPolicy, error) {
+	p, ok := f.policies[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+func (f *fakeRepo) CreateNetworkFlowPolicy(_ context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error) {
+	p.ID = policyID
+	f.policies[p.ID] = *p
+	return p, nil
+}
+
+func (f *fakeRepo) UpdateNetworkFlowPolicy(_ context.Context, p *models.NetworkFlowPolicy) (*models.NetworkFlowPolicy, error) {
+	f.policies[p.ID] = *p
+	return p, nil
+}
+
+func (f *fakeRepo) DeleteNetworkFlowPolicy(_ context.Context, id string) (bool, error) {
+	_, ok := f.policies[id]
+	delete(f.policies, id)
+	return ok, nil
+}
+
+func (f *fakeRepo) ListNetworkFlowViolations(_ context.Context, _, _ string, since time.Time, _ int) ([]models.NetworkFlowViolation, error) {
+	f.since = since
+	return f.violations, nil
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if err == nil || apperr.From(err).Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCreatePolicy_DefaultsAndNormalization(t *testing.T) {
+	svc := NewService(newFakeRepo())
+	p, err := svc.CreatePolicy(context.Background(), models.NetworkFlowPolicyInput{
+		Name: " DB egress ", Tags: []string{"db", " "}, Mode: ptr("allow"),
+		Rules: []models.NetworkFlowPolicyRule{{CIDR: "10.0.0.9/24", Port: 5432, Protocol: "TCP"}},
+	}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "DB egress" || p.Direction != "outbound" || p.Subject != "any" || !p.Enabled ||
+		len(p.Tags) != 1 || p.Rules[0].CIDR != "10.0.0.0/24" || p.Rules[0].Protocol != "tcp" || p.CreatedBy != "admin" {
+		t.Errorf("policy = %+v", p)
+	}
+}
+
+func TestCreatePolicy_Validation(t *testing.T) {
+	svc := NewService(newFakeRepo())
+	rules := []models.NetworkFlowPolicyRule{{Port: 25}}
+	for name, in := range map[string]models.NetworkFlowPolicyInput{
+		"no name":      {Mode: ptr("deny"), Rules: rules},
+		"no mode":      {Name: "x", Rules: rules},
+		"bad mode":     {Name: "x", Mode: ptr("log"), Rules: rules},
+		"bad subject":  {Name: "x", Mode: ptr("deny"), Subject: ptr("pod"), Rules: rules},
+		"bad dir":      {Name: "x", Mode: ptr("deny"), Direction: ptr("both"), Rules: rules},
+		"no rule":      {Name: "x", Mode: ptr("allow")},
+		"invalid rule": {Name: "x", Mode: ptr("deny"), Rules: []models.NetworkFlowPolicyRule{{CIDR: "nope"}}},
+	} {
+		if _, err := svc.CreatePolicy(context.Background(), in, "admin"); err == nil || apperr.From(err).Code != "validation" {
+			t.Errorf("%s: err = %v, want validation", name, err)
+		}
+	}
+}
+
+func TestUpdatePolicy_KeepsOmittedFields(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	if _, err := svc.CreatePolicy(context.Background(), models.NetworkFlowPolicyInput{
+		Name: "No SMTP", Subject: ptr("container"), Mode: ptr("deny"),
+		Rules: []models.NetworkFlowPolicyRule{{Port: 25}},
+	}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := svc.UpdatePolicy(context.Background(), policyID, models.NetworkFlowPolicyInput{Enabled: ptr(false)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Enabled || p.Subject != "container" || p.Mode != "deny" || len(p.Rules) != 1 {
+		t.Errorf("updated = %+v", p)
+	}
+
+	_, err = svc.UpdatePolicy(context.Background(), "not-a-uuid", models.NetworkFlowPolicyInput{})
+	wantCode(t, err, "not_found")
+	wantCode(t, svc.DeletePolicy(context.Background(), "0b6d4c1a-0000-4a8b-9c3d-7e1f2a3b4c5d"), "not_found")
+	if err := svc.DeletePolicy(context.Background(), policyID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListViolations_WindowAndContainer(t *testing.T) {
+	repo := newFakeRepo()
+	repo.violations = []models.NetworkFlowViolation{{RemoteIP: "10.0.0.1", ProcessName: "conteneur: worker"}}
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewService(repo)
+	svc.now = func() time.Time { return now }
+
+	got, err := svc.ListViolations(context.Background(), "", "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !repo.since.Equal(now.Add(-24*time.Hour)) || got[0].Container != "worker" {
+		t.Errorf("since = %v, violation = %+v", repo.since, got[0])
+	}
+	if _, err := svc.ListViolations(context.Background(), "", "", 10000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.since.Equal(now.Add(-maxListWindow)) {
+		t.Errorf("window not capped: since = %v", repo.since)
+	}
+}
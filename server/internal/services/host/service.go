@@ -9,6 +9,7 @@ import (
 	"context"
 	"encoding/json"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	"github.com/serversupervisor/server/internal/database"
 	"github.com/serversupervisor/server/internal/dispatch"
 	"github.com/serversupervisor/server/internal/events"
+	"github.com/serversupervisor/server/internal/geoip"
 	"github.com/serversupervisor/server/internal/models"
 	"github.com/serversupervisor/server/internal/safego"
 )
@@ -44,6 +46,13 @@ type Repository interface {
 	GetLatestNetworkFlowMetrics(ctx context.Context, hostID string) ([]models.NetworkFlowMetric, error)
 	GetNetworkFlowsHistory(ctx context.Context, hostID, remoteIP string, remotePort int, protocol string, since, until time.Time) ([]models.NetworkFlowSummaryPoint, error)
 	GetNetworkFlowsSummary(ctx context.Context, hostID string, since, until time.Time) ([]models.NetworkFlowSummaryPoint, error)
+	GetNetworkFlowsPeerTotals(ctx context.Context, hostID string, since, until time.Time, limit int) ([]models.NetworkFlowPeerTotal, error)
+	GetDNSQueryReport(ctx context.Context, hostID string, since, until time.Time, limit int) (*models.DNSQueryReport, error)
+	GetLatestNetInterfaces(ctx context.Context, hostID string) ([]models.NetInterfaceStatus, error)
+	GetNetInterfaceHistory(ctx context.Context, hostID, name string, since, until time.Time) (*models.NetInterfaceHistory, error)
+	GetDockerDiskUsageSummary(ctx context.Context, hostID string, limit int) (*models.DockerDiskUsageSummary, error)
+	GetDockerDiskUsageHistory(ctx context.Context, hostID, kind, name string, since, until time.Time) ([]models.DockerDiskUsagePoint, error)
+	GetDockerSwarmState(ctx context.Context, hostID string) (*models.DockerSwarmState, error)
 }
 
 // Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
@@ -317,7 +326,7 @@ func (s *Service) Complete(ctx context.Context, id string) (*HostComplete, error
 		AptStatus:          aptStatus,
 		DiskMetrics:        nonNilDiskMetrics(diskMetrics),
 		DiskHealth:         nonNilDiskHealth(diskHealth),
-		NetworkFlows:       nonNilNetworkFlows(networkFlows),
+		NetworkFlows:       withFlowGeo(nonNilNetworkFlows(networkFlows)),
 		CommandHistory:     nonNilCommands(cmdHistory),
 		LatestAgentVersion: s.latestVersion(),
 	}, nil
@@ -425,7 +434,7 @@ func (s *Service) NetworkFlows(ctx context.Context, id string) ([]models.Network
 	if err != nil {
 		return nil, err
 	}
-	return nonNilNetworkFlows(m), nil
+	return withFlowGeo(nonNilNetworkFlows(m)), nil
 }
 
 // NetworkFlowsHistory returns one talker's bandwidth over time (never nil),
@@ -454,6 +463,127 @@ func (s *Service) NetworkFlowsSummary(ctx context.Context, id string, since, unt
 	return points, nil
 }
 
+// dnsReportDomains is how many names each ranking of the DNS report lists.
+const dnsReportDomains = 20
+
+// DNSQueries returns a host's DNS activity over a window (top queried names,
+// names failing with NXDOMAIN, NXDOMAIN rate over time), from the samples of
+// the agent's optional L7 capture. until being zero means "open ended".
+func (s *Service) DNSQueries(ctx context.Context, id string, since, until time.Time) (*models.DNSQueryReport, error) {
+	return s.repo.GetDNSQueryReport(ctx, id, since, until, dnsReportDomains)
+}
+
+// NetInterfaces returns the interfaces of the host's latest report cycle with
+// their current rates (never nil).
+func (s *Service) NetInterfaces(ctx context.Context, id string) ([]models.NetInterfaceStatus, error) {
+	ifaces, err := s.repo.GetLatestNetInterfaces(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ifaces == nil {
+		ifaces = []models.NetInterfaceStatus{}
+	}
+	return ifaces, nil
+}
+
+// NetInterfaceHistory returns one interface's bucketed rates, errors and
+// link-down periods. until being zero means "open ended".
+func (s *Service) NetInterfaceHistory(ctx context.Context, id, name string, since, until time.Time) (*models.NetInterfaceHistory, error) {
+	return s.repo.GetNetInterfaceHistory(ctx, id, name, since, until)
+}
+
+// networkFlowsGeoPeers is how many of the busiest remote IPs the
+// per-country/ASN summary covers.
+const networkFlowsGeoPeers = 1000
+
+// NetworkFlowsGeo sums a host's tracked traffic per remote country or
+// autonomous system (groupBy: geoip.GroupByCountry or geoip.GroupByASN).
+// Peers the databases cannot place (private, unknown) are left out.
+func (s *Service) NetworkFlowsGeo(ctx context.Context, id string, since, until time.Time, groupBy string) ([]models.GeoGroup, error) {
+	if groupBy != geoip.GroupByCountry && groupBy != geoip.GroupByASN {
+		return nil, apperr.Validation("group_by doit valoir country ou asn")
+	}
+	peers, err := s.repo.GetNetworkFlowsPeerTotals(ctx, id, since, until, networkFlowsGeoPeers)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]*models.GeoGroup{}
+	for _, p := range peers {
+		geo := geoip.Lookup(p.RemoteIP)
+		key, label, ok := geoip.GroupKey(geo, groupBy)
+		if !ok {
+			continue
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &models.GeoGroup{Key: key, Label: label}
+			if groupBy == geoip.GroupByASN {
+				g.ASN = geo.ASN
+			} else {
+				g.CountryCode = geo.CountryCode
+			}
+			byKey[key] = g
+		}
+		g.IPs++
+		g.RxBytes += p.RxBytes
+		g.TxBytes += p.TxBytes
+	}
+	out := make([]models.GeoGroup, 0, len(byKey))
+	for _, g := range byKey {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ti, tj := out[i].RxBytes+out[i].TxBytes, out[j].RxBytes+out[j].TxBytes
+		if ti != tj {
+			return ti > tj
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+// withFlowGeo attaches the remote endpoint's geolocation to each talker.
+func withFlowGeo(flows []models.NetworkFlowMetric) []models.NetworkFlowMetric {
+	for i := range flows {
+		if flows[i].IsOthers {
+			continue
+		}
+		if geo := geoip.Lookup(flows[i].RemoteIP); geo != (models.GeoInfo{}) {
+			flows[i].Geo = &geo
+		}
+	}
+	return flows
+}
+
+// dockerDiskTopConsumers caps the "top consumers" list of the disk usage page.
+const dockerDiskTopConsumers = 20
+
+// DockerDiskUsage returns the host's latest Docker disk usage totals and its
+// largest images/containers/volumes. CollectedAt is nil when the agent never
+// reported it (Docker absent or agent too old).
+func (s *Service) DockerDiskUsage(ctx context.Context, id string) (*models.DockerDiskUsageSummary, error) {
+	return s.repo.GetDockerDiskUsageSummary(ctx, id, dockerDiskTopConsumers)
+}
+
+// DockerDiskUsageHistory returns one kind total (empty name) or one volume's /
+// container's size over time (never nil). until being zero means "open ended".
+func (s *Service) DockerDiskUsageHistory(ctx context.Context, id, kind, name string, since, until time.Time) ([]models.DockerDiskUsagePoint, error) {
+	points, err := s.repo.GetDockerDiskUsageHistory(ctx, id, kind, name, since, until)
+	if err != nil {
+		return nil, err
+	}
+	if points == nil {
+		points = []models.DockerDiskUsagePoint{}
+	}
+	return points, nil
+}
+
+// DockerSwarm returns the Swarm services, tasks and nodes last reported by
+// the host. UpdatedAt is nil when the host is not a Swarm manager.
+func (s *Service) DockerSwarm(ctx context.Context, id string) (*models.DockerSwarmState, error) {
+	return s.repo.GetDockerSwarmState(ctx, id)
+}
+
 // resolveTemp overrides the agent-reported CPU temperature with the effective
 // (sensor-source) one when available.
 func (s *Service) resolveTemp(ctx context.Context, id string, metrics *models.SystemMetrics) {
@@ -74,6 +74,27 @@ func (f *fakeRepo) GetNetworkFlowsHistory(context.Context, string, string, int,
 func (f *fakeRepo) GetNetworkFlowsSummary(context.Context, string, time.Time, time.Time) ([]models.NetworkFlowSummaryPoint, error) {
 	return nil, nil
 }
+func (f *fakeRepo) GetNetworkFlowsPeerTotals(context.Context, string, time.Time, time.Time, int) ([]models.NetworkFlowPeerTotal, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetDNSQueryReport(context.Context, string, time.Time, time.Time, int) (*models.DNSQueryReport, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetLatestNetInterfaces(context.Context, string) ([]models.NetInterfaceStatus, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetNetInterfaceHistory(context.Context, string, string, time.Time, time.Time) (*models.NetInterfaceHistory, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetDockerDiskUsageSummary(_ context.Context, hostID string, _ int) (*models.DockerDiskUsageSummary, error) {
+	return &models.DockerDiskUsageSummary{HostID: hostID, TopConsumers: []models.DockerDiskUsageItem{}}, nil
+}
+func (f *fakeRepo) GetDockerDiskUsageHistory(context.Context, string, string, string, time.Time, time.Time) ([]models.DockerDiskUsagePoint, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetDockerSwarmState(_ context.Context, hostID string) (*models.DockerSwarmState, error) {
+	return &models.DockerSwarmState{HostID: hostID}, nil
+}
 func (f *fakeRepo) GetRecentCommandsByHost(context.Context, string, int) ([]models.RemoteCommand, error) {
 	return nil, nil
 }
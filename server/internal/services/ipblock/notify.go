@@ -0,0 +1,59 @@
+package ipblock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/serversupervisor/server/internal/config"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/notifychannels"
+	"github.com/serversupervisor/server/internal/services/push"
+	"github.com/serversupervisor/server/internal/ws"
+)
+
+// ChannelNotifier sends decisions over the configured smtp/ntfy channels and
+// to the browser (WebSocket + Web Push).
+func ChannelNotifier(cfg *config.Config, notifHub *ws.NotificationHub, pushSvc *push.Service) Notifier {
+	d := notifychannels.NewDispatcher(cfg, pushSvc)
+	return func(ctx context.Context, channels []string, dec models.IPBlockDecision) {
+		var subject, title string
+		switch dec.Status {
+		case models.IPBlockStatusDryRun:
+			subject = fmt.Sprintf("[ServerSupervisor] %s serait bloquée (simulation)", dec.IP)
+			title = "Blocage IP simulé"
+		case models.IPBlockStatusApplied:
+			subject = fmt.Sprintf("[ServerSupervisor] %s bloquée pour %s", dec.IP, dec.Duration)
+			title = "IP bloquée automatiquement"
+		default:
+			subject = fmt.Sprintf("[ServerSupervisor] Échec du blocage de %s", dec.IP)
+			title = "Échec du blocage IP"
+		}
+		msg := fmt.Sprintf("Politique « %s » : score %.1f (%s), %d requêtes suspectes sur %d hôte(s) ; ban CrowdSec de %s sur %d hôte(s).",
+			dec.PolicyName, dec.Score, dec.Level, dec.Hits, dec.HostCount, dec.Duration, len(dec.TargetHostIDs))
+		if dec.Error != "" {
+			msg += " Erreur : " + dec.Error
+		}
+		d.Send(ctx, notifychannels.Event{
+			LogID:       fmt.Sprintf("ip_block=%d", dec.ID),
+			Channels:    channels,
+			SMTPSubject: subject,
+			SMTPBody:    msg,
+			SMTPTo:      cfg.SMTPTo,
+			NtfyTitle:   subject,
+			NtfyBody:    msg,
+			NtfyURL:     cfg.NotifyURL,
+			OnBrowser: func() {
+				if notifHub != nil {
+					notifHub.Broadcast(models.WSIPBlockMessage{Type: "ip_block", Notification: dec})
+				}
+			},
+			Push: &push.Payload{
+				Title:  title,
+				Body:   fmt.Sprintf("%s — score %.1f (%s)", dec.IP, dec.Score, dec.PolicyName),
+				Tag:    fmt.Sprintf("ip-block-%d", dec.ID),
+				URL:    "/security",
+				Status: dec.Status,
+			},
+		})
+	}
+}
@@ -0,0 +1,418 @@
+// Package ipblock is the application/service layer for automated IP blocking:
+// admins define policies ("threat score above X over the last N minutes, on
+// any host → CrowdSec ban for D on every host with CrowdSec"), Evaluate runs
+// them periodically on the leader, and every decision is recorded — dry runs
+// included — so it can be audited and reverted in one click. Logic sits
+// behind Repository, Dispatcher and ThreatScorer ports so it is unit-testable
+// without a database.
+package ipblock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/dispatch"
+	"github.com/serversupervisor/server/internal/geoip"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/services/crowdsec"
+)
+
+const (
+	defaultWindowMinutes = 10
+	maxWindowMinutes     = 24 * 60
+	maxAllowlistEntries  = 200
+	// maxDecisionsPerRun bounds how many IPs one policy acts on per
+	// evaluation, so a too-low threshold cannot flood the agents.
+	maxDecisionsPerRun = 20
+	defaultListLimit   = 200
+	maxListLimit       = 1000
+	// minPolicyRunGap is how long after a run a policy cannot be claimed
+	// again: half the evaluation interval, so the next tick always gets it
+	// but a second leader overlapping this one (see cluster.Elector) does not.
+	minPolicyRunGap = 30 * time.Second
+)
+
+var notifyChannels = map[string]bool{"smtp": true, "ntfy": true, "browser": true}
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	ListIPBlockPolicies(ctx context.Context) ([]models.IPBlockPolicy, error)
+	GetIPBlockPolicy(ctx context.Context, id string) (*models.IPBlockPolicy, error)
+	CreateIPBlockPolicy(ctx context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error)
+	UpdateIPBlockPolicy(ctx context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error)
+	DeleteIPBlockPolicy(ctx context.Context, id string) (bool, error)
+	ClaimIPBlockPolicyRun(ctx context.Context, id string, at, notBefore time.Time) (bool, error)
+	ListCrowdSecHostIDs(ctx context.Context) ([]string, error)
+	HasOpenIPBlockDecision(ctx context.Context, ip, policyID string, now time.Time) (bool, error)
+	CreateIPBlockDecision(ctx context.Context, d *models.IPBlockDecision) (*models.IPBlockDecision, error)
+	ListIPBlockDecisions(ctx context.Context, ip string, limit int) ([]models.IPBlockDecision, error)
+	GetIPBlockDecision(ctx context.Context, id int64) (*models.IPBlockDecision, error)
+	MarkIPBlockDecisionReverted(ctx context.Context, id int64, by string, commandIDs []string, failures string) (*models.IPBlockDecision, error)
+}
+
+// Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
+type Dispatcher interface {
+	Create(ctx context.Context, req dispatch.Request) (*dispatch.Result, error)
+}
+
+// ThreatScorer scores the suspicious IPs seen on all hosts over a window.
+// *weblogs.Service satisfies it.
+type ThreatScorer interface {
+	ThreatScores(ctx context.Context, since, until time.Time) ([]models.IPThreatScore, error)
+}
+
+// Notifier tells admins about a decision on the policy's channels. Nil
+// disables notifications.
+type Notifier func(ctx context.Context, channels []string, d models.IPBlockDecision)
+
+// Service holds the IP blocking use-cases.
+type Service struct {
+	repo       Repository
+	dispatcher Dispatcher
+	scorer     ThreatScorer
+	notify     Notifier
+	now        func() time.Time
+}
+
+func NewService(repo Repository, dispatcher Dispatcher, scorer ThreatScorer, notify Notifier) *Service {
+	return &Service{repo: repo, dispatcher: dispatcher, scorer: scorer, notify: notify, now: time.Now}
+}
+
+// ListPolicies returns every policy (never nil).
+func (s *Service) ListPolicies(ctx context.Context) ([]models.IPBlockPolicy, error) {
+	policies, err := s.repo.ListIPBlockPolicies(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if policies == nil {
+		policies = []models.IPBlockPolicy{}
+	}
+	return policies, nil
+}
+
+// applyInput validates in and copies the fields it sets onto p.
+func applyInput(p *models.IPBlockPolicy, in models.IPBlockPolicyInput) error {
+	if in.Name != "" || p.Name == "" {
+		name := strings.TrimSpace(in.Name)
+		if name == "" || len(name) > 100 {
+			return apperr.Validation("name requis (100 caractères max)")
+		}
+		p.Name = name
+	}
+	if in.Enabled != nil {
+		p.Enabled = *in.Enabled
+	}
+	if in.MinScore != nil {
+		p.MinScore = *in.MinScore
+	}
+	if p.MinScore <= 0 {
+		return apperr.Validation("min_score doit être strictement positif")
+	}
+	if in.WindowMinutes != nil {
+		p.WindowMinutes = *in.WindowMinutes
+	}
+	if p.WindowMinutes < 1 || p.WindowMinutes > maxWindowMinutes {
+		return apperr.Validation(fmt.Sprintf("window_minutes doit être compris entre 1 et %d", maxWindowMinutes))
+	}
+	if in.BanDuration != nil {
+		p.BanDuration = strings.TrimSpace(*in.BanDuration)
+	}
+	if _, err := crowdsec.ValidateBanDuration("ban_duration", p.BanDuration); err != nil {
+		return err
+	}
+	if in.DryRun != nil {
+		p.DryRun = *in.DryRun
+	}
+	if in.Allowlist != nil {
+		if len(in.Allowlist) > maxAllowlistEntries {
+			return apperr.Validation(fmt.Sprintf("allowlist : %d entrées max", maxAllowlistEntries))
+		}
+		allow := make([]string, 0, len(in.Allowlist))
+		for _, a := range in.Allowlist {
+			a = strings.TrimSpace(a)
+			if _, err := parseAllowEntry(a); err != nil {
+				return apperr.Validation(fmt.Sprintf("allowlist : %q n'est ni une IP ni un CIDR", a))
+			}
+			allow = append(allow, a)
+		}
+		p.Allowlist = allow
+	}
+	if in.NotifyChannels != nil {
+		for _, c := range in.NotifyChannels {
+			if !notifyChannels[c] {
+				return apperr.Validation("notify_channels : smtp, ntfy ou browser")
+			}
+		}
+		p.NotifyChannels = in.NotifyChannels
+	}
+	return nil
+}
+
+func parseAllowEntry(a string) (netip.Prefix, error) {
+	if strings.Contains(a, "/") {
+		p, err := netip.ParsePrefix(a)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(a)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// CreatePolicy adds a policy. It starts in dry run unless the input says
+// otherwise: see what it would block before letting it ban.
+func (s *Service) CreatePolicy(ctx context.Context, in models.IPBlockPolicyInput, createdBy string) (*models.IPBlockPolicy, error) {
+	p := &models.IPBlockPolicy{
+		Enabled:        true,
+		WindowMinutes:  defaultWindowMinutes,
+		BanDuration:    crowdsec.DefaultBanDuration,
+		DryRun:         true,
+		Allowlist:      []string{},
+		NotifyChannels: []string{"browser"},
+		CreatedBy:      createdBy,
+	}
+	if err := applyInput(p, in); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateIPBlockPolicy(ctx, p)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return created, nil
+}
+
+// UpdatePolicy edits the fields set in the input.
+func (s *Service) UpdatePolicy(ctx context.Context, id string, in models.IPBlockPolicyInput) (*models.IPBlockPolicy, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperr.NotFound("politique introuvable")
+	}
+	p, err := s.repo.GetIPBlockPolicy(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("politique introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	if err := applyInput(p, in); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateIPBlockPolicy(ctx, p)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("politique introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	return updated, nil
+}
+
+// DeletePolicy removes a policy. Its bans stay until they expire or are
+// reverted.
+func (s *Service) DeletePolicy(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.NotFound("politique introuvable")
+	}
+	ok, err := s.repo.DeleteIPBlockPolicy(ctx, id)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("politique introuvable")
+	}
+	return nil
+}
+
+// ListDecisions returns the latest decisions, newest first, optionally for
+// one IP.
+func (s *Service) ListDecisions(ctx context.Context, ip string, limit int) ([]models.IPBlockDecision, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+	decisions, err := s.repo.ListIPBlockDecisions(ctx, strings.TrimSpace(ip), limit)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	for i := range decisions {
+		if geo := geoip.Lookup(decisions[i].IP); geo != (models.GeoInfo{}) {
+			decisions[i].Geo = &geo
+		}
+	}
+	return decisions, nil
+}
+
+// Evaluate runs every enabled policy once. Called periodically on the leader.
+func (s *Service) Evaluate(ctx context.Context) {
+	policies, err := s.repo.ListIPBlockPolicies(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "ip block: failed to list policies", slog.Any("err", err))
+		return
+	}
+	for _, p := range policies {
+		if !p.Enabled {
+			continue
+		}
+		if err := s.evaluatePolicy(ctx, p); err != nil {
+			slog.ErrorContext(ctx, "ip block: policy evaluation failed", slog.String("policy", p.Name), slog.Any("err", err))
+		}
+	}
+}
+
+func (s *Service) evaluatePolicy(ctx context.Context, p models.IPBlockPolicy) error {
+	now := s.now().UTC()
+	// Two leaders may overlap for a few seconds after a failover: the claim
+	// makes sure only one of them dispatches bans for this run. The other
+	// one's next run finds the decisions already open.
+	claimed, err := s.repo.ClaimIPBlockPolicyRun(ctx, p.ID, now, now.Add(-minPolicyRunGap))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	scores, err := s.scorer.ThreatScores(ctx, now.Add(-time.Duration(p.WindowMinutes)*time.Minute), now)
+	if err != nil {
+		return err
+	}
+	allow := make([]netip.Prefix, 0, len(p.Allowlist))
+	for _, a := range p.Allowlist {
+		if prefix, err := parseAllowEntry(a); err == nil {
+			allow = append(allow, prefix)
+		}
+	}
+	banFor, err := time.ParseDuration(p.BanDuration)
+	if err != nil {
+		return err
+	}
+
+	var hosts []string
+	decided := 0
+	for _, sc := range scores {
+		if sc.Score <= p.MinScore {
+			break // sorted by score
+		}
+		if decided >= maxDecisionsPerRun {
+			slog.WarnContext(ctx, "ip block: per-run limit reached, remaining IPs wait for the next run",
+				slog.String("policy", p.Name), slog.Int("limit", maxDecisionsPerRun))
+			break
+		}
+		addr, err := netip.ParseAddr(sc.IP)
+		if err != nil || !blockable(addr.Unmap(), allow) {
+			continue
+		}
+		open, err := s.repo.HasOpenIPBlockDecision(ctx, sc.IP, p.ID, now)
+		if err != nil {
+			return err
+		}
+		if open {
+			continue
+		}
+		if hosts == nil && !p.DryRun {
+			if hosts, err = s.repo.ListCrowdSecHostIDs(ctx); err != nil {
+				return err
+			}
+		}
+		d := s.decide(ctx, p, sc, hosts, now, banFor)
+		stored, err := s.repo.CreateIPBlockDecision(ctx, &d)
+		if err != nil {
+			return err
+		}
+		decided++
+		if s.notify != nil {
+			s.notify(ctx, p.NotifyChannels, *stored)
+		}
+	}
+	return nil
+}
+
+// blockable is false for the allowlist and for addresses a ban could only
+// hurt (loopback, unspecified).
+func blockable(addr netip.Addr, allow []netip.Prefix) bool {
+	if addr.IsLoopback() || addr.IsUnspecified() {
+		return false
+	}
+	for _, p := range allow {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide dispatches the bans of one decision (unless dry run) and returns it.
+func (s *Service) decide(ctx context.Context, p models.IPBlockPolicy, sc models.IPThreatScore, hosts []string, now time.Time, banFor time.Duration) models.IPBlockDecision {
+	d := models.IPBlockDecision{
+		PolicyID: p.ID, PolicyName: p.Name, IP: sc.IP,
+		Score: sc.Score, Level: sc.Level, Hits: sc.Hits, UniquePaths: sc.UniquePaths, HostCount: sc.HostCount,
+		Duration: p.BanDuration, TargetHostIDs: []string{}, CommandIDs: []string{},
+		CreatedAt: now, ExpiresAt: now.Add(banFor),
+	}
+	if p.DryRun {
+		d.Status = models.IPBlockStatusDryRun
+		return d
+	}
+	if len(hosts) == 0 {
+		d.Status = models.IPBlockStatusFailed
+		d.Error = "aucun hôte ne peut appliquer de décision CrowdSec"
+		return d
+	}
+	sent := crowdsec.DispatchToHosts(ctx, s.dispatcher, hosts, crowdsec.HostAction{
+		Action: "ban", IP: sc.IP, Duration: p.BanDuration, TriggeredBy: "policy:" + p.Name,
+		AuditAction:  "crowdsec_auto_ban",
+		AuditDetails: fmt.Sprintf(`{"ip":%q,"duration":%q,"score":%s,"policy_id":%q}`, sc.IP, p.BanDuration, strconv.FormatFloat(sc.Score, 'f', 1, 64), p.ID),
+	})
+	d.TargetHostIDs = sent.HostIDs
+	d.CommandIDs = sent.CommandIDs
+	d.Status = models.IPBlockStatusApplied
+	if len(d.CommandIDs) == 0 {
+		d.Status = models.IPBlockStatusFailed
+	}
+	d.Error = strings.Join(sent.Failures, "; ")
+	return d
+}
+
+// Revert unbans the IP of an applied decision on the hosts it was banned on.
+// The IP is not banned again by a policy before the decision would have
+// expired. A host whose unban could not be dispatched is reported in the
+// decision's error; when none could, the decision stays applied.
+func (s *Service) Revert(ctx context.Context, id int64, username, clientIP string) (*models.IPBlockDecision, error) {
+	d, err := s.repo.GetIPBlockDecision(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("décision introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	if d.Status != models.IPBlockStatusApplied {
+		return nil, apperr.Conflict(fmt.Sprintf("décision %s : rien à annuler", d.Status))
+	}
+	sent := crowdsec.DispatchToHosts(ctx, s.dispatcher, d.TargetHostIDs, crowdsec.HostAction{
+		Action: "unban", IP: d.IP, TriggeredBy: username, ClientIP: clientIP,
+		AuditAction:  "crowdsec_auto_ban_revert",
+		AuditDetails: fmt.Sprintf(`{"ip":%q,"decision_id":%d}`, d.IP, d.ID),
+	})
+	if len(sent.CommandIDs) == 0 && len(d.TargetHostIDs) > 0 {
+		return nil, apperr.Failed("aucun unban n'a pu être envoyé, la décision reste appliquée : " + strings.Join(sent.Failures, "; "))
+	}
+	reverted, err := s.repo.MarkIPBlockDecisionReverted(ctx, id, username, sent.CommandIDs, strings.Join(sent.Failures, "; "))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.Conflict("décision déjà annulée")
+		}
+		return nil, apperr.Internal(err)
+	}
+	return reverted, nil
+}
@@ -0,0 +1,345 @@
+package ipblock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/dispatch"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const policyID = "6f1c2f7e-0d3b-4c1e-9a55-1f7f4c2b9d10"
+
+type fakeRepo struct {
+	policies  map[string]models.IPBlockPolicy
+	lastRun   map[string]time.Time
+	decisions []models.IPBlockDecision
+	hosts     []string
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{policies: map[string]models.IPBlockPolicy{}, lastRun: map[string]time.Time{}, hosts: []string{"h1", "h2"}}
+}
+
+func (f *fakeRepo) ListIPBlockPolicies(context.Context) ([]models.IPBlockPolicy, error) {
+	out := make([]models.IPBlockPolicy, 0, len(f.policies))
+	for _, p := range f.policies {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) GetIPBlockPolicy(_ context.Context, id string) (*models.IPBlockPolicy, error) {
+	p, ok := f.policies[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &p, nil
+}
+
+func (f *fakeRepo) CreateIPBlockPolicy(_ context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error) {
+	p.ID = policyID
+	f.policies[p.ID] = *p
+	return p, nil
+}
+
+func (f *fakeRepo) UpdateIPBlockPolicy(_ context.Context, p *models.IPBlockPolicy) (*models.IPBlockPolicy, error) {
+	f.policies[p.ID] = *p
+	return p, nil
+}
+
+func (f *fakeRepo) DeleteIPBlockPolicy(_ context.Context, id string) (bool, error) {
+	_, ok := f.policies[id]
+	delete(f.policies, id)
+	return ok, nil
+}
+
+func (f *fakeRepo) ClaimIPBlockPolicyRun(_ context.Context, id string, at, notBefore time.Time) (bool, error) {
+	if last, ok := f.lastRun[id]; ok && last.After(notBefore) {
+		return false, nil
+	}
+	f.lastRun[id] = at
+	return true, nil
+}
+
+func (f *fakeRepo) ListCrowdSecHostIDs(context.Context) ([]string, error) { return f.hosts, nil }
+
+func (f *fakeRepo) HasOpenIPBlockDecision(_ context.Context, ip, policyID string, now time.Time) (bool, error) {
+	for _, d := range f.decisions {
+		if d.IP == ip && d.ExpiresAt.After(now) && d.Status != models.IPBlockStatusFailed &&
+			(d.Status != models.IPBlockStatusDryRun || d.PolicyID == policyID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeRepo) CreateIPBlockDecision(_ context.Context, d *models.IPBlockDecision) (*models.IPBlockDecision, error) {
+	d.ID = int64(len(f.decisions) + 1)
+	f.decisions = append(f.decisions, *d)
+	return d, nil
+}
+
+func (f *fakeRepo) ListIPBlockDecisions(context.Context, string, int) ([]models.IPBlockDecision, error) {
+	return f.decisions, nil
+}
+
+func (f *fakeRepo) GetIPBlockDecision(_ context.Context, id int64) (*models.IPBlockDecision, error) {
+	if id < 1 || int(id) > len(f.decisions) {
+		return nil, sql.ErrNoRows
+	}
+	d := f.decisions[id-1]
+	return &d, nil
+}
+
+func (f *fakeRepo) MarkIPBlockDecisionReverted(_ context.Context, id int64, by string, commandIDs []string, failures string) (*models.IPBlockDecision, error) {
+	d := &f.decisions[id-1]
+	if d.Status != models.IPBlockStatusApplied {
+		return nil, sql.ErrNoRows
+	}
+	if failures != "" {
+		d.Error = failures
+	}
+	d.Status = models.IPBlockStatusReverted
+	d.RevertedBy = by
+	d.CommandIDs = append(d.CommandIDs, commandIDs...)
+	out := *d
+	return &out, nil
+}
+
+type fakeDispatcher struct {
+	reqs []dispatch.Request
+	fail map[string]bool
+}
+
+func (f *fakeDispatcher) Create(_ context.Context, req dispatch.Request) (*dispatch.Result, error) {
+	f.reqs = append(f.reqs, req)
+	if f.fail[req.HostID] {
+		return nil, errors.New("host offline")
+	}
+	return &dispatch.Result{Command: &models.RemoteCommand{ID: fmt.Sprintf("cmd-%d", len(f.reqs))}}, nil
+}
+
+type fakeScorer struct{ scores []models.IPThreatScore }
+
+func (f *fakeScorer) ThreatScores(context.Context, time.Time, time.Time) ([]models.IPThreatScore, error) {
+	return f.scores, nil
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if err == nil || apperr.From(err).Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func newTestService(t *testing.T, in models.IPBlockPolicyInput) (*Service, *fakeRepo, *fakeDispatcher, *[]models.IPBlockDecision) {
+	t.Helper()
+	repo := newFakeRepo()
+	disp := &fakeDispatcher{}
+	scorer := &fakeScorer{scores: []models.IPThreatScore{
+		{IP: "203.0.113.9", Score: 80, Level: "critical", Hits: 400, HostCount: 2},
+		{IP: "198.51.100.7", Score: 60, Level: "high", Hits: 90, HostCount: 1},
+		{IP: "192.0.2.1", Score: 10, Level: "low", Hits: 5, HostCount: 1},
+	}}
+	var notified []models.IPBlockDecision
+	svc := NewService(repo, disp, scorer, func(_ context.Context, _ []string, d models.IPBlockDecision) {
+		notified = append(notified, d)
+	})
+	// Each run happens one evaluation interval after the previous one.
+	clock := time.Now()
+	svc.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	if _, err := svc.CreatePolicy(context.Background(), in, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	return svc, repo, disp, &notified
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestEvaluate_DryRunByDefaultOnlyNotifies(t *testing.T) {
+	svc, repo, disp, notified := newTestService(t, models.IPBlockPolicyInput{Name: "Scanners", MinScore: ptr(50.0)})
+	svc.Evaluate(context.Background())
+
+	if len(disp.reqs) != 0 {
+		t.Errorf("dry run dispatched %d commands", len(disp.reqs))
+	}
+	if len(repo.decisions) != 2 || len(*notified) != 2 {
+		t.Fatalf("decisions = %d, notified = %d, want 2", len(repo.decisions), len(*notified))
+	}
+	for _, d := range repo.decisions {
+		if d.Status != models.IPBlockStatusDryRun {
+			t.Errorf("%s status = %s, want dry_run", d.IP, d.Status)
+		}
+	}
+
+	// The next run does not report the same IPs again.
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 2 {
+		t.Errorf("decisions after second run = %d, want 2", len(repo.decisions))
+	}
+}
+
+func TestEvaluate_BansOnCrowdSecHostsAndHonoursAllowlist(t *testing.T) {
+	svc, repo, disp, _ := newTestService(t, models.IPBlockPolicyInput{
+		Name: "Ban", MinScore: ptr(50.0), DryRun: ptr(false), Allowlist: []string{"198.51.100.0/24"},
+	})
+	svc.Evaluate(context.Background())
+
+	if len(repo.decisions) != 1 {
+		t.Fatalf("decisions = %+v, want one (the allowlisted IP is skipped)", repo.decisions)
+	}
+	d := repo.decisions[0]
+	if d.IP != "203.0.113.9" || d.Status != models.IPBlockStatusApplied || len(d.CommandIDs) != 2 {
+		t.Errorf("decision = %+v", d)
+	}
+	if d.ExpiresAt.Sub(d.CreatedAt) != 4*time.Hour {
+		t.Errorf("ban length = %s, want the 4h default", d.ExpiresAt.Sub(d.CreatedAt))
+	}
+	for _, r := range disp.reqs {
+		if r.Module != "crowdsec" || r.Action != "ban" || r.Target != "203.0.113.9" || r.TriggeredBy != "policy:Ban" {
+			t.Errorf("request = %+v", r)
+		}
+	}
+}
+
+func TestEvaluate_NoCrowdSecHostFails(t *testing.T) {
+	svc, repo, disp, _ := newTestService(t, models.IPBlockPolicyInput{Name: "Ban", MinScore: ptr(70.0), DryRun: ptr(false)})
+	repo.hosts = nil
+	svc.Evaluate(context.Background())
+	if len(disp.reqs) != 0 || len(repo.decisions) != 1 || repo.decisions[0].Status != models.IPBlockStatusFailed {
+		t.Errorf("decisions = %+v", repo.decisions)
+	}
+}
+
+// TestEvaluate_RetriesFailedDecisions: a ban that reached no host does not
+// keep the IP out of the next runs.
+func TestEvaluate_RetriesFailedDecisions(t *testing.T) {
+	svc, repo, disp, _ := newTestService(t, models.IPBlockPolicyInput{Name: "Ban", MinScore: ptr(70.0), DryRun: ptr(false)})
+	disp.fail = map[string]bool{"h1": true, "h2": true}
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 1 || repo.decisions[0].Status != models.IPBlockStatusFailed {
+		t.Fatalf("decisions = %+v, want one failed", repo.decisions)
+	}
+
+	disp.fail = nil
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 2 {
+		t.Fatalf("decisions = %+v, want the failed ban retried", repo.decisions)
+	}
+	if d := repo.decisions[1]; d.IP != "203.0.113.9" || d.Status != models.IPBlockStatusApplied || len(d.CommandIDs) != 2 {
+		t.Errorf("retry = %+v", d)
+	}
+
+	// The applied ban is not decided again.
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 2 {
+		t.Errorf("decisions after third run = %d, want 2", len(repo.decisions))
+	}
+}
+
+// TestEvaluate_SkipsPolicyClaimedByAnotherLeader: after a failover the old
+// and the new leader may run the job at the same time. The one that did not
+// claim the policy bans nothing, even before the other recorded its decisions.
+func TestEvaluate_SkipsPolicyClaimedByAnotherLeader(t *testing.T) {
+	svc, repo, disp, notified := newTestService(t, models.IPBlockPolicyInput{Name: "Ban", MinScore: ptr(70.0), DryRun: ptr(false)})
+	at := time.Now().Add(time.Hour)
+	svc.now = func() time.Time { return at }
+	for id := range repo.policies {
+		repo.lastRun[id] = at.Add(-5 * time.Second) // the other leader's run, still in flight
+	}
+
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 0 || len(disp.reqs) != 0 || len(*notified) != 0 {
+		t.Fatalf("decisions = %d, commands = %d, notified = %d, want none", len(repo.decisions), len(disp.reqs), len(*notified))
+	}
+
+	// The next tick, one interval later, evaluates it.
+	at = at.Add(time.Minute)
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 1 || len(disp.reqs) != 2 {
+		t.Errorf("decisions = %d, commands = %d after the next tick, want 1 and 2", len(repo.decisions), len(disp.reqs))
+	}
+}
+
+func TestRevert(t *testing.T) {
+	svc, repo, disp, _ := newTestService(t, models.IPBlockPolicyInput{Name: "Ban", MinScore: ptr(70.0), DryRun: ptr(false)})
+	svc.Evaluate(context.Background())
+	disp.reqs = nil
+
+	d, err := svc.Revert(context.Background(), 1, "alice", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != models.IPBlockStatusReverted || d.RevertedBy != "alice" || len(d.CommandIDs) != 4 {
+		t.Errorf("reverted = %+v", d)
+	}
+	if len(disp.reqs) != 2 || disp.reqs[0].Action != "unban" || disp.reqs[0].Audit.IPAddress != "10.0.0.1" {
+		t.Errorf("unban requests = %+v", disp.reqs)
+	}
+	_, err = svc.Revert(context.Background(), 1, "alice", "10.0.0.1")
+	wantCode(t, err, "conflict")
+	_, err = svc.Revert(context.Background(), 42, "alice", "10.0.0.1")
+	wantCode(t, err, "not_found")
+
+	// A reverted ban is not re-applied by the next run.
+	svc.Evaluate(context.Background())
+	if len(repo.decisions) != 1 {
+		t.Errorf("decisions after revert = %d, want 1", len(repo.decisions))
+	}
+}
+
+// TestRevert_DispatchFailures: a host the unban could not reach is reported;
+// with no unban dispatched at all, the decision stays applied.
+func TestRevert_DispatchFailures(t *testing.T) {
+	svc, repo, disp, _ := newTestService(t, models.IPBlockPolicyInput{Name: "Ban", MinScore: ptr(70.0), DryRun: ptr(false)})
+	svc.Evaluate(context.Background())
+	hosts := repo.decisions[0].TargetHostIDs
+
+	disp.fail = map[string]bool{hosts[0]: true, hosts[1]: true}
+	_, err := svc.Revert(context.Background(), 1, "alice", "")
+	wantCode(t, err, "failed")
+	if repo.decisions[0].Status != models.IPBlockStatusApplied {
+		t.Fatalf("status = %s, want still applied", repo.decisions[0].Status)
+	}
+
+	disp.fail = map[string]bool{hosts[0]: true}
+	d, err := svc.Revert(context.Background(), 1, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != models.IPBlockStatusReverted || !strings.Contains(d.Error, hosts[0]+": host offline") {
+		t.Errorf("reverted = %+v, want the failed host reported", d)
+	}
+}
+
+func TestPolicyValidation(t *testing.T) {
+	svc, _, _, _ := newTestService(t, models.IPBlockPolicyInput{Name: "Ok", MinScore: ptr(50.0)})
+	for name, in := range map[string]models.IPBlockPolicyInput{
+		"no name":      {MinScore: ptr(50.0)},
+		"no score":     {Name: "x"},
+		"bad window":   {Name: "x", MinScore: ptr(50.0), WindowMinutes: ptr(0)},
+		"bad duration": {Name: "x", MinScore: ptr(50.0), BanDuration: ptr("4 hours")},
+		"bad allow":    {Name: "x", MinScore: ptr(50.0), Allowlist: []string{"office"}},
+		"bad channel":  {Name: "x", MinScore: ptr(50.0), NotifyChannels: []string{"sms"}},
+	} {
+		if _, err := svc.CreatePolicy(context.Background(), in, "admin"); err == nil || apperr.From(err).Code != "validation" {
+			t.Errorf("%s: err = %v, want validation", name, err)
+		}
+	}
+	_, err := svc.UpdatePolicy(context.Background(), "nope", models.IPBlockPolicyInput{})
+	wantCode(t, err, "not_found")
+	p, err := svc.UpdatePolicy(context.Background(), policyID, models.IPBlockPolicyInput{DryRun: ptr(false)})
+	if err != nil || p.DryRun || p.Name != "Ok" {
+		t.Errorf("update = %+v, %v", p, err)
+	}
+}
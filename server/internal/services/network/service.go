@@ -31,11 +31,25 @@ type SnapshotBuilder func(ctx context.Context) (*models.NetworkSnapshot, error)
 // after this service in router.go, so it can't be a constructor argument.
 type IPInventoryBuilder func(ctx context.Context) (*models.NetworkIPInventory, error)
 
+// DependencyBuilder builds the service dependency graph from the network flows
+// of [since, until] (wired to networkview.BuildDependencies). Same
+// post-construction wiring as IPInventoryBuilder, for the same reason.
+type DependencyBuilder func(ctx context.Context, since, until time.Time) (*models.ServiceDependencyGraph, error)
+
+// TopologyOptions selects the optional layers of TopologySnapshot.
+type TopologyOptions struct {
+	// Dependencies adds the service dependency layer over [Since, Until].
+	Dependencies bool
+	Since        time.Time
+	Until        time.Time
+}
+
 // Service holds the network use-cases.
 type Service struct {
 	repo             Repository
 	build            SnapshotBuilder
 	buildIPInventory IPInventoryBuilder
+	buildDeps        DependencyBuilder
 	bus              *events.Bus
 }
 
@@ -48,6 +62,11 @@ func (s *Service) SetIPInventoryBuilder(build IPInventoryBuilder) {
 	s.buildIPInventory = build
 }
 
+// SetDependencyBuilder wires the dependency graph builder after construction.
+func (s *Service) SetDependencyBuilder(build DependencyBuilder) {
+	s.buildDeps = build
+}
+
 // Snapshot returns the live network snapshot.
 func (s *Service) Snapshot(ctx context.Context) (*models.NetworkSnapshot, error) {
 	return s.build(ctx)
@@ -76,17 +95,27 @@ func (s *Service) SaveTopologyConfig(ctx context.Context, cfg *models.NetworkTop
 	return nil
 }
 
-// TopologySnapshot returns the live snapshot merged with the persisted config.
-func (s *Service) TopologySnapshot(ctx context.Context) (*models.TopologySnapshot, error) {
+// TopologySnapshot returns the live snapshot merged with the persisted config,
+// plus the layers requested in opts.
+func (s *Service) TopologySnapshot(ctx context.Context, opts TopologyOptions) (*models.TopologySnapshot, error) {
 	base, err := s.build(ctx)
 	if err != nil {
 		return nil, err
 	}
 	config, _ := s.repo.GetNetworkTopologyConfig(ctx)
-	return &models.TopologySnapshot{
+	snap := &models.TopologySnapshot{
 		Hosts:      base.Hosts,
 		Containers: base.Containers,
 		Config:     config,
 		UpdatedAt:  time.Now(),
-	}, nil
+	}
+	if opts.Dependencies {
+		if s.buildDeps == nil {
+			return nil, apperr.Internal(errors.New("dependency builder not configured"))
+		}
+		if snap.Dependencies, err = s.buildDeps(ctx, opts.Since, opts.Until); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
 }
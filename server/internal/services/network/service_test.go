@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/serversupervisor/server/internal/models"
 )
@@ -30,7 +31,7 @@ func TestTopologySnapshot_MergesBaseAndConfig(t *testing.T) {
 			Containers: []models.NetworkContainer{{}, {}},
 		}, nil
 	}
-	snap, err := NewService(repo, build, nil).TopologySnapshot(context.Background())
+	snap, err := NewService(repo, build, nil).TopologySnapshot(context.Background(), TopologyOptions{})
 	if err != nil {
 		t.Fatalf("TopologySnapshot: %v", err)
 	}
@@ -43,13 +44,44 @@ func TestTopologySnapshot_MergesBaseAndConfig(t *testing.T) {
 	if snap.UpdatedAt.IsZero() {
 		t.Error("UpdatedAt should be stamped")
 	}
+	if snap.Dependencies != nil {
+		t.Error("the dependency layer should only be built on request")
+	}
+}
+
+func TestTopologySnapshot_DependencyLayer(t *testing.T) {
+	build := func(context.Context) (*models.NetworkSnapshot, error) {
+		return &models.NetworkSnapshot{}, nil
+	}
+	since := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	svc := NewService(&fakeRepo{}, build, nil)
+	opts := TopologyOptions{Dependencies: true, Since: since, Until: until}
+
+	if _, err := svc.TopologySnapshot(context.Background(), opts); err == nil {
+		t.Error("expected an error without a dependency builder")
+	}
+
+	var gotSince, gotUntil time.Time
+	graph := &models.ServiceDependencyGraph{}
+	svc.SetDependencyBuilder(func(_ context.Context, since, until time.Time) (*models.ServiceDependencyGraph, error) {
+		gotSince, gotUntil = since, until
+		return graph, nil
+	})
+	snap, err := svc.TopologySnapshot(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("TopologySnapshot: %v", err)
+	}
+	if snap.Dependencies != graph || !gotSince.Equal(since) || !gotUntil.Equal(until) {
+		t.Errorf("dependency layer = %v over [%v, %v]", snap.Dependencies, gotSince, gotUntil)
+	}
 }
 
 func TestTopologySnapshot_PropagatesBuildError(t *testing.T) {
 	build := func(context.Context) (*models.NetworkSnapshot, error) {
 		return nil, errors.New("boom")
 	}
-	if _, err := NewService(&fakeRepo{}, build, nil).TopologySnapshot(context.Background()); err == nil {
+	if _, err := NewService(&fakeRepo{}, build, nil).TopologySnapshot(context.Background(), TopologyOptions{}); err == nil {
 		t.Error("expected the builder error to propagate")
 	}
 }
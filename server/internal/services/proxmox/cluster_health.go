@@ -0,0 +1,256 @@
+package proxmox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+)
+
+// pollClusterHealth collects corosync quorum, Ceph and HA manager state for
+// one connection. node is any online node, used for the per-node Ceph
+// endpoints (they all return the cluster-wide view). Each part degrades on
+// its own: a cluster without Ceph or HA still gets its quorum tracked.
+func (s *Poller) pollClusterHealth(ctx context.Context, conn database.ProxmoxConnectionFull, client *proxmoxclient.Client,
+	statuses []proxmoxclient.PVEClusterStatus, node string, cutoff time.Time) {
+	h := models.ProxmoxClusterHealth{ConnectionID: conn.ID}
+	applyCorosyncStatus(&h, statuses)
+
+	cephOK := false
+	if node != "" {
+		cephOK = s.pollCeph(ctx, conn, client, node, &h)
+	}
+	if !cephOK {
+		if err := s.db.DeleteProxmoxCephObjects(ctx, conn.ID); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: clear ceph objects: %v", conn.Name, err))
+		}
+	}
+
+	entries, err := client.GetHAStatus()
+	if err != nil {
+		h.HAError = err.Error()
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox poller [%s]: get HA status: %v", conn.Name, err))
+	} else {
+		ha, rows := summarizeHAStatus(entries)
+		h.HAEnabled, h.HAQuorate, h.HAMasterNode = ha.HAEnabled, ha.HAQuorate, ha.HAMasterNode
+		for _, e := range rows {
+			if err := s.db.UpsertProxmoxHAEntry(ctx, conn.ID, e); err != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: upsert HA entry %s: %v", conn.Name, e.EntryID, err))
+			}
+		}
+	}
+
+	if err := s.db.UpsertProxmoxClusterHealth(ctx, h); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: upsert cluster health: %v", conn.Name, err))
+	}
+	_ = s.db.DeleteStaleProxmoxClusterObjects(ctx, conn.ID, cutoff)
+}
+
+// pollCeph fills the Ceph part of h and upserts OSDs and pools. It returns
+// false when the cluster does not run Ceph or the token may not read it
+// (Sys.Audit); any other failure means Ceph is there but not answering, which
+// is reported as HEALTH_UNKNOWN rather than hidden.
+func (s *Poller) pollCeph(ctx context.Context, conn database.ProxmoxConnectionFull, client *proxmoxclient.Client,
+	node string, h *models.ProxmoxClusterHealth) bool {
+	st, err := client.GetCephStatus()
+	if err != nil {
+		if proxmoxclient.CephNotConfigured(err) {
+			return false
+		}
+		h.CephError = err.Error()
+		if strings.Contains(err.Error(), "HTTP 403") {
+			slog.WarnContext(ctx, fmt.Sprintf("proxmox poller [%s]: get ceph status FAILED (check Sys.Audit privilege on API token): %v", conn.Name, err))
+			return false
+		}
+		slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: get ceph status: %v", conn.Name, err))
+		h.CephAvailable = true
+		h.CephHealth = "HEALTH_UNKNOWN"
+		return true
+	}
+
+	mons, err := client.GetCephMons(node)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: get ceph mons: %v", conn.Name, node, err))
+	}
+	applyCephStatus(h, st, mons)
+
+	if osds, err := client.GetCephOSDs(node); err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: get ceph osds: %v", conn.Name, node, err))
+	} else {
+		for _, o := range osds {
+			if err := s.db.UpsertProxmoxCephOSD(ctx, conn.ID, models.ProxmoxCephOSD{
+				OSDID: o.ID, Name: o.Name, Host: o.Host, Status: o.Status, In: o.In,
+				DeviceClass: o.DeviceClass, PercentUsed: o.PercentUsed,
+			}); err != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: upsert ceph %s: %v", conn.Name, o.Name, err))
+			}
+		}
+	}
+
+	if pools, err := client.GetCephPools(node); err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: get ceph pools: %v", conn.Name, node, err))
+	} else {
+		for _, p := range pools {
+			if err := s.db.UpsertProxmoxCephPool(ctx, conn.ID, models.ProxmoxCephPool{
+				Name: p.Name, Size: p.Size, MinSize: p.MinSize, PGNum: p.PGNum,
+				BytesUsed: p.BytesUsed, PercentUsed: p.PercentUsed * 100,
+			}); err != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: upsert ceph pool %s: %v", conn.Name, p.Name, err))
+			}
+		}
+	}
+	return true
+}
+
+// applyCorosyncStatus reads quorum and membership from GET /cluster/status.
+// A standalone node has no "cluster" entry and is always quorate.
+func applyCorosyncStatus(h *models.ProxmoxClusterHealth, statuses []proxmoxclient.PVEClusterStatus) {
+	h.Quorate = true
+	for _, st := range statuses {
+		switch st.Type {
+		case "cluster":
+			h.Quorate = st.Quorate != 0
+			h.ClusterNodes = st.Nodes
+		case "node":
+			if st.Online != 0 {
+				h.ClusterNodesOnline++
+			}
+		}
+	}
+	if h.ClusterNodes == 0 {
+		h.ClusterNodesOnline = 0
+	}
+}
+
+// applyCephStatus copies the Ceph status into h. mons (GET …/ceph/mon) give
+// each monitor's host; without them, the monitor name stands for its host
+// (pveceph names monitors after their node).
+func applyCephStatus(h *models.ProxmoxClusterHealth, st *proxmoxclient.CephStatus, mons []proxmoxclient.CephMon) {
+	h.CephAvailable = true
+	h.CephHealth = st.Health.Status
+	if h.CephHealth == "" {
+		h.CephHealth = "HEALTH_UNKNOWN"
+	}
+	h.CephChecks = make([]models.ProxmoxCephCheck, 0, len(st.Health.Checks))
+	for code, c := range st.Health.Checks {
+		h.CephChecks = append(h.CephChecks, models.ProxmoxCephCheck{
+			Code: code, Severity: c.Severity, Message: c.Summary.Message, Muted: c.Muted,
+		})
+	}
+	// HEALTH_ERR sorts before HEALTH_WARN: worst checks first.
+	sort.Slice(h.CephChecks, func(i, j int) bool {
+		if h.CephChecks[i].Severity != h.CephChecks[j].Severity {
+			return h.CephChecks[i].Severity < h.CephChecks[j].Severity
+		}
+		return h.CephChecks[i].Code < h.CephChecks[j].Code
+	})
+
+	h.OSDsTotal, h.OSDsUp, h.OSDsIn = st.OSDMap.NumOSDs, st.OSDMap.NumUpOSDs, st.OSDMap.NumInOSDs
+	h.PGsTotal = st.PGMap.NumPGs
+	h.PGsActiveClean = st.PGMap.ActiveClean()
+	h.PGStates = make([]models.ProxmoxCephPGState, 0, len(st.PGMap.PGsByState))
+	for _, s := range st.PGMap.PGsByState {
+		h.PGStates = append(h.PGStates, models.ProxmoxCephPGState{State: s.StateName, Count: s.Count})
+	}
+	h.CephBytesUsed, h.CephBytesTotal = st.PGMap.BytesUsed, st.PGMap.BytesTotal
+
+	inQuorum := make(map[string]bool, len(st.QuorumNames))
+	for _, name := range st.QuorumNames {
+		inQuorum[name] = true
+	}
+	h.Mons = make([]models.ProxmoxCephMon, 0, len(mons))
+	for _, m := range mons {
+		host := m.Host
+		if host == "" {
+			host = m.Name
+		}
+		h.Mons = append(h.Mons, models.ProxmoxCephMon{Name: m.Name, Host: host, InQuorum: inQuorum[m.Name]})
+	}
+	if len(h.Mons) == 0 {
+		for _, m := range st.MonMap.Mons {
+			h.Mons = append(h.Mons, models.ProxmoxCephMon{Name: m.Name, Host: m.Name, InQuorum: inQuorum[m.Name]})
+		}
+	}
+	h.MonsTotal = st.MonCount()
+	if len(h.Mons) > h.MonsTotal {
+		h.MonsTotal = len(h.Mons)
+	}
+	h.MonsInQuorum = len(st.QuorumNames)
+}
+
+// haUnhealthyServiceStates are HA resource states that need an operator:
+// the CRM gave up (error), is fencing the node, or is recovering the
+// resource elsewhere after a node loss.
+var haUnhealthyServiceStates = map[string]bool{"error": true, "fence": true, "recovery": true}
+
+// summarizeHAStatus turns GET /cluster/ha/status/current into the HA part of
+// the summary and one row per master / LRM / resource. The quorum entry only
+// feeds HAQuorate.
+func summarizeHAStatus(entries []proxmoxclient.HAStatusEntry) (models.ProxmoxClusterHealth, []models.ProxmoxHAEntry) {
+	h := models.ProxmoxClusterHealth{HAQuorate: true}
+	rows := make([]models.ProxmoxHAEntry, 0, len(entries))
+	for _, e := range entries {
+		row := models.ProxmoxHAEntry{
+			EntryID: e.ID, EntryType: e.Type, NodeName: e.Node, SID: e.SID,
+			State: e.State, RequestState: e.RequestState, Status: e.Status, Healthy: true,
+		}
+		switch e.Type {
+		case "quorum":
+			h.HAQuorate = e.Quorate != 0
+			continue
+		case "master":
+			h.HAMasterNode = e.Node
+			row.Healthy = haManagerHealthy(e.Status)
+		case "lrm":
+			row.Healthy = haManagerHealthy(e.Status) && e.State != "lost_agent_lock"
+		case "service":
+			h.HAEnabled = true
+			row.Healthy = !haUnhealthyServiceStates[e.State]
+		default:
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return h, rows
+}
+
+// haManagerHealthy parses the status line of a CRM/LRM entry: PVE writes
+// "old timestamp - dead?" once the daemon stopped updating the manager
+// status.
+func haManagerHealthy(status string) bool {
+	s := strings.ToLower(status)
+	return !strings.Contains(s, "old timestamp") && !strings.Contains(s, "dead")
+}
+
+// ─── Read side ───────────────────────────────────────────────────────────────
+
+// ListClusterHealth returns the Ceph/HA/quorum summary of every connection.
+func (s *Service) ListClusterHealth(ctx context.Context) ([]models.ProxmoxClusterHealth, error) {
+	out, err := s.repo.ListProxmoxClusterHealth(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return out, nil
+}
+
+// GetClusterHealth returns one connection's health with its OSDs, pools and
+// HA entries.
+func (s *Service) GetClusterHealth(ctx context.Context, connectionID string) (*models.ProxmoxClusterHealth, error) {
+	h, err := s.repo.GetProxmoxClusterHealth(ctx, connectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("santé du cluster non encore collectée")
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return h, nil
+}
@@ -0,0 +1,85 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+)
+
+func TestApplyCorosyncStatus(t *testing.T) {
+	var h models.ProxmoxClusterHealth
+	applyCorosyncStatus(&h, []proxmoxclient.PVEClusterStatus{
+		{Type: "cluster", Name: "lab", Quorate: 0, Nodes: 3},
+		{Type: "node", Name: "pve1", Online: 1},
+		{Type: "node", Name: "pve2", Online: 0},
+		{Type: "node", Name: "pve3", Online: 0},
+	})
+	if h.Quorate || h.ClusterNodes != 3 || h.ClusterNodesOnline != 1 {
+		t.Errorf("got quorate=%v nodes=%d online=%d", h.Quorate, h.ClusterNodes, h.ClusterNodesOnline)
+	}
+
+	var standalone models.ProxmoxClusterHealth
+	applyCorosyncStatus(&standalone, nil)
+	if !standalone.Quorate || standalone.ClusterNodes != 0 {
+		t.Errorf("standalone node must be quorate, got %+v", standalone)
+	}
+}
+
+func TestApplyCephStatus(t *testing.T) {
+	var st proxmoxclient.CephStatus
+	raw := `{
+		"health":{"status":"HEALTH_WARN","checks":{
+			"PG_DEGRADED":{"severity":"HEALTH_WARN","summary":{"message":"Degraded data redundancy"}},
+			"MON_DOWN":{"severity":"HEALTH_WARN","summary":{"message":"1/3 mons down"}}}},
+		"quorum_names":["pve1","pve2"],
+		"monmap":{"num_mons":3},
+		"osdmap":{"num_osds":6,"num_up_osds":4,"num_in_osds":5},
+		"pgmap":{"num_pgs":129,"pgs_by_state":[{"state_name":"active+clean","count":100},{"state_name":"active+undersized+degraded","count":29}]}
+	}`
+	if err := json.Unmarshal([]byte(raw), &st); err != nil {
+		t.Fatal(err)
+	}
+	var h models.ProxmoxClusterHealth
+	applyCephStatus(&h, &st, []proxmoxclient.CephMon{{Name: "pve1"}, {Name: "pve2"}, {Name: "pve3", Host: "pve3"}})
+
+	if !h.CephAvailable || h.CephHealth != "HEALTH_WARN" {
+		t.Errorf("health = %v %q", h.CephAvailable, h.CephHealth)
+	}
+	if len(h.CephChecks) != 2 || h.CephChecks[0].Code != "MON_DOWN" {
+		t.Errorf("checks = %+v", h.CephChecks)
+	}
+	if h.OSDsTotal != 6 || h.OSDsUp != 4 || h.OSDsIn != 5 || h.PGsTotal != 129 || h.PGsActiveClean != 100 {
+		t.Errorf("counters = %+v", h)
+	}
+	if h.MonsTotal != 3 || h.MonsInQuorum != 2 || h.Mons[2].InQuorum || !h.Mons[0].InQuorum || h.Mons[0].Host != "pve1" {
+		t.Errorf("mons = %d/%d %+v", h.MonsInQuorum, h.MonsTotal, h.Mons)
+	}
+}
+
+func TestSummarizeHAStatus(t *testing.T) {
+	h, rows := summarizeHAStatus([]proxmoxclient.HAStatusEntry{
+		{ID: "quorum", Type: "quorum", Quorate: 1, Status: "OK"},
+		{ID: "master", Type: "master", Node: "pve1", Status: "pve1 (active, Tue Oct 14 10:00:00 2026)"},
+		{ID: "lrm:pve1", Type: "lrm", Node: "pve1", State: "active", Status: "pve1 (active, Tue Oct 14 10:00:00 2026)"},
+		{ID: "lrm:pve2", Type: "lrm", Node: "pve2", Status: "pve2 (old timestamp - dead?, Tue Oct 14 09:00:00 2026)"},
+		{ID: "service:vm:100", Type: "service", SID: "vm:100", Node: "pve1", State: "started"},
+		{ID: "service:vm:101", Type: "service", SID: "vm:101", Node: "pve2", State: "fence"},
+	})
+	if !h.HAEnabled || !h.HAQuorate || h.HAMasterNode != "pve1" {
+		t.Errorf("summary = %+v", h)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5 (quorum entry is not a row)", len(rows))
+	}
+	unhealthy := map[string]bool{}
+	for _, r := range rows {
+		if !r.Healthy {
+			unhealthy[r.EntryID] = true
+		}
+	}
+	if len(unhealthy) != 2 || !unhealthy["lrm:pve2"] || !unhealthy["service:vm:101"] {
+		t.Errorf("unhealthy = %v, want lrm:pve2 and service:vm:101", unhealthy)
+	}
+}
@@ -0,0 +1,184 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+)
+
+// consoleSessionTTL bounds the gap between opening a console and attaching
+// its websocket. PVE's vncproxy/termproxy only wait about ten seconds for the
+// connection, so a longer TTL would only hand out dead sessions.
+const consoleSessionTTL = 15 * time.Second
+
+// consoleSession is a claimed console session, ready to be dialed.
+type consoleSession struct {
+	kind     string
+	clientIP string
+	guest    models.ProxmoxGuest
+	hostID   string // confirmed-linked host, "" when none
+	client   *proxmoxclient.Client
+	ticket   *proxmoxclient.ConsoleTicket
+}
+
+// consoleAllowed applies the console access rule: admins reach every guest;
+// operators only guests confirmed-linked to a host they operate (or any
+// linked guest when they have no per-host restriction). The linked host ID
+// is returned for the audit log.
+func (s *Service) consoleAllowed(ctx context.Context, guestID, username, role string) (string, error) {
+	link, err := s.repo.GetProxmoxGuestLinkByGuest(ctx, guestID)
+	hostID := ""
+	if err == nil && link != nil && link.Status == "confirmed" {
+		hostID = link.HostID
+	}
+	switch role {
+	case models.RoleAdmin:
+		return hostID, nil
+	case models.RoleOperator:
+	default:
+		return "", apperr.Forbidden("accès console réservé aux opérateurs")
+	}
+	if hostID == "" {
+		return "", apperr.Forbidden("ce guest n'est lié à aucun hôte : console réservée aux administrateurs")
+	}
+	restricted, level, err := s.repo.GetHostAccess(ctx, username, hostID)
+	if err != nil {
+		return "", apperr.Internal(err)
+	}
+	if restricted && level != "operator" {
+		return "", apperr.Forbidden("permission opérateur requise sur l'hôte lié")
+	}
+	return hostID, nil
+}
+
+// OpenConsole asks PVE for a console ticket on a guest and parks it until
+// AttachConsole. The opening is audit-logged, refused or not.
+func (s *Service) OpenConsole(ctx context.Context, guestID, kind, username, role, clientIP string) (*models.ProxmoxConsoleSession, error) {
+	if kind != "vnc" && kind != "term" {
+		return nil, apperr.Validation("type de console invalide (vnc ou term)")
+	}
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+	label := fmt.Sprintf("%s console on %s %d (%s) @ %s", kind, guest.GuestType, guest.VMID, guest.Name, guest.NodeName)
+	hostID, err := s.consoleAllowed(ctx, guestID, username, role)
+	if err != nil {
+		s.auditConsole(ctx, username, "proxmox_console_open", hostID, clientIP, label+": "+err.Error(), "denied")
+		return nil, err
+	}
+	if kind == "vnc" && guest.GuestType == "lxc" {
+		return nil, apperr.Validation("la console VNC n'est disponible que pour les VM, utilisez le terminal pour un LXC")
+	}
+
+	var ticket *proxmoxclient.ConsoleTicket
+	if kind == "vnc" {
+		ticket, err = client.OpenVNCProxy(guest.NodeName, guest.VMID, guest.GuestType)
+	} else {
+		ticket, err = client.OpenTermProxy(guest.NodeName, guest.VMID, guest.GuestType)
+	}
+	if err != nil {
+		s.auditConsole(ctx, username, "proxmox_console_open", hostID, clientIP, label+": "+err.Error(), "failed")
+		return nil, apperr.BadGateway(err.Error())
+	}
+
+	// The websocket may reach another replica than this request (HA mode):
+	// the session is parked in the database, not in this process.
+	rawTicket, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	pending := &models.ProxmoxPendingConsole{
+		ID: newConsoleSessionID(), Username: username, ClientIP: clientIP, Kind: kind,
+		GuestID: guest.ID, HostID: hostID, Ticket: string(rawTicket),
+		ExpiresAt: time.Now().Add(consoleSessionTTL),
+	}
+	if err := s.repo.CreateProxmoxConsoleSession(ctx, pending); err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	s.auditConsole(ctx, username, "proxmox_console_open", hostID, clientIP, label, "success")
+	out := &models.ProxmoxConsoleSession{SessionID: pending.ID, Kind: kind, GuestName: guest.Name, ExpiresAt: pending.ExpiresAt}
+	if kind == "vnc" {
+		out.Password = ticket.Ticket
+	}
+	return out, nil
+}
+
+// claimConsole removes and returns a session: each one is attached at most
+// once, and only by the user who opened it. The PVE client is rebuilt from
+// the guest's connection, as the session may have been opened by another
+// replica.
+func (s *Service) claimConsole(ctx context.Context, sessionID, username string) (*consoleSession, error) {
+	pending, err := s.repo.ClaimProxmoxConsoleSession(ctx, sessionID, username)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if pending == nil {
+		return nil, apperr.NotFound("session console introuvable")
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		return nil, apperr.NotFound("session console expirée")
+	}
+	var ticket proxmoxclient.ConsoleTicket
+	if err := json.Unmarshal([]byte(pending.Ticket), &ticket); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	guest, client, err := s.guestClient(ctx, pending.GuestID)
+	if err != nil {
+		return nil, err
+	}
+	return &consoleSession{
+		kind: pending.Kind, clientIP: pending.ClientIP, guest: *guest, hostID: pending.HostID,
+		client: client, ticket: &ticket,
+	}, nil
+}
+
+// AttachConsole dials the PVE websocket of a session opened by OpenConsole
+// (and logs in for a terminal). The caller relays frames both ways, then
+// calls done with the byte counts so the session close is audit-logged.
+func (s *Service) AttachConsole(ctx context.Context, sessionID, username string) (*websocket.Conn, func(sent, received int64), error) {
+	sess, err := s.claimConsole(ctx, sessionID, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	g := sess.guest
+	conn, err := sess.client.DialConsole(g.NodeName, g.VMID, g.GuestType, sess.ticket)
+	if err != nil {
+		return nil, nil, apperr.BadGateway(err.Error())
+	}
+	if sess.kind == "term" {
+		if err := conn.WriteMessage(websocket.BinaryMessage, proxmoxclient.TermProxyLogin(sess.ticket)); err != nil {
+			_ = conn.Close()
+			return nil, nil, apperr.BadGateway(err.Error())
+		}
+	}
+	started := time.Now()
+	done := func(sent, received int64) {
+		details := fmt.Sprintf("%s console on %s %d (%s) closed after %s (%d bytes sent, %d received)",
+			sess.kind, g.GuestType, g.VMID, g.Name, time.Since(started).Round(time.Second), sent, received)
+		s.auditConsole(context.WithoutCancel(ctx), username, "proxmox_console_close", sess.hostID, sess.clientIP, details, "success")
+	}
+	return conn, done, nil
+}
+
+func (s *Service) auditConsole(ctx context.Context, username, action, hostID, clientIP, details, status string) {
+	if _, err := s.repo.CreateAuditLog(ctx, username, action, hostID, clientIP, details, status); err != nil {
+		slog.WarnContext(ctx, "proxmox console: audit log failed", slog.Any("err", err))
+	}
+}
+
+func newConsoleSessionID() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
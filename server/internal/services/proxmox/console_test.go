@@ -0,0 +1,84 @@
+package proxmox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+func TestConsoleAllowed(t *testing.T) {
+	confirmed := &models.ProxmoxGuestLink{HostID: "host-1", Status: "confirmed"}
+	suggested := &models.ProxmoxGuestLink{HostID: "host-1", Status: "suggested"}
+	for _, tc := range []struct {
+		name       string
+		repo       *fakeRepo
+		role       string
+		wantStatus int
+		wantHost   string
+	}{
+		{"admin without link", &fakeRepo{}, models.RoleAdmin, 0, ""},
+		{"admin with link", &fakeRepo{guestLink: confirmed}, models.RoleAdmin, 0, "host-1"},
+		{"viewer", &fakeRepo{guestLink: confirmed}, models.RoleViewer, 403, ""},
+		{"operator without link", &fakeRepo{}, models.RoleOperator, 403, ""},
+		{"operator with unconfirmed link", &fakeRepo{guestLink: suggested}, models.RoleOperator, 403, ""},
+		{"unrestricted operator", &fakeRepo{guestLink: confirmed}, models.RoleOperator, 0, "host-1"},
+		{"host operator", &fakeRepo{guestLink: confirmed, restricted: true, accessLevel: "operator"}, models.RoleOperator, 0, "host-1"},
+		{"host viewer", &fakeRepo{guestLink: confirmed, restricted: true, accessLevel: "viewer"}, models.RoleOperator, 403, ""},
+		{"no access to host", &fakeRepo{guestLink: confirmed, restricted: true}, models.RoleOperator, 403, ""},
+	} {
+		hostID, err := newSvc(tc.repo).consoleAllowed(context.Background(), "guest-1", "alice", tc.role)
+		if status(err) != tc.wantStatus || hostID != tc.wantHost {
+			t.Errorf("%s: got (%q, %v), want host %q status %d", tc.name, hostID, err, tc.wantHost, tc.wantStatus)
+		}
+	}
+}
+
+func TestOpenConsole_InvalidKind(t *testing.T) {
+	_, err := newSvc(&fakeRepo{}).OpenConsole(context.Background(), "guest-1", "spice", "alice", models.RoleAdmin, "10.0.0.1")
+	if status(err) != 400 {
+		t.Fatalf("invalid kind should be 400, got %v", err)
+	}
+}
+
+// TestClaimConsole_SingleUseAndOwner: the session is read back from the
+// database (another replica may have opened it) and attached at most once,
+// by its owner, before it expires.
+func TestClaimConsole_SingleUseAndOwner(t *testing.T) {
+	repo := &fakeRepo{
+		guest: &models.ProxmoxGuest{ID: "guest-1", ConnectionID: "conn-1", NodeName: "pve1", VMID: 100, GuestType: "vm", Name: "web"},
+		enabledConns: []database.ProxmoxConnectionFull{
+			{ProxmoxConnection: models.ProxmoxConnection{ID: "conn-1"}, TokenSecret: "secret"},
+		},
+		connByID: map[string]*models.ProxmoxConnection{"conn-1": {ID: "conn-1", APIURL: "https://pve.local:8006", TokenID: "root@pam!ss"}},
+		consoles: map[string]models.ProxmoxPendingConsole{
+			"s1": {ID: "s1", Username: "alice", Kind: "vnc", GuestID: "guest-1", HostID: "host-1",
+				Ticket: `{"port":"5900","ticket":"PVEVNC:abc","user":"root@pam"}`, ExpiresAt: time.Now().Add(time.Minute)},
+			"old": {ID: "old", Username: "alice", Kind: "vnc", GuestID: "guest-1", Ticket: `{}`, ExpiresAt: time.Now().Add(-time.Second)},
+		},
+	}
+	svc := newSvc(repo)
+	ctx := context.Background()
+
+	if _, err := svc.claimConsole(ctx, "s1", "bob"); status(err) != 404 {
+		t.Errorf("another user must not claim the session, got %v", err)
+	}
+	sess, err := svc.claimConsole(ctx, "s1", "alice")
+	if err != nil {
+		t.Fatalf("owner claim: %v", err)
+	}
+	if sess.ticket.Ticket != "PVEVNC:abc" || sess.ticket.Port != 5900 || sess.guest.Name != "web" || sess.hostID != "host-1" || sess.client == nil {
+		t.Errorf("claimed session = %+v", sess)
+	}
+	if _, err := svc.claimConsole(ctx, "s1", "alice"); status(err) != 404 {
+		t.Errorf("a session is single-use, got %v", err)
+	}
+	if _, err := svc.claimConsole(ctx, "old", "alice"); status(err) != 404 {
+		t.Errorf("expired session should be refused, got %v", err)
+	}
+	if len(repo.consoles) != 0 {
+		t.Errorf("claimed/expired sessions should be dropped, %d left", len(repo.consoles))
+	}
+}
@@ -0,0 +1,121 @@
+package proxmox
+
+import (
+	"context"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/pbsclient"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// Proxmox Backup Server supervision: connection CRUD and the stored read
+// models (datastores, backup groups, jobs). Collection lives in pbs_poller.go.
+
+// PollAllPBS refreshes every enabled PBS connection.
+func (s *Service) PollAllPBS(ctx context.Context) {
+	s.poller.PollAllPBS(ctx)
+}
+
+// TriggerPBSPollByID launches an immediate poll of one enabled PBS connection
+// on the supplied (long-lived) ctx, ignoring its poll interval.
+func (s *Service) TriggerPBSPollByID(reqCtx, pollCtx context.Context, id string) error {
+	conns, err := s.repo.GetEnabledPBSConnections(reqCtx)
+	if err != nil {
+		return err
+	}
+	for _, conn := range conns {
+		if conn.ID == id {
+			go func() {
+				defer safego.Recover(pollCtx, "proxmox.TriggerPBSPollByID")
+				s.poller.PollOnePBS(pollCtx, conn, true)
+			}()
+			return nil
+		}
+	}
+	return apperr.NotFound("enabled connection not found")
+}
+
+// ===== connections CRUD =====
+
+func (s *Service) ListPBSConnections(ctx context.Context) ([]models.PBSConnection, error) {
+	return s.repo.ListPBSConnections(ctx)
+}
+
+func (s *Service) CreatePBSConnection(ctx context.Context, req models.PBSConnectionRequest) (*models.PBSConnection, error) {
+	if req.TokenSecret == "" {
+		return nil, apperr.Validation("token_secret is required when creating a connection")
+	}
+	id, err := s.repo.CreatePBSConnection(ctx, req.Name, req.APIURL, req.TokenID, req.TokenSecret, req.InsecureSkipVerify, req.Enabled, req.PollIntervalSec)
+	if err != nil {
+		return nil, err
+	}
+	conn, _ := s.repo.GetPBSConnectionByID(ctx, id)
+	return conn, nil
+}
+
+func (s *Service) GetPBSConnection(ctx context.Context, id string) (*models.PBSConnection, error) {
+	conn, err := s.repo.GetPBSConnectionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, apperr.NotFound("connection not found")
+	}
+	return conn, nil
+}
+
+func (s *Service) UpdatePBSConnection(ctx context.Context, id string, req models.PBSConnectionRequest) (*models.PBSConnection, error) {
+	if _, err := s.GetPBSConnection(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePBSConnection(ctx, id, req.Name, req.APIURL, req.TokenID, req.TokenSecret, req.InsecureSkipVerify, req.Enabled, req.PollIntervalSec); err != nil {
+		return nil, err
+	}
+	conn, _ := s.repo.GetPBSConnectionByID(ctx, id)
+	return conn, nil
+}
+
+func (s *Service) DeletePBSConnection(ctx context.Context, id string) error {
+	if _, err := s.GetPBSConnection(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeletePBSConnection(ctx, id)
+}
+
+// TestPBSConnection tests ad-hoc credentials (no persistence), like
+// TestConnection for PVE.
+func (s *Service) TestPBSConnection(apiURL, tokenID, secret string, insecure bool) (bool, string) {
+	if _, err := pbsclient.New(apiURL, tokenID, secret, insecure).GetVersion(); err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
+
+// TestPBSConnectionByID tests a stored PBS connection using its stored secret.
+func (s *Service) TestPBSConnectionByID(ctx context.Context, id string) (bool, string, error) {
+	conn, err := s.repo.GetPBSConnectionByID(ctx, id)
+	if err != nil || conn == nil {
+		return false, "", apperr.NotFound("connection not found")
+	}
+	secret, err := s.repo.GetPBSTokenSecret(ctx, id)
+	if err != nil {
+		return false, "", err
+	}
+	ok, msg := s.TestPBSConnection(conn.APIURL, conn.TokenID, secret, conn.InsecureSkipVerify)
+	return ok, msg, nil
+}
+
+// ===== read models =====
+
+func (s *Service) ListPBSDatastores(ctx context.Context, connectionID string) ([]models.PBSDatastore, error) {
+	return s.repo.ListPBSDatastores(ctx, connectionID)
+}
+
+func (s *Service) ListPBSBackupGroups(ctx context.Context, connectionID, store string) ([]models.PBSBackupGroup, error) {
+	return s.repo.ListPBSBackupGroups(ctx, connectionID, store)
+}
+
+func (s *Service) ListPBSJobs(ctx context.Context, connectionID string) ([]models.PBSJob, error) {
+	return s.repo.ListPBSJobs(ctx, connectionID)
+}
@@ -0,0 +1,199 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/pbsclient"
+)
+
+// pbsJobKinds are the PBS job lists polled each cycle (GET /admin/<kind>).
+var pbsJobKinds = []string{"gc", "prune", "verify", "sync"}
+
+// PollAllPBS iterates all enabled Proxmox Backup Server connections and polls
+// each one.
+func (s *Poller) PollAllPBS(ctx context.Context) {
+	conns, err := s.db.GetEnabledPBSConnections(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("pbs poller: failed to fetch connections: %v", err))
+		return
+	}
+	for _, c := range conns {
+		if ctx.Err() != nil {
+			return
+		}
+		s.PollOnePBS(ctx, c, false)
+	}
+}
+
+// PollOnePBS collects datastore usage, backup groups (with their verify
+// state) and the GC/prune/verify/sync job status of one PBS. force bypasses
+// the per-connection poll interval (poll-now).
+func (s *Poller) PollOnePBS(ctx context.Context, conn database.PBSConnectionFull, force bool) {
+	interval := time.Duration(conn.PollIntervalSec) * time.Second
+	if interval <= 0 {
+		interval = 300 * time.Second
+	}
+	if !force && conn.LastSuccessAt != nil && time.Since(*conn.LastSuccessAt) < interval {
+		return
+	}
+
+	client := pbsclient.New(conn.APIURL, conn.TokenID, conn.TokenSecret, conn.InsecureSkipVerify)
+
+	version, err := client.GetVersion()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: failed to get version: %v", conn.Name, err))
+		_ = s.db.UpdatePBSConnectionError(ctx, conn.ID, err.Error())
+		return
+	}
+	usage, err := client.GetDatastoreUsage()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: failed to get datastore usage: %v", conn.Name, err))
+		_ = s.db.UpdatePBSConnectionError(ctx, conn.ID, err.Error())
+		return
+	}
+
+	cutoff := time.Now().Add(-3 * interval)
+
+	for _, ds := range usage {
+		if ctx.Err() != nil {
+			return
+		}
+		storeErr := ds.Error
+		groups, err := client.GetGroups(ds.Store)
+		if err == nil {
+			var snaps []pbsclient.Snapshot
+			snaps, err = client.GetSnapshots(ds.Store)
+			if err == nil {
+				for _, g := range summarizePBSGroups(conn.ID, ds.Store, groups, snaps) {
+					if err := s.db.UpsertPBSBackupGroup(ctx, g); err != nil {
+						slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: upsert group %s/%s: %v", conn.Name, g.BackupType, g.BackupID, err))
+					}
+				}
+			}
+		}
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("pbs poller [%s]: datastore %s: %v", conn.Name, ds.Store, err))
+			storeErr = err.Error()
+		}
+		if err := s.db.UpsertPBSDatastore(ctx, conn.ID, ds.Store, ds.Total, ds.Used, ds.Avail, storeErr); err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: upsert datastore %s: %v", conn.Name, ds.Store, err))
+		}
+	}
+
+	// Job lists need Sys.Audit / Datastore.Audit; a token without them still
+	// gets datastores and groups, so a failing kind is only logged.
+	for _, kind := range pbsJobKinds {
+		jobs, err := client.GetJobs(kind)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("pbs poller [%s]: failed to get %s jobs: %v", conn.Name, kind, err))
+			continue
+		}
+		for _, j := range jobs {
+			if err := s.db.UpsertPBSJob(ctx, toPBSJob(conn.ID, kind, j)); err != nil {
+				slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: upsert %s job: %v", conn.Name, kind, err))
+			}
+		}
+	}
+
+	if err := s.db.DeleteStalePBSRows(ctx, conn.ID, cutoff); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("pbs poller [%s]: failed to delete stale rows: %v", conn.Name, err))
+	}
+	_ = s.db.UpdatePBSConnectionSuccess(ctx, conn.ID, version.Version)
+}
+
+// summarizePBSGroups builds one row per backup group: the last backup time
+// (from the group, else its newest snapshot), and the state of the newest
+// snapshot that has been verified. A failed verify stays visible until a newer
+// snapshot verifies OK, even if newer unverified snapshots exist.
+func summarizePBSGroups(connectionID, store string, groups []pbsclient.BackupGroup, snaps []pbsclient.Snapshot) []models.PBSBackupGroup {
+	type verify struct {
+		at        int64
+		state     string
+		failCount int
+		newest    int64
+	}
+	byGroup := make(map[string]*verify, len(groups))
+	for _, sn := range snaps {
+		key := sn.BackupType + "/" + sn.BackupID
+		v := byGroup[key]
+		if v == nil {
+			v = &verify{}
+			byGroup[key] = v
+		}
+		if sn.BackupTime > v.newest {
+			v.newest = sn.BackupTime
+		}
+		if sn.Verification == nil {
+			continue
+		}
+		if sn.Verification.State == "failed" {
+			v.failCount++
+		}
+		if sn.BackupTime > v.at {
+			v.at = sn.BackupTime
+			v.state = sn.Verification.State
+		}
+	}
+
+	out := make([]models.PBSBackupGroup, 0, len(groups))
+	for _, g := range groups {
+		row := models.PBSBackupGroup{
+			ConnectionID: connectionID,
+			Store:        store,
+			BackupType:   g.BackupType,
+			BackupID:     g.BackupID,
+			BackupCount:  g.BackupCount,
+			Owner:        g.Owner,
+			Comment:      g.Comment,
+		}
+		last := g.LastBackup
+		if v := byGroup[g.BackupType+"/"+g.BackupID]; v != nil {
+			if v.newest > last {
+				last = v.newest
+			}
+			row.LastVerifyState = v.state
+			row.LastVerifyAt = unixTimePtr(v.at)
+			row.VerifyFailedCount = v.failCount
+		}
+		row.LastBackupAt = unixTimePtr(last)
+		out = append(out, row)
+	}
+	return out
+}
+
+// toPBSJob maps a PBS job status entry. GC entries have no job ID: there is
+// one per datastore, keyed by the store name.
+func toPBSJob(connectionID, kind string, j pbsclient.JobStatus) models.PBSJob {
+	id := j.ID
+	if kind == "gc" || id == "" {
+		id = j.Store
+	}
+	return models.PBSJob{
+		ConnectionID: connectionID,
+		JobType:      kind,
+		JobID:        id,
+		Store:        j.Store,
+		Schedule:     j.Schedule,
+		LastRunState: j.LastRunState,
+		LastRunAt:    unixTimePtr(j.LastRunEndtime),
+		LastRunUPID:  j.LastRunUPID,
+		NextRunAt:    unixTimePtr(j.NextRun),
+		Remote:       j.Remote,
+		RemoteStore:  j.RemoteStore,
+		RemovedBytes: j.RemovedBytes,
+		PendingBytes: j.PendingBytes,
+	}
+}
+
+func unixTimePtr(sec int64) *time.Time {
+	if sec <= 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0).UTC()
+	return &t
+}
@@ -0,0 +1,66 @@
+package proxmox
+
+import (
+	"context"
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/pbsclient"
+)
+
+func TestSummarizePBSGroups_VerifyState(t *testing.T) {
+	groups := []pbsclient.BackupGroup{
+		{BackupType: "vm", BackupID: "100", LastBackup: 3000, BackupCount: 3},
+		{BackupType: "ct", BackupID: "101", BackupCount: 1},
+	}
+	snaps := []pbsclient.Snapshot{
+		{BackupType: "vm", BackupID: "100", BackupTime: 1000, Verification: &pbsclient.Verification{State: "ok"}},
+		{BackupType: "vm", BackupID: "100", BackupTime: 2000, Verification: &pbsclient.Verification{State: "failed"}},
+		{BackupType: "vm", BackupID: "100", BackupTime: 3000}, // not verified yet
+		{BackupType: "ct", BackupID: "101", BackupTime: 500},
+	}
+	rows := summarizePBSGroups("conn", "main", groups, snaps)
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+
+	vm := rows[0]
+	if vm.LastVerifyState != "failed" || vm.VerifyFailedCount != 1 {
+		t.Errorf("vm/100: state=%q failed=%d, want failed/1", vm.LastVerifyState, vm.VerifyFailedCount)
+	}
+	if vm.LastVerifyAt == nil || vm.LastVerifyAt.Unix() != 2000 {
+		t.Errorf("vm/100: last verify at %v, want 2000", vm.LastVerifyAt)
+	}
+	if vm.LastBackupAt == nil || vm.LastBackupAt.Unix() != 3000 {
+		t.Errorf("vm/100: last backup at %v, want 3000", vm.LastBackupAt)
+	}
+
+	// No last-backup on the group: falls back to the newest snapshot.
+	ct := rows[1]
+	if ct.LastBackupAt == nil || ct.LastBackupAt.Unix() != 500 {
+		t.Errorf("ct/101: last backup at %v, want 500", ct.LastBackupAt)
+	}
+	if ct.LastVerifyState != "" || ct.LastVerifyAt != nil {
+		t.Errorf("ct/101 was never verified, got %q", ct.LastVerifyState)
+	}
+}
+
+func TestToPBSJob_GCKeyedByStore(t *testing.T) {
+	j := toPBSJob("conn", "gc", pbsclient.JobStatus{Store: "main", LastRunState: "ok", LastRunEndtime: 1700000000})
+	if j.JobID != "main" || j.LastRunAt == nil || j.NextRunAt != nil {
+		t.Errorf("got %+v", j)
+	}
+	s := toPBSJob("conn", "sync", pbsclient.JobStatus{ID: "s-offsite", Store: "main", Remote: "offsite"})
+	if s.JobID != "s-offsite" || s.Remote != "offsite" {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestCreatePBSConnection_RequiresSecret(t *testing.T) {
+	_, err := newSvc(&fakeRepo{}).CreatePBSConnection(context.Background(), models.PBSConnectionRequest{
+		Name: "pbs", APIURL: "https://pbs:8007/api2/json", TokenID: "monitor@pbs!ss",
+	})
+	if status(err) != 400 {
+		t.Fatalf("missing secret should be 400, got %v", err)
+	}
+}
@@ -119,7 +119,7 @@ func (s *Poller) PollOne(ctx context.Context, conn database.ProxmoxConnectionFul
 				if guestID, err := s.db.GetProxmoxGuestIDByVMID(ctx, conn.ID, n.Node, vm.VMID); err == nil && guestID != "" {
 					_ = s.db.AutoSuggestProxmoxLink(ctx, guestID, vm.Name)
 					if vm.Status == "running" {
-						if err := s.db.InsertProxmoxGuestMetric(ctx, guestID, vm.CPU, vm.MaxMem, vm.Mem); err != nil {
+						if err := s.db.InsertProxmoxGuestMetric(ctx, guestID, vm.CPU, vm.MaxMem, vm.Mem, vm.Balloon, float64(vm.PressureCPUSome)); err != nil {
 							slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: insert vm metric %d: %v", conn.Name, n.Node, vm.VMID, err))
 						}
 					}
@@ -142,7 +142,7 @@ func (s *Poller) PollOne(ctx context.Context, conn database.ProxmoxConnectionFul
 				if guestID, err := s.db.GetProxmoxGuestIDByVMID(ctx, conn.ID, n.Node, lxc.VMID); err == nil && guestID != "" {
 					_ = s.db.AutoSuggestProxmoxLink(ctx, guestID, lxc.Name)
 					if lxc.Status == "running" {
-						if err := s.db.InsertProxmoxGuestMetric(ctx, guestID, lxc.CPU, lxc.MaxMem, lxc.Mem); err != nil {
+						if err := s.db.InsertProxmoxGuestMetric(ctx, guestID, lxc.CPU, lxc.MaxMem, lxc.Mem, 0, float64(lxc.PressureCPUSome)); err != nil {
 							slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: insert lxc metric %d: %v", conn.Name, n.Node, lxc.VMID, err))
 						}
 					}
@@ -158,7 +158,7 @@ func (s *Poller) PollOne(ctx context.Context, conn database.ProxmoxConnectionFul
 				if err := s.db.UpsertProxmoxStorage(ctx,
 					conn.ID, n.Node, st.Storage, st.Type,
 					st.Total, st.Used, st.Avail,
-					st.Enabled != 0, st.Active != 0, st.Shared != 0,
+					st.Enabled != 0, st.Active != 0, st.Shared != 0, st.Content,
 				); err != nil {
 					slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s/%s]: upsert storage %s: %v", conn.Name, n.Node, st.Storage, err))
 				}
@@ -234,6 +234,15 @@ func (s *Poller) PollOne(ctx context.Context, conn database.ProxmoxConnectionFul
 		}
 	}
 
+	healthNode := ""
+	for _, n := range nodes {
+		if n.Status == "online" {
+			healthNode = n.Node
+			break
+		}
+	}
+	s.pollClusterHealth(ctx, conn, client, clusterStatuses, healthNode, cutoff)
+
 	backupJobs, err := client.GetClusterBackup()
 	if err != nil {
 		slog.ErrorContext(ctx, fmt.Sprintf("proxmox poller [%s]: get backup jobs: %v", conn.Name, err))
@@ -0,0 +1,461 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// Provisioning: clone a QEMU template, size it, and hand cloud-init a NoCloud
+// seed served by ServerSupervisor itself (GET /api/provision/<token>/…). PVE
+// cannot upload cloud-init snippets through its API, so the seed URL travels
+// in the VM's SMBIOS serial ("ds=nocloud;s=<url>"); cloud-init still takes the
+// network from the template's cloud-init drive (ipconfig0) and the user-data
+// from the seed. The user-data installs the agent with a host API key minted
+// at fetch time, and the first report of that host links it to the guest.
+
+// HostRegistrar is the host port used to create the host a provision enrolls
+// and to mint its API key. *host.Service satisfies it.
+type HostRegistrar interface {
+	Register(ctx context.Context, req models.HostRegistration) (id, plainKey string, err error)
+	RotateKey(ctx context.Context, id string) (string, error)
+}
+
+// SetHostRegistrar wires the host port after construction. Without it,
+// provisioning is refused.
+func (s *Service) SetHostRegistrar(hosts HostRegistrar) {
+	s.hosts = hosts
+}
+
+// agentInstallScriptURL is the install script the seed's user-data runs (the
+// same one the "add host" screen hands out).
+const agentInstallScriptURL = "https://raw.githubusercontent.com/Rem7474/ServerSupervisor/main/agent/install.sh"
+
+// placeholderHostIP registers a DHCP guest before its address is known; the
+// first seed fetch replaces it with the address the VM called from.
+const placeholderHostIP = "0.0.0.0"
+
+const provisionListLimit = 50
+
+var (
+	provisionNameRe   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	provisionDiskRe   = regexp.MustCompile(`^(scsi|virtio|sata|ide)\d{1,2}$`)
+	provisionBridgeRe = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,32}$`)
+	provisionSSHKeyRe = regexp.MustCompile(`^(ssh-(rsa|ed25519|dss)|ecdsa-sha2-nistp\d+|sk-[a-z0-9@.-]+) [A-Za-z0-9+/=]+( .*)?$`)
+)
+
+// ListProvisionTemplates returns the QEMU templates of a connection, live from
+// PVE.
+func (s *Service) ListProvisionTemplates(ctx context.Context, connectionID string) ([]models.ProxmoxTemplate, error) {
+	secret, conn, err := s.resolveSecret(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+	client := proxmoxclient.New(conn.APIURL, conn.TokenID, secret, conn.InsecureSkipVerify)
+	nodes, err := client.GetNodes()
+	if err != nil {
+		return nil, apperr.BadGateway(err.Error())
+	}
+	out := []models.ProxmoxTemplate{}
+	for _, n := range nodes {
+		if n.Status != "online" {
+			continue
+		}
+		vms, err := client.GetNodeQemu(n.Node)
+		if err != nil {
+			continue
+		}
+		for _, vm := range vms {
+			if vm.Template == 1 {
+				out = append(out, models.ProxmoxTemplate{NodeName: n.Node, VMID: vm.VMID, Name: vm.Name})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *Service) ListProvisions(ctx context.Context) ([]models.ProxmoxProvision, error) {
+	return s.repo.ListProxmoxProvisions(ctx, provisionListLimit)
+}
+
+func (s *Service) GetProvision(ctx context.Context, id string) (*models.ProxmoxProvision, error) {
+	p, err := s.repo.GetProxmoxProvision(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("provision not found")
+	}
+	return p, err
+}
+
+// DeleteProvision forgets a finished (linked or failed) provision. The VM and
+// the host it registered are left untouched.
+func (s *Service) DeleteProvision(ctx context.Context, id string) error {
+	p, err := s.GetProvision(ctx, id)
+	if err != nil {
+		return err
+	}
+	if p.Status != "linked" && p.Status != "failed" {
+		return apperr.Conflict("provisionnement en cours")
+	}
+	return s.repo.DeleteProxmoxProvision(ctx, id)
+}
+
+// validateProvision checks a request and returns the host IP to register.
+func validateProvision(req *models.ProxmoxProvisionRequest) (string, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if !provisionNameRe.MatchString(req.Name) {
+		return "", apperr.Validation("nom invalide : doit être un nom d'hôte (lettres, chiffres, tirets, 63 caractères max)")
+	}
+	if req.TemplateVMID < 100 || req.VMID < 0 || (req.VMID > 0 && req.VMID < 100) {
+		return "", apperr.Validation("VMID invalide (100 minimum)")
+	}
+	if req.Cores < 0 || req.Cores > 512 {
+		return "", apperr.Validation("cores invalide")
+	}
+	if req.MemoryMB != 0 && (req.MemoryMB < 256 || req.MemoryMB > 4*1024*1024) {
+		return "", apperr.Validation("memory_mb invalide (256 minimum)")
+	}
+	if req.Disk == "" {
+		req.Disk = "scsi0"
+	}
+	if !provisionDiskRe.MatchString(req.Disk) {
+		return "", apperr.Validation("disque invalide (ex. scsi0, virtio0)")
+	}
+	if req.DiskSizeGB < 0 || req.DiskSizeGB > 65536 {
+		return "", apperr.Validation("disk_size_gb invalide")
+	}
+	if req.Bridge != "" && !provisionBridgeRe.MatchString(req.Bridge) {
+		return "", apperr.Validation("bridge invalide")
+	}
+	if req.VLANTag < 0 || req.VLANTag > 4094 || (req.VLANTag > 0 && req.Bridge == "") {
+		return "", apperr.Validation("vlan_tag invalide (1-4094, bridge requis)")
+	}
+	for _, ns := range strings.Fields(req.Nameserver) {
+		if net.ParseIP(ns) == nil {
+			return "", apperr.Validation("nameserver invalide : " + ns)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(req.SSHAuthorizedKeys), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !provisionSSHKeyRe.MatchString(line) {
+			return "", apperr.Validation("clé SSH publique invalide")
+		}
+	}
+	return parseIPConfig(req.IPConfig)
+}
+
+// parseIPConfig validates a PVE ipconfig0 value and returns the static
+// address it assigns, or placeholderHostIP for DHCP / an empty value.
+func parseIPConfig(cfg string) (string, error) {
+	cfg = strings.TrimSpace(cfg)
+	if cfg == "" {
+		return placeholderHostIP, nil
+	}
+	hostIP := placeholderHostIP
+	for _, part := range strings.Split(cfg, ",") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", apperr.Validation("ip_config invalide : " + part)
+		}
+		switch key {
+		case "ip":
+			if val == "dhcp" {
+				continue
+			}
+			ip, _, err := net.ParseCIDR(val)
+			if err != nil || ip.To4() == nil {
+				return "", apperr.Validation("ip_config : ip doit être dhcp ou une adresse IPv4/CIDR")
+			}
+			hostIP = ip.String()
+		case "gw":
+			if ip := net.ParseIP(val); ip == nil || ip.To4() == nil {
+				return "", apperr.Validation("ip_config : passerelle invalide")
+			}
+		case "ip6", "gw6":
+			// Passed through to PVE as is.
+		default:
+			return "", apperr.Validation("ip_config : clé inconnue " + key)
+		}
+	}
+	return hostIP, nil
+}
+
+// CreateProvision registers the host, records the provision and runs the
+// clone/configure/start sequence in the background on pollCtx.
+func (s *Service) CreateProvision(reqCtx, pollCtx context.Context, req models.ProxmoxProvisionRequest, username string) (*models.ProxmoxProvision, error) {
+	if s.hosts == nil {
+		return nil, apperr.Internal(errors.New("provisioning is not wired to the host service"))
+	}
+	hostIP, err := validateProvision(&req)
+	if err != nil {
+		return nil, err
+	}
+	seedBase, err := s.seedBaseURL()
+	if err != nil {
+		return nil, err
+	}
+	secret, conn, err := s.resolveSecret(reqCtx, req.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+	client := proxmoxclient.New(conn.APIURL, conn.TokenID, secret, conn.InsecureSkipVerify)
+
+	hostID, _, err := s.hosts.Register(reqCtx, models.HostRegistration{Name: req.Name, IPAddress: hostIP, Tags: req.Tags})
+	if err != nil {
+		return nil, err
+	}
+	token, tokenHash, err := newSeedToken()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	id, err := s.repo.CreateProxmoxProvision(reqCtx, models.ProxmoxProvision{
+		ConnectionID:      req.ConnectionID,
+		NodeName:          req.NodeName,
+		TemplateVMID:      req.TemplateVMID,
+		VMID:              req.VMID,
+		Name:              req.Name,
+		Cores:             req.Cores,
+		MemoryMB:          req.MemoryMB,
+		Disk:              req.Disk,
+		DiskSizeGB:        req.DiskSizeGB,
+		Bridge:            req.Bridge,
+		VLANTag:           req.VLANTag,
+		IPConfig:          strings.TrimSpace(req.IPConfig),
+		SSHAuthorizedKeys: strings.TrimSpace(req.SSHAuthorizedKeys),
+		HostID:            &hostID,
+		CreatedBy:         username,
+	}, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		defer safego.Recover(pollCtx, "proxmox.runProvision")
+		s.runProvision(pollCtx, id, client, req, seedBase+token+"/")
+	}()
+	return s.GetProvision(reqCtx, id)
+}
+
+// runProvision drives one provision up to 'waiting_agent' (or 'failed').
+func (s *Service) runProvision(ctx context.Context, id string, client *proxmoxclient.Client, req models.ProxmoxProvisionRequest, seedURL string) {
+	fail := func(step string, err error) {
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox provision %s (%s): %s: %v", id, req.Name, step, err))
+		_ = s.repo.UpdateProxmoxProvisionStatus(ctx, id, "failed", fmt.Sprintf("%s : %v", step, err))
+	}
+	node := req.NodeName
+
+	vmid := req.VMID
+	if vmid == 0 {
+		next, err := client.NextVMID()
+		if err != nil {
+			fail("allocation du VMID", err)
+			return
+		}
+		vmid = next
+	}
+	_ = s.repo.SetProxmoxProvisionVMID(ctx, id, vmid)
+
+	_ = s.repo.UpdateProxmoxProvisionStatus(ctx, id, "cloning", "")
+	upid, err := client.CloneVM(node, req.TemplateVMID, proxmoxclient.CloneVMOptions{
+		NewID: vmid, Name: req.Name, Full: req.FullClone, Storage: req.Storage,
+	})
+	if err == nil {
+		err = waitProxmoxTask(ctx, client, node, upid)
+	}
+	if err != nil {
+		fail("clonage", err)
+		return
+	}
+
+	_ = s.repo.UpdateProxmoxProvisionStatus(ctx, id, "configuring", "")
+	current, err := client.GetVMConfig(node, vmid)
+	if err != nil {
+		fail("lecture de la configuration", err)
+		return
+	}
+	if err := s.applyProvisionConfig(ctx, client, node, vmid, req, proxmoxclient.VMConfigString(current, "smbios1"), seedURL); err != nil {
+		fail("configuration", err)
+		return
+	}
+
+	_ = s.repo.UpdateProxmoxProvisionStatus(ctx, id, "starting", "")
+	upid, err = client.GuestAction(node, vmid, "vm", "start")
+	if err == nil {
+		err = waitProxmoxTask(ctx, client, node, upid)
+	}
+	if err != nil {
+		fail("démarrage", err)
+		return
+	}
+	_ = s.repo.UpdateProxmoxProvisionStatus(ctx, id, "waiting_agent", "")
+}
+
+// applyProvisionConfig sets sizing, network and the cloud-init seed, then
+// grows the disk when asked.
+func (s *Service) applyProvisionConfig(ctx context.Context, client *proxmoxclient.Client, node string, vmid int, req models.ProxmoxProvisionRequest, smbios, seedURL string) error {
+	params := provisionConfigParams(req, smbios, seedURL)
+	upid, err := client.UpdateVMConfig(node, vmid, params)
+	if err == nil && upid != "" {
+		err = waitProxmoxTask(ctx, client, node, upid)
+	}
+	if err != nil {
+		return err
+	}
+	if req.DiskSizeGB > 0 {
+		upid, err := client.ResizeVMDisk(node, vmid, req.Disk, strconv.Itoa(req.DiskSizeGB)+"G")
+		if err == nil && upid != "" {
+			err = waitProxmoxTask(ctx, client, node, upid)
+		}
+		if err != nil {
+			return fmt.Errorf("redimensionnement de %s : %w", req.Disk, err)
+		}
+	}
+	return nil
+}
+
+// provisionConfigParams builds the POST /config body of a fresh clone.
+func provisionConfigParams(req models.ProxmoxProvisionRequest, smbios, seedURL string) map[string]string {
+	params := map[string]string{
+		"smbios1":   proxmoxclient.SMBIOSWithSerial(smbios, "ds=nocloud;s="+seedURL),
+		"ipconfig0": "ip=dhcp",
+	}
+	if cfg := strings.TrimSpace(req.IPConfig); cfg != "" {
+		params["ipconfig0"] = cfg
+	}
+	if req.Cores > 0 {
+		params["cores"] = strconv.Itoa(req.Cores)
+	}
+	if req.MemoryMB > 0 {
+		params["memory"] = strconv.Itoa(req.MemoryMB)
+	}
+	if req.Bridge != "" {
+		net0 := "virtio,bridge=" + req.Bridge
+		if req.VLANTag > 0 {
+			net0 += ",tag=" + strconv.Itoa(req.VLANTag)
+		}
+		params["net0"] = net0
+	}
+	if ns := strings.Join(strings.Fields(req.Nameserver), " "); ns != "" {
+		params["nameserver"] = ns
+	}
+	return params
+}
+
+// seedBaseURL is the public prefix of the seed endpoint, from BASE_URL.
+func (s *Service) seedBaseURL() (string, error) {
+	if s.cfg == nil || s.cfg.BaseURL == "" {
+		return "", apperr.Validation("BASE_URL doit être configuré : la VM télécharge sa configuration cloud-init depuis ServerSupervisor")
+	}
+	return strings.TrimRight(s.cfg.BaseURL, "/") + "/api/provision/", nil
+}
+
+func newSeedToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	return token, hashSeedToken(token), nil
+}
+
+func hashSeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// seedProvision resolves a seed token to its provision. Only provisions still
+// on their way to the agent serve a seed; once linked or failed, the token is
+// dead.
+func (s *Service) seedProvision(ctx context.Context, token string) (*models.ProxmoxProvision, error) {
+	if len(token) != 64 {
+		return nil, apperr.NotFound("seed not found")
+	}
+	p, err := s.repo.GetProxmoxProvisionBySeedToken(ctx, hashSeedToken(token))
+	if err != nil || p.HostID == nil {
+		return nil, apperr.NotFound("seed not found")
+	}
+	switch p.Status {
+	case "configuring", "starting", "waiting_agent":
+		return p, nil
+	}
+	return nil, apperr.NotFound("seed not found")
+}
+
+// SeedMetaData serves the NoCloud meta-data of a provision.
+func (s *Service) SeedMetaData(ctx context.Context, token string) (string, error) {
+	p, err := s.seedProvision(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("instance-id: serversupervisor-%s\nlocal-hostname: %s\n", p.ID, p.Name), nil
+}
+
+// SeedUserData serves the NoCloud user-data of a provision. Each fetch mints
+// a new API key for the provision's host (the previous one is revoked), so
+// the key is never stored in clear. clientIP replaces the placeholder address
+// of a DHCP guest.
+func (s *Service) SeedUserData(ctx context.Context, token, clientIP string) (string, error) {
+	p, err := s.seedProvision(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if s.hosts == nil {
+		return "", apperr.Internal(errors.New("provisioning is not wired to the host service"))
+	}
+	apiKey, err := s.hosts.RotateKey(ctx, *p.HostID)
+	if err != nil {
+		return "", err
+	}
+	_ = s.repo.MarkProxmoxProvisionSeedFetched(ctx, p.ID)
+	if ip := net.ParseIP(clientIP); ip != nil {
+		if host, err := s.repo.GetHost(ctx, *p.HostID); err == nil && host != nil && host.IPAddress == placeholderHostIP {
+			addr := ip.String()
+			_ = s.repo.UpdateHost(ctx, *p.HostID, &models.HostUpdate{IPAddress: &addr})
+		}
+	}
+	return renderProvisionUserData(p, strings.TrimRight(s.cfg.BaseURL, "/"), apiKey), nil
+}
+
+// renderProvisionUserData returns the #cloud-config installing the agent.
+func renderProvisionUserData(p *models.ProxmoxProvision, serverURL, apiKey string) string {
+	var b strings.Builder
+	b.WriteString("#cloud-config\n")
+	fmt.Fprintf(&b, "hostname: %s\n", p.Name)
+	b.WriteString("manage_etc_hosts: true\n")
+	if keys := strings.TrimSpace(p.SSHAuthorizedKeys); keys != "" {
+		b.WriteString("ssh_authorized_keys:\n")
+		for _, k := range strings.Split(keys, "\n") {
+			if k = strings.TrimSpace(k); k != "" {
+				fmt.Fprintf(&b, "  - %q\n", k)
+			}
+		}
+	}
+	b.WriteString("package_update: true\n")
+	b.WriteString("packages:\n  - curl\n  - ca-certificates\n")
+	b.WriteString("runcmd:\n")
+	install := fmt.Sprintf("curl -fsSL %s | bash -s -- --server-url '%s' --api-key '%s'", agentInstallScriptURL, serverURL, apiKey)
+	fmt.Fprintf(&b, "  - [sh, -c, %q]\n", install)
+	return b.String()
+}
+
+// linkProvisionedGuests links the guests of provisions whose agent has
+// reported, once the poller has seen them.
+func (s *Service) linkProvisionedGuests(ctx context.Context) {
+	if n, err := s.repo.LinkProvisionedGuests(ctx, ""); err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("proxmox provision: link pass failed: %v", err))
+	} else if n > 0 {
+		slog.InfoContext(ctx, fmt.Sprintf("proxmox provision: linked %d new guest(s)", n))
+	}
+}
@@ -0,0 +1,119 @@
+package proxmox
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/serversupervisor/server/internal/config"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+type fakeHosts struct {
+	rotated int
+}
+
+func (f *fakeHosts) Register(context.Context, models.HostRegistration) (string, string, error) {
+	return "host-1", "host-1.first", nil
+}
+func (f *fakeHosts) RotateKey(context.Context, string) (string, error) {
+	f.rotated++
+	return "host-1.minted", nil
+}
+
+func TestParseIPConfig(t *testing.T) {
+	cases := []struct {
+		in, ip string
+		ok     bool
+	}{
+		{"", placeholderHostIP, true},
+		{"ip=dhcp", placeholderHostIP, true},
+		{"ip=10.0.0.20/24,gw=10.0.0.1", "10.0.0.20", true},
+		{"ip=10.0.0.20", "", false}, // CIDR required by PVE
+		{"ip=10.0.0.20/24,gw=nope", "", false},
+		{"ip=10.0.0.20/24,foo=bar", "", false},
+	}
+	for _, c := range cases {
+		ip, err := parseIPConfig(c.in)
+		if (err == nil) != c.ok || (c.ok && ip != c.ip) {
+			t.Errorf("parseIPConfig(%q) = %q, %v; want %q ok=%v", c.in, ip, err, c.ip, c.ok)
+		}
+	}
+}
+
+func TestValidateProvision_RejectsBadHostname(t *testing.T) {
+	req := models.ProxmoxProvisionRequest{Name: "web_01", TemplateVMID: 9000}
+	if _, err := validateProvision(&req); status(err) != 400 {
+		t.Fatalf("underscore in hostname should be 400, got %v", err)
+	}
+	req = models.ProxmoxProvisionRequest{Name: "web-01", TemplateVMID: 9000}
+	if _, err := validateProvision(&req); err != nil || req.Disk != "scsi0" {
+		t.Fatalf("valid request rejected (%v) or disk not defaulted (%q)", err, req.Disk)
+	}
+}
+
+func TestProvisionConfigParams(t *testing.T) {
+	params := provisionConfigParams(models.ProxmoxProvisionRequest{
+		Cores: 2, MemoryMB: 4096, Bridge: "vmbr1", VLANTag: 20, Nameserver: "1.1.1.1  9.9.9.9",
+	}, "uuid=abc", "https://ss.example/api/provision/tok/")
+	want := map[string]string{
+		"cores": "2", "memory": "4096", "net0": "virtio,bridge=vmbr1,tag=20",
+		"ipconfig0": "ip=dhcp", "nameserver": "1.1.1.1 9.9.9.9",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("%s = %q, want %q", k, params[k], v)
+		}
+	}
+	if !strings.HasPrefix(params["smbios1"], "uuid=abc,serial=") {
+		t.Errorf("smbios1 = %q", params["smbios1"])
+	}
+}
+
+func TestSeedUserData_MintsKeyAndFillsDHCPAddress(t *testing.T) {
+	token := strings.Repeat("ab", 32)
+	hostID := "host-1"
+	repo := &fakeRepo{
+		provision: &models.ProxmoxProvision{
+			ID: "prov-1", Name: "web-01", Status: "waiting_agent", HostID: &hostID,
+			SSHAuthorizedKeys: "ssh-ed25519 AAAAC3Nza admin@laptop",
+		},
+		seedTokenHash: hashSeedToken(token),
+		host:          &models.Host{ID: hostID, IPAddress: placeholderHostIP},
+	}
+	hosts := &fakeHosts{}
+	svc := &Service{repo: repo, cfg: &config.Config{BaseURL: "https://ss.example/"}, hosts: hosts}
+
+	ud, err := svc.SeedUserData(context.Background(), token, "192.168.1.50")
+	if err != nil {
+		t.Fatalf("SeedUserData: %v", err)
+	}
+	if hosts.rotated != 1 {
+		t.Errorf("key rotated %d times, want 1", hosts.rotated)
+	}
+	for _, want := range []string{"#cloud-config\n", "hostname: web-01", "--server-url 'https://ss.example' --api-key 'host-1.minted'", `"ssh-ed25519 AAAAC3Nza admin@laptop"`} {
+		if !strings.Contains(ud, want) {
+			t.Errorf("user-data misses %q:\n%s", want, ud)
+		}
+	}
+	if repo.hostIP != "192.168.1.50" {
+		t.Errorf("host IP = %q, want the caller address", repo.hostIP)
+	}
+}
+
+func TestSeedUserData_DeadOnceLinked(t *testing.T) {
+	token := strings.Repeat("cd", 32)
+	hostID := "host-1"
+	repo := &fakeRepo{
+		provision:     &models.ProxmoxProvision{ID: "prov-1", Status: "linked", HostID: &hostID},
+		seedTokenHash: hashSeedToken(token),
+	}
+	hosts := &fakeHosts{}
+	svc := &Service{repo: repo, cfg: &config.Config{BaseURL: "https://ss.example"}, hosts: hosts}
+	if _, err := svc.SeedUserData(context.Background(), token, ""); status(err) != 404 {
+		t.Fatalf("linked provision should not serve a seed, got %v", err)
+	}
+	if hosts.rotated != 0 {
+		t.Error("no key must be minted for a dead seed")
+	}
+}
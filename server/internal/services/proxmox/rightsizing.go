@@ -0,0 +1,430 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// Right-sizing thresholds. The window is also the cadence: one run a week,
+// over the week just past.
+const (
+	rightsizingWindow     = 7 * 24 * time.Hour
+	rightsizingMinHistory = 72 * time.Hour // younger guests are not judged yet
+
+	rsOverMemP95       = 0.30 // p95 RAM below this share of the allocation: overprovisioned
+	rsHighP95          = 0.90 // p95 RAM / CPU above this share: underprovisioned
+	rsBalloonSustained = 0.5  // balloon inflated at least half of the time
+	rsCPUPressureHigh  = 10.0 // average CPU pressure stall, %
+	rsMemHeadroom      = 1.3  // new size = p95 used × headroom when shrinking
+	rsMemTargetUse     = 0.7  // p95 used should land at 70 % of a grown allocation
+	rsMemStep          = 256 << 20
+	rsMemMin           = 512 << 20
+
+	rsNodeMemMax        = 0.90 // projected node RAM use past which a failover is flagged
+	rsNodeMemTarget     = 0.85 // a migration must not push its target past this
+	rsRebalanceGap      = 0.25 // RAM use gap between nodes worth a migration
+	rsRebalanceMaxMoves = 3    // per cluster and run
+	rsContentionCPUGap  = 0.20 // a contention target must be this much less busy
+)
+
+// RunDueRightsizing runs the analysis when the last one is older than the
+// window (scheduling owned by poller.Every; the check survives restarts).
+func (s *Service) RunDueRightsizing(ctx context.Context) {
+	last, err := s.repo.GetLatestProxmoxRightsizingRun(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "proxmox rightsizing: failed to read last run", slog.Any("err", err))
+		return
+	}
+	if last != nil && time.Since(last.StartedAt) < rightsizingWindow {
+		return
+	}
+	if _, err := s.RunRightsizing(ctx); err != nil {
+		slog.ErrorContext(ctx, "proxmox rightsizing: run failed", slog.Any("err", err))
+	}
+}
+
+// RunRightsizing analyses the last week of guest metrics against the current
+// node capacity and replaces the recommendations. DB only: no PVE call.
+func (s *Service) RunRightsizing(ctx context.Context) (*models.ProxmoxRightsizingRun, error) {
+	s.rsMu.Lock()
+	if s.rsRunning {
+		s.rsMu.Unlock()
+		return nil, apperr.Conflict("une analyse de dimensionnement est déjà en cours")
+	}
+	s.rsRunning = true
+	s.rsMu.Unlock()
+	defer func() {
+		s.rsMu.Lock()
+		s.rsRunning = false
+		s.rsMu.Unlock()
+	}()
+
+	runID, err := s.repo.CreateProxmoxRightsizingRun(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	run := models.ProxmoxRightsizingRun{ID: runID, Status: "success"}
+	var recs []models.ProxmoxRightsizingRecommendation
+
+	now := time.Now()
+	nodes, err := s.repo.ListProxmoxNodes(ctx)
+	var guests []models.ProxmoxGuest
+	var usage []database.ProxmoxGuestUsage
+	if err == nil {
+		guests, err = s.repo.ListProxmoxGuests(ctx, "", "", "running")
+	}
+	if err == nil {
+		usage, err = s.repo.GetProxmoxGuestUsage(ctx, now.Add(-rightsizingWindow))
+	}
+	if err != nil {
+		run.Status, run.Error = "failed", err.Error()
+	} else {
+		recs = analyzeRightsizing(nodes, guests, usage, now)
+		run.GuestsAnalyzed, run.NodesAnalyzed = len(usage), len(nodes)
+	}
+	if err := s.repo.FinishProxmoxRightsizingRun(context.WithoutCancel(ctx), run, recs); err != nil {
+		return nil, apperr.Internal(err)
+	}
+	slog.InfoContext(ctx, "proxmox rightsizing: run finished",
+		slog.String("status", run.Status), slog.Int("guests", run.GuestsAnalyzed), slog.Int("recommendations", len(recs)))
+	return s.repo.GetLatestProxmoxRightsizingRun(ctx)
+}
+
+// GetRightsizingReport returns the latest run and the recommendations of the
+// latest successful one.
+func (s *Service) GetRightsizingReport(ctx context.Context) (*models.ProxmoxRightsizingReport, error) {
+	run, err := s.repo.GetLatestProxmoxRightsizingRun(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	recs, err := s.repo.ListProxmoxRightsizingRecommendations(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	return &models.ProxmoxRightsizingReport{Run: run, Recommendations: recs}, nil
+}
+
+// rsNode is a node's capacity with the RAM use projected by the analysis.
+type rsNode struct {
+	name     string
+	memTotal int64
+	memUsed  int64
+	cpus     int
+	cpuUsage float64
+}
+
+func (n *rsNode) memRatio(extra int64) float64 {
+	if n.memTotal <= 0 {
+		return 1
+	}
+	return float64(n.memUsed+extra) / float64(n.memTotal)
+}
+
+// analyzeRightsizing turns guest usage and node capacity into
+// recommendations, cluster by cluster (one connection = one cluster).
+func analyzeRightsizing(nodes []models.ProxmoxNode, guests []models.ProxmoxGuest, usage []database.ProxmoxGuestUsage, now time.Time) []models.ProxmoxRightsizingRecommendation {
+	clusters := map[string]map[string]*rsNode{}
+	for _, n := range nodes {
+		if n.Status != "online" {
+			continue
+		}
+		if clusters[n.ConnectionID] == nil {
+			clusters[n.ConnectionID] = map[string]*rsNode{}
+		}
+		clusters[n.ConnectionID][n.NodeName] = &rsNode{
+			name: n.NodeName, memTotal: n.MemTotal, memUsed: n.MemUsed, cpus: n.CPUCount, cpuUsage: n.CPUUsage,
+		}
+	}
+
+	var recs []models.ProxmoxRightsizingRecommendation
+	moved := map[string]bool{} // guests already given a migration target
+	for _, u := range usage {
+		if now.Sub(u.Since) < rightsizingMinHistory || u.MemAlloc <= 0 {
+			continue
+		}
+		cluster := clusters[u.ConnectionID]
+		for _, r := range guestRecommendations(u, cluster) {
+			if r.TargetNode != "" {
+				moved[u.ID] = true
+			}
+			recs = append(recs, r)
+		}
+	}
+
+	connIDs := make([]string, 0, len(clusters))
+	for id := range clusters {
+		connIDs = append(connIDs, id)
+	}
+	sort.Strings(connIDs)
+	for _, connID := range connIDs {
+		cluster := clusters[connID]
+		if len(cluster) < 2 {
+			continue
+		}
+		var running []models.ProxmoxGuest
+		for _, g := range guests {
+			if g.ConnectionID == connID && g.Status == "running" && cluster[g.NodeName] != nil {
+				running = append(running, g)
+			}
+		}
+		recs = append(recs, failoverRecommendations(connID, cluster, running)...)
+		recs = append(recs, rebalanceRecommendations(connID, cluster, running, moved)...)
+	}
+	return recs
+}
+
+// guestRecommendations sizes one guest: at most one memory and one CPU finding.
+func guestRecommendations(u database.ProxmoxGuestUsage, cluster map[string]*rsNode) []models.ProxmoxRightsizingRecommendation {
+	base := models.ProxmoxRightsizingRecommendation{
+		ConnectionID: u.ConnectionID, GuestID: u.ID, GuestName: u.Name, GuestType: u.GuestType, VMID: u.VMID,
+		NodeName: u.NodeName, CurrentCPUs: u.CPUAlloc, CurrentMem: u.MemAlloc,
+		MemP95: u.MemP95, CPUP95: u.CPUP95, BalloonRatio: u.BalloonRatio, CPUPressure: u.CPUPressure,
+	}
+	node := cluster[u.NodeName]
+	var out []models.ProxmoxRightsizingRecommendation
+
+	switch {
+	case u.MemP95 >= rsHighP95 || u.BalloonRatio >= rsBalloonSustained:
+		r := base
+		r.Kind, r.Severity = "underprovisioned_memory", "warning"
+		r.SuggestedMem = roundMem(math.Max(float64(u.MemUsedP95)/rsMemTargetUse, float64(u.MemAlloc)*1.25))
+		var why []string
+		if u.MemP95 >= rsHighP95 {
+			why = append(why, fmt.Sprintf("RAM p95 à %.0f %% de l'allocation", u.MemP95*100))
+		}
+		if u.BalloonRatio >= rsBalloonSustained {
+			why = append(why, fmt.Sprintf("ballon gonflé %.0f %% du temps : l'hôte reprend de la mémoire au guest", u.BalloonRatio*100))
+		}
+		if node != nil && node.memRatio(r.SuggestedMem-u.MemAlloc) > rsNodeMemTarget {
+			if t := memTarget(cluster, u.NodeName, r.SuggestedMem); t != nil {
+				r.TargetNode = t.name
+				why = append(why, fmt.Sprintf("pas assez de RAM libre sur %s pour %s, migrer vers %s", u.NodeName, formatGB(r.SuggestedMem), t.name))
+			}
+		}
+		r.Reason = strings.Join(why, " ; ")
+		out = append(out, r)
+	case u.MemP95 < rsOverMemP95 && u.MemAlloc > rsMemMin:
+		suggested := roundMem(math.Max(float64(u.MemUsedP95)*rsMemHeadroom, rsMemMin))
+		if suggested < u.MemAlloc {
+			r := base
+			r.Kind, r.Severity = "overprovisioned_memory", "info"
+			r.SuggestedMem = suggested
+			r.Reason = fmt.Sprintf("RAM p95 à %.0f %% de l'allocation (%s sur %s) : %s libérés",
+				u.MemP95*100, formatGB(u.MemUsedP95), formatGB(u.MemAlloc), formatGB(u.MemAlloc-suggested))
+			out = append(out, r)
+		}
+	}
+
+	switch {
+	case u.CPUP95 >= rsHighP95:
+		suggested := math.Ceil(u.CPUAlloc * 1.5)
+		if node != nil && node.cpus > 0 {
+			suggested = math.Min(suggested, float64(node.cpus))
+		}
+		if suggested > u.CPUAlloc {
+			r := base
+			r.Kind, r.Severity = "underprovisioned_cpu", "warning"
+			r.SuggestedCPUs = suggested
+			r.Reason = fmt.Sprintf("CPU p95 à %.0f %% des %g vCPU alloués", u.CPUP95*100, u.CPUAlloc)
+			out = append(out, r)
+		}
+	case u.CPUPressure >= rsCPUPressureHigh:
+		// The guest waits for CPUs it does not even saturate: the node is the
+		// bottleneck, more vCPUs would make it worse.
+		r := base
+		r.Kind, r.Severity = "cpu_contention", "warning"
+		r.Reason = fmt.Sprintf("attente CPU moyenne de %.0f %% alors que le guest n'utilise que %.0f %% de ses vCPU : le nœud %s est saturé",
+			u.CPUPressure, u.CPUP95*100, u.NodeName)
+		if t := cpuTarget(cluster, node); t != nil {
+			r.TargetNode = t.name
+			r.Reason += fmt.Sprintf(", migrer vers %s (CPU à %.0f %%)", t.name, t.cpuUsage*100)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// failoverRecommendations simulates the loss of each node: its running
+// guests restart on the survivors, largest first, each on the one left the
+// least loaded. A node whose guests would not fit, or would push a survivor
+// past rsNodeMemMax, is flagged.
+func failoverRecommendations(connID string, cluster map[string]*rsNode, running []models.ProxmoxGuest) []models.ProxmoxRightsizingRecommendation {
+	var out []models.ProxmoxRightsizingRecommendation
+	for _, failed := range sortedNodes(cluster) {
+		var displaced []models.ProxmoxGuest
+		for _, g := range running {
+			if g.NodeName == failed.name {
+				displaced = append(displaced, g)
+			}
+		}
+		if len(displaced) == 0 {
+			continue
+		}
+		sort.SliceStable(displaced, func(i, j int) bool { return displaced[i].MemUsage > displaced[j].MemUsage })
+
+		extra := map[string]int64{}
+		var unplaced int
+		var unplacedMem int64
+		for _, g := range displaced {
+			var best *rsNode
+			for _, n := range sortedNodes(cluster) {
+				if n.name == failed.name || n.memUsed+extra[n.name]+g.MemUsage > n.memTotal {
+					continue
+				}
+				if best == nil || n.memRatio(extra[n.name]+g.MemUsage) < best.memRatio(extra[best.name]+g.MemUsage) {
+					best = n
+				}
+			}
+			if best == nil {
+				unplaced++
+				unplacedMem += g.MemUsage
+				continue
+			}
+			extra[best.name] += g.MemUsage
+		}
+
+		var worst *rsNode
+		for _, n := range sortedNodes(cluster) {
+			if n.name != failed.name && (worst == nil || n.memRatio(extra[n.name]) > worst.memRatio(extra[worst.name])) {
+				worst = n
+			}
+		}
+		r := models.ProxmoxRightsizingRecommendation{ConnectionID: connID, NodeName: failed.name, Kind: "node_failover"}
+		switch {
+		case unplaced > 0:
+			r.Severity = "critical"
+			r.Reason = fmt.Sprintf("si %s tombe, %d guest(s) (%s de RAM) ne trouveraient de place sur aucun autre nœud",
+				failed.name, unplaced, formatGB(unplacedMem))
+		case worst != nil && worst.memRatio(extra[worst.name]) > rsNodeMemMax:
+			r.Severity = "warning"
+			r.Reason = fmt.Sprintf("si %s tombe, %s monterait à %.0f %% de RAM en reprenant ses guests",
+				failed.name, worst.name, worst.memRatio(extra[worst.name])*100)
+		default:
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// rebalanceRecommendations moves running guests from the most to the least
+// RAM-loaded node while the gap exceeds rsRebalanceGap, picking each time the
+// guest that evens them out best.
+func rebalanceRecommendations(connID string, cluster map[string]*rsNode, running []models.ProxmoxGuest, moved map[string]bool) []models.ProxmoxRightsizingRecommendation {
+	used := map[string]int64{}
+	for name, n := range cluster {
+		used[name] = n.memUsed
+	}
+	ratio := func(n *rsNode) float64 { return n.memRatio(used[n.name] - n.memUsed) }
+
+	var out []models.ProxmoxRightsizingRecommendation
+	for len(out) < rsRebalanceMaxMoves {
+		var hi, lo *rsNode
+		for _, n := range sortedNodes(cluster) {
+			if hi == nil || ratio(n) > ratio(hi) {
+				hi = n
+			}
+			if lo == nil || ratio(n) < ratio(lo) {
+				lo = n
+			}
+		}
+		gap := ratio(hi) - ratio(lo)
+		if hi == lo || gap <= rsRebalanceGap {
+			break
+		}
+
+		var pick *models.ProxmoxGuest
+		var pickGap float64
+		for i := range running {
+			g := &running[i]
+			if g.NodeName != hi.name || moved[g.ID] || g.MemUsage <= 0 {
+				continue
+			}
+			newHi := float64(used[hi.name]-g.MemUsage) / float64(hi.memTotal)
+			newLo := float64(used[lo.name]+g.MemUsage) / float64(lo.memTotal)
+			newGap := math.Abs(newHi - newLo)
+			if newLo > rsNodeMemTarget || newGap >= gap {
+				continue
+			}
+			if pick == nil || newGap < pickGap {
+				pick, pickGap = g, newGap
+			}
+		}
+		if pick == nil {
+			break
+		}
+
+		hiBefore, loBefore := ratio(hi), ratio(lo)
+		used[hi.name] -= pick.MemUsage
+		used[lo.name] += pick.MemUsage
+		moved[pick.ID] = true
+		out = append(out, models.ProxmoxRightsizingRecommendation{
+			ConnectionID: connID, GuestID: pick.ID, GuestName: pick.Name, GuestType: pick.GuestType, VMID: pick.VMID,
+			NodeName: hi.name, Kind: "rebalance", Severity: "info",
+			CurrentCPUs: pick.CPUAlloc, CurrentMem: pick.MemAlloc, TargetNode: lo.name,
+			Reason: fmt.Sprintf("équilibrage RAM : %s %.0f %% → %.0f %%, %s %.0f %% → %.0f %%",
+				hi.name, hiBefore*100, ratio(hi)*100, lo.name, loBefore*100, ratio(lo)*100),
+		})
+	}
+	return out
+}
+
+// memTarget is the other node with the most free RAM that can take a guest
+// of the given size without passing rsNodeMemTarget.
+func memTarget(cluster map[string]*rsNode, current string, size int64) *rsNode {
+	var best *rsNode
+	for _, n := range sortedNodes(cluster) {
+		if n.name == current || n.memRatio(size) > rsNodeMemTarget {
+			continue
+		}
+		if best == nil || n.memTotal-n.memUsed > best.memTotal-best.memUsed {
+			best = n
+		}
+	}
+	return best
+}
+
+// cpuTarget is the least CPU-busy other node, when clearly less busy than
+// the current one.
+func cpuTarget(cluster map[string]*rsNode, current *rsNode) *rsNode {
+	if current == nil {
+		return nil
+	}
+	var best *rsNode
+	for _, n := range sortedNodes(cluster) {
+		if n.name != current.name && (best == nil || n.cpuUsage < best.cpuUsage) {
+			best = n
+		}
+	}
+	if best == nil || best.cpuUsage > current.cpuUsage-rsContentionCPUGap {
+		return nil
+	}
+	return best
+}
+
+func sortedNodes(cluster map[string]*rsNode) []*rsNode {
+	out := make([]*rsNode, 0, len(cluster))
+	for _, n := range cluster {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// roundMem rounds a memory size up to the next 256 MiB.
+func roundMem(b float64) int64 {
+	return int64(math.Ceil(b/rsMemStep)) * rsMemStep
+}
+
+func formatGB(b int64) string {
+	return fmt.Sprintf("%.1f GB", float64(b)/(1<<30))
+}
@@ -0,0 +1,155 @@
+package proxmox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/database"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+const gib = int64(1) << 30
+
+func rsUsage(id, node string, memAlloc int64, cpus float64, mutate func(*database.ProxmoxGuestUsage)) database.ProxmoxGuestUsage {
+	u := database.ProxmoxGuestUsage{
+		ProxmoxGuest: models.ProxmoxGuest{
+			ID: id, ConnectionID: "c1", NodeName: node, GuestType: "vm", Name: id, Status: "running",
+			CPUAlloc: cpus, MemAlloc: memAlloc,
+		},
+		Samples: 20000, Since: time.Now().Add(-7 * 24 * time.Hour),
+		MemP95: 0.5, MemUsedP95: memAlloc / 2, CPUP95: 0.4,
+	}
+	if mutate != nil {
+		mutate(&u)
+	}
+	return u
+}
+
+func rsNodes(used ...int64) []models.ProxmoxNode {
+	var out []models.ProxmoxNode
+	for i, u := range used {
+		out = append(out, models.ProxmoxNode{
+			ConnectionID: "c1", NodeName: []string{"pve1", "pve2", "pve3"}[i], Status: "online",
+			CPUCount: 16, CPUUsage: 0.3, MemTotal: 64 * gib, MemUsed: u,
+		})
+	}
+	return out
+}
+
+func kinds(recs []models.ProxmoxRightsizingRecommendation) map[string]models.ProxmoxRightsizingRecommendation {
+	out := map[string]models.ProxmoxRightsizingRecommendation{}
+	for _, r := range recs {
+		out[r.Kind+"/"+r.GuestID+r.NodeName] = r
+	}
+	return out
+}
+
+func TestAnalyzeRightsizing_GuestSizes(t *testing.T) {
+	usage := []database.ProxmoxGuestUsage{
+		// 16 GB allocated, p95 2 GB used -> shrink to 2.6 GB rounded up.
+		rsUsage("idle", "pve1", 16*gib, 4, func(u *database.ProxmoxGuestUsage) {
+			u.MemP95, u.MemUsedP95 = 0.125, 2*gib
+		}),
+		// Balloon inflated most of the time -> grow.
+		rsUsage("squeezed", "pve1", 8*gib, 2, func(u *database.ProxmoxGuestUsage) {
+			u.BalloonRatio = 0.8
+		}),
+		// CPU saturated -> 2 × 1.5 = 3 vCPUs.
+		rsUsage("busy", "pve1", 4*gib, 2, func(u *database.ProxmoxGuestUsage) {
+			u.CPUP95 = 0.97
+		}),
+		// Too young to be judged.
+		rsUsage("new", "pve1", 16*gib, 4, func(u *database.ProxmoxGuestUsage) {
+			u.MemP95, u.MemUsedP95, u.Since = 0.05, gib, time.Now().Add(-24*time.Hour)
+		}),
+	}
+	recs := kinds(analyzeRightsizing(rsNodes(20*gib), nil, usage, time.Now()))
+
+	over, ok := recs["overprovisioned_memory/idlepve1"]
+	if !ok || over.SuggestedMem != roundMem(float64(2*gib)*rsMemHeadroom) || over.SuggestedMem >= over.CurrentMem {
+		t.Errorf("idle guest: %+v", over)
+	}
+	under, ok := recs["underprovisioned_memory/squeezedpve1"]
+	if !ok || under.SuggestedMem != 10*gib || under.TargetNode != "" {
+		t.Errorf("ballooned guest: %+v", under)
+	}
+	cpu, ok := recs["underprovisioned_cpu/busypve1"]
+	if !ok || cpu.SuggestedCPUs != 3 {
+		t.Errorf("busy guest: %+v", cpu)
+	}
+	for k := range recs {
+		if k == "overprovisioned_memory/newpve1" {
+			t.Error("a guest with less than 3 days of history must not be judged")
+		}
+	}
+}
+
+func TestAnalyzeRightsizing_GrowOnFullNodeSuggestsMigration(t *testing.T) {
+	usage := []database.ProxmoxGuestUsage{
+		rsUsage("db", "pve1", 16*gib, 4, func(u *database.ProxmoxGuestUsage) {
+			u.MemP95, u.MemUsedP95 = 0.95, 15*gib
+		}),
+	}
+	recs := kinds(analyzeRightsizing(rsNodes(58*gib, 20*gib), nil, usage, time.Now()))
+	r, ok := recs["underprovisioned_memory/dbpve1"]
+	if !ok || r.TargetNode != "pve2" {
+		t.Fatalf("expected a migration to pve2, got %+v", r)
+	}
+}
+
+func TestAnalyzeRightsizing_CPUContention(t *testing.T) {
+	nodes := rsNodes(20*gib, 20*gib)
+	nodes[0].CPUUsage, nodes[1].CPUUsage = 0.95, 0.30
+	usage := []database.ProxmoxGuestUsage{
+		rsUsage("web", "pve1", 4*gib, 4, func(u *database.ProxmoxGuestUsage) {
+			u.CPUP95, u.CPUPressure = 0.5, 25
+		}),
+	}
+	recs := kinds(analyzeRightsizing(nodes, nil, usage, time.Now()))
+	r, ok := recs["cpu_contention/webpve1"]
+	if !ok || r.TargetNode != "pve2" || r.SuggestedCPUs != 0 {
+		t.Fatalf("expected a contention finding towards pve2, got %+v", r)
+	}
+}
+
+func TestAnalyzeRightsizing_NodeFailover(t *testing.T) {
+	running := func(id, node string, mem int64) models.ProxmoxGuest {
+		return models.ProxmoxGuest{ID: id, ConnectionID: "c1", NodeName: node, Status: "running", MemUsage: mem, MemAlloc: mem}
+	}
+	// pve1 carries 40 GB of guests; pve2 (50 GB used) cannot take them.
+	nodes := rsNodes(42*gib, 50*gib)
+	guests := []models.ProxmoxGuest{running("a", "pve1", 30*gib), running("b", "pve1", 10*gib), running("c", "pve2", 48*gib)}
+	recs := kinds(analyzeRightsizing(nodes, guests, nil, time.Now()))
+	if r, ok := recs["node_failover/pve1"]; !ok || r.Severity != "critical" {
+		t.Errorf("pve1 failure cannot be absorbed: %+v", r)
+	}
+	if r, ok := recs["node_failover/pve2"]; !ok || r.Severity != "critical" {
+		t.Errorf("pve2 failure cannot be absorbed: %+v", r)
+	}
+
+	// Three half-empty nodes absorb any single failure.
+	nodes = rsNodes(20*gib, 20*gib, 20*gib)
+	guests = []models.ProxmoxGuest{running("a", "pve1", 10*gib), running("b", "pve2", 10*gib), running("c", "pve3", 10*gib)}
+	for k := range kinds(analyzeRightsizing(nodes, guests, nil, time.Now())) {
+		t.Errorf("unexpected recommendation %s", k)
+	}
+}
+
+func TestAnalyzeRightsizing_Rebalance(t *testing.T) {
+	nodes := rsNodes(50*gib, 10*gib)
+	guests := []models.ProxmoxGuest{
+		{ID: "big", ConnectionID: "c1", NodeName: "pve1", Status: "running", MemUsage: 30 * gib},
+		{ID: "mid", ConnectionID: "c1", NodeName: "pve1", Status: "running", MemUsage: 20 * gib},
+	}
+	var moves []models.ProxmoxRightsizingRecommendation
+	for _, r := range analyzeRightsizing(nodes, guests, nil, time.Now()) {
+		if r.Kind == "rebalance" {
+			moves = append(moves, r)
+		}
+	}
+	// Moving "mid" (20 GB) evens pve1/pve2 out at 30/30 GB; "big" would
+	// just swap which node is overloaded.
+	if len(moves) != 1 || moves[0].GuestID != "mid" || moves[0].TargetNode != "pve2" {
+		t.Fatalf("moves = %+v", moves)
+	}
+}
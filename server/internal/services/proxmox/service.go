@@ -54,6 +54,7 @@ type Repository interface {
 	BackfillProxmoxNodeSensorSources(ctx context.Context) error
 	GetHost(ctx context.Context, id string) (*models.Host, error)
 
+	GetProxmoxStorageByID(ctx context.Context, id string) (*models.ProxmoxStorage, error)
 	ListProxmoxDisksByNode(ctx context.Context, connectionID, nodeName string) ([]models.ProxmoxDisk, error)
 	ListProxmoxDisksByHost(ctx context.Context, hostID string) ([]models.ProxmoxDisk, error)
 
@@ -63,6 +64,54 @@ type Repository interface {
 	ListProxmoxBackupRuns(ctx context.Context, connectionID string) ([]models.ProxmoxBackupRun, error)
 
 	GetExposureByIPs(ctx context.Context, ips []string, since time.Time) (map[string]*models.HostExposure, error)
+
+	ListProxmoxSnapshotPolicies(ctx context.Context) ([]models.ProxmoxSnapshotPolicy, error)
+	ListDueProxmoxSnapshotPolicies(ctx context.Context, now time.Time) ([]models.ProxmoxSnapshotPolicy, error)
+	GetProxmoxSnapshotPolicy(ctx context.Context, id string) (*models.ProxmoxSnapshotPolicy, error)
+	CreateProxmoxSnapshotPolicy(ctx context.Context, p models.ProxmoxSnapshotPolicy) (*models.ProxmoxSnapshotPolicy, error)
+	UpdateProxmoxSnapshotPolicy(ctx context.Context, p models.ProxmoxSnapshotPolicy) error
+	DeleteProxmoxSnapshotPolicy(ctx context.Context, id string) error
+	FinishProxmoxSnapshotPolicy(ctx context.Context, id string, ranAt time.Time, status, lastError string, nextRunAt *time.Time) error
+	CreateProxmoxSnapshotRun(ctx context.Context, policyID, guestID string) (int64, error)
+	FinishProxmoxSnapshotRun(ctx context.Context, id int64, status, snapshotName string, pruned int, errMsg string) error
+	ListProxmoxSnapshotRuns(ctx context.Context, policyID string, limit int) ([]models.ProxmoxSnapshotPolicyRun, error)
+
+	ListPBSConnections(ctx context.Context) ([]models.PBSConnection, error)
+	CreatePBSConnection(ctx context.Context, name, apiURL, tokenID, tokenSecret string, insecureSkipVerify, enabled bool, pollIntervalSec int) (string, error)
+	GetPBSConnectionByID(ctx context.Context, id string) (*models.PBSConnection, error)
+	UpdatePBSConnection(ctx context.Context, id, name, apiURL, tokenID, tokenSecret string, insecureSkipVerify, enabled bool, pollIntervalSec int) error
+	DeletePBSConnection(ctx context.Context, id string) error
+	GetEnabledPBSConnections(ctx context.Context) ([]database.PBSConnectionFull, error)
+	GetPBSTokenSecret(ctx context.Context, id string) (string, error)
+	ListPBSDatastores(ctx context.Context, connectionID string) ([]models.PBSDatastore, error)
+	ListPBSBackupGroups(ctx context.Context, connectionID, store string) ([]models.PBSBackupGroup, error)
+	ListPBSJobs(ctx context.Context, connectionID string) ([]models.PBSJob, error)
+
+	ListProxmoxClusterHealth(ctx context.Context) ([]models.ProxmoxClusterHealth, error)
+	GetProxmoxClusterHealth(ctx context.Context, connectionID string) (*models.ProxmoxClusterHealth, error)
+
+	CreateProxmoxProvision(ctx context.Context, p models.ProxmoxProvision, seedTokenHash string) (string, error)
+	GetProxmoxProvision(ctx context.Context, id string) (*models.ProxmoxProvision, error)
+	GetProxmoxProvisionBySeedToken(ctx context.Context, seedTokenHash string) (*models.ProxmoxProvision, error)
+	ListProxmoxProvisions(ctx context.Context, limit int) ([]models.ProxmoxProvision, error)
+	UpdateProxmoxProvisionStatus(ctx context.Context, id, status, errMsg string) error
+	SetProxmoxProvisionVMID(ctx context.Context, id string, vmid int) error
+	MarkProxmoxProvisionSeedFetched(ctx context.Context, id string) error
+	DeleteProxmoxProvision(ctx context.Context, id string) error
+	LinkProvisionedGuests(ctx context.Context, hostID string) (int, error)
+	UpdateHost(ctx context.Context, id string, update *models.HostUpdate) error
+
+	GetHostAccess(ctx context.Context, username, hostID string) (restricted bool, level string, err error)
+	CreateAuditLog(ctx context.Context, username, action, hostID, ipAddress, details, status string) (int64, error)
+
+	CreateProxmoxConsoleSession(ctx context.Context, s *models.ProxmoxPendingConsole) error
+	ClaimProxmoxConsoleSession(ctx context.Context, id, username string) (*models.ProxmoxPendingConsole, error)
+
+	GetProxmoxGuestUsage(ctx context.Context, since time.Time) ([]database.ProxmoxGuestUsage, error)
+	CreateProxmoxRightsizingRun(ctx context.Context) (string, error)
+	FinishProxmoxRightsizingRun(ctx context.Context, run models.ProxmoxRightsizingRun, recs []models.ProxmoxRightsizingRecommendation) error
+	GetLatestProxmoxRightsizingRun(ctx context.Context) (*models.ProxmoxRightsizingRun, error)
+	ListProxmoxRightsizingRecommendations(ctx context.Context) ([]models.ProxmoxRightsizingRecommendation, error)
 }
 
 // Service holds the Proxmox HTTP use-cases + owns the background poller.
@@ -71,6 +120,18 @@ type Service struct {
 	cfg    *config.Config
 	poller *Poller
 	bus    *events.Bus
+
+	// snapRunning guards against a policy running twice at once (scheduled
+	// tick vs "run now").
+	snapMu      sync.Mutex
+	snapRunning map[string]bool
+
+	// hosts registers the host a provision enrolls (see SetHostRegistrar).
+	hosts HostRegistrar
+
+	// rsRunning keeps the weekly right-sizing run and "run now" apart.
+	rsMu      sync.Mutex
+	rsRunning bool
 }
 
 func NewService(db *database.DB, cfg *config.Config, bus *events.Bus) *Service {
@@ -83,6 +144,7 @@ func NewService(db *database.DB, cfg *config.Config, bus *events.Bus) *Service {
 // dashboard renders Proxmox nodes/links). Nil-safe when no bus is wired.
 func (s *Service) PollAll(ctx context.Context) {
 	s.poller.PollAll(ctx)
+	s.linkProvisionedGuests(ctx)
 	s.bus.Publish(events.TopicDashboard)
 }
 
@@ -2,6 +2,7 @@ package proxmox
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
 	"time"
@@ -23,6 +24,20 @@ type fakeRepo struct {
 	enabledConns []database.ProxmoxConnectionFull
 	connByID     map[string]*models.ProxmoxConnection
 	guestsByNode map[string][]models.ProxmoxGuest
+
+	// Used by provisioning tests.
+	provision     *models.ProxmoxProvision
+	seedTokenHash string
+	host          *models.Host
+	hostIP        string
+
+	// Used by console tests.
+	guestLink   *models.ProxmoxGuestLink
+	restricted  bool
+	accessLevel string
+	audits      []string
+	guest       *models.ProxmoxGuest
+	consoles    map[string]models.ProxmoxPendingConsole
 }
 
 func (f *fakeRepo) ListProxmoxConnections(context.Context) ([]models.ProxmoxConnection, error) {
@@ -58,9 +73,50 @@ func (f *fakeRepo) ListProxmoxGuestsByNode(_ context.Context, connectionID, node
 	}
 	return nil, nil
 }
-func (f *fakeRepo) GetProxmoxGuestByID(context.Context, string) (*models.ProxmoxGuest, error) {
+func (f *fakeRepo) GetProxmoxGuestByID(_ context.Context, id string) (*models.ProxmoxGuest, error) {
+	if f.guest != nil && f.guest.ID == id {
+		return f.guest, nil
+	}
 	return nil, errors.New("not found")
 }
+func (f *fakeRepo) GetProxmoxStorageByID(context.Context, string) (*models.ProxmoxStorage, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetHostAccess(context.Context, string, string) (bool, string, error) {
+	return f.restricted, f.accessLevel, nil
+}
+func (f *fakeRepo) CreateAuditLog(_ context.Context, _, action, _, _, _, status string) (int64, error) {
+	f.audits = append(f.audits, action+":"+status)
+	return 0, nil
+}
+func (f *fakeRepo) CreateProxmoxConsoleSession(_ context.Context, s *models.ProxmoxPendingConsole) error {
+	if f.consoles == nil {
+		f.consoles = map[string]models.ProxmoxPendingConsole{}
+	}
+	f.consoles[s.ID] = *s
+	return nil
+}
+func (f *fakeRepo) ClaimProxmoxConsoleSession(_ context.Context, id, username string) (*models.ProxmoxPendingConsole, error) {
+	s, ok := f.consoles[id]
+	if !ok || s.Username != username {
+		return nil, nil
+	}
+	delete(f.consoles, id)
+	return &s, nil
+}
+func (f *fakeRepo) GetProxmoxGuestUsage(context.Context, time.Time) ([]database.ProxmoxGuestUsage, error) {
+	return nil, nil
+}
+func (f *fakeRepo) CreateProxmoxRightsizingRun(context.Context) (string, error) { return "run", nil }
+func (f *fakeRepo) FinishProxmoxRightsizingRun(context.Context, models.ProxmoxRightsizingRun, []models.ProxmoxRightsizingRecommendation) error {
+	return nil
+}
+func (f *fakeRepo) GetLatestProxmoxRightsizingRun(context.Context) (*models.ProxmoxRightsizingRun, error) {
+	return nil, nil
+}
+func (f *fakeRepo) ListProxmoxRightsizingRecommendations(context.Context) ([]models.ProxmoxRightsizingRecommendation, error) {
+	return nil, nil
+}
 func (f *fakeRepo) GetProxmoxGuestMetricsSummary(context.Context, string, int, int) ([]models.ProxmoxNodeMetricsSummary, error) {
 	return nil, nil
 }
@@ -78,7 +134,7 @@ func (f *fakeRepo) UpdateProxmoxGuestLink(context.Context, string, *string, *str
 }
 func (f *fakeRepo) DeleteProxmoxGuestLink(context.Context, string) error { return nil }
 func (f *fakeRepo) GetProxmoxGuestLinkByGuest(context.Context, string) (*models.ProxmoxGuestLink, error) {
-	return nil, nil
+	return f.guestLink, nil
 }
 func (f *fakeRepo) GetProxmoxGuestLinkByHost(context.Context, string) (*models.ProxmoxGuestLink, error) {
 	return nil, nil
@@ -109,7 +165,7 @@ func (f *fakeRepo) ListProxmoxNodeCPUTempSourceCandidates(context.Context, strin
 }
 func (f *fakeRepo) SetProxmoxNodeSensorSource(context.Context, string, string) error { return nil }
 func (f *fakeRepo) BackfillProxmoxNodeSensorSources(context.Context) error           { return nil }
-func (f *fakeRepo) GetHost(context.Context, string) (*models.Host, error)            { return nil, nil }
+func (f *fakeRepo) GetHost(context.Context, string) (*models.Host, error)            { return f.host, nil }
 func (f *fakeRepo) ListProxmoxDisksByNode(context.Context, string, string) ([]models.ProxmoxDisk, error) {
 	return nil, nil
 }
@@ -131,6 +187,95 @@ func (f *fakeRepo) ListProxmoxBackupRuns(context.Context, string) ([]models.Prox
 func (f *fakeRepo) GetExposureByIPs(context.Context, []string, time.Time) (map[string]*models.HostExposure, error) {
 	return nil, nil
 }
+func (f *fakeRepo) ListProxmoxSnapshotPolicies(context.Context) ([]models.ProxmoxSnapshotPolicy, error) {
+	return nil, nil
+}
+func (f *fakeRepo) ListDueProxmoxSnapshotPolicies(context.Context, time.Time) ([]models.ProxmoxSnapshotPolicy, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetProxmoxSnapshotPolicy(context.Context, string) (*models.ProxmoxSnapshotPolicy, error) {
+	return nil, sql.ErrNoRows
+}
+func (f *fakeRepo) CreateProxmoxSnapshotPolicy(_ context.Context, p models.ProxmoxSnapshotPolicy) (*models.ProxmoxSnapshotPolicy, error) {
+	return &p, nil
+}
+func (f *fakeRepo) UpdateProxmoxSnapshotPolicy(context.Context, models.ProxmoxSnapshotPolicy) error {
+	return nil
+}
+func (f *fakeRepo) DeleteProxmoxSnapshotPolicy(context.Context, string) error { return nil }
+func (f *fakeRepo) FinishProxmoxSnapshotPolicy(context.Context, string, time.Time, string, string, *time.Time) error {
+	return nil
+}
+func (f *fakeRepo) CreateProxmoxSnapshotRun(context.Context, string, string) (int64, error) {
+	return 0, nil
+}
+func (f *fakeRepo) FinishProxmoxSnapshotRun(context.Context, int64, string, string, int, string) error {
+	return nil
+}
+func (f *fakeRepo) ListProxmoxSnapshotRuns(context.Context, string, int) ([]models.ProxmoxSnapshotPolicyRun, error) {
+	return nil, nil
+}
+func (f *fakeRepo) ListPBSConnections(context.Context) ([]models.PBSConnection, error) {
+	return nil, nil
+}
+func (f *fakeRepo) CreatePBSConnection(context.Context, string, string, string, string, bool, bool, int) (string, error) {
+	return "", nil
+}
+func (f *fakeRepo) GetPBSConnectionByID(context.Context, string) (*models.PBSConnection, error) {
+	return nil, nil
+}
+func (f *fakeRepo) UpdatePBSConnection(context.Context, string, string, string, string, string, bool, bool, int) error {
+	return nil
+}
+func (f *fakeRepo) DeletePBSConnection(context.Context, string) error { return nil }
+func (f *fakeRepo) GetEnabledPBSConnections(context.Context) ([]database.PBSConnectionFull, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetPBSTokenSecret(context.Context, string) (string, error) { return "", nil }
+func (f *fakeRepo) ListPBSDatastores(context.Context, string) ([]models.PBSDatastore, error) {
+	return nil, nil
+}
+func (f *fakeRepo) ListPBSBackupGroups(context.Context, string, string) ([]models.PBSBackupGroup, error) {
+	return nil, nil
+}
+func (f *fakeRepo) ListPBSJobs(context.Context, string) ([]models.PBSJob, error) { return nil, nil }
+func (f *fakeRepo) ListProxmoxClusterHealth(context.Context) ([]models.ProxmoxClusterHealth, error) {
+	return nil, nil
+}
+func (f *fakeRepo) GetProxmoxClusterHealth(context.Context, string) (*models.ProxmoxClusterHealth, error) {
+	return nil, sql.ErrNoRows
+}
+func (f *fakeRepo) CreateProxmoxProvision(context.Context, models.ProxmoxProvision, string) (string, error) {
+	return "prov-1", nil
+}
+func (f *fakeRepo) GetProxmoxProvision(context.Context, string) (*models.ProxmoxProvision, error) {
+	if f.provision == nil {
+		return nil, sql.ErrNoRows
+	}
+	return f.provision, nil
+}
+func (f *fakeRepo) GetProxmoxProvisionBySeedToken(_ context.Context, hash string) (*models.ProxmoxProvision, error) {
+	if f.provision == nil || hash != f.seedTokenHash {
+		return nil, sql.ErrNoRows
+	}
+	return f.provision, nil
+}
+func (f *fakeRepo) ListProxmoxProvisions(context.Context, int) ([]models.ProxmoxProvision, error) {
+	return nil, nil
+}
+func (f *fakeRepo) UpdateProxmoxProvisionStatus(context.Context, string, string, string) error {
+	return nil
+}
+func (f *fakeRepo) SetProxmoxProvisionVMID(context.Context, string, int) error    { return nil }
+func (f *fakeRepo) MarkProxmoxProvisionSeedFetched(context.Context, string) error { return nil }
+func (f *fakeRepo) DeleteProxmoxProvision(context.Context, string) error          { return nil }
+func (f *fakeRepo) LinkProvisionedGuests(context.Context, string) (int, error)    { return 0, nil }
+func (f *fakeRepo) UpdateHost(_ context.Context, _ string, u *models.HostUpdate) error {
+	if u.IPAddress != nil {
+		f.hostIP = *u.IPAddress
+	}
+	return nil
+}
 
 func newSvc(repo Repository) *Service {
 	return &Service{repo: repo, cfg: &config.Config{}, poller: nil}
@@ -0,0 +1,518 @@
+package proxmox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// snapshotNameRe is PVE's own constraint on snapshot names.
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{1,39}$`)
+
+// autoSnapshotPrefix marks snapshots created by a policy. Retention only ever
+// prunes names carrying the policy's own prefix, so manual and runbook
+// snapshots are never touched.
+const autoSnapshotPrefix = "auto_"
+
+// snapshotTaskPollInterval / snapshotTaskTimeout bound the wait on a PVE
+// snapshot task: a policy deletes old snapshots only once the new one exists,
+// and a runbook step only advances once its snapshot is done.
+var snapshotTaskPollInterval = 2 * time.Second
+
+const snapshotTaskTimeout = 15 * time.Minute
+
+// guestClient resolves a guest row to (guest, PVE client) or an apperr.
+func (s *Service) guestClient(ctx context.Context, guestID string) (*models.ProxmoxGuest, *proxmoxclient.Client, error) {
+	guest, err := s.repo.GetProxmoxGuestByID(ctx, guestID)
+	if err != nil || guest == nil {
+		return nil, nil, apperr.NotFound("guest not found")
+	}
+	secret, conn, err := s.resolveSecret(ctx, guest.ConnectionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return guest, proxmoxclient.New(conn.APIURL, conn.TokenID, secret, conn.InsecureSkipVerify), nil
+}
+
+// ===== manual snapshots =====
+
+// ListGuestSnapshots returns a guest's snapshots live from PVE, newest first.
+func (s *Service) ListGuestSnapshots(ctx context.Context, guestID string) ([]models.ProxmoxSnapshot, error) {
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.ListGuestSnapshots(guest.NodeName, guest.VMID, guest.GuestType)
+	if err != nil {
+		return nil, apperr.BadGateway(err.Error())
+	}
+	out := make([]models.ProxmoxSnapshot, 0, len(raw))
+	for _, r := range raw {
+		out = append(out, toSnapshot(r))
+	}
+	sortSnapshotsNewestFirst(out)
+	return out, nil
+}
+
+// CreateGuestSnapshot takes a snapshot of a guest and returns its name and
+// the PVE task UPID. An empty name defaults to "manual_<timestamp>".
+func (s *Service) CreateGuestSnapshot(ctx context.Context, guestID string, req models.ProxmoxSnapshotCreate) (string, string, error) {
+	if !req.Confirm {
+		return "", "", apperr.Validation("confirmation requise")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "manual_" + time.Now().Format("20060102_150405")
+	}
+	if !snapshotNameRe.MatchString(name) {
+		return "", "", apperr.Validation("nom de snapshot invalide (lettre puis 1 à 39 caractères parmi A-Z a-z 0-9 _ -)")
+	}
+	if strings.HasPrefix(name, autoSnapshotPrefix) {
+		return "", "", apperr.Validation("le préfixe auto_ est réservé aux politiques de snapshot")
+	}
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return "", "", err
+	}
+	upid, err := client.CreateGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, name, strings.TrimSpace(req.Description), req.VMState)
+	if err != nil {
+		return "", "", apperr.BadGateway(err.Error())
+	}
+	return name, upid, nil
+}
+
+// RollbackGuestSnapshot reverts a guest to one of its snapshots.
+func (s *Service) RollbackGuestSnapshot(ctx context.Context, guestID, name string, confirm bool) (string, error) {
+	if !confirm {
+		return "", apperr.Validation("confirmation requise")
+	}
+	if !snapshotNameRe.MatchString(name) {
+		return "", apperr.Validation("nom de snapshot invalide")
+	}
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return "", err
+	}
+	upid, err := client.RollbackGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, name)
+	if err != nil {
+		return "", apperr.BadGateway(err.Error())
+	}
+	return upid, nil
+}
+
+// DeleteGuestSnapshot removes one snapshot of a guest.
+func (s *Service) DeleteGuestSnapshot(ctx context.Context, guestID, name string, confirm bool) (string, error) {
+	if !confirm {
+		return "", apperr.Validation("confirmation requise")
+	}
+	if !snapshotNameRe.MatchString(name) {
+		return "", apperr.Validation("nom de snapshot invalide")
+	}
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return "", err
+	}
+	upid, err := client.DeleteGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, name)
+	if err != nil {
+		return "", apperr.BadGateway(err.Error())
+	}
+	return upid, nil
+}
+
+// SnapshotLinkedGuest snapshots the Proxmox guest confirmed-linked to hostID
+// and waits for the PVE task to finish. It backs the "proxmox / snapshot"
+// runbook step, taken before a risky change on that host. Returns the
+// snapshot name.
+func (s *Service) SnapshotLinkedGuest(ctx context.Context, hostID, description string) (string, error) {
+	link, err := s.repo.GetProxmoxGuestLinkByHost(ctx, hostID)
+	if err != nil || link == nil || link.Status != "confirmed" {
+		return "", fmt.Errorf("aucune VM/LXC Proxmox confirmée n'est liée à cet hôte")
+	}
+	guest, client, err := s.guestClient(ctx, link.GuestID)
+	if err != nil {
+		return "", err
+	}
+	name := "runbook_" + time.Now().Format("20060102_150405")
+	upid, err := client.CreateGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, name, description, false)
+	if err != nil {
+		return "", err
+	}
+	if err := waitProxmoxTask(ctx, client, guest.NodeName, upid); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// ===== policies =====
+
+func (s *Service) ListSnapshotPolicies(ctx context.Context) ([]models.ProxmoxSnapshotPolicy, error) {
+	return s.repo.ListProxmoxSnapshotPolicies(ctx)
+}
+
+func (s *Service) GetSnapshotPolicy(ctx context.Context, id string) (*models.ProxmoxSnapshotPolicy, error) {
+	p, err := s.repo.GetProxmoxSnapshotPolicy(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperr.NotFound("politique de snapshot introuvable")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *Service) CreateSnapshotPolicy(ctx context.Context, req models.ProxmoxSnapshotPolicyRequest) (*models.ProxmoxSnapshotPolicy, error) {
+	p, err := s.snapshotPolicyFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateProxmoxSnapshotPolicy(ctx, *p)
+}
+
+func (s *Service) UpdateSnapshotPolicy(ctx context.Context, id string, req models.ProxmoxSnapshotPolicyRequest) (*models.ProxmoxSnapshotPolicy, error) {
+	if _, err := s.GetSnapshotPolicy(ctx, id); err != nil {
+		return nil, err
+	}
+	p, err := s.snapshotPolicyFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+	if err := s.repo.UpdateProxmoxSnapshotPolicy(ctx, *p); err != nil {
+		return nil, err
+	}
+	return s.GetSnapshotPolicy(ctx, id)
+}
+
+func (s *Service) DeleteSnapshotPolicy(ctx context.Context, id string) error {
+	if _, err := s.GetSnapshotPolicy(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteProxmoxSnapshotPolicy(ctx, id)
+}
+
+func (s *Service) ListSnapshotPolicyRuns(ctx context.Context, id string, limit int) ([]models.ProxmoxSnapshotPolicyRun, error) {
+	if _, err := s.GetSnapshotPolicy(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return s.repo.ListProxmoxSnapshotRuns(ctx, id, limit)
+}
+
+// snapshotPolicyFromRequest validates a request and normalizes it into a
+// policy with its next run computed.
+func (s *Service) snapshotPolicyFromRequest(ctx context.Context, req models.ProxmoxSnapshotPolicyRequest) (*models.ProxmoxSnapshotPolicy, error) {
+	p := &models.ProxmoxSnapshotPolicy{
+		Name:        strings.TrimSpace(req.Name),
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		Schedule:    strings.TrimSpace(req.Schedule),
+		KeepLast:    req.KeepLast,
+		KeepDaily:   req.KeepDaily,
+		KeepWeekly:  req.KeepWeekly,
+		KeepMonthly: req.KeepMonthly,
+		VMState:     req.VMState,
+	}
+	if p.Name == "" {
+		return nil, apperr.Validation("le nom de la politique est requis")
+	}
+	if _, err := cron.ParseStandard(p.Schedule); err != nil {
+		return nil, apperr.Validation("expression cron invalide : " + err.Error())
+	}
+	if p.KeepLast < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 || p.KeepMonthly < 0 {
+		return nil, apperr.Validation("les valeurs de rétention doivent être positives")
+	}
+	if p.KeepLast+p.KeepDaily+p.KeepWeekly+p.KeepMonthly == 0 {
+		return nil, apperr.Validation("la rétention doit conserver au moins un snapshot")
+	}
+	seen := make(map[string]bool, len(req.GuestIDs))
+	for _, id := range req.GuestIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if g, err := s.repo.GetProxmoxGuestByID(ctx, id); err != nil || g == nil {
+			return nil, apperr.Validation("VM/LXC introuvable : " + id)
+		}
+		seen[id] = true
+		p.GuestIDs = append(p.GuestIDs, id)
+	}
+	if len(p.GuestIDs) == 0 {
+		return nil, apperr.Validation("au moins une VM/LXC est requise")
+	}
+	if p.Enabled {
+		p.NextRunAt = nextSnapshotRun(p.Schedule, time.Now())
+	}
+	return p, nil
+}
+
+// RunSnapshotPolicyNow launches one run of a policy on the supplied
+// (long-lived) ctx, whatever its schedule. The next scheduled run is
+// recomputed afterwards as usual.
+func (s *Service) RunSnapshotPolicyNow(reqCtx, runCtx context.Context, id string) error {
+	p, err := s.GetSnapshotPolicy(reqCtx, id)
+	if err != nil {
+		return err
+	}
+	if !s.claimSnapshotPolicy(p.ID) {
+		return apperr.Conflict("cette politique est déjà en cours d'exécution")
+	}
+	go func() {
+		defer safego.Recover(runCtx, "proxmox.RunSnapshotPolicyNow")
+		defer s.releaseSnapshotPolicy(p.ID)
+		s.runSnapshotPolicy(runCtx, *p)
+	}()
+	return nil
+}
+
+// RunDueSnapshotPolicies runs every enabled policy whose next run is due.
+// Called on a short tick; policies run one after the other.
+func (s *Service) RunDueSnapshotPolicies(ctx context.Context) {
+	due, err := s.repo.ListDueProxmoxSnapshotPolicies(ctx, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "proxmox snapshots: failed to list due policies", slog.Any("err", err))
+		return
+	}
+	for _, p := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		if !s.claimSnapshotPolicy(p.ID) {
+			continue
+		}
+		s.runSnapshotPolicy(ctx, p)
+		s.releaseSnapshotPolicy(p.ID)
+	}
+}
+
+func (s *Service) claimSnapshotPolicy(id string) bool {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+	if s.snapRunning == nil {
+		s.snapRunning = make(map[string]bool)
+	}
+	if s.snapRunning[id] {
+		return false
+	}
+	s.snapRunning[id] = true
+	return true
+}
+
+func (s *Service) releaseSnapshotPolicy(id string) {
+	s.snapMu.Lock()
+	defer s.snapMu.Unlock()
+	delete(s.snapRunning, id)
+}
+
+// runSnapshotPolicy snapshots then prunes every guest of the policy, records
+// one run row per guest and the overall status: success, partial (some
+// guests failed) or failed (all did).
+func (s *Service) runSnapshotPolicy(ctx context.Context, p models.ProxmoxSnapshotPolicy) {
+	startedAt := time.Now()
+	failed := 0
+	var lastErr string
+	for _, guestID := range p.GuestIDs {
+		runID, rerr := s.repo.CreateProxmoxSnapshotRun(ctx, p.ID, guestID)
+		name, pruned, err := s.runSnapshotPolicyGuest(ctx, p, guestID, startedAt)
+		status, msg := "success", ""
+		if err != nil {
+			status, msg = "failed", err.Error()
+			failed++
+			lastErr = msg
+			slog.WarnContext(ctx, "proxmox snapshots: policy run failed for guest",
+				slog.String("policy", p.Name), slog.String("guest_id", guestID), slog.Any("err", err))
+		}
+		if rerr == nil {
+			if ferr := s.repo.FinishProxmoxSnapshotRun(ctx, runID, status, name, pruned, msg); ferr != nil {
+				slog.WarnContext(ctx, "proxmox snapshots: failed to record run", slog.Int64("run_id", runID), slog.Any("err", ferr))
+			}
+		}
+	}
+
+	status := "success"
+	switch {
+	case failed > 0 && failed == len(p.GuestIDs):
+		status = "failed"
+	case failed > 0:
+		status = "partial"
+	}
+	if err := s.repo.FinishProxmoxSnapshotPolicy(ctx, p.ID, startedAt, status, lastErr, nextSnapshotRun(p.Schedule, time.Now())); err != nil {
+		slog.ErrorContext(ctx, "proxmox snapshots: failed to record policy run", slog.String("policy_id", p.ID), slog.Any("err", err))
+	}
+}
+
+// runSnapshotPolicyGuest takes the policy snapshot of one guest then deletes
+// the policy's snapshots the retention no longer keeps. The new snapshot
+// must exist before anything is pruned.
+func (s *Service) runSnapshotPolicyGuest(ctx context.Context, p models.ProxmoxSnapshotPolicy, guestID string, now time.Time) (string, int, error) {
+	guest, client, err := s.guestClient(ctx, guestID)
+	if err != nil {
+		return "", 0, err
+	}
+	name := policySnapshotPrefix(p.ID) + now.Format("20060102_1504")
+	vmstate := p.VMState && guest.Status == "running"
+	upid, err := client.CreateGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, name, "Snapshot automatique — politique "+p.Name, vmstate)
+	if err == nil {
+		err = waitProxmoxTask(ctx, client, guest.NodeName, upid)
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("création du snapshot : %w", err)
+	}
+
+	raw, err := client.ListGuestSnapshots(guest.NodeName, guest.VMID, guest.GuestType)
+	if err != nil {
+		return name, 0, fmt.Errorf("liste des snapshots : %w", err)
+	}
+	own := make([]models.ProxmoxSnapshot, 0, len(raw))
+	for _, r := range raw {
+		if strings.HasPrefix(r.Name, policySnapshotPrefix(p.ID)) {
+			own = append(own, toSnapshot(r))
+		}
+	}
+	pruned := 0
+	for _, old := range snapshotsToPrune(own, p, time.Local) {
+		upid, err := client.DeleteGuestSnapshot(guest.NodeName, guest.VMID, guest.GuestType, old)
+		if err == nil {
+			err = waitProxmoxTask(ctx, client, guest.NodeName, upid)
+		}
+		if err != nil {
+			return name, pruned, fmt.Errorf("suppression de %s : %w", old, err)
+		}
+		pruned++
+	}
+	return name, pruned, nil
+}
+
+// policySnapshotPrefix is "auto_<first 8 hex of the policy id>_", which keeps
+// a full name ("auto_1a2b3c4d_20261018_0300") within PVE's 40 characters.
+func policySnapshotPrefix(policyID string) string {
+	tag := strings.ReplaceAll(policyID, "-", "")
+	if len(tag) > 8 {
+		tag = tag[:8]
+	}
+	return autoSnapshotPrefix + tag + "_"
+}
+
+// snapshotsToPrune applies the policy's keep rules to the snapshots it
+// created and returns the names to delete, oldest first. Like restic's
+// forget: keep_last keeps the N newest; keep_daily/weekly/monthly keep the
+// newest snapshot of each of the last N days/ISO weeks/months that have one.
+// A snapshot survives when any rule keeps it.
+func snapshotsToPrune(snaps []models.ProxmoxSnapshot, p models.ProxmoxSnapshotPolicy, loc *time.Location) []string {
+	sorted := append([]models.ProxmoxSnapshot(nil), snaps...)
+	sortSnapshotsNewestFirst(sorted)
+
+	keep := make(map[string]bool, len(sorted))
+	for i := 0; i < len(sorted) && i < p.KeepLast; i++ {
+		keep[sorted[i].Name] = true
+	}
+	keepPerPeriod := func(n int, period func(time.Time) string) {
+		if n <= 0 {
+			return
+		}
+		seen := make(map[string]bool, n)
+		for _, s := range sorted {
+			if s.SnapTime == nil {
+				continue
+			}
+			key := period(s.SnapTime.In(loc))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keep[s.Name] = true
+			if len(seen) >= n {
+				return
+			}
+		}
+	}
+	keepPerPeriod(p.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+	keepPerPeriod(p.KeepWeekly, func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	})
+	keepPerPeriod(p.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+
+	var prune []string
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if !keep[sorted[i].Name] {
+			prune = append(prune, sorted[i].Name)
+		}
+	}
+	return prune
+}
+
+// nextSnapshotRun returns the schedule's next activation after from, or nil
+// for an unparsable expression (the policy then never runs by itself).
+func nextSnapshotRun(schedule string, from time.Time) *time.Time {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil
+	}
+	next := sched.Next(from)
+	return &next
+}
+
+// waitProxmoxTask polls a PVE task until it stops. "WARNINGS: n" exit
+// statuses count as success, as in the PVE UI.
+func waitProxmoxTask(ctx context.Context, client *proxmoxclient.Client, node, upid string) error {
+	deadline := time.Now().Add(snapshotTaskTimeout)
+	for {
+		st, err := client.GetTaskStatus(node, upid)
+		if err == nil && st.Status == "stopped" {
+			if st.ExitStatus == "OK" || strings.HasPrefix(st.ExitStatus, "WARNINGS") {
+				return nil
+			}
+			return fmt.Errorf("tâche PVE en échec : %s", st.ExitStatus)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("tâche PVE toujours en cours après %s", snapshotTaskTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(snapshotTaskPollInterval):
+		}
+	}
+}
+
+func toSnapshot(r proxmoxclient.PVESnapshot) models.ProxmoxSnapshot {
+	snap := models.ProxmoxSnapshot{
+		Name:        r.Name,
+		Description: strings.TrimSpace(r.Description),
+		Parent:      r.Parent,
+		VMState:     r.VMState != 0,
+		Automatic:   strings.HasPrefix(r.Name, autoSnapshotPrefix),
+	}
+	if r.SnapTime > 0 {
+		t := time.Unix(int64(r.SnapTime), 0)
+		snap.SnapTime = &t
+	}
+	return snap
+}
+
+func sortSnapshotsNewestFirst(snaps []models.ProxmoxSnapshot) {
+	sort.SliceStable(snaps, func(i, j int) bool {
+		ti, tj := snaps[i].SnapTime, snaps[j].SnapTime
+		switch {
+		case ti == nil:
+			return false
+		case tj == nil:
+			return true
+		default:
+			return ti.After(*tj)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package proxmox
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+func snapAt(name string, t time.Time) models.ProxmoxSnapshot {
+	return models.ProxmoxSnapshot{Name: name, SnapTime: &t}
+}
+
+func TestSnapshotsToPrune_DailyAndWeekly(t *testing.T) {
+	// One snapshot a day at 03:00 for 21 days, the newest on Sun 2026-10-18.
+	var snaps []models.ProxmoxSnapshot
+	last := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 21; i++ {
+		d := last.AddDate(0, 0, -i)
+		snaps = append(snaps, snapAt(d.Format("d0102"), d))
+	}
+	p := models.ProxmoxSnapshotPolicy{KeepDaily: 7, KeepWeekly: 3}
+	prune := snapshotsToPrune(snaps, p, time.UTC)
+
+	// Kept: the 7 newest days (10-12..10-18, ISO week 42) plus the newest
+	// snapshot of weeks 41 and 40 (Sundays 10-11 and 10-04).
+	if len(prune) != 12 {
+		t.Fatalf("pruned %d snapshots, want 12: %v", len(prune), prune)
+	}
+	if prune[0] != "d0928" || prune[len(prune)-1] != "d1010" {
+		t.Errorf("want oldest first from d0928 to d1010, got %v", prune)
+	}
+	for _, kept := range []string{"d1018", "d1012", "d1011", "d1004"} {
+		for _, name := range prune {
+			if name == kept {
+				t.Errorf("%s should be kept", kept)
+			}
+		}
+	}
+}
+
+func TestSnapshotsToPrune_KeepLastOnly(t *testing.T) {
+	base := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	snaps := []models.ProxmoxSnapshot{
+		snapAt("b", base.Add(-1*time.Hour)),
+		snapAt("a", base.Add(-2*time.Hour)),
+		snapAt("c", base),
+	}
+	got := snapshotsToPrune(snaps, models.ProxmoxSnapshotPolicy{KeepLast: 2}, time.UTC)
+	if !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("got %v, want [a]", got)
+	}
+}
+
+func TestPolicySnapshotPrefix_FitsPVELimit(t *testing.T) {
+	prefix := policySnapshotPrefix("1a2b3c4d-0000-4000-8000-000000000000")
+	if prefix != "auto_1a2b3c4d_" {
+		t.Fatalf("prefix = %q", prefix)
+	}
+	name := prefix + time.Now().Format("20060102_1504")
+	if !snapshotNameRe.MatchString(name) {
+		t.Errorf("policy snapshot name %q rejected by PVE name rule", name)
+	}
+}
+
+func TestCreateGuestSnapshot_RequiresConfirm(t *testing.T) {
+	_, _, err := newSvc(&fakeRepo{}).CreateGuestSnapshot(context.Background(), "guest-1", models.ProxmoxSnapshotCreate{Name: "pre_upgrade"})
+	if status(err) != 400 {
+		t.Fatalf("unconfirmed snapshot should be 400, got %v", err)
+	}
+}
+
+func TestCreateGuestSnapshot_RejectsReservedPrefix(t *testing.T) {
+	_, _, err := newSvc(&fakeRepo{}).CreateGuestSnapshot(context.Background(), "guest-1",
+		models.ProxmoxSnapshotCreate{Name: "auto_manual", Confirm: true})
+	if status(err) != 400 {
+		t.Fatalf("auto_ prefix should be reserved, got %v", err)
+	}
+}
+
+func TestCreateSnapshotPolicy_Validation(t *testing.T) {
+	svc := newSvc(&fakeRepo{})
+	cases := map[string]models.ProxmoxSnapshotPolicyRequest{
+		"bad cron":      {Name: "p", Schedule: "every day", KeepDaily: 7, GuestIDs: []string{"g"}},
+		"no retention":  {Name: "p", Schedule: "0 3 * * *", GuestIDs: []string{"g"}},
+		"unknown guest": {Name: "p", Schedule: "0 3 * * *", KeepDaily: 7, GuestIDs: []string{"g"}},
+	}
+	for name, req := range cases {
+		if _, err := svc.CreateSnapshotPolicy(context.Background(), req); status(err) != 400 {
+			t.Errorf("%s: want 400, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+)
+
+// storageContentTypes are the PVE content types the browser can filter on.
+var storageContentTypes = map[string]bool{
+	"iso": true, "vztmpl": true, "backup": true, "images": true, "rootdir": true, "snippets": true,
+}
+
+// downloadFileSuffixes are the extensions PVE accepts per download-url
+// content type; checking them here gives a French error instead of PVE's.
+var downloadFileSuffixes = map[string][]string{
+	"iso":    {".iso", ".img"},
+	"vztmpl": {".tar.gz", ".tar.xz", ".tar.zst", ".tgz"},
+}
+
+var (
+	storageFilenameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]{0,254}$`)
+	checksumRe        = regexp.MustCompile(`^[0-9a-fA-F]{32,128}$`)
+	checksumAlgos     = map[string]bool{"md5": true, "sha1": true, "sha224": true, "sha256": true, "sha384": true, "sha512": true}
+)
+
+// storageClient resolves a proxmox_storages row to (storage, PVE client).
+func (s *Service) storageClient(ctx context.Context, storageID string) (*models.ProxmoxStorage, *proxmoxclient.Client, error) {
+	st, err := s.repo.GetProxmoxStorageByID(ctx, storageID)
+	if err != nil {
+		return nil, nil, apperr.Internal(err)
+	}
+	if st == nil {
+		return nil, nil, apperr.NotFound("stockage introuvable")
+	}
+	secret, conn, err := s.resolveSecret(ctx, st.ConnectionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st, proxmoxclient.New(conn.APIURL, conn.TokenID, secret, conn.InsecureSkipVerify), nil
+}
+
+// ListStorageContent returns a storage's volumes live from PVE, optionally
+// filtered on one content type or on orphaned disk images only. Disk images
+// are checked against the cluster's live VMID list.
+func (s *Service) ListStorageContent(ctx context.Context, storageID, content string, orphanedOnly bool) ([]models.ProxmoxStorageVolume, error) {
+	if content != "" && !storageContentTypes[content] {
+		return nil, apperr.Validation("type de contenu invalide")
+	}
+	st, client, err := s.storageClient(ctx, storageID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := client.ListStorageContent(st.NodeName, st.StorageName, content)
+	if err != nil {
+		return nil, apperr.BadGateway(err.Error())
+	}
+	var vmids map[int]bool
+	if hasDiskImages(raw) {
+		if vmids, err = client.ListClusterVMIDs(); err != nil {
+			return nil, apperr.BadGateway(err.Error())
+		}
+	}
+	guests, _ := s.repo.ListProxmoxGuests(ctx, st.ConnectionID, "", "")
+	names := make(map[int]string, len(guests))
+	for _, g := range guests {
+		names[g.VMID] = g.Name
+	}
+
+	out := toStorageVolumes(raw, vmids, names)
+	if orphanedOnly {
+		kept := out[:0]
+		for _, v := range out {
+			if v.Orphaned {
+				kept = append(kept, v)
+			}
+		}
+		out = kept
+	}
+	return out, nil
+}
+
+// DownloadToStorage makes the storage's node fetch an ISO or container
+// template by URL. Returns the file name and the PVE task UPID.
+func (s *Service) DownloadToStorage(ctx context.Context, storageID string, req models.ProxmoxStorageDownloadRequest) (string, string, error) {
+	if !req.Confirm {
+		return "", "", apperr.Validation("confirmation requise")
+	}
+	opts, err := downloadOptions(req)
+	if err != nil {
+		return "", "", err
+	}
+	st, client, err := s.storageClient(ctx, storageID)
+	if err != nil {
+		return "", "", err
+	}
+	// Rows polled before the content column existed have it empty: PVE
+	// itself rejects a storage without the content type then.
+	if st.Content != "" && !storageAccepts(st.Content, opts.Content) {
+		return "", "", apperr.Validation(fmt.Sprintf("le stockage %s n'accepte pas le contenu %s", st.StorageName, opts.Content))
+	}
+	upid, err := client.DownloadURLToStorage(st.NodeName, st.StorageName, opts)
+	if err != nil {
+		return "", "", apperr.BadGateway(err.Error())
+	}
+	return opts.Filename, upid, nil
+}
+
+// DeleteStorageVolume removes an ISO, template, backup or orphaned disk image.
+// The volume is re-read live first: a protected backup or a disk image still
+// owned by an existing guest is refused.
+func (s *Service) DeleteStorageVolume(ctx context.Context, storageID string, req models.ProxmoxStorageVolumeDelete) (string, error) {
+	if !req.Confirm {
+		return "", apperr.Validation("confirmation requise")
+	}
+	st, client, err := s.storageClient(ctx, storageID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(req.VolID, st.StorageName+":") {
+		return "", apperr.Validation("volume hors de ce stockage")
+	}
+	raw, err := client.ListStorageContent(st.NodeName, st.StorageName, "")
+	if err != nil {
+		return "", apperr.BadGateway(err.Error())
+	}
+	var vol *proxmoxclient.PVEStorageVolume
+	for i := range raw {
+		if raw[i].VolID == req.VolID {
+			vol = &raw[i]
+			break
+		}
+	}
+	if vol == nil {
+		return "", apperr.NotFound("volume introuvable")
+	}
+	var vmids map[int]bool
+	if isDiskImage(vol.Content) {
+		if vmids, err = client.ListClusterVMIDs(); err != nil {
+			return "", apperr.BadGateway(err.Error())
+		}
+	}
+	if err := checkVolumeDeletable(*vol, vmids); err != nil {
+		return "", err
+	}
+	upid, err := client.DeleteStorageVolume(st.NodeName, st.StorageName, req.VolID)
+	if err != nil {
+		return "", apperr.BadGateway(err.Error())
+	}
+	return upid, nil
+}
+
+// downloadOptions validates a download request and fills in the file name.
+func downloadOptions(req models.ProxmoxStorageDownloadRequest) (proxmoxclient.DownloadURLOptions, error) {
+	var opts proxmoxclient.DownloadURLOptions
+	suffixes, ok := downloadFileSuffixes[req.Content]
+	if !ok {
+		return opts, apperr.Validation("type de contenu invalide (iso ou vztmpl)")
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return opts, apperr.Validation("URL invalide (http ou https)")
+	}
+	filename := strings.TrimSpace(req.Filename)
+	if filename == "" {
+		filename = path.Base(u.Path)
+	}
+	if !storageFilenameRe.MatchString(filename) {
+		return opts, apperr.Validation("nom de fichier invalide")
+	}
+	if !hasAnySuffix(strings.ToLower(filename), suffixes) {
+		return opts, apperr.Validation(fmt.Sprintf("le nom de fichier doit se terminer par %s", strings.Join(suffixes, ", ")))
+	}
+	checksum := strings.TrimSpace(req.Checksum)
+	if checksum != "" {
+		if !checksumAlgos[req.ChecksumAlgorithm] {
+			return opts, apperr.Validation("algorithme de somme de contrôle invalide")
+		}
+		if !checksumRe.MatchString(checksum) {
+			return opts, apperr.Validation("somme de contrôle invalide (hexadécimal attendu)")
+		}
+	}
+	return proxmoxclient.DownloadURLOptions{
+		URL:                u.String(),
+		Filename:           filename,
+		Content:            req.Content,
+		Checksum:           strings.ToLower(checksum),
+		ChecksumAlgorithm:  req.ChecksumAlgorithm,
+		VerifyCertificates: !req.SkipCertVerify,
+	}, nil
+}
+
+// checkVolumeDeletable enforces what the UI may remove: ISOs, templates,
+// unprotected backups, and disk images whose VMID no longer exists. Deleting
+// a disk attached to a guest goes through the guest, not the storage browser.
+func checkVolumeDeletable(vol proxmoxclient.PVEStorageVolume, vmids map[int]bool) error {
+	switch vol.Content {
+	case "iso", "vztmpl":
+		return nil
+	case "backup":
+		if vol.Protected != 0 {
+			return apperr.Conflict("sauvegarde protégée : retirez la protection dans Proxmox avant de la supprimer")
+		}
+		return nil
+	case "images", "rootdir":
+		if vol.VMID == 0 || vmids[int(vol.VMID)] {
+			return apperr.Conflict(fmt.Sprintf("le disque appartient à la VM %d, qui existe toujours", vol.VMID))
+		}
+		return nil
+	default:
+		return apperr.Validation("ce type de contenu ne peut pas être supprimé ici")
+	}
+}
+
+// toStorageVolumes converts PVE volumes, flags orphaned disk images (vmids is
+// nil when the list holds none) and sorts by content type, newest first.
+func toStorageVolumes(raw []proxmoxclient.PVEStorageVolume, vmids map[int]bool, names map[int]string) []models.ProxmoxStorageVolume {
+	out := make([]models.ProxmoxStorageVolume, 0, len(raw))
+	for _, r := range raw {
+		v := models.ProxmoxStorageVolume{
+			VolID: r.VolID, Content: r.Content, Format: r.Format, Size: r.Size, Used: r.Used,
+			VMID: int(r.VMID), GuestName: names[int(r.VMID)], Notes: r.Notes, Protected: r.Protected != 0,
+		}
+		if r.CTime > 0 {
+			t := time.Unix(int64(r.CTime), 0)
+			v.CreatedAt = &t
+		}
+		v.Orphaned = isDiskImage(r.Content) && v.VMID > 0 && vmids != nil && !vmids[v.VMID]
+		out = append(out, v)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Content != out[j].Content {
+			return out[i].Content < out[j].Content
+		}
+		ti, tj := out[i].CreatedAt, out[j].CreatedAt
+		if ti != nil && tj != nil && !ti.Equal(*tj) {
+			return ti.After(*tj)
+		}
+		return out[i].VolID < out[j].VolID
+	})
+	return out
+}
+
+func isDiskImage(content string) bool {
+	return content == "images" || content == "rootdir"
+}
+
+func hasDiskImages(raw []proxmoxclient.PVEStorageVolume) bool {
+	for _, r := range raw {
+		if isDiskImage(r.Content) {
+			return true
+		}
+	}
+	return false
+}
+
+// storageAccepts reports whether a PVE comma-separated content list holds c.
+func storageAccepts(content, c string) bool {
+	for _, part := range strings.Split(content, ",") {
+		if strings.TrimSpace(part) == c {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suf := range suffixes {
+		if strings.HasSuffix(s, suf) {
+			return true
+		}
+	}
+	return false
+}
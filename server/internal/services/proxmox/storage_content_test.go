@@ -0,0 +1,89 @@
+package proxmox
+
+import (
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/proxmoxclient"
+)
+
+func TestDownloadOptions(t *testing.T) {
+	opts, err := downloadOptions(models.ProxmoxStorageDownloadRequest{
+		URL: "https://cdimage.debian.org/debian-cd/12.7.0/amd64/iso-cd/debian-12.7.0-amd64-netinst.iso", Content: "iso",
+	})
+	if err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+	if opts.Filename != "debian-12.7.0-amd64-netinst.iso" || !opts.VerifyCertificates {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	for name, req := range map[string]models.ProxmoxStorageDownloadRequest{
+		"bad content":   {URL: "https://x.org/a.iso", Content: "backup"},
+		"ftp":           {URL: "ftp://x.org/a.iso", Content: "iso"},
+		"no host":       {URL: "https:///a.iso", Content: "iso"},
+		"bad suffix":    {URL: "https://x.org/a.tar.gz", Content: "iso"},
+		"path in name":  {URL: "https://x.org/a.iso", Filename: "../a.iso", Content: "iso"},
+		"bad algorithm": {URL: "https://x.org/a.iso", Content: "iso", Checksum: "abcdef0123456789abcdef0123456789", ChecksumAlgorithm: "crc32"},
+		"bad checksum":  {URL: "https://x.org/a.iso", Content: "iso", Checksum: "not-hex", ChecksumAlgorithm: "sha256"},
+	} {
+		if _, err := downloadOptions(req); err == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	if _, err := downloadOptions(models.ProxmoxStorageDownloadRequest{
+		URL: "https://x.org/dl?id=1", Filename: "debian-12-standard_12.7-1_amd64.tar.zst", Content: "vztmpl",
+	}); err != nil {
+		t.Errorf("template with explicit filename: %v", err)
+	}
+}
+
+func TestCheckVolumeDeletable(t *testing.T) {
+	vmids := map[int]bool{100: true}
+	cases := []struct {
+		vol proxmoxclient.PVEStorageVolume
+		ok  bool
+	}{
+		{proxmoxclient.PVEStorageVolume{Content: "iso"}, true},
+		{proxmoxclient.PVEStorageVolume{Content: "backup", VMID: 100}, true},
+		{proxmoxclient.PVEStorageVolume{Content: "backup", VMID: 100, Protected: 1}, false},
+		{proxmoxclient.PVEStorageVolume{Content: "images", VMID: 100}, false},
+		{proxmoxclient.PVEStorageVolume{Content: "images", VMID: 250}, true},
+		{proxmoxclient.PVEStorageVolume{Content: "rootdir"}, false},
+		{proxmoxclient.PVEStorageVolume{Content: "snippets"}, false},
+	}
+	for _, c := range cases {
+		if err := checkVolumeDeletable(c.vol, vmids); (err == nil) != c.ok {
+			t.Errorf("%s vmid=%d protected=%d: err = %v, want ok=%v", c.vol.Content, c.vol.VMID, c.vol.Protected, err, c.ok)
+		}
+	}
+}
+
+func TestToStorageVolumes_OrphansAndOrder(t *testing.T) {
+	raw := []proxmoxclient.PVEStorageVolume{
+		{VolID: "local:backup/old", Content: "backup", VMID: 100, CTime: 1000},
+		{VolID: "local:backup/new", Content: "backup", VMID: 100, CTime: 2000},
+		{VolID: "local:100/vm-100-disk-0.qcow2", Content: "images", VMID: 100},
+		{VolID: "local:250/vm-250-disk-0.qcow2", Content: "images", VMID: 250},
+	}
+	out := toStorageVolumes(raw, map[int]bool{100: true}, map[int]string{100: "web"})
+	if len(out) != 4 {
+		t.Fatalf("got %d volumes", len(out))
+	}
+	if out[0].VolID != "local:backup/new" || out[1].VolID != "local:backup/old" {
+		t.Errorf("backups not newest first: %s, %s", out[0].VolID, out[1].VolID)
+	}
+	if out[0].GuestName != "web" || out[0].CreatedAt == nil {
+		t.Errorf("backup = %+v", out[0])
+	}
+	if out[2].Orphaned || !out[3].Orphaned {
+		t.Errorf("orphan flags = %v, %v", out[2].Orphaned, out[3].Orphaned)
+	}
+	// Without the VMID list nothing may be reported as orphaned.
+	for _, v := range toStorageVolumes(raw, nil, nil) {
+		if v.Orphaned {
+			t.Errorf("%s flagged orphaned without a VMID list", v.VolID)
+		}
+	}
+}
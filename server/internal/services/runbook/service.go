@@ -17,6 +17,7 @@ import (
 	"github.com/serversupervisor/server/internal/apperr"
 	"github.com/serversupervisor/server/internal/dispatch"
 	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/safego"
 )
 
 // Repository is the data-access port. *database.DB satisfies it structurally.
@@ -38,6 +39,8 @@ type Repository interface {
 	LinkCommandToRunbookExecution(ctx context.Context, commandID, executionID string) error
 
 	GetRemoteCommandByID(ctx context.Context, id string) (*models.RemoteCommand, error)
+	CreateServerRemoteCommand(ctx context.Context, hostID, module, action, target, triggeredBy string) (string, error)
+	UpdateRemoteCommandStatus(ctx context.Context, id, status, output string) error
 }
 
 // Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
@@ -45,15 +48,30 @@ type Dispatcher interface {
 	Create(ctx context.Context, req dispatch.Request) (*dispatch.Result, error)
 }
 
+// Snapshotter is the Proxmox port behind the "proxmox / snapshot" step:
+// it snapshots the guest linked to a host and returns the snapshot name.
+// *proxmox.Service satisfies it.
+type Snapshotter interface {
+	SnapshotLinkedGuest(ctx context.Context, hostID, description string) (string, error)
+}
+
 type Service struct {
-	repo       Repository
-	dispatcher Dispatcher
+	repo        Repository
+	dispatcher  Dispatcher
+	snapshotter Snapshotter
 }
 
 func NewService(repo Repository, dispatcher Dispatcher) *Service {
 	return &Service{repo: repo, dispatcher: dispatcher}
 }
 
+// SetSnapshotter wires the Proxmox snapshot port after construction — the
+// proxmox service is built after the runbook one. Without it, a "proxmox"
+// step fails at run time.
+func (s *Service) SetSnapshotter(snapshotter Snapshotter) {
+	s.snapshotter = snapshotter
+}
+
 // ===== definitions =====
 
 func (s *Service) List(ctx context.Context) ([]models.Runbook, error) {
@@ -219,6 +237,10 @@ func (s *Service) NotifyComplete(ctx context.Context, commandID, status string)
 }
 
 func (s *Service) dispatchStep(ctx context.Context, executionID string, step models.RunbookStep) {
+	if step.Module == "proxmox" {
+		s.runServerStep(ctx, executionID, step)
+		return
+	}
 	result, err := s.dispatcher.Create(ctx, dispatch.Request{
 		HostID:      step.HostID,
 		Module:      step.Module,
@@ -237,6 +259,41 @@ func (s *Service) dispatchStep(ctx context.Context, executionID string, step mod
 	}
 }
 
+// runServerStep executes a step the server performs itself (today only the
+// Proxmox snapshot taken before a risky change). It still gets a
+// remote_commands row linked to the execution, so the step history and
+// NotifyComplete work exactly as for agent steps.
+func (s *Service) runServerStep(ctx context.Context, executionID string, step models.RunbookStep) {
+	commandID, err := s.repo.CreateServerRemoteCommand(ctx, step.HostID, step.Module, step.Action, step.Target, "runbook")
+	if err != nil {
+		slog.ErrorContext(ctx, "runbook: failed to record server step", "execution_id", executionID, "position", step.Position, "err", err)
+		_ = s.repo.FinishRunbookExecution(ctx, executionID, "failed")
+		return
+	}
+	if err := s.repo.LinkCommandToRunbookExecution(ctx, commandID, executionID); err != nil {
+		slog.WarnContext(ctx, "runbook: failed to link command to execution", "command_id", commandID, "execution_id", executionID, "err", err)
+	}
+
+	// The snapshot can take minutes: run it detached from the caller (often
+	// an HTTP request) and report back through NotifyComplete.
+	bg := context.WithoutCancel(ctx)
+	go func() {
+		defer safego.Recover(bg, "runbook.runServerStep")
+		status, output := "completed", ""
+		if s.snapshotter == nil {
+			status, output = "failed", "intégration Proxmox indisponible"
+		} else if name, err := s.snapshotter.SnapshotLinkedGuest(bg, step.HostID, "Snapshot avant runbook (exécution "+executionID+")"); err != nil {
+			status, output = "failed", "Snapshot impossible : "+err.Error()
+		} else {
+			output = "Snapshot " + name + " créé"
+		}
+		if err := s.repo.UpdateRemoteCommandStatus(bg, commandID, status, output); err != nil {
+			slog.WarnContext(bg, "runbook: failed to record server step outcome", "command_id", commandID, "err", err)
+		}
+		s.NotifyComplete(bg, commandID, status)
+	}()
+}
+
 // ===== validation =====
 
 // commandModuleActions mirrors alertrule's whitelist for the same reason:
@@ -250,6 +307,9 @@ var commandModuleActions = map[string][]string{
 	"systemd":   {"status", "start", "stop", "restart", "list"},
 	"processes": {"list"},
 	"custom":    {"run"},
+	// Executed by the server, not an agent: snapshots the Proxmox guest
+	// linked to the step's host (see runServerStep).
+	"proxmox": {"snapshot"},
 }
 
 var commandModuleRequiresTarget = map[string]bool{
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/serversupervisor/server/internal/dispatch"
 	"github.com/serversupervisor/server/internal/models"
@@ -15,6 +16,10 @@ type fakeRepo struct {
 	executions map[string]*models.RunbookExecution
 	commands   map[string]*models.RemoteCommand
 	validHosts map[string]bool
+
+	// finished, when set, receives every terminal execution status — lets
+	// tests wait on steps the service runs in a goroutine.
+	finished chan string
 }
 
 func newFakeRepo() *fakeRepo {
@@ -147,6 +152,9 @@ func (f *fakeRepo) FinishRunbookExecution(_ context.Context, executionID, status
 		return sql.ErrNoRows
 	}
 	exec.Status = status
+	if f.finished != nil {
+		f.finished <- status
+	}
 	return nil
 }
 
@@ -167,6 +175,19 @@ func (f *fakeRepo) GetRemoteCommandByID(_ context.Context, id string) (*models.R
 	return &cp, nil
 }
 
+func (f *fakeRepo) CreateServerRemoteCommand(_ context.Context, hostID, module, action, target, _ string) (string, error) {
+	id := fmt.Sprintf("srv-%d", len(f.commands)+1)
+	f.commands[id] = &models.RemoteCommand{ID: id, HostID: hostID, Module: module, Action: action, Target: target, Status: "running"}
+	return id, nil
+}
+
+func (f *fakeRepo) UpdateRemoteCommandStatus(_ context.Context, id, status, output string) error {
+	if cmd, ok := f.commands[id]; ok {
+		cmd.Status, cmd.Output = status, output
+	}
+	return nil
+}
+
 // fakeDispatcher records every dispatched request and registers a fake
 // command into the shared fakeRepo, so LinkCommandToRunbookExecution and
 // GetRemoteCommandByID see it exactly like the real dispatcher+DB would.
@@ -398,3 +419,63 @@ func (d *fakeDispatcher) requestsLastID(t *testing.T) string {
 	}
 	return fmt.Sprintf("cmd-%d", len(d.requests))
 }
+
+type fakeSnapshotter struct {
+	hostID string
+	err    error
+}
+
+func (f *fakeSnapshotter) SnapshotLinkedGuest(_ context.Context, hostID, _ string) (string, error) {
+	f.hostID = hostID
+	return "runbook_20261018_120000", f.err
+}
+
+func runSnapshotRunbook(t *testing.T, snap *fakeSnapshotter) (*fakeRepo, string) {
+	t.Helper()
+	repo := newFakeRepo()
+	repo.finished = make(chan string, 1)
+	svc := NewService(repo, &fakeDispatcher{repo: repo})
+	svc.SetSnapshotter(snap)
+	rb, err := svc.Create(context.Background(), models.RunbookCreate{
+		Name:  "Upgrade with safety net",
+		Steps: []models.RunbookStepCreate{{HostID: "host-1", Module: "proxmox", Action: "snapshot"}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := svc.Run(context.Background(), rb.ID, "alice"); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	select {
+	case status := <-repo.finished:
+		return repo, status
+	case <-time.After(5 * time.Second):
+		t.Fatal("snapshot step never completed")
+		return nil, ""
+	}
+}
+
+func TestProxmoxSnapshotStep_RunsOnServer(t *testing.T) {
+	snap := &fakeSnapshotter{}
+	repo, status := runSnapshotRunbook(t, snap)
+	if status != "completed" {
+		t.Fatalf("execution status = %q, want completed", status)
+	}
+	if snap.hostID != "host-1" {
+		t.Errorf("snapshotted host %q, want host-1", snap.hostID)
+	}
+	cmd := repo.commands["srv-1"]
+	if cmd == nil || cmd.Status != "completed" || cmd.RunbookExecutionID == nil {
+		t.Errorf("server step not recorded as a completed, linked command: %+v", cmd)
+	}
+}
+
+func TestProxmoxSnapshotStep_FailureStopsRunbook(t *testing.T) {
+	repo, status := runSnapshotRunbook(t, &fakeSnapshotter{err: fmt.Errorf("no linked guest")})
+	if status != "failed" {
+		t.Fatalf("execution status = %q, want failed", status)
+	}
+	if cmd := repo.commands["srv-1"]; cmd == nil || cmd.Status != "failed" {
+		t.Errorf("server step should be failed: %+v", cmd)
+	}
+}
@@ -0,0 +1,310 @@
+// Package threatrules is the application/service layer for the threat
+// detection rules used by internal/threatdetect: admins add, disable and test
+// signatures, import rule packs, and read per-rule hit counters to tune false
+// positives without a server release. Every change recompiles the active
+// ruleset; on a multi-replica deployment SetOnChange tells the other
+// replicas to Reload. Logic sits behind a Repository port so it is
+// unit-testable without a database.
+package threatrules
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/threatdetect"
+)
+
+const (
+	maxNameLength = 200
+	// maxReplayRequests caps how many stored requests a rule test replays.
+	maxReplayRequests = 5000
+	maxReplayHours    = 7 * 24
+	maxTestExamples   = 20
+	maxPackRules      = 1000
+)
+
+// packNamePattern keeps pack names usable as a rule id prefix and a source.
+var packNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Repository is the data-access port. *database.DB satisfies it structurally.
+type Repository interface {
+	ListThreatRules(ctx context.Context) ([]models.ThreatRule, error)
+	GetThreatRule(ctx context.Context, id string) (*models.ThreatRule, error)
+	CreateThreatRule(ctx context.Context, r *models.ThreatRule) (*models.ThreatRule, error)
+	UpdateThreatRule(ctx context.Context, r *models.ThreatRule) (*models.ThreatRule, error)
+	DeleteThreatRule(ctx context.Context, id string) (bool, error)
+	UpsertThreatRules(ctx context.Context, rules []models.ThreatRule) (created, updated int, err error)
+	ThreatRuleHits(ctx context.Context) (map[string]models.ThreatRuleHits, error)
+	RecentWebRequestsForRuleTest(ctx context.Context, since time.Time, limit int) ([]models.ThreatRuleSample, error)
+}
+
+// Service holds the threat rule use-cases.
+type Service struct {
+	repo     Repository
+	onChange func() // nil: single server, nothing to tell
+	now      func() time.Time
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// SetOnChange registers fn, called after every change so the other replicas
+// can Reload.
+func (s *Service) SetOnChange(fn func()) {
+	s.onChange = fn
+}
+
+// SyncBuiltins stores the built-in rules shipped with this release (new ones
+// added, existing ones refreshed, their enabled state kept).
+func (s *Service) SyncBuiltins(ctx context.Context) error {
+	_, _, err := s.repo.UpsertThreatRules(ctx, threatdetect.BuiltinRules())
+	return err
+}
+
+// Reload compiles the stored rules into the ruleset threatdetect uses. A rule
+// that no longer compiles is logged and skipped; the others still apply.
+func (s *Service) Reload(ctx context.Context) error {
+	rules, err := s.repo.ListThreatRules(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "threat rules: failed to load", slog.Any("err", err))
+		return err
+	}
+	rs, err := threatdetect.NewRuleset(rules)
+	if err != nil {
+		slog.WarnContext(ctx, "threat rules: some rules were skipped", slog.Any("err", err))
+	}
+	threatdetect.SetRules(rs)
+	return nil
+}
+
+// changed reloads the local ruleset and tells the other replicas.
+func (s *Service) changed(ctx context.Context) {
+	_ = s.Reload(ctx)
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// List returns every rule with its hit counters (never nil).
+func (s *Service) List(ctx context.Context) ([]models.ThreatRule, error) {
+	rules, err := s.repo.ListThreatRules(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	hits, err := s.repo.ThreatRuleHits(ctx)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if rules == nil {
+		rules = []models.ThreatRule{}
+	}
+	for i := range rules {
+		h := hits[rules[i].ID]
+		rules[i].Hits24h, rules[i].Hits7d, rules[i].LastHitAt = h.Hits24h, h.Hits7d, h.LastHitAt
+	}
+	return rules, nil
+}
+
+// ruleFromInput validates the input and copies it onto r.
+func ruleFromInput(r *models.ThreatRule, in models.ThreatRuleInput) error {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > maxNameLength {
+		return apperr.Validation(fmt.Sprintf("name requis (%d caractères max)", maxNameLength))
+	}
+	r.Name = name
+	r.Description = strings.TrimSpace(in.Description)
+	r.Category = strings.TrimSpace(in.Category)
+	r.Severity = strings.ToLower(strings.TrimSpace(in.Severity))
+	r.MethodPattern = in.MethodPattern
+	r.PathPattern = in.PathPattern
+	r.QueryPattern = in.QueryPattern
+	r.UserAgentPattern = in.UserAgentPattern
+	r.StatusPattern = in.StatusPattern
+	if in.Enabled != nil {
+		r.Enabled = *in.Enabled
+	}
+	if err := threatdetect.ValidateRule(*r); err != nil {
+		return apperr.Validation(err.Error())
+	}
+	return nil
+}
+
+// Create adds a custom rule, enabled unless the input says otherwise.
+func (s *Service) Create(ctx context.Context, in models.ThreatRuleInput, createdBy string) (*models.ThreatRule, error) {
+	r := &models.ThreatRule{ID: uuid.NewString(), Enabled: true, Source: models.ThreatRuleSourceCustom, CreatedBy: createdBy}
+	if err := ruleFromInput(r, in); err != nil {
+		return nil, err
+	}
+	created, err := s.repo.CreateThreatRule(ctx, r)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	s.changed(ctx)
+	return created, nil
+}
+
+// Update edits a rule. Built-in rules ship with the server: only Enabled is
+// taken into account for them.
+func (s *Service) Update(ctx context.Context, id string, in models.ThreatRuleInput) (*models.ThreatRule, error) {
+	r, err := s.repo.GetThreatRule(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("règle introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	if r.Source == models.ThreatRuleSourceBuiltin {
+		if in.Enabled == nil {
+			return nil, apperr.Validation("règle intégrée : seul enabled est modifiable")
+		}
+		r.Enabled = *in.Enabled
+	} else if err := ruleFromInput(r, in); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateThreatRule(ctx, r)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperr.NotFound("règle introuvable")
+		}
+		return nil, apperr.Internal(err)
+	}
+	s.changed(ctx)
+	return updated, nil
+}
+
+// Delete removes a custom or imported rule. Built-in rules can only be
+// disabled: they would come back at the next start anyway.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	r, err := s.repo.GetThreatRule(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperr.NotFound("règle introuvable")
+		}
+		return apperr.Internal(err)
+	}
+	if r.Source == models.ThreatRuleSourceBuiltin {
+		return apperr.Conflict("une règle intégrée ne peut pas être supprimée, désactivez-la")
+	}
+	ok, err := s.repo.DeleteThreatRule(ctx, id)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.NotFound("règle introuvable")
+	}
+	s.changed(ctx)
+	return nil
+}
+
+// Test tries a draft rule, without saving it, against the given samples and
+// against the requests stored over the last req.Hours.
+func (s *Service) Test(ctx context.Context, req models.ThreatRuleTestRequest) (*models.ThreatRuleTestResult, error) {
+	draft := &models.ThreatRule{ID: "test", Enabled: true, Source: models.ThreatRuleSourceCustom}
+	in := req.Rule
+	if strings.TrimSpace(in.Name) == "" {
+		in.Name = "test"
+	}
+	in.Enabled = nil
+	if err := ruleFromInput(draft, in); err != nil {
+		return nil, err
+	}
+	rs, err := threatdetect.NewRuleset([]models.ThreatRule{*draft})
+	if err != nil {
+		return nil, apperr.Validation(err.Error())
+	}
+	match := func(sm models.ThreatRuleSample) bool {
+		_, _, ok := rs.Match(threatdetect.Request{
+			Method: sm.Method, Path: sm.Path, Query: sm.Query, UserAgent: sm.UserAgent, Status: sm.Status,
+		})
+		return ok
+	}
+
+	res := &models.ThreatRuleTestResult{SampleMatches: make([]bool, len(req.Samples)), Examples: []models.ThreatRuleSample{}}
+	for i, sm := range req.Samples {
+		res.SampleMatches[i] = match(sm)
+	}
+	if req.Hours < 0 {
+		return res, nil
+	}
+	hours := req.Hours
+	if hours == 0 {
+		hours = 24
+	}
+	if hours > maxReplayHours {
+		return nil, apperr.Validation(fmt.Sprintf("hours doit être compris entre 1 et %d", maxReplayHours))
+	}
+	stored, err := s.repo.RecentWebRequestsForRuleTest(ctx, s.now().Add(-time.Duration(hours)*time.Hour), maxReplayRequests)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	res.Scanned = len(stored)
+	for _, sm := range stored {
+		if !match(sm) {
+			continue
+		}
+		res.Matched++
+		if len(res.Examples) < maxTestExamples {
+			res.Examples = append(res.Examples, sm)
+		}
+	}
+	return res, nil
+}
+
+// Import adds or refreshes the rules of a pack — one shipped with the server
+// (req.Bundled) or the given one. Its rules get "<pack>:<id>" ids and the
+// pack name as source; new ones are enabled, existing ones keep their state.
+// The whole pack is rejected if any rule is invalid.
+func (s *Service) Import(ctx context.Context, req models.ThreatRuleImportRequest, createdBy string) (*models.ThreatRuleImportResult, error) {
+	pack := req.Pack
+	if req.Bundled != "" {
+		bundled, ok := threatdetect.BundledPack(req.Bundled)
+		if !ok {
+			return nil, apperr.NotFound(fmt.Sprintf("pack %q introuvable (disponibles : %s)", req.Bundled, strings.Join(threatdetect.BundledPackNames(), ", ")))
+		}
+		pack = bundled
+	}
+	if pack == nil {
+		return nil, apperr.Validation("bundled ou pack requis")
+	}
+	if !packNamePattern.MatchString(pack.Name) || pack.Name == models.ThreatRuleSourceBuiltin || pack.Name == models.ThreatRuleSourceCustom {
+		return nil, apperr.Validation("nom de pack invalide : minuscules, chiffres, _ ou -, 50 caractères max")
+	}
+	if len(pack.Rules) == 0 || len(pack.Rules) > maxPackRules {
+		return nil, apperr.Validation(fmt.Sprintf("un pack contient entre 1 et %d règles", maxPackRules))
+	}
+
+	rules := make([]models.ThreatRule, 0, len(pack.Rules))
+	seen := map[string]bool{}
+	for _, pr := range pack.Rules {
+		if pr.ID == "" || seen[pr.ID] {
+			return nil, apperr.Validation(fmt.Sprintf("règle %q : id manquant ou en double", pr.ID))
+		}
+		seen[pr.ID] = true
+		r := models.ThreatRule{ID: pack.Name + ":" + pr.ID, Enabled: true, Source: pack.Name, CreatedBy: createdBy}
+		if err := ruleFromInput(&r, models.ThreatRuleInput{
+			Name: pr.Name, Description: pr.Description, Category: pr.Category, Severity: pr.Severity,
+			MethodPattern: pr.MethodPattern, PathPattern: pr.PathPattern, QueryPattern: pr.QueryPattern,
+			UserAgentPattern: pr.UserAgentPattern, StatusPattern: pr.StatusPattern,
+		}); err != nil {
+			return nil, apperr.Validation(fmt.Sprintf("règle %q : %s", pr.ID, err.Error()))
+		}
+		rules = append(rules, r)
+	}
+	created, updated, err := s.repo.UpsertThreatRules(ctx, rules)
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	s.changed(ctx)
+	return &models.ThreatRuleImportResult{Pack: pack.Name, Created: created, Updated: updated}, nil
+}
@@ -0,0 +1,195 @@
+package threatrules
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/threatdetect"
+)
+
+type fakeRepo struct {
+	rules  map[string]models.ThreatRule
+	stored []models.ThreatRuleSample
+}
+
+func newFakeRepo() *fakeRepo { return &fakeRepo{rules: map[string]models.ThreatRule{}} }
+
+func (f *fakeRepo) ListThreatRules(context.Context) ([]models.ThreatRule, error) {
+	out := make([]models.ThreatRule, 0, len(f.rules))
+	for _, r := range f.rules {
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) GetThreatRule(_ context.Context, id string) (*models.ThreatRule, error) {
+	r, ok := f.rules[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &r, nil
+}
+
+func (f *fakeRepo) CreateThreatRule(_ context.Context, r *models.ThreatRule) (*models.ThreatRule, error) {
+	f.rules[r.ID] = *r
+	return r, nil
+}
+
+func (f *fakeRepo) UpdateThreatRule(_ context.Context, r *models.ThreatRule) (*models.ThreatRule, error) {
+	if _, ok := f.rules[r.ID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	f.rules[r.ID] = *r
+	return r, nil
+}
+
+func (f *fakeRepo) DeleteThreatRule(_ context.Context, id string) (bool, error) {
+	_, ok := f.rules[id]
+	delete(f.rules, id)
+	return ok, nil
+}
+
+func (f *fakeRepo) UpsertThreatRules(_ context.Context, rules []models.ThreatRule) (int, int, error) {
+	created, updated := 0, 0
+	for _, r := range rules {
+		if prev, ok := f.rules[r.ID]; ok {
+			r.Enabled = prev.Enabled
+			updated++
+		} else {
+			created++
+		}
+		f.rules[r.ID] = r
+	}
+	return created, updated, nil
+}
+
+func (f *fakeRepo) ThreatRuleHits(context.Context) (map[string]models.ThreatRuleHits, error) {
+	return map[string]models.ThreatRuleHits{"builtin-wordpress": {Hits24h: 3, Hits7d: 10}}, nil
+}
+
+func (f *fakeRepo) RecentWebRequestsForRuleTest(context.Context, time.Time, int) ([]models.ThreatRuleSample, error) {
+	return f.stored, nil
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if err == nil || apperr.From(err).Code != code {
+		t.Fatalf("err = %v, want %s", err, code)
+	}
+}
+
+func TestCreateRuleAppliesImmediately(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	changes := 0
+	svc.SetOnChange(func() { changes++ })
+
+	_, err := svc.Create(context.Background(), models.ThreatRuleInput{
+		Name: "Bad bot", Category: "BadBot", Severity: "medium", UserAgentPattern: "(?i)evilbot",
+	}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != 1 {
+		t.Errorf("onChange calls = %d, want 1", changes)
+	}
+	if got := threatdetect.Classify("GET", "/", "EvilBot/2.0"); got != "BadBot" {
+		t.Errorf("Classify = %q, want BadBot", got)
+	}
+
+	_, err = svc.Create(context.Background(), models.ThreatRuleInput{Name: "x", Category: "X", Severity: "high", PathPattern: "("}, "admin")
+	wantCode(t, err, "validation")
+}
+
+func TestBuiltinRulesCanOnlyBeDisabled(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	if err := svc.SyncBuiltins(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	r, err := svc.Update(context.Background(), "builtin-wordpress", models.ThreatRuleInput{Name: "x", PathPattern: "/", Enabled: &off})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Enabled || r.PathPattern == "/" {
+		t.Errorf("builtin update = %+v, want only enabled changed", r)
+	}
+	if got := threatdetect.Classify("GET", "/wp-login.php", "Mozilla/5.0"); got == threatdetect.CategoryWordPress {
+		t.Error("disabled builtin rule still classifies")
+	}
+	wantCode(t, svc.Delete(context.Background(), "builtin-wordpress"), "conflict")
+
+	// A new release refreshes the built-ins but keeps them disabled.
+	if err := svc.SyncBuiltins(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repo.rules["builtin-wordpress"].Enabled {
+		t.Error("SyncBuiltins re-enabled a rule the admin disabled")
+	}
+
+	rules, err := svc.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rules {
+		if r.ID == "builtin-wordpress" && r.Hits7d != 10 {
+			t.Errorf("hits_7d = %d, want 10", r.Hits7d)
+		}
+	}
+}
+
+func TestTestRule(t *testing.T) {
+	repo := newFakeRepo()
+	repo.stored = []models.ThreatRuleSample{
+		{Method: "GET", Path: "/search", Query: "q=<script>alert(1)</script>", Status: 200},
+		{Method: "GET", Path: "/search", Query: "q=shoes", Status: 200},
+	}
+	svc := NewService(repo)
+	res, err := svc.Test(context.Background(), models.ThreatRuleTestRequest{
+		Rule:    models.ThreatRuleInput{Category: "XSS", Severity: "high", QueryPattern: "(?i)<script"},
+		Samples: []models.ThreatRuleSample{{Path: "/", Query: "a=<SCRIPT>"}, {Path: "/"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.SampleMatches[0] || res.SampleMatches[1] {
+		t.Errorf("sample matches = %v", res.SampleMatches)
+	}
+	if res.Scanned != 2 || res.Matched != 1 || len(res.Examples) != 1 {
+		t.Errorf("replay = %d/%d (%d examples)", res.Matched, res.Scanned, len(res.Examples))
+	}
+	if len(repo.rules) != 0 {
+		t.Error("testing a rule must not store it")
+	}
+}
+
+func TestImportBundledPackTwice(t *testing.T) {
+	repo := newFakeRepo()
+	svc := NewService(repo)
+	res, err := svc.Import(context.Background(), models.ThreatRuleImportRequest{Bundled: "crs-lite"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created == 0 || res.Updated != 0 {
+		t.Errorf("first import = %+v", res)
+	}
+	if r, ok := repo.rules["crs-lite:log4shell"]; !ok || r.Source != "crs-lite" {
+		t.Errorf("imported rule = %+v", r)
+	}
+	res, err = svc.Import(context.Background(), models.ThreatRuleImportRequest{Bundled: "crs-lite"}, "admin")
+	if err != nil || res.Created != 0 || res.Updated == 0 {
+		t.Errorf("second import = %+v, %v", res, err)
+	}
+
+	_, err = svc.Import(context.Background(), models.ThreatRuleImportRequest{Bundled: "nope"}, "admin")
+	wantCode(t, err, "not_found")
+	_, err = svc.Import(context.Background(), models.ThreatRuleImportRequest{Pack: &models.ThreatRulePack{
+		Name: "mine", Rules: []models.ThreatRulePackRule{{ID: "a", Name: "A", Category: "X", Severity: "low"}},
+	}}, "admin")
+	wantCode(t, err, "validation")
+}
@@ -1,15 +1,13 @@
 // Package weblogs is the application/service layer for the web-logs / threats
-// views. It owns the CrowdSec ban/unban dispatch, the summary enrichment (top-IP
-// geolocation with bounded concurrency + cache, KPI window comparison, threats
+// views. It owns the CrowdSec ban/unban dispatch, the summary enrichment (offline
+// top-IP geolocation via internal/geoip, KPI window comparison, threats
 // promotion) and the detail reads behind a Repository + Dispatcher port. HTTP
 // query parsing/validation stays in the handler.
 package weblogs
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"net/netip"
 	"sort"
 	"strings"
@@ -20,6 +18,7 @@ import (
 	"github.com/serversupervisor/server/internal/config"
 	"github.com/serversupervisor/server/internal/database"
 	"github.com/serversupervisor/server/internal/dispatch"
+	"github.com/serversupervisor/server/internal/geoip"
 	"github.com/serversupervisor/server/internal/models"
 	"github.com/serversupervisor/server/internal/safego"
 	"github.com/serversupervisor/server/internal/threatdetect"
@@ -35,6 +34,7 @@ type Repository interface {
 	GetDomainDetails(ctx context.Context, domain string, since, until time.Time, hostID, source string, filter database.DomainDetailsFilter, limit, offset int) (map[string]any, error)
 	GetWebLogsTimeseries(ctx context.Context, since, until time.Time, hostID, source, bucket string) ([]map[string]any, error)
 	GetWebLogsLive(ctx context.Context, hostID, source string, limit int) ([]map[string]any, error)
+	GetWebLogsPerformance(ctx context.Context, since, until time.Time, hostID, source string, routeLimit int) (domains, routes []models.WebPerformanceStat, err error)
 }
 
 // Dispatcher is the agent-command port. *dispatch.Dispatcher satisfies it.
@@ -67,6 +67,7 @@ func weightsFromConfig(cfg *config.Config) threatdetect.Weights {
 		CategoryPathTraversal:    cfg.ThreatWeightPathTraversal,
 		CategoryKnownScanner:     cfg.ThreatWeightKnownScanner,
 		CategorySuspiciousMethod: cfg.ThreatWeightSuspiciousMethod,
+		CategoryOther:            threatdetect.DefaultWeights().CategoryOther,
 		Status2xxMultiplier:      cfg.ThreatWeightStatus2xx,
 		Status3xxMultiplier:      cfg.ThreatWeightStatus3xx,
 		Status404Multiplier:      cfg.ThreatWeightStatus404,
@@ -90,6 +91,16 @@ func (s *Service) applyThreatScoring(threats map[string]any) {
 	if !ok {
 		return
 	}
+	s.scoreIPs(rawIPs)
+	if len(rawIPs) > 25 {
+		rawIPs = rawIPs[:25]
+	}
+	threats["top_ips"] = rawIPs
+}
+
+// scoreIPs sets threat_score/level on each raw top-IP row and sorts them by
+// score, highest first.
+func (s *Service) scoreIPs(rawIPs []map[string]any) {
 	w := weightsFromConfig(s.cfg)
 	for _, ip := range rawIPs {
 		hits := anyToInt64(ip["hits"])
@@ -105,10 +116,30 @@ func (s *Service) applyThreatScoring(threats map[string]any) {
 	sort.Slice(rawIPs, func(i, j int) bool {
 		return toFloat(rawIPs[i]["threat_score"]) > toFloat(rawIPs[j]["threat_score"])
 	})
-	if len(rawIPs) > 25 {
-		rawIPs = rawIPs[:25]
+}
+
+// ThreatScores returns the threat score of every suspicious IP seen on any
+// host over [since, until), highest first — what the automated blocking
+// policies (internal/services/ipblock) act on.
+func (s *Service) ThreatScores(ctx context.Context, since, until time.Time) ([]models.IPThreatScore, error) {
+	threats, err := s.repo.GetWebLogsThreats(ctx, since, until, "", "")
+	if err != nil {
+		return nil, err
 	}
-	threats["top_ips"] = rawIPs
+	rawIPs, _ := threats["top_ips"].([]map[string]any)
+	s.scoreIPs(rawIPs)
+	out := make([]models.IPThreatScore, 0, len(rawIPs))
+	for _, ip := range rawIPs {
+		out = append(out, models.IPThreatScore{
+			IP:          anyToString(ip["ip"]),
+			Score:       toFloat(ip["threat_score"]),
+			Level:       anyToString(ip["level"]),
+			Hits:        anyToInt64(ip["hits"]),
+			UniquePaths: anyToInt64(ip["unique_paths"]),
+			HostCount:   anyToInt64(ip["host_count"]),
+		})
+	}
+	return out, nil
 }
 
 // BlockIP validates the IP + duration and dispatches a CrowdSec ban, returning
@@ -179,8 +210,7 @@ type SummaryResult struct {
 // top-IP geolocation and a current-vs-previous KPI comparison.
 //
 // scope == "threats" returns only the threats block: it skips the unindexed
-// traffic aggregates, the top-IP geolocation HTTP fan-out and the compare KPI
-// windows. The threats-only consumer (BotView) reads only summary.threats, so
+// traffic aggregates, the top-client-IP query and the compare KPI windows. The threats-only consumer (BotView) reads only summary.threats, so
 // this keeps that page responsive on large windows where the full summary would
 // otherwise time out.
 func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, source, scope string) (*SummaryResult, error) {
@@ -192,13 +222,12 @@ func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, s
 		s.applyThreatScoring(threats)
 		// No traffic block in this scope; promote only applies the crowdsec bump.
 		promoteBlockedIntoThreats(map[string]any{"threats": threats}, threats)
-		// Same geoloc helper the "full" scope uses for its traffic map, run
-		// inline here instead of in a goroutine: applyThreatScoring already
-		// capped top_ips at 25 (vs. 120 for traffic), so this is a fraction of
-		// the fan-out the full scope already accepts, bounded by the same
-		// 4-worker/2s-timeout/24h-cache resolveIPsWithContext.
+		enrichThreats(threats)
+		// Same distributions the "full" scope computes for its traffic map,
+		// over the (25, already capped) scored top_ips.
 		if ips, ok := threats["top_ips"].([]map[string]any); ok {
 			threats["country_distribution"] = countryDistribution(ips)
+			threats["asn_distribution"] = asnDistribution(ips)
 		}
 		return &SummaryResult{Since: since, Threats: threats}, nil
 	}
@@ -215,7 +244,7 @@ func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, s
 		firstErr                error
 		summary                 map[string]any
 		topIPs                  []map[string]any
-		countryDist             []map[string]any
+		countryDist, asnDist    []map[string]any
 		currentKPI, previousKPI map[string]any
 	)
 	setErr := func(err error) {
@@ -262,8 +291,10 @@ func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, s
 		if err != nil {
 			return
 		}
+		enrichIPRows(ips)
 		topIPs = ips
 		countryDist = countryDistribution(ips)
+		asnDist = asnDistribution(ips)
 	}()
 	go func() {
 		defer wg.Done()
@@ -293,6 +324,7 @@ func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, s
 	if traffic, ok := summary["traffic"].(map[string]any); ok && topIPs != nil {
 		traffic["top_client_ips"] = topIPs
 		traffic["country_distribution"] = countryDist
+		traffic["asn_distribution"] = asnDist
 	}
 
 	compare := map[string]any{
@@ -309,12 +341,100 @@ func (s *Service) Summary(ctx context.Context, since, until time.Time, hostID, s
 	threats := summary["threats"]
 	if threatsMap, ok := threats.(map[string]any); ok {
 		s.applyThreatScoring(threatsMap)
+		enrichThreats(threatsMap)
 	}
 	promoteBlockedIntoThreats(summary, threats)
 
 	return &SummaryResult{Since: since, Traffic: summary["traffic"], Threats: threats, Compare: compare}, nil
 }
 
+// geoSummaryIPs is how many of the busiest client IPs a geo summary covers
+// (the repository's own cap).
+const geoSummaryIPs = 500
+
+// GeoSummary groups the traffic of the period's busiest client IPs by
+// country or by autonomous system (groupBy: geoip.GroupByCountry or
+// geoip.GroupByASN).
+func (s *Service) GeoSummary(ctx context.Context, since, until time.Time, hostID, source, groupBy string) ([]models.GeoGroup, error) {
+	if groupBy != geoip.GroupByCountry && groupBy != geoip.GroupByASN {
+		return nil, apperr.Validation("group_by doit valoir country ou asn")
+	}
+	ips, err := s.repo.GetWebLogsTopClientIPs(ctx, since, until, hostID, source, geoSummaryIPs)
+	if err != nil {
+		return nil, err
+	}
+	return geoGroups(ips, groupBy), nil
+}
+
+const (
+	// performanceRoutes is how many of the busiest routes a performance
+	// report covers; the rankings are drawn from them.
+	performanceRoutes = 200
+	// performanceRankSize is the length of each ranking.
+	performanceRankSize = 10
+	// performanceRankMinRequests keeps near-idle routes out of the rankings,
+	// where a single 502 or slow request would top them.
+	performanceRankMinRequests = 10
+)
+
+// Performance returns the latency percentiles and error rates per domain and
+// per normalized route, plus the routes ranked by 5xx rate and by p95.
+func (s *Service) Performance(ctx context.Context, since, until time.Time, hostID, source string) (*models.WebPerformanceReport, error) {
+	domains, routes, err := s.repo.GetWebLogsPerformance(ctx, since, until, hostID, source, performanceRoutes)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebPerformanceReport{
+		Domains: domains,
+		Routes:  routes,
+		ErrorRoutes: rankRoutes(routes, func(r models.WebPerformanceStat) (float64, bool) {
+			return r.ErrorRate, r.Requests >= performanceRankMinRequests && r.Errors5xx > 0
+		}),
+		SlowestRoutes: rankRoutes(routes, func(r models.WebPerformanceStat) (float64, bool) {
+			return r.P95Ms, r.TimedRequests >= performanceRankMinRequests
+		}),
+	}, nil
+}
+
+// rankRoutes keeps the routes key accepts and returns the top
+// performanceRankSize by descending key (ties: busiest first).
+func rankRoutes(routes []models.WebPerformanceStat, key func(models.WebPerformanceStat) (float64, bool)) []models.WebPerformanceStat {
+	type ranked struct {
+		stat  models.WebPerformanceStat
+		value float64
+	}
+	var kept []ranked
+	for _, r := range routes {
+		if v, ok := key(r); ok {
+			kept = append(kept, ranked{r, v})
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].value != kept[j].value {
+			return kept[i].value > kept[j].value
+		}
+		return kept[i].stat.Requests > kept[j].stat.Requests
+	})
+	out := make([]models.WebPerformanceStat, 0, performanceRankSize)
+	for i := 0; i < len(kept) && i < performanceRankSize; i++ {
+		out = append(out, kept[i].stat)
+	}
+	return out
+}
+
+// GeoIPStatus reports which offline GeoIP databases are loaded.
+func (s *Service) GeoIPStatus() models.GeoIPStatus { return geoip.Status() }
+
+// enrichThreats geolocates the scored top IPs and the CrowdSec decisions.
+func enrichThreats(threats map[string]any) {
+	if ips, ok := threats["top_ips"].([]map[string]any); ok {
+		enrichIPRows(ips)
+	}
+	if entries, ok := threats["crowdsec_top_blocked"].([]map[string]any); ok {
+		enrichCrowdSecDecisions(entries)
+	}
+}
+
 // IPTimeline returns the request timeline for an IP.
 func (s *Service) IPTimeline(ctx context.Context, ip string, since, until time.Time, hostID string, limit int) ([]models.WebLogIPTimelineRow, error) {
 	return s.repo.GetIPTimeline(ctx, ip, since, until, hostID, limit)
@@ -360,7 +480,17 @@ func promoteBlockedIntoThreats(summary map[string]any, threats any) {
 // countryDistribution resolves the top IPs to countries and returns a list sorted
 // by hits descending.
 func countryDistribution(topIPs []map[string]any) []map[string]any {
-	countryHits, countryCodes := resolveIPsWithContext(topIPs)
+	countryHits := make(map[string]int64)
+	countryCodes := make(map[string]string)
+	for _, row := range topIPs {
+		hits := anyToInt64(row["hits"])
+		if hits <= 0 {
+			continue
+		}
+		country, code := geoLabel(anyToString(row["ip"]))
+		countryHits[country] += hits
+		countryCodes[country] = code
+	}
 	dist := make([]map[string]any, 0, len(countryHits))
 	for country, hits := range countryHits {
 		dist = append(dist, map[string]any{
@@ -369,14 +499,59 @@ func countryDistribution(topIPs []map[string]any) []map[string]any {
 			"hits":         hits,
 		})
 	}
-	for i := 0; i < len(dist); i++ {
-		for j := i + 1; j < len(dist); j++ {
-			if anyToInt64(dist[j]["hits"]) > anyToInt64(dist[i]["hits"]) {
-				dist[i], dist[j] = dist[j], dist[i]
+	sort.SliceStable(dist, func(i, j int) bool { return anyToInt64(dist[i]["hits"]) > anyToInt64(dist[j]["hits"]) })
+	return dist
+}
+
+// asnDistribution sums the hits of topIPs per autonomous system. IPs without
+// an ASN (private, or no ASN database) are left out.
+func asnDistribution(topIPs []map[string]any) []map[string]any {
+	groups := geoGroups(topIPs, geoip.GroupByASN)
+	dist := make([]map[string]any, 0, len(groups))
+	for _, g := range groups {
+		dist = append(dist, map[string]any{"asn": g.ASN, "as_org": g.Label, "hits": g.Hits, "ips": g.IPs})
+	}
+	return dist
+}
+
+// geoGroups sums the hits of rows ({ip, hits}) per country or ASN, busiest
+// first.
+func geoGroups(rows []map[string]any, groupBy string) []models.GeoGroup {
+	byKey := map[string]*models.GeoGroup{}
+	for _, row := range rows {
+		hits := anyToInt64(row["hits"])
+		if hits <= 0 {
+			continue
+		}
+		geo := geoip.Lookup(anyToString(row["ip"]))
+		key, label, ok := geoip.GroupKey(geo, groupBy)
+		if !ok {
+			continue
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &models.GeoGroup{Key: key, Label: label}
+			if groupBy == geoip.GroupByASN {
+				g.ASN = geo.ASN
+			} else {
+				g.CountryCode = geo.CountryCode
 			}
+			byKey[key] = g
 		}
+		g.IPs++
+		g.Hits += hits
 	}
-	return dist
+	out := make([]models.GeoGroup, 0, len(byKey))
+	for _, g := range byKey {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hits != out[j].Hits {
+			return out[i].Hits > out[j].Hits
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
 }
 
 func toFloat(v any) float64 {
@@ -430,61 +605,50 @@ func isPrivateOrLocalIP(ip string) bool {
 	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsUnspecified()
 }
 
-type ipCountryInfo struct {
-	Country     string
-	CountryCode string
-	UpdatedAt   time.Time
+// enrichIPRows adds the offline geolocation (country, country_code, city,
+// asn, as_org) of each row's "ip".
+func enrichIPRows(rows []map[string]any) {
+	for _, row := range rows {
+		ip := strings.TrimSpace(anyToString(row["ip"]))
+		if ip == "" {
+			continue
+		}
+		geo := geoip.Lookup(ip)
+		if geo.CountryCode != "" {
+			row["country"] = geo.Country
+			row["country_code"] = geo.CountryCode
+		}
+		if geo.City != "" {
+			row["city"] = geo.City
+		}
+		if geo.ASN != 0 {
+			row["asn"] = geo.ASN
+			row["as_org"] = geo.ASOrg
+		}
+	}
 }
 
-var (
-	ipCountryCache   = map[string]ipCountryInfo{}
-	ipCountryCacheMu sync.RWMutex
-)
-
-// resolveIPsWithContext resolves IPs to countries with bounded concurrency (4
-// workers), returning aggregated country hits + codes.
-func resolveIPsWithContext(topIPs []map[string]any) (map[string]int64, map[string]string) {
-	countryHits := make(map[string]int64)
-	countryCodes := make(map[string]string)
-	var mu sync.Mutex
-
-	const concurrency = 4
-	jobs := make(chan map[string]any, concurrency)
-	var wg sync.WaitGroup
-	for w := 0; w < concurrency; w++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			defer safego.Recover(context.Background(), "weblogs.resolveIPsWithContext")
-			for row := range jobs {
-				ip := strings.TrimSpace(anyToString(row["ip"]))
-				hits := anyToInt64(row["hits"])
-				if ip == "" || hits <= 0 {
-					continue
-				}
-				country, code := resolveCountryForIP(ip)
-				if country == "" {
-					country = "Unknown"
-				}
-				if code == "" {
-					code = "--"
-				}
-				mu.Lock()
-				countryHits[country] += hits
-				countryCodes[country] = code
-				mu.Unlock()
-			}
-		}()
-	}
-	for _, row := range topIPs {
-		jobs <- row
+// enrichCrowdSecDecisions fills the country/AS of CrowdSec decisions the
+// agent reported without them (CrowdSec only knows them when its own GeoIP
+// enricher is installed).
+func enrichCrowdSecDecisions(entries []map[string]any) {
+	for _, e := range entries {
+		geo := geoip.Lookup(anyToString(e["ip"]))
+		if anyToString(e["country"]) == "" && geo.CountryCode != "" {
+			e["country"] = geo.CountryCode
+		}
+		if anyToString(e["as_name"]) == "" && geo.ASOrg != "" {
+			e["as_name"] = geo.ASOrg
+		}
+		if geo.ASN != 0 {
+			e["asn"] = geo.ASN
+		}
 	}
-	close(jobs)
-	wg.Wait()
-	return countryHits, countryCodes
 }
 
-func resolveCountryForIP(ip string) (string, string) {
+// geoLabel is the country of an IP for the country distribution, with the
+// historical "Local / Private" and "Unknown" buckets.
+func geoLabel(ip string) (country, code string) {
 	ip = strings.TrimSpace(ip)
 	if ip == "" {
 		return "Unknown", "--"
@@ -492,46 +656,12 @@ func resolveCountryForIP(ip string) (string, string) {
 	if isPrivateOrLocalIP(ip) {
 		return "Local / Private", "LAN"
 	}
-
-	now := time.Now().UTC()
-	ipCountryCacheMu.RLock()
-	if cached, ok := ipCountryCache[ip]; ok && now.Sub(cached.UpdatedAt) < 24*time.Hour {
-		ipCountryCacheMu.RUnlock()
-		return cached.Country, cached.CountryCode
-	}
-	ipCountryCacheMu.RUnlock()
-
-	client := &http.Client{Timeout: 2 * time.Second}
-	req, err := http.NewRequest(http.MethodGet, "https://ipwho.is/"+ip+"?fields=success,country,country_code", nil)
-	if err != nil {
+	geo := geoip.Lookup(ip)
+	if geo.CountryCode == "" {
 		return "Unknown", "--"
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "Unknown", "--"
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	var payload struct {
-		Success     bool   `json:"success"`
-		Country     string `json:"country"`
-		CountryCode string `json:"country_code"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil || !payload.Success {
-		return "Unknown", "--"
-	}
-
-	country := strings.TrimSpace(payload.Country)
-	if country == "" {
-		country = "Unknown"
+	if geo.Country == "" {
+		return geo.CountryCode, geo.CountryCode
 	}
-	code := strings.ToUpper(strings.TrimSpace(payload.CountryCode))
-	if code == "" {
-		code = "--"
-	}
-
-	ipCountryCacheMu.Lock()
-	ipCountryCache[ip] = ipCountryInfo{Country: country, CountryCode: code, UpdatedAt: now}
-	ipCountryCacheMu.Unlock()
-	return country, code
+	return geo.Country, geo.CountryCode
 }
@@ -13,7 +13,8 @@ import (
 	"github.com/serversupervisor/server/internal/threatdetect"
 )
 
-type fakeRepo struct{}
+// fakeRepo returns routes from GetWebLogsPerformance and nothing elsewhere.
+type fakeRepo struct{ routes []models.WebPerformanceStat }
 
 func (fakeRepo) GetWebLogsSummary(context.Context, time.Time, time.Time, string, string) (map[string]any, error) {
 	return nil, nil
@@ -39,6 +40,9 @@ func (fakeRepo) GetWebLogsTimeseries(context.Context, time.Time, time.Time, stri
 func (fakeRepo) GetWebLogsLive(context.Context, string, string, int) ([]map[string]any, error) {
 	return nil, nil
 }
+func (f fakeRepo) GetWebLogsPerformance(context.Context, time.Time, time.Time, string, string, int) ([]models.WebPerformanceStat, []models.WebPerformanceStat, error) {
+	return nil, f.routes, nil
+}
 
 type fakeDispatcher struct{ called bool }
 
@@ -156,7 +160,47 @@ func TestCountryDistribution_PrivateIPsSortedNoHTTP(t *testing.T) {
 	}
 }
 
+func TestGeoSummary_WithoutDatabase(t *testing.T) {
+	svc := NewService(fakeRepo{}, &fakeDispatcher{}, nil)
+	_, err := svc.GeoSummary(context.Background(), time.Now().Add(-time.Hour), time.Time{}, "", "", "city")
+	if !isValidationErr(err) {
+		t.Errorf("group_by=city: err = %v, want validation", err)
+	}
+	// No database configured: public IPs land in "Unknown", nothing is grouped
+	// by ASN, and no request leaves the server.
+	top := []map[string]any{{"ip": "203.0.113.7", "hits": int64(3)}}
+	if dist := countryDistribution(top); len(dist) != 1 || dist[0]["country_code"] != "--" {
+		t.Errorf("country distribution = %v", dist)
+	}
+	if dist := asnDistribution(top); len(dist) != 0 {
+		t.Errorf("asn distribution = %v, want empty", dist)
+	}
+}
+
 func isValidationErr(err error) bool {
 	var ae *apperr.Error
 	return errors.As(err, &ae) && ae.HTTPStatus == 400
 }
+
+func TestPerformance_Rankings(t *testing.T) {
+	repo := fakeRepo{routes: []models.WebPerformanceStat{
+		{Domain: "a.test", Route: "/api/users/:id", Requests: 500, TimedRequests: 500, Errors5xx: 5, ErrorRate: 1, P95Ms: 120},
+		{Domain: "a.test", Route: "/api/export", Requests: 40, TimedRequests: 40, Errors5xx: 8, ErrorRate: 20, P95Ms: 2400},
+		{Domain: "b.test", Route: "/", Requests: 900, TimedRequests: 0, ErrorRate: 0},
+		{Domain: "b.test", Route: "/health", Requests: 3, TimedRequests: 3, Errors5xx: 3, ErrorRate: 100, P95Ms: 9000},
+	}}
+	report, err := NewService(repo, &fakeDispatcher{}, nil).Performance(context.Background(), time.Now().Add(-time.Hour), time.Time{}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Routes) != 4 {
+		t.Errorf("routes = %d, want 4", len(report.Routes))
+	}
+	// /health has too few requests to rank; / has no error and no timing.
+	if got := report.ErrorRoutes; len(got) != 2 || got[0].Route != "/api/export" || got[1].Route != "/api/users/:id" {
+		t.Errorf("error ranking = %+v", got)
+	}
+	if got := report.SlowestRoutes; len(got) != 2 || got[0].Route != "/api/export" {
+		t.Errorf("slow ranking = %+v", got)
+	}
+}
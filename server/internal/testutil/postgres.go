@@ -199,6 +199,12 @@ func buildTemplateDatabase(conn *sql.DB, host, port string) error {
 // green build for the rest of the suite.
 func NewPostgresDB(t *testing.T) *database.DB {
 	t.Helper()
+	db, _ := newPostgresDB(t)
+	return db
+}
+
+func newPostgresDB(t *testing.T) (*database.DB, *config.Config) {
+	t.Helper()
 
 	if os.Getenv("SS_SKIP_INTEGRATION") != "" {
 		t.Skip("SS_SKIP_INTEGRATION is set — skipping integration test")
@@ -253,7 +259,7 @@ func NewPostgresDB(t *testing.T) *database.DB {
 		}
 	})
 
-	return db
+	return db, cfg
 }
 
 // NewPostgresDBWithConfig is identical to NewPostgresDB but also returns the
@@ -261,10 +267,16 @@ func NewPostgresDB(t *testing.T) *database.DB {
 // that take a config.
 func NewPostgresDBWithConfig(t *testing.T) (*database.DB, *config.Config) {
 	t.Helper()
-	db := NewPostgresDB(t)
-	// Pull the same secret + DSN bits back out — easier than threading them
-	// through return values everywhere.
+	db, dbCfg := newPostgresDB(t)
+	// Only the DSN bits are carried over (so DBDSN() reaches the test
+	// database); the rest is what handlers / middlewares need.
 	cfg := &config.Config{
+		DBHost:                 dbCfg.DBHost,
+		DBPort:                 dbCfg.DBPort,
+		DBUser:                 dbCfg.DBUser,
+		DBPassword:             dbCfg.DBPassword,
+		DBName:                 dbCfg.DBName,
+		DBSSLMode:              dbCfg.DBSSLMode,
 		JWTSecret:              "test-jwt-secret-with-enough-length-1234",
 		JWTExpiration:          24 * time.Hour,
 		RefreshTokenExpiration: 7 * 24 * time.Hour,
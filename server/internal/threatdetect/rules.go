@@ -0,0 +1,236 @@
+package threatdetect
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// maxPatternLength bounds each rule pattern. Go regexps run in linear time,
+// so this only keeps a pathological rule from slowing every report.
+const maxPatternLength = 1000
+
+var severityRank = map[string]int{
+	models.ThreatSeverityCritical: 4,
+	models.ThreatSeverityHigh:     3,
+	models.ThreatSeverityMedium:   2,
+	models.ThreatSeverityLow:      1,
+}
+
+// categoryPattern is the shape of a rule category — stored as-is in
+// web_log_requests.category.
+var categoryPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,49}$`)
+
+// BuiltinRules returns the signatures shipped with the server, in
+// evaluation order within a severity. They replace the fixed needle lists
+// Classify used to match: admins can disable them but not edit them, and a
+// release can refine their patterns (see database.SyncBuiltinThreatRules).
+func BuiltinRules() []models.ThreatRule {
+	builtin := func(id, name, category, severity string, set func(*models.ThreatRule)) models.ThreatRule {
+		r := models.ThreatRule{ID: id, Name: name, Category: category, Severity: severity, Enabled: true, Source: models.ThreatRuleSourceBuiltin}
+		set(&r)
+		return r
+	}
+	return []models.ThreatRule{
+		builtin("builtin-path-traversal", "Path traversal", CategoryPathTraversal, models.ThreatSeverityCritical, func(r *models.ThreatRule) {
+			r.PathPattern = `(?i)\.\./|/etc/passwd|/bin/bash`
+		}),
+		builtin("builtin-wordpress", "WordPress probes", CategoryWordPress, models.ThreatSeverityHigh, func(r *models.ThreatRule) {
+			r.PathPattern = `(?i)/wp-|/xmlrpc\.php`
+		}),
+		builtin("builtin-admin-panel", "Admin panels", CategoryAdminPanel, models.ThreatSeverityHigh, func(r *models.ThreatRule) {
+			r.PathPattern = `(?i)/admin|/manager/html|/phpmyadmin`
+		}),
+		builtin("builtin-scanner-paths", "Scanner paths", CategoryKnownScanner, models.ThreatSeverityMedium, func(r *models.ThreatRule) {
+			r.PathPattern = `(?i)/\.env|/cgi-bin|/pma|/actuator|/\.git|/vendor/phpunit|/solr|/hudson|/jenkins|/autodiscover|/owa|/struts|/boaform|/api/jsonws`
+		}),
+		builtin("builtin-scanner-agents", "Scanner user agents", CategoryKnownScanner, models.ThreatSeverityMedium, func(r *models.ThreatRule) {
+			r.UserAgentPattern = `(?i)masscan|nmap|zgrab|sqlmap|nikto|dirbuster|gobuster|wpscan|acunetix|nessus`
+		}),
+		builtin("builtin-suspicious-methods", "Unusual methods", CategorySuspiciousMethod, models.ThreatSeverityLow, func(r *models.ThreatRule) {
+			r.MethodPattern = `(?i)^(OPTIONS|PROPFIND|TRACE|CONNECT)$`
+		}),
+	}
+}
+
+type compiledRule struct {
+	id, category, severity string
+	builtinOrder           int // index in BuiltinRules, len(BuiltinRules) for others
+	method, path, query    *regexp.Regexp
+	userAgent, status      *regexp.Regexp
+}
+
+// Request is what a rule is matched against.
+type Request struct {
+	Method, Path, Query, UserAgent string
+	Status                         int
+}
+
+func (r *compiledRule) match(req Request) bool {
+	return matches(r.method, req.Method) && matches(r.path, req.Path) && matches(r.query, req.Query) &&
+		matches(r.userAgent, req.UserAgent) && (r.status == nil || r.status.MatchString(strconv.Itoa(req.Status)))
+}
+
+func matches(re *regexp.Regexp, s string) bool {
+	return re == nil || re.MatchString(s)
+}
+
+// ValidateRule checks a rule's category, severity and patterns; the error
+// message is meant for the admin who wrote it.
+func ValidateRule(r models.ThreatRule) error {
+	_, err := compileRule(r, 0)
+	return err
+}
+
+func compileRule(r models.ThreatRule, builtinOrder int) (*compiledRule, error) {
+	if !categoryPattern.MatchString(r.Category) {
+		return nil, errors.New("category : lettres, chiffres, _ ou -, 50 caractères max, commençant par une lettre")
+	}
+	if severityRank[r.Severity] == 0 {
+		return nil, errors.New("severity doit être low, medium, high ou critical")
+	}
+	c := &compiledRule{id: r.ID, category: r.Category, severity: r.Severity, builtinOrder: builtinOrder}
+	fields := []struct {
+		name    string
+		pattern string
+		dst     **regexp.Regexp
+	}{
+		{"method_pattern", r.MethodPattern, &c.method},
+		{"path_pattern", r.PathPattern, &c.path},
+		{"query_pattern", r.QueryPattern, &c.query},
+		{"user_agent_pattern", r.UserAgentPattern, &c.userAgent},
+		{"status_pattern", r.StatusPattern, &c.status},
+	}
+	any := false
+	for _, f := range fields {
+		if f.pattern == "" {
+			continue
+		}
+		if len(f.pattern) > maxPatternLength {
+			return nil, fmt.Errorf("%s : %d caractères max", f.name, maxPatternLength)
+		}
+		re, err := regexp.Compile(f.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalide : %v", f.name, err)
+		}
+		*f.dst = re
+		any = true
+	}
+	if !any {
+		return nil, errors.New("au moins un motif est requis (method, path, query, user_agent ou status)")
+	}
+	return c, nil
+}
+
+// Ruleset is an immutable, ordered set of enabled rules: most severe first,
+// then built-ins in their shipped order, then the others in the order given.
+type Ruleset struct {
+	rules []*compiledRule
+}
+
+// NewRuleset compiles the enabled rules. A rule that fails to compile is
+// left out and reported in the returned error; the rest still apply.
+func NewRuleset(rules []models.ThreatRule) (*Ruleset, error) {
+	order := map[string]int{}
+	for i, b := range BuiltinRules() {
+		order[b.ID] = i
+	}
+	rs := &Ruleset{}
+	var errs []error
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		bo, ok := order[r.ID]
+		if !ok || r.Source != models.ThreatRuleSourceBuiltin {
+			bo = len(order)
+		}
+		c, err := compileRule(r, bo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.ID, err))
+			continue
+		}
+		rs.rules = append(rs.rules, c)
+	}
+	sort.SliceStable(rs.rules, func(i, j int) bool {
+		a, b := rs.rules[i], rs.rules[j]
+		if severityRank[a.severity] != severityRank[b.severity] {
+			return severityRank[a.severity] > severityRank[b.severity]
+		}
+		return a.builtinOrder < b.builtinOrder
+	})
+	return rs, errors.Join(errs...)
+}
+
+// Match returns the ID and category of the first rule matching req.
+func (rs *Ruleset) Match(req Request) (ruleID, category string, ok bool) {
+	for _, r := range rs.rules {
+		if r.match(req) {
+			return r.id, r.category, true
+		}
+	}
+	return "", "", false
+}
+
+// Len is the number of enabled rules.
+func (rs *Ruleset) Len() int { return len(rs.rules) }
+
+var active atomic.Pointer[Ruleset]
+
+func init() {
+	rs, err := NewRuleset(BuiltinRules())
+	if err != nil {
+		panic(err)
+	}
+	active.Store(rs)
+}
+
+// SetRules replaces the ruleset every later classification uses. Until the
+// rule store has been loaded, the built-in rules apply.
+func SetRules(rs *Ruleset) {
+	if rs != nil {
+		active.Store(rs)
+	}
+}
+
+// ActiveRules returns the ruleset currently in use.
+func ActiveRules() *Ruleset {
+	return active.Load()
+}
+
+//go:embed packs/*.json
+var bundledPacks embed.FS
+
+// BundledPack returns a rule pack shipped with the server, by name.
+func BundledPack(name string) (*models.ThreatRulePack, bool) {
+	if strings.ContainsAny(name, "/\\.") {
+		return nil, false
+	}
+	data, err := bundledPacks.ReadFile("packs/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	var pack models.ThreatRulePack
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return nil, false
+	}
+	return &pack, true
+}
+
+// BundledPackNames lists the rule packs shipped with the server.
+func BundledPackNames() []string {
+	entries, _ := bundledPacks.ReadDir("packs")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return names
+}
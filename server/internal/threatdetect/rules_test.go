@@ -0,0 +1,108 @@
+package threatdetect
+
+import (
+	"testing"
+
+	"github.com/serversupervisor/server/internal/models"
+)
+
+func TestRuleset_AllPatternsMustMatch(t *testing.T) {
+	rs, err := NewRuleset([]models.ThreatRule{{
+		ID: "login-bruteforce", Category: "BruteForce", Severity: models.ThreatSeverityMedium, Enabled: true,
+		MethodPattern: "^POST$", PathPattern: "^/login$", StatusPattern: "^401$",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, cat, ok := rs.Match(Request{Method: "POST", Path: "/login", Status: 401}); !ok || id != "login-bruteforce" || cat != "BruteForce" {
+		t.Errorf("Match = %q, %q, %v", id, cat, ok)
+	}
+	if _, _, ok := rs.Match(Request{Method: "POST", Path: "/login", Status: 200}); ok {
+		t.Error("a successful login must not match a rule on 401")
+	}
+}
+
+func TestRuleset_MostSevereWins(t *testing.T) {
+	rules := append(BuiltinRules(), models.ThreatRule{
+		ID: "sqli", Category: "SQLInjection", Severity: models.ThreatSeverityCritical, Enabled: true,
+		QueryPattern: `(?i)union\s+select`,
+	})
+	rs, err := NewRuleset(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Also an admin path (high), but the critical custom rule comes first.
+	if id, _, _ := rs.Match(Request{Method: "GET", Path: "/admin/users", Query: "id=1 UNION SELECT password"}); id != "sqli" {
+		t.Errorf("matched %q, want sqli", id)
+	}
+	if id, _, _ := rs.Match(Request{Method: "GET", Path: "/admin/users", Query: "id=1"}); id != "builtin-admin-panel" {
+		t.Errorf("matched %q, want builtin-admin-panel", id)
+	}
+}
+
+func TestRuleset_SkipsDisabledAndInvalid(t *testing.T) {
+	rs, err := NewRuleset([]models.ThreatRule{
+		{ID: "off", Category: "X", Severity: models.ThreatSeverityLow, PathPattern: "/", Enabled: false},
+		{ID: "broken", Category: "X", Severity: models.ThreatSeverityLow, PathPattern: "(", Enabled: true},
+		{ID: "ok", Category: "X", Severity: models.ThreatSeverityLow, PathPattern: "^/x$", Enabled: true},
+	})
+	if err == nil {
+		t.Error("expected the invalid rule to be reported")
+	}
+	if rs.Len() != 1 {
+		t.Errorf("Len = %d, want 1", rs.Len())
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	base := models.ThreatRule{Category: "XSS", Severity: models.ThreatSeverityHigh, QueryPattern: "<script"}
+	if err := ValidateRule(base); err != nil {
+		t.Errorf("valid rule rejected: %v", err)
+	}
+	for name, mutate := range map[string]func(*models.ThreatRule){
+		"no pattern":   func(r *models.ThreatRule) { r.QueryPattern = "" },
+		"bad regex":    func(r *models.ThreatRule) { r.QueryPattern = "[" },
+		"bad severity": func(r *models.ThreatRule) { r.Severity = "urgent" },
+		"bad category": func(r *models.ThreatRule) { r.Category = "a b" },
+	} {
+		r := base
+		mutate(&r)
+		if err := ValidateRule(r); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBundledPackRulesAreValid(t *testing.T) {
+	pack, ok := BundledPack("crs-lite")
+	if !ok || len(pack.Rules) == 0 {
+		t.Fatal("crs-lite pack missing")
+	}
+	rules := make([]models.ThreatRule, 0, len(pack.Rules))
+	for _, pr := range pack.Rules {
+		r := models.ThreatRule{
+			ID: pr.ID, Category: pr.Category, Severity: pr.Severity, Enabled: true,
+			MethodPattern: pr.MethodPattern, PathPattern: pr.PathPattern, QueryPattern: pr.QueryPattern,
+			UserAgentPattern: pr.UserAgentPattern, StatusPattern: pr.StatusPattern,
+		}
+		if err := ValidateRule(r); err != nil {
+			t.Errorf("rule %s: %v", pr.ID, err)
+		}
+		rules = append(rules, r)
+	}
+	rs, _ := NewRuleset(rules)
+	cases := map[string]Request{
+		"sqli-union-select": {Path: "/item", Query: "id=1%20UNION%20ALL%20SELECT%20null"},
+		"log4shell-ua":      {Path: "/", UserAgent: "${jndi:ldap://x/a}"},
+		"lfi-wrappers":      {Path: "/index.php", Query: "page=php://filter/resource=index"},
+		"sensitive-files":   {Path: "/backup.sql"},
+	}
+	for want, req := range cases {
+		if id, _, _ := rs.Match(req); id != want {
+			t.Errorf("%+v matched %q, want %q", req, id, want)
+		}
+	}
+	if _, ok := BundledPack("../rules"); ok {
+		t.Error("pack names must not escape the packs directory")
+	}
+}
@@ -10,7 +10,6 @@ package threatdetect
 
 import (
 	"math"
-	"strings"
 
 	"github.com/serversupervisor/server/internal/models"
 )
@@ -25,59 +24,25 @@ const (
 	CategorySuspiciousMethod = "SuspiciousMethod"
 )
 
-var pathNeedles = []string{
-	"/.env", "/wp-admin", "/wp-login", "/xmlrpc.php", "/cgi-bin", "/phpmyadmin", "/pma",
-	"/manager/html", "/actuator", "/.git", "/vendor/phpunit", "/solr", "/hudson", "/jenkins",
-	"/autodiscover", "/owa", "../", "/etc/passwd", "/bin/bash", "/struts", "/boaform", "/api/jsonws",
-}
-
-var uaNeedles = []string{
-	"masscan", "nmap", "zgrab", "sqlmap", "nikto", "dirbuster", "gobuster", "wpscan", "acunetix", "nessus",
-}
-
-// Classify returns the attack-pattern category matched by this request, or
-// "" when it looks benign. The pattern list is still fixed in code — only
-// the per-category/per-status weights used to turn matches into a score are
-// admin-configurable (see Weights).
+// Classify returns the category of the first active rule matching this
+// request, or "" when it looks benign. Rules live in the threat_rules table
+// (see rules.go); until they are loaded, the built-in ones apply.
 func Classify(method, path, userAgent string) string {
-	pathLower := strings.ToLower(path)
-	switch {
-	case strings.Contains(pathLower, "/wp-") || strings.Contains(pathLower, "/xmlrpc.php"):
-		return CategoryWordPress
-	case strings.Contains(pathLower, "/admin") || strings.Contains(pathLower, "/manager/html") || strings.Contains(pathLower, "/phpmyadmin"):
-		return CategoryAdminPanel
-	case strings.Contains(pathLower, "../") || strings.Contains(pathLower, "/etc/passwd") || strings.Contains(pathLower, "/bin/bash"):
-		return CategoryPathTraversal
-	}
-
-	for _, needle := range pathNeedles {
-		if strings.Contains(pathLower, needle) {
-			return CategoryKnownScanner
-		}
-	}
-
-	uaLower := strings.ToLower(userAgent)
-	for _, needle := range uaNeedles {
-		if strings.Contains(uaLower, needle) {
-			return CategoryKnownScanner
-		}
-	}
-
-	switch strings.ToUpper(method) {
-	case "OPTIONS", "PROPFIND", "TRACE", "CONNECT":
-		return CategorySuspiciousMethod
-	}
-
-	return ""
+	_, category, _ := ActiveRules().Match(Request{Method: method, Path: path, UserAgent: userAgent})
+	return category
 }
 
-// ClassifyRequests fills in Category for every request in place. The agent
-// no longer decides this (see agent/CLAUDE.md's protocol contract note) —
-// call this once, server-side, right after decoding an agent report and
-// before persisting it.
+// ClassifyRequests fills in Category and RuleID for every request in place.
+// The agent no longer decides this (see agent/CLAUDE.md's protocol contract
+// note) — call this once, server-side, right after decoding an agent report
+// and before persisting it.
 func ClassifyRequests(requests []models.WebRequest) {
+	rs := ActiveRules()
 	for i := range requests {
-		requests[i].Category = Classify(requests[i].Method, requests[i].Path, requests[i].UserAgent)
+		r := &requests[i]
+		r.RuleID, r.Category, _ = rs.Match(Request{
+			Method: r.Method, Path: r.Path, Query: r.Query, UserAgent: r.UserAgent, Status: r.Status,
+		})
 	}
 }
 
@@ -105,6 +70,7 @@ type CategoryCounts struct {
 	PathTraversal    int64
 	KnownScanner     int64
 	SuspiciousMethod int64
+	Other            int64 // categories defined by custom rules or packs
 }
 
 // StatusCounts buckets an IP's suspicious hits by HTTP response status, used
@@ -128,6 +94,7 @@ type Weights struct {
 	CategoryPathTraversal    float64
 	CategoryKnownScanner     float64
 	CategorySuspiciousMethod float64
+	CategoryOther            float64 // any category outside the five above
 
 	Status2xxMultiplier      float64
 	Status3xxMultiplier      float64
@@ -161,6 +128,7 @@ func DefaultWeights() Weights {
 		CategoryPathTraversal:    5,
 		CategoryKnownScanner:     4,
 		CategorySuspiciousMethod: 2,
+		CategoryOther:            3,
 
 		Status2xxMultiplier:      0.1,
 		Status3xxMultiplier:      1,
@@ -199,7 +167,8 @@ func Score(hits, uniquePaths int64, cat CategoryCounts, st StatusCounts, w Weigh
 		float64(cat.AdminPanel)*w.CategoryAdminPanel +
 		float64(cat.PathTraversal)*w.CategoryPathTraversal +
 		float64(cat.KnownScanner)*w.CategoryKnownScanner +
-		float64(cat.SuspiciousMethod)*w.CategorySuspiciousMethod) / h
+		float64(cat.SuspiciousMethod)*w.CategorySuspiciousMethod +
+		float64(cat.Other)*w.CategoryOther) / h
 	avgStatus := (float64(st.Status2xx)*w.Status2xxMultiplier +
 		float64(st.Status3xx)*w.Status3xxMultiplier +
 		float64(st.Status404)*w.Status404Multiplier +
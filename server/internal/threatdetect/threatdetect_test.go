@@ -34,6 +34,36 @@ func TestClassify(t *testing.T) {
 	}
 }
 
+// TestClassify_OverlappingSignatures pins how a request matching several
+// built-in signatures is classified. Rules are tried most severe first, so a
+// path traversal (critical) now wins over a WordPress or admin panel probe
+// (high) where the former fixed switch tested those first; within a
+// severity the shipped order still decides.
+func TestClassify_OverlappingSignatures(t *testing.T) {
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		ua     string
+		want   string
+	}{
+		{"traversal through wordpress (was WordPress)", "GET", "/wp-admin/../../etc/passwd", "Mozilla/5.0", CategoryPathTraversal},
+		{"traversal through admin (was AdminPanel)", "GET", "/admin/../etc/passwd", "Mozilla/5.0", CategoryPathTraversal},
+		{"wordpress before admin at equal severity", "GET", "/wp-content/plugins/x/admin.php", "Mozilla/5.0", CategoryWordPress},
+		{"traversal before scanner path", "GET", "/cgi-bin/../../bin/bash", "Mozilla/5.0", CategoryPathTraversal},
+		{"admin path before scanner agent", "GET", "/admin", "sqlmap/1.6", CategoryAdminPanel},
+		{"wordpress before suspicious method", "PROPFIND", "/wp-login.php", "Mozilla/5.0", CategoryWordPress},
+		{"scanner agent before suspicious method", "OPTIONS", "/", "nmap", CategoryKnownScanner},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Classify(tc.method, tc.path, tc.ua); got != tc.want {
+				t.Errorf("Classify(%q, %q, %q) = %q, want %q", tc.method, tc.path, tc.ua, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestClassifyRequests(t *testing.T) {
 	requests := []models.WebRequest{
 		{Method: "GET", Path: "/wp-admin", UserAgent: "curl"},
@@ -2,6 +2,7 @@ package ws
 
 import (
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -18,14 +19,32 @@ import (
 // best-effort — an agent with no connection (an older binary, or one behind
 // a proxy that blocks WebSocket upgrades) keeps working exactly as before,
 // picking the command up on its next regularly scheduled poll.
+//
+// An agent speaking protocol v2 (see agent_protocol.go) gets the commands
+// themselves over its connection instead: Notify then wakes the connection's
+// session, which claims through the same atomic query and pushes the result.
+// The two paths still cannot double-deliver, since either claim takes a
+// command out of pending.
+//
+// With several replicas an agent is connected to only one of them: a nudge
+// for a host this replica does not hold goes to the others through peers,
+// and connections announced by the others are remembered in elsewhere so a
+// reconnect to another replica is not mistaken for a disconnect.
 type AgentHub struct {
 	mu           sync.RWMutex
-	conns        map[string]*websocket.Conn // host_id -> latest connection
+	conns        map[string]*websocket.Conn        // host_id -> latest connection
+	push         map[*websocket.Conn]chan struct{} // v2 connections -> their session's wake-up
+	elsewhere    map[string]time.Time              // host_id -> last connection announced by another replica
 	onDisconnect func(hostID string)
+	peers        Peers
 }
 
 func NewAgentHub() *AgentHub {
-	return &AgentHub{conns: make(map[string]*websocket.Conn)}
+	return &AgentHub{
+		conns:     make(map[string]*websocket.Conn),
+		push:      make(map[*websocket.Conn]chan struct{}),
+		elsewhere: make(map[string]time.Time),
+	}
 }
 
 // SetOnDisconnect registers a callback fired whenever a host's live
@@ -45,10 +64,35 @@ func (h *AgentHub) Register(hostID string, conn *websocket.Conn) {
 	h.mu.Lock()
 	old := h.conns[hostID]
 	h.conns[hostID] = conn
+	delete(h.elsewhere, hostID)
+	if old != conn {
+		delete(h.push, old)
+	}
 	h.mu.Unlock()
 	if old != nil && old != conn {
 		_ = old.Close()
 	}
+	if h.peers != nil {
+		h.peers.AgentConnected(hostID)
+	}
+}
+
+// MarkConnectedElsewhere records that another replica just took hostID's
+// connection. A copy still registered here is left alone: announcements can
+// arrive late, and a stale socket dies on its own at the next missed pong.
+func (h *AgentHub) MarkConnectedElsewhere(hostID string) {
+	h.mu.Lock()
+	h.elsewhere[hostID] = time.Now()
+	h.mu.Unlock()
+}
+
+// ConnectedElsewhere reports whether another replica announced a connection
+// for hostID after since.
+func (h *AgentHub) ConnectedElsewhere(hostID string, since time.Time) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	at, ok := h.elsewhere[hostID]
+	return ok && at.After(since)
 }
 
 // Unregister removes conn if it is still the registered connection for
@@ -63,6 +107,7 @@ func (h *AgentHub) Unregister(hostID string, conn *websocket.Conn) {
 		delete(h.conns, hostID)
 		removed = true
 	}
+	delete(h.push, conn)
 	fn := h.onDisconnect
 	h.mu.Unlock()
 	if removed && fn != nil {
@@ -70,6 +115,40 @@ func (h *AgentHub) Unregister(hostID string, conn *websocket.Conn) {
 	}
 }
 
+// Disconnect closes hostID's connection here and on the other replicas, e.g.
+// once the credential that authenticated it is revoked: the agent has to
+// reconnect, and authenticate again.
+func (h *AgentHub) Disconnect(hostID string) {
+	h.DisconnectLocal(hostID)
+	if h.peers != nil {
+		h.peers.DisconnectAgent(hostID)
+	}
+}
+
+// DisconnectLocal is Disconnect restricted to this replica's own connection.
+// Closing it ends its read loop, which unregisters it as on any disconnect.
+func (h *AgentHub) DisconnectLocal(hostID string) {
+	h.mu.RLock()
+	conn := h.conns[hostID]
+	h.mu.RUnlock()
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
+
+// EnablePush switches hostID's registered conn to command push (protocol
+// v2). The returned channel is signalled, coalesced, on every Notify for the
+// host; it is never closed.
+func (h *AgentHub) EnablePush(hostID string, conn *websocket.Conn) <-chan struct{} {
+	kick := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.conns[hostID] == conn {
+		h.push[conn] = kick
+	}
+	h.mu.Unlock()
+	return kick
+}
+
 // Connected reports whether hostID currently has a live push connection.
 func (h *AgentHub) Connected(hostID string) bool {
 	h.mu.RLock()
@@ -83,17 +162,37 @@ type agentPollNowMessage struct {
 }
 
 // Notify best-effort tells hostID's live connection, if any, to poll for
-// pending commands immediately. The returned bool is diagnostic only ("was a
+// pending commands immediately (or, for a v2 connection, to have them pushed). The returned bool is diagnostic only ("was a
 // live connection nudged") — callers must never treat a false return as an
 // error: the command is already durably queued in remote_commands and will
 // be picked up on the agent's next regularly scheduled poll regardless.
+// A host with no connection here is nudged through the other replicas.
 func (h *AgentHub) Notify(hostID string) bool {
+	if h.NotifyLocal(hostID) {
+		return true
+	}
+	if h.peers != nil {
+		h.peers.NotifyAgent(hostID)
+	}
+	return false
+}
+
+// NotifyLocal is Notify restricted to this replica's own connections.
+func (h *AgentHub) NotifyLocal(hostID string) bool {
 	h.mu.RLock()
 	conn := h.conns[hostID]
+	kick := h.push[conn]
 	h.mu.RUnlock()
 	if conn == nil {
 		return false
 	}
+	if kick != nil {
+		select {
+		case kick <- struct{}{}:
+		default:
+		}
+		return true
+	}
 
 	if err := safeWriteJSON(conn, agentPollNowMessage{Type: "poll_now"}); err != nil {
 		_ = conn.Close()
@@ -165,3 +165,88 @@ func TestAgentHub_Notify(t *testing.T) {
 		}
 	})
 }
+
+// recordingPeers captures what the hubs hand to the other replicas.
+type recordingPeers struct {
+	notified, connected []string
+	chunks              []string
+	notifications       []interface{}
+}
+
+func (p *recordingPeers) NotifyAgent(hostID string)    { p.notified = append(p.notified, hostID) }
+func (p *recordingPeers) AgentConnected(hostID string) { p.connected = append(p.connected, hostID) }
+func (p *recordingPeers) DisconnectAgent(string)       {}
+func (p *recordingPeers) StreamChunk(commandID, chunk string) {
+	p.chunks = append(p.chunks, commandID+":"+chunk)
+}
+func (p *recordingPeers) StreamStatus(string, string, string) {}
+func (p *recordingPeers) BroadcastNotification(payload interface{}) {
+	p.notifications = append(p.notifications, payload)
+}
+
+func TestAgentHub_NotifyGoesToPeersOnlyWhenNotLocal(t *testing.T) {
+	peers := &recordingPeers{}
+	hub := NewAgentHub()
+	hub.peers = peers
+
+	if hub.Notify("host-1") {
+		t.Error("Notify without a local connection must report false")
+	}
+	server, client := newTestAgentConn(t)
+	hub.Register("host-1", server)
+	if !hub.Notify("host-1") {
+		t.Error("Notify with a local connection must report true")
+	}
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := client.ReadMessage(); err != nil {
+		t.Fatalf("agent did not receive the local nudge: %v", err)
+	}
+
+	if len(peers.notified) != 1 || peers.notified[0] != "host-1" {
+		t.Errorf("peer nudges = %v, want one for the unconnected call", peers.notified)
+	}
+	if len(peers.connected) != 1 {
+		t.Errorf("Register must announce the connection once, got %v", peers.connected)
+	}
+}
+
+func TestAgentHub_ConnectedElsewhere(t *testing.T) {
+	hub := NewAgentHub()
+	before := time.Now().Add(-time.Second)
+	hub.MarkConnectedElsewhere("host-1")
+	if !hub.ConnectedElsewhere("host-1", before) {
+		t.Error("expected an announcement after since to count")
+	}
+	if hub.ConnectedElsewhere("host-1", time.Now().Add(time.Second)) {
+		t.Error("an announcement older than since must not count")
+	}
+
+	server, _ := newTestAgentConn(t)
+	hub.Register("host-1", server)
+	if hub.ConnectedElsewhere("host-1", before) {
+		t.Error("a local Register supersedes the other replica's connection")
+	}
+}
+
+func TestStreamAndNotificationHubs_ForwardButLocalDoesNot(t *testing.T) {
+	peers := &recordingPeers{}
+	streams := NewCommandStreamHub()
+	streams.peers = peers
+	notifs := NewNotificationHub()
+	notifs.peers = peers
+
+	streams.Broadcast("cmd-1", "a")
+	streams.BroadcastLocal("cmd-1", "b")
+	if got := streams.GetBufferedOutput("cmd-1"); got != "ab" {
+		t.Errorf("buffer = %q, want both chunks", got)
+	}
+	notifs.Broadcast(map[string]string{"type": "x"})
+	notifs.BroadcastLocal(map[string]string{"type": "y"})
+
+	if len(peers.chunks) != 1 || peers.chunks[0] != "cmd-1:a" {
+		t.Errorf("forwarded chunks = %v", peers.chunks)
+	}
+	if len(peers.notifications) != 1 {
+		t.Errorf("forwarded notifications = %v", peers.notifications)
+	}
+}
@@ -0,0 +1,199 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+	"github.com/serversupervisor/server/internal/safego"
+)
+
+// AgentProtocolVersion is the highest agent channel protocol this server
+// speaks. Version 1 (an agent that never says hello) only receives "poll_now"
+// nudges. Version 2 carries the commands themselves, their live output and
+// their results over the same socket; see protocol/README.md.
+const AgentProtocolVersion = 2
+
+// agentReleaseTimeout bounds the release of unacknowledged commands once a
+// push connection is gone.
+const agentReleaseTimeout = 10 * time.Second
+
+// AgentCommands is what the agent channel needs to carry commands and their
+// results for protocol v2 agents. *agent.Service satisfies it.
+type AgentCommands interface {
+	ClaimCommands(ctx context.Context, hostID string) ([]models.PendingCommand, error)
+	ReleaseCommands(ctx context.Context, hostID string, ids []string) error
+	ReportCommandResult(ctx context.Context, hostID string, result models.CommandResult) error
+	StreamCommandOutput(ctx context.Context, hostID, commandID, chunk string) error
+}
+
+// SetAgentCommands enables protocol v2 on the agent channel. Without it every
+// agent is served as v1. Must be called before serving.
+func (h *WSHandler) SetAgentCommands(c AgentCommands) {
+	h.agentCommands = c
+}
+
+// agentInboundMessage is the shape of app-level messages an agent sends over
+// its push connection. A v1 agent only ever sends "heartbeat"; a v2 agent
+// opens with "hello" and then acks pushed commands and sends their output and
+// results. Unknown types are ignored, leaving room for later versions.
+type agentInboundMessage struct {
+	Type      string                `json:"type"`
+	V         int                   `json:"v,omitempty"`          // hello: highest version the agent speaks
+	IDs       []string              `json:"ids,omitempty"`        // ack, reject
+	Seq       uint64                `json:"seq,omitempty"`        // result: echoed in its result_ack
+	Result    *models.CommandResult `json:"result,omitempty"`     // result
+	CommandID string                `json:"command_id,omitempty"` // output
+	Chunk     string                `json:"chunk,omitempty"`      // output
+}
+
+// agentOutboundMessage is what the server sends a v2 agent.
+type agentOutboundMessage struct {
+	Type     string                  `json:"type"`
+	V        int                     `json:"v,omitempty"`        // welcome: version in use for this connection
+	Commands []models.PendingCommand `json:"commands,omitempty"` // commands
+	Seq      uint64                  `json:"seq,omitempty"`      // result_ack: the result's seq
+	Error    string                  `json:"error,omitempty"`    // result_ack: the result was refused for good
+}
+
+// agentSession is the v2 state of one agent connection: the commands pushed
+// but not yet acknowledged, which go back to pending if the connection drops
+// so the next push or report delivers them again. The agent ignores a command
+// ID it has already run, so a redelivery after a lost ack is harmless.
+type agentSession struct {
+	hostID string
+	conn   *websocket.Conn
+	cmds   AgentCommands
+	kick   <-chan struct{}
+
+	mu      sync.Mutex
+	unacked map[string]struct{}
+}
+
+// negotiate answers an agent's hello. It returns nil when the connection stays
+// on v1 (old hello, or v2 not enabled on this server).
+func (h *WSHandler) negotiate(hostID string, conn *websocket.Conn, agentVersion int) *agentSession {
+	version := min(agentVersion, AgentProtocolVersion)
+	if h.agentCommands == nil {
+		version = 1
+	}
+	if version < 1 {
+		return nil
+	}
+	if err := safeWriteJSON(conn, agentOutboundMessage{Type: "welcome", V: version}); err != nil || version < 2 {
+		return nil
+	}
+	return &agentSession{
+		hostID:  hostID,
+		conn:    conn,
+		cmds:    h.agentCommands,
+		kick:    h.agentHub.EnablePush(hostID, conn),
+		unacked: make(map[string]struct{}),
+	}
+}
+
+// run pushes the host's pending commands now (anything queued while the agent
+// was away, or released by its previous connection) and again on every
+// Notify, until ctx is cancelled.
+func (s *agentSession) run(ctx context.Context) {
+	defer safego.Recover(ctx, "ws.agentSession.run")
+	s.deliver(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.kick:
+			s.deliver(ctx)
+		}
+	}
+}
+
+func (s *agentSession) deliver(ctx context.Context) {
+	cmds, err := s.cmds.ClaimCommands(ctx, s.hostID)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "agent channel: failed to claim commands", slog.String("host_id", s.hostID), slog.Any("err", err))
+		}
+		return
+	}
+	if len(cmds) == 0 {
+		return
+	}
+	s.mu.Lock()
+	for _, c := range cmds {
+		s.unacked[c.ID] = struct{}{}
+	}
+	s.mu.Unlock()
+	// On a failed write the connection is dead: the commands stay unacked and
+	// release() hands them back when the channel closes.
+	if err := safeWriteJSON(s.conn, agentOutboundMessage{Type: "commands", Commands: cmds}); err != nil {
+		_ = s.conn.Close()
+	}
+}
+
+// handle processes one inbound v2 message.
+func (s *agentSession) handle(ctx context.Context, msg agentInboundMessage) {
+	switch msg.Type {
+	case "ack":
+		s.forget(msg.IDs)
+	case "reject":
+		// The agent could not queue them (its command queue is full): back to
+		// pending for a later push or report.
+		s.forget(msg.IDs)
+		if err := s.cmds.ReleaseCommands(ctx, s.hostID, msg.IDs); err != nil {
+			slog.WarnContext(ctx, "agent channel: failed to release rejected commands", slog.String("host_id", s.hostID), slog.Any("err", err))
+		}
+	case "output":
+		_ = s.cmds.StreamCommandOutput(ctx, s.hostID, msg.CommandID, msg.Chunk)
+	case "result":
+		if msg.Result == nil {
+			return
+		}
+		s.forget([]string{msg.Result.CommandID})
+		ack := agentOutboundMessage{Type: "result_ack", Seq: msg.Seq}
+		if err := s.cmds.ReportCommandResult(ctx, s.hostID, *msg.Result); err != nil {
+			var ae *apperr.Error
+			if !errors.As(err, &ae) || ae.HTTPStatus >= 500 {
+				// No ack: the agent resends it, over HTTP if need be.
+				return
+			}
+			ack.Error = ae.Message
+		}
+		_ = safeWriteJSON(s.conn, ack)
+	}
+}
+
+func (s *agentSession) forget(ids []string) {
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.unacked, id)
+	}
+	s.mu.Unlock()
+}
+
+// release returns the commands the agent never acknowledged to pending. It
+// reports whether there were any.
+func (s *agentSession) release() bool {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.unacked))
+	for id := range s.unacked {
+		ids = append(ids, id)
+	}
+	s.unacked = make(map[string]struct{})
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), agentReleaseTimeout)
+	defer cancel()
+	if err := s.cmds.ReleaseCommands(ctx, s.hostID, ids); err != nil {
+		slog.WarnContext(ctx, "agent channel: failed to release unacknowledged commands", slog.String("host_id", s.hostID), slog.Any("err", err))
+		return false
+	}
+	return true
+}
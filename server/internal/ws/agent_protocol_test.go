@@ -0,0 +1,173 @@
+package ws
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/serversupervisor/server/internal/apperr"
+	"github.com/serversupervisor/server/internal/models"
+)
+
+// fakeAgentCommands hands out the queued commands once and records the rest.
+type fakeAgentCommands struct {
+	mu        sync.Mutex
+	pending   []models.PendingCommand
+	released  []string
+	results   []models.CommandResult
+	chunks    []string
+	resultErr error
+}
+
+func (f *fakeAgentCommands) ClaimCommands(context.Context, string) ([]models.PendingCommand, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cmds := f.pending
+	f.pending = nil
+	return cmds, nil
+}
+
+func (f *fakeAgentCommands) ReleaseCommands(_ context.Context, _ string, ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = append(f.released, ids...)
+	return nil
+}
+
+func (f *fakeAgentCommands) ReportCommandResult(_ context.Context, _ string, r models.CommandResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, r)
+	return f.resultErr
+}
+
+func (f *fakeAgentCommands) StreamCommandOutput(_ context.Context, _, commandID, chunk string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.chunks = append(f.chunks, commandID+":"+chunk)
+	return nil
+}
+
+func readOutbound(t *testing.T, client *websocket.Conn) agentOutboundMessage {
+	t.Helper()
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg agentOutboundMessage
+	if err := client.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func newV2Session(t *testing.T, cmds *fakeAgentCommands) (*WSHandler, *agentSession, *websocket.Conn) {
+	t.Helper()
+	h := &WSHandler{agentHub: NewAgentHub(), agentCommands: cmds}
+	server, client := newTestAgentConn(t)
+	h.agentHub.Register("host-1", server)
+	s := h.negotiate("host-1", server, 2)
+	if s == nil {
+		t.Fatal("a v2 hello must open a session")
+	}
+	if msg := readOutbound(t, client); msg.Type != "welcome" || msg.V != 2 {
+		t.Fatalf("got %+v, want welcome v2", msg)
+	}
+	return h, s, client
+}
+
+func TestAgentSession_PushAckAndRelease(t *testing.T) {
+	cmds := &fakeAgentCommands{pending: []models.PendingCommand{{ID: "c1"}, {ID: "c2"}}}
+	h, s, client := newV2Session(t, cmds)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.run(ctx); close(done) }()
+
+	// Pending on connect: pushed at once.
+	msg := readOutbound(t, client)
+	if msg.Type != "commands" || len(msg.Commands) != 2 {
+		t.Fatalf("got %+v, want the two pending commands", msg)
+	}
+	s.handle(ctx, agentInboundMessage{Type: "ack", IDs: []string{"c1"}})
+
+	// Dispatched later: Notify wakes the session instead of sending poll_now.
+	cmds.mu.Lock()
+	cmds.pending = []models.PendingCommand{{ID: "c3"}}
+	cmds.mu.Unlock()
+	if !h.agentHub.Notify("host-1") {
+		t.Fatal("Notify must report the push connection as reached")
+	}
+	if msg := readOutbound(t, client); msg.Type != "commands" || len(msg.Commands) != 1 || msg.Commands[0].ID != "c3" {
+		t.Fatalf("got %+v, want c3 pushed", msg)
+	}
+
+	cancel()
+	<-done
+	if !s.release() {
+		t.Fatal("release must report the unacknowledged commands")
+	}
+	sort.Strings(cmds.released)
+	if len(cmds.released) != 2 || cmds.released[0] != "c2" || cmds.released[1] != "c3" {
+		t.Errorf("released %v, want the unacknowledged c2 and c3", cmds.released)
+	}
+	if s.release() {
+		t.Error("a second release has nothing left to return")
+	}
+}
+
+func TestAgentSession_RejectReleasesAtOnce(t *testing.T) {
+	cmds := &fakeAgentCommands{}
+	_, s, _ := newV2Session(t, cmds)
+	s.unacked["c1"] = struct{}{}
+	s.handle(context.Background(), agentInboundMessage{Type: "reject", IDs: []string{"c1"}})
+	if len(cmds.released) != 1 || len(s.unacked) != 0 {
+		t.Errorf("released %v, unacked %v", cmds.released, s.unacked)
+	}
+}
+
+func TestAgentSession_ResultAcks(t *testing.T) {
+	cmds := &fakeAgentCommands{}
+	_, s, client := newV2Session(t, cmds)
+	ctx := context.Background()
+
+	s.handle(ctx, agentInboundMessage{Type: "output", CommandID: "c1", Chunk: "line\n"})
+	s.handle(ctx, agentInboundMessage{Type: "result", Seq: 7, Result: &models.CommandResult{CommandID: "c1", Status: "completed"}})
+	if msg := readOutbound(t, client); msg.Type != "result_ack" || msg.Seq != 7 || msg.Error != "" {
+		t.Fatalf("got %+v, want a clean result_ack for seq 7", msg)
+	}
+	if len(cmds.chunks) != 1 || len(cmds.results) != 1 {
+		t.Errorf("chunks %v, results %v", cmds.chunks, cmds.results)
+	}
+
+	// Refused for good: acked with the reason so the agent stops resending.
+	cmds.resultErr = apperr.Forbidden("command does not belong to host")
+	s.handle(ctx, agentInboundMessage{Type: "result", Seq: 8, Result: &models.CommandResult{CommandID: "c2", Status: "completed"}})
+	if msg := readOutbound(t, client); msg.Seq != 8 || msg.Error == "" {
+		t.Fatalf("got %+v, want a result_ack carrying the error", msg)
+	}
+
+	// Server-side failure: no ack, the agent retries.
+	cmds.resultErr = apperr.Failed("failed to update command")
+	s.handle(ctx, agentInboundMessage{Type: "result", Result: &models.CommandResult{CommandID: "c3", Status: "completed"}})
+	_ = client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, _, err := client.ReadMessage(); err == nil {
+		t.Error("a result the server failed to record must not be acked")
+	}
+}
+
+func TestNegotiate_FallsBackToV1(t *testing.T) {
+	h := &WSHandler{agentHub: NewAgentHub()} // v2 not enabled
+	server, client := newTestAgentConn(t)
+	h.agentHub.Register("host-1", server)
+	if s := h.negotiate("host-1", server, 2); s != nil {
+		t.Fatal("no session without AgentCommands")
+	}
+	if msg := readOutbound(t, client); msg.Type != "welcome" || msg.V != 1 {
+		t.Fatalf("got %+v, want welcome v1", msg)
+	}
+	// Still nudged the v1 way.
+	h.agentHub.Notify("host-1")
+	if msg := readOutbound(t, client); msg.Type != "poll_now" {
+		t.Fatalf("got %+v, want poll_now", msg)
+	}
+}
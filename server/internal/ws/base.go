@@ -68,10 +68,12 @@ type WSHandler struct {
 	streamHub          *CommandStreamHub
 	notifHub           *NotificationHub
 	agentHub           *AgentHub
+	agentCommands      AgentCommands
 	events             *events.Bus
 	latestAgentVersion func() string
 	ipConns            map[string]int
 	ipConnsMu          sync.Mutex
+	consoles           ProxmoxConsoleAttacher
 
 	// Shared, short-lived cache of the dashboard snapshot payload. The payload is
 	// identical for every client (no per-user filtering), so it is computed once
@@ -117,10 +119,13 @@ const agentDisconnectGrace = 10 * time.Second
 // agentws connection (older agents, proxies blocking WS upgrades) are
 // unaffected and keep relying on that sweep exactly as before.
 func (h *WSHandler) handleAgentDisconnect(hostID string) {
+	// An announcement from another replica up to a grace window before the
+	// close counts too: the agent may have reconnected before we noticed.
+	since := time.Now().Add(-agentDisconnectGrace)
 	safego.Go(context.Background(), "ws.agent-disconnect-check", func() {
 		time.Sleep(agentDisconnectGrace)
-		if h.agentHub.Connected(hostID) {
-			return // reconnected within the grace window
+		if h.agentHub.Connected(hostID) || h.agentHub.ConnectedElsewhere(hostID, since) {
+			return // reconnected within the grace window, here or to another replica
 		}
 		ctx := context.Background()
 		changed, err := h.db.MarkHostOfflineIfStale(ctx, hostID, agentDisconnectGrace)
@@ -18,6 +18,10 @@ type CommandStreamHub struct {
 
 	bufferMu sync.Mutex
 	buffers  map[string]string // accumulated stream output per active command
+
+	// The agent posts its output to one replica while the browser may watch
+	// from another: peers replays chunks and statuses there.
+	peers Peers
 }
 
 // NewCommandStreamHub creates a new streaming hub.
@@ -61,6 +65,14 @@ func (h *CommandStreamHub) Unregister(commandID string, conn *websocket.Conn) {
 // The chunk is also appended to an in-memory buffer so late-joining clients
 // can receive the full output history via cmd_stream_init.
 func (h *CommandStreamHub) Broadcast(commandID string, logChunk string) {
+	h.BroadcastLocal(commandID, logChunk)
+	if h.peers != nil {
+		h.peers.StreamChunk(commandID, logChunk)
+	}
+}
+
+// BroadcastLocal is Broadcast restricted to this replica's clients.
+func (h *CommandStreamHub) BroadcastLocal(commandID string, logChunk string) {
 	h.bufferMu.Lock()
 	h.buffers[commandID] += logChunk
 	h.bufferMu.Unlock()
@@ -90,6 +102,14 @@ func (h *CommandStreamHub) GetBufferedOutput(commandID string) string {
 // output is included in the payload when non-empty (e.g. for completed commands).
 // On terminal statuses (completed/failed) the in-memory chunk buffer is released.
 func (h *CommandStreamHub) BroadcastStatus(commandID, status, output string) {
+	h.BroadcastStatusLocal(commandID, status, output)
+	if h.peers != nil {
+		h.peers.StreamStatus(commandID, status, output)
+	}
+}
+
+// BroadcastStatusLocal is BroadcastStatus restricted to this replica's clients.
+func (h *CommandStreamHub) BroadcastStatusLocal(commandID, status, output string) {
 	h.mu.RLock()
 	conns := make([]*websocket.Conn, 0, len(h.clients[commandID]))
 	for conn := range h.clients[commandID] {
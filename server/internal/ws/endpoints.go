@@ -53,15 +53,6 @@ func (h *WSHandler) Apt(c *gin.Context) {
 	})
 }
 
-// agentInboundMessage is the shape of app-level messages an agent sends over
-// its push connection. Only "heartbeat" is meaningful today — the connection
-// itself already proves liveness via WS ping/pong; the heartbeat exists to
-// make that traffic explicit and app-level, and to leave room for the agent
-// to report something more useful here later without a wire-format change.
-type agentInboundMessage struct {
-	Type string `json:"type"`
-}
-
 // AgentChannel is a persistent, agent-initiated WebSocket connection used to
 // push newly dispatched commands to the agent immediately (see AgentHub)
 // instead of it waiting out its next poll interval. It sits on the same
@@ -70,6 +61,10 @@ type agentInboundMessage struct {
 // context by the time this runs. Purely optional from the agent's point of
 // view: an agent that never connects here keeps working exactly as before
 // via the existing poll/report cycle, just without the latency win.
+//
+// An agent that opens with a v2 hello also gets its commands pushed here and
+// sends their output and results back on the same socket (agentSession);
+// commands it had not acknowledged when the socket drops go back to pending.
 func (h *WSHandler) AgentChannel(c *gin.Context) {
 	hostID := c.GetString("host_id")
 	if hostID == "" {
@@ -104,7 +99,29 @@ func (h *WSHandler) AgentChannel(c *gin.Context) {
 	pingTicker := time.NewTicker(wsPingInterval)
 	defer pingTicker.Stop()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	var session *agentSession
+	var sessionDone chan struct{}
 	done := make(chan struct{})
+	defer func() {
+		// The read loop owns session until it exits.
+		_ = conn.Close()
+		<-done
+		cancel()
+		if session == nil {
+			return
+		}
+		<-sessionDone
+		// Whatever this connection was still holding goes to the host's
+		// current connection, here or on another replica, or waits for the
+		// next report. Unregistered first so the nudge cannot land on this
+		// dead connection (the outer deferred Unregister is then a no-op).
+		h.agentHub.Unregister(hostID, conn)
+		if session.release() {
+			h.agentHub.Notify(hostID)
+		}
+	}()
+
 	go func() {
 		defer close(done)
 		defer safego.Recover(context.Background(), "ws.agentChannel.readLoop")
@@ -113,9 +130,22 @@ func (h *WSHandler) AgentChannel(c *gin.Context) {
 			if err := conn.ReadJSON(&msg); err != nil {
 				return
 			}
-			// Any well-formed inbound message (heartbeat today) is itself
-			// proof of liveness, same as a protocol pong.
+			// Any well-formed inbound message is itself proof of liveness,
+			// same as a protocol pong.
 			_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			switch {
+			case msg.Type == "hello" && session == nil:
+				if s := h.negotiate(hostID, conn, msg.V); s != nil {
+					sessionDone = make(chan struct{})
+					session = s
+					go func() {
+						defer close(sessionDone)
+						s.run(ctx)
+					}()
+				}
+			case session != nil:
+				session.handle(ctx, msg)
+			}
 		}
 	}()
 
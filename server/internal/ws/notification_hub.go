@@ -11,6 +11,7 @@ import (
 type NotificationHub struct {
 	mu      sync.Mutex
 	clients map[*websocket.Conn]struct{}
+	peers   Peers
 }
 
 func NewNotificationHub() *NotificationHub {
@@ -29,9 +30,17 @@ func (h *NotificationHub) Unregister(conn *websocket.Conn) {
 	h.mu.Unlock()
 }
 
-// Broadcast sends a JSON payload to all registered clients.
-// Failed connections are silently dropped.
+// Broadcast sends a JSON payload to all registered clients, on this replica
+// and the others. Failed connections are silently dropped.
 func (h *NotificationHub) Broadcast(payload interface{}) {
+	h.BroadcastLocal(payload)
+	if h.peers != nil {
+		h.peers.BroadcastNotification(payload)
+	}
+}
+
+// BroadcastLocal sends a JSON payload to the clients of this replica only.
+func (h *NotificationHub) BroadcastLocal(payload interface{}) {
 	h.mu.Lock()
 	conns := make([]*websocket.Conn, 0, len(h.clients))
 	for conn := range h.clients {
@@ -0,0 +1,28 @@
+package ws
+
+// Peers carries hub traffic to the other server replicas sharing the database
+// (cluster.Relay when HA_ENABLED is set; unset on a single server). Every
+// method is fire-and-forget and must not block: the receiving replicas replay
+// the call on their own hubs through the *Local / *Elsewhere methods, which
+// never forward again.
+type Peers interface {
+	// NotifyAgent nudges hostID on whichever replica holds its connection.
+	NotifyAgent(hostID string)
+	// AgentConnected announces that this replica now holds hostID's connection.
+	AgentConnected(hostID string)
+	// DisconnectAgent closes hostID's connection on whichever replica holds it.
+	DisconnectAgent(hostID string)
+	StreamChunk(commandID, chunk string)
+	StreamStatus(commandID, status, output string)
+	BroadcastNotification(payload interface{})
+}
+
+// SetPeers wires the hubs of this handler (agent, command stream,
+// notifications) to the other replicas. Must be called before serving.
+func (h *WSHandler) SetPeers(p Peers) {
+	h.agentHub.peers = p
+	h.streamHub.peers = p
+	if h.notifHub != nil {
+		h.notifHub.peers = p
+	}
+}
@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/serversupervisor/server/internal/apperr"
+)
+
+// ProxmoxConsoleAttacher hands out the upstream PVE console websocket of a
+// session opened through POST /proxmox/guests/:id/console (implemented by the
+// proxmox service, which also owns the authorization and audit trail).
+type ProxmoxConsoleAttacher interface {
+	AttachConsole(ctx context.Context, sessionID, username string) (*websocket.Conn, func(sent, received int64), error)
+}
+
+// SetProxmoxConsole wires the console broker; ProxmoxConsole answers 404
+// until it is set.
+func (h *WSHandler) SetProxmoxConsole(a ProxmoxConsoleAttacher) {
+	h.consoles = a
+}
+
+// ProxmoxConsole relays a guest console between the browser (noVNC or
+// xterm.js) and PVE. The frames are passed through untouched: RFB for a VNC
+// session, PVE's termproxy protocol for a terminal.
+func (h *WSHandler) ProxmoxConsole(c *gin.Context) {
+	if h.consoles == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "console unavailable"})
+		return
+	}
+	ip := c.ClientIP()
+	if !h.acquireConn(ip) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many WebSocket connections from this IP"})
+		return
+	}
+	defer h.releaseConn(ip)
+
+	// noVNC asks for the "binary" subprotocol and drops the connection
+	// when the server does not echo it.
+	up := h.upgrader()
+	up.Subprotocols = []string{"binary"}
+	conn, err := up.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	claims, ok := h.authenticateWSClaims(c, conn)
+	if !ok {
+		return
+	}
+	username, _ := claims["sub"].(string)
+
+	upstream, done, err := h.consoles.AttachConsole(c.Request.Context(), c.Param("session"), username)
+	if err != nil {
+		msg := err.Error()
+		var ae *apperr.Error
+		if errors.As(err, &ae) {
+			msg = ae.Message
+		}
+		if len(msg) > 120 { // control frame payload limit
+			msg = msg[:120]
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, msg))
+		return
+	}
+	defer func() { _ = upstream.Close() }()
+
+	sent, received := relayConsole(conn, upstream)
+	done(sent, received)
+}
+
+// relayConsole copies frames both ways until either side closes, then closes
+// both. It returns the bytes sent by the browser and received from PVE.
+func relayConsole(browser, upstream *websocket.Conn) (sent, received int64) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	pump := func(dst, src *websocket.Conn, n *int64) {
+		defer wg.Done()
+		// Closing both ends unblocks the other pump's read.
+		defer func() {
+			_ = dst.Close()
+			_ = src.Close()
+		}()
+		for {
+			mt, data, err := src.ReadMessage()
+			if err != nil {
+				return
+			}
+			*n += int64(len(data))
+			if err := dst.WriteMessage(mt, data); err != nil {
+				return
+			}
+		}
+	}
+	go pump(upstream, browser, &sent)
+	go pump(browser, upstream, &received)
+	wg.Wait()
+	return sent, received
+}
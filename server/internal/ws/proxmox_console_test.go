@@ -0,0 +1,49 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRelayConsole_BothWaysUntilClose(t *testing.T) {
+	browserSide, browser := newTestAgentConn(t)
+	upstreamSide, pve := newTestAgentConn(t)
+
+	type counts struct{ sent, received int64 }
+	done := make(chan counts, 1)
+	go func() {
+		s, r := relayConsole(browserSide, upstreamSide)
+		done <- counts{s, r}
+	}()
+
+	if err := browser.WriteMessage(websocket.BinaryMessage, []byte("RFB 003.008\n")); err != nil {
+		t.Fatal(err)
+	}
+	_ = pve.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, msg, err := pve.ReadMessage(); err != nil || string(msg) != "RFB 003.008\n" {
+		t.Fatalf("upstream got %q, %v", msg, err)
+	}
+	if err := pve.WriteMessage(websocket.BinaryMessage, []byte("frame")); err != nil {
+		t.Fatal(err)
+	}
+	_ = browser.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, msg, err := browser.ReadMessage(); err != nil || string(msg) != "frame" {
+		t.Fatalf("browser got %q, %v", msg, err)
+	}
+
+	// PVE hanging up must end the relay and drop the browser too.
+	_ = pve.Close()
+	select {
+	case c := <-done:
+		if c.sent != 12 || c.received != 5 {
+			t.Errorf("counts = %+v, want sent 12 received 5", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("relay did not stop after upstream close")
+	}
+	if _, _, err := browser.ReadMessage(); err == nil {
+		t.Error("browser connection should be closed")
+	}
+}